@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/deployra/deployra/api/internal/config"
 	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	webhookgithub "github.com/deployra/deployra/api/internal/handlers/webhooks/github"
+	"github.com/deployra/deployra/api/internal/middleware"
 	"github.com/deployra/deployra/api/internal/redis"
 	"github.com/deployra/deployra/api/internal/routes"
 	"github.com/deployra/deployra/api/internal/websocket"
+	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 )
 
 func main() {
@@ -35,13 +44,16 @@ func main() {
 
 	// Middleware
 	app.Use(recover.New())
-	app.Use(logger.New())
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     cfg.CorsOrigins,
-		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
-		AllowCredentials: true,
+	app.Use(requestid.New())
+	app.Use(middleware.RequestContextMiddleware())
+	app.Use(logger.New(logger.Config{
+		Format: "[${time}] ${locals:requestid} ${status} - ${latency} ${method} ${path}\n",
 	}))
+	corsMiddleware, err := middleware.CORSMiddleware(cfg)
+	if err != nil {
+		log.Fatalf("Invalid CORS configuration: %v", err)
+	}
+	app.Use(corsMiddleware)
 
 	// Setup routes
 	routes.Setup(app, cfg)
@@ -49,14 +61,82 @@ func main() {
 	// Initialize WebSocket hub
 	websocket.GetHub()
 
+	// Create context that is cancelled on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Start Redis subscriber for WebSocket messages
-	go websocket.StartRedisSubscriber(cfg)
+	subscriberDone := make(chan struct{})
+	go func() {
+		websocket.StartRedisSubscriber(ctx, cfg)
+		close(subscriberDone)
+	}()
+
+	// Delete old deployment logs in the background
+	retentionDone := make(chan struct{})
+	go func() {
+		deploy.StartLogRetention(ctx)
+		close(retentionDone)
+	}()
+
+	// Fail deployments that never report back
+	watchdogDone := make(chan struct{})
+	go func() {
+		deploy.StartDeploymentWatchdog(ctx)
+		close(watchdogDone)
+	}()
+
+	// Hard delete services once they can no longer be restored
+	reaperDone := make(chan struct{})
+	go func() {
+		deploy.StartServiceReaper(ctx)
+		close(reaperDone)
+	}()
+
+	// Build debounced GitHub pushes once they are due
+	pushBuildsDone := make(chan struct{})
+	go func() {
+		webhookgithub.StartPushBuilds(ctx)
+		close(pushBuildsDone)
+	}()
+
+	// Handle graceful shutdown
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signalChan
+		log.Println("Received termination signal, shutting down gracefully...")
+
+		// WebSocket connections never finish on their own, close them so draining can complete
+		websocket.GetHub().CloseAll()
+
+		if err := app.ShutdownWithTimeout(time.Duration(cfg.ShutdownTimeout) * time.Second); err != nil {
+			log.Printf("Error shutting down server: %v", err)
+		}
+	}()
 
 	// Start server
 	log.Printf("Server starting on port %s", cfg.Port)
 	if err := app.Listen(":" + cfg.Port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+
+	// Stop background workers and close connections
+	cancel()
+	<-subscriberDone
+	<-retentionDone
+	<-watchdogDone
+	<-reaperDone
+	<-pushBuildsDone
+
+	if err := redis.Close(); err != nil {
+		log.Printf("Error closing Redis: %v", err)
+	}
+	if err := database.Close(); err != nil {
+		log.Printf("Error closing database: %v", err)
+	}
+
+	log.Println("Server stopped")
 }
 
 func customErrorHandler(c *fiber.Ctx, err error) error {
@@ -65,7 +145,8 @@ func customErrorHandler(c *fiber.Ctx, err error) error {
 		code = e.Code
 	}
 	return c.Status(code).JSON(fiber.Map{
-		"status":  "error",
-		"message": err.Error(),
+		"status":    "error",
+		"message":   err.Error(),
+		"requestId": response.RequestID(c),
 	})
 }
@@ -0,0 +1,368 @@
+package analyze
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Runtimes the builder builds an image with
+const (
+	RuntimeDockerfile = "dockerfile"
+	RuntimeBuildpacks = "buildpacks"
+)
+
+// Port web services are reached on, the container port is suggested per framework
+const defaultServicePort = 80
+
+// Container port when nothing in the repository tells
+const defaultContainerPort = 8080
+
+// FileReader reads the files of a repository at the analyzed ref. ReadFile returns nil
+// content and no error when the file doesn't exist.
+type FileReader interface {
+	ReadFile(ctx context.Context, path string) ([]byte, error)
+}
+
+// Suggestion is the service configuration suggested for a repository
+type Suggestion struct {
+	ServiceType     string `json:"serviceType"`
+	Runtime         string `json:"runtime"`
+	Language        string `json:"language,omitempty"`
+	Framework       string `json:"framework,omitempty"`
+	Port            int    `json:"port"`
+	BuildCommand    string `json:"buildCommand,omitempty"`
+	StartCommand    string `json:"startCommand,omitempty"`
+	HealthCheckPath string `json:"healthCheckPath,omitempty"`
+
+	// Files the suggestion is based on
+	DetectedFiles []string `json:"detectedFiles"`
+}
+
+// A web framework and the port it listens on by default
+type framework struct {
+	dependency string
+	name       string
+	port       int
+}
+
+// Node.js frameworks, meta frameworks first as they depend on the others
+var nodeFrameworks = []framework{
+	{"next", "Next.js", 3000},
+	{"nuxt", "Nuxt", 3000},
+	{"@remix-run/node", "Remix", 3000},
+	{"@nestjs/core", "NestJS", 3000},
+	{"express", "Express", 3000},
+	{"fastify", "Fastify", 3000},
+	{"koa", "Koa", 3000},
+	{"@hapi/hapi", "hapi", 3000},
+}
+
+// Go web frameworks, matched on module paths of go.mod
+var goFrameworks = []framework{
+	{"github.com/gin-gonic/gin", "Gin", 8080},
+	{"github.com/labstack/echo", "Echo", 8080},
+	{"github.com/gofiber/fiber", "Fiber", 8080},
+	{"github.com/go-chi/chi", "chi", 8080},
+	{"github.com/gorilla/mux", "Gorilla", 8080},
+}
+
+// Python web frameworks, matched on package names of requirements.txt and pyproject.toml
+var pythonFrameworks = []framework{
+	{"django", "Django", 8000},
+	{"fastapi", "FastAPI", 8000},
+	{"flask", "Flask", 5000},
+}
+
+// Ruby web frameworks, matched on gems of the Gemfile
+var rubyFrameworks = []framework{
+	{"rails", "Rails", 3000},
+	{"sinatra", "Sinatra", 4567},
+}
+
+// Language manifests and what they tell about the service
+var manifests = []struct {
+	path    string
+	analyze func(*Suggestion, []byte)
+}{
+	{"package.json", analyzeNode},
+	{"go.mod", analyzeGo},
+	{"requirements.txt", analyzePython},
+	{"pyproject.toml", analyzePython},
+	{"Gemfile", analyzeRuby},
+}
+
+// First port a Dockerfile exposes
+var exposeRegex = regexp.MustCompile(`(?im)^\s*EXPOSE\s+(\d+)`)
+
+// Package names start a line of requirements.txt, or of the Poetry dependencies of
+// pyproject.toml, before any version specifier
+var requirementRegex = regexp.MustCompile(`^\s*([A-Za-z0-9_.\-\[\]]+)`)
+
+// packageJSON is the part of a package.json the analysis reads
+type packageJSON struct {
+	Main            string            `json:"main"`
+	PackageManager  string            `json:"packageManager"`
+	Scripts         map[string]string `json:"scripts"`
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+// Analyze suggests the configuration of a service built from a repository, from its
+// Dockerfile, Procfile and the manifests of the languages buildpacks detect. dockerfilePath
+// is the path of the Dockerfile, Dockerfile when empty.
+func Analyze(ctx context.Context, files FileReader, dockerfilePath string) (*Suggestion, error) {
+	if dockerfilePath == "" {
+		dockerfilePath = "Dockerfile"
+	}
+
+	s := &Suggestion{
+		ServiceType:   "private",
+		Runtime:       RuntimeBuildpacks,
+		Port:          defaultContainerPort,
+		DetectedFiles: []string{},
+	}
+	read := func(path string) ([]byte, error) {
+		content, err := files.ReadFile(ctx, path)
+		if err == nil && content != nil {
+			s.DetectedFiles = append(s.DetectedFiles, path)
+		}
+		return content, err
+	}
+
+	// The first manifest found decides the language
+	for _, m := range manifests {
+		content, err := read(m.path)
+		if err != nil {
+			return nil, err
+		}
+		if content != nil {
+			m.analyze(s, content)
+			break
+		}
+	}
+
+	// A Procfile names the processes buildpacks start
+	procfile, err := read("Procfile")
+	if err != nil {
+		return nil, err
+	}
+	if procfile != nil {
+		processes := parseProcfile(procfile)
+		if command, ok := processes["web"]; ok {
+			s.ServiceType = "web"
+			s.StartCommand = command
+		} else if command, ok := processes["worker"]; ok {
+			s.ServiceType = "private"
+			s.StartCommand = command
+		}
+	}
+
+	// A Dockerfile is built as it is, its CMD starts the container
+	dockerfile, err := read(dockerfilePath)
+	if err != nil {
+		return nil, err
+	}
+	if dockerfile != nil {
+		s.Runtime = RuntimeDockerfile
+		s.BuildCommand = ""
+		s.StartCommand = ""
+		if m := exposeRegex.FindSubmatch(dockerfile); m != nil {
+			if port, err := strconv.Atoi(string(m[1])); err == nil && port > 0 && port <= 65535 {
+				s.Port = port
+				s.ServiceType = "web"
+			}
+		}
+	}
+
+	if s.ServiceType == "web" {
+		s.HealthCheckPath = "/"
+	}
+
+	return s, nil
+}
+
+// analyzeNode reads the scripts and dependencies of a package.json
+func analyzeNode(s *Suggestion, content []byte) {
+	s.Language = "JavaScript"
+
+	var pkg packageJSON
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return
+	}
+	if _, ok := pkg.DevDependencies["typescript"]; ok {
+		s.Language = "TypeScript"
+	}
+
+	dependencies := make(map[string]bool, len(pkg.Dependencies)+len(pkg.DevDependencies))
+	for name := range pkg.Dependencies {
+		dependencies[name] = true
+	}
+	for name := range pkg.DevDependencies {
+		dependencies[name] = true
+	}
+	for _, f := range nodeFrameworks {
+		if dependencies[f.dependency] {
+			applyFramework(s, f)
+			break
+		}
+	}
+
+	// "pnpm@8.15.0" names the package manager, npm when there is none
+	manager := "npm"
+	if name, _, _ := strings.Cut(pkg.PackageManager, "@"); name == "yarn" || name == "pnpm" {
+		manager = name
+	}
+
+	if _, ok := pkg.Scripts["build"]; ok {
+		s.BuildCommand = manager + " run build"
+	}
+	if _, ok := pkg.Scripts["start"]; ok {
+		s.StartCommand = manager + " start"
+	} else if pkg.Main != "" {
+		s.StartCommand = "node " + pkg.Main
+	}
+}
+
+// analyzeGo reads the required modules of a go.mod
+func analyzeGo(s *Suggestion, content []byte) {
+	s.Language = "Go"
+	s.BuildCommand = "go build -o app ."
+	s.StartCommand = "./app"
+
+	for _, f := range goFrameworks {
+		if bytes.Contains(content, []byte(f.dependency)) {
+			applyFramework(s, f)
+			return
+		}
+	}
+}
+
+// analyzePython reads the packages of a requirements.txt or pyproject.toml
+func analyzePython(s *Suggestion, content []byte) {
+	s.Language = "Python"
+
+	packages := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// PEP 621 dependencies of pyproject.toml are quoted, "fastapi>=0.100",
+		line = strings.TrimLeft(line, `"'`)
+		if m := requirementRegex.FindStringSubmatch(line); m != nil {
+			name, _, _ := strings.Cut(m[1], "[")
+			packages[strings.ToLower(name)] = true
+		}
+	}
+
+	for _, f := range pythonFrameworks {
+		if !packages[f.dependency] {
+			continue
+		}
+		applyFramework(s, f)
+		port := strconv.Itoa(f.port)
+		switch f.dependency {
+		case "django":
+			s.StartCommand = "python manage.py runserver 0.0.0.0:" + port
+		case "fastapi":
+			s.StartCommand = "uvicorn main:app --host 0.0.0.0 --port " + port
+		case "flask":
+			if packages["gunicorn"] {
+				s.StartCommand = "gunicorn --bind 0.0.0.0:" + port + " app:app"
+			} else {
+				s.StartCommand = "flask run --host 0.0.0.0 --port " + port
+			}
+		}
+		return
+	}
+}
+
+// analyzeRuby reads the gems of a Gemfile
+func analyzeRuby(s *Suggestion, content []byte) {
+	s.Language = "Ruby"
+
+	for _, f := range rubyFrameworks {
+		if bytes.Contains(content, []byte(`gem "`+f.dependency+`"`)) ||
+			bytes.Contains(content, []byte(`gem '`+f.dependency+`'`)) {
+			applyFramework(s, f)
+			if f.dependency == "rails" {
+				s.StartCommand = "bundle exec rails server -b 0.0.0.0 -p " + strconv.Itoa(f.port)
+			}
+			return
+		}
+	}
+}
+
+// applyFramework suggests a web service on the default port of a framework
+func applyFramework(s *Suggestion, f framework) {
+	s.Framework = f.name
+	s.Port = f.port
+	s.ServiceType = "web"
+}
+
+// parseProcfile returns the commands of the processes of a Procfile by name
+func parseProcfile(content []byte) map[string]string {
+	processes := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, command, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		processes[strings.TrimSpace(name)] = strings.TrimSpace(command)
+	}
+	return processes
+}
+
+// Service of a template, with the fields of services/template.ServiceTemplate the
+// suggestion sets
+type templateService struct {
+	Name            string         `yaml:"name"`
+	Type            string         `yaml:"type"`
+	Plan            string         `yaml:"plan"`
+	Runtime         string         `yaml:"runtime"`
+	HealthCheckPath string         `yaml:"healthCheckPath,omitempty"`
+	Ports           []templatePort `yaml:"ports,omitempty"`
+	Command         []string       `yaml:"command,omitempty"`
+}
+
+// Port of a template service
+type templatePort struct {
+	ServicePort   int `yaml:"servicePort"`
+	ContainerPort int `yaml:"containerPort"`
+}
+
+// Template returns the template YAML creating the suggested service. The Dockerfile or
+// Procfile starts the container, the suggested start command only when there is neither.
+func (s *Suggestion) Template(name, plan string) (string, error) {
+	service := templateService{
+		Name:            name,
+		Type:            s.ServiceType,
+		Plan:            plan,
+		Runtime:         "docker",
+		HealthCheckPath: s.HealthCheckPath,
+		Ports: []templatePort{{
+			ServicePort:   defaultServicePort,
+			ContainerPort: s.Port,
+		}},
+	}
+	if s.Runtime == RuntimeBuildpacks && !slices.Contains(s.DetectedFiles, "Procfile") && s.StartCommand != "" {
+		service.Command = []string{s.StartCommand}
+	}
+
+	out, err := yaml.Marshal(map[string][]templateService{"services": {service}})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
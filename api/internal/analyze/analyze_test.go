@@ -0,0 +1,240 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// repo is a fixture repository, the contents of its files by path
+type repo map[string]string
+
+// ReadFile implements FileReader
+func (r repo) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	content, ok := r[path]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(content), nil
+}
+
+// failingRepo fails to read any file
+type failingRepo struct{}
+
+var errUnreadable = errors.New("unreadable")
+
+// ReadFile implements FileReader
+func (failingRepo) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	return nil, errUnreadable
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name string
+		repo repo
+		want Suggestion
+	}{
+		{
+			name: "next.js with pnpm",
+			repo: repo{"package.json": `{
+				"packageManager": "pnpm@8.15.0",
+				"scripts": {"build": "next build", "start": "next start"},
+				"dependencies": {"next": "14.1.0", "react": "18.2.0"},
+				"devDependencies": {"typescript": "5.3.3"}
+			}`},
+			want: Suggestion{
+				ServiceType: "web", Runtime: RuntimeBuildpacks, Language: "TypeScript", Framework: "Next.js", Port: 3000,
+				BuildCommand: "pnpm run build", StartCommand: "pnpm start", HealthCheckPath: "/",
+				DetectedFiles: []string{"package.json"},
+			},
+		},
+		{
+			name: "express without a start script",
+			repo: repo{"package.json": `{"main": "server.js", "dependencies": {"express": "4.18.2"}}`},
+			want: Suggestion{
+				ServiceType: "web", Runtime: RuntimeBuildpacks, Language: "JavaScript", Framework: "Express", Port: 3000,
+				StartCommand: "node server.js", HealthCheckPath: "/",
+				DetectedFiles: []string{"package.json"},
+			},
+		},
+		{
+			name: "node without a framework",
+			repo: repo{"package.json": `{"scripts": {"start": "node worker.js"}}`},
+			want: Suggestion{
+				ServiceType: "private", Runtime: RuntimeBuildpacks, Language: "JavaScript", Port: defaultContainerPort,
+				StartCommand:  "npm start",
+				DetectedFiles: []string{"package.json"},
+			},
+		},
+		{
+			name: "go with gin",
+			repo: repo{"go.mod": "module example.com/app\n\ngo 1.22\n\nrequire github.com/gin-gonic/gin v1.9.1\n"},
+			want: Suggestion{
+				ServiceType: "web", Runtime: RuntimeBuildpacks, Language: "Go", Framework: "Gin", Port: 8080,
+				BuildCommand: "go build -o app .", StartCommand: "./app", HealthCheckPath: "/",
+				DetectedFiles: []string{"go.mod"},
+			},
+		},
+		{
+			name: "flask with gunicorn",
+			repo: repo{"requirements.txt": "# web\nFlask==3.0.0\ngunicorn>=21.2\n"},
+			want: Suggestion{
+				ServiceType: "web", Runtime: RuntimeBuildpacks, Language: "Python", Framework: "Flask", Port: 5000,
+				StartCommand: "gunicorn --bind 0.0.0.0:5000 app:app", HealthCheckPath: "/",
+				DetectedFiles: []string{"requirements.txt"},
+			},
+		},
+		{
+			name: "fastapi in pyproject.toml",
+			repo: repo{"pyproject.toml": "[project]\nname = \"api\"\ndependencies = [\n  \"fastapi[all]>=0.100\",\n]\n"},
+			want: Suggestion{
+				ServiceType: "web", Runtime: RuntimeBuildpacks, Language: "Python", Framework: "FastAPI", Port: 8000,
+				StartCommand: "uvicorn main:app --host 0.0.0.0 --port 8000", HealthCheckPath: "/",
+				DetectedFiles: []string{"pyproject.toml"},
+			},
+		},
+		{
+			name: "rails",
+			repo: repo{"Gemfile": "source 'https://rubygems.org'\ngem 'rails', '~> 7.1'\n"},
+			want: Suggestion{
+				ServiceType: "web", Runtime: RuntimeBuildpacks, Language: "Ruby", Framework: "Rails", Port: 3000,
+				StartCommand: "bundle exec rails server -b 0.0.0.0 -p 3000", HealthCheckPath: "/",
+				DetectedFiles: []string{"Gemfile"},
+			},
+		},
+		{
+			name: "procfile worker",
+			repo: repo{
+				"requirements.txt": "celery==5.3.6\n",
+				"Procfile":         "# background jobs\nworker: celery -A tasks worker\n",
+			},
+			want: Suggestion{
+				ServiceType: "private", Runtime: RuntimeBuildpacks, Language: "Python", Port: defaultContainerPort,
+				StartCommand:  "celery -A tasks worker",
+				DetectedFiles: []string{"requirements.txt", "Procfile"},
+			},
+		},
+		{
+			name: "procfile web",
+			repo: repo{"Procfile": "web: ./bin/server\nworker: ./bin/jobs\n"},
+			want: Suggestion{
+				ServiceType: "web", Runtime: RuntimeBuildpacks, Port: defaultContainerPort,
+				StartCommand: "./bin/server", HealthCheckPath: "/",
+				DetectedFiles: []string{"Procfile"},
+			},
+		},
+		{
+			// The Dockerfile builds and starts the container, its port wins over the framework's
+			name: "dockerfile",
+			repo: repo{
+				"package.json": `{"scripts": {"build": "tsc", "start": "node dist"}, "dependencies": {"express": "4.18.2"}}`,
+				"Dockerfile":   "FROM node:20\nCOPY . .\n  expose 4000\nCMD [\"node\", \"dist\"]\n",
+			},
+			want: Suggestion{
+				ServiceType: "web", Runtime: RuntimeDockerfile, Language: "JavaScript", Framework: "Express", Port: 4000,
+				HealthCheckPath: "/",
+				DetectedFiles:   []string{"package.json", "Dockerfile"},
+			},
+		},
+		{
+			name: "empty repository",
+			repo: repo{},
+			want: Suggestion{
+				ServiceType: "private", Runtime: RuntimeBuildpacks, Port: defaultContainerPort,
+				DetectedFiles: []string{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Analyze(context.Background(), tt.repo, "")
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("suggestion = %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeDockerfilePath(t *testing.T) {
+	files := repo{"Dockerfile": "FROM scratch\n", "docker/api.Dockerfile": "FROM golang:1.22\nEXPOSE 9000\n"}
+
+	got, err := Analyze(context.Background(), files, "docker/api.Dockerfile")
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if got.Runtime != RuntimeDockerfile || got.Port != 9000 || !reflect.DeepEqual(got.DetectedFiles, []string{"docker/api.Dockerfile"}) {
+		t.Errorf("suggestion = %+v, want the Dockerfile at the given path", *got)
+	}
+}
+
+func TestAnalyzeReadError(t *testing.T) {
+	if _, err := Analyze(context.Background(), failingRepo{}, ""); !errors.Is(err, errUnreadable) {
+		t.Errorf("Analyze error = %v, want %v", err, errUnreadable)
+	}
+}
+
+func TestSuggestionTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		repo    repo
+		command []interface{}
+	}{
+		{
+			name:    "start command",
+			repo:    repo{"go.mod": "module example.com/app\n\nrequire github.com/go-chi/chi/v5 v5.0.11\n"},
+			command: []interface{}{"./app"},
+		},
+		// The Procfile and the Dockerfile start the container themselves
+		{
+			name: "procfile",
+			repo: repo{"go.mod": "module example.com/app\n", "Procfile": "web: ./app --serve\n"},
+		},
+		{
+			name: "dockerfile",
+			repo: repo{"go.mod": "module example.com/app\n", "Dockerfile": "FROM golang\nEXPOSE 8080\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestion, err := Analyze(context.Background(), tt.repo, "")
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			out, err := suggestion.Template("app", "starter")
+			if err != nil {
+				t.Fatalf("Template: %v", err)
+			}
+
+			var template struct {
+				Services []map[string]interface{} `yaml:"services"`
+			}
+			if err := yaml.Unmarshal([]byte(out), &template); err != nil {
+				t.Fatalf("template %s: %v", out, err)
+			}
+			if len(template.Services) != 1 {
+				t.Fatalf("template %s, want one service", out)
+			}
+			service := template.Services[0]
+			if service["name"] != "app" || service["type"] != "web" || service["plan"] != "starter" || service["runtime"] != "docker" {
+				t.Errorf("service = %v, want the web service app on the starter plan", service)
+			}
+			if service["healthCheckPath"] != "/" {
+				t.Errorf("healthCheckPath = %v, want /", service["healthCheckPath"])
+			}
+			want := []interface{}{map[string]interface{}{"servicePort": defaultServicePort, "containerPort": 8080}}
+			if !reflect.DeepEqual(service["ports"], want) {
+				t.Errorf("ports = %v, want %v", service["ports"], want)
+			}
+			command, _ := service["command"].([]interface{})
+			if !reflect.DeepEqual(command, tt.command) {
+				t.Errorf("command = %v, want %v", service["command"], tt.command)
+			}
+		})
+	}
+}
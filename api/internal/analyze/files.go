@@ -0,0 +1,46 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/deployra/deployra/api/pkg/github"
+)
+
+// GitHubFiles reads the files of a GitHub repository at a branch, tag or commit
+type GitHubFiles struct {
+	Client *github.Client
+	Owner  string
+	Repo   string
+	Ref    string
+}
+
+// ReadFile implements FileReader
+func (f GitHubFiles) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	content, err := f.Client.GetFileContent(ctx, f.Owner, f.Repo, f.Ref, path)
+	if errors.Is(err, github.ErrFileNotFound) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// DirFiles reads the files of a repository cloned to a directory
+type DirFiles string
+
+// ReadFile implements FileReader
+func (d DirFiles) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	root, err := os.OpenRoot(string(d))
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	content, err := root.ReadFile(filepath.FromSlash(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return content, err
+}
@@ -0,0 +1,28 @@
+package analyze
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "docker"), 0o755)
+	os.WriteFile(filepath.Join(dir, "docker", "api.Dockerfile"), []byte("FROM scratch\n"), 0o644)
+	files := DirFiles(dir)
+
+	content, err := files.ReadFile(context.Background(), "docker/api.Dockerfile")
+	if err != nil || string(content) != "FROM scratch\n" {
+		t.Errorf("ReadFile = %q, %v, want the Dockerfile", content, err)
+	}
+	// A missing file is no error
+	if content, err := files.ReadFile(context.Background(), "Procfile"); content != nil || err != nil {
+		t.Errorf("ReadFile of a missing file = %q, %v, want nothing", content, err)
+	}
+	// Paths can't leave the clone
+	if _, err := files.ReadFile(context.Background(), "../"+filepath.Base(dir)+"/docker/api.Dockerfile"); err == nil {
+		t.Error("read a file outside of the repository")
+	}
+}
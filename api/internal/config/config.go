@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/joho/godotenv"
@@ -30,6 +32,9 @@ type Config struct {
 	GitHubAppPrivateKey string
 	GitHubWebhookSecret string
 
+	// Window in seconds during which rapid pushes to the same branch are coalesced into one build
+	GitHubPushDebounce int
+
 	// AWS
 	AWSRegion          string
 	AWSAccessKeyID     string
@@ -45,10 +50,70 @@ type Config struct {
 	WebhookApiKey string
 
 	// CORS
-	CorsOrigins string
+	CorsOrigins        string
+	CorsOriginPatterns string // Comma-separated subdomain wildcards (https://*.example.com) or regexes
+	CorsCredentials    bool
+
+	// Time in seconds to wait for in-flight requests to finish on shutdown
+	ShutdownTimeout int
+
+	// Time in hours an Idempotency-Key response is replayed for
+	IdempotencyTTL int
+
+	// Rate limits in requests per minute per user (or IP for public routes), 0 disables
+	RateLimitPerMinute int
+	DeployRateLimit    int
+
+	// Builds an organization may run at once, 0 disables the limit. Organizations can override it.
+	DeployConcurrencyLimit int
+
+	// Days deployment logs are kept for, and the size in KB the logs of one deployment may
+	// reach before the oldest lines are dropped. 0 disables either.
+	DeploymentLogRetentionDays int
+	DeploymentLogMaxKB         int
+
+	// Seconds after a service starts or finishes scaling during which replica reports don't
+	// start a new scale, so an oscillating autoscaler doesn't flood the events. 0 disables.
+	ScalingCooldownSeconds int
+
+	// Minutes a deployment may stay BUILDING or DEPLOYING before it is failed, 0 disables.
+	// Instance types can override it.
+	DeploymentTimeoutMinutes int
+
+	// Size in MB of a build context above which deployment pre-flight checks warn, 0 disables
+	BuildContextWarnMB int
+
+	// Hours after deleting a service during which it can be restored, the reaper deletes it after
+	ServiceRestoreWindowHours int
 
 	// App Domain (for subdomain suffix, e.g., "example.com" results in "*.example.com")
 	AppDomain string
+
+	// External TCP ports the ingress proxy assigns to private services, and the host they are reached on
+	IngressPortMin int
+	IngressPortMax int
+	IngressHost    string
+
+	// Host database and memory services are reached on through their proxies (defaults to AppDomain)
+	DBProxyHost string
+
+	// Allows users to open a shell in their service pods over WebSocket
+	PodExecEnabled bool
+
+	// Largest storage capacity in GB a service may have when its instance type sets no limit
+	MaxStorageCapacity int
+
+	// Environment variables a service may have, and the size in KB of their keys and values
+	// together. They end up in one Kubernetes secret and the container env. 0 disables either.
+	EnvVarsMaxCount int
+	EnvVarsMaxKB    int
+
+	// How web service subdomains are generated: "random" (name plus random suffix) or "name"
+	SubdomainStrategy string
+
+	// Regions services can run in, each a Kubernetes cluster. The first is the default, empty
+	// runs every service in the one cluster.
+	Regions []string
 }
 
 func Load() *Config {
@@ -56,33 +121,80 @@ func Load() *Config {
 		_ = godotenv.Load()
 
 		instance = &Config{
-			Port:                getEnv("PORT", "8080"),
-			DatabaseURL:         getEnv("DATABASE_URL", ""),
-			JWTSecret:           getEnv("JWT_SECRET", ""),
-			AppURL:              getEnv("APP_URL", "http://localhost:3000"),
-			ApiURL:              getEnv("API_URL", "http://localhost:8080/api"),
-			EncryptionKey:       getEnv("ENCRYPTION_KEY", ""),
-			GitHubClientID:      getEnv("GITHUB_CLIENT_ID", ""),
-			GitHubClientSecret:  getEnv("GITHUB_CLIENT_SECRET", ""),
-			GitHubAppID:         getEnv("GITHUB_APP_ID", ""),
-			GitHubAppName:       getEnv("GITHUB_APP_NAME", ""),
-			GitHubAppPrivateKey: getEnv("GITHUB_APP_PRIVATE_KEY", ""),
-			GitHubWebhookSecret: getEnv("GITHUB_WEBHOOK_SECRET", ""),
-			AWSRegion:           getEnv("AWS_REGION", "eu-west-1"),
-			AWSAccessKeyID:      getEnv("AWS_ACCESS_KEY_ID", ""),
-			AWSSecretAccessKey:  getEnv("AWS_SECRET_ACCESS_KEY", ""),
-			RedisHost:           getEnv("REDIS_HOST", "localhost"),
-			RedisPort:           getEnv("REDIS_PORT", "6379"),
-			RedisUsername:       getEnv("REDIS_USERNAME", ""),
-			RedisPassword:       getEnv("REDIS_PASSWORD", ""),
-			WebhookApiKey:       getEnv("WEBHOOK_API_KEY", ""),
-			CorsOrigins:         getEnv("CORS_ORIGINS", "http://localhost:3000,http://127.0.0.1:3000"),
-			AppDomain:           getEnv("APP_DOMAIN", ""),
+			Port:                   getEnv("PORT", "8080"),
+			DatabaseURL:            getEnv("DATABASE_URL", ""),
+			JWTSecret:              getEnv("JWT_SECRET", ""),
+			AppURL:                 getEnv("APP_URL", "http://localhost:3000"),
+			ApiURL:                 getEnv("API_URL", "http://localhost:8080/api"),
+			EncryptionKey:          getEnv("ENCRYPTION_KEY", ""),
+			GitHubClientID:         getEnv("GITHUB_CLIENT_ID", ""),
+			GitHubClientSecret:     getEnv("GITHUB_CLIENT_SECRET", ""),
+			GitHubAppID:            getEnv("GITHUB_APP_ID", ""),
+			GitHubAppName:          getEnv("GITHUB_APP_NAME", ""),
+			GitHubAppPrivateKey:    getEnv("GITHUB_APP_PRIVATE_KEY", ""),
+			GitHubWebhookSecret:    getEnv("GITHUB_WEBHOOK_SECRET", ""),
+			GitHubPushDebounce:     getEnvInt("GITHUB_PUSH_DEBOUNCE_SECONDS", 10),
+			AWSRegion:              getEnv("AWS_REGION", "eu-west-1"),
+			AWSAccessKeyID:         getEnv("AWS_ACCESS_KEY_ID", ""),
+			AWSSecretAccessKey:     getEnv("AWS_SECRET_ACCESS_KEY", ""),
+			RedisHost:              getEnv("REDIS_HOST", "localhost"),
+			RedisPort:              getEnv("REDIS_PORT", "6379"),
+			RedisUsername:          getEnv("REDIS_USERNAME", ""),
+			RedisPassword:          getEnv("REDIS_PASSWORD", ""),
+			WebhookApiKey:          getEnv("WEBHOOK_API_KEY", ""),
+			CorsOrigins:            getEnv("CORS_ORIGINS", "http://localhost:3000,http://127.0.0.1:3000"),
+			CorsOriginPatterns:     getEnv("CORS_ORIGIN_PATTERNS", ""),
+			CorsCredentials:        getEnv("CORS_ALLOW_CREDENTIALS", "true") == "true",
+			ShutdownTimeout:        getEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 30),
+			IdempotencyTTL:         getEnvInt("IDEMPOTENCY_KEY_TTL_HOURS", 24),
+			RateLimitPerMinute:     getEnvInt("RATE_LIMIT_PER_MINUTE", 300),
+			DeployRateLimit:        getEnvInt("DEPLOY_RATE_LIMIT_PER_MINUTE", 10),
+			DeployConcurrencyLimit: getEnvInt("DEPLOY_CONCURRENCY_PER_ORGANIZATION", 5),
+			AppDomain:              getEnv("APP_DOMAIN", ""),
+			IngressPortMin:         getEnvInt("INGRESS_PORT_RANGE_START", 20000),
+			IngressPortMax:         getEnvInt("INGRESS_PORT_RANGE_END", 20999),
+			IngressHost:            getEnv("INGRESS_HOST", ""),
+			DBProxyHost:            getEnv("DB_PROXY_HOST", ""),
+			PodExecEnabled:         getEnv("POD_EXEC_ENABLED", "false") == "true",
+			MaxStorageCapacity:     getEnvInt("MAX_STORAGE_CAPACITY_GB", 100),
+			SubdomainStrategy:      getEnv("SUBDOMAIN_STRATEGY", "random"),
+
+			// Deployment logs
+			DeploymentLogRetentionDays: getEnvInt("DEPLOYMENT_LOG_RETENTION_DAYS", 30),
+			DeploymentLogMaxKB:         getEnvInt("DEPLOYMENT_LOG_MAX_KB", 5120),
+
+			// Scaling
+			ScalingCooldownSeconds: getEnvInt("SCALING_COOLDOWN_SECONDS", 60),
+
+			// Deployment timeout
+			DeploymentTimeoutMinutes: getEnvInt("DEPLOYMENT_TIMEOUT_MINUTES", 30),
+
+			// Build context size warning
+			BuildContextWarnMB: getEnvInt("BUILD_CONTEXT_WARN_MB", 100),
+
+			// Deleted service restore window
+			ServiceRestoreWindowHours: getEnvInt("SERVICE_RESTORE_WINDOW_HOURS", 72),
+
+			// Environment variable limits
+			EnvVarsMaxCount: getEnvInt("ENV_VARS_MAX_COUNT", 500),
+			EnvVarsMaxKB:    getEnvInt("ENV_VARS_MAX_KB", 512),
+
+			// Regions
+			Regions: getEnvList("REGIONS"),
 		}
 	})
 	return instance
 }
 
+// DefaultRegion returns the region services run in unless they or their organization pick
+// another, empty when no regions are configured
+func (c *Config) DefaultRegion() string {
+	if len(c.Regions) == 0 {
+		return ""
+	}
+	return c.Regions[0]
+}
+
 // Get returns the loaded config instance
 func Get() *Config {
 	return instance
@@ -94,3 +206,23 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList returns the non-empty items of a comma separated variable
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,254 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// The runtime image has no zoneinfo
+	_ "time/tzdata"
+)
+
+// DefaultTimezone is the timezone of cronjobs that set none
+const DefaultTimezone = "UTC"
+
+// How far ahead Next looks for a matching time, a schedule like "0 0 31 2 *" never matches
+const searchYears = 5
+
+// A field of a cron expression, the values it takes and the names it accepts for them
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondField = field{min: 0, max: 59}
+	minuteField = field{min: 0, max: 59}
+	hourField   = field{min: 0, max: 23}
+	domField    = field{min: 1, max: 31}
+	monthField  = field{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+		"january": 1, "february": 2, "march": 3, "april": 4, "june": 6, "july": 7,
+		"august": 8, "september": 9, "october": 10, "november": 11, "december": 12,
+	}}
+	// Both 0 and 7 are Sunday
+	dowField = field{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+		"sunday": 0, "monday": 1, "tuesday": 2, "wednesday": 3, "thursday": 4, "friday": 5, "saturday": 6,
+	}}
+)
+
+// The fields of a six field expression, a five field one has no seconds
+var fields = [6]field{secondField, minuteField, hourField, domField, monthField, dowField}
+
+// Shortcuts for common schedules
+var shortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// Whether the day fields are "*". When both are restricted a day matches either of them,
+	// like in crontab
+	domAny, dowAny bool
+}
+
+// Parse parses a cron expression of five space separated fields: minute, hour, day of month,
+// month and day of week, optionally preceded by a sixth field of seconds like node-cron
+// accepts. A field is "*", a value, a range "a-b" or a comma separated list of them, each
+// optionally followed by a step "/n". Months and days of week can be given by name, JAN or
+// january, MON or monday, and 7 is Sunday too. Shortcuts like @daily stand for their
+// expression.
+func Parse(expression string) (Schedule, error) {
+	if shortcut, ok := shortcuts[strings.ToLower(strings.TrimSpace(expression))]; ok {
+		expression = shortcut
+	}
+	parts := strings.Fields(expression)
+	// Field numbers in errors are those of the expression
+	offset := 1
+	switch len(parts) {
+	case 5:
+		// Without seconds a schedule runs at the start of its minutes
+		parts = append([]string{"0"}, parts...)
+		offset = 0
+	case 6:
+	default:
+		return Schedule{}, fmt.Errorf("expected 5 or 6 fields, got %d", len(parts))
+	}
+
+	var bits [6]uint64
+	for i, part := range parts {
+		b, err := parseField(part, fields[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("field %d: %w", i+offset, err)
+		}
+		bits[i] = b
+	}
+
+	// 7 is Sunday
+	dow := bits[5]
+	if dow&(1<<7) != 0 {
+		dow = dow&^(1<<7) | 1
+	}
+
+	return Schedule{
+		second: bits[0],
+		minute: bits[1],
+		hour:   bits[2],
+		dom:    bits[3],
+		month:  bits[4],
+		dow:    dow,
+		domAny: parts[3] == "*",
+		dowAny: parts[5] == "*",
+	}, nil
+}
+
+// parseField returns the values a field matches as bits
+func parseField(part string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(part, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		start, end := f.min, f.max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			n, err := f.value(from)
+			if err != nil {
+				return 0, err
+			}
+			start, end = n, n
+			if isRange {
+				if end, err = f.value(to); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// "a/n" runs from a to the end of the field
+				end = f.max
+			}
+		}
+		if start < f.min || end > f.max || start > end {
+			return 0, fmt.Errorf("%q out of range %d-%d", rangePart, f.min, f.max)
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a number or a name of the field
+func (f field) value(s string) (int, error) {
+	if n, ok := f.names[strings.ToLower(s)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return n, nil
+}
+
+// Next returns the first time after t the schedule matches, in the location of t, or the zero
+// time if it matches none in the next years
+func (s Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Second).Add(time.Second)
+	limit := t.Year() + searchYears
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		// Seconds, minutes and hours advance in absolute time, wall clock hours repeat when
+		// DST ends
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Add(time.Duration(60-t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute - time.Duration(t.Second())*time.Second)
+			continue
+		}
+		if s.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day of month and day of week fields
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// NextRuns returns the next n times after t the schedule matches, in loc
+func (s Schedule) NextRuns(t time.Time, loc *time.Location, n int) []time.Time {
+	runs := make([]time.Time, 0, n)
+	t = t.In(loc)
+	for len(runs) < n {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+// LoadLocation returns the location of a cronjob timezone, UTC when empty
+func LoadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		timezone = DefaultTimezone
+	}
+	return time.LoadLocation(timezone)
+}
+
+// NextRun returns the first run of a cronjob schedule after t, nil if the schedule or timezone
+// is invalid or the schedule never matches
+func NextRun(expression, timezone string, t time.Time) *time.Time {
+	schedule, err := Parse(expression)
+	if err != nil {
+		return nil
+	}
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return nil
+	}
+	runs := schedule.NextRuns(t, loc, 1)
+	if len(runs) == 0 {
+		return nil
+	}
+	return &runs[0]
+}
@@ -0,0 +1,199 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2024, time.May, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		schedule string
+		want     time.Time
+	}{
+		{schedule: "* * * * *", want: time.Date(2024, time.May, 15, 10, 31, 0, 0, time.UTC)},
+		{schedule: "*/15 * * * *", want: time.Date(2024, time.May, 15, 10, 45, 0, 0, time.UTC)},
+		{schedule: "0 9 * * *", want: time.Date(2024, time.May, 16, 9, 0, 0, 0, time.UTC)},
+		{schedule: "0 9-17/4 * * *", want: time.Date(2024, time.May, 15, 13, 0, 0, 0, time.UTC)},
+		{schedule: "0 0 1 * *", want: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{schedule: "30 4 29 2 *", want: time.Date(2028, time.February, 29, 4, 30, 0, 0, time.UTC)},
+		// With both day fields restricted either of them matches
+		{schedule: "0 0 20 * 5", want: time.Date(2024, time.May, 17, 0, 0, 0, 0, time.UTC)},
+		{schedule: "0 8 * * MON-FRI", want: time.Date(2024, time.May, 16, 8, 0, 0, 0, time.UTC)},
+		{schedule: "0 8 * * sat,SUN", want: time.Date(2024, time.May, 18, 8, 0, 0, 0, time.UTC)},
+		{schedule: "0 8 * * 7", want: time.Date(2024, time.May, 19, 8, 0, 0, 0, time.UTC)},
+		{schedule: "0 8 * * 5-7", want: time.Date(2024, time.May, 17, 8, 0, 0, 0, time.UTC)},
+		{schedule: "0 0 1 JAN,jul *", want: time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{schedule: "0 0 2 december monday", want: time.Date(2024, time.December, 2, 0, 0, 0, 0, time.UTC)},
+		// Six fields start with seconds
+		{schedule: "*/20 * * * * *", want: time.Date(2024, time.May, 15, 10, 30, 20, 0, time.UTC)},
+		{schedule: "30 0 12 * * *", want: time.Date(2024, time.May, 15, 12, 0, 30, 0, time.UTC)},
+		{schedule: "0 31 10 * * *", want: time.Date(2024, time.May, 15, 10, 31, 0, 0, time.UTC)},
+		{schedule: "@hourly", want: time.Date(2024, time.May, 15, 11, 0, 0, 0, time.UTC)},
+		{schedule: "@weekly", want: time.Date(2024, time.May, 19, 0, 0, 0, 0, time.UTC)},
+		{schedule: "@yearly", want: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			schedule, err := Parse(tt.schedule)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextNeverMatches(t *testing.T) {
+	schedule, err := Parse("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := schedule.Next(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next = %v, want the zero time", got)
+	}
+}
+
+func TestParseRejectsInvalidSchedule(t *testing.T) {
+	tests := []struct {
+		schedule string
+		err      string
+	}{
+		{schedule: "* * * *", err: "expected 5 or 6 fields, got 4"},
+		{schedule: "* * * * * * *", err: "expected 5 or 6 fields, got 7"},
+		{schedule: "60 * * * *", err: "field 1"},
+		{schedule: "* 24 * * *", err: "field 2"},
+		{schedule: "* * 0 * *", err: "field 3"},
+		{schedule: "* * * 13 *", err: "field 4"},
+		{schedule: "* * * * 8", err: "field 5"},
+		{schedule: "60 * * * * *", err: "field 1"},
+		{schedule: "* * * * * 8", err: "field 6"},
+		{schedule: "* * * * FRI-MON", err: "out of range"},
+		{schedule: "* * * * MONDAYS", err: "invalid value"},
+		{schedule: "*/0 * * * *", err: "invalid step"},
+		{schedule: "@sometimes", err: "expected 5 or 6 fields, got 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			_, err := Parse(tt.schedule)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Parse error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestNextRunsInTimezone(t *testing.T) {
+	tokyo := mustLoad(t, "Asia/Tokyo")
+	schedule, err := Parse("0 9 * * *")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	// 01:00 UTC is 10:00 in Tokyo, the 9:00 run of the day passed
+	runs := schedule.NextRuns(time.Date(2024, time.May, 15, 1, 0, 0, 0, time.UTC), tokyo, 2)
+	want := []time.Time{
+		time.Date(2024, time.May, 16, 9, 0, 0, 0, tokyo),
+		time.Date(2024, time.May, 17, 9, 0, 0, 0, tokyo),
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("runs = %v, want %v", runs, want)
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) || runs[i].Location() != tokyo {
+			t.Errorf("run %d = %v, want %v", i, runs[i], want[i])
+		}
+	}
+}
+
+func TestNextRunsAcrossDST(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name     string
+		schedule string
+		from     time.Time
+		want     []time.Time
+	}{
+		{
+			// 02:30 doesn't exist on the day clocks go forward, that run is skipped
+			name:     "clocks forward",
+			schedule: "30 2 * * *",
+			from:     time.Date(2024, time.March, 9, 12, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2024, time.March, 11, 2, 30, 0, 0, newYork),
+				time.Date(2024, time.March, 12, 2, 30, 0, 0, newYork),
+			},
+		},
+		{
+			// 01:30 happens twice on the day clocks go back, it runs at both
+			name:     "clocks back",
+			schedule: "30 1 * * *",
+			from:     time.Date(2024, time.November, 3, 0, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2024, time.November, 3, 5, 30, 0, 0, time.UTC),
+				time.Date(2024, time.November, 3, 6, 30, 0, 0, time.UTC),
+				time.Date(2024, time.November, 4, 1, 30, 0, 0, newYork),
+			},
+		},
+		{
+			name:     "hourly across clocks forward",
+			schedule: "0 * * * *",
+			from:     time.Date(2024, time.March, 10, 0, 30, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2024, time.March, 10, 1, 0, 0, 0, newYork),
+				time.Date(2024, time.March, 10, 3, 0, 0, 0, newYork),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.schedule)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			runs := schedule.NextRuns(tt.from, newYork, len(tt.want))
+			if len(runs) != len(tt.want) {
+				t.Fatalf("runs = %v, want %v", runs, tt.want)
+			}
+			for i := range tt.want {
+				if !runs[i].Equal(tt.want[i]) {
+					t.Errorf("run %d = %v, want %v", i, runs[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	from := time.Date(2024, time.May, 15, 10, 30, 0, 0, time.UTC)
+
+	next := NextRun("0 12 * * *", "Europe/Berlin", from)
+	// 12:00 in Berlin is 10:00 UTC in summer, the next run is tomorrow's
+	if want := time.Date(2024, time.May, 16, 10, 0, 0, 0, time.UTC); next == nil || !next.Equal(want) {
+		t.Errorf("NextRun = %v, want %v", next, want)
+	}
+	if next := NextRun("0 12 * * *", "", from); next == nil || !next.Equal(time.Date(2024, time.May, 15, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("NextRun without a timezone = %v, want 12:00 UTC", next)
+	}
+	if next := NextRun("0 12 * *", "UTC", from); next != nil {
+		t.Errorf("NextRun of an invalid schedule = %v, want nil", next)
+	}
+	if next := NextRun("0 12 * * *", "Mars/Olympus", from); next != nil {
+		t.Errorf("NextRun in an unknown timezone = %v, want nil", next)
+	}
+}
@@ -107,36 +107,48 @@ func Decrypt(ciphertext string) (string, error) {
 	return string(plaintext), nil
 }
 
-// EncryptEnvVars encrypts a slice of environment variables
+// DecryptWithFallback decrypts a single value, returning it unchanged if it is not encrypted
+// (for backward compatibility with values stored before encryption was enabled)
+func DecryptWithFallback(value string) string {
+	decrypted, err := Decrypt(value)
+	if err != nil {
+		return value
+	}
+	return decrypted
+}
+
+// EncryptEnvVars encrypts the secret values of a slice of environment variables, plain config
+// values are stored as they are
 func EncryptEnvVars(envVars []EnvironmentVariable) ([]EnvironmentVariable, error) {
 	encrypted := make([]EnvironmentVariable, len(envVars))
 	for i, v := range envVars {
+		encrypted[i] = v
+		if !v.Secret() {
+			continue
+		}
 		encryptedValue, err := Encrypt(v.Value)
 		if err != nil {
 			return nil, err
 		}
-		encrypted[i] = EnvironmentVariable{
-			Key:   v.Key,
-			Value: encryptedValue,
-		}
+		encrypted[i].Value = encryptedValue
 	}
 	return encrypted, nil
 }
 
-// DecryptEnvVars decrypts a slice of environment variables
+// DecryptEnvVars decrypts the secret values of a slice of environment variables
 func DecryptEnvVars(envVars []EnvironmentVariable) ([]EnvironmentVariable, error) {
 	decrypted := make([]EnvironmentVariable, len(envVars))
 	for i, v := range envVars {
+		decrypted[i] = v
+		if !v.Secret() {
+			continue
+		}
 		decryptedValue, err := Decrypt(v.Value)
 		if err != nil {
 			// If decryption fails, return plaintext (for backward compatibility with existing data)
-			decrypted[i] = v
 			continue
 		}
-		decrypted[i] = EnvironmentVariable{
-			Key:   v.Key,
-			Value: decryptedValue,
-		}
+		decrypted[i].Value = decryptedValue
 	}
 	return decrypted, nil
 }
@@ -145,6 +157,15 @@ func DecryptEnvVars(envVars []EnvironmentVariable) ([]EnvironmentVariable, error
 type EnvironmentVariable struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
+
+	// IsSecret is false for plain config, which is stored unencrypted and shown unmasked.
+	// Variables stored before the flag existed have none and are secrets.
+	IsSecret *bool `json:"isSecret,omitempty"`
+}
+
+// Secret reports whether the value of an environment variable is a secret
+func (v EnvironmentVariable) Secret() bool {
+	return v.IsSecret == nil || *v.IsSecret
 }
 
 // EncryptHeaders encrypts header values in a map
@@ -180,3 +201,32 @@ func DecryptHeaders(headers map[string]string) (map[string]string, error) {
 	}
 	return decrypted, nil
 }
+
+// EncryptOptional encrypts a value that may not be set, nil and empty values are kept as-is
+func EncryptOptional(value *string) (*string, error) {
+	if value == nil || *value == "" {
+		return value, nil
+	}
+	encrypted, err := Encrypt(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &encrypted, nil
+}
+
+// DecryptOptional decrypts a value that may not be set, returning it unchanged if it is
+// not encrypted
+func DecryptOptional(value *string) *string {
+	if value == nil || *value == "" {
+		return value
+	}
+	decrypted := DecryptWithFallback(*value)
+	return &decrypted
+}
+
+// IsEncrypted reports whether a stored value is encrypted with the current key. Values are
+// always considered encrypted when encryption is disabled, there is nothing to migrate then.
+func IsEncrypted(value string) bool {
+	_, err := Decrypt(value)
+	return err == nil
+}
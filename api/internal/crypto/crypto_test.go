@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"os"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("ENCRYPTION_KEY", "0123456789abcdef0123456789abcdef")
+	config.Load()
+	if err := Initialize(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestEncryptOptionalRoundTrip(t *testing.T) {
+	value := "registry-password"
+	encrypted, err := EncryptOptional(&value)
+	if err != nil {
+		t.Fatalf("EncryptOptional: %v", err)
+	}
+	if encrypted == nil || *encrypted == value {
+		t.Fatalf("EncryptOptional = %v, want the value encrypted", encrypted)
+	}
+	if !IsEncrypted(*encrypted) {
+		t.Error("an encrypted value is not reported as encrypted")
+	}
+	if decrypted := DecryptOptional(encrypted); decrypted == nil || *decrypted != value {
+		t.Errorf("DecryptOptional = %v, want %q", decrypted, value)
+	}
+}
+
+func TestOptionalValuesNotSet(t *testing.T) {
+	empty := ""
+	for _, value := range []*string{nil, &empty} {
+		if encrypted, err := EncryptOptional(value); err != nil || encrypted != value {
+			t.Errorf("EncryptOptional(%v) = %v, %v, want it kept as-is", value, encrypted, err)
+		}
+		if decrypted := DecryptOptional(value); decrypted != value {
+			t.Errorf("DecryptOptional(%v) = %v, want it kept as-is", value, decrypted)
+		}
+	}
+}
+
+func TestPlaintextValuesAreKept(t *testing.T) {
+	// Values stored before they were encrypted read back unchanged
+	plaintext := "deployer"
+	if IsEncrypted(plaintext) {
+		t.Error("a plaintext value is reported as encrypted")
+	}
+	if decrypted := DecryptOptional(&plaintext); decrypted == nil || *decrypted != plaintext {
+		t.Errorf("DecryptOptional = %v, want the plaintext %q", decrypted, plaintext)
+	}
+}
@@ -0,0 +1,47 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// deployTokenPrefix makes deploy tokens recognisable in CI secrets and logs
+const deployTokenPrefix = "ddt_"
+
+// tunnelTokenPrefix makes tunnel tokens recognisable, they don't deploy anything
+const tunnelTokenPrefix = "dtt_"
+
+// GenerateDeployToken generates a random token external CI uses to deploy a service
+func GenerateDeployToken() (string, error) {
+	return generateToken(deployTokenPrefix)
+}
+
+// GenerateTunnelToken generates a random token clients open tunnels to a service with. It is
+// stored hashed with HashDeployToken, which is the hash the web proxy checks.
+func GenerateTunnelToken() (string, error) {
+	return generateToken(tunnelTokenPrefix)
+}
+
+func generateToken(prefix string) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
+
+// HashDeployToken returns the hash of a deploy token as it is stored
+func HashDeployToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyDeployToken checks a token against a stored hash in constant time
+func VerifyDeployToken(token, hash string) bool {
+	if token == "" || hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(HashDeployToken(token)), []byte(hash)) == 1
+}
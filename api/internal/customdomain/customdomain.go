@@ -0,0 +1,160 @@
+package customdomain
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/models"
+	"golang.org/x/net/idna"
+	"gorm.io/gorm"
+)
+
+// RFC 1123 label, lowercase only
+var labelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ErrTaken is returned when another service already uses the domain
+var ErrTaken = errors.New("Custom domain is already used by another service")
+
+// ErrNotPointed is returned when the DNS of a custom domain doesn't lead to the platform
+var ErrNotPointed = errors.New("Custom domain does not point to the platform yet")
+
+// DNS lookups of Verify, replaced in tests
+var (
+	lookupCNAME = net.LookupCNAME
+	lookupIP    = net.LookupIP
+)
+
+// Normalize turns what users paste, like "https://WWW.Example.com/", into the host the
+// proxy matches against SNI, "www.example.com". Internationalized domains are converted to
+// punycode. Wildcards, IP addresses and domains of the platform itself are rejected.
+func Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("Custom domain is required")
+	}
+	if strings.Contains(raw, "*") {
+		return "", errors.New("Wildcard custom domains are not supported")
+	}
+
+	// Parse as a URL so schemes, ports, paths and queries can be dropped
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" || u.User != nil {
+		return "", fmt.Errorf("Invalid custom domain %q", strings.TrimPrefix(raw, "http://"))
+	}
+	host := strings.TrimSuffix(u.Hostname(), ".")
+
+	if net.ParseIP(host) != nil {
+		return "", errors.New("Custom domain must be a domain name, not an IP address")
+	}
+
+	domain, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("Invalid custom domain %q", host)
+	}
+	domain = strings.ToLower(domain)
+
+	if err := Validate(domain); err != nil {
+		return "", err
+	}
+	return domain, nil
+}
+
+// Validate checks that a normalized domain is a fully qualified domain name outside the
+// platform's own domain
+func Validate(domain string) error {
+	if len(domain) > 253 {
+		return errors.New("Custom domain must be at most 253 characters")
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return errors.New("Custom domain must be a fully qualified domain name, like app.example.com")
+	}
+	for _, label := range labels {
+		if !labelPattern.MatchString(label) {
+			return fmt.Errorf("Invalid custom domain %q", domain)
+		}
+	}
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return fmt.Errorf("Invalid custom domain %q", domain)
+	}
+
+	if appDomain := strings.ToLower(config.Get().AppDomain); appDomain != "" {
+		if domain == appDomain || strings.HasSuffix(domain, "."+appDomain) {
+			return fmt.Errorf("Domains under %s can't be used as custom domains, set a subdomain instead", appDomain)
+		}
+	}
+
+	return nil
+}
+
+// WwwVariant returns the other form of a domain, "www.example.com" for "example.com" and
+// the other way around
+func WwwVariant(domain string) string {
+	if strings.HasPrefix(domain, "www.") {
+		return strings.TrimPrefix(domain, "www.")
+	}
+	return "www." + domain
+}
+
+// Redirect returns the host that 301s to the primary domain of a service when it serves
+// both the apex and www forms of its custom domain. ok is false when nothing redirects.
+func Redirect(service models.Service) (from string, to string, ok bool) {
+	if !service.RedirectWww || service.CustomDomain == nil || *service.CustomDomain == "" {
+		return "", "", false
+	}
+
+	to = *service.CustomDomain
+	if service.PrimaryDomain != nil && *service.PrimaryDomain != "" {
+		to = *service.PrimaryDomain
+	}
+	return WwwVariant(to), to, true
+}
+
+// IsAvailable reports whether no other service, apart from the deleted ones, uses the domain.
+// Services redirecting www also use the other form of their custom domain.
+func IsAvailable(db *gorm.DB, domain string, serviceID string) (bool, error) {
+	var count int64
+	if err := db.Model(&models.Service{}).
+		Where("(LOWER(customDomain) = ? OR (redirectWww = ? AND LOWER(customDomain) = ?)) AND id <> ? AND deletedAt IS NULL",
+			domain, true, WwwVariant(domain), serviceID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// Verify checks that the DNS of a custom domain leads to target, the host the service is
+// reachable at on the platform: a CNAME to it, or addresses it resolves to as well
+func Verify(domain, target string) error {
+	target = strings.ToLower(strings.TrimSuffix(target, "."))
+
+	if cname, err := lookupCNAME(domain); err == nil && strings.ToLower(strings.TrimSuffix(cname, ".")) == target {
+		return nil
+	}
+
+	domainIPs, err := lookupIP(domain)
+	if err != nil || len(domainIPs) == 0 {
+		return fmt.Errorf("%w: %s does not resolve", ErrNotPointed, domain)
+	}
+	targetIPs, err := lookupIP(target)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", target, err)
+	}
+	for _, ip := range domainIPs {
+		for _, targetIP := range targetIPs {
+			if ip.Equal(targetIP) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: add a CNAME record for %s pointing to %s", ErrNotPointed, domain, target)
+}
@@ -0,0 +1,199 @@
+package customdomain
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	config.Load()
+	os.Exit(m.Run())
+}
+
+func useAppDomain(t *testing.T, domain string) {
+	t.Helper()
+	cfg := config.Get()
+	previous := cfg.AppDomain
+	cfg.AppDomain = domain
+	t.Cleanup(func() { cfg.AppDomain = previous })
+}
+
+func TestNormalize(t *testing.T) {
+	useAppDomain(t, "deployra.app")
+	tests := map[string]string{
+		"example.com":                          "example.com",
+		"  Example.COM  ":                      "example.com",
+		"https://www.example.com/":             "www.example.com",
+		"http://app.example.com:8080/path?q=1": "app.example.com",
+		"www.example.com.":                     "www.example.com",
+		"bücher.example":                       "xn--bcher-kva.example",
+		"deployra.app.example.com":             "deployra.app.example.com",
+	}
+	for raw, want := range tests {
+		got, err := Normalize(raw)
+		if err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	useAppDomain(t, "deployra.app")
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "", want: "required"},
+		{raw: "*.example.com", want: "Wildcard"},
+		{raw: "192.168.1.10", want: "IP address"},
+		{raw: "http://[2001:db8::1]/", want: "IP address"},
+		{raw: "localhost", want: "fully qualified"},
+		{raw: "user@example.com", want: "Invalid"},
+		{raw: "exa_mple.com", want: "Invalid"},
+		{raw: "-example.com", want: "Invalid"},
+		{raw: "example.123", want: "Invalid"},
+		{raw: "app.deployra.app", want: "deployra.app"},
+		{raw: "Deployra.App", want: "deployra.app"},
+	}
+	for _, tt := range tests {
+		_, err := Normalize(tt.raw)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Normalize(%q) error = %v, want %q", tt.raw, err, tt.want)
+		}
+	}
+}
+
+func TestIsAvailable(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnQuery("LOWER(customDomain)", dbtest.Row{"count(*)": int64(0)})
+
+	available, err := IsAvailable(database.GetDatabase(), "example.com", "svc1")
+	if err != nil || !available {
+		t.Fatalf("IsAvailable = %v, %v, want an unused domain available", available, err)
+	}
+
+	queries := db.Statements("LOWER(customDomain)")
+	if len(queries) != 1 {
+		t.Fatalf("sent %d queries, want 1", len(queries))
+	}
+	// The service's own domain and services redirecting the www form count as well
+	var args []string
+	for _, arg := range queries[0].Args {
+		args = append(args, fmt.Sprint(arg))
+	}
+	if got := strings.Join(args, " "); got != "example.com true www.example.com svc1" {
+		t.Errorf("query args = %s, want the domain, its www form and the service left out", got)
+	}
+}
+
+func TestIsAvailableTaken(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnQuery("LOWER(customDomain)", dbtest.Row{"count(*)": int64(1)})
+
+	available, err := IsAvailable(database.GetDatabase(), "example.com", "svc1")
+	if err != nil || available {
+		t.Errorf("IsAvailable = %v, %v, want a domain of another service taken", available, err)
+	}
+}
+
+// stubDNS answers Verify's lookups from the given records
+func stubDNS(t *testing.T, cnames map[string]string, ips map[string][]string) {
+	t.Helper()
+	lookupCNAME = func(host string) (string, error) {
+		if cname, ok := cnames[host]; ok {
+			return cname, nil
+		}
+		return host + ".", nil
+	}
+	lookupIP = func(host string) ([]net.IP, error) {
+		var result []net.IP
+		for _, ip := range ips[host] {
+			result = append(result, net.ParseIP(ip))
+		}
+		if len(result) == 0 {
+			return nil, errors.New("no such host")
+		}
+		return result, nil
+	}
+	t.Cleanup(func() {
+		lookupCNAME = net.LookupCNAME
+		lookupIP = net.LookupIP
+	})
+}
+
+func TestVerifyAcceptsCNAMEToTarget(t *testing.T) {
+	stubDNS(t, map[string]string{"app.example.com": "Web-1.Deployra.App."}, nil)
+	if err := Verify("app.example.com", "web-1.deployra.app"); err != nil {
+		t.Fatalf("Verify = %v, want nil", err)
+	}
+}
+
+func TestVerifyAcceptsSharedAddress(t *testing.T) {
+	stubDNS(t, nil, map[string][]string{
+		"example.com":        {"203.0.113.7"},
+		"web-1.deployra.app": {"203.0.113.5", "203.0.113.7"},
+	})
+	if err := Verify("example.com", "web-1.deployra.app"); err != nil {
+		t.Fatalf("Verify = %v, want nil", err)
+	}
+}
+
+func TestVerifyRejectsOtherDNS(t *testing.T) {
+	stubDNS(t, nil, map[string][]string{
+		"example.com":        {"198.51.100.1"},
+		"web-1.deployra.app": {"203.0.113.5"},
+	})
+	if err := Verify("example.com", "web-1.deployra.app"); !errors.Is(err, ErrNotPointed) {
+		t.Fatalf("Verify = %v, want ErrNotPointed", err)
+	}
+	if err := Verify("unresolved.example.com", "web-1.deployra.app"); !errors.Is(err, ErrNotPointed) {
+		t.Fatalf("Verify of an unresolved domain = %v, want ErrNotPointed", err)
+	}
+}
+
+func TestWwwVariant(t *testing.T) {
+	for domain, want := range map[string]string{
+		"example.com":         "www.example.com",
+		"www.example.com":     "example.com",
+		"app.example.com":     "www.app.example.com",
+		"www.app.example.com": "app.example.com",
+	} {
+		if got := WwwVariant(domain); got != want {
+			t.Errorf("WwwVariant(%q) = %q, want %q", domain, got, want)
+		}
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	domain := "example.com"
+	tests := []struct {
+		name     string
+		service  models.Service
+		from, to string
+		ok       bool
+	}{
+		{name: "redirect off", service: models.Service{CustomDomain: &domain}},
+		{name: "no custom domain", service: models.Service{RedirectWww: true}},
+		{name: "apex primary", service: models.Service{CustomDomain: &domain, RedirectWww: true}, from: "www.example.com", to: "example.com", ok: true},
+		{name: "www primary", service: models.Service{CustomDomain: &domain, RedirectWww: true, PrimaryDomain: utils.Ptr("www.example.com")},
+			from: "example.com", to: "www.example.com", ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, ok := Redirect(tt.service)
+			if from != tt.from || to != tt.to || ok != tt.ok {
+				t.Errorf("Redirect = %q, %q, %v, want %q, %q, %v", from, to, ok, tt.from, tt.to, tt.ok)
+			}
+		})
+	}
+}
@@ -64,6 +64,20 @@ func GetDatabase() *gorm.DB {
 	return db
 }
 
+// Close closes the database connection pool
+func Close() error {
+	if db == nil {
+		return nil
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+
+	return sqlDB.Close()
+}
+
 // SetDatabase sets the database instance (for testing purposes only)
 func SetDatabase(database *gorm.DB) {
 	db = database
@@ -0,0 +1,289 @@
+// Package dbtest provides a fake database for tests of code using database.GetDatabase.
+// gorm talks to it through the MySQL dialector, queries are answered with rows set up by
+// the test and every statement is recorded so tests can check what was written.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Statement is a query or command gorm sent to the database
+type Statement struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// Row is a result row, keyed by column name
+type Row map[string]driver.Value
+
+type queryRule struct {
+	match  string
+	rows   []Row
+	answer func(args []driver.Value) []Row
+	err    error
+}
+
+type execRule struct {
+	match        string
+	rowsAffected int64
+	answer       func(args []driver.Value) int64
+	err          error
+}
+
+// DB is a fake database installed as the database of the database package
+type DB struct {
+	mu         sync.Mutex
+	queries    []queryRule
+	execs      []execRule
+	statements []Statement
+}
+
+var drivers atomic.Int64
+
+// New installs a fake database for the test, the previous one is restored when it ends
+func New(t *testing.T) *DB {
+	t.Helper()
+
+	fake := &DB{}
+	name := fmt.Sprintf("dbtest-%d", drivers.Add(1))
+	sql.Register(name, fakeDriver{db: fake})
+
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("open fake database: %v", err)
+	}
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+		NamingStrategy: schema.NamingStrategy{
+			SingularTable: true,
+			NoLowerCase:   true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("open gorm on the fake database: %v", err)
+	}
+
+	previous := database.GetDatabase()
+	database.SetDatabase(gormDB)
+	t.Cleanup(func() {
+		database.SetDatabase(previous)
+		sqlDB.Close()
+	})
+	return fake
+}
+
+// OnQuery answers queries containing match with rows, the latest matching rule wins.
+// Queries without a rule return no rows.
+func (db *DB) OnQuery(match string, rows ...Row) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries = append(db.queries, queryRule{match: match, rows: rows})
+}
+
+// OnQueryFunc answers queries containing match with the rows answer returns for their arguments
+func (db *DB) OnQueryFunc(match string, answer func(args []driver.Value) []Row) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries = append(db.queries, queryRule{match: match, answer: answer})
+}
+
+// FailQuery makes queries containing match fail with err
+func (db *DB) FailQuery(match string, err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries = append(db.queries, queryRule{match: match, err: err})
+}
+
+// OnExec reports rowsAffected for commands containing match, the latest matching rule wins.
+// Commands without a rule affect one row.
+func (db *DB) OnExec(match string, rowsAffected int64) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.execs = append(db.execs, execRule{match: match, rowsAffected: rowsAffected})
+}
+
+// OnExecFunc reports the rows answer returns for the arguments of commands containing match
+func (db *DB) OnExecFunc(match string, answer func(args []driver.Value) int64) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.execs = append(db.execs, execRule{match: match, answer: answer})
+}
+
+// FailExec makes commands containing match fail with err
+func (db *DB) FailExec(match string, err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.execs = append(db.execs, execRule{match: match, err: err})
+}
+
+// Statements returns the statements containing match, in the order they were sent
+func (db *DB) Statements(match string) []Statement {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var statements []Statement
+	for _, statement := range db.statements {
+		if strings.Contains(statement.SQL, match) {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+func (db *DB) record(query string, args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.statements = append(db.statements, Statement{SQL: query, Args: values})
+	return values
+}
+
+func (db *DB) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	values := db.record(query, args)
+
+	db.mu.Lock()
+	var matched *queryRule
+	for i := len(db.queries) - 1; i >= 0; i-- {
+		if rule := db.queries[i]; strings.Contains(query, rule.match) {
+			matched = &rule
+			break
+		}
+	}
+	db.mu.Unlock()
+
+	switch {
+	case matched == nil:
+		return newRows(nil), nil
+	case matched.err != nil:
+		return nil, matched.err
+	case matched.answer != nil:
+		// Called without the lock, so answers may look at the recorded statements
+		return newRows(matched.answer(values)), nil
+	default:
+		return newRows(matched.rows), nil
+	}
+}
+
+func (db *DB) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	values := db.record(query, args)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for i := len(db.execs) - 1; i >= 0; i-- {
+		rule := db.execs[i]
+		if strings.Contains(query, rule.match) {
+			if rule.err != nil {
+				return nil, rule.err
+			}
+			if rule.answer != nil {
+				return driver.RowsAffected(rule.answer(values)), nil
+			}
+			return driver.RowsAffected(rule.rowsAffected), nil
+		}
+	}
+	return result{rowsAffected: 1}, nil
+}
+
+type result struct {
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) { return 1, nil }
+func (r result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type fakeDriver struct {
+	db *DB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &conn{db: d.db}, nil
+}
+
+type conn struct {
+	db *DB
+}
+
+func (c *conn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("dbtest: prepared statements are not supported")
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) { return tx{}, nil }
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(query, args)
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.db.exec(query, args)
+}
+
+// CheckNamedValue accepts every argument as it is, the fake doesn't convert types
+func (c *conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+// rows returns the rows of a query, the columns are the union of the rows' keys
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func newRows(data []Row) *rows {
+	r := &rows{}
+	seen := map[string]bool{}
+	for _, row := range data {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				r.columns = append(r.columns, column)
+			}
+		}
+	}
+	for _, row := range data {
+		values := make([]driver.Value, len(r.columns))
+		for i, column := range r.columns {
+			values[i] = row[column]
+		}
+		r.values = append(r.values, values)
+	}
+	return r
+}
+
+func (r *rows) Columns() []string { return r.columns }
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
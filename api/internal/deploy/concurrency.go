@@ -0,0 +1,123 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+)
+
+// ErrConcurrencyLimit is returned when a manual build is started while its organization
+// already runs as many builds as it may
+var ErrConcurrencyLimit = errors.New("organization is at its concurrent deployment limit")
+
+// HoldsBuildSlot reports whether a deployment in this status counts against its
+// organization's concurrency limit, i.e. it is waiting for or using a builder
+func HoldsBuildSlot(status models.DeploymentStatus) bool {
+	return status == models.DeploymentStatusPending || status == models.DeploymentStatusBuilding
+}
+
+// concurrencyLimit returns how many builds an organization may run at once, 0 for no limit
+func concurrencyLimit(org models.Organization) int {
+	if org.MaxConcurrentDeployments != nil {
+		return *org.MaxConcurrentDeployments
+	}
+	return config.Get().DeployConcurrencyLimit
+}
+
+// buildSlotLockWait is how long a build waits for another one of its organization to take
+// its slot
+const buildSlotLockWait = 5 * time.Second
+
+// buildSlotLockKey returns the Redis lock serializing the builds of an organization between
+// counting its active builds and creating the deployment that takes the slot
+func buildSlotLockKey(organizationID string) string {
+	return fmt.Sprintf("build-slot-lock:%s", organizationID)
+}
+
+// lockBuildSlots waits for the build slot lock of an organization and returns its release.
+// Without it two builds could both count a free slot and both take it.
+func lockBuildSlots(ctx context.Context, organizationID string) (func(), error) {
+	key := buildSlotLockKey(organizationID)
+	deadline := time.Now().Add(buildSlotLockWait)
+	for {
+		acquired, err := redis.AcquireLock(ctx, key, 10)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			return func() { redis.ReleaseLock(ctx, key) }, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("another deployment of the organization is starting, try again")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// countActiveBuilds counts the deployments of an organization that hold a build slot,
+// including the held ones
+func countActiveBuilds(organizationID string) (int64, error) {
+	var count int64
+	err := database.GetDatabase().Model(&models.Deployment{}).
+		Joins("JOIN Service ON Service.id = Deployment.serviceId").
+		Joins("JOIN Project ON Project.id = Service.projectId").
+		Where("Project.organizationId = ? AND Deployment.status IN ?", organizationID,
+			[]models.DeploymentStatus{models.DeploymentStatusPending, models.DeploymentStatusBuilding}).
+		Count(&count).Error
+	return count, err
+}
+
+// StartDeferredBuild moves the oldest held build of an organization to the builder queue.
+// It is called whenever one of the organization's builds gives up its slot, held builds
+// that were cancelled in the meantime are dropped.
+func StartDeferredBuild(organizationID string) {
+	db := database.GetDatabase()
+	ctx := context.Background()
+
+	for {
+		job, err := redis.PopDeferredBuilderJob(ctx, organizationID)
+		if err != nil {
+			log.Printf("Failed to read held builds of organization %s: %v", organizationID, err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		var deployment models.Deployment
+		if err := db.Where("id = ? AND status = ?", job.DeploymentID, models.DeploymentStatusPending).
+			First(&deployment).Error; err != nil {
+			continue
+		}
+
+		if err := redis.AddToBuilderQueue(ctx, *job); err != nil {
+			log.Printf("Failed to start held build %s: %v", job.DeploymentID, err)
+			markFailed(job.DeploymentID, "Failed to start deployment after waiting for a build slot")
+			continue
+		}
+
+		log.Printf("Started held build %s of organization %s", job.DeploymentID, organizationID)
+		return
+	}
+}
+
+// markFailed fails a deployment that could not be handed to the builder
+func markFailed(deploymentID, message string) {
+	db := database.GetDatabase()
+
+	db.Model(&models.Deployment{}).
+		Where("id = ?", deploymentID).
+		Update("status", models.DeploymentStatusFailed)
+	db.Create(&models.DeploymentLog{
+		DeploymentID: deploymentID,
+		Text:         message,
+		Type:         models.LogTypeError,
+	})
+}
@@ -0,0 +1,87 @@
+package deploy
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/redis"
+)
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	os.Setenv("ENCRYPTION_KEY", "0123456789abcdef0123456789abcdef")
+	config.Load()
+	if err := crypto.Initialize(); err != nil {
+		panic(err)
+	}
+
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+func TestLockBuildSlotsSerializesAnOrganization(t *testing.T) {
+	redisServer.FlushAll()
+	ctx := context.Background()
+
+	// Each holder counts and takes a slot under the lock, no two of them hold it at once
+	var holders, maxHolders int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := lockBuildSlots(ctx, "org")
+			if err != nil {
+				t.Errorf("lockBuildSlots: %v", err)
+				return
+			}
+			if n := atomic.AddInt32(&holders, 1); n > atomic.LoadInt32(&maxHolders) {
+				atomic.StoreInt32(&maxHolders, n)
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if maxHolders != 1 {
+		t.Errorf("%d builds held the slot lock at once, want 1", maxHolders)
+	}
+	if redisServer.Exists(buildSlotLockKey("org")) {
+		t.Error("slot lock was not released")
+	}
+}
+
+func TestLockBuildSlotsIsPerOrganization(t *testing.T) {
+	redisServer.FlushAll()
+	ctx := context.Background()
+
+	release, err := lockBuildSlots(ctx, "org-a")
+	if err != nil {
+		t.Fatalf("lock org-a: %v", err)
+	}
+	defer release()
+
+	releaseB, err := lockBuildSlots(ctx, "org-b")
+	if err != nil {
+		t.Fatalf("lock org-b while org-a is locked: %v", err)
+	}
+	releaseB()
+}
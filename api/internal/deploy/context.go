@@ -0,0 +1,136 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/pkg/github"
+)
+
+// Directories that are large and have no place in an image, or are installed by the build
+var heavyDirs = []string{".git", "node_modules", "vendor"}
+
+// NormalizeBuildContextPath returns a build context path relative to the root of the
+// repository, nil for the root itself
+func NormalizeBuildContextPath(raw string) (*string, error) {
+	raw = strings.Trim(strings.TrimSpace(raw), "/")
+	for _, segment := range strings.Split(raw, "/") {
+		if segment == ".." {
+			return nil, errors.New("Build context path must be inside the repository")
+		}
+	}
+	if contextPath := path.Clean("/" + raw)[1:]; contextPath != "" {
+		return &contextPath, nil
+	}
+	return nil, nil
+}
+
+// CheckBuildContextPath returns an error when a build context path isn't a directory of a
+// repository at a ref. Only GitHub repositories are checked, the builder fails builds of
+// others whose context is missing.
+func CheckBuildContextPath(ctx context.Context, provider *models.GitProvider, repositoryName, ref, contextPath string) error {
+	if provider.Type != models.GitProviderTypeGitHub {
+		return nil
+	}
+
+	client, err := repositoryClientFor(ctx, provider)
+	if err != nil {
+		return err
+	}
+	return checkContextDirectory(ctx, client, repositoryName, ref, contextPath)
+}
+
+// contentLister lists the contents of a path of a repository, as github.Client does
+type contentLister interface {
+	ListContents(ctx context.Context, owner, repo, ref, path string) ([]github.ContentEntry, bool, error)
+}
+
+// checkContextDirectory returns an error when contextPath isn't a directory of a repository
+func checkContextDirectory(ctx context.Context, client contentLister, repositoryName, ref, contextPath string) error {
+	owner, repo, _ := strings.Cut(repositoryName, "/")
+	_, dir, err := client.ListContents(ctx, owner, repo, ref, contextPath)
+	if errors.Is(err, github.ErrPathNotFound) || (err == nil && !dir) {
+		return fmt.Errorf("Build context path %s is not a directory of the repository at %s", contextPath, ref)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to access the repository: %v", err)
+	}
+	return nil
+}
+
+// contextFiles reads the .dockerignore and the tree of a repository, as github.Client does
+type contextFiles interface {
+	GetFileContent(ctx context.Context, owner, repo, ref, path string) ([]byte, error)
+	ListTree(ctx context.Context, owner, repo, sha string) ([]github.TreeEntry, bool, error)
+}
+
+// checkBuildContext estimates the size of the build context of a Dockerfile build, the
+// repository or its contextPath directory minus what the .dockerignore of the context
+// ignores, and warns when it is large or heavy directories aren't ignored
+func checkBuildContext(ctx context.Context, v *Validation, client contextFiles, owner, repo, sha, contextPath string) {
+	content, err := client.GetFileContent(ctx, owner, repo, sha, path.Join(contextPath, ".dockerignore"))
+	if err != nil && !errors.Is(err, github.ErrFileNotFound) {
+		v.Add("buildContext", IssueWarning, "Failed to read the .dockerignore: "+err.Error())
+		return
+	}
+	hasDockerignore := err == nil
+	ignore := parseDockerignore(content)
+
+	entries, truncated, err := client.ListTree(ctx, owner, repo, sha)
+	if err != nil {
+		v.Add("buildContext", IssueWarning, "Failed to list the files of the repository: "+err.Error())
+		return
+	}
+
+	// The builder clones the repository, its .git directory isn't part of the tree. Without a
+	// .dockerignore the builder writes one ignoring it. A subdirectory context has no .git.
+	if !hasDockerignore {
+		v.Add("buildContext", IssueWarning, "Build context has no .dockerignore, the builder's default one is used")
+	} else if contextPath == "" && !ignore.ignored(".git") {
+		v.Add("buildContext", IssueWarning, ".git is not ignored by the .dockerignore")
+	}
+
+	var size int64
+	reported := make(map[string]bool)
+	for _, entry := range entries {
+		// .dockerignore patterns are relative to the root of the context
+		if contextPath != "" {
+			relative, ok := strings.CutPrefix(entry.Path, contextPath+"/")
+			if !ok {
+				continue
+			}
+			entry.Path = relative
+		}
+		if ignore.ignored(entry.Path) {
+			continue
+		}
+		size += entry.Size
+
+		name := path.Base(entry.Path)
+		if !entry.Dir || !slices.Contains(heavyDirs, name) || reported[name] {
+			continue
+		}
+		reported[name] = true
+		if hasDockerignore {
+			v.Add("buildContext", IssueWarning, fmt.Sprintf("%s is not ignored by the .dockerignore", entry.Path))
+		} else {
+			v.Add("buildContext", IssueWarning, fmt.Sprintf("%s is committed to the repository, ignore it in a .dockerignore", entry.Path))
+		}
+	}
+
+	limitMB := config.Get().BuildContextWarnMB
+	if sizeMB := size >> 20; limitMB > 0 && sizeMB >= int64(limitMB) {
+		// GitHub lists part of the files of large repositories
+		estimate := "about"
+		if truncated {
+			estimate = "at least"
+		}
+		v.Add("buildContext", IssueWarning, fmt.Sprintf("Build context is %s %d MB, contexts this large slow down builds and can make them fail", estimate, sizeMB))
+	}
+}
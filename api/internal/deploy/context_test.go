@@ -0,0 +1,247 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/github"
+)
+
+func TestNormalizeBuildContextPath(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "", want: ""},
+		{raw: "/", want: ""},
+		{raw: ".", want: ""},
+		{raw: "services/api", want: "services/api"},
+		{raw: " /services/api/ ", want: "services/api"},
+		{raw: "services//api/./", want: "services/api"},
+		{raw: "../secrets", wantErr: true},
+		{raw: "services/../../secrets", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := NormalizeBuildContextPath(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NormalizeBuildContextPath(%q) = %v, want an error", tt.raw, utils.PtrValue(got, ""))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeBuildContextPath(%q): %v", tt.raw, err)
+			}
+			if tt.want == "" && got != nil {
+				t.Errorf("NormalizeBuildContextPath(%q) = %q, want nil for the repository root", tt.raw, *got)
+			}
+			if tt.want != "" && utils.PtrValue(got, "") != tt.want {
+				t.Errorf("NormalizeBuildContextPath(%q) = %q, want %q", tt.raw, utils.PtrValue(got, ""), tt.want)
+			}
+		})
+	}
+}
+
+// fakeContents answers ListContents from a fixed listing of the paths of a repository
+type fakeContents struct {
+	dirs  map[string]bool
+	files map[string]bool
+	err   error
+}
+
+func (f fakeContents) ListContents(ctx context.Context, owner, repo, ref, path string) ([]github.ContentEntry, bool, error) {
+	switch {
+	case f.err != nil:
+		return nil, false, f.err
+	case f.dirs[path]:
+		return []github.ContentEntry{}, true, nil
+	case f.files[path]:
+		return []github.ContentEntry{{Path: path, Type: "file"}}, false, nil
+	}
+	return nil, false, github.ErrPathNotFound
+}
+
+func TestCheckContextDirectory(t *testing.T) {
+	contents := fakeContents{
+		dirs:  map[string]bool{"services/api": true},
+		files: map[string]bool{"services/api/Dockerfile": true},
+	}
+
+	if err := checkContextDirectory(context.Background(), contents, "acme/mono", "abc", "services/api"); err != nil {
+		t.Errorf("existing directory: %v", err)
+	}
+
+	for _, contextPath := range []string{"services/web", "services/api/Dockerfile"} {
+		err := checkContextDirectory(context.Background(), contents, "acme/mono", "abc", contextPath)
+		if err == nil || !strings.Contains(err.Error(), "is not a directory") {
+			t.Errorf("%s: error = %v, want it reported as not a directory", contextPath, err)
+		}
+	}
+
+	failing := fakeContents{err: errors.New("rate limited")}
+	err := checkContextDirectory(context.Background(), failing, "acme/mono", "abc", "services/api")
+	if err == nil || !strings.Contains(err.Error(), "Failed to access the repository") {
+		t.Errorf("GitHub failure: error = %v, want an access error", err)
+	}
+}
+
+func TestBuilderJobCarriesBuildContextPath(t *testing.T) {
+	service := models.Service{
+		ID:               "svc",
+		RepositoryName:   utils.Ptr("acme/mono"),
+		BuildContextPath: utils.Ptr("services/api"),
+		Ports:            []models.ServicePort{{ServicePort: 80, ContainerPort: 8080}},
+	}
+
+	job := builderJob(service, "dep", "abc", "main", nil)
+	if utils.PtrValue(job.BuildContextPath, "") != "services/api" {
+		t.Errorf("job context path = %v, want services/api", utils.PtrValue(job.BuildContextPath, ""))
+	}
+	if job.ServiceID != "svc" || job.DeploymentID != "dep" || job.RepositoryName != "acme/mono" || len(job.Ports) != 1 {
+		t.Errorf("job = %+v, want the service, deployment, repository and ports", job)
+	}
+
+	// A service building from the repository root sends no context path
+	service.BuildContextPath = nil
+	if job := builderJob(service, "dep", "abc", "main", nil); job.BuildContextPath != nil {
+		t.Errorf("root job context path = %q, want none", *job.BuildContextPath)
+	}
+}
+
+// fakeRepository answers the build context check from fixed files and a fixed tree
+type fakeRepository struct {
+	files     map[string]string
+	tree      []github.TreeEntry
+	truncated bool
+}
+
+func (f fakeRepository) GetFileContent(ctx context.Context, owner, repo, ref, path string) ([]byte, error) {
+	content, ok := f.files[path]
+	if !ok {
+		return nil, github.ErrFileNotFound
+	}
+	return []byte(content), nil
+}
+
+func (f fakeRepository) ListTree(ctx context.Context, owner, repo, sha string) ([]github.TreeEntry, bool, error) {
+	return f.tree, f.truncated, nil
+}
+
+// nodeTree is the tree of a Node.js app with its node_modules committed
+var nodeTree = []github.TreeEntry{
+	{Path: "Dockerfile", Size: 200},
+	{Path: "package.json", Size: 500},
+	{Path: "src", Dir: true},
+	{Path: "src/index.js", Size: 2000},
+	{Path: "node_modules", Dir: true},
+	{Path: "node_modules/express", Dir: true},
+	{Path: "node_modules/express/index.js", Size: 1000},
+	{Path: "node_modules/express/node_modules", Dir: true},
+}
+
+// buildContextWarnings returns the messages of the warnings the build context check adds
+func buildContextWarnings(repository fakeRepository, contextPath string) []string {
+	v := &Validation{Valid: true}
+	checkBuildContext(context.Background(), v, repository, "acme", "app", "abc", contextPath)
+
+	var messages []string
+	for _, issue := range v.Issues {
+		if issue.Check == "buildContext" && issue.Severity == IssueWarning {
+			messages = append(messages, issue.Message)
+		}
+	}
+	if !v.Valid {
+		messages = append(messages, "the validation failed")
+	}
+	return messages
+}
+
+func TestBuildContextWithoutDockerignoreWarnsAboutNodeModules(t *testing.T) {
+	got := buildContextWarnings(fakeRepository{tree: nodeTree}, "")
+	// Nested node_modules are reported once
+	want := []string{
+		"Build context has no .dockerignore, the builder's default one is used",
+		"node_modules is committed to the repository, ignore it in a .dockerignore",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+}
+
+func TestBuildContextDockerignoreMissingHeavyDirs(t *testing.T) {
+	repository := fakeRepository{files: map[string]string{".dockerignore": "# local files\n.env\n"}, tree: nodeTree}
+
+	got := buildContextWarnings(repository, "")
+	want := []string{
+		".git is not ignored by the .dockerignore",
+		"node_modules is not ignored by the .dockerignore",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+}
+
+func TestBuildContextIgnoredHeavyDirs(t *testing.T) {
+	repository := fakeRepository{files: map[string]string{".dockerignore": ".git\n**/node_modules\n"}, tree: nodeTree}
+
+	if got := buildContextWarnings(repository, ""); len(got) != 0 {
+		t.Errorf("warnings = %q, want none", got)
+	}
+}
+
+func TestBuildContextOfSubdirectory(t *testing.T) {
+	repository := fakeRepository{
+		files: map[string]string{"services/api/.dockerignore": "node_modules\n"},
+		tree: []github.TreeEntry{
+			{Path: "node_modules", Dir: true},
+			{Path: "services/api/Dockerfile", Size: 200},
+			{Path: "services/api/node_modules", Dir: true},
+			{Path: "services/web/vendor", Dir: true},
+		},
+	}
+
+	// The .dockerignore of the context applies, directories outside of it and .git are no part of it
+	if got := buildContextWarnings(repository, "services/api"); len(got) != 0 {
+		t.Errorf("warnings = %q, want none", got)
+	}
+}
+
+func TestBuildContextSize(t *testing.T) {
+	cfg := config.Get()
+	previous := cfg.BuildContextWarnMB
+	cfg.BuildContextWarnMB = 10
+	t.Cleanup(func() { cfg.BuildContextWarnMB = previous })
+
+	tree := []github.TreeEntry{
+		{Path: "Dockerfile", Size: 200},
+		{Path: "assets/video.mp4", Size: 12 << 20},
+		{Path: "fixtures/dump.sql", Size: 50 << 20},
+	}
+	dockerignore := map[string]string{".dockerignore": ".git\nfixtures\n"}
+
+	got := buildContextWarnings(fakeRepository{files: dockerignore, tree: tree}, "")
+	// Ignored files don't count
+	want := []string{"Build context is about 12 MB, contexts this large slow down builds and can make them fail"}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+
+	got = buildContextWarnings(fakeRepository{files: dockerignore, tree: tree, truncated: true}, "")
+	want = []string{"Build context is at least 12 MB, contexts this large slow down builds and can make them fail"}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings of a truncated tree = %q, want %q", got, want)
+	}
+
+	dockerignore[".dockerignore"] += "assets\n"
+	if got := buildContextWarnings(fakeRepository{files: dockerignore, tree: tree}, ""); len(got) != 0 {
+		t.Errorf("warnings = %q, want none for a small context", got)
+	}
+}
@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/deployra/deployra/api/internal/config"
 	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/customdomain"
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
 	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/region"
 	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/github"
 )
 
 // Shell operator regex for detecting shell syntax
@@ -46,6 +50,22 @@ func parseContainerCommand(command string) []string {
 
 // BuildService starts a new build for a service
 func BuildService(serviceID string, userID string, triggerType string, commitSha string) (*models.Deployment, error) {
+	return buildService(serviceID, userID, triggerType, commitSha, "")
+}
+
+// BuildServiceRef starts a new build for a service from a resolved branch, tag or commit.
+// Branches and tags are cloned by name, commits from the service's branch.
+func BuildServiceRef(serviceID string, userID string, triggerType string, ref *github.Ref) (*models.Deployment, error) {
+	branch := ""
+	if ref.Kind != github.RefKindCommit {
+		branch = ref.Name
+	}
+	return buildService(serviceID, userID, triggerType, ref.CommitSha, branch)
+}
+
+// buildService starts a build of commitSha, cloned from branch or, when it's empty, the
+// service's branch
+func buildService(serviceID string, userID string, triggerType string, commitSha string, branch string) (*models.Deployment, error) {
 	db := database.GetDatabase()
 	ctx := context.Background()
 
@@ -53,11 +73,17 @@ func BuildService(serviceID string, userID string, triggerType string, commitSha
 	var service models.Service
 	if err := db.Preload("GitProvider.GithubAccount").
 		Preload("Ports").
+		Preload("Project.Organization").
 		Where("id = ? AND deletedAt IS NULL", serviceID).
 		First(&service).Error; err != nil {
 		return nil, fmt.Errorf("service not found")
 	}
 
+	// Stopped services stay at zero replicas until they are started again
+	if service.Stopped {
+		return nil, fmt.Errorf("service is stopped, start it before deploying")
+	}
+
 	// Check if there's already a deployment in progress
 	var activeDeployment models.Deployment
 	err := db.Where("serviceId = ? AND status IN ?", serviceID,
@@ -67,6 +93,30 @@ func BuildService(serviceID string, userID string, triggerType string, commitSha
 		return nil, fmt.Errorf("a deployment is already in progress")
 	}
 
+	// Keep an organization from taking every builder. Users are told right away,
+	// pushes and automatic builds are held until one of the organization's builds finishes.
+	organizationID := service.Project.OrganizationID
+	deferBuild := false
+	if limit := concurrencyLimit(service.Project.Organization); limit > 0 {
+		// Held until the deployment is created, it counts as active from then on
+		release, err := lockBuildSlots(ctx, organizationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve a build slot: %w", err)
+		}
+		defer release()
+
+		active, err := countActiveBuilds(organizationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count active deployments: %w", err)
+		}
+		if active >= int64(limit) {
+			if triggerType == "manual" {
+				return nil, fmt.Errorf("%w of %d, wait for a running deployment to finish", ErrConcurrencyLimit, limit)
+			}
+			deferBuild = true
+		}
+	}
+
 	// Get the latest deployment number
 	var latestDeployment models.Deployment
 	db.Where("serviceId = ?", serviceID).
@@ -78,9 +128,11 @@ func BuildService(serviceID string, userID string, triggerType string, commitSha
 		nextDeploymentNumber = latestDeployment.DeploymentNumber + 1
 	}
 
-	branch := "main"
-	if service.Branch != nil {
-		branch = *service.Branch
+	if branch == "" {
+		branch = "main"
+		if service.Branch != nil {
+			branch = *service.Branch
+		}
 	}
 
 	// Create a new deployment record
@@ -107,23 +159,43 @@ func BuildService(serviceID string, userID string, triggerType string, commitSha
 		DeploymentID: &deployment.ID,
 	})
 
-	// Parse environment variables
-	var envVars []redis.EnvironmentVariable
+	// Parse environment variables, keeping which of them are secrets
+	var storedEnvVars []crypto.EnvironmentVariable
 	if service.EnvironmentVariables != nil {
-		service.EnvironmentVariables.UnmarshalTo(&envVars)
+		service.EnvironmentVariables.UnmarshalTo(&storedEnvVars)
 	}
 
 	// Decrypt environment variables before sending to builder
-	cryptoEnvVars := make([]crypto.EnvironmentVariable, len(envVars))
-	for i, v := range envVars {
-		cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
-	}
-	decryptedEnvVars, _ := crypto.DecryptEnvVars(cryptoEnvVars)
-	envVars = make([]redis.EnvironmentVariable, len(decryptedEnvVars))
+	decryptedEnvVars, _ := crypto.DecryptEnvVars(storedEnvVars)
+	envVars := make([]redis.EnvironmentVariable, len(decryptedEnvVars))
 	for i, v := range decryptedEnvVars {
 		envVars[i] = redis.EnvironmentVariable{Key: v.Key, Value: v.Value}
 	}
 
+	job := builderJob(service, deployment.ID, commitSha, branch, envVars)
+
+	if deferBuild {
+		if err := redis.AddToDeferredBuilderQueue(ctx, organizationID, job); err != nil {
+			return nil, fmt.Errorf("failed to hold builder job: %w", err)
+		}
+		db.Create(&models.DeploymentLog{
+			DeploymentID: deployment.ID,
+			Text:         "Waiting for another deployment in the organization to finish...",
+			Type:         models.LogTypeInfo,
+		})
+		return &deployment, nil
+	}
+
+	// Add to builder queue
+	if err := redis.AddToBuilderQueue(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to add job to builder queue: %w", err)
+	}
+
+	return &deployment, nil
+}
+
+// builderJob returns the job the builder builds a deployment of a service from
+func builderJob(service models.Service, deploymentID, commitSha, branch string, envVars []redis.EnvironmentVariable) redis.BuilderJob {
 	// Convert ports
 	var ports []redis.Port
 	for _, p := range service.Ports {
@@ -133,14 +205,14 @@ func BuildService(serviceID string, userID string, triggerType string, commitSha
 		})
 	}
 
-	// Build the job
 	job := redis.BuilderJob{
-		DeploymentID:         deployment.ID,
-		ServiceID:            serviceID,
+		DeploymentID:         deploymentID,
+		ServiceID:            service.ID,
 		CommitSha:            commitSha,
 		Branch:               branch,
 		RepositoryName:       "",
 		RuntimeFilePath:      service.RuntimeFilePath,
+		BuildContextPath:     service.BuildContextPath,
 		EnvironmentVariables: envVars,
 		Ports:                ports,
 	}
@@ -151,30 +223,94 @@ func BuildService(serviceID string, userID string, triggerType string, commitSha
 
 	// Add git provider info
 	if service.GitProvider != nil {
-		gitProvider := &redis.BuilderGitProvider{
-			Type:           string(service.GitProvider.Type),
-			InstallationID: service.GitProvider.InstallationID,
-			URL:            service.GitProvider.URL,
-			Username:       service.GitProvider.Username,
-			Password:       service.GitProvider.Password,
-		}
+		job.GitProvider = builderGitProvider(service.GitProvider)
+	}
 
-		if service.GitProvider.GithubAccount != nil {
+	return job
+}
+
+// builderGitProvider builds the clone credentials sent to the builder. GitHub providers
+// use the account token, any other provider is treated as a generic HTTPS git server
+// authenticated with the stored URL and basic-auth credentials.
+func builderGitProvider(provider *models.GitProvider) *redis.BuilderGitProvider {
+	gitProvider := &redis.BuilderGitProvider{
+		Type: string(provider.Type),
+	}
+
+	if provider.Type == models.GitProviderTypeGitHub {
+		gitProvider.InstallationID = provider.InstallationID
+		if provider.GithubAccount != nil {
 			gitProvider.GithubAccount = &redis.BuilderGithubAccount{
-				Username:    service.GitProvider.GithubAccount.Username,
-				AccessToken: service.GitProvider.GithubAccount.AccessToken,
+				Username:    provider.GithubAccount.Username,
+				AccessToken: provider.GithubAccount.AccessToken,
 			}
 		}
+		return gitProvider
+	}
 
-		job.GitProvider = gitProvider
+	// The builder only knows GITHUB and CUSTOM, every other provider clones over HTTPS
+	gitProvider.Type = string(models.GitProviderTypeCustom)
+	gitProvider.URL = provider.URL
+	gitProvider.Username = provider.Username
+	if provider.Password != nil {
+		gitProvider.Password = utils.Ptr(crypto.DecryptWithFallback(*provider.Password))
 	}
 
-	// Add to builder queue
-	if err := redis.AddToBuilderQueue(ctx, job); err != nil {
-		return nil, fmt.Errorf("failed to add job to builder queue: %w", err)
+	return gitProvider
+}
+
+// CancelDeployment removes a deployment from the queues, signals builders to stop it
+// and marks it as cancelled. Returns true if the deployment was removed before processing.
+func CancelDeployment(ctx context.Context, deploymentID string) (bool, error) {
+	db := database.GetDatabase()
+
+	var deployment models.Deployment
+	db.Preload("Service.Project").Where("id = ?", deploymentID).First(&deployment)
+	organizationID := deployment.Service.Project.OrganizationID
+
+	// Try to remove from queues
+	removedFromQueue := false
+	for _, queue := range append([]string{redis.QueueBuilder}, redis.DeploymentQueues()...) {
+		removed, err := redis.RemoveDeploymentFromQueue(ctx, queue, deploymentID)
+		if err != nil {
+			fmt.Printf("Failed to remove deployment from queue: %v\n", err)
+		}
+		removedFromQueue = removedFromQueue || removed
 	}
 
-	return &deployment, nil
+	// Held builds wait in their organization's queue
+	heldBuild := false
+	if organizationID != "" {
+		removed, err := redis.RemoveDeploymentFromQueue(ctx, redis.DeferredBuilderQueue(organizationID), deploymentID)
+		if err != nil {
+			fmt.Printf("Failed to remove deployment from queue: %v\n", err)
+		}
+		heldBuild = removed
+		removedFromQueue = removedFromQueue || removed
+	}
+
+	// Send cancellation signal
+	if err := redis.PublishBuilderCancellation(ctx, deploymentID); err != nil {
+		fmt.Printf("Failed to publish builder cancellation: %v\n", err)
+	}
+
+	// Update deployment status
+	now := time.Now()
+	if err := db.Model(&models.Deployment{}).
+		Where("id = ?", deploymentID).
+		Updates(map[string]interface{}{
+			"status":      models.DeploymentStatusCancelled,
+			"completedAt": &now,
+		}).Error; err != nil {
+		return removedFromQueue, fmt.Errorf("failed to cancel deployment: %w", err)
+	}
+
+	// A held build never had a slot, any other build gives its slot to the next held one
+	if organizationID != "" && HoldsBuildSlot(deployment.Status) && !heldBuild {
+		go StartDeferredBuild(organizationID)
+	}
+
+	return removedFromQueue, nil
 }
 
 // DeployService sends a service to the deployment queue
@@ -198,29 +334,58 @@ func DeployService(deployType string, deploymentID *string, serviceID string) er
 		return fmt.Errorf("project not found for service: %s", serviceID)
 	}
 
-	// Parse environment variables
-	var envVars []redis.EnvironmentVariable
+	// Parse environment variables, keeping which of them are secrets
+	var storedEnvVars []crypto.EnvironmentVariable
 	if service.EnvironmentVariables != nil {
-		service.EnvironmentVariables.UnmarshalTo(&envVars)
+		service.EnvironmentVariables.UnmarshalTo(&storedEnvVars)
 	}
 
 	// Decrypt environment variables before sending to kubestrator
-	cryptoEnvVars := make([]crypto.EnvironmentVariable, len(envVars))
-	for i, v := range envVars {
-		cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
-	}
-	decryptedEnvVars, _ := crypto.DecryptEnvVars(cryptoEnvVars)
-	envVars = make([]redis.EnvironmentVariable, len(decryptedEnvVars))
+	decryptedEnvVars, _ := crypto.DecryptEnvVars(storedEnvVars)
+	envVars := make([]redis.EnvironmentVariable, len(decryptedEnvVars))
 	for i, v := range decryptedEnvVars {
 		envVars[i] = redis.EnvironmentVariable{Key: v.Key, Value: v.Value}
 	}
 
+	// Registry credentials are only decrypted for the job
+	registryUsername, registryPassword := registryCredentials(service)
+
+	job := deploymentJob(deployType, deploymentID, service, project.OrganizationID, envVars, registryUsername, registryPassword)
+
+	// Create the pull secret here so the credentials stay out of the queue. Kubestrator still
+	// creates it from the job when the API can't reach the cluster.
+	if service.ServiceTypeID == "web" || service.ServiceTypeID == "private" {
+		pullSecret, err := ensurePullSecret(ctx, service, registryUsername, registryPassword)
+		if err != nil {
+			fmt.Printf("Failed to create pull secret for service %s: %v\n", serviceID, err)
+		} else if pullSecret != "" {
+			job.ContainerRegistry.PullSecretName = pullSecret
+			job.ContainerRegistry.Username = ""
+			job.ContainerRegistry.Password = ""
+		}
+	}
+
+	// Add to deployment queue
+	if err := redis.AddToDeploymentQueue(ctx, job); err != nil {
+		return fmt.Errorf("failed to add job to deployment queue: %w", err)
+	}
+
+	return nil
+}
+
+// deploymentJob builds the job kubestrator deploys a service from, envVars and the registry
+// credentials already decrypted
+func deploymentJob(deployType string, deploymentID *string, service models.Service, organizationID string, envVars []redis.EnvironmentVariable, registryUsername, registryPassword string) redis.DeploymentJob {
 	// Create domains array
 	var domains []string
 	if service.Subdomain != nil && config.Get().AppDomain != "" {
 		domains = append(domains, *service.Subdomain+"."+config.Get().AppDomain)
 	}
-	if service.CustomDomain != nil {
+	// With a www redirect only the primary form of the custom domain is routed
+	redirectFrom, redirectTo, redirected := customdomain.Redirect(service)
+	if redirected {
+		domains = append(domains, redirectTo)
+	} else if service.CustomDomain != nil {
 		domains = append(domains, *service.CustomDomain)
 	}
 
@@ -268,19 +433,25 @@ func DeployService(deployType string, deploymentID *string, serviceID string) er
 		command = parseContainerCommand(*service.ContainerCommand)
 	}
 
+	// Arguments are passed to the entrypoint (or the command override) as-is
+	var args []string
+	if service.ContainerArgs != nil {
+		service.ContainerArgs.UnmarshalTo(&args)
+	}
+
 	// Build the deployment job
 	job := redis.DeploymentJob{
 		Type:           deployType,
 		ServiceType:    service.ServiceTypeID,
 		DeploymentID:   deploymentID,
-		ServiceID:      serviceID,
+		ServiceID:      service.ID,
 		ProjectID:      service.ProjectID,
-		OrganizationID: project.OrganizationID,
+		OrganizationID: organizationID,
 		ContainerRegistry: redis.ContainerRegistry{
 			Type:     utils.PtrValue(service.ContainerRegistryType, "ecr"),
 			ImageUri: utils.PtrValue(service.ContainerRegistryImageUri, ""),
-			Username: utils.PtrValue(service.ContainerRegistryUsername, ""),
-			Password: utils.PtrValue(service.ContainerRegistryPassword, ""),
+			Username: registryUsername,
+			Password: registryPassword,
 		},
 		EnvironmentVariables: envVars,
 		AutoScalingEnabled:   service.AutoScalingEnabled,
@@ -300,6 +471,22 @@ func DeployService(deployType string, deploymentID *string, serviceID string) er
 		Domains:            domains,
 		ScaleToZeroEnabled: scaleToZeroEnabled,
 		Command:            command,
+		Args:               args,
+		Region:             region.Of(service),
+	}
+
+	if redirected {
+		job.Redirect = &redis.Redirect{From: redirectFrom, To: redirectTo}
+	}
+
+	// Only the web proxy tunnels, redeploys keep what the tunnel endpoints set
+	if service.ServiceTypeID == "web" && service.TunnelEnabled && service.TunnelTokenHash != nil {
+		job.Tunnel = &redis.Tunnel{TokenHash: *service.TunnelTokenHash}
+	}
+
+	// Private services exposed through the ingress proxy carry their external port as a label
+	if service.IngressPort != nil {
+		job.IngressPort = *service.IngressPort
 	}
 
 	// Add probes for HTTP services
@@ -348,10 +535,5 @@ func DeployService(deployType string, deploymentID *string, serviceID string) er
 		}
 	}
 
-	// Add to deployment queue
-	if err := redis.AddToDeploymentQueue(ctx, job); err != nil {
-		return fmt.Errorf("failed to add job to deployment queue: %w", err)
-	}
-
-	return nil
+	return job
 }
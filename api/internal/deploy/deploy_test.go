@@ -0,0 +1,214 @@
+package deploy
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+func TestBuilderGitProviderGeneric(t *testing.T) {
+	encrypted, err := crypto.Encrypt("s3cret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	provider := builderGitProvider(&models.GitProvider{
+		Type:     models.GitProviderTypeCustom,
+		URL:      utils.Ptr("https://git.example.com"),
+		Username: utils.Ptr("deploy"),
+		Password: &encrypted,
+		// Left over from elsewhere, never sent for a generic provider
+		InstallationID: utils.Ptr("123"),
+	})
+
+	payload, err := json.Marshal(provider)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	// The builder clones over HTTPS with the decrypted password
+	want := `{"type":"CUSTOM","url":"https://git.example.com","username":"deploy","password":"s3cret"}`
+	if string(payload) != want {
+		t.Errorf("payload = %s, want %s", payload, want)
+	}
+
+	// Passwords stored before encryption was enabled are sent as they are
+	provider = builderGitProvider(&models.GitProvider{Type: models.GitProviderTypeCustom, Password: utils.Ptr("plain")})
+	if provider.Password == nil || *provider.Password != "plain" {
+		t.Errorf("plaintext password = %v, want plain", provider.Password)
+	}
+}
+
+func TestBuilderGitProviderGitHub(t *testing.T) {
+	provider := builderGitProvider(&models.GitProvider{
+		Type:           models.GitProviderTypeGitHub,
+		InstallationID: utils.Ptr("123"),
+		URL:            utils.Ptr("https://github.com"),
+		Password:       utils.Ptr("unused"),
+		GithubAccount:  &models.GithubAccount{Username: "octocat", AccessToken: "token"},
+	})
+
+	payload, err := json.Marshal(provider)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"type":"GITHUB","installationId":"123","githubAccount":{"username":"octocat","accessToken":"token"}}`
+	if string(payload) != want {
+		t.Errorf("payload = %s, want %s", payload, want)
+	}
+}
+
+func TestDeploymentJobCommandAndArgs(t *testing.T) {
+	service := models.Service{
+		ID:               "svc1",
+		ServiceTypeID:    "web",
+		ContainerCommand: utils.Ptr("node server.js"),
+		ContainerArgs:    models.JSON(`["--port","8080"]`),
+	}
+
+	job := deploymentJob("deploy", nil, service, "org1", nil, "", "")
+	if strings.Join(job.Command, " ") != "node server.js" || len(job.Command) != 2 {
+		t.Errorf("command = %q, want [node server.js]", job.Command)
+	}
+	if len(job.Args) != 2 || job.Args[0] != "--port" || job.Args[1] != "8080" {
+		t.Errorf("args = %q, want [--port 8080]", job.Args)
+	}
+
+	// Without overrides the image's entrypoint and arguments are used
+	job = deploymentJob("deploy", nil, models.Service{ID: "svc1", ServiceTypeID: "web"}, "org1", nil, "", "")
+	if job.Command != nil || job.Args != nil {
+		t.Errorf("job without overrides has command %q and args %q", job.Command, job.Args)
+	}
+}
+
+func TestDeploymentJobKeepsHTTPPortWithMetricsPort(t *testing.T) {
+	service := models.Service{
+		ID:            "svc1",
+		ServiceTypeID: "web",
+		Ports: []models.ServicePort{
+			{ServicePort: 80, ContainerPort: 3000},
+			{ServicePort: 9090, ContainerPort: 9091},
+		},
+	}
+
+	job := deploymentJob("deploy", nil, service, "org1", nil, "", "")
+	if len(job.Ports) != 2 || job.Ports[0] != (redis.Port{ServicePort: 80, ContainerPort: 3000}) || job.Ports[1] != (redis.Port{ServicePort: 9090, ContainerPort: 9091}) {
+		t.Errorf("ports = %v, want 80 and 9090", job.Ports)
+	}
+	// PORT and the probes follow the HTTP entry, not the metrics port
+	if job.ReadinessProbe == nil || job.ReadinessProbe.HTTPGet.Port != 3000 {
+		t.Errorf("readiness probe = %+v, want port 3000", job.ReadinessProbe)
+	}
+	var port string
+	for _, env := range job.EnvironmentVariables {
+		if env.Key == "PORT" {
+			port = env.Value
+		}
+	}
+	if port != "3000" {
+		t.Errorf("PORT = %q, want 3000", port)
+	}
+}
+
+func TestDeploymentJobRedirectsWww(t *testing.T) {
+	tests := []struct {
+		name     string
+		service  models.Service
+		domain   string
+		redirect *redis.Redirect
+	}{
+		{
+			name:    "no redirect",
+			service: models.Service{ID: "svc1", ServiceTypeID: "web", CustomDomain: utils.Ptr("example.com")},
+			domain:  "example.com",
+		},
+		{
+			name:     "apex primary",
+			service:  models.Service{ID: "svc1", ServiceTypeID: "web", CustomDomain: utils.Ptr("example.com"), RedirectWww: true},
+			domain:   "example.com",
+			redirect: &redis.Redirect{From: "www.example.com", To: "example.com"},
+		},
+		{
+			name: "www primary",
+			service: models.Service{ID: "svc1", ServiceTypeID: "web", CustomDomain: utils.Ptr("example.com"), RedirectWww: true,
+				PrimaryDomain: utils.Ptr("www.example.com")},
+			domain:   "www.example.com",
+			redirect: &redis.Redirect{From: "example.com", To: "www.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := deploymentJob("deploy", nil, tt.service, "org1", nil, "", "")
+			// Only the primary form is routed, the other one redirects to it
+			if len(job.Domains) != 1 || job.Domains[0] != tt.domain {
+				t.Errorf("domains = %v, want [%s]", job.Domains, tt.domain)
+			}
+			if (job.Redirect == nil) != (tt.redirect == nil) || (job.Redirect != nil && *job.Redirect != *tt.redirect) {
+				t.Errorf("redirect = %+v, want %+v", job.Redirect, tt.redirect)
+			}
+		})
+	}
+}
+
+func TestDeploymentJobTargetsRegion(t *testing.T) {
+	cfg := config.Get()
+	previous := cfg.Regions
+	cfg.Regions = []string{"eu-west", "us-east"}
+	t.Cleanup(func() { cfg.Regions = previous })
+
+	service := models.Service{ID: "svc1", ServiceTypeID: "web", Region: utils.Ptr("us-east")}
+	if job := deploymentJob("deploy", nil, service, "org1", nil, "", ""); job.Region != "us-east" {
+		t.Errorf("job region = %q, want the service's us-east", job.Region)
+	}
+
+	// Services created before regions were enabled deploy to the default region
+	service.Region = nil
+	if job := deploymentJob("deploy", nil, service, "org1", nil, "", ""); job.Region != "eu-west" {
+		t.Errorf("job region = %q, want the default eu-west", job.Region)
+	}
+}
+
+func TestDeployServiceDecryptsOnlySecrets(t *testing.T) {
+	redisServer.FlushAll()
+	secret, err := crypto.Encrypt("s3cret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	// Plain config is sent as it is stored, even when it happens to decrypt
+	plain, err := crypto.Encrypt("not-this")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	envVars, _ := json.Marshal([]crypto.EnvironmentVariable{
+		{Key: "DB_PASSWORD", Value: secret, IsSecret: utils.Ptr(true)},
+		{Key: "LEGACY_TOKEN", Value: secret},
+		{Key: "BLOB", Value: plain, IsSecret: utils.Ptr(false)},
+	})
+
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1", "serviceTypeId": "private", "environmentVariables": envVars})
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+
+	if err := DeployService("deploy", nil, "svc1"); err != nil {
+		t.Fatalf("DeployService: %v", err)
+	}
+	jobs := queuedDeploymentJobs(t)
+	if len(jobs) != 1 {
+		t.Fatalf("queued jobs = %v, want 1", jobs)
+	}
+	want := []redis.EnvironmentVariable{
+		{Key: "DB_PASSWORD", Value: "s3cret"},
+		{Key: "LEGACY_TOKEN", Value: "s3cret"},
+		{Key: "BLOB", Value: plain},
+	}
+	if got := jobs[0].EnvironmentVariables; !reflect.DeepEqual(got, want) {
+		t.Errorf("env vars = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package deploy
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// dockerignorePattern is a line of a .dockerignore
+type dockerignorePattern struct {
+	regex     *regexp.Regexp
+	exclusion bool
+}
+
+// dockerignore matches paths like the Docker CLI does when it sends the build context
+type dockerignore []dockerignorePattern
+
+// parseDockerignore parses the patterns of a .dockerignore. Patterns that don't compile are
+// skipped, the Docker CLI fails the build on them.
+func parseDockerignore(content []byte) dockerignore {
+	var patterns dockerignore
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		exclusion := strings.HasPrefix(line, "!")
+		if exclusion {
+			line = strings.TrimSpace(line[1:])
+		}
+		// Patterns are relative to the root of the context, "/" and ".." can't leave it
+		line = strings.TrimPrefix(path.Clean("/"+line), "/")
+		if line == "" {
+			continue
+		}
+
+		regex, err := regexp.Compile(patternRegex(line))
+		if err != nil {
+			continue
+		}
+		patterns = append(patterns, dockerignorePattern{regex: regex, exclusion: exclusion})
+	}
+	return patterns
+}
+
+// patternRegex translates a .dockerignore pattern to a regular expression: "*" and "?"
+// don't match "/", "**" matches any number of directories
+func patternRegex(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// ignored reports whether a path of the repository is left out of the build context. The last
+// pattern matching the path or one of its parent directories decides.
+func (d dockerignore) ignored(file string) bool {
+	ignored := false
+	for _, p := range d {
+		match := p.regex.MatchString(file)
+		for dir := path.Dir(file); !match && dir != "."; dir = path.Dir(dir) {
+			match = p.regex.MatchString(dir)
+		}
+		if match {
+			ignored = !p.exclusion
+		}
+	}
+	return ignored
+}
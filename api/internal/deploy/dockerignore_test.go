@@ -0,0 +1,49 @@
+package deploy
+
+import "testing"
+
+func TestDockerignoreIgnored(t *testing.T) {
+	ignore := parseDockerignore([]byte(`# build output
+/dist
+*.log
+**/node_modules
+docs/??.md
+!docs/en.md
+tmp/[!k]*
+../outside
+`))
+
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{path: "dist", ignored: true},
+		{path: "dist/app.js", ignored: true},
+		{path: "src/dist", ignored: false},
+		{path: "error.log", ignored: true},
+		{path: "logs/error.log", ignored: false},
+		{path: "node_modules/express/index.js", ignored: true},
+		{path: "packages/web/node_modules", ignored: true},
+		{path: "docs/de.md", ignored: true},
+		{path: "docs/guide.md", ignored: false},
+		// The last matching pattern decides
+		{path: "docs/en.md", ignored: false},
+		{path: "tmp/cache", ignored: true},
+		{path: "tmp/keep", ignored: false},
+		// Patterns can't leave the context
+		{path: "outside", ignored: true},
+		{path: "src/index.js", ignored: false},
+	}
+	for _, tt := range tests {
+		if got := ignore.ignored(tt.path); got != tt.ignored {
+			t.Errorf("ignored(%q) = %v, want %v", tt.path, got, tt.ignored)
+		}
+	}
+}
+
+func TestParseDockerignoreSkipsInvalidPatterns(t *testing.T) {
+	ignore := parseDockerignore([]byte("\n  \n# comment\n[z-a]\n/\nvendor\n"))
+	if len(ignore) != 1 || !ignore.ignored("vendor/lib.go") {
+		t.Errorf("patterns = %d, want only vendor", len(ignore))
+	}
+}
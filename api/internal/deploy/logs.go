@@ -0,0 +1,161 @@
+package deploy
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+)
+
+const (
+	// Logs over the size cap are trimmed down to this share of it, so a build at the cap
+	// doesn't trim on every line
+	logTrimTarget = 0.9
+
+	// How long the log size counter of a deployment lives after its last line
+	logBytesTTL = 24 * time.Hour
+
+	// How often old deployment logs are deleted, and how many rows one statement deletes
+	logRetentionInterval = time.Hour
+	logRetentionBatch    = 5000
+)
+
+// logRow is the id and size of a stored log line
+type logRow struct {
+	ID   int   `gorm:"column:id"`
+	Size int64 `gorm:"column:size"`
+}
+
+// CapLogs counts a log line of added bytes towards its deployment's size cap and, once the
+// logs exceed it, deletes the oldest lines. It returns how many lines were deleted.
+func CapLogs(ctx context.Context, deploymentID string, added int) (int, error) {
+	db := database.GetDatabase()
+
+	maxBytes := int64(config.Get().DeploymentLogMaxKB) * 1024
+	if maxBytes <= 0 {
+		return 0, nil
+	}
+
+	size, err := redis.AddDeploymentLogBytes(ctx, deploymentID, int64(added), logBytesTTL)
+	if err != nil {
+		return 0, err
+	}
+
+	// The counter was missing, e.g. it expired or Redis was flushed, so count what is stored
+	if size == int64(added) {
+		if err := db.Model(&models.DeploymentLog{}).
+			Select("COALESCE(SUM(LENGTH(text)), 0)").
+			Where("deploymentId = ?", deploymentID).
+			Scan(&size).Error; err != nil {
+			return 0, err
+		}
+		if err := redis.SetDeploymentLogBytes(ctx, deploymentID, size, logBytesTTL); err != nil {
+			return 0, err
+		}
+	}
+
+	if size <= maxBytes {
+		return 0, nil
+	}
+
+	// Find the oldest lines that bring the logs back under the target
+	excess := size - int64(float64(maxBytes)*logTrimTarget)
+	var freed int64
+	var deleted, lastID int
+	for freed < excess {
+		var rows []logRow
+		if err := db.Model(&models.DeploymentLog{}).
+			Select("id, LENGTH(text) AS size").
+			Where("deploymentId = ? AND id > ?", deploymentID, lastID).
+			Order("id ASC").
+			Limit(1000).
+			Scan(&rows).Error; err != nil {
+			return 0, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		for _, row := range rows {
+			freed += row.Size
+			deleted++
+			lastID = row.ID
+			if freed >= excess {
+				break
+			}
+		}
+	}
+
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	if err := db.Where("deploymentId = ? AND id <= ?", deploymentID, lastID).
+		Delete(&models.DeploymentLog{}).Error; err != nil {
+		return 0, err
+	}
+	if _, err := redis.AddDeploymentLogBytes(ctx, deploymentID, -freed, logBytesTTL); err != nil {
+		return deleted, err
+	}
+
+	return deleted, nil
+}
+
+// StartLogRetention deletes the logs of deployments older than the configured retention
+// right away and then every logRetentionInterval, until ctx is cancelled
+func StartLogRetention(ctx context.Context) {
+	days := config.Get().DeploymentLogRetentionDays
+	if days <= 0 {
+		log.Println("Deployment log retention is disabled")
+		return
+	}
+
+	log.Printf("Deleting deployment logs older than %d days", days)
+
+	ticker := time.NewTicker(logRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := DeleteExpiredLogs(ctx, time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			log.Printf("Failed to delete expired deployment logs: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Deleted %d expired deployment log lines", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeleteExpiredLogs deletes the logs of deployments created before cutoff, in batches so
+// the table isn't locked for long, and returns how many lines were deleted
+func DeleteExpiredLogs(ctx context.Context, cutoff time.Time) (int64, error) {
+	db := database.GetDatabase().WithContext(ctx)
+
+	expired := db.Model(&models.Deployment{}).
+		Select("id").
+		Where("createdAt < ?", cutoff)
+
+	var total int64
+	for {
+		result := db.Where("deploymentId IN (?)", expired).
+			Limit(logRetentionBatch).
+			Delete(&models.DeploymentLog{})
+		if result.Error != nil {
+			return total, result.Error
+		}
+
+		total += result.RowsAffected
+		if result.RowsAffected < logRetentionBatch {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package deploy
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/redis"
+)
+
+func useLogMaxKB(t *testing.T, kb int) {
+	t.Helper()
+	cfg := config.Get()
+	previous := cfg.DeploymentLogMaxKB
+	cfg.DeploymentLogMaxKB = kb
+	t.Cleanup(func() { cfg.DeploymentLogMaxKB = previous })
+}
+
+// logRows returns stored log lines with ids from 1, one per size
+func logRows(sizes ...int64) []dbtest.Row {
+	rows := make([]dbtest.Row, len(sizes))
+	for i, size := range sizes {
+		rows[i] = dbtest.Row{"id": int64(i + 1), "size": size}
+	}
+	return rows
+}
+
+func logBytes(t *testing.T, deploymentID string) string {
+	t.Helper()
+	size, err := redisServer.Get("deployment:log-bytes:" + deploymentID)
+	if err != nil {
+		t.Fatalf("log size counter: %v", err)
+	}
+	return size
+}
+
+func TestCapLogsUnderCap(t *testing.T) {
+	redisServer.FlushAll()
+	useLogMaxKB(t, 1)
+	db := dbtest.New(t)
+	ctx := context.Background()
+
+	if err := redis.SetDeploymentLogBytes(ctx, "dep1", 500, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := CapLogs(ctx, "dep1", 100)
+	if err != nil || deleted != 0 {
+		t.Fatalf("CapLogs = %d, %v, want nothing deleted", deleted, err)
+	}
+	if size := logBytes(t, "dep1"); size != "600" {
+		t.Errorf("log size = %s, want 600", size)
+	}
+	if len(db.Statements("DELETE")) != 0 {
+		t.Error("deleted logs under the cap")
+	}
+}
+
+func TestCapLogsTrimsOldestLines(t *testing.T) {
+	redisServer.FlushAll()
+	useLogMaxKB(t, 1)
+	db := dbtest.New(t)
+	db.OnQuery("LENGTH(text) AS size", logRows(100, 100, 100, 100, 100)...)
+	ctx := context.Background()
+
+	if err := redis.SetDeploymentLogBytes(ctx, "dep1", 1000, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// 1200 bytes over a 1024 cap are trimmed to 921, so the 3 oldest lines go
+	deleted, err := CapLogs(ctx, "dep1", 200)
+	if err != nil || deleted != 3 {
+		t.Fatalf("CapLogs = %d, %v, want 3 lines deleted", deleted, err)
+	}
+
+	deletes := db.Statements("DELETE FROM `DeploymentLog`")
+	if len(deletes) != 1 {
+		t.Fatalf("sent %d deletes, want 1", len(deletes))
+	}
+	if args := deletes[0].Args; len(args) != 2 || args[0] != "dep1" || fmt.Sprint(args[1]) != "3" {
+		t.Errorf("delete args = %v, want the lines of dep1 up to id 3", args)
+	}
+	if size := logBytes(t, "dep1"); size != "900" {
+		t.Errorf("log size = %s, want 900 after freeing 300 bytes", size)
+	}
+}
+
+func TestCapLogsRecountsMissingCounter(t *testing.T) {
+	redisServer.FlushAll()
+	useLogMaxKB(t, 1)
+	db := dbtest.New(t)
+	db.OnQuery("SUM(LENGTH(text))", dbtest.Row{"total": int64(1200)})
+	db.OnQuery("LENGTH(text) AS size", logRows(400, 400, 400)...)
+
+	deleted, err := CapLogs(context.Background(), "dep1", 200)
+	if err != nil || deleted != 1 {
+		t.Fatalf("CapLogs = %d, %v, want 1 line deleted", deleted, err)
+	}
+	if len(db.Statements("SUM(LENGTH(text))")) != 1 {
+		t.Error("a missing counter was not recounted from the stored logs")
+	}
+	if size := logBytes(t, "dep1"); size != "800" {
+		t.Errorf("log size = %s, want the recounted 1200 less 400 freed", size)
+	}
+}
+
+func TestCapLogsDisabled(t *testing.T) {
+	redisServer.FlushAll()
+	useLogMaxKB(t, 0)
+	db := dbtest.New(t)
+
+	deleted, err := CapLogs(context.Background(), "dep1", 1<<20)
+	if err != nil || deleted != 0 {
+		t.Fatalf("CapLogs = %d, %v, want nothing deleted", deleted, err)
+	}
+	if redisServer.Exists("deployment:log-bytes:dep1") {
+		t.Error("counted log bytes without a cap")
+	}
+	if len(db.Statements("")) != 0 {
+		t.Error("queried the database without a cap")
+	}
+}
+
+func TestDeleteExpiredLogsInBatches(t *testing.T) {
+	db := dbtest.New(t)
+	batches := []int64{logRetentionBatch, logRetentionBatch, 120}
+	db.OnExecFunc("DELETE FROM `DeploymentLog`", func(args []driver.Value) int64 {
+		n := batches[0]
+		batches = batches[1:]
+		return n
+	})
+	cutoff := time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC)
+
+	deleted, err := DeleteExpiredLogs(context.Background(), cutoff)
+	if err != nil {
+		t.Fatalf("DeleteExpiredLogs: %v", err)
+	}
+	if deleted != 2*logRetentionBatch+120 {
+		t.Errorf("deleted %d lines, want %d", deleted, 2*logRetentionBatch+120)
+	}
+
+	deletes := db.Statements("DELETE FROM `DeploymentLog`")
+	if len(deletes) != 3 {
+		t.Fatalf("sent %d deletes, want a batch until one is short", len(deletes))
+	}
+	for _, statement := range deletes {
+		if !strings.Contains(statement.SQL, "createdAt <") || !strings.Contains(statement.SQL, "LIMIT 5000") {
+			t.Errorf("delete %s is not a batch of the logs of expired deployments", statement.SQL)
+		}
+		if len(statement.Args) != 1 || statement.Args[0] != cutoff {
+			t.Errorf("delete args = %v, want the cutoff", statement.Args)
+		}
+	}
+}
+
+func TestDeleteExpiredLogsNothingExpired(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnExec("DELETE FROM `DeploymentLog`", 0)
+
+	deleted, err := DeleteExpiredLogs(context.Background(), time.Now())
+	if err != nil || deleted != 0 {
+		t.Errorf("DeleteExpiredLogs = %d, %v, want nothing deleted", deleted, err)
+	}
+	if n := len(db.Statements("DELETE FROM `DeploymentLog`")); n != 1 {
+		t.Errorf("sent %d deletes, want 1", n)
+	}
+}
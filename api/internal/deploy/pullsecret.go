@@ -0,0 +1,81 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/kubernetes"
+)
+
+// PullSecretName is the image pull secret of a service, shared with the ECR token renewal
+func PullSecretName(serviceID string) string {
+	return serviceID + "-container-registry-secret"
+}
+
+// RegistryHost returns the registry an image is pulled from. Images without a registry
+// domain, like "nginx" or "user/app", come from Docker Hub.
+func RegistryHost(imageUri string) string {
+	idx := strings.Index(imageUri, "/")
+	if idx == -1 {
+		return "docker.io"
+	}
+
+	host := imageUri[:idx]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return "docker.io"
+	}
+	return host
+}
+
+// ensurePullSecret creates or updates the pull secret of a service that pulls from a
+// private registry with a username and password, so credential changes apply on the next
+// deployment. It returns the name of the secret, empty when the service needs none. ECR
+// secrets are created from a fresh token by Kubestrator instead.
+func ensurePullSecret(ctx context.Context, service models.Service, username, password string) (string, error) {
+	if service.ContainerRegistryType == nil || *service.ContainerRegistryType != "docker" {
+		return "", nil
+	}
+	if service.ContainerRegistryImageUri == nil || password == "" {
+		return "", nil
+	}
+
+	name := PullSecretName(service.ID)
+	if err := kubernetes.CreateDockerConfigSecret(ctx, region.Of(service), name, service.ProjectID, RegistryHost(*service.ContainerRegistryImageUri), username, password); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// registryCredentials decrypts the registry username and password of a service. Values
+// stored before they were encrypted are encrypted in place.
+func registryCredentials(service models.Service) (string, string) {
+	updates := map[string]interface{}{}
+	for column, value := range map[string]*string{
+		"containerRegistryUsername": service.ContainerRegistryUsername,
+		"containerRegistryPassword": service.ContainerRegistryPassword,
+	} {
+		if value == nil || *value == "" || crypto.IsEncrypted(*value) {
+			continue
+		}
+		encrypted, err := crypto.Encrypt(*value)
+		if err != nil {
+			fmt.Printf("Failed to encrypt %s of service %s: %v\n", column, service.ID, err)
+			continue
+		}
+		updates[column] = encrypted
+	}
+	if len(updates) > 0 {
+		if err := database.GetDatabase().Model(&models.Service{}).Where("id = ?", service.ID).Updates(updates).Error; err != nil {
+			fmt.Printf("Failed to encrypt registry credentials of service %s: %v\n", service.ID, err)
+		}
+	}
+
+	return utils.PtrValue(crypto.DecryptOptional(service.ContainerRegistryUsername), ""),
+		utils.PtrValue(crypto.DecryptOptional(service.ContainerRegistryPassword), "")
+}
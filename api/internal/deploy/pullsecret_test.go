@@ -0,0 +1,98 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+func TestRegistryHost(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                         "docker.io",
+		"nginx:1.27":                    "docker.io",
+		"acme/app:v1":                   "docker.io",
+		"ghcr.io/acme/app:v1":           "ghcr.io",
+		"registry.example.com:5000/app": "registry.example.com:5000",
+		"localhost/app":                 "localhost",
+		"localhost:5000/app":            "localhost:5000",
+		"123.dkr.ecr.eu-west-1.amazonaws.com/app": "123.dkr.ecr.eu-west-1.amazonaws.com",
+	}
+	for image, want := range tests {
+		if got := RegistryHost(image); got != want {
+			t.Errorf("RegistryHost(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestEnsurePullSecretWithoutCredentials(t *testing.T) {
+	image := utils.Ptr("registry.example.com/app:v1")
+	tests := []struct {
+		name     string
+		service  models.Service
+		password string
+	}{
+		{name: "public image", service: models.Service{ID: "svc1", ContainerRegistryImageUri: image}, password: "s3cret"},
+		{name: "ecr registry", service: models.Service{ID: "svc1", ContainerRegistryType: utils.Ptr("ecr"), ContainerRegistryImageUri: image}, password: "s3cret"},
+		{name: "no password", service: models.Service{ID: "svc1", ContainerRegistryType: utils.Ptr("docker"), ContainerRegistryImageUri: image}},
+		{name: "no image", service: models.Service{ID: "svc1", ContainerRegistryType: utils.Ptr("docker")}, password: "s3cret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No cluster is reached for a service that needs no pull secret
+			name, err := ensurePullSecret(context.Background(), tt.service, "user", tt.password)
+			if err != nil || name != "" {
+				t.Errorf("ensurePullSecret = %q, %v, want no pull secret", name, err)
+			}
+		})
+	}
+}
+
+func TestRegistryCredentialsDecrypts(t *testing.T) {
+	db := dbtest.New(t)
+	username, _ := crypto.Encrypt("deployer")
+	password, _ := crypto.Encrypt("s3cret")
+
+	gotUsername, gotPassword := registryCredentials(models.Service{
+		ID: "svc1", ContainerRegistryUsername: &username, ContainerRegistryPassword: &password,
+	})
+	if gotUsername != "deployer" || gotPassword != "s3cret" {
+		t.Errorf("registryCredentials = %q, %q, want the decrypted credentials", gotUsername, gotPassword)
+	}
+	if updates := db.Statements("UPDATE `Service`"); len(updates) != 0 {
+		t.Errorf("updated encrypted credentials: %v", updates)
+	}
+}
+
+func TestRegistryCredentialsEncryptsPlaintext(t *testing.T) {
+	db := dbtest.New(t)
+
+	username, password := registryCredentials(models.Service{
+		ID: "svc1", ContainerRegistryUsername: utils.Ptr("deployer"), ContainerRegistryPassword: utils.Ptr("s3cret"),
+	})
+	if username != "deployer" || password != "s3cret" {
+		t.Errorf("registryCredentials = %q, %q, want the stored credentials", username, password)
+	}
+
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 1 {
+		t.Fatalf("sent %d updates, want the plaintext credentials encrypted in place", len(updates))
+	}
+	var encrypted []string
+	for _, arg := range updates[0].Args {
+		if s, ok := arg.(string); ok && s != "svc1" {
+			encrypted = append(encrypted, s)
+		}
+	}
+	if len(encrypted) != 2 {
+		t.Fatalf("update args = %v, want both credentials", updates[0].Args)
+	}
+	for _, value := range encrypted {
+		if decrypted := crypto.DecryptWithFallback(value); !crypto.IsEncrypted(value) || (decrypted != "deployer" && decrypted != "s3cret") {
+			t.Errorf("stored %q, want an encrypted credential", value)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/customdomain"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/pkg/ecr"
+	"github.com/deployra/deployra/api/pkg/kubernetes"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// How often deleted services are reaped, and how many one pass reaps
+	reapInterval = 10 * time.Minute
+	reapBatch    = 100
+
+	// Only the instance leading the reaper reaps. It renews its term every reapInterval, when
+	// it stops another instance takes over once the term expires.
+	reaperLeaderKey = "service-reaper-leader"
+	reaperLeaderTTL = 3 * reapInterval
+
+	// The Kubernetes cleanup of a service whose reap keeps failing is queued again with
+	// exponential backoff, reapCleanupAttempts times at most
+	reapCleanupBackoff  = reapInterval
+	reapCleanupAttempts = 5
+	reapCleanupTTL      = 7 * 24 * time.Hour
+)
+
+// deleteCertificate deletes the certificate secret of a domain in the web proxy of a region
+var deleteCertificate = kubernetes.DeleteCertificate
+
+// StartServiceReaper hard deletes services once their restore window has passed, every
+// reapInterval until ctx is cancelled, while this instance leads the reaper
+func StartServiceReaper(ctx context.Context) {
+	holder := uuid.NewString()
+	defer func() {
+		if err := redis.ReleaseLeadership(context.Background(), reaperLeaderKey, holder); err != nil {
+			log.Printf("Failed to release service reaper leadership: %v", err)
+		}
+	}()
+
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	for {
+		leader, err := redis.AcquireLeadership(ctx, reaperLeaderKey, holder, reaperLeaderTTL)
+		if err != nil {
+			log.Printf("Failed to acquire service reaper leadership: %v", err)
+		} else if leader {
+			reaped, err := ReapDeletedServices(ctx, time.Now())
+			if err != nil {
+				log.Printf("Failed to reap deleted services: %v", err)
+			} else if reaped > 0 {
+				log.Printf("Reaped %d deleted services", reaped)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReapDeletedServices hard deletes the services deleted longer than the restore window ago,
+// as of now, with what they left behind, and returns how many were reaped
+func ReapDeletedServices(ctx context.Context, now time.Time) (int, error) {
+	db := database.GetDatabase().WithContext(ctx)
+
+	cutoff := now.Add(-time.Duration(config.Get().ServiceRestoreWindowHours) * time.Hour)
+
+	var services []models.Service
+	if err := db.Where("deletedAt IS NOT NULL AND deletedAt < ?", cutoff).
+		Order("deletedAt").
+		Limit(reapBatch).
+		Find(&services).Error; err != nil {
+		return 0, err
+	}
+
+	reaped := 0
+	for _, service := range services {
+		if err := reapService(ctx, service, cutoff, now); err != nil {
+			log.Printf("Failed to reap service %s: %v", service.ID, err)
+			continue
+		}
+		reaped++
+	}
+
+	return reaped, nil
+}
+
+// reapService removes the Kubernetes resources, ECR repository and certificates of a deleted
+// service, then its rows. The rows go last so a failed cleanup is retried on the next pass.
+func reapService(ctx context.Context, service models.Service, cutoff, now time.Time) error {
+	db := database.GetDatabase().WithContext(ctx)
+
+	// Deleting the service removed its Kubernetes resources, queue it again in case that failed
+	if err := queueReapCleanup(ctx, service.ID, now); err != nil {
+		return err
+	}
+
+	if service.ContainerRegistryImageUri != nil && strings.Contains(*service.ContainerRegistryImageUri, ".ecr.") {
+		if err := ecr.DeleteRepository(service.ID); err != nil {
+			return fmt.Errorf("failed to delete ECR repository: %w", err)
+		}
+	}
+
+	// Certificates of custom domains another service uses since are kept
+	for _, domain := range reapedDomains(service) {
+		available, err := customdomain.IsAvailable(db, domain, service.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check domain %s: %w", domain, err)
+		}
+		if !available {
+			continue
+		}
+		if err := deleteCertificate(ctx, region.Of(service), domain); err != nil {
+			return fmt.Errorf("failed to delete certificate of %s: %w", domain, err)
+		}
+	}
+
+	// Deleting the service cascades to its other rows, deployment logs have to go first
+	deleted := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("deploymentId IN (?)", tx.Model(&models.Deployment{}).Select("id").Where("serviceId = ?", service.ID)).
+			Delete(&models.DeploymentLog{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ? AND deletedAt IS NOT NULL AND deletedAt < ?", service.ID, cutoff).Delete(&models.Service{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected > 0
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete rows: %w", err)
+	}
+
+	if deleted {
+		log.Printf("[Audit] Reaped service %s (%s) of project %s, deleted at %s", service.ID, service.Name, service.ProjectID, service.DeletedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// queueReapCleanup queues the Kubernetes cleanup of a reaped service if it is due as of now
+func queueReapCleanup(ctx context.Context, serviceID string, now time.Time) error {
+	cleanup, err := redis.GetReapCleanup(ctx, serviceID)
+	if err != nil {
+		return fmt.Errorf("failed to read Kubernetes cleanup attempts: %w", err)
+	}
+	if !reapCleanupDue(cleanup, now) {
+		return nil
+	}
+
+	if err := DeployService("delete-service", nil, serviceID); err != nil {
+		return fmt.Errorf("failed to queue Kubernetes cleanup: %w", err)
+	}
+
+	cleanup.Attempts++
+	cleanup.LastAt = now
+	if cleanup.Attempts == reapCleanupAttempts {
+		log.Printf("Queued the Kubernetes cleanup of service %s %d times, it isn't queued again", serviceID, cleanup.Attempts)
+	}
+	if err := redis.SetReapCleanup(ctx, serviceID, cleanup, reapCleanupTTL); err != nil {
+		return fmt.Errorf("failed to record Kubernetes cleanup attempt: %w", err)
+	}
+	return nil
+}
+
+// reapCleanupDue reports whether the Kubernetes cleanup of a reaped service is queued again as
+// of now. The wait doubles after every attempt, starting at reapCleanupBackoff.
+func reapCleanupDue(cleanup redis.ReapCleanup, now time.Time) bool {
+	if cleanup.Attempts == 0 {
+		return true
+	}
+	if cleanup.Attempts >= reapCleanupAttempts {
+		return false
+	}
+
+	wait := reapCleanupBackoff << (cleanup.Attempts - 1)
+	return !now.Before(cleanup.LastAt.Add(wait))
+}
+
+// reapedDomains returns the domains the web proxy may hold certificates of for a service
+func reapedDomains(service models.Service) []string {
+	var domains []string
+	if service.Subdomain != nil && config.Get().AppDomain != "" {
+		domains = append(domains, *service.Subdomain+"."+config.Get().AppDomain)
+	}
+	if service.CustomDomain != nil {
+		domains = append(domains, *service.CustomDomain)
+		if service.RedirectWww {
+			domains = append(domains, customdomain.WwwVariant(*service.CustomDomain))
+		}
+	}
+	return domains
+}
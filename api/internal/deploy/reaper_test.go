@@ -0,0 +1,202 @@
+package deploy
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+func TestReapCleanupDue(t *testing.T) {
+	last := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		attempts int
+		after    time.Duration
+		want     bool
+	}{
+		{name: "never queued", attempts: 0, want: true},
+		{name: "first retry waits one pass", attempts: 1, after: reapCleanupBackoff - time.Second, want: false},
+		{name: "first retry after one pass", attempts: 1, after: reapCleanupBackoff, want: true},
+		{name: "third retry waits four passes", attempts: 3, after: 3 * reapCleanupBackoff, want: false},
+		{name: "third retry after four passes", attempts: 3, after: 4 * reapCleanupBackoff, want: true},
+		{name: "limit reached", attempts: reapCleanupAttempts, after: 30 * 24 * time.Hour, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanup := redis.ReapCleanup{Attempts: tt.attempts, LastAt: last}
+			if got := reapCleanupDue(cleanup, last.Add(tt.after)); got != tt.want {
+				t.Errorf("reapCleanupDue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueReapCleanupStopsAtLimit(t *testing.T) {
+	redisServer.FlushAll()
+	ctx := context.Background()
+	now := time.Now()
+
+	// Nothing is queued, queueing would need the service from the database
+	exhausted := redis.ReapCleanup{Attempts: reapCleanupAttempts, LastAt: now.Add(-30 * 24 * time.Hour)}
+	if err := redis.SetReapCleanup(ctx, "svc", exhausted, time.Hour); err != nil {
+		t.Fatalf("SetReapCleanup: %v", err)
+	}
+	if err := queueReapCleanup(ctx, "svc", now); err != nil {
+		t.Fatalf("queueReapCleanup: %v", err)
+	}
+
+	cleanup, err := redis.GetReapCleanup(ctx, "svc")
+	if err != nil {
+		t.Fatalf("GetReapCleanup: %v", err)
+	}
+	if cleanup.Attempts != reapCleanupAttempts {
+		t.Errorf("attempts = %d, want %d left as they were", cleanup.Attempts, reapCleanupAttempts)
+	}
+}
+
+// stubDeleteCertificate records the domains whose certificates are deleted. Each deletion
+// also records whether a row of the service was deleted before it.
+func stubDeleteCertificate(t *testing.T, db *dbtest.DB) *[]string {
+	t.Helper()
+	var deleted []string
+	previous := deleteCertificate
+	deleteCertificate = func(_ context.Context, _, domain string) error {
+		if len(db.Statements("DELETE FROM `Service`")) > 0 {
+			t.Errorf("certificate of %s deleted after the rows of its service", domain)
+		}
+		deleted = append(deleted, domain)
+		return nil
+	}
+	t.Cleanup(func() { deleteCertificate = previous })
+	return &deleted
+}
+
+// deletedServices answers the lookups of the reaper with services deleted at the given times,
+// the reaper's query only gets those deleted before its cutoff
+func deletedServices(db *dbtest.DB, deletedAt map[string]time.Time) {
+	db.OnQueryFunc("FROM `Service`", func(args []driver.Value) []dbtest.Row {
+		cutoff, ok := args[0].(time.Time)
+		if !ok {
+			// The lookup of the service whose cleanup is queued
+			return []dbtest.Row{{"id": args[0], "projectId": "proj1", "serviceTypeId": "web"}}
+		}
+		var rows []dbtest.Row
+		for id, at := range deletedAt {
+			if at.Before(cutoff) {
+				rows = append(rows, dbtest.Row{"id": id, "projectId": "proj1", "serviceTypeId": "web", "deletedAt": at})
+			}
+		}
+		return rows
+	})
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+}
+
+func TestReapDeletedServicesHonoursRestoreWindow(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	stubDeleteCertificate(t, db)
+	now := time.Now()
+	window := time.Duration(config.Get().ServiceRestoreWindowHours) * time.Hour
+	deletedServices(db, map[string]time.Time{
+		"recent": now.Add(-window + time.Hour),
+		"old":    now.Add(-window - time.Hour),
+	})
+
+	reaped, err := ReapDeletedServices(context.Background(), now)
+	if err != nil {
+		t.Fatalf("ReapDeletedServices: %v", err)
+	}
+	if reaped != 1 {
+		t.Errorf("reaped = %d, want 1", reaped)
+	}
+
+	deletes := db.Statements("DELETE FROM `Service`")
+	if len(deletes) != 1 || !containsArg(deletes[0], "old") {
+		t.Errorf("deletes = %v, want only the service deleted before the window", deletes)
+	}
+	// The delete checks the window again, in case the service was restored meanwhile
+	if len(deletes) == 1 && !reflect.DeepEqual(deletes[0].Args[1], now.Add(-window)) {
+		t.Errorf("delete args = %v, want the cutoff %v", deletes[0].Args, now.Add(-window))
+	}
+	for _, job := range queuedDeploymentJobs(t) {
+		if job.ServiceID != "old" {
+			t.Errorf("queued the cleanup of %s, want only old", job.ServiceID)
+		}
+	}
+}
+
+func TestReapServiceDeletesFreeCertificatesThenRows(t *testing.T) {
+	redisServer.FlushAll()
+	cfg := config.Get()
+	previousDomain := cfg.AppDomain
+	cfg.AppDomain = "apps.example.com"
+	t.Cleanup(func() { cfg.AppDomain = previousDomain })
+
+	db := dbtest.New(t)
+	certificates := stubDeleteCertificate(t, db)
+	deletedServices(db, nil)
+	// Another service uses the custom domain since
+	db.OnQueryFunc("count(*)", func(args []driver.Value) []dbtest.Row {
+		if fmt.Sprint(args[0]) == "shop.example.com" {
+			return []dbtest.Row{{"count(*)": int64(1)}}
+		}
+		return []dbtest.Row{{"count(*)": int64(0)}}
+	})
+
+	now := time.Now()
+	service := models.Service{
+		ID: "svc1", ProjectID: "proj1", Subdomain: utils.Ptr("shop"), CustomDomain: utils.Ptr("shop.example.com"),
+		DeletedAt: utils.Ptr(now.Add(-100 * time.Hour)),
+	}
+	if err := reapService(context.Background(), service, now.Add(-72*time.Hour), now); err != nil {
+		t.Fatalf("reapService: %v", err)
+	}
+
+	jobs := queuedDeploymentJobs(t)
+	if len(jobs) != 1 || jobs[0].Type != "delete-service" || jobs[0].ServiceID != "svc1" {
+		t.Errorf("queued jobs = %+v, want the deletion of svc1", jobs)
+	}
+	if !reflect.DeepEqual(*certificates, []string{"shop.apps.example.com"}) {
+		t.Errorf("deleted certificates = %v, want only the one of the free subdomain", *certificates)
+	}
+
+	// Deployment logs go before the service, whose deletion cascades to its other rows
+	deletes := db.Statements("DELETE FROM")
+	if len(deletes) != 2 || !strings.Contains(deletes[0].SQL, "`DeploymentLog`") || !strings.Contains(deletes[1].SQL, "`Service`") {
+		t.Fatalf("deletes = %v, want the deployment logs then the service", deletes)
+	}
+	if statements := db.Statements(""); statements[len(statements)-1].SQL != deletes[1].SQL {
+		t.Errorf("last statement = %s, want the rows deleted last", statements[len(statements)-1].SQL)
+	}
+}
+
+// queuedDeploymentJobs returns the jobs of the deployment queue
+func queuedDeploymentJobs(t *testing.T) []redis.DeploymentJob {
+	t.Helper()
+	if !redisServer.Exists(redis.QueueDeployment) {
+		return nil
+	}
+	items, err := redisServer.List(redis.QueueDeployment)
+	if err != nil {
+		t.Fatalf("read deployment queue: %v", err)
+	}
+	jobs := make([]redis.DeploymentJob, len(items))
+	for i, item := range items {
+		if err := json.Unmarshal([]byte(item), &jobs[i]); err != nil {
+			t.Fatalf("unmarshal queued job %s: %v", item, err)
+		}
+	}
+	return jobs
+}
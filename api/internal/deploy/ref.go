@@ -0,0 +1,140 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/pkg/github"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Abbreviated or full commit SHA
+var commitShaRegex = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// ResolveRef resolves a branch, tag or commit SHA of the repository of a service to the
+// commit to build. The service needs its GitProvider.GithubAccount preloaded. Returns
+// github.ErrRefNotFound when the repository has no such ref.
+func ResolveRef(ctx context.Context, service models.Service, ref string) (*github.Ref, error) {
+	if service.GitProvider == nil || service.RepositoryName == nil {
+		return nil, fmt.Errorf("service has no repository")
+	}
+
+	parts := strings.Split(*service.RepositoryName, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid repository name %q", *service.RepositoryName)
+	}
+	owner, repo := parts[0], parts[1]
+
+	provider := service.GitProvider
+	if provider.Type == models.GitProviderTypeGitHub {
+		client, err := repositoryClientFor(ctx, provider)
+		if err != nil {
+			return nil, err
+		}
+		return client.ResolveRef(ctx, owner, repo, ref)
+	}
+
+	return resolveRemoteRef(ctx, provider, owner, repo, ref)
+}
+
+// repositoryClient reads a GitHub repository, as github.Client does
+type repositoryClient interface {
+	contentLister
+	contextFiles
+	ResolveRef(ctx context.Context, owner, repo, ref string) (*github.Ref, error)
+	GetRepoDescription(ctx context.Context, owner, repo, branch, dockerfilePath string) (*github.RepoDescription, error)
+}
+
+// repositoryClientFor returns the client the repositories of a GitHub provider are read with
+var repositoryClientFor = func(ctx context.Context, provider *models.GitProvider) (repositoryClient, error) {
+	client, err := GitHubClient(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// GitHubClient authenticates with the installation of a GitHub provider, or the token of its
+// account when it has none
+func GitHubClient(ctx context.Context, provider *models.GitProvider) (*github.Client, error) {
+	if provider.InstallationID != nil && *provider.InstallationID != "" {
+		installationID, err := strconv.ParseInt(*provider.InstallationID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid installation ID")
+		}
+		return github.NewClientWithInstallation(ctx, installationID)
+	}
+
+	if provider.GithubAccount == nil {
+		return nil, fmt.Errorf("GitHub account not found for this provider")
+	}
+	account, err := github.EnsureValidGithubToken(provider.GithubAccount.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate with GitHub: %w", err)
+	}
+	return github.NewClientWithToken(ctx, account.AccessToken), nil
+}
+
+// resolveRemoteRef resolves a ref of a repository on a custom provider from the refs its
+// remote advertises, without cloning it
+func resolveRemoteRef(ctx context.Context, provider *models.GitProvider, owner, repo, ref string) (*github.Ref, error) {
+	if provider.URL == nil || provider.Username == nil || provider.Password == nil {
+		return nil, fmt.Errorf("missing required Git provider credentials")
+	}
+
+	parsedURL, err := url.Parse(*provider.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Git provider URL")
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{fmt.Sprintf("%s/%s/%s.git", parsedURL.String(), owner, repo)},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{
+		Auth: &http.BasicAuth{
+			Username: *provider.Username,
+			Password: crypto.DecryptWithFallback(*provider.Password),
+		},
+		// Annotated tags are listed with the commit they point at as <tag>^{}
+		PeelingOption: git.AppendPeeled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote refs: %w", err)
+	}
+
+	advertised := make(map[plumbing.ReferenceName]string, len(refs))
+	for _, r := range refs {
+		if r.Type() == plumbing.HashReference {
+			advertised[r.Name()] = r.Hash().String()
+		}
+	}
+
+	if sha, ok := advertised[plumbing.NewBranchReferenceName(ref)]; ok {
+		return &github.Ref{Name: ref, Kind: github.RefKindBranch, CommitSha: sha}, nil
+	}
+	tagName := plumbing.NewTagReferenceName(ref)
+	if sha, ok := advertised[tagName+"^{}"]; ok {
+		return &github.Ref{Name: ref, Kind: github.RefKindTag, CommitSha: sha}, nil
+	}
+	if sha, ok := advertised[tagName]; ok {
+		return &github.Ref{Name: ref, Kind: github.RefKindTag, CommitSha: sha}, nil
+	}
+
+	// Remotes only advertise refs, so a SHA can't be checked before the builder fetches it
+	if commitShaRegex.MatchString(ref) {
+		return &github.Ref{Name: ref, Kind: github.RefKindCommit, CommitSha: strings.ToLower(ref)}, nil
+	}
+
+	return nil, github.ErrRefNotFound
+}
@@ -0,0 +1,143 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/webhook"
+)
+
+// How often deployments are checked against their timeout
+const timeoutCheckInterval = time.Minute
+
+// deploymentTimeout returns how long a deployment of a service may stay BUILDING or
+// DEPLOYING, 0 for no limit
+func deploymentTimeout(instanceType models.InstanceType) time.Duration {
+	minutes := config.Get().DeploymentTimeoutMinutes
+	if instanceType.DeploymentTimeoutMinutes != nil {
+		minutes = *instanceType.DeploymentTimeoutMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// StartDeploymentWatchdog fails deployments that stay BUILDING or DEPLOYING past their
+// timeout, e.g. because the image can't be pulled and no status ever arrives, every
+// timeoutCheckInterval until ctx is cancelled
+func StartDeploymentWatchdog(ctx context.Context) {
+	ticker := time.NewTicker(timeoutCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		failed, err := FailTimedOutDeployments(ctx, time.Now())
+		if err != nil {
+			log.Printf("Failed to check deployment timeouts: %v", err)
+		} else if failed > 0 {
+			log.Printf("Failed %d timed out deployments", failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// FailTimedOutDeployments fails the deployments that have been BUILDING or DEPLOYING for
+// longer than the timeout of their service's instance type, as of now, and returns how
+// many were failed
+func FailTimedOutDeployments(ctx context.Context, now time.Time) (int, error) {
+	db := database.GetDatabase().WithContext(ctx)
+
+	var deployments []models.Deployment
+	if err := db.Preload("Service.InstanceType").
+		Preload("Service.Project.Organization").
+		Where("status IN ?", []models.DeploymentStatus{models.DeploymentStatusBuilding, models.DeploymentStatusDeploying}).
+		Find(&deployments).Error; err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for _, deployment := range deployments {
+		timeout := deploymentTimeout(deployment.Service.InstanceType)
+		// The status webhook sets updatedAt, so it is when the deployment entered its status
+		if timeout <= 0 || now.Sub(deployment.UpdatedAt) < timeout {
+			continue
+		}
+
+		ok, err := failTimedOut(ctx, deployment, timeout, now)
+		if err != nil {
+			log.Printf("Failed to fail timed out deployment %s: %v", deployment.ID, err)
+			continue
+		}
+		if ok {
+			failed++
+		}
+	}
+
+	return failed, nil
+}
+
+// failTimedOut marks a deployment failed unless its status changed in the meantime, and
+// records it like a failure reported by the builder or Kubestrator
+func failTimedOut(ctx context.Context, deployment models.Deployment, timeout time.Duration, now time.Time) (bool, error) {
+	db := database.GetDatabase()
+
+	// Only fail it if no status arrived since it was read
+	result := db.Model(&models.Deployment{}).
+		Where("id = ? AND status = ?", deployment.ID, deployment.Status).
+		Updates(map[string]interface{}{
+			"status":      models.DeploymentStatusFailed,
+			"completedAt": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	message := fmt.Sprintf("Deployment timed out after %d minutes %s", int(timeout.Minutes()), deploymentStage(deployment.Status))
+	log.Printf("Deployment %s of service %s: %s", deployment.ID, deployment.ServiceID, message)
+
+	db.Create(&models.DeploymentLog{
+		DeploymentID: deployment.ID,
+		Text:         message,
+		Type:         models.LogTypeError,
+	})
+	db.Create(&models.ServiceEvent{
+		ServiceID:    deployment.ServiceID,
+		Type:         models.EventTypeDeployFailed,
+		Message:      &message,
+		DeploymentID: &deployment.ID,
+	})
+
+	// A build may still be running, stop it and give its slot to the next held build
+	if deployment.Status == models.DeploymentStatusBuilding {
+		if err := redis.PublishBuilderCancellation(ctx, deployment.ID); err != nil {
+			log.Printf("Failed to publish builder cancellation: %v", err)
+		}
+	}
+	if HoldsBuildSlot(deployment.Status) {
+		go StartDeferredBuild(deployment.Service.Project.OrganizationID)
+	}
+
+	deployment.CompletedAt = &now
+	webhook.SendDeploymentStatus(deployment, string(models.DeploymentStatusFailed), message)
+
+	return true, nil
+}
+
+// deploymentStage describes the stage a deployment timed out in
+func deploymentStage(status models.DeploymentStatus) string {
+	if status == models.DeploymentStatusBuilding {
+		return "while building"
+	}
+	return "while deploying"
+}
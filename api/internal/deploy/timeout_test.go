@@ -0,0 +1,166 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+var watchdogNow = time.Date(2026, 9, 14, 12, 0, 0, 0, time.UTC)
+
+func useDeploymentTimeout(t *testing.T, minutes int) {
+	t.Helper()
+	cfg := config.Get()
+	previous := cfg.DeploymentTimeoutMinutes
+	cfg.DeploymentTimeoutMinutes = minutes
+	t.Cleanup(func() { cfg.DeploymentTimeoutMinutes = previous })
+}
+
+// activeDeployment returns a deployment of a service that entered status minutes before watchdogNow
+func activeDeployment(id, serviceID string, status models.DeploymentStatus, minutes int) dbtest.Row {
+	return dbtest.Row{
+		"id": id, "serviceId": serviceID, "status": string(status),
+		"updatedAt": watchdogNow.Add(-time.Duration(minutes) * time.Minute),
+	}
+}
+
+// watchdogServices answers the preloads of the watchdog with services of svc1 on instance
+// type it1 and svc2 on it2, in a project with a webhook the deliveries of which fail at once
+func watchdogServices(db *dbtest.DB, instanceTypes ...dbtest.Row) {
+	db.OnQuery("FROM `Service`",
+		dbtest.Row{"id": "svc1", "name": "web", "projectId": "proj1", "instanceTypeId": "it1"},
+		dbtest.Row{"id": "svc2", "name": "worker", "projectId": "proj1", "instanceTypeId": "it2"})
+	db.OnQuery("FROM `InstanceType`", instanceTypes...)
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1", "webhookUrl": "http://127.0.0.1/hook"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1"})
+}
+
+// waitForDeliveries waits until n webhook deliveries recorded their result
+func waitForDeliveries(t *testing.T, db *dbtest.DB, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(db.Statements("UPDATE `WebhookDelivery`")) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d webhook deliveries finished, want %d", len(db.Statements("UPDATE `WebhookDelivery`")), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFailTimedOutDeploymentsFailsStuckDeployment(t *testing.T) {
+	redisServer.FlushAll()
+	useDeploymentTimeout(t, 30)
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Deployment`",
+		activeDeployment("dep1", "svc1", models.DeploymentStatusDeploying, 31),
+		activeDeployment("dep2", "svc2", models.DeploymentStatusDeploying, 5))
+	watchdogServices(db, dbtest.Row{"id": "it1"}, dbtest.Row{"id": "it2"})
+
+	failed, err := FailTimedOutDeployments(context.Background(), watchdogNow)
+	if err != nil {
+		t.Fatalf("FailTimedOutDeployments: %v", err)
+	}
+	if failed != 1 {
+		t.Fatalf("failed %d deployments, want only the one past the timeout", failed)
+	}
+	waitForDeliveries(t, db, 1)
+
+	updates := db.Statements("UPDATE `Deployment`")
+	if len(updates) != 1 || !containsArg(updates[0], "dep1") {
+		t.Fatalf("updates = %v, want dep1 failed", updates)
+	}
+	// The deployment is only failed if it is still in the status it was read in
+	if !containsArg(updates[0], "FAILED") || !strings.Contains(updates[0].SQL, "status = ?") {
+		t.Errorf("update %s %v, want dep1 failed unless its status changed", updates[0].SQL, updates[0].Args)
+	}
+
+	message := "Deployment timed out after 30 minutes while deploying"
+	if logs := db.Statements("INSERT INTO `DeploymentLog`"); len(logs) != 1 || !containsArg(logs[0], message) {
+		t.Errorf("deployment logs = %v, want %q", logs, message)
+	}
+	events := db.Statements("INSERT INTO `ServiceEvent`")
+	if len(events) != 1 || fmt.Sprint(events[0].Args[1]) != string(models.EventTypeDeployFailed) {
+		t.Errorf("service events = %v, want a deploy failure", events)
+	}
+	deliveries := db.Statements("INSERT INTO `WebhookDelivery`")
+	if len(deliveries) != 1 {
+		t.Fatalf("recorded %d webhook deliveries, want 1", len(deliveries))
+	}
+	var payload string
+	for _, arg := range deliveries[0].Args {
+		if s := fmt.Sprintf("%s", arg); strings.HasPrefix(s, "{") {
+			payload = s
+		}
+	}
+	if !strings.Contains(payload, `"status":"FAILED"`) || !strings.Contains(payload, message) {
+		t.Errorf("webhook payload = %s, want the failure and its message", payload)
+	}
+}
+
+func TestFailTimedOutDeploymentsUsesPlanTimeout(t *testing.T) {
+	redisServer.FlushAll()
+	useDeploymentTimeout(t, 30)
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Deployment`",
+		activeDeployment("dep1", "svc1", models.DeploymentStatusBuilding, 60),
+		activeDeployment("dep2", "svc2", models.DeploymentStatusDeploying, 600))
+	// it1 allows 2 hours, it2 has no limit at all
+	watchdogServices(db, dbtest.Row{"id": "it1", "deploymentTimeoutMinutes": int64(120)},
+		dbtest.Row{"id": "it2", "deploymentTimeoutMinutes": int64(0)})
+
+	failed, err := FailTimedOutDeployments(context.Background(), watchdogNow)
+	if err != nil || failed != 0 {
+		t.Fatalf("FailTimedOutDeployments = %d, %v, want nothing failed", failed, err)
+	}
+	if updates := db.Statements("UPDATE `Deployment`"); len(updates) != 0 {
+		t.Errorf("updated deployments within the timeout of their plan: %v", updates)
+	}
+}
+
+func TestFailTimedOutDeploymentsSkipsChangedStatus(t *testing.T) {
+	redisServer.FlushAll()
+	useDeploymentTimeout(t, 30)
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Deployment`",
+		activeDeployment("dep1", "svc1", models.DeploymentStatusDeploying, 45),
+		activeDeployment("dep2", "svc2", models.DeploymentStatusDeploying, 5))
+	watchdogServices(db, dbtest.Row{"id": "it1"}, dbtest.Row{"id": "it2"})
+	// A status arrived between reading the deployment and failing it
+	db.OnExec("UPDATE `Deployment`", 0)
+
+	failed, err := FailTimedOutDeployments(context.Background(), watchdogNow)
+	if err != nil || failed != 0 {
+		t.Fatalf("FailTimedOutDeployments = %d, %v, want nothing failed", failed, err)
+	}
+	if events := db.Statements("INSERT INTO `ServiceEvent`"); len(events) != 0 {
+		t.Errorf("recorded a failure of a deployment that moved on: %v", events)
+	}
+	if deliveries := db.Statements("INSERT INTO `WebhookDelivery`"); len(deliveries) != 0 {
+		t.Errorf("sent a webhook for a deployment that moved on")
+	}
+}
+
+func TestTimeoutMessageNamesStage(t *testing.T) {
+	if got := deploymentStage(models.DeploymentStatusBuilding); got != "while building" {
+		t.Errorf("stage of a building deployment = %q", got)
+	}
+	if got := deploymentStage(models.DeploymentStatusDeploying); got != "while deploying" {
+		t.Errorf("stage of a deploying deployment = %q", got)
+	}
+}
+
+// containsArg reports whether a statement was sent with an argument printing as want
+func containsArg(statement dbtest.Statement, want string) bool {
+	for _, arg := range statement.Args {
+		if fmt.Sprint(arg) == want {
+			return true
+		}
+	}
+	return false
+}
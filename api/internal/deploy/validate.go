@@ -0,0 +1,163 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/github"
+)
+
+// Severities of the issues of a validation, errors fail the build or keep it from starting
+const (
+	IssueError   = "error"
+	IssueWarning = "warning"
+)
+
+// Runtimes the builder builds an image with
+const (
+	RuntimeDockerfile = "dockerfile"
+	RuntimeBuildpacks = "buildpacks"
+)
+
+// Issue is a problem found by a deployment pre-flight check
+type Issue struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Validation is the report of the pre-flight checks of a deployment
+type Validation struct {
+	Valid bool `json:"valid"`
+
+	// Ref is the resolved branch, tag or commit, nil when it couldn't be resolved
+	Ref *github.Ref `json:"ref"`
+
+	// Runtime is how the image would be built, empty when the repository couldn't be read
+	Runtime string `json:"runtime,omitempty"`
+
+	Issues []Issue `json:"issues"`
+}
+
+// Add records an issue, errors make the validation fail
+func (v *Validation) Add(check, severity, message string) {
+	v.Issues = append(v.Issues, Issue{Check: check, Severity: severity, Message: message})
+	if severity == IssueError {
+		v.Valid = false
+	}
+}
+
+// ValidateBuild runs the checks BuildService does before queueing a build and the ones the
+// build would fail on, without creating a deployment. ref is a branch, tag or commit SHA, the
+// service's branch when empty. The service needs GitProvider.GithubAccount,
+// Project.Organization and InstanceType preloaded.
+func ValidateBuild(ctx context.Context, service models.Service, ref string) *Validation {
+	db := database.GetDatabase()
+	v := &Validation{Valid: true, Issues: []Issue{}}
+
+	if service.Stopped {
+		v.Add("service", IssueError, "Service is stopped, start it before deploying")
+	}
+
+	var activeDeployment models.Deployment
+	if err := db.Where("serviceId = ? AND status IN ?", service.ID,
+		[]string{"PENDING", "BUILDING", "DEPLOYING"}).
+		First(&activeDeployment).Error; err == nil {
+		v.Add("deployment", IssueError, "A deployment is already in progress")
+	}
+
+	if limit := concurrencyLimit(service.Project.Organization); limit > 0 {
+		active, err := countActiveBuilds(service.Project.OrganizationID)
+		if err != nil {
+			v.Add("concurrency", IssueWarning, "Failed to count active deployments of the organization")
+		} else if active >= int64(limit) {
+			v.Add("concurrency", IssueError, fmt.Sprintf("Organization is at its limit of %d concurrent deployments", limit))
+		}
+	}
+
+	if service.InstanceType.ID == "" {
+		v.Add("instanceType", IssueError, "Instance type of the service not found")
+	} else if !service.InstanceType.IsVisible {
+		v.Add("instanceType", IssueWarning, fmt.Sprintf("Instance type %s is no longer offered", service.InstanceType.Name))
+	}
+
+	if service.GitProvider == nil || service.RepositoryName == nil {
+		v.Add("repository", IssueError, "Service has no repository")
+		return v
+	}
+
+	if ref == "" {
+		ref = "main"
+		if service.Branch != nil {
+			ref = *service.Branch
+		}
+	}
+	resolved, err := ResolveRef(ctx, service, ref)
+	if errors.Is(err, github.ErrRefNotFound) {
+		v.Add("ref", IssueError, fmt.Sprintf("Ref %q not found in the repository", ref))
+		return v
+	}
+	if err != nil {
+		v.Add("repository", IssueError, "Failed to access the repository: "+err.Error())
+		return v
+	}
+	v.Ref = resolved
+
+	// Only GitHub lets files be read without cloning the repository
+	if service.GitProvider.Type != models.GitProviderTypeGitHub {
+		v.Add("runtime", IssueWarning, "The Dockerfile is only checked for GitHub repositories")
+		return v
+	}
+
+	client, err := repositoryClientFor(ctx, service.GitProvider)
+	if err != nil {
+		v.Add("repository", IssueError, "Failed to access the repository: "+err.Error())
+		return v
+	}
+	owner, repo, _ := strings.Cut(*service.RepositoryName, "/")
+	dockerfilePath := ""
+	if service.RuntimeFilePath != nil {
+		dockerfilePath = *service.RuntimeFilePath
+	}
+
+	// Without a Dockerfile path the builder uses the Dockerfile at the root of the context
+	contextPath := utils.PtrValue(service.BuildContextPath, "")
+	lookupPath := dockerfilePath
+	if contextPath != "" {
+		if err := CheckBuildContextPath(ctx, service.GitProvider, *service.RepositoryName, resolved.CommitSha, contextPath); err != nil {
+			v.Add("buildContext", IssueError, err.Error())
+			return v
+		}
+		if lookupPath == "" {
+			lookupPath = path.Join(contextPath, "Dockerfile")
+		}
+	}
+	desc, err := client.GetRepoDescription(ctx, owner, repo, resolved.CommitSha, lookupPath)
+	if err != nil {
+		v.Add("repository", IssueError, "Failed to access the repository: "+err.Error())
+		return v
+	}
+
+	// The builder falls back to buildpacks when there is no Dockerfile
+	switch {
+	case desc.HasDockerfile:
+		v.Runtime = RuntimeDockerfile
+		checkBuildContext(ctx, v, client, owner, repo, resolved.CommitSha, contextPath)
+	case dockerfilePath != "":
+		v.Runtime = RuntimeBuildpacks
+		v.Add("dockerfile", IssueError, fmt.Sprintf("Dockerfile %s not found at %s", dockerfilePath, resolved.Name))
+	default:
+		v.Runtime = RuntimeBuildpacks
+		if len(desc.Languages) == 0 {
+			v.Add("runtime", IssueWarning, "Repository has no Dockerfile and no language buildpacks could detect")
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,188 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/github"
+)
+
+// fakeProvider is a GitHub repository with the branches of refs, the files of fakeRepository
+// and languages. A provider with err set can't be read.
+type fakeProvider struct {
+	fakeContents
+	fakeRepository
+	refs      map[string]string
+	languages map[string]int
+	err       error
+}
+
+func (f fakeProvider) ResolveRef(ctx context.Context, owner, repo, ref string) (*github.Ref, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	sha, ok := f.refs[ref]
+	if !ok {
+		return nil, github.ErrRefNotFound
+	}
+	return &github.Ref{Name: ref, Kind: github.RefKindBranch, CommitSha: sha}, nil
+}
+
+func (f fakeProvider) GetRepoDescription(ctx context.Context, owner, repo, branch, dockerfilePath string) (*github.RepoDescription, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if dockerfilePath == "" {
+		dockerfilePath = "Dockerfile"
+	}
+	_, hasDockerfile := f.fakeRepository.files[dockerfilePath]
+	return &github.RepoDescription{Languages: f.languages, HasDockerfile: hasDockerfile, DefaultBranch: "main"}, nil
+}
+
+// useProvider makes the repositories of GitHub providers read from provider, or fail with err
+func useProvider(t *testing.T, provider fakeProvider, err error) {
+	t.Helper()
+	previous := repositoryClientFor
+	repositoryClientFor = func(context.Context, *models.GitProvider) (repositoryClient, error) {
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
+	}
+	t.Cleanup(func() { repositoryClientFor = previous })
+}
+
+// githubService is a service built from acme/app on GitHub, with no deployment in progress
+func githubService(t *testing.T) models.Service {
+	t.Helper()
+	db := dbtest.New(t)
+	db.OnQuery("count(*)", dbtest.Row{"count(*)": int64(0)})
+	return models.Service{
+		ID:             "svc1",
+		RepositoryName: utils.Ptr("acme/app"),
+		Branch:         utils.Ptr("main"),
+		GitProvider:    &models.GitProvider{Type: models.GitProviderTypeGitHub},
+		InstanceType:   models.InstanceType{ID: "small", Name: "small", IsVisible: true},
+	}
+}
+
+// issues returns the issues of a validation as check/severity: message
+func issues(v *Validation) []string {
+	out := make([]string, len(v.Issues))
+	for i, issue := range v.Issues {
+		out[i] = fmt.Sprintf("%s/%s: %s", issue.Check, issue.Severity, issue.Message)
+	}
+	return out
+}
+
+func TestValidateBuildWithDockerfile(t *testing.T) {
+	service := githubService(t)
+	useProvider(t, fakeProvider{
+		refs:           map[string]string{"main": "abc123"},
+		fakeRepository: fakeRepository{files: map[string]string{"Dockerfile": "FROM scratch\n", ".dockerignore": ".git\n"}, tree: []github.TreeEntry{{Path: "Dockerfile", Size: 20}}},
+	}, nil)
+
+	v := ValidateBuild(context.Background(), service, "")
+	if !v.Valid || v.Runtime != RuntimeDockerfile || len(v.Issues) != 0 {
+		t.Errorf("validation = %+v, issues %q, want a valid Dockerfile build", *v, issues(v))
+	}
+	if v.Ref == nil || v.Ref.Name != "main" || v.Ref.CommitSha != "abc123" {
+		t.Errorf("ref = %+v, want the service's branch main at abc123", v.Ref)
+	}
+}
+
+func TestValidateBuildWithoutDockerfile(t *testing.T) {
+	tests := []struct {
+		name            string
+		runtimeFilePath *string
+		languages       map[string]int
+		valid           bool
+		issues          []string
+	}{
+		{
+			// The builder uses buildpacks
+			name:      "buildpacks",
+			languages: map[string]int{"Go": 1200},
+			valid:     true,
+			issues:    []string{},
+		},
+		{
+			name:   "nothing to build",
+			valid:  true,
+			issues: []string{"runtime/warning: Repository has no Dockerfile and no language buildpacks could detect"},
+		},
+		{
+			name:            "missing Dockerfile path",
+			runtimeFilePath: utils.Ptr("docker/api.Dockerfile"),
+			languages:       map[string]int{"Go": 1200},
+			issues:          []string{"dockerfile/error: Dockerfile docker/api.Dockerfile not found at main"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := githubService(t)
+			service.RuntimeFilePath = tt.runtimeFilePath
+			useProvider(t, fakeProvider{
+				refs:           map[string]string{"main": "abc123"},
+				languages:      tt.languages,
+				fakeRepository: fakeRepository{files: map[string]string{"go.mod": "module app\n"}},
+			}, nil)
+
+			v := ValidateBuild(context.Background(), service, "")
+			if v.Valid != tt.valid || v.Runtime != RuntimeBuildpacks || fmt.Sprint(issues(v)) != fmt.Sprint(tt.issues) {
+				t.Errorf("validation = valid %v, runtime %s, issues %q, want valid %v, buildpacks, issues %q", v.Valid, v.Runtime, issues(v), tt.valid, tt.issues)
+			}
+		})
+	}
+}
+
+func TestValidateBuildInaccessibleRepository(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider fakeProvider
+		err      error
+		issue    string
+	}{
+		{
+			name:  "no access to the provider",
+			err:   errors.New("failed to authenticate with GitHub: token revoked"),
+			issue: "repository/error: Failed to access the repository: failed to authenticate with GitHub: token revoked",
+		},
+		{
+			name:     "no access to the repository",
+			provider: fakeProvider{err: errors.New("failed to get branch: 404 Not Found")},
+			issue:    "repository/error: Failed to access the repository: failed to get branch: 404 Not Found",
+		},
+		{
+			name:     "unknown ref",
+			provider: fakeProvider{refs: map[string]string{"develop": "def456"}},
+			issue:    `ref/error: Ref "main" not found in the repository`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := githubService(t)
+			useProvider(t, tt.provider, tt.err)
+
+			v := ValidateBuild(context.Background(), service, "")
+			if v.Valid || v.Ref != nil || v.Runtime != "" || fmt.Sprint(issues(v)) != fmt.Sprint([]string{tt.issue}) {
+				t.Errorf("validation = valid %v, ref %+v, runtime %q, issues %q, want only %q", v.Valid, v.Ref, v.Runtime, issues(v), tt.issue)
+			}
+		})
+	}
+}
+
+func TestValidateBuildWithoutRepository(t *testing.T) {
+	service := githubService(t)
+	service.GitProvider = nil
+
+	v := ValidateBuild(context.Background(), service, "")
+	if v.Valid || fmt.Sprint(issues(v)) != fmt.Sprint([]string{"repository/error: Service has no repository"}) {
+		t.Errorf("validation = valid %v, issues %q, want the missing repository reported", v.Valid, issues(v))
+	}
+}
@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// QueueResponse is the depth of a worker queue
+type QueueResponse struct {
+	Length           int64      `json:"length"`
+	OldestEnqueuedAt *time.Time `json:"oldestEnqueuedAt"`
+	OldestAgeSeconds *int64     `json:"oldestAgeSeconds"`
+}
+
+// OrganizationBuilds counts the builds of an organization that hold a build slot, and how
+// many of them wait in its deferred queue for a free slot
+type OrganizationBuilds struct {
+	OrganizationID string `json:"organizationId"`
+	InFlight       int64  `json:"inFlight"`
+	Deferred       int64  `json:"deferred"`
+}
+
+// inFlightRow is the number of builds holding a slot in one organization
+type inFlightRow struct {
+	OrganizationID string `gorm:"column:organizationId"`
+	Count          int64  `gorm:"column:count"`
+}
+
+// GET /api/admin/queues
+func GetQueues(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+	ctx := context.Background()
+	now := time.Now()
+
+	queues := make(map[string]QueueResponse)
+	for _, queue := range append([]string{redis.QueueBuilder}, redis.DeploymentQueues()...) {
+		depth, err := redis.GetQueueDepth(ctx, queue)
+		if err != nil {
+			return response.InternalServerError(c, "Failed to read queue depths")
+		}
+
+		queueResponse := QueueResponse{
+			Length:           depth.Length,
+			OldestEnqueuedAt: depth.OldestEnqueuedAt,
+		}
+		if depth.OldestEnqueuedAt != nil {
+			age := int64(now.Sub(*depth.OldestEnqueuedAt) / time.Second)
+			queueResponse.OldestAgeSeconds = &age
+		}
+		queues[queue] = queueResponse
+	}
+
+	deferred, err := redis.GetDeferredBuilderQueueLengths(ctx)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to read deferred builds")
+	}
+
+	// Same statuses as deploy.HoldsBuildSlot
+	var rows []inFlightRow
+	if err := db.Model(&models.Deployment{}).
+		Select("Project.organizationId AS organizationId, COUNT(*) AS count").
+		Joins("JOIN Service ON Service.id = Deployment.serviceId").
+		Joins("JOIN Project ON Project.id = Service.projectId").
+		Where("Deployment.status IN ?", []models.DeploymentStatus{models.DeploymentStatusPending, models.DeploymentStatusBuilding}).
+		Group("Project.organizationId").
+		Scan(&rows).Error; err != nil {
+		return response.InternalServerError(c, "Failed to count in-flight builds")
+	}
+
+	builds := make(map[string]*OrganizationBuilds)
+	for _, row := range rows {
+		builds[row.OrganizationID] = &OrganizationBuilds{OrganizationID: row.OrganizationID, InFlight: row.Count}
+	}
+	for organizationID, length := range deferred {
+		if _, ok := builds[organizationID]; !ok {
+			builds[organizationID] = &OrganizationBuilds{OrganizationID: organizationID}
+		}
+		builds[organizationID].Deferred = length
+	}
+
+	organizations := make([]*OrganizationBuilds, 0, len(builds))
+	for _, organization := range builds {
+		organizations = append(organizations, organization)
+	}
+	sort.Slice(organizations, func(i, j int) bool {
+		if organizations[i].InFlight != organizations[j].InFlight {
+			return organizations[i].InFlight > organizations[j].InFlight
+		}
+		return organizations[i].OrganizationID < organizations[j].OrganizationID
+	})
+
+	return response.Success(c, fiber.Map{
+		"queues":        queues,
+		"organizations": organizations,
+	})
+}
@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/gofiber/fiber/v2"
+)
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	config.Load()
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+type queuesResponse struct {
+	Data struct {
+		Queues        map[string]QueueResponse `json:"queues"`
+		Organizations []OrganizationBuilds     `json:"organizations"`
+	} `json:"data"`
+}
+
+func getQueues(t *testing.T) (int, queuesResponse) {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/admin/queues", GetQueues)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/admin/queues", nil))
+	if err != nil {
+		t.Fatalf("queues request: %v", err)
+	}
+	var decoded queuesResponse
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+// queueJob returns a queued job enqueued at the given time
+func queueJob(id string, enqueuedAt time.Time) string {
+	return fmt.Sprintf(`{"deploymentId":%q,"enqueuedAt":%q}`, id, enqueuedAt.Format(time.RFC3339Nano))
+}
+
+func useRegions(t *testing.T, regions ...string) {
+	t.Helper()
+	cfg := config.Get()
+	previous := cfg.Regions
+	cfg.Regions = regions
+	t.Cleanup(func() { cfg.Regions = previous })
+}
+
+func TestGetQueuesReportsDepths(t *testing.T) {
+	redisServer.FlushAll()
+	useRegions(t, "eu", "us")
+	oldest := time.Now().Add(-90 * time.Second).UTC()
+
+	redisServer.RPush(redis.QueueBuilder, queueJob("dep1", oldest), queueJob("dep2", oldest.Add(30*time.Second)), queueJob("dep3", time.Now()))
+	// Jobs queued before enqueuedAt was recorded have no age
+	redisServer.RPush(redis.QueueDeployment, `{"deploymentId":"dep4"}`)
+	redisServer.RPush(redis.DeferredBuilderQueue("org2"), queueJob("dep5", oldest), queueJob("dep6", oldest))
+
+	db := dbtest.New(t)
+	db.OnQuery("GROUP BY `Project`.`organizationId`",
+		dbtest.Row{"organizationId": "org2", "count": int64(1)},
+		dbtest.Row{"organizationId": "org1", "count": int64(3)})
+
+	status, decoded := getQueues(t)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	queues := decoded.Data.Queues
+
+	builder := queues[redis.QueueBuilder]
+	if builder.Length != 3 {
+		t.Errorf("builder queue length = %d, want 3", builder.Length)
+	}
+	if builder.OldestEnqueuedAt == nil || !builder.OldestEnqueuedAt.Equal(oldest) {
+		t.Errorf("builder queue oldest job = %v, want %v", builder.OldestEnqueuedAt, oldest)
+	}
+	if builder.OldestAgeSeconds == nil || *builder.OldestAgeSeconds < 90 || *builder.OldestAgeSeconds > 92 {
+		t.Errorf("builder queue age = %v, want 90s", builder.OldestAgeSeconds)
+	}
+
+	deployment := queues[redis.QueueDeployment]
+	if deployment.Length != 1 || deployment.OldestEnqueuedAt != nil || deployment.OldestAgeSeconds != nil {
+		t.Errorf("deployment queue = %+v, want 1 job without an age", deployment)
+	}
+	// The queue of a region other than the default is reported even when empty
+	if regional, ok := queues["deployment-queue:us"]; !ok || regional.Length != 0 {
+		t.Errorf("us deployment queue = %+v, %v, want an empty queue", regional, ok)
+	}
+	if len(queues) != 3 {
+		t.Errorf("reported %d queues, want the builder queue and 2 regional deployment queues", len(queues))
+	}
+
+	want := []OrganizationBuilds{
+		{OrganizationID: "org1", InFlight: 3},
+		{OrganizationID: "org2", InFlight: 1, Deferred: 2},
+	}
+	if got := decoded.Data.Organizations; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("organizations = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetQueuesCountsDeferredOnlyOrganizations(t *testing.T) {
+	redisServer.FlushAll()
+	useRegions(t)
+	redisServer.RPush(redis.DeferredBuilderQueue("org3"), queueJob("dep1", time.Now()))
+	dbtest.New(t)
+
+	status, decoded := getQueues(t)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if builder := decoded.Data.Queues[redis.QueueBuilder]; builder.Length != 0 || builder.OldestAgeSeconds != nil {
+		t.Errorf("empty builder queue = %+v, want no jobs", builder)
+	}
+	want := OrganizationBuilds{OrganizationID: "org3", Deferred: 1}
+	if got := decoded.Data.Organizations; len(got) != 1 || got[0] != want {
+		t.Errorf("organizations = %+v, want %+v", got, want)
+	}
+}
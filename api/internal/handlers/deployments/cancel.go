@@ -2,11 +2,10 @@ package deployments
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
 	"github.com/deployra/deployra/api/internal/models"
-	redisClient "github.com/deployra/deployra/api/internal/redis"
 	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/gofiber/fiber/v2"
 )
@@ -46,25 +45,8 @@ func CancelDeployment(c *fiber.Ctx) error {
 		return response.BadRequest(c, fmt.Sprintf("Deployment already %s, cannot cancel", deployment.Status))
 	}
 
-	// Try to remove from queue
-	removedFromQueue, err := redisClient.RemoveDeploymentFromQueue(ctx, redisClient.QueueDeployment, deploymentID)
+	removedFromQueue, err := deploy.CancelDeployment(ctx, deploymentID)
 	if err != nil {
-		fmt.Printf("Failed to remove deployment from queue: %v\n", err)
-	}
-
-	// Send cancellation signal
-	if err := redisClient.PublishBuilderCancellation(ctx, deploymentID); err != nil {
-		fmt.Printf("Failed to publish builder cancellation: %v\n", err)
-	}
-
-	// Update deployment status
-	now := time.Now()
-	if err := db.Model(&models.Deployment{}).
-		Where("id = ?", deploymentID).
-		Updates(map[string]interface{}{
-			"status":      models.DeploymentStatusCancelled,
-			"completedAt": &now,
-		}).Error; err != nil {
 		return response.InternalServerError(c, "Failed to cancel deployment")
 	}
 
@@ -0,0 +1,106 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/pkg/kubernetes"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Domain types
+const (
+	DomainTypeSubdomain = "subdomain"
+	DomainTypeCustom    = "custom"
+)
+
+// Certificate statuses
+const (
+	CertificateStatusActive  = "ACTIVE"
+	CertificateStatusExpired = "EXPIRED"
+	CertificateStatusPending = "PENDING"
+)
+
+// certificateExpiry returns when the certificate of a domain in the web proxy of a region
+// expires, tests replace it
+var certificateExpiry = kubernetes.GetCertificateExpiry
+
+// GET /api/domains
+func List(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Invalid authentication")
+	}
+
+	// Fetch services owned by the user that have a domain assigned
+	var services []models.Service
+	if err := db.Preload("Project").
+		Joins("JOIN Project ON Service.projectId = Project.id AND Project.deletedAt IS NULL").
+		Joins("JOIN Organization ON Organization.id = Project.organizationId AND Organization.deletedAt IS NULL").
+		Where("Organization.userId = ? AND Service.deletedAt IS NULL", user.ID).
+		Where("Service.subdomain IS NOT NULL OR Service.customDomain IS NOT NULL").
+		Order("Service.createdAt ASC").
+		Find(&services).Error; err != nil {
+		return response.InternalServerError(c, "Failed to fetch domains")
+	}
+
+	appDomain := config.Get().AppDomain
+
+	result := make([]fiber.Map, 0)
+	for _, service := range services {
+		// The web proxy of the service's cluster holds its certificates
+		serviceRegion := region.Of(service)
+
+		if service.Subdomain != nil && appDomain != "" {
+			domain := *service.Subdomain + "." + appDomain
+
+			// Subdomains are usually served by the wildcard certificate of the app domain
+			expiresAt, err := certificateExpiry(c.UserContext(), serviceRegion, domain, false)
+			if err != nil {
+				expiresAt, _ = certificateExpiry(c.UserContext(), serviceRegion, appDomain, true)
+			}
+
+			result = append(result, formatDomain(service, domain, DomainTypeSubdomain, nil, expiresAt))
+		}
+
+		if service.CustomDomain != nil && *service.CustomDomain != "" {
+			expiresAt, _ := certificateExpiry(c.UserContext(), serviceRegion, *service.CustomDomain, false)
+			result = append(result, formatDomain(service, *service.CustomDomain, DomainTypeCustom, service.CustomDomainVerifiedAt, expiresAt))
+		}
+	}
+
+	return response.Success(c, result)
+}
+
+// formatDomain builds the response entry for a single domain. A custom domain is verified once
+// its DNS was found pointing to the platform, whatever the state of its certificate. Subdomains
+// are the platform's own and always verified.
+func formatDomain(service models.Service, domain, domainType string, verifiedAt, certExpiresAt *time.Time) fiber.Map {
+	status := CertificateStatusPending
+	if certExpiresAt != nil {
+		if certExpiresAt.After(time.Now()) {
+			status = CertificateStatusActive
+		} else {
+			status = CertificateStatusExpired
+		}
+	}
+
+	return fiber.Map{
+		"domain":               domain,
+		"type":                 domainType,
+		"serviceId":            service.ID,
+		"serviceName":          service.Name,
+		"projectId":            service.ProjectID,
+		"projectName":          service.Project.Name,
+		"verified":             domainType == DomainTypeSubdomain || verifiedAt != nil,
+		"verifiedAt":           verifiedAt,
+		"certificateStatus":    status,
+		"certificateExpiresAt": certExpiresAt,
+	}
+}
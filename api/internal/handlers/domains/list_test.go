@@ -0,0 +1,146 @@
+package domains
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMain(m *testing.M) {
+	config.Load()
+	os.Exit(m.Run())
+}
+
+func TestFormatDomainVerification(t *testing.T) {
+	service := models.Service{ID: "web-1", Name: "web"}
+	now := time.Now()
+	expired := now.Add(-time.Hour)
+	valid := now.Add(30 * 24 * time.Hour)
+
+	tests := map[string]struct {
+		domainType string
+		verifiedAt *time.Time
+		certExpiry *time.Time
+		verified   bool
+		certStatus string
+	}{
+		"subdomain without certificate": {DomainTypeSubdomain, nil, nil, true, CertificateStatusPending},
+		"unverified custom domain":      {DomainTypeCustom, nil, nil, false, CertificateStatusPending},
+		"verified custom domain":        {DomainTypeCustom, &now, &valid, true, CertificateStatusActive},
+		"verified with expired cert":    {DomainTypeCustom, &now, &expired, true, CertificateStatusExpired},
+		"unverified with a valid cert":  {DomainTypeCustom, nil, &valid, false, CertificateStatusActive},
+	}
+	for name, tt := range tests {
+		entry := formatDomain(service, "example.com", tt.domainType, tt.verifiedAt, tt.certExpiry)
+		if entry["verified"] != tt.verified {
+			t.Errorf("%s: verified = %v, want %v", name, entry["verified"], tt.verified)
+		}
+		if entry["certificateStatus"] != tt.certStatus {
+			t.Errorf("%s: certificateStatus = %v, want %s", name, entry["certificateStatus"], tt.certStatus)
+		}
+	}
+}
+
+// listedDomain is an entry of the domains List returns
+type listedDomain struct {
+	Domain            string `json:"domain"`
+	Type              string `json:"type"`
+	ServiceID         string `json:"serviceId"`
+	ProjectName       string `json:"projectName"`
+	Verified          bool   `json:"verified"`
+	CertificateStatus string `json:"certificateStatus"`
+}
+
+// stubCertificates answers certificate lookups with the expiry of the certificates by secret
+// domain, wildcard ones prefixed with *.
+func stubCertificates(t *testing.T, certificates map[string]time.Time) {
+	t.Helper()
+	previous := certificateExpiry
+	certificateExpiry = func(ctx context.Context, region, domain string, wildcard bool) (*time.Time, error) {
+		if wildcard {
+			domain = "*." + domain
+		}
+		expiresAt, ok := certificates[domain]
+		if !ok {
+			return nil, errors.New("certificate secret not found")
+		}
+		return &expiresAt, nil
+	}
+	t.Cleanup(func() { certificateExpiry = previous })
+}
+
+func TestListListsDomainsOfEveryService(t *testing.T) {
+	cfg := config.Get()
+	previousDomain := cfg.AppDomain
+	cfg.AppDomain = "apps.example.com"
+	t.Cleanup(func() { cfg.AppDomain = previousDomain })
+
+	valid := time.Now().Add(30 * 24 * time.Hour)
+	stubCertificates(t, map[string]time.Time{"*.apps.example.com": valid})
+
+	db := dbtest.New(t)
+	verifiedAt := time.Now().Add(-time.Hour)
+	db.OnQuery("FROM `Service`",
+		dbtest.Row{"id": "svc1", "name": "shop", "projectId": "proj1", "subdomain": "shop"},
+		dbtest.Row{"id": "svc2", "name": "blog", "projectId": "proj2", "customDomain": "blog.example.org", "customDomainVerifiedAt": verifiedAt},
+	)
+	db.OnQuery("FROM `Project`",
+		dbtest.Row{"id": "proj1", "name": "store"},
+		dbtest.Row{"id": "proj2", "name": "writing"},
+	)
+
+	app := fiber.New()
+	app.Get("/domains", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user1"})
+		return c.Next()
+	}, List)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/domains", nil))
+	if err != nil {
+		t.Fatalf("list request: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var decoded struct {
+		Data []listedDomain `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	// The subdomain is served by the wildcard certificate, the custom domain has none yet
+	want := []listedDomain{
+		{Domain: "shop.apps.example.com", Type: DomainTypeSubdomain, ServiceID: "svc1", ProjectName: "store", Verified: true, CertificateStatus: CertificateStatusActive},
+		{Domain: "blog.example.org", Type: DomainTypeCustom, ServiceID: "svc2", ProjectName: "writing", Verified: true, CertificateStatus: CertificateStatusPending},
+	}
+	if !reflect.DeepEqual(decoded.Data, want) {
+		t.Errorf("domains = %+v\nwant %+v", decoded.Data, want)
+	}
+
+	finds := db.Statements("FROM `Service`")
+	if len(finds) != 1 || !containsArg(finds[0], "user1") {
+		t.Errorf("service queries = %v, want the services of user1", finds)
+	}
+}
+
+// containsArg reports whether a statement was sent with an argument printing as want
+func containsArg(statement dbtest.Statement, want string) bool {
+	for _, arg := range statement.Args {
+		if fmt.Sprint(arg) == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package gitproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/analyze"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AnalyzeRequest represents the repository analysis request body
+type AnalyzeRequest struct {
+	ProviderID     string `json:"providerId"`
+	RepositoryName string `json:"repositoryName"`
+	Branch         string `json:"branch"`
+	DockerfilePath string `json:"dockerfilePath"`
+}
+
+// POST /api/git/analyze
+// Suggests the configuration of a service built from a repository, and the template creating it
+func AnalyzeRepository(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+	ctx := context.Background()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Invalid authentication")
+	}
+
+	var req AnalyzeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if req.ProviderID == "" {
+		return response.BadRequest(c, "Provider ID is required")
+	}
+	if req.RepositoryName == "" {
+		return response.BadRequest(c, "Repository name is required")
+	}
+	if req.Branch == "" {
+		return response.BadRequest(c, "Branch name is required")
+	}
+
+	// Check access
+	if !checkGitProviderAccess(user, req.ProviderID) {
+		return response.Forbidden(c, "Git provider not found or access denied")
+	}
+
+	// Find provider with GitHub account
+	var provider models.GitProvider
+	if err := db.Preload("GithubAccount").
+		Where("id = ? AND deletedAt IS NULL", req.ProviderID).
+		First(&provider).Error; err != nil {
+		return response.NotFound(c, "Git provider not found")
+	}
+
+	// Parse repo name
+	parts := strings.Split(req.RepositoryName, "/")
+	if len(parts) != 2 {
+		return response.BadRequest(c, "Invalid repository name format. Expected format: owner/repo")
+	}
+	owner, repo := parts[0], parts[1]
+
+	var files analyze.FileReader
+	if provider.Type == models.GitProviderTypeGitHub {
+		client, err := deploy.GitHubClient(ctx, &provider)
+		if err != nil {
+			fmt.Printf("Failed to create GitHub client: %v\n", err)
+			return response.Unauthorized(c, "Failed to authenticate with GitHub: "+err.Error())
+		}
+		files = analyze.GitHubFiles{Client: client, Owner: owner, Repo: repo, Ref: req.Branch}
+	} else {
+		// Other providers are cloned to read the files
+		if provider.Password == nil || provider.URL == nil || provider.Username == nil {
+			return response.BadRequest(c, "Missing required Git provider credentials")
+		}
+		tempDir, err := cloneRepository(&provider, owner, repo, req.Branch)
+		if errors.Is(err, errInvalidProviderURL) {
+			return response.BadRequest(c, "Invalid Git provider URL")
+		}
+		if err != nil {
+			fmt.Printf("Failed to clone repository: %v\n", err)
+			return response.InternalServerError(c, "Failed to fetch data from custom Git provider")
+		}
+		defer os.RemoveAll(tempDir)
+		files = analyze.DirFiles(tempDir)
+	}
+
+	suggestion, err := analyze.Analyze(ctx, files, req.DockerfilePath)
+	if err != nil {
+		fmt.Printf("Failed to analyze repository: %v\n", err)
+		return response.InternalServerError(c, "Failed to read the repository")
+	}
+
+	// Smallest plan offered for the suggested service type
+	var instanceType models.InstanceType
+	db.Joins("JOIN InstanceTypeGroup ON InstanceTypeGroup.id = InstanceType.instanceTypeGroupId").
+		Where("InstanceTypeGroup.serviceTypeId = ? AND InstanceType.isVisible = ?", suggestion.ServiceType, true).
+		Order("InstanceTypeGroup.`index`, InstanceType.`index`").
+		First(&instanceType)
+
+	template, err := suggestion.Template(repo, instanceType.ID)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to build the template")
+	}
+
+	return response.Success(c, fiber.Map{
+		"suggestion": suggestion,
+		"template":   template,
+	})
+}
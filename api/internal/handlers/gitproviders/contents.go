@@ -0,0 +1,87 @@
+package gitproviders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/pkg/github"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /api/git/:providerId/repos/:owner/:repo/contents?path=&ref=
+// Lists a directory of a repository so a Dockerfile or subdirectory can be picked, the
+// default branch when no ref is given
+func ListContents(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+	ctx := context.Background()
+	providerID := c.Params("providerId")
+	owner := c.Params("owner")
+	repo := c.Params("repo")
+	ref := c.Query("ref")
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Invalid authentication")
+	}
+
+	if providerID == "" {
+		return response.BadRequest(c, "Provider ID is required")
+	}
+	if owner == "" || repo == "" {
+		return response.BadRequest(c, "Repository name is required")
+	}
+
+	// Paths are relative to the root of the repository
+	contentPath := strings.TrimPrefix(path.Clean("/"+c.Query("path")), "/")
+
+	// Check access
+	if !checkGitProviderAccess(user, providerID) {
+		return response.Forbidden(c, "Git provider not found or access denied")
+	}
+
+	// Find provider with GitHub account
+	var provider models.GitProvider
+	if err := db.Preload("GithubAccount").
+		Where("id = ? AND deletedAt IS NULL", providerID).
+		First(&provider).Error; err != nil {
+		return response.NotFound(c, "Git provider not found")
+	}
+
+	if provider.Type != models.GitProviderTypeGitHub {
+		return response.BadRequest(c, "Browsing repository contents is only supported for GitHub providers")
+	}
+
+	// Refreshes the token of the GitHub account when it expired
+	client, err := deploy.GitHubClient(ctx, &provider)
+	if err != nil {
+		fmt.Printf("Failed to create GitHub client: %v\n", err)
+		return response.Unauthorized(c, "Failed to authenticate with GitHub: "+err.Error())
+	}
+
+	entries, dir, err := client.ListContents(ctx, owner, repo, ref, contentPath)
+	if errors.Is(err, github.ErrPathNotFound) {
+		return response.NotFound(c, "Path not found in repository")
+	}
+	if err != nil {
+		fmt.Printf("Failed to list repository contents: %v\n", err)
+		return response.InternalServerError(c, "Failed to fetch repository contents")
+	}
+
+	contentType := "file"
+	if dir {
+		contentType = "dir"
+	}
+
+	return response.Success(c, fiber.Map{
+		"path":    contentPath,
+		"type":    contentType,
+		"entries": entries,
+	})
+}
@@ -0,0 +1,50 @@
+package gitproviders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// listContents browses the contents of acme/app through provider gp1 as user1
+func listContents(t *testing.T) *http.Response {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/git/:providerId/repos/:owner/:repo/contents", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user1"})
+		return c.Next()
+	}, ListContents)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/git/gp1/repos/acme/app/contents?path=services", nil))
+	if err != nil {
+		t.Fatalf("contents request: %v", err)
+	}
+	return resp
+}
+
+func TestListContentsChecksOwnership(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnQuery("FROM `GitProvider`", dbtest.Row{"id": "gp1", "organizationId": "org1", "type": string(models.GitProviderTypeGitHub)})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": "someone-else"})
+
+	if resp := listContents(t); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if lookups := db.Statements("FROM `GithubAccount`"); len(lookups) != 0 {
+		t.Errorf("read the GitHub account of a provider of another user")
+	}
+}
+
+func TestListContentsOnlyGitHub(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnQuery("FROM `GitProvider`", dbtest.Row{"id": "gp1", "organizationId": "org1", "type": string(models.GitProviderTypeCustom)})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": "user1"})
+
+	if resp := listContents(t); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
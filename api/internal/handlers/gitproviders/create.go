@@ -1,6 +1,9 @@
 package gitproviders
 
 import (
+	"log"
+
+	"github.com/deployra/deployra/api/internal/crypto"
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
 	"github.com/deployra/deployra/api/pkg/response"
@@ -48,6 +51,13 @@ func CreateProvider(c *fiber.Ctx) error {
 		return response.NotFound(c, "Organization not found")
 	}
 
+	// Encrypt the password before storing it
+	encryptedPassword, err := crypto.Encrypt(req.Password)
+	if err != nil {
+		log.Printf("Error encrypting git provider password: %v", err)
+		return response.InternalServerError(c, "Failed to encrypt git provider credentials")
+	}
+
 	// Create provider
 	repoSelection := "all"
 	provider := models.GitProvider{
@@ -56,7 +66,7 @@ func CreateProvider(c *fiber.Ctx) error {
 		Type:                models.GitProviderTypeCustom,
 		URL:                 &req.URL,
 		Username:            &req.Username,
-		Password:            &req.Password,
+		Password:            &encryptedPassword,
 		RepositorySelection: &repoSelection,
 	}
 
@@ -2,10 +2,8 @@ package gitproviders
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
+	"errors"
 	"fmt"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -15,9 +13,6 @@ import (
 	"github.com/deployra/deployra/api/internal/models"
 	"github.com/deployra/deployra/api/pkg/github"
 	"github.com/deployra/deployra/api/pkg/response"
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -119,36 +114,15 @@ func GetRepositoryDescription(c *fiber.Ctx) error {
 			return response.BadRequest(c, "Missing required Git provider credentials")
 		}
 
-		// Create a temporary directory
-		randomBytes := make([]byte, 8)
-		rand.Read(randomBytes)
-		tempDir := filepath.Join(os.TempDir(), "git-repo-"+hex.EncodeToString(randomBytes))
-		if err := os.MkdirAll(tempDir, 0755); err != nil {
-			return response.InternalServerError(c, "Failed to create temporary directory")
-		}
-		defer os.RemoveAll(tempDir)
-
-		// Construct the Git URL with authentication
-		parsedURL, err := url.Parse(*provider.URL)
-		if err != nil {
+		tempDir, err := cloneRepository(&provider, owner, repo, req.Branch)
+		if errors.Is(err, errInvalidProviderURL) {
 			return response.BadRequest(c, "Invalid Git provider URL")
 		}
-		gitURL := fmt.Sprintf("%s/%s/%s.git", parsedURL.String(), owner, repo)
-
-		// Clone the repository
-		_, err = git.PlainClone(tempDir, false, &git.CloneOptions{
-			URL:           gitURL,
-			ReferenceName: plumbing.NewBranchReferenceName(req.Branch),
-			Depth:         1,
-			Auth: &http.BasicAuth{
-				Username: *provider.Username,
-				Password: *provider.Password,
-			},
-		})
 		if err != nil {
 			fmt.Printf("Failed to clone repository: %v\n", err)
 			return response.InternalServerError(c, "Failed to fetch data from custom Git provider")
 		}
+		defer os.RemoveAll(tempDir)
 
 		desc = &github.RepoDescription{
 			Languages:     make(map[string]int),
@@ -1,7 +1,6 @@
 package gitproviders
 
 import (
-	"context"
 	"fmt"
 	"strconv"
 
@@ -12,11 +11,12 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// ListRepositories returns repositories for a git provider
+// ListRepositories returns repositories for a git provider, filtered by ?name= (a substring
+// of the full name) and ?language= (the primary language)
 func ListRepositories(c *fiber.Ctx) error {
 	db := database.GetDatabase()
 	providerID := c.Params("providerId")
-	ctx := context.Background()
+	ctx := c.UserContext()
 
 	user, ok := c.Locals("user").(*models.User)
 	if !ok {
@@ -78,5 +78,5 @@ func ListRepositories(c *fiber.Ctx) error {
 		repos = []github.Repository{}
 	}
 
-	return response.Success(c, repos)
+	return response.Success(c, github.FilterRepositories(repos, c.Query("name"), c.Query("language")))
 }
@@ -1,10 +1,25 @@
 package gitproviders
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/deployra/deployra/api/internal/crypto"
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
 )
 
+// errInvalidProviderURL is returned when the URL of a custom git provider can't be parsed
+var errInvalidProviderURL = errors.New("invalid Git provider URL")
+
 // checkOrganizationAccess checks if user has access to the organization
 func checkOrganizationAccess(user *models.User, organizationID string) bool {
 	db := database.GetDatabase()
@@ -31,3 +46,38 @@ func checkGitProviderAccess(user *models.User, providerID string) bool {
 
 	return provider.Organization.UserID == user.ID
 }
+
+// cloneRepository clones a branch of a repository of a custom git provider into a new
+// temporary directory and returns it, the caller removes it
+func cloneRepository(provider *models.GitProvider, owner, repo, branch string) (string, error) {
+	parsedURL, err := url.Parse(*provider.URL)
+	if err != nil {
+		return "", errInvalidProviderURL
+	}
+	gitURL := fmt.Sprintf("%s/%s/%s.git", parsedURL.String(), owner, repo)
+
+	// Create a temporary directory
+	randomBytes := make([]byte, 8)
+	rand.Read(randomBytes)
+	tempDir := filepath.Join(os.TempDir(), "git-repo-"+hex.EncodeToString(randomBytes))
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	// Clone the repository
+	_, err = git.PlainClone(tempDir, false, &git.CloneOptions{
+		URL:           gitURL,
+		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		Depth:         1,
+		Auth: &http.BasicAuth{
+			Username: *provider.Username,
+			Password: crypto.DecryptWithFallback(*provider.Password),
+		},
+	})
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return "", err
+	}
+
+	return tempDir, nil
+}
@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Timeout for each dependency check
+const checkTimeout = 2 * time.Second
+
+// GET /livez
+func Live(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// GET /readyz
+func Ready(c *fiber.Ctx) error {
+	checks := fiber.Map{
+		"database": checkDatabase(),
+		"redis":    checkRedis(),
+	}
+
+	ready := true
+	for _, result := range checks {
+		if result != "ok" {
+			ready = false
+		}
+	}
+
+	if !ready {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status": "error",
+			"checks": checks,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "ok",
+		"checks": checks,
+	})
+}
+
+// checkDatabase pings the database and returns "ok" or the failure reason
+func checkDatabase() string {
+	db := database.GetDatabase()
+	if db == nil {
+		return "not initialized"
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
+
+// checkRedis pings Redis and returns "ok" or the failure reason
+func checkRedis() string {
+	client := redis.GetClient()
+	if client == nil {
+		return "not initialized"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
@@ -0,0 +1,101 @@
+package health
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// pingDriver is a database/sql driver whose connections only answer pings
+type pingDriver struct{}
+
+type pingConn struct{}
+
+func (pingDriver) Open(string) (driver.Conn, error) { return pingConn{}, nil }
+
+func (pingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (pingConn) Close() error                        { return nil }
+func (pingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	sql.Register("health-ping", pingDriver{})
+
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+// useDatabase points the database package at a database that is up
+func useDatabase(t *testing.T) {
+	t.Helper()
+	sqlDB, err := sql.Open("health-ping", "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	database.SetDatabase(db)
+	t.Cleanup(func() { database.SetDatabase(nil) })
+}
+
+func get(t *testing.T, app *fiber.App, path string) int {
+	t.Helper()
+	// The checks of a dependency that is down take up to checkTimeout
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil), int((2 * checkTimeout).Milliseconds()))
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	return resp.StatusCode
+}
+
+func TestReadyFollowsRedisLiveDoesNot(t *testing.T) {
+	useDatabase(t)
+
+	app := fiber.New()
+	app.Get("/livez", Live)
+	app.Get("/readyz", Ready)
+
+	if status := get(t, app, "/readyz"); status != fiber.StatusOK {
+		t.Fatalf("readyz with every dependency up = %d, want 200", status)
+	}
+
+	// Redis goes down, the API isn't ready but is still alive
+	redisServer.Close()
+	defer redisServer.Restart()
+	if status := get(t, app, "/readyz"); status != fiber.StatusServiceUnavailable {
+		t.Errorf("readyz with Redis down = %d, want 503", status)
+	}
+	if status := get(t, app, "/livez"); status != fiber.StatusOK {
+		t.Errorf("livez with Redis down = %d, want 200", status)
+	}
+}
+
+func TestReadyWithoutDatabase(t *testing.T) {
+	app := fiber.New()
+	app.Get("/readyz", Ready)
+	if status := get(t, app, "/readyz"); status != fiber.StatusServiceUnavailable {
+		t.Errorf("readyz without a database = %d, want 503", status)
+	}
+}
@@ -3,6 +3,7 @@ package organizations
 import (
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
 	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/deployra/deployra/api/pkg/utils"
 	"github.com/gofiber/fiber/v2"
@@ -11,6 +12,9 @@ import (
 type CreateOrganizationRequest struct {
 	Name        string  `json:"name"`
 	Description *string `json:"description"`
+
+	// DefaultRegion is the region new services run in, the configured default when unset
+	DefaultRegion *string `json:"defaultRegion"`
 }
 
 // POST /api/organizations
@@ -31,6 +35,15 @@ func Create(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Organization name is required")
 	}
 
+	if req.DefaultRegion != nil && *req.DefaultRegion == "" {
+		req.DefaultRegion = nil
+	}
+	if req.DefaultRegion != nil {
+		if err := region.Validate(*req.DefaultRegion); err != nil {
+			return response.BadRequest(c, err.Error())
+		}
+	}
+
 	// Check if organization with same name already exists for this user
 	var existingOrg models.Organization
 	if err := db.Where("userId = ? AND name = ? AND deletedAt IS NULL", user.ID, req.Name).
@@ -39,10 +52,11 @@ func Create(c *fiber.Ctx) error {
 	}
 
 	organization := models.Organization{
-		ID:          utils.GenerateShortID(),
-		UserID:      user.ID,
-		Name:        req.Name,
-		Description: req.Description,
+		ID:            utils.GenerateShortID(),
+		UserID:        user.ID,
+		Name:          req.Name,
+		Description:   req.Description,
+		DefaultRegion: req.DefaultRegion,
 	}
 
 	if err := db.Create(&organization).Error; err != nil {
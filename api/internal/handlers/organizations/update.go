@@ -0,0 +1,88 @@
+package organizations
+
+import (
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+type UpdateOrganizationRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+
+	// DefaultRegion is the region new services run in, empty to use the configured default
+	DefaultRegion *string `json:"defaultRegion"`
+}
+
+// POST /api/organizations/:organizationId
+func Update(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+	organizationID := c.Params("organizationId")
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Invalid authentication")
+	}
+
+	if organizationID == "" {
+		return response.BadRequest(c, "Organization ID is required")
+	}
+
+	var req UpdateOrganizationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if req.Name != nil && *req.Name == "" {
+		return response.BadRequest(c, "Organization name is required")
+	}
+
+	// Check access
+	if !checkOrganizationAccess(user, organizationID) {
+		return response.Forbidden(c, "Organization not found or access denied")
+	}
+
+	var organization models.Organization
+	if err := db.Where("id = ? AND deletedAt IS NULL", organizationID).
+		First(&organization).Error; err != nil {
+		return response.NotFound(c, "Organization not found")
+	}
+
+	// Build updates
+	updates := make(map[string]interface{})
+	if req.Name != nil && *req.Name != organization.Name {
+		var existingOrg models.Organization
+		if err := db.Where("userId = ? AND name = ? AND deletedAt IS NULL", user.ID, *req.Name).
+			First(&existingOrg).Error; err == nil {
+			return response.BadRequest(c, "Organization already exists")
+		}
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = req.Description
+	}
+	if req.DefaultRegion != nil {
+		// Existing services stay in their region
+		if *req.DefaultRegion == "" {
+			updates["defaultRegion"] = nil
+		} else {
+			if err := region.Validate(*req.DefaultRegion); err != nil {
+				return response.BadRequest(c, err.Error())
+			}
+			updates["defaultRegion"] = *req.DefaultRegion
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := db.Model(&models.Organization{}).Where("id = ?", organizationID).Updates(updates).Error; err != nil {
+			return response.InternalServerError(c, "Failed to update organization")
+		}
+	}
+
+	// Fetch updated organization
+	db.Where("id = ?", organizationID).First(&organization)
+
+	return response.Success(c, organization)
+}
@@ -0,0 +1,104 @@
+package organizations
+
+import (
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ServiceTypeUsage is the usage of all services of one type in an organization
+type ServiceTypeUsage struct {
+	ServiceTypeID     string  `gorm:"column:serviceTypeId" json:"serviceTypeId"`
+	Services          int64   `gorm:"column:services" json:"services"`
+	RunningServices   int64   `gorm:"column:runningServices" json:"runningServices"`
+	RunningReplicas   int64   `gorm:"column:runningReplicas" json:"runningReplicas"`
+	StorageCapacityGB int64   `gorm:"column:storageCapacityGB" json:"storageCapacityGB"`
+	StorageUsageBytes float64 `gorm:"column:storageUsageBytes" json:"storageUsageBytes"`
+}
+
+// GET /api/organizations/:organizationId/usage
+func GetUsage(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+	organizationID := c.Params("organizationId")
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Invalid authentication")
+	}
+
+	if organizationID == "" {
+		return response.BadRequest(c, "Organization ID is required")
+	}
+
+	// Check access
+	if !checkOrganizationAccess(user, organizationID) {
+		return response.Forbidden(c, "Organization not found or access denied")
+	}
+
+	var projectCount int64
+	if err := db.Model(&models.Project{}).
+		Where("organizationId = ? AND deletedAt IS NULL", organizationID).
+		Count(&projectCount).Error; err != nil {
+		return response.InternalServerError(c, "Failed to calculate usage")
+	}
+
+	// One grouped query over the services of every project in the organization
+	var byType []ServiceTypeUsage
+	if err := db.Table("Service").
+		Select(`Service.serviceTypeId AS serviceTypeId,
+			COUNT(*) AS services,
+			SUM(CASE WHEN Service.status = ? THEN 1 ELSE 0 END) AS runningServices,
+			COALESCE(SUM(CASE WHEN Service.status = ? THEN Service.currentReplicas ELSE 0 END), 0) AS runningReplicas,
+			COALESCE(SUM(Service.storageCapacity), 0) AS storageCapacityGB,
+			COALESCE(SUM(Service.storageUsage), 0) AS storageUsageBytes`,
+			models.ServiceStatusRunning, models.ServiceStatusRunning).
+		Joins("JOIN Project ON Project.id = Service.projectId").
+		Where("Project.organizationId = ? AND Project.deletedAt IS NULL AND Service.deletedAt IS NULL", organizationID).
+		Group("Service.serviceTypeId").
+		Order("Service.serviceTypeId").
+		Scan(&byType).Error; err != nil {
+		return response.InternalServerError(c, "Failed to calculate usage")
+	}
+
+	// The web proxy counts traffic per service and month in Redis
+	var serviceIDs []string
+	if err := db.Table("Service").
+		Joins("JOIN Project ON Project.id = Service.projectId").
+		Where("Project.organizationId = ? AND Project.deletedAt IS NULL AND Service.deletedAt IS NULL", organizationID).
+		Pluck("Service.id", &serviceIDs).Error; err != nil {
+		return response.InternalServerError(c, "Failed to calculate usage")
+	}
+	month := time.Now().UTC()
+	monthlyTraffic, err := redis.GetMonthlyTraffic(c.UserContext(), serviceIDs, month)
+	if err != nil {
+		log.Printf("Error getting traffic of organization %s: %v", organizationID, err)
+		return response.InternalServerError(c, "Failed to calculate usage")
+	}
+
+	total := ServiceTypeUsage{}
+	for _, usage := range byType {
+		total.Services += usage.Services
+		total.RunningServices += usage.RunningServices
+		total.RunningReplicas += usage.RunningReplicas
+		total.StorageCapacityGB += usage.StorageCapacityGB
+		total.StorageUsageBytes += usage.StorageUsageBytes
+	}
+
+	return response.Success(c, fiber.Map{
+		"organizationId":    organizationID,
+		"projects":          projectCount,
+		"services":          total.Services,
+		"runningServices":   total.RunningServices,
+		"runningReplicas":   total.RunningReplicas,
+		"storageCapacityGB": total.StorageCapacityGB,
+		"storageUsageBytes": total.StorageUsageBytes,
+		"trafficMonth":      month.Format("2006-01"),
+		"trafficBytes":      monthlyTraffic,
+		"serviceTypes":      byType,
+	})
+}
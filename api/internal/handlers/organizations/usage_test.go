@@ -0,0 +1,126 @@
+package organizations
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/gofiber/fiber/v2"
+)
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+type usageResponse struct {
+	Data struct {
+		Projects          int64              `json:"projects"`
+		Services          int64              `json:"services"`
+		RunningServices   int64              `json:"runningServices"`
+		RunningReplicas   int64              `json:"runningReplicas"`
+		StorageCapacityGB int64              `json:"storageCapacityGB"`
+		StorageUsageBytes float64            `json:"storageUsageBytes"`
+		TrafficMonth      string             `json:"trafficMonth"`
+		TrafficBytes      int64              `json:"trafficBytes"`
+		ServiceTypes      []ServiceTypeUsage `json:"serviceTypes"`
+	} `json:"data"`
+}
+
+func getUsage(t *testing.T) (int, usageResponse) {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/organizations/:organizationId/usage", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user1"})
+		return c.Next()
+	}, GetUsage)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/organizations/org1/usage", nil))
+	if err != nil {
+		t.Fatalf("usage request: %v", err)
+	}
+	var decoded usageResponse
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+func TestGetUsageSumsServiceTypes(t *testing.T) {
+	redisServer.FlushAll()
+	month := time.Now().UTC()
+	redisServer.Set(redis.TrafficKey("web-1", month), "1500")
+	redisServer.Set(redis.TrafficKey("web-2", month), "500")
+	// Last month's traffic isn't counted
+	redisServer.Set(redis.TrafficKey("web-1", month.AddDate(0, -1, 0)), "9999")
+
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": "user1"})
+	db.OnQuery("FROM `Project`", dbtest.Row{"count(*)": int64(2)})
+	// The database aggregates each service type in one grouped query
+	db.OnQuery("GROUP BY",
+		dbtest.Row{"serviceTypeId": "mysql", "services": int64(1), "runningServices": int64(1), "runningReplicas": int64(1),
+			"storageCapacityGB": int64(10), "storageUsageBytes": float64(2048)},
+		dbtest.Row{"serviceTypeId": "web", "services": int64(2), "runningServices": int64(1), "runningReplicas": int64(3),
+			"storageCapacityGB": int64(0), "storageUsageBytes": float64(0)},
+	)
+	db.OnQuery("`Service`.`id`", dbtest.Row{"id": "web-1"}, dbtest.Row{"id": "web-2"}, dbtest.Row{"id": "db-1"})
+
+	status, resp := getUsage(t)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	usage := resp.Data
+	if usage.Projects != 2 {
+		t.Errorf("projects = %d, want 2", usage.Projects)
+	}
+	if usage.Services != 3 || usage.RunningServices != 2 || usage.RunningReplicas != 4 {
+		t.Errorf("services %d, running %d, replicas %d, want 3, 2 and 4", usage.Services, usage.RunningServices, usage.RunningReplicas)
+	}
+	if usage.StorageCapacityGB != 10 || usage.StorageUsageBytes != 2048 {
+		t.Errorf("storage %d GB and %.0f bytes, want 10 GB and 2048 bytes", usage.StorageCapacityGB, usage.StorageUsageBytes)
+	}
+	if usage.TrafficBytes != 2000 || usage.TrafficMonth != month.Format("2006-01") {
+		t.Errorf("traffic %d bytes in %s, want 2000 this month", usage.TrafficBytes, usage.TrafficMonth)
+	}
+	if len(usage.ServiceTypes) != 2 || usage.ServiceTypes[1].ServiceTypeID != "web" || usage.ServiceTypes[1].RunningReplicas != 3 {
+		t.Errorf("service types = %+v, want mysql and web", usage.ServiceTypes)
+	}
+
+	// No query per service: the grouped aggregate and the service IDs for traffic
+	if queries := db.Statements("FROM `Service`"); len(queries) != 2 {
+		t.Errorf("service queries = %v, want 2", queries)
+	}
+	if queries := db.Statements("GROUP BY"); len(queries) != 1 || !strings.Contains(queries[0].SQL, "JOIN Project") {
+		t.Errorf("grouped queries = %v, want one over the organization's projects", queries)
+	}
+}
+
+func TestGetUsageIsOwnerOnly(t *testing.T) {
+	db := dbtest.New(t)
+
+	if status, _ := getUsage(t); status != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", status, http.StatusForbidden)
+	}
+	if queries := db.Statements("FROM `Service`"); len(queries) != 0 {
+		t.Errorf("usage of another user's organization ran %v", queries)
+	}
+}
@@ -0,0 +1,165 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Number of services a batch operates on at the same time
+const batchConcurrency = 5
+
+// batchAction applies a batch action to a single service, tests replace it
+var batchAction = runBatchAction
+
+// BatchServicesRequest represents the request body for a batch service operation
+type BatchServicesRequest struct {
+	Action     string   `json:"action"`
+	ServiceIDs []string `json:"serviceIds,omitempty"`
+}
+
+// BatchServiceResult is the outcome of a batch operation for a single service
+type BatchServiceResult struct {
+	ServiceID string  `json:"serviceId"`
+	Success   bool    `json:"success"`
+	Error     *string `json:"error,omitempty"`
+}
+
+// POST /api/projects/:projectId/services/batch
+func BatchServices(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+	projectID := c.Params("projectId")
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Invalid authentication")
+	}
+
+	if projectID == "" {
+		return response.BadRequest(c, "Project ID is required")
+	}
+
+	var req BatchServicesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	switch req.Action {
+	case "restart", "deploy", "stop":
+	default:
+		return response.BadRequest(c, "Action must be one of restart, deploy or stop")
+	}
+
+	// Check access
+	if !checkProjectAccess(user, projectID) {
+		return response.Forbidden(c, "Project not found or access denied")
+	}
+
+	var services []models.Service
+	if err := db.Where("projectId = ? AND deletedAt IS NULL", projectID).
+		Order("createdAt ASC").
+		Find(&services).Error; err != nil {
+		return response.InternalServerError(c, "Failed to fetch services")
+	}
+
+	byID := make(map[string]models.Service, len(services))
+	for _, service := range services {
+		byID[service.ID] = service
+	}
+
+	// Without a filter every service of the project is included
+	var serviceIDs []string
+	if len(req.ServiceIDs) == 0 {
+		for _, service := range services {
+			serviceIDs = append(serviceIDs, service.ID)
+		}
+	} else {
+		seen := make(map[string]bool, len(req.ServiceIDs))
+		for _, serviceID := range req.ServiceIDs {
+			if !seen[serviceID] {
+				seen[serviceID] = true
+				serviceIDs = append(serviceIDs, serviceID)
+			}
+		}
+	}
+
+	results := make([]BatchServiceResult, len(serviceIDs))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	ctx := c.UserContext()
+
+	for i, serviceID := range serviceIDs {
+		results[i] = BatchServiceResult{ServiceID: serviceID}
+
+		service, exists := byID[serviceID]
+		if !exists {
+			results[i].Error = utils.Ptr("Service not found")
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, service models.Service) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := batchAction(ctx, req.Action, service, user.ID); err != nil {
+				results[i].Error = utils.Ptr(err.Error())
+				return
+			}
+			results[i].Success = true
+		}(i, service)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+
+	return response.Success(c, fiber.Map{
+		"action":    req.Action,
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	})
+}
+
+// runBatchAction applies a batch action to a single service
+func runBatchAction(ctx context.Context, action string, service models.Service, userID string) error {
+	var err error
+	switch action {
+	case "restart":
+		err = servicestatus.Restart(service.ID)
+	case "stop":
+		err = servicestatus.Stop(ctx, service)
+	case "deploy":
+		if service.Runtime != models.RuntimeDocker {
+			return errors.New("Service runtime is not Docker")
+		}
+		_, err = deploy.BuildService(service.ID, userID, "manual", "")
+	}
+
+	if errors.Is(err, servicestatus.ErrInvalidTransition) {
+		return fmt.Errorf("Service cannot %s while %s", action, service.Status)
+	}
+	if errors.Is(err, servicestatus.ErrServiceStopped) {
+		return errors.New("Service is stopped, start it first")
+	}
+	if err != nil {
+		log.Printf("Error running batch %s for service %s: %v", action, service.ID, err)
+	}
+	return err
+}
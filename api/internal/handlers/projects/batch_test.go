@@ -0,0 +1,184 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type batchResponse struct {
+	Data struct {
+		Action    string               `json:"action"`
+		Results   []BatchServiceResult `json:"results"`
+		Succeeded int                  `json:"succeeded"`
+		Failed    int                  `json:"failed"`
+	} `json:"data"`
+}
+
+// projectServices answers lookups of proj1, owned by user1, and its services
+func projectServices(db *dbtest.DB, serviceIDs ...string) {
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": "user1"})
+
+	var rows []dbtest.Row
+	for _, serviceID := range serviceIDs {
+		rows = append(rows, dbtest.Row{"id": serviceID, "projectId": "proj1"})
+	}
+	db.OnQuery("FROM `Service`", rows...)
+}
+
+// stubBatchAction replaces the per-service action for the test
+func stubBatchAction(t *testing.T, action func(ctx context.Context, action string, service models.Service, userID string) error) {
+	t.Helper()
+	previous := batchAction
+	batchAction = action
+	t.Cleanup(func() { batchAction = previous })
+}
+
+func postBatch(t *testing.T, userID, body string) (int, batchResponse) {
+	t.Helper()
+	app := fiber.New()
+	app.Post("/projects/:projectId/services/batch", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: userID})
+		return c.Next()
+	}, BatchServices)
+
+	req := httptest.NewRequest(http.MethodPost, "/projects/proj1/services/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("batch request: %v", err)
+	}
+
+	var decoded batchResponse
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+func TestBatchServicesReportsEachService(t *testing.T) {
+	db := dbtest.New(t)
+	projectServices(db, "svc1", "svc2")
+
+	var mu sync.Mutex
+	var ran []string
+	stubBatchAction(t, func(_ context.Context, action string, service models.Service, userID string) error {
+		mu.Lock()
+		ran = append(ran, action+" "+service.ID+" "+userID)
+		mu.Unlock()
+		if service.ID == "svc2" {
+			return errors.New("Service cannot restart while DEPLOYING")
+		}
+		return nil
+	})
+
+	status, resp := postBatch(t, "user1", `{"action":"restart","serviceIds":["svc1","missing","svc2","svc1"]}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	results := resp.Data.Results
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want one per distinct service: %+v", len(results), results)
+	}
+	if results[0].ServiceID != "svc1" || !results[0].Success || results[0].Error != nil {
+		t.Errorf("svc1 result = %+v, want success", results[0])
+	}
+	if results[1].ServiceID != "missing" || results[1].Success || results[1].Error == nil || *results[1].Error != "Service not found" {
+		t.Errorf("missing result = %+v, want Service not found", results[1])
+	}
+	if results[2].ServiceID != "svc2" || results[2].Success || results[2].Error == nil || *results[2].Error != "Service cannot restart while DEPLOYING" {
+		t.Errorf("svc2 result = %+v, want the action's error", results[2])
+	}
+	if resp.Data.Succeeded != 1 || resp.Data.Failed != 2 {
+		t.Errorf("succeeded %d and failed %d, want 1 and 2", resp.Data.Succeeded, resp.Data.Failed)
+	}
+
+	// The unknown service is never acted on
+	if len(ran) != 2 {
+		t.Errorf("action ran %v, want svc1 and svc2 restarted by user1", ran)
+	}
+}
+
+func TestBatchServicesDefaultsToEveryService(t *testing.T) {
+	db := dbtest.New(t)
+	projectServices(db, "svc1", "svc2", "svc3")
+	stubBatchAction(t, func(context.Context, string, models.Service, string) error { return nil })
+
+	_, resp := postBatch(t, "user1", `{"action":"stop"}`)
+	if resp.Data.Succeeded != 3 || resp.Data.Failed != 0 {
+		t.Errorf("succeeded %d and failed %d, want 3 and 0", resp.Data.Succeeded, resp.Data.Failed)
+	}
+}
+
+func TestBatchServicesBoundsConcurrency(t *testing.T) {
+	db := dbtest.New(t)
+	serviceIDs := []string{"svc1", "svc2", "svc3", "svc4", "svc5", "svc6", "svc7", "svc8"}
+	projectServices(db, serviceIDs...)
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	stubBatchAction(t, func(context.Context, string, models.Service, string) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+
+	_, resp := postBatch(t, "user1", `{"action":"deploy"}`)
+	if resp.Data.Succeeded != len(serviceIDs) {
+		t.Fatalf("succeeded %d, want %d", resp.Data.Succeeded, len(serviceIDs))
+	}
+	if peak > batchConcurrency {
+		t.Errorf("%d services ran at once, want at most %d", peak, batchConcurrency)
+	}
+}
+
+func TestBatchServicesRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		body   string
+		want   int
+	}{
+		{name: "unknown action", userID: "user1", body: `{"action":"delete"}`, want: http.StatusBadRequest},
+		{name: "another user's project", userID: "user2", body: `{"action":"restart"}`, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			projectServices(db, "svc1")
+			stubBatchAction(t, func(context.Context, string, models.Service, string) error {
+				t.Error("action ran for a rejected batch")
+				return nil
+			})
+
+			if status, _ := postBatch(t, tt.userID, tt.body); status != tt.want {
+				t.Errorf("status = %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunBatchActionDeployNeedsDocker(t *testing.T) {
+	err := runBatchAction(context.Background(), "deploy", models.Service{ID: "svc1", Runtime: models.RuntimeImage}, "user1")
+	if err == nil || err.Error() != "Service runtime is not Docker" {
+		t.Errorf("deploy of an image service: %v, want Service runtime is not Docker", err)
+	}
+}
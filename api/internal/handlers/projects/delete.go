@@ -1,17 +1,25 @@
 package projects
 
 import (
+	"context"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
 	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/pkg/ecr"
 	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // DELETE /api/projects/:projectId
 func Delete(c *fiber.Ctx) error {
 	db := database.GetDatabase()
+	ctx := context.Background()
 	projectID := c.Params("projectId")
 
 	user, ok := c.Locals("user").(*models.User)
@@ -23,31 +31,92 @@ func Delete(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Project ID is required")
 	}
 
-	// Check access
-	if !checkProjectAccess(user, projectID) {
+	// Deleted projects are looked up too, so repeating a delete succeeds
+	var project models.Project
+	if err := db.Preload("Organization").
+		Where("id = ?", projectID).
+		First(&project).Error; err != nil {
+		return response.NotFound(c, "Project not found")
+	}
+
+	// Only the owner of the organization may delete its projects
+	if project.Organization.UserID != user.ID {
 		return response.Forbidden(c, "Project not found or access denied")
 	}
 
-	// Verify project exists and check for services
-	var existingProject models.Project
-	if err := db.Preload("Services", "deletedAt IS NULL").
-		Where("id = ? AND deletedAt IS NULL", projectID).
-		First(&existingProject).Error; err != nil {
-		return response.NotFound(c, "Project not found")
+	if project.DeletedAt != nil {
+		return response.Success(c, fiber.Map{
+			"message": "Project deleted successfully",
+		})
 	}
 
-	// Check if project has active services
-	if len(existingProject.Services) > 0 {
-		return response.BadRequest(c, "Cannot delete project with existing services. Please delete all services first.")
+	var services []models.Service
+	if err := db.Where("projectId = ? AND deletedAt IS NULL", projectID).
+		Find(&services).Error; err != nil {
+		return response.InternalServerError(c, "Failed to fetch project services")
 	}
 
-	// Soft delete project
+	serviceIDs := make([]string, len(services))
+	for i, service := range services {
+		serviceIDs[i] = service.ID
+	}
+
+	// Soft delete the project and its services, releasing their domains and ingress ports
 	now := time.Now()
-	if err := db.Model(&models.Project{}).Where("id = ?", projectID).Update("deletedAt", &now).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(serviceIDs) > 0 {
+			if err := tx.Model(&models.Service{}).Where("id IN ?", serviceIDs).Updates(map[string]interface{}{
+				"deletedAt":              now,
+				"subdomain":              nil,
+				"customDomain":           nil,
+				"customDomainVerifiedAt": nil,
+				"ingressPort":            nil,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Project{}).Where("id = ?", projectID).Update("deletedAt", &now).Error
+	})
+	if err != nil {
+		log.Printf("Error deleting project %s: %v", projectID, err)
 		return response.InternalServerError(c, "Failed to delete project")
 	}
 
+	// Stop deployments that are still running for the deleted services
+	if len(serviceIDs) > 0 {
+		var activeDeployments []models.Deployment
+		db.Where("serviceId IN ? AND status IN ?", serviceIDs,
+			[]string{"PENDING", "BUILDING", "DEPLOYING"}).
+			Find(&activeDeployments)
+		for _, deployment := range activeDeployments {
+			if _, err := deploy.CancelDeployment(ctx, deployment.ID); err != nil {
+				log.Printf("Error cancelling deployment %s: %v", deployment.ID, err)
+			}
+		}
+	}
+
+	// Delete ECR repositories of services that use ECR
+	for _, service := range services {
+		if service.ContainerRegistryImageUri != nil && strings.Contains(*service.ContainerRegistryImageUri, ".ecr.") {
+			go func(serviceID string) {
+				if err := ecr.DeleteRepository(serviceID); err != nil {
+					log.Printf("Error deleting ECR repository for service %s: %v", serviceID, err)
+				}
+			}(service.ID)
+		}
+	}
+
+	// Remove every Kubernetes resource of the project
+	if err := redis.AddToProjectDeletionQueue(ctx, redis.ProjectDeletionJob{
+		Type:           "delete-project",
+		ProjectID:      projectID,
+		OrganizationID: project.OrganizationID,
+	}); err != nil {
+		log.Printf("Error queueing Kubernetes deletion for project %s: %v", projectID, err)
+	}
+
 	return response.Success(c, fiber.Map{
-		"message": "Project deleted successfully",
+		"message":         "Project deleted successfully",
+		"deletedServices": len(services),
 	})
 }
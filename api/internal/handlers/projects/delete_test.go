@@ -0,0 +1,147 @@
+package projects
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/gofiber/fiber/v2"
+)
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	config.Load()
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+func deleteProject(t *testing.T, userID string) int {
+	t.Helper()
+	app := fiber.New()
+	app.Delete("/projects/:projectId", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: userID})
+		return c.Next()
+	}, Delete)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodDelete, "/projects/proj1", nil))
+	if err != nil {
+		t.Fatalf("delete request: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func queuedProjectDeletions(t *testing.T) []redis.ProjectDeletionJob {
+	t.Helper()
+	if !redisServer.Exists(redis.QueueDeployment) {
+		return nil
+	}
+	items, err := redisServer.List(redis.QueueDeployment)
+	if err != nil {
+		t.Fatalf("read deployment queue: %v", err)
+	}
+	var jobs []redis.ProjectDeletionJob
+	for _, item := range items {
+		var job redis.ProjectDeletionJob
+		if err := json.Unmarshal([]byte(item), &job); err != nil {
+			t.Fatalf("unmarshal queued job %s: %v", item, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func TestDeleteCascadesToServices(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	projectServices(db, "svc1", "svc2")
+
+	if status := deleteProject(t, "user1"); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	// Both services are soft deleted at once, releasing their domains
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 1 {
+		t.Fatalf("service updates = %v, want 1", updates)
+	}
+	for _, column := range []string{"`deletedAt`", "`subdomain`", "`customDomain`", "`ingressPort`"} {
+		if !strings.Contains(updates[0].SQL, column) {
+			t.Errorf("service update %q doesn't set %s", updates[0].SQL, column)
+		}
+	}
+	for _, serviceID := range []string{"svc1", "svc2"} {
+		if !containsArg(updates[0], serviceID) {
+			t.Errorf("service update args %v don't include %s", updates[0].Args, serviceID)
+		}
+	}
+
+	projectUpdates := db.Statements("UPDATE `Project`")
+	if len(projectUpdates) != 1 || !strings.Contains(projectUpdates[0].SQL, "`deletedAt`") {
+		t.Errorf("project updates = %v, want the project soft deleted", projectUpdates)
+	}
+
+	jobs := queuedProjectDeletions(t)
+	if len(jobs) != 1 || jobs[0].Type != "delete-project" || jobs[0].ProjectID != "proj1" || jobs[0].OrganizationID != "org1" {
+		t.Errorf("queued jobs = %+v, want a delete-project job for proj1", jobs)
+	}
+}
+
+func TestDeleteIsIdempotent(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	projectServices(db, "svc1")
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1", "deletedAt": time.Now()})
+
+	if status := deleteProject(t, "user1"); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if updates := db.Statements("UPDATE"); len(updates) != 0 {
+		t.Errorf("deleting a deleted project wrote %v", updates)
+	}
+	if jobs := queuedProjectDeletions(t); len(jobs) != 0 {
+		t.Errorf("deleting a deleted project queued %+v", jobs)
+	}
+}
+
+func TestDeleteIsOwnerOnly(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	projectServices(db, "svc1")
+
+	if status := deleteProject(t, "user2"); status != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", status, http.StatusForbidden)
+	}
+	if updates := db.Statements("UPDATE"); len(updates) != 0 {
+		t.Errorf("a forbidden delete wrote %v", updates)
+	}
+	if jobs := queuedProjectDeletions(t); len(jobs) != 0 {
+		t.Errorf("a forbidden delete queued %+v", jobs)
+	}
+}
+
+func containsArg(statement dbtest.Statement, want string) bool {
+	for _, arg := range statement.Args {
+		if s, ok := arg.(string); ok && s == want {
+			return true
+		}
+	}
+	return false
+}
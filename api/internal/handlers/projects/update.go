@@ -1,8 +1,14 @@
 package projects
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/crypto"
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/webhook"
 	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/gofiber/fiber/v2"
 )
@@ -11,6 +17,11 @@ type UpdateProjectRequest struct {
 	Name        *string `json:"name"`
 	Description *string `json:"description"`
 	WebhookUrl  *string `json:"webhookUrl"`
+
+	// WebhookEvents subscribes the webhook to deployment statuses, an empty list to all.
+	// An empty WebhookSecret stops signing deliveries.
+	WebhookEvents *[]string `json:"webhookEvents"`
+	WebhookSecret *string   `json:"webhookSecret"`
 }
 
 // POST /api/projects/:projectId
@@ -63,7 +74,42 @@ func Update(c *fiber.Ctx) error {
 		updates["description"] = req.Description
 	}
 	if req.WebhookUrl != nil {
-		updates["webhookUrl"] = req.WebhookUrl
+		if *req.WebhookUrl == "" {
+			updates["webhookUrl"] = nil
+		} else {
+			if err := webhook.ValidateURL(*req.WebhookUrl); err != nil {
+				return response.BadRequest(c, "Invalid webhook URL: "+err.Error())
+			}
+			updates["webhookUrl"] = *req.WebhookUrl
+		}
+	}
+	if req.WebhookEvents != nil {
+		events := make([]string, 0, len(*req.WebhookEvents))
+		for _, event := range *req.WebhookEvents {
+			event = strings.ToLower(event)
+			if !webhook.DeploymentEvents[event] {
+				return response.BadRequest(c, fmt.Sprintf("Unknown webhook event %q", event))
+			}
+			events = append(events, event)
+		}
+
+		if len(events) == 0 {
+			updates["webhookEvents"] = nil
+		} else {
+			data, _ := json.Marshal(events)
+			updates["webhookEvents"] = data
+		}
+	}
+	if req.WebhookSecret != nil {
+		if *req.WebhookSecret == "" {
+			updates["webhookSecret"] = nil
+		} else {
+			encrypted, err := crypto.Encrypt(*req.WebhookSecret)
+			if err != nil {
+				return response.InternalServerError(c, "Failed to encrypt webhook secret")
+			}
+			updates["webhookSecret"] = encrypted
+		}
 	}
 
 	if len(updates) > 0 {
@@ -0,0 +1,101 @@
+package projects
+
+import (
+	"errors"
+	"log"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/webhook"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 100
+)
+
+// GET /api/projects/:projectId/webhook-deliveries
+func ListWebhookDeliveries(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+	projectID := c.Params("projectId")
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Invalid authentication")
+	}
+
+	if projectID == "" {
+		return response.BadRequest(c, "Project ID is required")
+	}
+
+	// Check access
+	if !checkProjectAccess(user, projectID) {
+		return response.Forbidden(c, "Project not found or access denied")
+	}
+
+	limit := c.QueryInt("limit", defaultDeliveriesLimit)
+	if limit < 1 || limit > maxDeliveriesLimit {
+		limit = defaultDeliveriesLimit
+	}
+
+	query := db.Where("projectId = ?", projectID)
+	if event := c.Query("event"); event != "" {
+		query = query.Where("event = ?", event)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("createdAt DESC").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return response.InternalServerError(c, "Failed to fetch webhook deliveries")
+	}
+
+	return response.Success(c, deliveries)
+}
+
+// POST /api/projects/:projectId/webhook-deliveries/:deliveryId/replay
+func ReplayWebhookDelivery(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+	projectID := c.Params("projectId")
+	deliveryID := c.Params("deliveryId")
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Invalid authentication")
+	}
+
+	if projectID == "" || deliveryID == "" {
+		return response.BadRequest(c, "Project ID and delivery ID are required")
+	}
+
+	var project models.Project
+	if err := db.Preload("Organization").
+		Where("id = ? AND deletedAt IS NULL", projectID).
+		First(&project).Error; err != nil {
+		return response.NotFound(c, "Project not found")
+	}
+
+	// Check access
+	if project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Project not found or access denied")
+	}
+
+	var original models.WebhookDelivery
+	if err := db.Where("id = ? AND projectId = ?", deliveryID, projectID).
+		First(&original).Error; err != nil {
+		return response.NotFound(c, "Webhook delivery not found")
+	}
+
+	delivery, err := webhook.Replay(original, project)
+	if err != nil {
+		if errors.Is(err, webhook.ErrNoWebhook) {
+			return response.BadRequest(c, "Project has no webhook URL")
+		}
+		log.Printf("Error replaying webhook delivery %s: %v", deliveryID, err)
+		return response.InternalServerError(c, "Failed to replay webhook delivery")
+	}
+
+	return response.Success(c, delivery)
+}
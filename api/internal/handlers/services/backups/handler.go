@@ -0,0 +1,138 @@
+package backups
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Service types whose data volume can be backed up
+var backupServiceTypes = map[string]bool{
+	"mysql":      true,
+	"postgresql": true,
+	"memory":     true,
+}
+
+// GET /api/services/:serviceId/backups
+func List(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	var backups []models.ServiceBackup
+	if err := db.Where("serviceId = ?", serviceID).
+		Order("createdAt DESC").
+		Limit(100).
+		Find(&backups).Error; err != nil {
+		return response.InternalServerError(c, "Failed to fetch backups")
+	}
+
+	return response.Success(c, backups)
+}
+
+// POST /api/services/:serviceId/backups
+func Create(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+	ctx := context.Background()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if !backupServiceTypes[service.ServiceTypeID] {
+		return response.BadRequest(c, "Backups are only available for database and memory services")
+	}
+
+	// Only one backup may run at a time
+	var activeCount int64
+	db.Model(&models.ServiceBackup{}).
+		Where("serviceId = ? AND status IN ?", serviceID,
+			[]string{string(models.BackupStatusPending), string(models.BackupStatusInProgress)}).
+		Count(&activeCount)
+	if activeCount > 0 {
+		return response.Error(c, fiber.StatusConflict, "A backup is already in progress for this service")
+	}
+
+	backupID := uuid.New().String()
+	backup := models.ServiceBackup{
+		ID:           backupID,
+		ServiceID:    serviceID,
+		Status:       models.BackupStatusPending,
+		SnapshotName: fmt.Sprintf("%s-snapshot-%s", serviceID, time.Now().UTC().Format("20060102-150405")),
+		TriggeredBy:  &user.ID,
+	}
+
+	if err := db.Create(&backup).Error; err != nil {
+		log.Printf("Error creating backup for service %s: %v", serviceID, err)
+		return response.InternalServerError(c, "Failed to create backup")
+	}
+
+	// Kubestrator snapshots the service's volume and reports back through the backups webhook
+	if err := redis.AddToBackupQueue(ctx, redis.BackupJob{
+		Type:         "backup-service",
+		BackupID:     backupID,
+		ServiceID:    serviceID,
+		ProjectID:    service.ProjectID,
+		ServiceType:  service.ServiceTypeID,
+		SnapshotName: backup.SnapshotName,
+		Region:       region.Of(service),
+	}); err != nil {
+		log.Printf("Error queueing backup %s: %v", backupID, err)
+		db.Model(&backup).Updates(map[string]interface{}{
+			"status": models.BackupStatusFailed,
+			"error":  "Failed to queue backup",
+		})
+		return response.InternalServerError(c, "Failed to queue backup")
+	}
+
+	return response.Success(c, backup)
+}
@@ -0,0 +1,129 @@
+package backups
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/gofiber/fiber/v2"
+)
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	config.Load()
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+// createBackup posts a backup of svc1 as user1, whose organization owns the service
+func createBackup(t *testing.T, db *dbtest.DB, serviceType string) *http.Response {
+	t.Helper()
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1", "serviceTypeId": serviceType})
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": "user1"})
+
+	app := fiber.New()
+	app.Post("/services/:serviceId/backups", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user1"})
+		return c.Next()
+	}, Create)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/services/svc1/backups", nil))
+	if err != nil {
+		t.Fatalf("create backup request: %v", err)
+	}
+	return resp
+}
+
+func queuedBackups(t *testing.T) []redis.BackupJob {
+	t.Helper()
+	if !redisServer.Exists(redis.QueueDeployment) {
+		return nil
+	}
+	items, err := redisServer.List(redis.QueueDeployment)
+	if err != nil {
+		t.Fatalf("read deployment queue: %v", err)
+	}
+	var jobs []redis.BackupJob
+	for _, item := range items {
+		var job redis.BackupJob
+		if err := json.Unmarshal([]byte(item), &job); err != nil {
+			t.Fatalf("unmarshal queued job %s: %v", item, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func TestCreateQueuesBackupJob(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+
+	resp := createBackup(t, db, "postgresql")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	inserts := db.Statements("INSERT INTO `ServiceBackup`")
+	if len(inserts) != 1 {
+		t.Fatalf("inserted %d backups, want 1", len(inserts))
+	}
+
+	jobs := queuedBackups(t)
+	if len(jobs) != 1 {
+		t.Fatalf("queued %d jobs, want 1", len(jobs))
+	}
+	job := jobs[0]
+	if job.Type != "backup-service" || job.ServiceID != "svc1" || job.ProjectID != "proj1" || job.ServiceType != "postgresql" {
+		t.Errorf("queued job = %+v, want a backup-service job for svc1", job)
+	}
+	if job.BackupID == "" || job.SnapshotName == "" {
+		t.Errorf("queued job = %+v, want a backup ID and snapshot name", job)
+	}
+}
+
+func TestCreateRejectsServicesWithoutVolumes(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+
+	resp := createBackup(t, db, "web")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if inserts := db.Statements("INSERT INTO `ServiceBackup`"); len(inserts) != 0 {
+		t.Errorf("inserted %d backups for a web service", len(inserts))
+	}
+	if jobs := queuedBackups(t); len(jobs) != 0 {
+		t.Errorf("queued %d jobs for a web service", len(jobs))
+	}
+}
+
+func TestCreateRejectsWhileBackupInProgress(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	db.OnQuery("count(*)", dbtest.Row{"count(*)": int64(1)})
+
+	resp := createBackup(t, db, "mysql")
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+	if jobs := queuedBackups(t); len(jobs) != 0 {
+		t.Errorf("queued %d jobs while a backup is in progress", len(jobs))
+	}
+}
@@ -21,6 +21,7 @@ func createMySQLService(c *fiber.Ctx, user *models.User, req CreateServiceReques
 		Name:            req.Name,
 		ServiceTypeID:   "mysql",
 		ProjectID:       req.ProjectID,
+		Region:          req.Region,
 		Runtime:         models.RuntimeImage,
 		InstanceTypeID:  req.InstanceTypeID,
 		StorageCapacity: req.StorageCapacity,
@@ -93,6 +94,7 @@ func createPostgreSQLService(c *fiber.Ctx, user *models.User, req CreateServiceR
 		Name:            req.Name,
 		ServiceTypeID:   "postgresql",
 		ProjectID:       req.ProjectID,
+		Region:          req.Region,
 		Runtime:         models.RuntimeImage,
 		InstanceTypeID:  req.InstanceTypeID,
 		StorageCapacity: req.StorageCapacity,
@@ -165,6 +167,7 @@ func createMemoryService(c *fiber.Ctx, user *models.User, req CreateServiceReque
 		Name:           req.Name,
 		ServiceTypeID:  "memory",
 		ProjectID:      req.ProjectID,
+		Region:         req.Region,
 		Runtime:        models.RuntimeImage,
 		InstanceTypeID: req.InstanceTypeID,
 	}
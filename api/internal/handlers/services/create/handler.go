@@ -2,7 +2,10 @@ package create
 
 import (
 	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
 	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/internal/utils"
 	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/gofiber/fiber/v2"
 )
@@ -61,6 +64,13 @@ func Create(c *fiber.Ctx) error {
 		return response.Forbidden(c, "Project not found or unauthorized access")
 	}
 
+	// Region the service runs in, the organization's default when none is requested
+	serviceRegion, err := region.Resolve(req.Region, project.Organization)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	req.Region = serviceRegion
+
 	// Check if a service with the same name already exists in this project
 	var existingService models.Service
 	if err := db.Where("projectId = ? AND name = ? AND deletedAt IS NULL", req.ProjectID, req.Name).
@@ -88,14 +98,34 @@ func Create(c *fiber.Ctx) error {
 	}
 
 	// Check git provider access if provided
+	var gitProvider *models.GitProvider
 	if req.GitProviderID != nil && *req.GitProviderID != "" {
-		var gitProvider models.GitProvider
-		if err := db.Preload("Organization").Where("id = ? AND deletedAt IS NULL", *req.GitProviderID).First(&gitProvider).Error; err != nil {
+		var provider models.GitProvider
+		if err := db.Preload("Organization").Preload("GithubAccount").Where("id = ? AND deletedAt IS NULL", *req.GitProviderID).First(&provider).Error; err != nil {
 			return response.NotFound(c, "Git provider not found")
 		}
-		if gitProvider.Organization.UserID != user.ID {
+		if provider.Organization.UserID != user.ID {
 			return response.Forbidden(c, "Git provider not found or unauthorized access")
 		}
+		gitProvider = &provider
+	}
+
+	// A monorepo service builds from a subdirectory, it must exist in the branch it deploys
+	if req.BuildContextPath != nil {
+		contextPath, err := deploy.NormalizeBuildContextPath(*req.BuildContextPath)
+		if err != nil {
+			return response.BadRequest(c, err.Error())
+		}
+		if contextPath != nil {
+			if gitProvider == nil || req.RepositoryName == nil {
+				return response.BadRequest(c, "Build context path requires a repository")
+			}
+			branch := utils.PtrValue(req.Branch, "main")
+			if err := deploy.CheckBuildContextPath(c.UserContext(), gitProvider, *req.RepositoryName, branch, *contextPath); err != nil {
+				return response.BadRequest(c, err.Error())
+			}
+		}
+		req.BuildContextPath = contextPath
 	}
 
 	// Route to appropriate handler based on service type
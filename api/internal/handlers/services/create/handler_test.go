@@ -0,0 +1,52 @@
+package create
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMain(m *testing.M) {
+	config.Load()
+	os.Exit(m.Run())
+}
+
+func TestCreateRejectsUnknownRegion(t *testing.T) {
+	cfg := config.Get()
+	previous := cfg.Regions
+	cfg.Regions = []string{"eu-west", "us-east"}
+	t.Cleanup(func() { cfg.Regions = previous })
+
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": "user1"})
+
+	app := fiber.New()
+	app.Post("/services", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user1"})
+		return c.Next()
+	}, Create)
+
+	req := httptest.NewRequest(http.MethodPost, "/services", strings.NewReader(
+		`{"name":"web","serviceTypeId":"web","projectId":"proj1","instanceTypeId":"it1","region":"ap-south"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "Unknown region: ap-south") {
+		t.Errorf("response = %d %s, want the unknown region rejected", resp.StatusCode, body)
+	}
+	if inserts := db.Statements("INSERT INTO `Service`"); len(inserts) != 0 {
+		t.Errorf("created a service in an unknown region")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/deploy"
 	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
 	"github.com/deployra/deployra/api/internal/utils"
 	"github.com/deployra/deployra/api/pkg/github"
 	"github.com/deployra/deployra/api/pkg/response"
@@ -29,7 +30,10 @@ func createPrivateService(c *fiber.Ctx, user *models.User, req CreateServiceRequ
 		// Convert to crypto type for encryption
 		cryptoEnvVars := make([]crypto.EnvironmentVariable, len(req.EnvironmentVariables))
 		for i, v := range req.EnvironmentVariables {
-			cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
+			cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
+		}
+		if err := servicestatus.CheckEnvVarLimits(cryptoEnvVars); err != nil {
+			return response.BadRequest(c, err.Error())
 		}
 		encryptedEnvVars, err := crypto.EncryptEnvVars(cryptoEnvVars)
 		if err != nil {
@@ -39,7 +43,7 @@ func createPrivateService(c *fiber.Ctx, user *models.User, req CreateServiceRequ
 		// Convert back for storage
 		storageEnvVars := make([]EnvironmentVariable, len(encryptedEnvVars))
 		for i, v := range encryptedEnvVars {
-			storageEnvVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value}
+			storageEnvVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 		}
 		envVarsJSON, _ = json.Marshal(storageEnvVars)
 	}
@@ -56,9 +60,11 @@ func createPrivateService(c *fiber.Ctx, user *models.User, req CreateServiceRequ
 		Name:              req.Name,
 		ServiceTypeID:     "private",
 		ProjectID:         req.ProjectID,
+		Region:            req.Region,
 		Runtime:           runtime,
 		InstanceTypeID:    req.InstanceTypeID,
 		AutoDeployEnabled: autoDeployEnabled,
+		DeployPathFilter:  req.DeployPathFilter,
 		HealthCheckPath:   req.HealthCheckPath,
 		StorageCapacity:   req.StorageCapacity,
 	}
@@ -73,13 +79,23 @@ func createPrivateService(c *fiber.Ctx, user *models.User, req CreateServiceRequ
 		service.RepositoryName = req.RepositoryName
 		service.Branch = req.Branch
 		service.RuntimeFilePath = req.RuntimeFilePath
+		service.BuildContextPath = req.BuildContextPath
 	} else if runtime == models.RuntimeImage {
 		if req.DockerImageUrl != nil {
 			containerType := "docker"
 			service.ContainerRegistryType = &containerType
 			service.ContainerRegistryImageUri = req.DockerImageUrl
-			service.ContainerRegistryUsername = req.DockerUsername
-			service.ContainerRegistryPassword = req.DockerPassword
+			// Registry credentials are stored encrypted like environment variables
+			username, err := crypto.EncryptOptional(req.DockerUsername)
+			if err != nil {
+				return response.InternalServerError(c, "Failed to encrypt registry credentials")
+			}
+			password, err := crypto.EncryptOptional(req.DockerPassword)
+			if err != nil {
+				return response.InternalServerError(c, "Failed to encrypt registry credentials")
+			}
+			service.ContainerRegistryUsername = username
+			service.ContainerRegistryPassword = password
 		}
 	}
 
@@ -145,6 +161,7 @@ func createPrivateService(c *fiber.Ctx, user *models.User, req CreateServiceRequ
 		"repositoryName":            service.RepositoryName,
 		"branch":                    service.Branch,
 		"runtimeFilePath":           service.RuntimeFilePath,
+		"buildContextPath":          service.BuildContextPath,
 		"runtime":                   service.Runtime,
 		"createdAt":                 service.CreatedAt,
 		"updatedAt":                 service.UpdatedAt,
@@ -154,12 +171,13 @@ func createPrivateService(c *fiber.Ctx, user *models.User, req CreateServiceRequ
 		"healthCheckPath":           service.HealthCheckPath,
 		"autoScalingEnabled":        service.AutoScalingEnabled,
 		"autoDeployEnabled":         service.AutoDeployEnabled,
+		"deployPathFilter":          service.DeployPathFilter,
 		"maxReplicas":               service.MaxReplicas,
 		"minReplicas":               service.MinReplicas,
 		"replicas":                  service.Replicas,
 		"containerRegistryType":     service.ContainerRegistryType,
 		"containerRegistryImageUri": service.ContainerRegistryImageUri,
-		"containerRegistryUsername": service.ContainerRegistryUsername,
+		"containerRegistryUsername": crypto.DecryptOptional(service.ContainerRegistryUsername),
 		"instanceTypeId":            service.InstanceTypeID,
 		"currentReplicas":           service.CurrentReplicas,
 		"targetReplicas":            service.TargetReplicas,
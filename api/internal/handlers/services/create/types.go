@@ -3,6 +3,9 @@ package create
 type EnvironmentVariable struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
+
+	// IsSecret is false for plain config, unset means secret
+	IsSecret *bool `json:"isSecret,omitempty"`
 }
 
 type PortSetting struct {
@@ -18,6 +21,7 @@ type CreateServiceRequest struct {
 	RepositoryName       *string               `json:"repositoryName,omitempty"`
 	Branch               *string               `json:"branch,omitempty"`
 	RuntimeFilePath      *string               `json:"runtimeFilePath,omitempty"`
+	BuildContextPath     *string               `json:"buildContextPath,omitempty"`
 	DockerImageUrl       *string               `json:"dockerImageUrl,omitempty"`
 	DockerUsername       *string               `json:"dockerUsername,omitempty"`
 	DockerPassword       *string               `json:"dockerPassword,omitempty"`
@@ -25,6 +29,9 @@ type CreateServiceRequest struct {
 	PortSettings         []PortSetting         `json:"portSettings,omitempty"`
 	HealthCheckPath      *string               `json:"healthCheckPath,omitempty"`
 	AutoDeployEnabled    *bool                 `json:"autoDeployEnabled,omitempty"`
+	DeployPathFilter     *string               `json:"deployPathFilter,omitempty"`
 	InstanceTypeID       string                `json:"instanceTypeId"`
 	StorageCapacity      *int                  `json:"storageCapacity,omitempty"`
+	Subdomain            *string               `json:"subdomain,omitempty"`
+	Region               *string               `json:"region,omitempty"`
 }
@@ -2,6 +2,7 @@ package create
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"strings"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/deploy"
 	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	servicesubdomain "github.com/deployra/deployra/api/internal/subdomain"
 	"github.com/deployra/deployra/api/internal/utils"
 	"github.com/deployra/deployra/api/pkg/github"
 	"github.com/deployra/deployra/api/pkg/response"
@@ -18,8 +21,14 @@ import (
 func createWebService(c *fiber.Ctx, user *models.User, req CreateServiceRequest, project models.Project, instanceType models.InstanceType) error {
 	db := database.GetDatabase()
 
-	// Generate subdomain
-	subdomain := utils.GenerateSubdomain(req.Name)
+	// Use the requested subdomain or generate one
+	subdomain, err := servicesubdomain.Resolve(db, req.Subdomain, req.Name)
+	if err != nil {
+		if errors.Is(err, servicesubdomain.ErrTaken) {
+			return response.Error(c, fiber.StatusConflict, err.Error())
+		}
+		return response.BadRequest(c, err.Error())
+	}
 
 	// Determine runtime
 	runtime := models.RuntimeImage
@@ -33,7 +42,10 @@ func createWebService(c *fiber.Ctx, user *models.User, req CreateServiceRequest,
 		// Convert to crypto type for encryption
 		cryptoEnvVars := make([]crypto.EnvironmentVariable, len(req.EnvironmentVariables))
 		for i, v := range req.EnvironmentVariables {
-			cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
+			cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
+		}
+		if err := servicestatus.CheckEnvVarLimits(cryptoEnvVars); err != nil {
+			return response.BadRequest(c, err.Error())
 		}
 		encryptedEnvVars, err := crypto.EncryptEnvVars(cryptoEnvVars)
 		if err != nil {
@@ -43,7 +55,7 @@ func createWebService(c *fiber.Ctx, user *models.User, req CreateServiceRequest,
 		// Convert back for storage
 		storageEnvVars := make([]EnvironmentVariable, len(encryptedEnvVars))
 		for i, v := range encryptedEnvVars {
-			storageEnvVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value}
+			storageEnvVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 		}
 		envVarsJSON, _ = json.Marshal(storageEnvVars)
 	}
@@ -60,10 +72,12 @@ func createWebService(c *fiber.Ctx, user *models.User, req CreateServiceRequest,
 		Name:              req.Name,
 		ServiceTypeID:     "web",
 		ProjectID:         req.ProjectID,
+		Region:            req.Region,
 		Subdomain:         &subdomain,
 		Runtime:           runtime,
 		InstanceTypeID:    req.InstanceTypeID,
 		AutoDeployEnabled: autoDeployEnabled,
+		DeployPathFilter:  req.DeployPathFilter,
 		HealthCheckPath:   req.HealthCheckPath,
 		StorageCapacity:   req.StorageCapacity,
 	}
@@ -78,13 +92,23 @@ func createWebService(c *fiber.Ctx, user *models.User, req CreateServiceRequest,
 		service.RepositoryName = req.RepositoryName
 		service.Branch = req.Branch
 		service.RuntimeFilePath = req.RuntimeFilePath
+		service.BuildContextPath = req.BuildContextPath
 	} else if runtime == models.RuntimeImage {
 		if req.DockerImageUrl != nil {
 			containerType := "docker"
 			service.ContainerRegistryType = &containerType
 			service.ContainerRegistryImageUri = req.DockerImageUrl
-			service.ContainerRegistryUsername = req.DockerUsername
-			service.ContainerRegistryPassword = req.DockerPassword
+			// Registry credentials are stored encrypted like environment variables
+			username, err := crypto.EncryptOptional(req.DockerUsername)
+			if err != nil {
+				return response.InternalServerError(c, "Failed to encrypt registry credentials")
+			}
+			password, err := crypto.EncryptOptional(req.DockerPassword)
+			if err != nil {
+				return response.InternalServerError(c, "Failed to encrypt registry credentials")
+			}
+			service.ContainerRegistryUsername = username
+			service.ContainerRegistryPassword = password
 		}
 	}
 
@@ -153,6 +177,7 @@ func createWebService(c *fiber.Ctx, user *models.User, req CreateServiceRequest,
 		"repositoryName":            service.RepositoryName,
 		"branch":                    service.Branch,
 		"runtimeFilePath":           service.RuntimeFilePath,
+		"buildContextPath":          service.BuildContextPath,
 		"runtime":                   service.Runtime,
 		"createdAt":                 service.CreatedAt,
 		"updatedAt":                 service.UpdatedAt,
@@ -163,12 +188,13 @@ func createWebService(c *fiber.Ctx, user *models.User, req CreateServiceRequest,
 		"healthCheckPath":           service.HealthCheckPath,
 		"autoScalingEnabled":        service.AutoScalingEnabled,
 		"autoDeployEnabled":         service.AutoDeployEnabled,
+		"deployPathFilter":          service.DeployPathFilter,
 		"maxReplicas":               service.MaxReplicas,
 		"minReplicas":               service.MinReplicas,
 		"replicas":                  service.Replicas,
 		"containerRegistryType":     service.ContainerRegistryType,
 		"containerRegistryImageUri": service.ContainerRegistryImageUri,
-		"containerRegistryUsername": service.ContainerRegistryUsername,
+		"containerRegistryUsername": crypto.DecryptOptional(service.ContainerRegistryUsername),
 		"instanceTypeId":            service.InstanceTypeID,
 		"currentReplicas":           service.CurrentReplicas,
 		"targetReplicas":            service.TargetReplicas,
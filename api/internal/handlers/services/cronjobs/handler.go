@@ -3,10 +3,12 @@ package cronjobs
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
-	"regexp"
 	"strings"
+	"time"
 
+	"github.com/deployra/deployra/api/internal/cron"
 	"github.com/deployra/deployra/api/internal/crypto"
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
@@ -41,13 +43,11 @@ func replaceEnvVarsInHeaders(headers map[string]string, envVars []envVar) map[st
 	return result
 }
 
-// cronExpressionRegex validates cron expression format
-var cronExpressionRegex = regexp.MustCompile(`^(\*|([0-9]|1[0-9]|2[0-9]|3[0-9]|4[0-9]|5[0-9])|\*\/([0-9]|1[0-9]|2[0-9]|3[0-9]|4[0-9]|5[0-9])) (\*|([0-9]|1[0-9]|2[0-3])|\*\/([0-9]|1[0-9]|2[0-3])) (\*|([1-9]|1[0-9]|2[0-9]|3[0-1])|\*\/([1-9]|1[0-9]|2[0-9]|3[0-1])) (\*|([1-9]|1[0-2])|\*\/([1-9]|1[0-2])) (\*|([0-6])|\*\/([0-6]))$`)
-
 // CreateCronJobRequest represents the request body for creating a cronjob
 type CreateCronJobRequest struct {
 	Name     string            `json:"name"`
 	Schedule string            `json:"schedule"`
+	Timezone string            `json:"timezone,omitempty"`
 	Path     string            `json:"path"`
 	Headers  map[string]string `json:"headers,omitempty"`
 	Enabled  *bool             `json:"enabled,omitempty"`
@@ -57,11 +57,39 @@ type CreateCronJobRequest struct {
 type UpdateCronJobRequest struct {
 	Name     *string            `json:"name,omitempty"`
 	Schedule *string            `json:"schedule,omitempty"`
+	Timezone *string            `json:"timezone,omitempty"`
 	Path     *string            `json:"path,omitempty"`
 	Headers  *map[string]string `json:"headers,omitempty"`
 	Enabled  *bool              `json:"enabled,omitempty"`
 }
 
+// cronJobSortColumns maps the sort query parameter of List to its column
+var cronJobSortColumns = map[string]string{
+	"name":      "name",
+	"nextRunAt": "nextRunAt",
+	"createdAt": "createdAt",
+}
+
+// Next runs returned by Get by default, and at most
+const (
+	defaultNextRuns = 5
+	maxNextRuns     = 50
+)
+
+// cronJobWithRuns is a cronjob with its upcoming runs
+type cronJobWithRuns struct {
+	models.CronJob
+	NextRuns []time.Time `json:"nextRuns"`
+}
+
+// nextRunAt returns the next run of a cronjob, nil when it is disabled
+func nextRunAt(cronJob models.CronJob) *time.Time {
+	if !cronJob.Enabled {
+		return nil
+	}
+	return cron.NextRun(cronJob.Schedule, cronJob.Timezone, time.Now())
+}
+
 // GET /api/services/:serviceId/cronjobs
 func List(c *fiber.Ctx) error {
 	db := database.GetDatabase()
@@ -76,6 +104,17 @@ func List(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Service ID is required")
 	}
 
+	// Parse query parameters
+	enabled := c.Query("enabled")
+	sort := c.Query("sort", "createdAt")
+	order := strings.ToLower(c.Query("order", "desc"))
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	if page < 1 || limit < 1 {
+		return response.BadRequest(c, "Page and limit must be positive")
+	}
+	skip := (page - 1) * limit
+
 	// Fetch the service with access check
 	var service models.Service
 	if err := db.Preload("Project.Organization").
@@ -89,13 +128,61 @@ func List(c *fiber.Ctx) error {
 		return response.Forbidden(c, "Service not found or access denied")
 	}
 
+	// Build query
+	query := db.Where("serviceId = ?", serviceID)
+
+	// Add enabled filter
+	switch enabled {
+	case "":
+		// All cronjobs
+	case "true":
+		query = query.Where("enabled = ?", true)
+	case "false":
+		query = query.Where("enabled = ?", false)
+	default:
+		return response.BadRequest(c, "Enabled must be true or false")
+	}
+
+	column, ok := cronJobSortColumns[sort]
+	if !ok {
+		return response.BadRequest(c, "Sort must be one of name, nextRunAt or createdAt")
+	}
+	if order != "asc" && order != "desc" {
+		return response.BadRequest(c, "Order must be asc or desc")
+	}
+	orderBy := column + " " + strings.ToUpper(order)
+	// Disabled cronjobs have no next run, keep them last
+	if sort == "nextRunAt" {
+		orderBy = "nextRunAt IS NULL, " + orderBy
+	}
+
+	// Count total
+	var total int64
+	query.Model(&models.CronJob{}).Count(&total)
+
 	// Fetch cronjobs
 	var cronJobs []models.CronJob
-	db.Where("serviceId = ?", serviceID).
-		Order("createdAt DESC").
+	query.Order(orderBy).
+		Order("id").
+		Offset(skip).
+		Limit(limit).
 		Find(&cronJobs)
 
-	return response.Success(c, cronJobs)
+	for i := range cronJobs {
+		cronJobs[i].NextRunAt = nextRunAt(cronJobs[i])
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	return response.Success(c, fiber.Map{
+		"cronJobs": cronJobs,
+		"pagination": fiber.Map{
+			"totalItems":   total,
+			"totalPages":   totalPages,
+			"currentPage":  page,
+			"itemsPerPage": limit,
+		},
+	})
 }
 
 // POST /api/services/:serviceId/cronjobs
@@ -128,9 +215,17 @@ func Create(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Path is required")
 	}
 
-	// Validate cron expression
-	if !cronExpressionRegex.MatchString(req.Schedule) {
-		return response.BadRequest(c, "Invalid cron expression format")
+	// Validate cron expression, the syntax kronjob's node-cron runs
+	if _, err := cron.Parse(req.Schedule); err != nil {
+		return response.BadRequest(c, "Invalid cron expression format: "+err.Error())
+	}
+
+	// Validate timezone
+	if req.Timezone == "" {
+		req.Timezone = cron.DefaultTimezone
+	}
+	if _, err := cron.LoadLocation(req.Timezone); err != nil {
+		return response.BadRequest(c, "Invalid timezone")
 	}
 
 	// Fetch the service with access check
@@ -177,11 +272,13 @@ func Create(c *fiber.Ctx) error {
 		ID:        utils.GenerateShortID(),
 		Name:      req.Name,
 		Schedule:  req.Schedule,
+		Timezone:  req.Timezone,
 		Path:      req.Path,
 		Headers:   headersJSON,
 		Enabled:   enabled,
 		ServiceID: serviceID,
 	}
+	cronJob.NextRunAt = nextRunAt(cronJob)
 
 	if err := db.Create(&cronJob).Error; err != nil {
 		return response.InternalServerError(c, "Failed to create cronjob")
@@ -190,13 +287,10 @@ func Create(c *fiber.Ctx) error {
 	// Parse environment variables from service
 	var envVars []envVar
 	if service.EnvironmentVariables != nil {
-		service.EnvironmentVariables.UnmarshalTo(&envVars)
-		// Decrypt environment variables
-		cryptoEnvVars := make([]crypto.EnvironmentVariable, len(envVars))
-		for i, v := range envVars {
-			cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
-		}
-		decryptedEnvVars, _ := crypto.DecryptEnvVars(cryptoEnvVars)
+		// Decrypt the secret environment variables
+		var storedEnvVars []crypto.EnvironmentVariable
+		service.EnvironmentVariables.UnmarshalTo(&storedEnvVars)
+		decryptedEnvVars, _ := crypto.DecryptEnvVars(storedEnvVars)
 		envVars = make([]envVar, len(decryptedEnvVars))
 		for i, v := range decryptedEnvVars {
 			envVars[i] = envVar{Key: v.Key, Value: v.Value}
@@ -214,6 +308,7 @@ func Create(c *fiber.Ctx) error {
 		ID:        cronJob.ID,
 		Name:      cronJob.Name,
 		Schedule:  cronJob.Schedule,
+		Timezone:  cronJob.Timezone,
 		Path:      cronJob.Path,
 		Headers:   processedHeaders,
 		Enabled:   cronJob.Enabled,
@@ -263,7 +358,26 @@ func Get(c *fiber.Ctx) error {
 		return response.NotFound(c, "CronJob not found")
 	}
 
-	return response.Success(c, cronJob)
+	runs := c.QueryInt("runs", defaultNextRuns)
+	if runs < 1 || runs > maxNextRuns {
+		return response.BadRequest(c, fmt.Sprintf("Runs must be between 1 and %d", maxNextRuns))
+	}
+
+	// Disabled cronjobs and schedules matching no time have no upcoming runs
+	nextRuns := []time.Time{}
+	if cronJob.Enabled {
+		schedule, err := cron.Parse(cronJob.Schedule)
+		loc, locErr := cron.LoadLocation(cronJob.Timezone)
+		if err == nil && locErr == nil {
+			nextRuns = schedule.NextRuns(time.Now(), loc, runs)
+		}
+	}
+	cronJob.NextRunAt = nil
+	if len(nextRuns) > 0 {
+		cronJob.NextRunAt = &nextRuns[0]
+	}
+
+	return response.Success(c, cronJobWithRuns{CronJob: cronJob, NextRuns: nextRuns})
 }
 
 // PATCH /api/services/:serviceId/cronjobs/:cronJobId
@@ -287,8 +401,20 @@ func Update(c *fiber.Ctx) error {
 	}
 
 	// Validate cron expression if provided
-	if req.Schedule != nil && !cronExpressionRegex.MatchString(*req.Schedule) {
-		return response.BadRequest(c, "Invalid cron expression format")
+	if req.Schedule != nil {
+		if _, err := cron.Parse(*req.Schedule); err != nil {
+			return response.BadRequest(c, "Invalid cron expression format: "+err.Error())
+		}
+	}
+
+	// Validate timezone if provided
+	if req.Timezone != nil {
+		if *req.Timezone == "" {
+			*req.Timezone = cron.DefaultTimezone
+		}
+		if _, err := cron.LoadLocation(*req.Timezone); err != nil {
+			return response.BadRequest(c, "Invalid timezone")
+		}
 	}
 
 	// Fetch the service with access check
@@ -331,6 +457,9 @@ func Update(c *fiber.Ctx) error {
 	if req.Schedule != nil {
 		updates["schedule"] = *req.Schedule
 	}
+	if req.Timezone != nil {
+		updates["timezone"] = *req.Timezone
+	}
 	if req.Path != nil {
 		updates["path"] = *req.Path
 	}
@@ -357,16 +486,19 @@ func Update(c *fiber.Ctx) error {
 	// Reload cronjob
 	db.First(&cronJob, "id = ?", cronJobID)
 
+	// The next run moves with the schedule
+	if req.Schedule != nil || req.Timezone != nil || req.Enabled != nil {
+		cronJob.NextRunAt = nextRunAt(cronJob)
+		db.Model(&models.CronJob{}).Where("id = ?", cronJobID).Update("nextRunAt", cronJob.NextRunAt)
+	}
+
 	// Parse environment variables from service
 	var envVars []envVar
 	if service.EnvironmentVariables != nil {
-		service.EnvironmentVariables.UnmarshalTo(&envVars)
-		// Decrypt environment variables
-		cryptoEnvVars := make([]crypto.EnvironmentVariable, len(envVars))
-		for i, v := range envVars {
-			cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
-		}
-		decryptedEnvVars, _ := crypto.DecryptEnvVars(cryptoEnvVars)
+		// Decrypt the secret environment variables
+		var storedEnvVars []crypto.EnvironmentVariable
+		service.EnvironmentVariables.UnmarshalTo(&storedEnvVars)
+		decryptedEnvVars, _ := crypto.DecryptEnvVars(storedEnvVars)
 		envVars = make([]envVar, len(decryptedEnvVars))
 		for i, v := range decryptedEnvVars {
 			envVars[i] = envVar{Key: v.Key, Value: v.Value}
@@ -388,6 +520,7 @@ func Update(c *fiber.Ctx) error {
 		ID:        cronJob.ID,
 		Name:      cronJob.Name,
 		Schedule:  cronJob.Schedule,
+		Timezone:  cronJob.Timezone,
 		Path:      cronJob.Path,
 		Headers:   processedHeaders,
 		Enabled:   cronJob.Enabled,
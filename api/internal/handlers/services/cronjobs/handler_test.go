@@ -0,0 +1,238 @@
+package cronjobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMain(m *testing.M) {
+	config.Load()
+	redisServer := miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+type listResponse struct {
+	Data struct {
+		CronJobs   []models.CronJob `json:"cronJobs"`
+		Pagination struct {
+			TotalItems   int64 `json:"totalItems"`
+			TotalPages   int   `json:"totalPages"`
+			CurrentPage  int   `json:"currentPage"`
+			ItemsPerPage int   `json:"itemsPerPage"`
+		} `json:"pagination"`
+	} `json:"data"`
+}
+
+// ownedService answers service lookups with a service of an organization owned by ownerID
+func ownedService(db *dbtest.DB, ownerID string) {
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1"})
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": ownerID})
+}
+
+// listCronJobs lists the cronjobs of svc1 as user1 with the given query string
+func listCronJobs(t *testing.T, query string) *http.Response {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/services/:serviceId/cronjobs", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user1"})
+		return c.Next()
+	}, List)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/services/svc1/cronjobs"+query, nil))
+	if err != nil {
+		t.Fatalf("list request: %v", err)
+	}
+	return resp
+}
+
+func TestListPagesCronJobs(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+	db.OnQuery("FROM `CronJob`", dbtest.Row{"id": "cron3", "name": "cleanup", "serviceId": "svc1", "enabled": true})
+	db.OnQuery("count(*)", dbtest.Row{"count(*)": int64(5)})
+
+	resp := listCronJobs(t, "?page=2&limit=2")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var decoded listResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if jobs := decoded.Data.CronJobs; len(jobs) != 1 || jobs[0].ID != "cron3" {
+		t.Errorf("cronJobs = %+v, want cron3", jobs)
+	}
+	pagination := decoded.Data.Pagination
+	if pagination.TotalItems != 5 || pagination.TotalPages != 3 || pagination.CurrentPage != 2 || pagination.ItemsPerPage != 2 {
+		t.Errorf("pagination = %+v, want page 2 of 3 with 2 of 5 cronjobs each", pagination)
+	}
+
+	finds := db.Statements("SELECT * FROM `CronJob`")
+	if len(finds) != 1 {
+		t.Fatalf("cronjob queries = %v, want 1", finds)
+	}
+	// The second page of 2 starts after the first 2 cronjobs
+	if sql := finds[0].SQL; !strings.Contains(sql, "LIMIT 2 OFFSET 2") || !strings.Contains(sql, "ORDER BY createdAt DESC,id") {
+		t.Errorf("query = %s, want the second page of the newest cronjobs", sql)
+	}
+	if !containsArg(finds[0], "svc1") {
+		t.Errorf("query args = %v, want it limited to svc1", finds[0].Args)
+	}
+}
+
+func TestListFiltersEnabled(t *testing.T) {
+	for _, enabled := range []string{"true", "false"} {
+		db := dbtest.New(t)
+		ownedService(db, "user1")
+
+		if resp := listCronJobs(t, "?enabled="+enabled); resp.StatusCode != http.StatusOK {
+			t.Fatalf("enabled=%s: status = %d, want %d", enabled, resp.StatusCode, http.StatusOK)
+		}
+		// The count and the page are both filtered
+		queries := db.Statements("FROM `CronJob`")
+		if len(queries) != 2 {
+			t.Fatalf("enabled=%s: cronjob queries = %v, want a count and a page", enabled, queries)
+		}
+		for _, query := range queries {
+			if !strings.Contains(query.SQL, "enabled = ?") || !containsArg(query, enabled) {
+				t.Errorf("enabled=%s: query %s %v is not filtered", enabled, query.SQL, query.Args)
+			}
+		}
+	}
+}
+
+func TestListWithoutFilterListsAll(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+
+	if resp := listCronJobs(t, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	for _, query := range db.Statements("FROM `CronJob`") {
+		if strings.Contains(query.SQL, "enabled") {
+			t.Errorf("query %s filters on enabled without a filter", query.SQL)
+		}
+	}
+}
+
+func TestListSortsByNextRun(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+
+	if resp := listCronJobs(t, "?sort=nextRunAt&order=asc"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	// Cronjobs without a next run come last
+	finds := db.Statements("SELECT * FROM `CronJob`")
+	if len(finds) != 1 || !strings.Contains(finds[0].SQL, "ORDER BY nextRunAt IS NULL, nextRunAt ASC,id") {
+		t.Errorf("cronjob queries = %v, want them sorted by next run", finds)
+	}
+}
+
+func TestListRejectsInvalidQuery(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+
+	for _, query := range []string{"?page=0", "?limit=-1", "?enabled=yes", "?sort=schedule", "?order=up"} {
+		if resp := listCronJobs(t, query); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+	if queries := db.Statements("FROM `CronJob`"); len(queries) != 0 {
+		t.Errorf("listed cronjobs for an invalid query: %v", queries)
+	}
+}
+
+func TestListChecksOwnership(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "someone-else")
+
+	if resp := listCronJobs(t, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if queries := db.Statements("FROM `CronJob`"); len(queries) != 0 {
+		t.Errorf("listed the cronjobs of a service of another user")
+	}
+}
+
+// updateCronJob updates cron1 of svc1 as user1
+func updateCronJob(t *testing.T, body string) *http.Response {
+	t.Helper()
+	app := fiber.New()
+	app.Patch("/services/:serviceId/cronjobs/:cronJobId", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user1"})
+		return c.Next()
+	}, Update)
+
+	req := httptest.NewRequest(http.MethodPatch, "/services/svc1/cronjobs/cron1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("update request: %v", err)
+	}
+	return resp
+}
+
+func TestUpdateAcceptsNodeCronSchedule(t *testing.T) {
+	for _, schedule := range []string{"0 8 * * MON-FRI", "0 0 1 JAN,JUL *", "0 8 * * 7", "*/30 * * * * *"} {
+		db := dbtest.New(t)
+		ownedService(db, "user1")
+		db.OnQuery("FROM `CronJob`", dbtest.Row{"id": "cron1", "name": "report", "serviceId": "svc1", "schedule": schedule, "enabled": true})
+
+		body, _ := json.Marshal(map[string]string{"schedule": schedule})
+		if resp := updateCronJob(t, string(body)); resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", schedule, resp.StatusCode, http.StatusOK)
+			continue
+		}
+		updates := db.Statements("UPDATE `CronJob`")
+		if len(updates) != 2 || !containsArg(updates[0], schedule) || !strings.Contains(updates[1].SQL, "`nextRunAt`=?") || updates[1].Args[0] == nil {
+			t.Errorf("%s: updates = %v, want the schedule and its next run saved", schedule, updates)
+		}
+	}
+}
+
+func TestUpdateRejectsInvalidSchedule(t *testing.T) {
+	for _, schedule := range []string{"0 8 * *", "0 8 * * MON-FRY", "0 24 * * *", "0 8 * * 8"} {
+		db := dbtest.New(t)
+		ownedService(db, "user1")
+
+		body, _ := json.Marshal(map[string]string{"schedule": schedule})
+		if resp := updateCronJob(t, string(body)); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", schedule, resp.StatusCode, http.StatusBadRequest)
+		}
+		if updates := db.Statements("UPDATE `CronJob`"); len(updates) != 0 {
+			t.Errorf("%s: saved an invalid schedule: %v", schedule, updates)
+		}
+	}
+}
+
+// containsArg reports whether a statement was sent with an argument printing as want
+func containsArg(statement dbtest.Statement, want string) bool {
+	for _, arg := range statement.Args {
+		if fmt.Sprint(arg) == want {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package envvars
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/url"
 	"regexp"
@@ -13,6 +14,7 @@ import (
 	"github.com/deployra/deployra/api/internal/deploy"
 	"github.com/deployra/deployra/api/internal/models"
 	"github.com/deployra/deployra/api/internal/redis"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
 	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/gofiber/fiber/v2"
 )
@@ -24,6 +26,27 @@ var ENV_KEY_REGEX = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
 type EnvironmentVariable struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
+
+	// IsSecret is false for plain config, unset means secret
+	IsSecret *bool `json:"isSecret,omitempty"`
+}
+
+// secret reports whether the value of an environment variable is masked and encrypted
+func (v EnvironmentVariable) secret() bool {
+	return v.IsSecret == nil || *v.IsSecret
+}
+
+// describeEnvVars formats environment variables for logs, with secret values masked
+func describeEnvVars(envVars []EnvironmentVariable) string {
+	parts := make([]string, len(envVars))
+	for i, v := range envVars {
+		if v.secret() {
+			parts[i] = v.Key + "=***"
+		} else {
+			parts[i] = v.Key + "=" + v.Value
+		}
+	}
+	return strings.Join(parts, ", ")
 }
 
 // checkEnvVariableAccess checks if user has access to environment variables
@@ -55,15 +78,22 @@ func replaceEnvVarsInHeaders(headers map[string]string, envVars []EnvironmentVar
 // UpdateEnvVarsRequest represents the request body for updating environment variables
 type UpdateEnvVarsRequest struct {
 	Variables []EnvironmentVariable `json:"variables"`
+
+	// Version is the version of the service the client read, the update is rejected when the
+	// service changed since
+	Version *int `json:"version"`
 }
 
 // DeleteEnvVarsRequest represents the request body for deleting environment variables
 type DeleteEnvVarsRequest struct {
 	Keys []string `json:"keys"`
+
+	// Version is the version of the service the client read, as in UpdateEnvVarsRequest
+	Version *int `json:"version"`
 }
 
 // GET /api/services/:serviceId/environment-variables
-// Returns all environment variables with masked values
+// Returns all environment variables, secrets with masked values
 func List(c *fiber.Ctx) error {
 	db := database.GetDatabase()
 
@@ -94,12 +124,18 @@ func List(c *fiber.Ctx) error {
 	var envVars []EnvironmentVariable
 	service.EnvironmentVariables.UnmarshalTo(&envVars)
 
-	// Mask all values
+	// Mask secret values, plain config is stored unencrypted
 	maskedVars := make([]EnvironmentVariable, len(envVars))
 	for i, v := range envVars {
+		secret := v.secret()
+		value := v.Value
+		if secret {
+			value = "***"
+		}
 		maskedVars[i] = EnvironmentVariable{
-			Key:   v.Key,
-			Value: "***",
+			Key:      v.Key,
+			Value:    value,
+			IsSecret: &secret,
 		}
 	}
 
@@ -148,7 +184,7 @@ func Get(c *fiber.Ctx) error {
 	// Decrypt environment variables
 	cryptoEnvVars := make([]crypto.EnvironmentVariable, len(envVars))
 	for i, v := range envVars {
-		cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
+		cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 	}
 	decryptedEnvVars, _ := crypto.DecryptEnvVars(cryptoEnvVars)
 
@@ -210,6 +246,9 @@ func Update(c *fiber.Ctx) error {
 	if !checkEnvVariableAccess(user, &service) {
 		return response.Forbidden(c, "Service not found or access denied")
 	}
+	if err := servicestatus.CheckVersion(service, req.Version); err != nil {
+		return response.Conflict(c, err.Error())
+	}
 
 	// Get current environment variables and decrypt them
 	var currentEnvVars []EnvironmentVariable
@@ -218,21 +257,26 @@ func Update(c *fiber.Ctx) error {
 	// Decrypt existing variables for comparison
 	cryptoEnvVars := make([]crypto.EnvironmentVariable, len(currentEnvVars))
 	for i, v := range currentEnvVars {
-		cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
+		cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 	}
 	decryptedEnvVars, _ := crypto.DecryptEnvVars(cryptoEnvVars)
 
 	// Convert back to local type
 	currentDecrypted := make([]EnvironmentVariable, len(decryptedEnvVars))
 	for i, v := range decryptedEnvVars {
-		currentDecrypted[i] = EnvironmentVariable{Key: v.Key, Value: v.Value}
+		currentDecrypted[i] = EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 	}
 
 	// Update or add variables (working with decrypted values)
+	changed := make([]EnvironmentVariable, 0, len(req.Variables))
 	for _, newVar := range req.Variables {
 		found := false
 		for i, existingVar := range currentDecrypted {
 			if existingVar.Key == newVar.Key {
+				// A variable updated without the flag stays what it was
+				if newVar.IsSecret == nil {
+					newVar.IsSecret = existingVar.IsSecret
+				}
 				currentDecrypted[i] = newVar
 				found = true
 				break
@@ -241,12 +285,16 @@ func Update(c *fiber.Ctx) error {
 		if !found {
 			currentDecrypted = append(currentDecrypted, newVar)
 		}
+		changed = append(changed, newVar)
 	}
 
 	// Encrypt before storing
 	toEncrypt := make([]crypto.EnvironmentVariable, len(currentDecrypted))
 	for i, v := range currentDecrypted {
-		toEncrypt[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
+		toEncrypt[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
+	}
+	if err := servicestatus.CheckEnvVarLimits(toEncrypt); err != nil {
+		return response.BadRequest(c, err.Error())
 	}
 	encryptedEnvVars, err := crypto.EncryptEnvVars(toEncrypt)
 	if err != nil {
@@ -256,14 +304,18 @@ func Update(c *fiber.Ctx) error {
 	// Convert back to local type for storage
 	currentEnvVars = make([]EnvironmentVariable, len(encryptedEnvVars))
 	for i, v := range encryptedEnvVars {
-		currentEnvVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value}
+		currentEnvVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 	}
 
 	// Update service
 	envJSON, _ := json.Marshal(currentEnvVars)
-	if err := db.Model(&service).Update("environmentVariables", envJSON).Error; err != nil {
+	if err := servicestatus.UpdateVersioned(db, serviceID, service.Version, map[string]interface{}{"environmentVariables": envJSON}); err != nil {
+		if errors.Is(err, servicestatus.ErrStaleService) {
+			return response.Conflict(c, err.Error())
+		}
 		return response.InternalServerError(c, "Failed to update environment variables")
 	}
+	log.Printf("Updated environment variables of service %s: %s", serviceID, describeEnvVars(changed))
 
 	// Trigger redeploy if service is running
 	if service.Status == models.ServiceStatusRunning ||
@@ -321,6 +373,9 @@ func Delete(c *fiber.Ctx) error {
 	if !checkEnvVariableAccess(user, &service) {
 		return response.Forbidden(c, "Service not found or access denied")
 	}
+	if err := servicestatus.CheckVersion(service, req.Version); err != nil {
+		return response.Conflict(c, err.Error())
+	}
 
 	// Get current environment variables and decrypt them
 	var currentEnvVars []EnvironmentVariable
@@ -329,14 +384,14 @@ func Delete(c *fiber.Ctx) error {
 	// Decrypt existing variables
 	cryptoEnvVars := make([]crypto.EnvironmentVariable, len(currentEnvVars))
 	for i, v := range currentEnvVars {
-		cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
+		cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 	}
 	decryptedEnvVars, _ := crypto.DecryptEnvVars(cryptoEnvVars)
 
 	// Convert back to local type
 	currentDecrypted := make([]EnvironmentVariable, len(decryptedEnvVars))
 	for i, v := range decryptedEnvVars {
-		currentDecrypted[i] = EnvironmentVariable{Key: v.Key, Value: v.Value}
+		currentDecrypted[i] = EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 	}
 
 	// Filter out variables to delete (working with decrypted values)
@@ -357,7 +412,7 @@ func Delete(c *fiber.Ctx) error {
 	// Encrypt before storing
 	toEncrypt := make([]crypto.EnvironmentVariable, len(updatedDecrypted))
 	for i, v := range updatedDecrypted {
-		toEncrypt[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
+		toEncrypt[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 	}
 	encryptedEnvVars, err := crypto.EncryptEnvVars(toEncrypt)
 	if err != nil {
@@ -367,12 +422,15 @@ func Delete(c *fiber.Ctx) error {
 	// Convert back to local type for storage
 	updatedEnvVars := make([]EnvironmentVariable, len(encryptedEnvVars))
 	for i, v := range encryptedEnvVars {
-		updatedEnvVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value}
+		updatedEnvVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 	}
 
 	// Update service
 	envJSON, _ := json.Marshal(updatedEnvVars)
-	if err := db.Model(&service).Update("environmentVariables", envJSON).Error; err != nil {
+	if err := servicestatus.UpdateVersioned(db, serviceID, service.Version, map[string]interface{}{"environmentVariables": envJSON}); err != nil {
+		if errors.Is(err, servicestatus.ErrStaleService) {
+			return response.Conflict(c, err.Error())
+		}
 		return response.InternalServerError(c, "Failed to delete environment variables")
 	}
 
@@ -415,16 +473,7 @@ func updateCronJobsForService(serviceID, projectID string, envVars []Environment
 		}
 
 		// Publish update event to Redis for the cron executor with full payload
-		cronJobEvent := redis.CronJobEvent{
-			ID:        cronjob.ID,
-			Name:      cronjob.Name,
-			Schedule:  cronjob.Schedule,
-			Path:      cronjob.Path,
-			Headers:   processedHeaders,
-			Enabled:   cronjob.Enabled,
-			ServiceID: cronjob.ServiceID,
-			ProjectID: projectID,
-		}
+		cronJobEvent := cronJobUpdatedEvent(cronjob, projectID, processedHeaders)
 		if err := redis.PublishCronJobUpdated(ctx, cronJobEvent); err != nil {
 			log.Printf("Failed to publish cronjob updated event for %s: %v", cronjob.ID, err)
 		} else {
@@ -432,3 +481,19 @@ func updateCronJobsForService(serviceID, projectID string, envVars []Environment
 		}
 	}
 }
+
+// cronJobUpdatedEvent returns the event republishing a cronjob with headers resolved against
+// new env vars, the executor replaces the whole job with it
+func cronJobUpdatedEvent(cronjob models.CronJob, projectID string, headers map[string]string) redis.CronJobEvent {
+	return redis.CronJobEvent{
+		ID:        cronjob.ID,
+		Name:      cronjob.Name,
+		Schedule:  cronjob.Schedule,
+		Timezone:  cronjob.Timezone,
+		Path:      cronjob.Path,
+		Headers:   headers,
+		Enabled:   cronjob.Enabled,
+		ServiceID: cronjob.ServiceID,
+		ProjectID: projectID,
+	}
+}
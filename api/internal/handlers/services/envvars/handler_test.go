@@ -0,0 +1,81 @@
+package envvars
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCronJobUpdatedEventKeepsTimezone(t *testing.T) {
+	cronjob := models.CronJob{
+		ID:        "cron-1",
+		Name:      "nightly",
+		Schedule:  "0 3 * * *",
+		Timezone:  "Europe/Istanbul",
+		Path:      "/jobs/nightly",
+		Enabled:   true,
+		ServiceID: "service-1",
+	}
+
+	event := cronJobUpdatedEvent(cronjob, "project-1", map[string]string{"Authorization": "Bearer token"})
+
+	if event.Timezone != "Europe/Istanbul" {
+		t.Fatalf("Timezone = %q, want Europe/Istanbul", event.Timezone)
+	}
+	if event.Schedule != cronjob.Schedule || event.ProjectID != "project-1" || event.Headers["Authorization"] != "Bearer token" {
+		t.Fatalf("event = %+v", event)
+	}
+}
+
+func TestListMasksOnlySecrets(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1", "environmentVariables": []byte(`[
+		{"key": "LOG_LEVEL", "value": "debug", "isSecret": false},
+		{"key": "API_KEY", "value": "encrypted-key", "isSecret": true},
+		{"key": "LEGACY_TOKEN", "value": "encrypted-token"}
+	]`)})
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": "user1"})
+
+	app := fiber.New()
+	app.Get("/services/:serviceId/environment-variables", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user1"})
+		return c.Next()
+	}, List)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/services/svc1/environment-variables", nil))
+	if err != nil {
+		t.Fatalf("list request: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var decoded struct {
+		Data []EnvironmentVariable `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	// Variables stored without the flag are secrets
+	want := []EnvironmentVariable{
+		{Key: "LOG_LEVEL", Value: "debug", IsSecret: utils.Ptr(false)},
+		{Key: "API_KEY", Value: "***", IsSecret: utils.Ptr(true)},
+		{Key: "LEGACY_TOKEN", Value: "***", IsSecret: utils.Ptr(true)},
+	}
+	if !reflect.DeepEqual(decoded.Data, want) {
+		t.Errorf("env vars = %s, want %s", describe(decoded.Data), describe(want))
+	}
+}
+
+// describe formats environment variables with their secret flag for test failures
+func describe(envVars []EnvironmentVariable) string {
+	out, _ := json.Marshal(envVars)
+	return string(out)
+}
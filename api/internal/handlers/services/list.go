@@ -3,6 +3,7 @@ package services
 import (
 	"strings"
 
+	"github.com/deployra/deployra/api/internal/crypto"
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
 	"github.com/deployra/deployra/api/pkg/response"
@@ -71,6 +72,7 @@ func List(c *fiber.Ctx) error {
 			"repositoryName":            service.RepositoryName,
 			"branch":                    service.Branch,
 			"runtimeFilePath":           service.RuntimeFilePath,
+			"buildContextPath":          service.BuildContextPath,
 			"runtime":                   service.Runtime,
 			"createdAt":                 service.CreatedAt,
 			"updatedAt":                 service.UpdatedAt,
@@ -81,12 +83,13 @@ func List(c *fiber.Ctx) error {
 			"healthCheckPath":           service.HealthCheckPath,
 			"autoScalingEnabled":        service.AutoScalingEnabled,
 			"autoDeployEnabled":         service.AutoDeployEnabled,
+			"deployPathFilter":          service.DeployPathFilter,
 			"maxReplicas":               service.MaxReplicas,
 			"minReplicas":               service.MinReplicas,
 			"replicas":                  service.Replicas,
 			"containerRegistryType":     service.ContainerRegistryType,
 			"containerRegistryImageUri": service.ContainerRegistryImageUri,
-			"containerRegistryUsername": service.ContainerRegistryUsername,
+			"containerRegistryUsername": crypto.DecryptOptional(service.ContainerRegistryUsername),
 			"instanceTypeId":            service.InstanceTypeID,
 			"instanceType":              service.InstanceType,
 			"serviceType":               service.ServiceType,
@@ -99,6 +102,8 @@ func List(c *fiber.Ctx) error {
 			"storageCapacity":           service.StorageCapacity,
 			"scalingStatus":             service.ScalingStatus,
 			"containerCommand":          service.ContainerCommand,
+			"containerArgs":             service.ContainerArgs,
+			"stopped":                   service.Stopped,
 		}
 	}
 
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /api/services/:serviceId/connection
+// Returns what clients need to connect to a database or memory service, the password only
+// with ?showPassword=true
+func GetConnection(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Preload("Credentials").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access - credentials are only shown to the owner, no admin bypass
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if !servicestatus.CredentialServiceTypes[service.ServiceTypeID] {
+		return response.BadRequest(c, "Only database and memory services have connection info")
+	}
+	if service.Credentials == nil {
+		return response.NotFound(c, "Service has no credentials")
+	}
+
+	return response.Success(c, connectionInfo(&service, config.Get(), c.QueryBool("showPassword")))
+}
+
+// connectionInfo builds the connection info of a service with credentials. Credential
+// passwords are stored as they are, not encrypted, so the password is returned unchanged.
+func connectionInfo(service *models.Service, cfg *config.Config, showPassword bool) fiber.Map {
+	credential := service.Credentials
+	host := credential.Host
+	if host == "" {
+		host = cfg.DBProxyHost
+	}
+	if host == "" {
+		host = cfg.AppDomain
+	}
+
+	var password string
+	if showPassword {
+		password = credential.Password
+	}
+
+	result := fiber.Map{
+		"host":             host,
+		"port":             credential.Port,
+		"internalHost":     servicestatus.InternalHost(service.ID),
+		"username":         credential.Username,
+		"database":         credential.Database,
+		"connectionString": servicestatus.ConnectionString(service.ServiceTypeID, host, credential.Port, credential.Username, password, credential.Database),
+	}
+	if password != "" {
+		result["password"] = password
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+func TestConnectionInfoReturnsStoredPassword(t *testing.T) {
+	if err := crypto.Initialize(); err != nil {
+		t.Fatalf("crypto.Initialize: %v", err)
+	}
+	// A generated password that happens to decrypt must still come back as it is stored
+	stored, err := crypto.Encrypt("something-else")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	service := &models.Service{
+		ID:            "db1",
+		ServiceTypeID: "mysql",
+		Credentials: &models.ServiceCredential{
+			Host:     "db.example.com",
+			Port:     3306,
+			Username: "app",
+			Password: stored,
+			Database: "app",
+		},
+	}
+
+	info := connectionInfo(service, config.Get(), true)
+	if info["password"] != stored {
+		t.Errorf("password = %v, want the stored value %q", info["password"], stored)
+	}
+	if s, _ := info["connectionString"].(string); !strings.HasPrefix(s, "mysql://app:") || !strings.Contains(s, "@db.example.com:3306/app") {
+		t.Errorf("connectionString = %q", s)
+	}
+
+	// Without showPassword neither the password nor the connection string carry it
+	info = connectionInfo(service, config.Get(), false)
+	if _, ok := info["password"]; ok {
+		t.Error("password returned without showPassword")
+	}
+	if s := info["connectionString"]; s != "mysql://app@db.example.com:3306/app" {
+		t.Errorf("connectionString = %v, want it without a password", s)
+	}
+}
+
+// getConnection asks for the connection info of svc1 as user1
+func getConnection(t *testing.T, query string) *http.Response {
+	t.Helper()
+	app := serviceApp(&models.User{ID: "user1"}, http.MethodGet, "/services/:serviceId/connection", GetConnection)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/services/svc1/connection"+query, nil))
+	if err != nil {
+		t.Fatalf("connection request: %v", err)
+	}
+	return resp
+}
+
+func TestGetConnectionChecksOwnership(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1", "serviceTypeId": "postgresql"})
+	db.OnQuery("FROM `ServiceCredential`", dbtest.Row{"id": "cred1", "serviceId": "svc1", "port": int64(5432), "username": "app", "password": "secret"})
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": "someone-else"})
+
+	resp := getConnection(t, "?showPassword=true")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if body, _ := io.ReadAll(resp.Body); strings.Contains(string(body), "secret") {
+		t.Errorf("body = %s, want the password of another user's service left out", body)
+	}
+}
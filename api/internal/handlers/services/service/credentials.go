@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// POST /api/services/:serviceId/credentials/rotate
+func RotateCredentials(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if !servicestatus.CredentialServiceTypes[service.ServiceTypeID] {
+		return response.BadRequest(c, "Only database and memory services have credentials")
+	}
+
+	credential, err := servicestatus.RotateCredentials(c.UserContext(), service)
+	if err != nil {
+		if errors.Is(err, servicestatus.ErrServiceNotRunning) {
+			return response.Error(c, fiber.StatusConflict, fmt.Sprintf("Credentials cannot be rotated while the service is %s", service.Status))
+		}
+		if errors.Is(err, servicestatus.ErrNoCredentials) {
+			return response.NotFound(c, "Service has no credentials")
+		}
+		log.Printf("Error rotating credentials of service %s: %v", service.ID, err)
+		return response.InternalServerError(c, "Failed to rotate credentials")
+	}
+
+	// The new password is stored, and given to the services using it, once the server uses it
+	return response.Success(c, fiber.Map{
+		"message": "Credential rotation initiated",
+		"pending": true,
+		"credentials": fiber.Map{
+			"host":     credential.Host,
+			"port":     credential.Port,
+			"username": credential.Username,
+			"password": credential.Password,
+			"database": credential.Database,
+		},
+	})
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/customdomain"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// customDomainTarget returns the host a custom domain of the service must point to, its
+// subdomain on the platform or the app domain itself
+func customDomainTarget(service models.Service, appDomain string) string {
+	if service.Subdomain != nil && *service.Subdomain != "" {
+		return *service.Subdomain + "." + appDomain
+	}
+	return appDomain
+}
+
+// POST /api/services/:serviceId/custom-domain/verify
+func VerifyCustomDomain(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if service.CustomDomain == nil || *service.CustomDomain == "" {
+		return response.BadRequest(c, "Service has no custom domain")
+	}
+	appDomain := config.Get().AppDomain
+	if appDomain == "" {
+		return response.BadRequest(c, "Custom domains can't be verified without an app domain")
+	}
+
+	target := customDomainTarget(service, appDomain)
+	if err := customdomain.Verify(*service.CustomDomain, target); err != nil {
+		if errors.Is(err, customdomain.ErrNotPointed) {
+			return response.BadRequest(c, err.Error())
+		}
+		log.Printf("Error verifying custom domain %s: %v", *service.CustomDomain, err)
+		return response.InternalServerError(c, "Failed to verify custom domain")
+	}
+
+	// Only the domain that was checked is marked, a concurrent change keeps it unverified
+	verifiedAt := time.Now()
+	if err := db.Model(&models.Service{}).
+		Where("id = ? AND customDomain = ?", serviceID, *service.CustomDomain).
+		Update("customDomainVerifiedAt", verifiedAt).Error; err != nil {
+		return response.InternalServerError(c, "Failed to save custom domain verification")
+	}
+
+	return response.Success(c, fiber.Map{
+		"customDomain": service.CustomDomain,
+		"verified":     true,
+		"verifiedAt":   verifiedAt,
+	})
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"log"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// POST /api/services/:serviceId/deploy-token
+func CreateDeployToken(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if service.Runtime != models.RuntimeImage {
+		return response.BadRequest(c, "Deploy tokens are only available for services deployed from an image")
+	}
+
+	// A new token replaces the previous one
+	token, err := crypto.GenerateDeployToken()
+	if err != nil {
+		log.Printf("Error generating deploy token: %v", err)
+		return response.InternalServerError(c, "Failed to generate deploy token")
+	}
+
+	if err := db.Model(&models.Service{}).Where("id = ?", serviceID).
+		Update("deployTokenHash", crypto.HashDeployToken(token)).Error; err != nil {
+		return response.InternalServerError(c, "Failed to save deploy token")
+	}
+
+	// The token is only shown once, only its hash is stored
+	return response.Success(c, fiber.Map{
+		"token": token,
+	})
+}
+
+// DELETE /api/services/:serviceId/deploy-token
+func DeleteDeployToken(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if err := db.Model(&models.Service{}).Where("id = ?", serviceID).
+		Update("deployTokenHash", nil).Error; err != nil {
+		return response.InternalServerError(c, "Failed to revoke deploy token")
+	}
+
+	return response.Success(c, fiber.Map{
+		"message": "Deploy token revoked",
+	})
+}
@@ -2,16 +2,22 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/customdomain"
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/deploy"
 	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
 	"github.com/deployra/deployra/api/internal/utils"
 	"github.com/deployra/deployra/api/pkg/ecr"
+	"github.com/deployra/deployra/api/pkg/github"
 	"github.com/deployra/deployra/api/pkg/kubernetes"
 	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/gofiber/fiber/v2"
@@ -71,6 +77,7 @@ func Get(c *fiber.Ctx) error {
 		"repositoryName":            service.RepositoryName,
 		"branch":                    service.Branch,
 		"runtimeFilePath":           service.RuntimeFilePath,
+		"buildContextPath":          service.BuildContextPath,
 		"runtime":                   service.Runtime,
 		"createdAt":                 service.CreatedAt,
 		"updatedAt":                 service.UpdatedAt,
@@ -81,12 +88,13 @@ func Get(c *fiber.Ctx) error {
 		"healthCheckPath":           service.HealthCheckPath,
 		"autoScalingEnabled":        service.AutoScalingEnabled,
 		"autoDeployEnabled":         service.AutoDeployEnabled,
+		"deployPathFilter":          service.DeployPathFilter,
 		"maxReplicas":               service.MaxReplicas,
 		"minReplicas":               service.MinReplicas,
 		"replicas":                  service.Replicas,
 		"containerRegistryType":     service.ContainerRegistryType,
 		"containerRegistryImageUri": service.ContainerRegistryImageUri,
-		"containerRegistryUsername": service.ContainerRegistryUsername,
+		"containerRegistryUsername": crypto.DecryptOptional(service.ContainerRegistryUsername),
 		"instanceTypeId":            service.InstanceTypeID,
 		"instanceType":              service.InstanceType,
 		"serviceType":               service.ServiceType,
@@ -100,6 +108,15 @@ func Get(c *fiber.Ctx) error {
 		"storageCapacity":           service.StorageCapacity,
 		"scalingStatus":             service.ScalingStatus,
 		"containerCommand":          service.ContainerCommand,
+		"containerArgs":             service.ContainerArgs,
+		"ingressPort":               service.IngressPort,
+		"stopped":                   service.Stopped,
+		"version":                   service.Version,
+
+		"deployOnlyProtectedBranches": service.DeployOnlyProtectedBranches,
+		"deployOn":                    service.DeployOn,
+		"primaryDomain":               service.PrimaryDomain,
+		"redirectWww":                 service.RedirectWww,
 	})
 }
 
@@ -140,9 +157,12 @@ func Delete(c *fiber.Ctx) error {
 		}()
 	}
 
-	// Soft delete the service
+	// Soft delete the service, releasing its ingress port for other services
 	now := time.Now()
-	if err := db.Model(&service).Update("deletedAt", now).Error; err != nil {
+	if err := db.Model(&service).Updates(map[string]interface{}{
+		"deletedAt":   now,
+		"ingressPort": nil,
+	}).Error; err != nil {
 		return response.InternalServerError(c, "Failed to delete service")
 	}
 
@@ -172,9 +192,13 @@ func Update(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Service ID is required")
 	}
 
+	// A JSON Patch or JSON Merge Patch applies to the current service, a flat body sets fields
+	patchType := patchContentType(c)
 	var req UpdateServiceRequest
-	if err := c.BodyParser(&req); err != nil {
-		return response.BadRequest(c, "Invalid request body")
+	if patchType == "" {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body")
+		}
 	}
 
 	// Fetch the service with access check
@@ -192,12 +216,126 @@ func Update(c *fiber.Ctx) error {
 		return response.Forbidden(c, "Service not found or access denied")
 	}
 
+	if patchType != "" {
+		patched, err := patchUpdateRequest(service, patchType, c.Body())
+		if errors.Is(err, servicestatus.ErrStaleService) {
+			return response.Conflict(c, err.Error())
+		}
+		if err != nil {
+			return response.BadRequest(c, err.Error())
+		}
+		req = *patched
+	}
+
+	// The client edited an older version of the service
+	if err := servicestatus.CheckVersion(service, req.Version); err != nil {
+		return response.Conflict(c, err.Error())
+	}
+
 	// Validate instance type if provided
+	instanceType := service.InstanceType
+	var planChange *PlanChange
 	if req.InstanceTypeID != nil && *req.InstanceTypeID != service.InstanceTypeID {
 		var newInstanceType models.InstanceType
-		if err := db.Where("id = ?", *req.InstanceTypeID).First(&newInstanceType).Error; err != nil {
+		if err := db.Preload("InstanceTypeGroup").Where("id = ?", *req.InstanceTypeID).First(&newInstanceType).Error; err != nil {
 			return response.BadRequest(c, "Invalid instance type")
 		}
+		if err := servicestatus.ValidateInstanceTypeChange(db, service, newInstanceType, req.ForceInstanceTypeChange); err != nil {
+			return response.BadRequest(c, err.Error())
+		}
+		planChange = newPlanChange(service.InstanceType, newInstanceType)
+		instanceType = newInstanceType
+	}
+
+	// Validate storage capacity against the instance type the service will run on
+	storageResized := false
+	if req.StorageCapacity != nil {
+		if err := servicestatus.ValidateStorageCapacity(service, instanceType, *req.StorageCapacity); err != nil {
+			return response.BadRequest(c, err.Error())
+		}
+		storageResized = service.StorageCapacity == nil || *req.StorageCapacity != *service.StorageCapacity
+	}
+
+	// Normalize the custom domain so it matches SNI in the proxy, an empty one removes it
+	var customDomain *string
+	if req.CustomDomain != nil && strings.TrimSpace(*req.CustomDomain) != "" {
+		domain, err := customdomain.Normalize(*req.CustomDomain)
+		if err != nil {
+			return response.BadRequest(c, err.Error())
+		}
+		available, err := customdomain.IsAvailable(db, domain, service.ID)
+		if err != nil {
+			log.Printf("Error checking custom domain %s: %v", domain, err)
+			return response.InternalServerError(c, "Failed to check custom domain")
+		}
+		if !available {
+			return response.Error(c, fiber.StatusConflict, customdomain.ErrTaken.Error())
+		}
+		customDomain = &domain
+	}
+
+	// The www redirect serves the other form of the custom domain too, so it must be free,
+	// and the primary domain has to be one of the two forms
+	effectiveDomain := service.CustomDomain
+	if req.CustomDomain != nil {
+		effectiveDomain = customDomain
+	}
+	redirectWww := service.RedirectWww
+	if req.RedirectWww != nil {
+		redirectWww = *req.RedirectWww
+	}
+	if redirectWww && effectiveDomain == nil {
+		if req.RedirectWww != nil {
+			return response.BadRequest(c, "Set a custom domain before redirecting www")
+		}
+		// The custom domain was removed, there is nothing left to redirect
+		redirectWww = false
+	}
+	if redirectWww {
+		variant := customdomain.WwwVariant(*effectiveDomain)
+		available, err := customdomain.IsAvailable(db, variant, service.ID)
+		if err != nil {
+			log.Printf("Error checking custom domain %s: %v", variant, err)
+			return response.InternalServerError(c, "Failed to check custom domain")
+		}
+		if !available {
+			return response.Error(c, fiber.StatusConflict, fmt.Sprintf("%s is already used by another service", variant))
+		}
+	}
+	primaryDomain := service.PrimaryDomain
+	if req.PrimaryDomain != nil {
+		primaryDomain = nil
+		if strings.TrimSpace(*req.PrimaryDomain) != "" {
+			domain, err := customdomain.Normalize(*req.PrimaryDomain)
+			if err != nil {
+				return response.BadRequest(c, err.Error())
+			}
+			primaryDomain = &domain
+		}
+	}
+	if primaryDomain != nil && (effectiveDomain == nil ||
+		(*primaryDomain != *effectiveDomain && *primaryDomain != customdomain.WwwVariant(*effectiveDomain))) {
+		if req.PrimaryDomain != nil {
+			return response.BadRequest(c, "Primary domain must be the custom domain or its www form")
+		}
+		// The custom domain changed, it is primary again
+		primaryDomain = nil
+	}
+
+	// Services deploy on every push, or only when a pull request is merged into their branch
+	if req.DeployOn != nil {
+		switch models.DeployTrigger(*req.DeployOn) {
+		case models.DeployTriggerPush, models.DeployTriggerMergeToDefault:
+		default:
+			return response.BadRequest(c, "Deploy trigger must be push or mergeToDefault")
+		}
+	}
+
+	// Validate port settings if provided
+	if len(req.PortSettings) > 0 {
+		if err := validatePortSettings(service.ServiceTypeID, req.PortSettings); err != nil {
+			return response.BadRequest(c, err.Error())
+		}
 	}
 
 	// Build update map
@@ -210,7 +348,10 @@ func Update(c *fiber.Ctx) error {
 		// Convert to crypto type for encryption
 		cryptoEnvVars := make([]crypto.EnvironmentVariable, len(req.EnvironmentVariables))
 		for i, v := range req.EnvironmentVariables {
-			cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
+			cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
+		}
+		if err := servicestatus.CheckEnvVarLimits(cryptoEnvVars); err != nil {
+			return response.BadRequest(c, err.Error())
 		}
 		encryptedEnvVars, err := crypto.EncryptEnvVars(cryptoEnvVars)
 		if err != nil {
@@ -220,7 +361,7 @@ func Update(c *fiber.Ctx) error {
 		// Convert back for storage
 		storageEnvVars := make([]EnvironmentVar, len(encryptedEnvVars))
 		for i, v := range encryptedEnvVars {
-			storageEnvVars[i] = EnvironmentVar{Key: v.Key, Value: v.Value}
+			storageEnvVars[i] = EnvironmentVar{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
 		}
 		envJSON, _ := json.Marshal(storageEnvVars)
 		updates["environmentVariables"] = envJSON
@@ -243,53 +384,113 @@ func Update(c *fiber.Ctx) error {
 	if req.AutoDeployEnabled != nil {
 		updates["autoDeployEnabled"] = *req.AutoDeployEnabled
 	}
+	if req.DeployPathFilter != nil {
+		// An empty filter clears it so every push deploys again
+		if strings.TrimSpace(*req.DeployPathFilter) == "" {
+			updates["deployPathFilter"] = nil
+		} else {
+			updates["deployPathFilter"] = strings.TrimSpace(*req.DeployPathFilter)
+		}
+	}
+	if req.DeployOnlyProtectedBranches != nil {
+		updates["deployOnlyProtectedBranches"] = *req.DeployOnlyProtectedBranches
+	}
+	if req.DeployOn != nil {
+		updates["deployOn"] = *req.DeployOn
+	}
 	if req.CustomDomain != nil {
-		updates["customDomain"] = *req.CustomDomain
+		updates["customDomain"] = customDomain
+		// A new custom domain has to be verified again
+		if utils.PtrValue(customDomain, "") != utils.PtrValue(service.CustomDomain, "") {
+			updates["customDomainVerifiedAt"] = nil
+		}
+	}
+	if req.CustomDomain != nil || req.PrimaryDomain != nil {
+		updates["primaryDomain"] = primaryDomain
+	}
+	if redirectWww != service.RedirectWww {
+		updates["redirectWww"] = redirectWww
 	}
 	if req.HealthCheckPath != nil {
 		updates["healthCheckPath"] = *req.HealthCheckPath
 	}
-	if req.InstanceTypeID != nil {
+	if planChange != nil {
 		updates["instanceTypeId"] = *req.InstanceTypeID
 		updates["instanceTypeChangedAt"] = time.Now()
 	}
-	if req.StorageCapacity != nil {
+	if storageResized {
 		updates["storageCapacity"] = *req.StorageCapacity
 		updates["storageCapacityChangedAt"] = time.Now()
 	}
-	if req.ContainerCommand != nil {
-		updates["containerCommand"] = *req.ContainerCommand
+	if err := setContainerOverrides(updates, req.ContainerCommand, req.ContainerArgs); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	if req.BuildContextPath != nil {
+		// An empty path builds from the root of the repository again, the next build uses it
+		contextPath, err := deploy.NormalizeBuildContextPath(*req.BuildContextPath)
+		if err != nil {
+			return response.BadRequest(c, err.Error())
+		}
+		if contextPath != nil {
+			if service.Runtime != models.RuntimeDocker || service.GitProviderID == nil || service.RepositoryName == nil {
+				return response.BadRequest(c, "Build context path requires a repository")
+			}
+			var provider models.GitProvider
+			if err := db.Preload("GithubAccount").Where("id = ?", *service.GitProviderID).First(&provider).Error; err != nil {
+				return response.BadRequest(c, "Git provider not found")
+			}
+			if err := deploy.CheckBuildContextPath(c.UserContext(), &provider, *service.RepositoryName, utils.PtrValue(service.Branch, "main"), *contextPath); err != nil {
+				return response.BadRequest(c, err.Error())
+			}
+		}
+		updates["buildContextPath"] = contextPath
 	}
 
-	// Update service
-	if len(updates) > 0 {
-		if err := db.Model(&models.Service{}).Where("id = ?", serviceID).Updates(updates).Error; err != nil {
+	// Update service, only when nobody else updated it since it was read
+	portsChanged := len(req.PortSettings) > 0 && (service.ServiceTypeID == "web" || service.ServiceTypeID == "private")
+	if len(updates) > 0 || portsChanged {
+		if err := servicestatus.UpdateVersioned(db, serviceID, service.Version, updates); err != nil {
+			if errors.Is(err, servicestatus.ErrStaleService) {
+				return response.Conflict(c, err.Error())
+			}
 			return response.InternalServerError(c, "Failed to update service")
 		}
 	}
 
+	if storageResized {
+		previous := "none"
+		if service.StorageCapacity != nil {
+			previous = fmt.Sprintf("%d GB", *service.StorageCapacity)
+		}
+		db.Create(&models.ServiceEvent{
+			ServiceID: serviceID,
+			Type:      models.EventTypeStorageResized,
+			Message:   utils.Ptr(fmt.Sprintf("Storage capacity changed from %s to %d GB", previous, *req.StorageCapacity)),
+		})
+	}
+
 	// Update port settings if provided
-	if req.PortSettings != nil && len(req.PortSettings) > 0 {
-		if service.ServiceTypeID == "web" || service.ServiceTypeID == "private" {
-			// Delete existing ports
-			db.Where("serviceId = ?", serviceID).Delete(&models.ServicePort{})
+	if portsChanged {
+		// Delete existing ports
+		db.Where("serviceId = ?", serviceID).Delete(&models.ServicePort{})
 
-			if service.ServiceTypeID == "web" {
-				// Web services only allow one port with servicePort=80
+		if service.ServiceTypeID == "web" {
+			// Web services keep servicePort=80 as the HTTP entry, additional ports are exposed as-is
+			for _, port := range webPortSettings(req.PortSettings) {
 				db.Create(&models.ServicePort{
 					ServiceID:     serviceID,
-					ServicePort:   80,
-					ContainerPort: req.PortSettings[0].ContainerPort,
+					ServicePort:   port.ServicePort,
+					ContainerPort: port.ContainerPort,
+				})
+			}
+		} else {
+			// Private services can have multiple ports
+			for _, port := range req.PortSettings {
+				db.Create(&models.ServicePort{
+					ServiceID:     serviceID,
+					ServicePort:   port.ServicePort,
+					ContainerPort: port.ContainerPort,
 				})
-			} else {
-				// Private services can have multiple ports
-				for _, port := range req.PortSettings {
-					db.Create(&models.ServicePort{
-						ServiceID:     serviceID,
-						ServicePort:   port.ServicePort,
-						ContainerPort: port.ContainerPort,
-					})
-				}
 			}
 		}
 	}
@@ -328,6 +529,7 @@ func Update(c *fiber.Ctx) error {
 		"healthCheckPath":    service.HealthCheckPath,
 		"autoScalingEnabled": service.AutoScalingEnabled,
 		"autoDeployEnabled":  service.AutoDeployEnabled,
+		"deployPathFilter":   service.DeployPathFilter,
 		"maxReplicas":        service.MaxReplicas,
 		"minReplicas":        service.MinReplicas,
 		"replicas":           service.Replicas,
@@ -338,9 +540,51 @@ func Update(c *fiber.Ctx) error {
 		"scaleToZeroEnabled": scaleToZeroEnabled,
 		"storageCapacity":    service.StorageCapacity,
 		"containerCommand":   service.ContainerCommand,
+		"containerArgs":      service.ContainerArgs,
+		"buildContextPath":   service.BuildContextPath,
+		"planChange":         planChange,
+		"version":            service.Version,
+
+		"deployOnlyProtectedBranches": service.DeployOnlyProtectedBranches,
+		"deployOn":                    service.DeployOn,
+		"primaryDomain":               service.PrimaryDomain,
+		"redirectWww":                 service.RedirectWww,
 	})
 }
 
+// setContainerOverrides adds the command and args overrides of an update to updates
+func setContainerOverrides(updates map[string]interface{}, command *string, args *[]string) error {
+	if command != nil {
+		// A command given in array form must not be empty
+		trimmed := strings.TrimSpace(*command)
+		if strings.HasPrefix(trimmed, "[") {
+			var parsed []string
+			if err := json.Unmarshal([]byte(trimmed), &parsed); err != nil || len(parsed) == 0 {
+				return errors.New("Container command must be a non-empty array")
+			}
+		}
+		updates["containerCommand"] = *command
+	}
+	if args != nil {
+		// An empty array clears the override
+		if len(*args) == 0 {
+			updates["containerArgs"] = nil
+			return nil
+		}
+		for _, arg := range *args {
+			if strings.TrimSpace(arg) == "" {
+				return errors.New("Container args must not contain empty values")
+			}
+		}
+		argsJSON, _ := json.Marshal(*args)
+		updates["containerArgs"] = models.JSON(argsJSON)
+	}
+	return nil
+}
+
+// resolveRef resolves the ref of a deploy request, tests replace it
+var resolveRef = deploy.ResolveRef
+
 // POST /api/services/:serviceId/deploy
 func Deploy(c *fiber.Ctx) error {
 	db := database.GetDatabase()
@@ -364,6 +608,7 @@ func Deploy(c *fiber.Ctx) error {
 	// Fetch the service with access check
 	var service models.Service
 	if err := db.Preload("Project.Organization").
+		Preload("GitProvider.GithubAccount").
 		Where("id = ? AND deletedAt IS NULL", serviceID).
 		First(&service).Error; err != nil {
 		return response.NotFound(c, "Service not found")
@@ -383,8 +628,33 @@ func Deploy(c *fiber.Ctx) error {
 	if req.CommitSha != nil {
 		commitSha = *req.CommitSha
 	}
+	ref := ""
+	if req.Ref != nil {
+		ref = strings.TrimSpace(*req.Ref)
+	}
+	if ref != "" && commitSha != "" {
+		return response.BadRequest(c, "Specify either ref or commitSha, not both")
+	}
 
-	deployment, err := deploy.BuildService(serviceID, user.ID, "manual", commitSha)
+	var deployment *models.Deployment
+	var err error
+	if ref != "" {
+		// Resolve the ref now, so an unknown one is rejected instead of failing the build
+		resolved, resolveErr := resolveRef(c.UserContext(), service, ref)
+		if errors.Is(resolveErr, github.ErrRefNotFound) {
+			return response.BadRequest(c, fmt.Sprintf("Ref %q not found in the repository", ref))
+		}
+		if resolveErr != nil {
+			log.Printf("Error resolving ref %s of service %s: %v", ref, serviceID, resolveErr)
+			return response.BadRequest(c, "Failed to resolve ref: "+resolveErr.Error())
+		}
+		deployment, err = deploy.BuildServiceRef(serviceID, user.ID, "manual", resolved)
+	} else {
+		deployment, err = deploy.BuildService(serviceID, user.ID, "manual", commitSha)
+	}
+	if errors.Is(err, deploy.ErrConcurrencyLimit) {
+		return response.TooManyRequests(c, "Failed to start deployment: "+err.Error())
+	}
 	if err != nil {
 		log.Printf("Error starting deployment: %v", err)
 		return response.BadRequest(c, "Failed to start deployment: "+err.Error())
@@ -420,22 +690,16 @@ func Restart(c *fiber.Ctx) error {
 		return response.Forbidden(c, "Service not found or access denied")
 	}
 
-	// Create restart event
-	db.Create(&models.ServiceEvent{
-		ServiceID: serviceID,
-		Type:      models.EventTypeServiceRestartStarted,
-		Message:   utils.Ptr("Service restart initiated"),
-	})
-
-	// Update service status to RESTARTING
-	db.Model(&service).Update("status", models.ServiceStatusRestarting)
-
-	// Deploy to kubernetes
-	go func() {
-		if err := deploy.DeployService("deploy-service", nil, serviceID); err != nil {
-			log.Printf("Error restarting service: %v", err)
+	// Mark the service as RESTARTING and redeploy it to kubernetes
+	if err := servicestatus.Restart(serviceID); err != nil {
+		if errors.Is(err, servicestatus.ErrInvalidTransition) {
+			return response.Error(c, fiber.StatusConflict, fmt.Sprintf("Service cannot be restarted while %s", service.Status))
 		}
-	}()
+		if errors.Is(err, servicestatus.ErrServiceStopped) {
+			return response.Error(c, fiber.StatusConflict, "Service is stopped, start it before restarting")
+		}
+		return response.InternalServerError(c, "Failed to update service status")
+	}
 
 	return response.Success(c, fiber.Map{
 		"message": "Service restart initiated",
@@ -771,7 +1035,7 @@ func GetPods(c *fiber.Ctx) error {
 	}
 
 	// Get pods from Kubernetes
-	pods, err := kubernetes.GetPodsForService(service.ProjectID, serviceID)
+	pods, err := kubernetes.GetPodsForService(c.UserContext(), region.Of(service), service.ProjectID, serviceID)
 	if err != nil {
 		log.Printf("Error getting pods for service %s: %v", serviceID, err)
 		return response.Success(c, []kubernetes.Pod{})
@@ -779,3 +1043,138 @@ func GetPods(c *fiber.Ctx) error {
 
 	return response.Success(c, pods)
 }
+
+// POST /api/services/:serviceId/pods/:podId/restart
+func RestartPod(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	podID := c.Params("podId")
+	if serviceID == "" || podID == "" {
+		return response.BadRequest(c, "Service ID and pod ID are required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	// Delete the pod, the deployment schedules a replacement
+	if err := kubernetes.DeleteServicePod(c.UserContext(), region.Of(service), service.ProjectID, serviceID, podID); err != nil {
+		if errors.Is(err, kubernetes.ErrPodNotFound) {
+			return response.NotFound(c, "Pod not found")
+		}
+		log.Printf("Error deleting pod %s of service %s: %v", podID, serviceID, err)
+		return response.InternalServerError(c, "Failed to restart pod")
+	}
+
+	// Create pod restart event
+	db.Create(&models.ServiceEvent{
+		ServiceID: serviceID,
+		Type:      models.EventTypePodRestarted,
+		Message:   utils.Ptr(fmt.Sprintf("Pod %s restarted", podID)),
+	})
+
+	return response.Success(c, fiber.Map{
+		"message": "Pod restart initiated",
+	})
+}
+
+// GET /api/services/:serviceId/k8s-events
+func GetKubernetesEvents(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	// Get events from Kubernetes
+	events, err := kubernetes.GetEventsForService(c.UserContext(), region.Of(service), service.ProjectID, serviceID, limit)
+	if err != nil {
+		log.Printf("Error getting Kubernetes events for service %s: %v", serviceID, err)
+		return response.Error(c, fiber.StatusBadGateway, fmt.Sprintf("Failed to get Kubernetes events: %v", err))
+	}
+
+	return response.Success(c, events)
+}
+
+// validatePortSettings checks port ranges and that no service port is used twice
+func validatePortSettings(serviceTypeID string, settings []PortSetting) error {
+	if serviceTypeID == "web" {
+		settings = webPortSettings(settings)
+	}
+
+	seen := make(map[int]bool)
+	for _, port := range settings {
+		if port.ServicePort < 1 || port.ServicePort > 65535 {
+			return fmt.Errorf("service port must be between 1 and 65535")
+		}
+		if port.ContainerPort < 1 || port.ContainerPort > 65535 {
+			return fmt.Errorf("container port must be between 1 and 65535")
+		}
+		if seen[port.ServicePort] {
+			return fmt.Errorf("service port %d is used more than once", port.ServicePort)
+		}
+		seen[port.ServicePort] = true
+	}
+
+	return nil
+}
+
+// webPortSettings returns the ports of a web service with the HTTP entry (servicePort=80) first.
+// When no port is mapped to 80, the first port becomes the HTTP entry as before.
+func webPortSettings(settings []PortSetting) []PortSetting {
+	primary := 0
+	for i, port := range settings {
+		if port.ServicePort == 80 {
+			primary = i
+			break
+		}
+	}
+
+	result := []PortSetting{{ServicePort: 80, ContainerPort: settings[primary].ContainerPort}}
+	for i, port := range settings {
+		if i != primary {
+			result = append(result, port)
+		}
+	}
+
+	return result
+}
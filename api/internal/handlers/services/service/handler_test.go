@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/github"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSetContainerOverrides(t *testing.T) {
+	updates := map[string]interface{}{}
+	args := []string{"--port", "8080"}
+	if err := setContainerOverrides(updates, utils.Ptr(`["node","server.js"]`), &args); err != nil {
+		t.Fatalf("setContainerOverrides: %v", err)
+	}
+	if updates["containerCommand"] != `["node","server.js"]` {
+		t.Errorf("containerCommand = %v", updates["containerCommand"])
+	}
+	if got, ok := updates["containerArgs"].(models.JSON); !ok || string(got) != `["--port","8080"]` {
+		t.Errorf("containerArgs = %v, want the args as JSON", updates["containerArgs"])
+	}
+
+	// An empty array clears the args override, a missing one leaves it alone
+	updates = map[string]interface{}{}
+	if err := setContainerOverrides(updates, nil, &[]string{}); err != nil {
+		t.Fatalf("clearing args: %v", err)
+	}
+	if v, ok := updates["containerArgs"]; !ok || v != nil {
+		t.Errorf("containerArgs = %v, want it cleared", v)
+	}
+	if _, ok := updates["containerCommand"]; ok {
+		t.Error("containerCommand updated without being given")
+	}
+}
+
+func TestSetContainerOverridesRejectsInvalidValues(t *testing.T) {
+	for name, tt := range map[string]struct {
+		command *string
+		args    *[]string
+	}{
+		"empty command array":    {command: utils.Ptr("[]")},
+		"malformed command JSON": {command: utils.Ptr(`["node",`)},
+		"blank arg":              {args: &[]string{"--port", " "}},
+	} {
+		updates := map[string]interface{}{}
+		if err := setContainerOverrides(updates, tt.command, tt.args); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestWebPortSettingsKeepsHTTPEntry(t *testing.T) {
+	// A metrics port next to the HTTP one, in any order
+	ports := webPortSettings([]PortSetting{
+		{ServicePort: 9090, ContainerPort: 9091},
+		{ServicePort: 80, ContainerPort: 3000},
+	})
+	want := []PortSetting{{ServicePort: 80, ContainerPort: 3000}, {ServicePort: 9090, ContainerPort: 9091}}
+	if len(ports) != len(want) || ports[0] != want[0] || ports[1] != want[1] {
+		t.Errorf("ports = %v, want %v", ports, want)
+	}
+
+	// Without a port 80 the first port is the HTTP entry, as it was with a single port
+	ports = webPortSettings([]PortSetting{{ServicePort: 8080, ContainerPort: 3000}})
+	if len(ports) != 1 || ports[0] != (PortSetting{ServicePort: 80, ContainerPort: 3000}) {
+		t.Errorf("ports = %v, want the first port on 80", ports)
+	}
+}
+
+func TestValidatePortSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		serviceType string
+		ports       []PortSetting
+		wantErr     bool
+	}{
+		{name: "web with a metrics port", serviceType: "web", ports: []PortSetting{{80, 3000}, {9090, 9090}}},
+		{name: "private ports", serviceType: "private", ports: []PortSetting{{5000, 5000}, {5001, 5001}}},
+		{name: "duplicate service port", serviceType: "private", ports: []PortSetting{{5000, 5000}, {5000, 5001}}, wantErr: true},
+		{name: "container port out of range", serviceType: "web", ports: []PortSetting{{80, 70000}}, wantErr: true},
+		{name: "service port out of range", serviceType: "private", ports: []PortSetting{{0, 3000}}, wantErr: true},
+		// The first port becomes 80 and collides with the one mapped to it
+		{name: "web port collides with the HTTP entry", serviceType: "web", ports: []PortSetting{{80, 3000}, {80, 3001}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePortSettings(tt.serviceType, tt.ports)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePortSettings error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// serviceApp returns an app serving handler on route for user
+func serviceApp(user *models.User, method, route string, handler fiber.Handler) *fiber.App {
+	app := fiber.New()
+	app.Add(method, route, func(c *fiber.Ctx) error {
+		c.Locals("user", user)
+		return c.Next()
+	}, handler)
+	return app
+}
+
+// ownedService answers service lookups with a service of an organization owned by ownerID
+func ownedService(db *dbtest.DB, ownerID string) {
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1"})
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": ownerID})
+}
+
+func TestRestartPodChecksOwnership(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "someone-else")
+	app := serviceApp(&models.User{ID: "user1"}, http.MethodPost, "/services/:serviceId/pods/:podId/restart", RestartPod)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/services/svc1/pods/svc1-7d9f-xyz/restart", nil))
+	if err != nil {
+		t.Fatalf("restart request: %v", err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if inserts := db.Statements("INSERT INTO `ServiceEvent`"); len(inserts) != 0 {
+		t.Errorf("recorded %d service events for a forbidden restart", len(inserts))
+	}
+}
+
+func TestRestartPodUnknownService(t *testing.T) {
+	db := dbtest.New(t)
+	app := serviceApp(&models.User{ID: "user1"}, http.MethodPost, "/services/:serviceId/pods/:podId/restart", RestartPod)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/services/svc1/pods/svc1-7d9f-xyz/restart", nil))
+	if err != nil {
+		t.Fatalf("restart request: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	if inserts := db.Statements("INSERT INTO `ServiceEvent`"); len(inserts) != 0 {
+		t.Errorf("recorded %d service events for an unknown service", len(inserts))
+	}
+}
+
+func TestDeployRejectsRefWithCommitSha(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+	app := serviceApp(&models.User{ID: "user1"}, http.MethodPost, "/services/:serviceId/deploy", Deploy)
+
+	req := httptest.NewRequest(http.MethodPost, "/services/svc1/deploy", strings.NewReader(`{"ref":"v1.0","commitSha":"abc1234"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("deploy request: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if inserts := db.Statements("INSERT INTO `Deployment`"); len(inserts) != 0 {
+		t.Errorf("created a deployment for an ambiguous request")
+	}
+}
+
+func TestDeployResolvesRefWithRequestContext(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1", "runtime": "DOCKER"})
+
+	type requestKey struct{}
+	var resolvedWith context.Context
+	previous := resolveRef
+	resolveRef = func(ctx context.Context, service models.Service, ref string) (*github.Ref, error) {
+		resolvedWith = ctx
+		return nil, github.ErrRefNotFound
+	}
+	t.Cleanup(func() { resolveRef = previous })
+
+	app := fiber.New()
+	app.Post("/services/:serviceId/deploy", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user1"})
+		c.SetUserContext(context.WithValue(c.UserContext(), requestKey{}, "req1"))
+		return c.Next()
+	}, Deploy)
+
+	req := httptest.NewRequest(http.MethodPost, "/services/svc1/deploy", strings.NewReader(`{"ref":"v9.9"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("deploy request: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d for an unknown ref", resp.StatusCode, http.StatusBadRequest)
+	}
+	// The ref is resolved within the request, so it is abandoned with it
+	if resolvedWith == nil || resolvedWith.Value(requestKey{}) != "req1" {
+		t.Errorf("ref resolved with context %v, want the request's", resolvedWith)
+	}
+}
+
+// updateService sends a flat update of svc1 as its owner
+func updateService(t *testing.T, body string) *http.Response {
+	t.Helper()
+	app := serviceApp(&models.User{ID: "user1"}, http.MethodPatch, "/services/:serviceId", Update)
+	req := httptest.NewRequest(http.MethodPatch, "/services/svc1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("update request: %v", err)
+	}
+	return resp
+}
+
+func TestUpdateRejectsMalformedCustomDomain(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+
+	for _, domain := range []string{"*.example.com", "10.0.0.1", "localhost"} {
+		if resp := updateService(t, `{"customDomain":"`+domain+`"}`); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", domain, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+	if updates := db.Statements("UPDATE `Service`"); len(updates) != 0 {
+		t.Errorf("stored a malformed custom domain: %v", updates)
+	}
+}
+
+func TestUpdateRejectsCustomDomainOfAnotherService(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+	db.OnQuery("LOWER(customDomain)", dbtest.Row{"count(*)": int64(1)})
+
+	resp := updateService(t, `{"customDomain":"https://WWW.Example.com/"}`)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+	// The domain is looked up in its normalized form
+	checks := db.Statements("LOWER(customDomain)")
+	if len(checks) != 1 || !containsArg(checks[0], "www.example.com") {
+		t.Errorf("availability checks = %v, want one for www.example.com", checks)
+	}
+	if updates := db.Statements("UPDATE `Service`"); len(updates) != 0 {
+		t.Errorf("stored a custom domain used by another service: %v", updates)
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/pkg/kubernetes"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// IngressPortLabel is the Kubernetes service label the ingress proxy watches for
+// external port mappings
+const IngressPortLabel = "ingress-port"
+
+const ingressPortLockKey = "ingress-port-lock"
+
+var errNoIngressPortAvailable = errors.New("no ingress port available")
+
+// POST /api/services/:serviceId/ingress-port
+func AllocateIngressPort(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+	ctx := context.Background()
+	cfg := config.Get()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if service.ServiceTypeID != "private" {
+		return response.BadRequest(c, "Only private services can be exposed through the ingress proxy")
+	}
+
+	// Requesting a port again returns the existing assignment
+	if service.IngressPort != nil {
+		return response.Success(c, ingressPortResponse(cfg, *service.IngressPort))
+	}
+
+	// Serialize allocations so two requests don't pick the same port
+	lockAcquired, err := redis.AcquireLock(ctx, ingressPortLockKey, 10)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to acquire lock")
+	}
+	if !lockAcquired {
+		return response.TooManyRequests(c, "Another ingress port allocation is in progress, please retry")
+	}
+	defer redis.ReleaseLock(ctx, ingressPortLockKey)
+
+	var used []int
+	if err := db.Model(&models.Service{}).
+		Where("ingressPort IS NOT NULL").
+		Pluck("ingressPort", &used).Error; err != nil {
+		return response.InternalServerError(c, "Failed to allocate ingress port")
+	}
+
+	port, err := nextFreeIngressPort(used, cfg.IngressPortMin, cfg.IngressPortMax)
+	if err != nil {
+		return response.Error(c, fiber.StatusConflict, "No ingress ports are available")
+	}
+
+	// The unique index on ingressPort guards against allocations made outside the lock
+	if err := db.Model(&service).Update("ingressPort", port).Error; err != nil {
+		log.Printf("Error assigning ingress port %d to service %s: %v", port, serviceID, err)
+		return response.InternalServerError(c, "Failed to allocate ingress port")
+	}
+
+	// Label the running service right away, later deployments keep the label in place
+	value := strconv.Itoa(port)
+	if err := kubernetes.SetServiceLabel(c.UserContext(), region.Of(service), service.ProjectID, serviceID+"-service", IngressPortLabel, &value); err != nil {
+		log.Printf("Error labelling service %s with ingress port %d: %v", serviceID, port, err)
+	}
+
+	log.Printf("Assigned ingress port %d to service %s", port, serviceID)
+
+	return response.Success(c, ingressPortResponse(cfg, port))
+}
+
+// DELETE /api/services/:serviceId/ingress-port
+func ReleaseIngressPort(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if service.IngressPort == nil {
+		return response.NotFound(c, "Service has no ingress port")
+	}
+
+	if err := db.Model(&service).Update("ingressPort", nil).Error; err != nil {
+		return response.InternalServerError(c, "Failed to release ingress port")
+	}
+
+	if err := kubernetes.SetServiceLabel(c.UserContext(), region.Of(service), service.ProjectID, serviceID+"-service", IngressPortLabel, nil); err != nil {
+		log.Printf("Error removing ingress port label from service %s: %v", serviceID, err)
+	}
+
+	log.Printf("Released ingress port %d of service %s", *service.IngressPort, serviceID)
+
+	return response.Success(c, fiber.Map{
+		"message": "Ingress port released successfully",
+	})
+}
+
+// nextFreeIngressPort returns the lowest port in [min, max] that is not in use
+func nextFreeIngressPort(used []int, min, max int) (int, error) {
+	sort.Ints(used)
+
+	port := min
+	for _, p := range used {
+		if p < port {
+			continue
+		}
+		if p > port {
+			break
+		}
+		port++
+	}
+
+	if port > max {
+		return 0, errNoIngressPortAvailable
+	}
+
+	return port, nil
+}
+
+// ingressPortResponse builds the external address of an ingress port
+func ingressPortResponse(cfg *config.Config, port int) fiber.Map {
+	host := cfg.IngressHost
+	if host == "" {
+		host = cfg.AppDomain
+	}
+
+	return fiber.Map{
+		"ingressPort": port,
+		"host":        host,
+		"address":     fmt.Sprintf("%s:%d", host, port),
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+)
+
+func TestNextFreeIngressPort(t *testing.T) {
+	tests := []struct {
+		name    string
+		used    []int
+		want    int
+		wantErr bool
+	}{
+		{name: "first allocation", want: 30000},
+		{name: "skips ports in use", used: []int{30001, 30000}, want: 30002},
+		{name: "fills a released port", used: []int{30000, 30002}, want: 30001},
+		{name: "ignores ports outside the range", used: []int{80, 40000}, want: 30000},
+		{name: "range exhausted", used: []int{30000, 30001, 30002}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, err := nextFreeIngressPort(tt.used, 30000, 30002)
+			if tt.wantErr {
+				if !errors.Is(err, errNoIngressPortAvailable) {
+					t.Fatalf("error = %v, want errNoIngressPortAvailable", err)
+				}
+				return
+			}
+			if err != nil || port != tt.want {
+				t.Errorf("nextFreeIngressPort = %d, %v, want %d", port, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngressPortResponse(t *testing.T) {
+	got := ingressPortResponse(&config.Config{IngressHost: "tcp.example.com", AppDomain: "example.com"}, 30000)
+	if got["address"] != "tcp.example.com:30000" {
+		t.Errorf("address = %v, want tcp.example.com:30000", got["address"])
+	}
+
+	// Without an ingress host the app domain is used
+	got = ingressPortResponse(&config.Config{AppDomain: "example.com"}, 30000)
+	if got["address"] != "example.com:30000" {
+		t.Errorf("address = %v, want example.com:30000", got["address"])
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Env var formats of a database link
+const (
+	linkFormatVars = "vars"
+	linkFormatURL  = "url"
+)
+
+// Env var keys and prefixes may only hold what env var keys hold
+var linkKeyRegex = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// LinkDatabaseRequest is the body of a database link
+type LinkDatabaseRequest struct {
+	// TargetServiceID is the service that gets the env vars
+	TargetServiceID string `json:"targetServiceId"`
+
+	// Format is "vars" for HOST, PORT, USER, PASSWORD and DATABASE vars, or "url" for a
+	// single connection string
+	Format string `json:"format"`
+
+	// Prefix of the "vars" keys, DB_ by default, REDIS_ for memory services
+	Prefix *string `json:"prefix"`
+
+	// Key of the "url" var, DATABASE_URL by default, REDIS_URL for memory services
+	Key string `json:"key"`
+}
+
+// linkEnvVars returns the env vars a service connecting to a database or memory service gets,
+// resolved like the fromDatabase env vars of a template
+func linkEnvVars(dbService models.Service, credential models.ServiceCredential, req LinkDatabaseRequest) []crypto.EnvironmentVariable {
+	if req.Format == linkFormatURL {
+		key := req.Key
+		if key == "" {
+			key = "DATABASE_URL"
+			if dbService.ServiceTypeID == "memory" {
+				key = "REDIS_URL"
+			}
+		}
+		return []crypto.EnvironmentVariable{{
+			Key: key,
+			Value: servicestatus.ConnectionString(dbService.ServiceTypeID, servicestatus.InternalHost(dbService.ID),
+				credential.Port, credential.Username, credential.Password, credential.Database),
+		}}
+	}
+
+	prefix := "DB_"
+	if dbService.ServiceTypeID == "memory" {
+		prefix = "REDIS_"
+	}
+	if req.Prefix != nil {
+		prefix = *req.Prefix
+	}
+
+	properties := []string{"host", "port", "user", "password", "database"}
+	// Memory services have no database
+	if dbService.ServiceTypeID == "memory" {
+		properties = properties[:4]
+	}
+
+	envVars := make([]crypto.EnvironmentVariable, 0, len(properties))
+	for _, property := range properties {
+		value, _ := servicestatus.DatabaseProperty(dbService.ID, credential, property)
+		envVars = append(envVars, crypto.EnvironmentVariable{
+			Key:   prefix + strings.ToUpper(property),
+			Value: value,
+		})
+	}
+	return envVars
+}
+
+// POST /api/services/:serviceId/connection/link
+// Sets the env vars connecting another service of the project to a database or memory service
+func LinkDatabase(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	var req LinkDatabaseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+	if req.TargetServiceID == "" {
+		return response.BadRequest(c, "Target service ID is required")
+	}
+	if req.Format == "" {
+		req.Format = linkFormatVars
+	}
+	if req.Format != linkFormatVars && req.Format != linkFormatURL {
+		return response.BadRequest(c, "Format must be \"vars\" or \"url\"")
+	}
+	if req.Prefix != nil && *req.Prefix != "" && !linkKeyRegex.MatchString(*req.Prefix) {
+		return response.BadRequest(c, "Invalid prefix format. Prefixes must only contain letters, numbers, hyphens, underscores, and periods.")
+	}
+	if req.Key != "" && !linkKeyRegex.MatchString(req.Key) {
+		return response.BadRequest(c, "Invalid key format. Keys must only contain letters, numbers, hyphens, underscores, and periods.")
+	}
+
+	// Fetch the database service with access check
+	var dbService models.Service
+	if err := db.Preload("Project.Organization").
+		Preload("Credentials").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&dbService).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+	if dbService.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+	if !servicestatus.CredentialServiceTypes[dbService.ServiceTypeID] {
+		return response.BadRequest(c, "Only database and memory services can be linked")
+	}
+	if dbService.Credentials == nil {
+		return response.NotFound(c, "Service has no credentials")
+	}
+
+	// Fetch the target service with access check
+	var target models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", req.TargetServiceID).
+		First(&target).Error; err != nil {
+		return response.NotFound(c, "Target service not found")
+	}
+	if target.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Target service not found or access denied")
+	}
+	if target.ServiceTypeID != "web" && target.ServiceTypeID != "private" {
+		return response.BadRequest(c, "Only web and private services can be linked to a database")
+	}
+	// The internal host only resolves in the namespace of the project
+	if target.ProjectID != dbService.ProjectID {
+		return response.BadRequest(c, "Target service must be in the same project")
+	}
+
+	linked := linkEnvVars(dbService, *dbService.Credentials, req)
+
+	// Merge into the target's env vars, linked keys replace existing ones
+	var stored []crypto.EnvironmentVariable
+	target.EnvironmentVariables.UnmarshalTo(&stored)
+	envVars, _ := crypto.DecryptEnvVars(stored)
+	index := make(map[string]int, len(envVars))
+	for i, v := range envVars {
+		index[v.Key] = i
+	}
+	keys := make([]string, 0, len(linked))
+	for _, v := range linked {
+		keys = append(keys, v.Key)
+		if i, ok := index[v.Key]; ok {
+			envVars[i].Value = v.Value
+			continue
+		}
+		index[v.Key] = len(envVars)
+		envVars = append(envVars, v)
+	}
+
+	if err := servicestatus.CheckEnvVarLimits(envVars); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	encrypted, err := crypto.EncryptEnvVars(envVars)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to encrypt environment variables")
+	}
+	envJSON, _ := json.Marshal(encrypted)
+	if err := servicestatus.UpdateVersioned(db, target.ID, target.Version, map[string]interface{}{"environmentVariables": envJSON}); err != nil {
+		if errors.Is(err, servicestatus.ErrStaleService) {
+			return response.Conflict(c, err.Error())
+		}
+		return response.InternalServerError(c, "Failed to update environment variables")
+	}
+
+	db.Create(&models.ServiceEvent{
+		ServiceID: target.ID,
+		Type:      models.EventTypeConfigUpdated,
+		Message:   utils.Ptr(fmt.Sprintf("Linked to %s: %s", dbService.Name, strings.Join(keys, ", "))),
+	})
+
+	// Trigger redeploy if service is running
+	if target.Status == models.ServiceStatusRunning ||
+		target.Status == models.ServiceStatusFailed ||
+		target.Status == models.ServiceStatusRestarting {
+		go func() {
+			if err := deploy.DeployService("deploy-service", nil, target.ID); err != nil {
+				log.Printf("Error redeploying service: %v", err)
+			}
+		}()
+	}
+
+	return response.Success(c, fiber.Map{
+		"message": "Database linked successfully",
+		"keys":    keys,
+	})
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLogPageSize  = 100
+	maxLogPageSize      = 1000
+	logDownloadPageSize = 1000
+)
+
+// GET /api/services/:serviceId/deployments/:deploymentId/logs
+func GetDeploymentLogs(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	deploymentID := c.Params("deploymentId")
+	if serviceID == "" || deploymentID == "" {
+		return response.BadRequest(c, "Service ID and deployment ID are required")
+	}
+
+	// Fetch the deployment with access check
+	var deployment models.Deployment
+	if err := db.Preload("Service.Project.Organization").
+		Where("id = ? AND serviceId = ?", deploymentID, serviceID).
+		First(&deployment).Error; err != nil {
+		return response.NotFound(c, "Deployment not found")
+	}
+
+	// Check access
+	if deployment.Service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Deployment not found or access denied")
+	}
+
+	// Filter by log type (comma-separated)
+	var types []string
+	if typeParam := c.Query("type"); typeParam != "" {
+		for _, t := range strings.Split(typeParam, ",") {
+			logType := models.LogType(strings.ToUpper(strings.TrimSpace(t)))
+			if !isValidLogType(logType) {
+				return response.BadRequest(c, fmt.Sprintf("Invalid log type: %s", t))
+			}
+			types = append(types, string(logType))
+		}
+	}
+
+	fetch := func(cursor, limit int) ([]models.DeploymentLog, error) {
+		return fetchDeploymentLogs(db, deploymentID, types, cursor, limit)
+	}
+
+	if c.QueryBool("download") {
+		return downloadDeploymentLogs(c, deployment, fetch)
+	}
+
+	limit := c.QueryInt("limit", defaultLogPageSize)
+	if limit < 1 || limit > maxLogPageSize {
+		limit = defaultLogPageSize
+	}
+
+	// The cursor is the ID of the last log of the previous page
+	logs, nextCursor, err := pageDeploymentLogs(fetch, c.QueryInt("cursor", 0), limit)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve deployment logs")
+	}
+
+	return response.Success(c, fiber.Map{
+		"logs":       logs,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != nil,
+	})
+}
+
+// logFetcher returns up to limit logs with an ID greater than cursor, oldest first
+type logFetcher func(cursor, limit int) ([]models.DeploymentLog, error)
+
+// pageDeploymentLogs returns the page of logs after cursor and the cursor of the next
+// page, nil on the last one
+func pageDeploymentLogs(fetch logFetcher, cursor, limit int) ([]models.DeploymentLog, *int, error) {
+	// Fetch one extra row to know whether there is another page
+	logs, err := fetch(cursor, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(logs) <= limit {
+		return logs, nil, nil
+	}
+
+	logs = logs[:limit]
+	return logs, &logs[len(logs)-1].ID, nil
+}
+
+// downloadDeploymentLogs streams every log of a deployment as plain text
+func downloadDeploymentLogs(c *fiber.Ctx, deployment models.Deployment, fetch logFetcher) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	c.Set(fiber.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=\"%s-deployment-%d.log\"", deployment.Service.Name, deployment.DeploymentNumber))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := writeDeploymentLogs(w, fetch); err != nil {
+			log.Printf("Error streaming logs for deployment %s: %v", deployment.ID, err)
+		}
+	})
+
+	return nil
+}
+
+// writeDeploymentLogs writes every log as a plain text line, a page at a time
+func writeDeploymentLogs(w *bufio.Writer, fetch logFetcher) error {
+	cursor := 0
+	for {
+		logs, err := fetch(cursor, logDownloadPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range logs {
+			fmt.Fprintf(w, "%s [%s] %s\n", entry.CreatedAt.UTC().Format(time.RFC3339), entry.Type, strings.TrimRight(entry.Text, "\n"))
+		}
+		if err := w.Flush(); err != nil {
+			// Client went away
+			return nil
+		}
+
+		if len(logs) < logDownloadPageSize {
+			return nil
+		}
+		cursor = logs[len(logs)-1].ID
+	}
+}
+
+// fetchDeploymentLogs returns up to limit logs with an ID greater than cursor, oldest first
+func fetchDeploymentLogs(db *gorm.DB, deploymentID string, types []string, cursor, limit int) ([]models.DeploymentLog, error) {
+	query := db.Where("deploymentId = ? AND id > ?", deploymentID, cursor)
+	if len(types) > 0 {
+		query = query.Where("type IN ?", types)
+	}
+
+	var logs []models.DeploymentLog
+	err := query.Order("id ASC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+func isValidLogType(logType models.LogType) bool {
+	switch logType {
+	case models.LogTypeStdout, models.LogTypeStderr, models.LogTypeInfo, models.LogTypeWarning, models.LogTypeError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+// sliceFetcher pages through logs sorted by ID, as fetchDeploymentLogs does in the database
+func sliceFetcher(logs []models.DeploymentLog) logFetcher {
+	return func(cursor, limit int) ([]models.DeploymentLog, error) {
+		var page []models.DeploymentLog
+		for _, entry := range logs {
+			if entry.ID > cursor && len(page) < limit {
+				page = append(page, entry)
+			}
+		}
+		return page, nil
+	}
+}
+
+func testLogs(n int) []models.DeploymentLog {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	logs := make([]models.DeploymentLog, n)
+	for i := range logs {
+		logs[i] = models.DeploymentLog{
+			ID:        (i + 1) * 2, // IDs of other deployments' logs leave gaps
+			Type:      models.LogTypeStdout,
+			Text:      fmt.Sprintf("line %d\n", i+1),
+			CreatedAt: start.Add(time.Duration(i) * time.Second),
+		}
+	}
+	return logs
+}
+
+func TestPageDeploymentLogs(t *testing.T) {
+	fetch := sliceFetcher(testLogs(5))
+
+	// Pages follow each other in ID order without repeating a log
+	var ids []int
+	cursor, pages := 0, 0
+	for {
+		logs, next, err := pageDeploymentLogs(fetch, cursor, 2)
+		if err != nil {
+			t.Fatalf("pageDeploymentLogs: %v", err)
+		}
+		pages++
+		for _, entry := range logs {
+			ids = append(ids, entry.ID)
+		}
+		if next == nil {
+			break
+		}
+		if *next != logs[len(logs)-1].ID {
+			t.Fatalf("next cursor %d is not the last log of the page %v", *next, logs)
+		}
+		cursor = *next
+	}
+
+	if pages != 3 {
+		t.Errorf("read %d pages of 2 logs, want 3", pages)
+	}
+	if fmt.Sprint(ids) != "[2 4 6 8 10]" {
+		t.Errorf("paged IDs = %v, want [2 4 6 8 10]", ids)
+	}
+
+	// A page that ends exactly with the last log has no next page
+	logs, next, _ := pageDeploymentLogs(fetch, 6, 2)
+	if len(logs) != 2 || next != nil {
+		t.Errorf("last full page = %d logs, next %v, want 2 and nil", len(logs), next)
+	}
+}
+
+func TestWriteDeploymentLogs(t *testing.T) {
+	// More than a download page, so the download pages through them too
+	logs := testLogs(logDownloadPageSize + 5)
+	logs[0].Type = models.LogTypeError
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	if err := writeDeploymentLogs(w, sliceFetcher(logs)); err != nil {
+		t.Fatalf("writeDeploymentLogs: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(logs) {
+		t.Fatalf("wrote %d lines, want %d", len(lines), len(logs))
+	}
+	if lines[0] != "2026-01-02T03:04:05Z [ERROR] line 1" {
+		t.Errorf("first line = %q", lines[0])
+	}
+	if want := fmt.Sprintf("line %d", len(logs)); !strings.HasSuffix(lines[len(lines)-1], want) {
+		t.Errorf("last line = %q, want it to end with %q", lines[len(lines)-1], want)
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/gofiber/fiber/v2"
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+)
+
+// Content types of the patches Update takes next to the flat JSON body
+const (
+	contentTypeJSONPatch  = "application/json-patch+json"
+	contentTypeMergePatch = "application/merge-patch+json"
+)
+
+// patchContentType returns the patch content type of a request, empty for a flat body
+func patchContentType(c *fiber.Ctx) string {
+	mediaType, _, _ := strings.Cut(c.Get(fiber.HeaderContentType), ";")
+	switch mediaType = strings.ToLower(strings.TrimSpace(mediaType)); mediaType {
+	case contentTypeJSONPatch, contentTypeMergePatch:
+		return mediaType
+	}
+	return ""
+}
+
+// serviceDocument returns the fields of a service the flat update body sets, with their
+// current values. Patches apply to it, environment variables are decrypted.
+func serviceDocument(service models.Service) (UpdateServiceRequest, error) {
+	doc := UpdateServiceRequest{
+		Name:                           &service.Name,
+		EnvironmentVariables:           []EnvironmentVar{},
+		Replicas:                       &service.Replicas,
+		TargetCPUUtilizationPercentage: service.TargetCPUUtilizationPercentage,
+		MinReplicas:                    &service.MinReplicas,
+		MaxReplicas:                    &service.MaxReplicas,
+		AutoScalingEnabled:             &service.AutoScalingEnabled,
+		AutoDeployEnabled:              &service.AutoDeployEnabled,
+		DeployPathFilter:               service.DeployPathFilter,
+		DeployOnlyProtectedBranches:    &service.DeployOnlyProtectedBranches,
+		DeployOn:                       (*string)(&service.DeployOn),
+		CustomDomain:                   service.CustomDomain,
+		PrimaryDomain:                  service.PrimaryDomain,
+		RedirectWww:                    &service.RedirectWww,
+		HealthCheckPath:                service.HealthCheckPath,
+		InstanceTypeID:                 &service.InstanceTypeID,
+		StorageCapacity:                service.StorageCapacity,
+		PortSettings:                   []PortSetting{},
+		ContainerCommand:               service.ContainerCommand,
+		ContainerArgs:                  &[]string{},
+		BuildContextPath:               service.BuildContextPath,
+
+		// A "test" operation on the version rejects a patch made against an older service
+		Version: &service.Version,
+	}
+
+	var stored []crypto.EnvironmentVariable
+	if service.EnvironmentVariables != nil {
+		service.EnvironmentVariables.UnmarshalTo(&stored)
+	}
+	envVars, err := crypto.DecryptEnvVars(stored)
+	if err != nil {
+		return doc, err
+	}
+	for _, v := range envVars {
+		doc.EnvironmentVariables = append(doc.EnvironmentVariables, EnvironmentVar{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret})
+	}
+
+	for _, port := range service.Ports {
+		doc.PortSettings = append(doc.PortSettings, PortSetting{ServicePort: port.ServicePort, ContainerPort: port.ContainerPort})
+	}
+
+	if service.ContainerArgs != nil {
+		service.ContainerArgs.UnmarshalTo(doc.ContainerArgs)
+	}
+
+	return doc, nil
+}
+
+// patchUpdateRequest applies a JSON Patch (RFC 6902) or JSON Merge Patch (RFC 7396) to the
+// document of a service and returns the flat update request setting the fields the patch
+// changed, so the result goes through the same validation as a flat body
+func patchUpdateRequest(service models.Service, contentType string, patch []byte) (*UpdateServiceRequest, error) {
+	doc, err := serviceDocument(service)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decrypt environment variables: %v", err)
+	}
+	original, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	if contentType == contentTypeJSONPatch {
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, errInvalidPatch(err)
+		}
+		if patched, err = operations.Apply(original); err != nil {
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				return nil, servicestatus.ErrStaleService
+			}
+			return nil, errInvalidPatch(err)
+		}
+	} else {
+		if patched, err = jsonpatch.MergePatch(original, patch); err != nil {
+			return nil, errInvalidPatch(err)
+		}
+	}
+
+	// The result must still be a service document
+	var result UpdateServiceRequest
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("Patched service is invalid: %v", err)
+	}
+
+	return changedFields(doc, result)
+}
+
+// errInvalidPatch reports a patch that can't be decoded or applied
+func errInvalidPatch(err error) error {
+	return fmt.Errorf("Invalid patch: %v", err)
+}
+
+// changedFields returns the update request setting the fields of a patched document that
+// differ from the original. Removing a string clears it, removing a list empties it, other
+// fields can't be removed.
+func changedFields(original, patched UpdateServiceRequest) (*UpdateServiceRequest, error) {
+	var req UpdateServiceRequest
+	reqValue := reflect.ValueOf(&req).Elem()
+	originalValue := reflect.ValueOf(original)
+	patchedValue := reflect.ValueOf(patched)
+
+	for i := 0; i < reqValue.NumField(); i++ {
+		field := reqValue.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		before, _ := json.Marshal(originalValue.Field(i).Interface())
+		after, _ := json.Marshal(patchedValue.Field(i).Interface())
+		if bytes.Equal(before, after) {
+			continue
+		}
+
+		value := patchedValue.Field(i)
+		if value.Kind() == reflect.Pointer && value.IsNil() {
+			switch field.Type.Elem().Kind() {
+			case reflect.String:
+				value = reflect.ValueOf(new(string))
+			case reflect.Slice:
+				value = reflect.New(field.Type.Elem())
+				value.Elem().Set(reflect.MakeSlice(field.Type.Elem(), 0, 0))
+			default:
+				return nil, fmt.Errorf("Patched service is invalid: %s can't be removed", name)
+			}
+		} else if value.Kind() == reflect.Slice && value.IsNil() {
+			value = reflect.MakeSlice(field.Type, 0, 0)
+		}
+		reqValue.Field(i).Set(value)
+	}
+
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		return nil, errors.New("Patched service is invalid: name is required")
+	}
+	for _, v := range req.EnvironmentVariables {
+		if strings.TrimSpace(v.Key) == "" {
+			return nil, errors.New("Patched service is invalid: environment variable keys are required")
+		}
+	}
+	// An empty list of ports leaves the ports unchanged in a flat body
+	if req.PortSettings != nil && len(req.PortSettings) == 0 {
+		return nil, errors.New("Patched service is invalid: portSettings can't be empty")
+	}
+
+	return &req, nil
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+// patchedService is a web service with a plain env var, a health check and one port
+func patchedService() models.Service {
+	return models.Service{
+		ID:                   "svc1",
+		Name:                 "web",
+		ServiceTypeID:        "web",
+		Replicas:             1,
+		MinReplicas:          1,
+		MaxReplicas:          3,
+		HealthCheckPath:      utils.Ptr("/health"),
+		Version:              4,
+		EnvironmentVariables: models.JSON(`[{"key":"LOG_LEVEL","value":"info","isSecret":false}]`),
+		Ports:                []models.ServicePort{{ServicePort: 80, ContainerPort: 3000}},
+	}
+}
+
+func TestPatchUpdateRequestJSONPatch(t *testing.T) {
+	patch := `[
+		{"op": "add", "path": "/environmentVariables/-", "value": {"key": "PORT", "value": "8080", "isSecret": false}},
+		{"op": "replace", "path": "/portSettings/0/containerPort", "value": 8080},
+		{"op": "replace", "path": "/name", "value": "api"},
+		{"op": "remove", "path": "/healthCheckPath"}
+	]`
+
+	req, err := patchUpdateRequest(patchedService(), contentTypeJSONPatch, []byte(patch))
+	if err != nil {
+		t.Fatalf("patchUpdateRequest: %v", err)
+	}
+
+	want := UpdateServiceRequest{
+		Name: utils.Ptr("api"),
+		EnvironmentVariables: []EnvironmentVar{
+			{Key: "LOG_LEVEL", Value: "info", IsSecret: utils.Ptr(false)},
+			{Key: "PORT", Value: "8080", IsSecret: utils.Ptr(false)},
+		},
+		// Removing a string clears it
+		HealthCheckPath: utils.Ptr(""),
+		PortSettings:    []PortSetting{{ServicePort: 80, ContainerPort: 8080}},
+	}
+	// Only the fields the patch changed are set
+	if !reflect.DeepEqual(*req, want) {
+		t.Errorf("request = %+v\nwant %+v", *req, want)
+	}
+}
+
+func TestPatchUpdateRequestMergePatch(t *testing.T) {
+	patch := `{"replicas": 2, "healthCheckPath": null, "containerArgs": ["--verbose"]}`
+
+	req, err := patchUpdateRequest(patchedService(), contentTypeMergePatch, []byte(patch))
+	if err != nil {
+		t.Fatalf("patchUpdateRequest: %v", err)
+	}
+	want := UpdateServiceRequest{
+		Replicas:        utils.Ptr(2),
+		HealthCheckPath: utils.Ptr(""),
+		ContainerArgs:   &[]string{"--verbose"},
+	}
+	if !reflect.DeepEqual(*req, want) {
+		t.Errorf("request = %+v\nwant %+v", *req, want)
+	}
+}
+
+func TestPatchUpdateRequestVersionTest(t *testing.T) {
+	current := `[{"op": "test", "path": "/version", "value": 4}, {"op": "replace", "path": "/replicas", "value": 2}]`
+	if req, err := patchUpdateRequest(patchedService(), contentTypeJSONPatch, []byte(current)); err != nil || utils.PtrValue(req.Replicas, 0) != 2 {
+		t.Errorf("patch of the current version = %+v, %v, want 2 replicas", req, err)
+	}
+
+	stale := `[{"op": "test", "path": "/version", "value": 3}, {"op": "replace", "path": "/replicas", "value": 2}]`
+	if _, err := patchUpdateRequest(patchedService(), contentTypeJSONPatch, []byte(stale)); !errors.Is(err, servicestatus.ErrStaleService) {
+		t.Errorf("patch of an older version: error = %v, want %v", err, servicestatus.ErrStaleService)
+	}
+}
+
+func TestPatchUpdateRequestRejectsInvalidResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		err         string
+	}{
+		{name: "not a patch", contentType: contentTypeJSONPatch, patch: `{"name": "api"}`, err: "Invalid patch"},
+		{name: "missing path", contentType: contentTypeJSONPatch, patch: `[{"op": "remove", "path": "/portSettings/3"}]`, err: "Invalid patch"},
+		{name: "not a merge patch", contentType: contentTypeMergePatch, patch: `{"name":`, err: "Invalid patch"},
+		{name: "unknown field", contentType: contentTypeJSONPatch, patch: `[{"op": "add", "path": "/image", "value": "nginx"}]`, err: "Patched service is invalid"},
+		{name: "wrong type", contentType: contentTypeMergePatch, patch: `{"replicas": "three"}`, err: "Patched service is invalid"},
+		{name: "removed number", contentType: contentTypeJSONPatch, patch: `[{"op": "remove", "path": "/replicas"}]`, err: "replicas can't be removed"},
+		{name: "removed name", contentType: contentTypeJSONPatch, patch: `[{"op": "remove", "path": "/name"}]`, err: "name is required"},
+		{name: "env var without key", contentType: contentTypeJSONPatch, patch: `[{"op": "replace", "path": "/environmentVariables/0/key", "value": " "}]`, err: "keys are required"},
+		{name: "no ports", contentType: contentTypeMergePatch, patch: `{"portSettings": []}`, err: "portSettings can't be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := patchUpdateRequest(patchedService(), tt.contentType, []byte(tt.patch))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// patchWebService sends a patch of svc1 as its owner
+func patchWebService(t *testing.T, contentType, patch string) *http.Response {
+	t.Helper()
+	app := serviceApp(&models.User{ID: "user1"}, http.MethodPatch, "/services/:serviceId", Update)
+	req := httptest.NewRequest(http.MethodPatch, "/services/svc1", strings.NewReader(patch))
+	req.Header.Set("Content-Type", contentType)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("patch request: %v", err)
+	}
+	return resp
+}
+
+func TestUpdateAppliesJSONPatch(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+
+	resp := patchWebService(t, "application/json-patch+json; charset=utf-8", `[{"op": "add", "path": "/healthCheckPath", "value": "/ready"}]`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, "`healthCheckPath`=?") || !containsArg(updates[0], "/ready") {
+		t.Errorf("updates = %v, want the health check path set", updates)
+	}
+}
+
+func TestUpdateRejectsInvalidPatch(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+
+	if resp := patchWebService(t, contentTypeMergePatch, `{"replicas": "three"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if updates := db.Statements("UPDATE `Service`"); len(updates) != 0 {
+		t.Errorf("applied an invalid patch: %v", updates)
+	}
+}
+
+func TestUpdateRejectsStaleVersion(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1", "replicas": int64(1), "version": int64(4)})
+
+	resp := patchWebService(t, contentTypeJSONPatch, `[{"op": "test", "path": "/version", "value": 3}, {"op": "replace", "path": "/replicas", "value": 2}]`)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+	if updates := db.Statements("UPDATE `Service`"); len(updates) != 0 {
+		t.Errorf("updated a service patched at an older version: %v", updates)
+	}
+}
+
+func TestUpdateBumpsVersion(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1", "replicas": int64(1), "version": int64(4)})
+
+	resp := patchWebService(t, contentTypeJSONPatch, `[{"op": "test", "path": "/version", "value": 4}, {"op": "replace", "path": "/name", "value": "api"}]`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, "`version`=version + 1") || !strings.Contains(updates[0].SQL, "version = ?") || fmt.Sprint(updates[0].Args[len(updates[0].Args)-1]) != "4" {
+		t.Errorf("updates = %v, want the update made at version 4 bumping it", updates)
+	}
+}
+
+func TestUpdateConflictsWithConcurrentWrite(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1", "replicas": int64(1), "version": int64(4)})
+	// Another writer bumped the version after the service was read
+	db.OnExec("UPDATE `Service`", 0)
+
+	if resp := patchWebService(t, contentTypeMergePatch, `{"name": "api"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// PlanChange describes how the resources of a service change with a new instance type
+type PlanChange struct {
+	FromInstanceTypeID string  `json:"fromInstanceTypeId"`
+	ToInstanceTypeID   string  `json:"toInstanceTypeId"`
+	CpuCountDelta      float64 `json:"cpuCountDelta"`
+	MemoryMBDelta      int     `json:"memoryMBDelta"`
+	Downgrade          bool    `json:"downgrade"`
+}
+
+func newPlanChange(from, to models.InstanceType) *PlanChange {
+	return &PlanChange{
+		FromInstanceTypeID: from.ID,
+		ToInstanceTypeID:   to.ID,
+		CpuCountDelta:      to.CpuCount - from.CpuCount,
+		MemoryMBDelta:      to.MemoryMB - from.MemoryMB,
+		Downgrade:          to.CpuCount < from.CpuCount || to.MemoryMB < from.MemoryMB,
+	}
+}
+
+// GET /api/services/:serviceId/plan-change?instanceTypeId=...
+func PreviewPlanChange(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	instanceTypeID := c.Query("instanceTypeId")
+	if serviceID == "" || instanceTypeID == "" {
+		return response.BadRequest(c, "Service ID and instance type ID are required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Preload("InstanceType").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	var target models.InstanceType
+	if err := db.Preload("InstanceTypeGroup").Where("id = ?", instanceTypeID).First(&target).Error; err != nil {
+		return response.BadRequest(c, "Invalid instance type")
+	}
+
+	// Report a failed check instead of rejecting, so the caller can show why
+	var problem *string
+	if err := servicestatus.ValidateInstanceTypeChange(db, service, target, false); err != nil {
+		message := err.Error()
+		problem = &message
+	}
+
+	return response.Success(c, fiber.Map{
+		"planChange": newPlanChange(service.InstanceType, target),
+		"allowed":    problem == nil,
+		"error":      problem,
+	})
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+func TestNewPlanChange(t *testing.T) {
+	small := models.InstanceType{ID: "small", CpuCount: 1, MemoryMB: 1024}
+	large := models.InstanceType{ID: "large", CpuCount: 2, MemoryMB: 4096}
+	highCpu := models.InstanceType{ID: "high-cpu", CpuCount: 4, MemoryMB: 512}
+
+	upgrade := newPlanChange(small, large)
+	if upgrade.FromInstanceTypeID != "small" || upgrade.ToInstanceTypeID != "large" {
+		t.Errorf("upgrade = %+v, want small to large", upgrade)
+	}
+	if upgrade.CpuCountDelta != 1 || upgrade.MemoryMBDelta != 3072 || upgrade.Downgrade {
+		t.Errorf("upgrade = %+v, want +1 CPU, +3072 MB and no downgrade", upgrade)
+	}
+
+	downgrade := newPlanChange(large, small)
+	if downgrade.CpuCountDelta != -1 || downgrade.MemoryMBDelta != -3072 || !downgrade.Downgrade {
+		t.Errorf("downgrade = %+v, want -1 CPU, -3072 MB and a downgrade", downgrade)
+	}
+
+	// Less of any resource is a downgrade
+	if change := newPlanChange(small, highCpu); !change.Downgrade {
+		t.Errorf("change to less memory = %+v, want a downgrade", change)
+	}
+}
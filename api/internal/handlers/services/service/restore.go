@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/customdomain"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/ecr"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// checkRestorable returns why a deleted service can't be restored, nil when it can
+func checkRestorable(service models.Service, now time.Time) error {
+	if service.DeletedAt == nil {
+		return errors.New("Service is not deleted")
+	}
+	if service.Project.DeletedAt != nil {
+		return errors.New("Project of the service was deleted")
+	}
+
+	window := time.Duration(config.Get().ServiceRestoreWindowHours) * time.Hour
+	if now.Sub(*service.DeletedAt) > window {
+		return fmt.Errorf("Service was deleted more than %d hours ago and can no longer be restored", config.Get().ServiceRestoreWindowHours)
+	}
+
+	// Deleting the service deleted its volume, restoring it would come back without its data
+	if service.StorageCapacity != nil {
+		return errors.New("Storage of the service was deleted with it, it can't be restored")
+	}
+	return nil
+}
+
+// POST /api/services/:serviceId/restore
+func Restore(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the deleted service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ?", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if err := checkRestorable(service, time.Now()); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	// Another service may have taken its name or custom domain since
+	var existingService models.Service
+	if err := db.Where("projectId = ? AND name = ? AND id <> ? AND deletedAt IS NULL", service.ProjectID, service.Name, service.ID).
+		First(&existingService).Error; err == nil {
+		return response.BadRequest(c, "A service with this name already exists in this project")
+	}
+	if service.CustomDomain != nil {
+		available, err := customdomain.IsAvailable(db, *service.CustomDomain, service.ID)
+		if err != nil {
+			log.Printf("Error checking custom domain %s: %v", *service.CustomDomain, err)
+			return response.InternalServerError(c, "Failed to check custom domain")
+		}
+		if !available {
+			return response.Error(c, fiber.StatusConflict, customdomain.ErrTaken.Error())
+		}
+	}
+
+	// The ingress port was released on deletion, private services allocate a new one
+	result := db.Model(&models.Service{}).
+		Where("id = ? AND deletedAt IS NOT NULL", serviceID).
+		Update("deletedAt", nil)
+	if result.Error != nil {
+		return response.InternalServerError(c, "Failed to restore service")
+	}
+	if result.RowsAffected == 0 {
+		return response.BadRequest(c, "Service is not deleted")
+	}
+
+	// The restored service is deployed again right away
+	if err := servicestatus.SetStatus(serviceID, models.ServiceStatusDeploying, nil); err != nil {
+		log.Printf("Error setting status of restored service %s: %v", serviceID, err)
+	}
+
+	db.Create(&models.ServiceEvent{
+		ServiceID: serviceID,
+		Type:      models.EventTypeServiceRestored,
+		Message:   utils.Ptr("Service restored"),
+	})
+
+	// The ECR repository was deleted with its images, a Docker service is built again
+	usesECR := service.ContainerRegistryImageUri != nil && strings.Contains(*service.ContainerRegistryImageUri, ".ecr.")
+	go func() {
+		if usesECR {
+			if err := ecr.CreateRepository(serviceID); err != nil {
+				log.Printf("Error recreating ECR repository for service %s: %v", serviceID, err)
+			}
+		}
+		if service.Runtime == models.RuntimeDocker {
+			if _, err := deploy.BuildService(serviceID, user.ID, "manual", ""); err != nil {
+				log.Printf("Error starting build of restored service %s: %v", serviceID, err)
+			}
+			return
+		}
+		if err := deploy.DeployService("deploy-service", nil, serviceID); err != nil {
+			log.Printf("Error deploying restored service %s: %v", serviceID, err)
+		}
+	}()
+
+	return response.Success(c, fiber.Map{
+		"message": "Service restored successfully",
+	})
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	os.Setenv("SERVICE_RESTORE_WINDOW_HOURS", "72")
+	os.Setenv("ENCRYPTION_KEY", "0123456789abcdef0123456789abcdef")
+	config.Load()
+
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+func TestCheckRestorable(t *testing.T) {
+	now := time.Now()
+	tests := map[string]struct {
+		service models.Service
+		ok      bool
+	}{
+		"not deleted": {
+			service: models.Service{},
+		},
+		"deleted within the window": {
+			service: models.Service{DeletedAt: utils.Ptr(now.Add(-time.Hour))},
+			ok:      true,
+		},
+		"deleted outside the window": {
+			service: models.Service{DeletedAt: utils.Ptr(now.Add(-73 * time.Hour))},
+		},
+		"project deleted": {
+			service: models.Service{
+				DeletedAt: utils.Ptr(now.Add(-time.Hour)),
+				Project:   models.Project{DeletedAt: utils.Ptr(now)},
+			},
+		},
+		"storage deleted": {
+			service: models.Service{DeletedAt: utils.Ptr(now.Add(-time.Hour)), StorageCapacity: utils.Ptr(10)},
+		},
+	}
+	for name, tt := range tests {
+		err := checkRestorable(tt.service, now)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: checkRestorable = %v, want ok %v", name, err, tt.ok)
+		}
+	}
+}
+
+// deletedService answers lookups of svc1 with a stopped image service of user1 deleted at
+// deletedAt, no other service has its name
+func deletedService(db *dbtest.DB, deletedAt time.Time) {
+	db.OnQuery("FROM `Service`", dbtest.Row{
+		"id": "svc1", "name": "web", "projectId": "proj1", "serviceTypeId": "web",
+		"status": "STOPPED", "runtime": "IMAGE", "deletedAt": deletedAt,
+	})
+	db.OnQuery("AND name = ?")
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": "user1"})
+}
+
+// restoreService restores svc1 as user1
+func restoreService(t *testing.T) *http.Response {
+	t.Helper()
+	app := serviceApp(&models.User{ID: "user1"}, http.MethodPost, "/services/:serviceId/restore", Restore)
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/services/svc1/restore", nil))
+	if err != nil {
+		t.Fatalf("restore request: %v", err)
+	}
+	return resp
+}
+
+func TestRestoreUndeletesAndDeploysService(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	deletedService(db, time.Now().Add(-time.Hour))
+
+	if resp := restoreService(t); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) < 2 {
+		t.Fatalf("updates = %v, want deletedAt cleared and the status set", updates)
+	}
+	if !strings.Contains(updates[0].SQL, "`deletedAt`=?") || updates[0].Args[0] != nil || !strings.Contains(updates[0].SQL, "deletedAt IS NOT NULL") {
+		t.Errorf("first update = %v, want deletedAt of the deleted service cleared", updates[0])
+	}
+	if !strings.Contains(updates[1].SQL, "`status`=?") || fmt.Sprint(updates[1].Args[0]) != "DEPLOYING" {
+		t.Errorf("second update = %v, want the status set to DEPLOYING", updates[1])
+	}
+	// Setting the status records a STATUS_CHANGED event as well
+	var restored []dbtest.Statement
+	for _, event := range db.Statements("INSERT INTO `ServiceEvent`") {
+		if fmt.Sprint(event.Args[1]) == string(models.EventTypeServiceRestored) {
+			restored = append(restored, event)
+		}
+	}
+	if len(restored) != 1 || restored[0].Args[0] != "svc1" {
+		t.Errorf("restore events = %v, want SERVICE_RESTORED recorded for svc1", restored)
+	}
+
+	// The image service is deployed again in the background
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if jobs, _ := redisServer.List(redis.QueueDeployment); len(jobs) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the restored service wasn't queued for deployment")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRestoreRejectsServiceDeletedBeforeWindow(t *testing.T) {
+	db := dbtest.New(t)
+	deletedService(db, time.Now().Add(-73*time.Hour))
+
+	if resp := restoreService(t); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if updates := db.Statements("UPDATE `Service`"); len(updates) != 0 {
+		t.Errorf("restored a service deleted before the window: %v", updates)
+	}
+	if events := db.Statements("INSERT INTO `ServiceEvent`"); len(events) != 0 {
+		t.Errorf("recorded events for a rejected restore: %v", events)
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// POST /api/services/:serviceId/scaling/cancel
+func CancelScaling(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	updated, err := servicestatus.CancelScaling(c.UserContext(), serviceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, servicestatus.ErrNotScaling):
+			return response.Error(c, fiber.StatusConflict, "Service is not scaling")
+		case errors.Is(err, servicestatus.ErrReplicaUpdateInProgress):
+			return response.TooManyRequests(c, "Another update is in progress for this service")
+		case errors.Is(err, servicestatus.ErrServiceNotFound):
+			return response.NotFound(c, "Service not found")
+		}
+		log.Printf("Error cancelling scaling of service %s: %v", serviceID, err)
+		return response.InternalServerError(c, "Failed to cancel scaling")
+	}
+
+	return response.Success(c, fiber.Map{
+		"serviceId":       serviceID,
+		"currentReplicas": updated.CurrentReplicas,
+		"targetReplicas":  updated.TargetReplicas,
+		"scalingStatus":   updated.ScalingStatus,
+	})
+}
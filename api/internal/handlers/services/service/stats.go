@@ -0,0 +1,164 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultStatsWindowDays = 30
+	maxStatsWindowDays     = 365
+)
+
+// DurationStats summarizes a deployment phase duration in seconds. The values are nil
+// when no deployment in the window went through the phase.
+type DurationStats struct {
+	Count   int64    `json:"count"`
+	Average *float64 `json:"average"`
+	P50     *float64 `json:"p50"`
+	P90     *float64 `json:"p90"`
+	P99     *float64 `json:"p99"`
+}
+
+// deploymentOutcomes counts the deployments of a window by how they ended
+type deploymentOutcomes struct {
+	Total     int64 `gorm:"column:total"`
+	Deployed  int64 `gorm:"column:deployed"`
+	Failed    int64 `gorm:"column:failed"`
+	Cancelled int64 `gorm:"column:cancelled"`
+}
+
+// GET /api/services/:serviceId/deployments/stats
+func GetDeploymentStats(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	days := c.QueryInt("days", defaultStatsWindowDays)
+	if days < 1 || days > maxStatsWindowDays {
+		return response.BadRequest(c, fmt.Sprintf("days must be between 1 and %d", maxStatsWindowDays))
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+
+	var outcomes deploymentOutcomes
+	if err := db.Model(&models.Deployment{}).
+		Select(`COUNT(*) AS total,
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS deployed,
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS failed,
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS cancelled`,
+			models.DeploymentStatusDeployed, models.DeploymentStatusFailed, models.DeploymentStatusCancelled).
+		Where("serviceId = ? AND startedAt >= ?", serviceID, since).
+		Scan(&outcomes).Error; err != nil {
+		return response.InternalServerError(c, "Failed to calculate deployment stats")
+	}
+
+	// Build time counts every deployment that finished building, deploy and total time
+	// only the successful ones
+	build, err := durationStats(db, serviceID, since, "startedAt", "builtAt", "")
+	if err != nil {
+		return response.InternalServerError(c, "Failed to calculate deployment stats")
+	}
+	deployed, err := durationStats(db, serviceID, since, "builtAt", "completedAt", string(models.DeploymentStatusDeployed))
+	if err != nil {
+		return response.InternalServerError(c, "Failed to calculate deployment stats")
+	}
+	total, err := durationStats(db, serviceID, since, "startedAt", "completedAt", string(models.DeploymentStatusDeployed))
+	if err != nil {
+		return response.InternalServerError(c, "Failed to calculate deployment stats")
+	}
+
+	// Cancelled deployments say nothing about whether builds work
+	var successRate *float64
+	if finished := outcomes.Deployed + outcomes.Failed; finished > 0 {
+		rate := float64(outcomes.Deployed) / float64(finished)
+		successRate = &rate
+	}
+
+	return response.Success(c, fiber.Map{
+		"windowDays":  days,
+		"since":       since,
+		"deployments": outcomes.Total,
+		"deployed":    outcomes.Deployed,
+		"failed":      outcomes.Failed,
+		"cancelled":   outcomes.Cancelled,
+		"successRate": successRate,
+		"build":       build,
+		"deploy":      deployed,
+		"total":       total,
+	})
+}
+
+// durationStats computes the count, average and nearest-rank percentiles of the seconds
+// between two timestamp columns of a service's deployments started since a time,
+// optionally only those in one status. The column names are never user input.
+func durationStats(db *gorm.DB, serviceID string, since time.Time, fromColumn, toColumn, status string) (DurationStats, error) {
+	query := db.Model(&models.Deployment{}).
+		Where("serviceId = ? AND startedAt >= ?", serviceID, since).
+		Where(fmt.Sprintf("%s IS NOT NULL AND %s IS NOT NULL", fromColumn, toColumn))
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var seconds []float64
+	if err := query.Order("seconds").
+		Pluck(fmt.Sprintf("TIMESTAMPDIFF(SECOND, %s, %s) AS seconds", fromColumn, toColumn), &seconds).Error; err != nil {
+		return DurationStats{}, err
+	}
+	return summarizeDurations(seconds), nil
+}
+
+// summarizeDurations computes the stats of durations sorted in ascending order. The
+// percentiles are nearest-rank: the smallest duration at least that share of them reach.
+func summarizeDurations(seconds []float64) DurationStats {
+	stats := DurationStats{Count: int64(len(seconds))}
+	if len(seconds) == 0 {
+		return stats
+	}
+
+	var sum float64
+	for _, s := range seconds {
+		sum += s
+	}
+	average := sum / float64(len(seconds))
+	stats.Average = &average
+
+	percentile := func(p float64) *float64 {
+		// The epsilon keeps e.g. 0.99 * 100 from rounding up to rank 100
+		rank := int(math.Ceil(p*float64(len(seconds)) - 1e-9))
+		value := seconds[max(rank, 1)-1]
+		return &value
+	}
+	stats.P50 = percentile(0.5)
+	stats.P90 = percentile(0.9)
+	stats.P99 = percentile(0.99)
+	return stats
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+func TestSummarizeDurations(t *testing.T) {
+	hundred := make([]float64, 100)
+	for i := range hundred {
+		hundred[i] = float64(i + 1)
+	}
+
+	tests := []struct {
+		name                   string
+		seconds                []float64
+		average, p50, p90, p99 float64
+	}{
+		{name: "1 to 100", seconds: hundred, average: 50.5, p50: 50, p90: 90, p99: 99},
+		{name: "four builds", seconds: []float64{60, 90, 120, 600}, average: 217.5, p50: 90, p90: 600, p99: 600},
+		{name: "ties", seconds: []float64{30, 30, 30, 30, 30, 30, 30, 30, 30, 300}, average: 57, p50: 30, p90: 30, p99: 300},
+		{name: "one build", seconds: []float64{42}, average: 42, p50: 42, p90: 42, p99: 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := summarizeDurations(tt.seconds)
+			if stats.Count != int64(len(tt.seconds)) {
+				t.Errorf("count = %d, want %d", stats.Count, len(tt.seconds))
+			}
+			if *stats.Average != tt.average || *stats.P50 != tt.p50 || *stats.P90 != tt.p90 || *stats.P99 != tt.p99 {
+				t.Errorf("average/p50/p90/p99 = %v/%v/%v/%v, want %v/%v/%v/%v",
+					*stats.Average, *stats.P50, *stats.P90, *stats.P99, tt.average, tt.p50, tt.p90, tt.p99)
+			}
+		})
+	}
+
+	if stats := summarizeDurations(nil); stats.Count != 0 || stats.Average != nil || stats.P50 != nil {
+		t.Errorf("stats of no deployments = %+v, want no values", stats)
+	}
+}
+
+// durationRows returns the rows of a duration query
+func durationRows(seconds ...float64) []dbtest.Row {
+	rows := make([]dbtest.Row, len(seconds))
+	for i, s := range seconds {
+		rows[i] = dbtest.Row{"seconds": s}
+	}
+	return rows
+}
+
+// deploymentStatsResponse is the JSON of GetDeploymentStats
+type deploymentStatsResponse struct {
+	Data struct {
+		Deployments int64         `json:"deployments"`
+		Deployed    int64         `json:"deployed"`
+		Failed      int64         `json:"failed"`
+		Cancelled   int64         `json:"cancelled"`
+		SuccessRate *float64      `json:"successRate"`
+		Build       DurationStats `json:"build"`
+		Deploy      DurationStats `json:"deploy"`
+		Total       DurationStats `json:"total"`
+	} `json:"data"`
+}
+
+func TestGetDeploymentStats(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+	db.OnQuery("AS total", dbtest.Row{"total": int64(6), "deployed": int64(3), "failed": int64(1), "cancelled": int64(2)})
+	db.OnQuery("TIMESTAMPDIFF(SECOND, startedAt, builtAt)", durationRows(60, 90, 120, 600)...)
+	db.OnQuery("TIMESTAMPDIFF(SECOND, builtAt, completedAt)", durationRows(10, 20, 30)...)
+	db.OnQuery("TIMESTAMPDIFF(SECOND, startedAt, completedAt)", durationRows(70, 110, 150)...)
+	app := serviceApp(&models.User{ID: "user1"}, http.MethodGet, "/services/:serviceId/deployments/stats", GetDeploymentStats)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/services/svc1/deployments/stats?days=7", nil))
+	if err != nil {
+		t.Fatalf("stats request: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var decoded deploymentStatsResponse
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	stats := decoded.Data
+
+	if stats.Deployments != 6 || stats.Deployed != 3 || stats.Failed != 1 || stats.Cancelled != 2 {
+		t.Errorf("outcomes = %+v, want 6 deployments, 3 deployed, 1 failed and 2 cancelled", stats)
+	}
+	// Cancelled deployments are left out of the success rate
+	if stats.SuccessRate == nil || *stats.SuccessRate != 0.75 {
+		t.Errorf("successRate = %v, want 0.75", stats.SuccessRate)
+	}
+	if stats.Build.Count != 4 || *stats.Build.P50 != 90 || *stats.Build.P90 != 600 || *stats.Build.Average != 217.5 {
+		t.Errorf("build = %+v, want 4 builds with p50 90, p90 600 and average 217.5", stats.Build)
+	}
+	if stats.Deploy.Count != 3 || *stats.Deploy.P50 != 20 || *stats.Deploy.P99 != 30 {
+		t.Errorf("deploy = %+v, want 3 deploys with p50 20 and p99 30", stats.Deploy)
+	}
+	if stats.Total.Count != 3 || *stats.Total.P90 != 150 {
+		t.Errorf("total = %+v, want p90 150", stats.Total)
+	}
+
+	// Only the deployments of the service in the window are counted
+	for _, query := range db.Statements("TIMESTAMPDIFF") {
+		if !containsArg(query, "svc1") {
+			t.Errorf("duration query %s is not limited to the service", query.SQL)
+		}
+	}
+}
+
+func TestGetDeploymentStatsChecksOwnership(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "someone-else")
+	app := serviceApp(&models.User{ID: "user1"}, http.MethodGet, "/services/:serviceId/deployments/stats", GetDeploymentStats)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/services/svc1/deployments/stats", nil))
+	if err != nil {
+		t.Fatalf("stats request: %v", err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if queries := db.Statements("FROM `Deployment`"); len(queries) != 0 {
+		t.Errorf("read %d deployment queries for a service of another user", len(queries))
+	}
+}
+
+func TestGetDeploymentStatsRejectsWindow(t *testing.T) {
+	dbtest.New(t)
+	app := serviceApp(&models.User{ID: "user1"}, http.MethodGet, "/services/:serviceId/deployments/stats", GetDeploymentStats)
+
+	for _, days := range []string{"0", "366"} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/services/svc1/deployments/stats?days="+days, nil))
+		if err != nil {
+			t.Fatalf("stats request: %v", err)
+		}
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("days=%s: status = %d, want %d", days, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+func containsArg(statement dbtest.Statement, want string) bool {
+	for _, arg := range statement.Args {
+		if s, ok := arg.(string); ok && s == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// POST /api/services/:serviceId/stop
+func Stop(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if err := servicestatus.Stop(c.UserContext(), service); err != nil {
+		if errors.Is(err, servicestatus.ErrInvalidTransition) {
+			return response.Error(c, fiber.StatusConflict, fmt.Sprintf("Service cannot be stopped while %s", service.Status))
+		}
+		log.Printf("Error stopping service %s: %v", service.ID, err)
+		return response.InternalServerError(c, "Failed to stop service")
+	}
+
+	return response.Success(c, fiber.Map{
+		"message": "Service stop initiated",
+	})
+}
+
+// POST /api/services/:serviceId/start
+func Start(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if err := servicestatus.Start(c.UserContext(), service); err != nil {
+		if errors.Is(err, servicestatus.ErrServiceNotStopped) {
+			return response.Error(c, fiber.StatusConflict, "Service is not stopped")
+		}
+		if errors.Is(err, servicestatus.ErrInvalidTransition) {
+			return response.Error(c, fiber.StatusConflict, fmt.Sprintf("Service cannot be started while %s", service.Status))
+		}
+		log.Printf("Error starting service %s: %v", service.ID, err)
+		return response.InternalServerError(c, "Failed to start service")
+	}
+
+	return response.Success(c, fiber.Map{
+		"message": "Service start initiated",
+	})
+}
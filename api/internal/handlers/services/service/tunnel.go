@@ -0,0 +1,131 @@
+package service
+
+import (
+	"log"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/kubernetes"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// tunnelMetadata returns the labels and annotations the web proxy reads the tunnel of a
+// service from, a nil token hash removes them
+func tunnelMetadata(tokenHash *string) (labels, annotations map[string]*string) {
+	var enabled *string
+	if tokenHash != nil {
+		enabled = utils.Ptr("true")
+	}
+	return map[string]*string{"tunnelEnabled": enabled}, map[string]*string{"tunnelTokenHash": tokenHash}
+}
+
+// POST /api/services/:serviceId/tunnel
+func EnableTunnel(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if service.ServiceTypeID != "web" {
+		return response.BadRequest(c, "Tunnels are only available for web services")
+	}
+
+	// Enabling an enabled tunnel rotates its token, the previous one stops working
+	token, err := crypto.GenerateTunnelToken()
+	if err != nil {
+		log.Printf("Error generating tunnel token: %v", err)
+		return response.InternalServerError(c, "Failed to generate tunnel token")
+	}
+	tokenHash := crypto.HashDeployToken(token)
+
+	if err := db.Model(&models.Service{}).Where("id = ?", serviceID).
+		Updates(map[string]interface{}{
+			"tunnelEnabled":   true,
+			"tunnelTokenHash": tokenHash,
+		}).Error; err != nil {
+		return response.InternalServerError(c, "Failed to save tunnel token")
+	}
+
+	// Redeploys set the same metadata, this applies it to the running service now
+	labels, annotations := tunnelMetadata(&tokenHash)
+	if err := kubernetes.SetServiceMetadata(c.UserContext(), region.Of(service), service.ProjectID, service.ID+"-service", labels, annotations); err != nil {
+		log.Printf("Error enabling tunnel on Kubernetes service %s: %v", service.ID, err)
+		return response.InternalServerError(c, "Failed to enable tunnel on the cluster, try again")
+	}
+
+	// The token is only shown once, only its hash is stored
+	return response.Success(c, fiber.Map{
+		"token": token,
+	})
+}
+
+// DELETE /api/services/:serviceId/tunnel
+func DisableTunnel(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	if err := db.Model(&models.Service{}).Where("id = ?", serviceID).
+		Updates(map[string]interface{}{
+			"tunnelEnabled":   false,
+			"tunnelTokenHash": nil,
+		}).Error; err != nil {
+		return response.InternalServerError(c, "Failed to disable tunnel")
+	}
+
+	// New tunnels are refused right away, not only after the next redeploy
+	labels, annotations := tunnelMetadata(nil)
+	if err := kubernetes.SetServiceMetadata(c.UserContext(), region.Of(service), service.ProjectID, service.ID+"-service", labels, annotations); err != nil {
+		log.Printf("Error disabling tunnel on Kubernetes service %s: %v", service.ID, err)
+		return response.InternalServerError(c, "Tunnel was disabled but is still open on the cluster, try again")
+	}
+
+	return response.Success(c, fiber.Map{
+		"message": "Tunnel disabled",
+	})
+}
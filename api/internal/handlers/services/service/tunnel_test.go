@@ -0,0 +1,36 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+)
+
+func TestTunnelMetadata(t *testing.T) {
+	token, err := crypto.GenerateTunnelToken()
+	if err != nil {
+		t.Fatalf("GenerateTunnelToken: %v", err)
+	}
+	if !strings.HasPrefix(token, "dtt_") {
+		t.Errorf("tunnel token %q has no dtt_ prefix", token)
+	}
+	hash := crypto.HashDeployToken(token)
+
+	labels, annotations := tunnelMetadata(&hash)
+	if v := labels["tunnelEnabled"]; v == nil || *v != "true" {
+		t.Errorf("enabled tunnelEnabled label = %v, want true", v)
+	}
+	if v := annotations["tunnelTokenHash"]; v == nil || *v != hash {
+		t.Errorf("enabled tunnelTokenHash annotation = %v, want %s", v, hash)
+	}
+
+	// Disabling removes both, a merge patch deletes keys set to null
+	labels, annotations = tunnelMetadata(nil)
+	if v, ok := labels["tunnelEnabled"]; !ok || v != nil {
+		t.Errorf("disabled tunnelEnabled label = %v, want removed", v)
+	}
+	if v, ok := annotations["tunnelTokenHash"]; !ok || v != nil {
+		t.Errorf("disabled tunnelTokenHash annotation = %v, want removed", v)
+	}
+}
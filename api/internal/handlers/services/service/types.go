@@ -10,18 +10,33 @@ type UpdateServiceRequest struct {
 	MaxReplicas                    *int             `json:"maxReplicas"`
 	AutoScalingEnabled             *bool            `json:"autoScalingEnabled"`
 	AutoDeployEnabled              *bool            `json:"autoDeployEnabled"`
+	DeployPathFilter               *string          `json:"deployPathFilter"`
+	DeployOnlyProtectedBranches    *bool            `json:"deployOnlyProtectedBranches"`
+	DeployOn                       *string          `json:"deployOn"`
 	CustomDomain                   *string          `json:"customDomain"`
+	PrimaryDomain                  *string          `json:"primaryDomain"`
+	RedirectWww                    *bool            `json:"redirectWww"`
 	HealthCheckPath                *string          `json:"healthCheckPath"`
 	InstanceTypeID                 *string          `json:"instanceTypeId"`
+	ForceInstanceTypeChange        bool             `json:"forceInstanceTypeChange"`
 	StorageCapacity                *int             `json:"storageCapacity"`
 	PortSettings                   []PortSetting    `json:"portSettings"`
 	ContainerCommand               *string          `json:"containerCommand"`
+	ContainerArgs                  *[]string        `json:"containerArgs"`
+	BuildContextPath               *string          `json:"buildContextPath"`
+
+	// Version is the version of the service the client read, the update is rejected when the
+	// service changed since
+	Version *int `json:"version"`
 }
 
 // EnvironmentVar represents an environment variable
 type EnvironmentVar struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
+
+	// IsSecret is false for plain config, unset means secret
+	IsSecret *bool `json:"isSecret,omitempty"`
 }
 
 // PortSetting represents a port configuration
@@ -33,4 +48,6 @@ type PortSetting struct {
 // DeployRequest represents the request body for deploying a service
 type DeployRequest struct {
 	CommitSha *string `json:"commitSha"`
+	// Ref is a branch, tag or commit SHA resolved before the build is queued
+	Ref *string `json:"ref"`
 }
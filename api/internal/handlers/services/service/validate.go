@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// POST /api/services/:serviceId/deploy/validate
+// Runs the pre-flight checks of a deployment without starting one
+func ValidateDeploy(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return response.Unauthorized(c, "Unauthorized")
+	}
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	var req DeployRequest
+	if err := c.BodyParser(&req); err != nil {
+		// Allow empty body
+		req = DeployRequest{}
+	}
+
+	// Fetch the service with access check
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Preload("GitProvider.GithubAccount").
+		Preload("InstanceType").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	// Check access
+	if service.Project.Organization.UserID != user.ID {
+		return response.Forbidden(c, "Service not found or access denied")
+	}
+
+	// Check if service runtime is Docker
+	if service.Runtime != models.RuntimeDocker {
+		return response.BadRequest(c, "Service runtime is not Docker")
+	}
+
+	ref := ""
+	if req.Ref != nil {
+		ref = strings.TrimSpace(*req.Ref)
+	}
+	if req.CommitSha != nil && *req.CommitSha != "" {
+		if ref != "" {
+			return response.BadRequest(c, "Specify either ref or commitSha, not both")
+		}
+		ref = *req.CommitSha
+	}
+
+	validation := deploy.ValidateBuild(context.Background(), service, ref)
+
+	// The builder and Kubernetes get the decrypted env vars
+	var stored []crypto.EnvironmentVariable
+	service.EnvironmentVariables.UnmarshalTo(&stored)
+	envVars, _ := crypto.DecryptEnvVars(stored)
+	if err := servicestatus.CheckEnvVarLimits(envVars); err != nil {
+		validation.Add("environmentVariables", deploy.IssueError, err.Error())
+	}
+
+	return response.Success(c, validation)
+}
@@ -0,0 +1,381 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// existingServices returns the services of a project keyed by name, the oldest one when
+// names repeat
+func existingServices(db *gorm.DB, projectID string) (map[string]models.Service, error) {
+	var services []models.Service
+	if err := db.Preload("Ports").
+		Preload("InstanceType").
+		Where("projectId = ? AND deletedAt IS NULL", projectID).
+		Order("createdAt ASC").
+		Find(&services).Error; err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]models.Service, len(services))
+	for _, service := range services {
+		if _, ok := byName[service.Name]; !ok {
+			byName[service.Name] = service
+		}
+	}
+	return byName, nil
+}
+
+// validateApply checks that every template entry matching an existing service by name has
+// the same type, a service can't be turned into a database
+func validateApply(template ParsedTemplate, existing map[string]models.Service) error {
+	check := func(name, serviceType string) error {
+		if current, ok := existing[name]; ok && current.ServiceTypeID != serviceType {
+			return fmt.Errorf("Service '%s' already exists as a %s service, not %s", name, current.ServiceTypeID, serviceType)
+		}
+		return nil
+	}
+
+	for _, service := range template.Services {
+		if err := check(service.Name, service.Type); err != nil {
+			return err
+		}
+	}
+	for _, database := range template.Databases {
+		if err := check(database.Name, database.Type); err != nil {
+			return err
+		}
+	}
+	for _, memory := range template.Memory {
+		if err := check(memory.Name, memory.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyServiceTemplate updates an existing web or private service from a template. Env vars
+// of the template are set, the service's other env vars are kept.
+func applyServiceTemplate(db *gorm.DB, service models.Service, template ServiceTemplate, createdServices []CreatedServiceInfo) (*CreatedServiceInfo, fiber.Map, error) {
+	updates, _, err := planUpdates(db, service, template.Plan)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(template.EnvVars) > 0 {
+		var stored []EnvironmentVariable
+		service.EnvironmentVariables.UnmarshalTo(&stored)
+		cryptoEnvVars := make([]crypto.EnvironmentVariable, len(stored))
+		for i, v := range stored {
+			cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
+		}
+		decryptedEnvVars, _ := crypto.DecryptEnvVars(cryptoEnvVars)
+
+		envVars := make([]EnvironmentVariable, len(decryptedEnvVars))
+		current := make(map[string]string, len(decryptedEnvVars))
+		index := make(map[string]int, len(decryptedEnvVars))
+		for i, v := range decryptedEnvVars {
+			envVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
+			current[v.Key] = v.Value
+			index[v.Key] = i
+		}
+
+		for _, v := range resolveEnvVars(template.EnvVars, createdServices, current) {
+			if i, ok := index[v.Key]; ok {
+				envVars[i].Value = v.Value
+				if v.IsSecret != nil {
+					envVars[i].IsSecret = v.IsSecret
+				}
+				continue
+			}
+			index[v.Key] = len(envVars)
+			envVars = append(envVars, v)
+		}
+
+		if err := checkEnvVarLimits(service.Name, envVars); err != nil {
+			return nil, nil, err
+		}
+		envVarsJSON, err := encryptEnvVars(envVars)
+		if err != nil {
+			return nil, nil, err
+		}
+		updates["environmentVariables"] = envVarsJSON
+	}
+
+	if template.HealthCheckPath != nil {
+		updates["healthCheckPath"] = *template.HealthCheckPath
+	}
+	if len(template.Command) > 0 {
+		updates["containerCommand"] = strings.Join(template.Command, " ")
+	}
+	if len(template.Args) > 0 {
+		argsJSON, _ := json.Marshal(template.Args)
+		updates["containerArgs"] = models.JSON(argsJSON)
+	}
+	if service.Runtime == models.RuntimeImage && template.Image != nil && template.Image.URL != "" {
+		updates["containerRegistryImageUri"] = template.Image.URL
+	}
+
+	portsChanged := len(template.Ports) > 0 && !samePorts(service.Ports, template.Ports)
+	if err := applyUpdates(db, service, updates, portsChanged, func() {
+		db.Where("serviceId = ?", service.ID).Delete(&models.ServicePort{})
+		for _, port := range template.Ports {
+			db.Create(&models.ServicePort{
+				ServiceID:     service.ID,
+				ServicePort:   port.ServicePort,
+				ContainerPort: port.ContainerPort,
+			})
+		}
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("Service %s updated from template", template.Name)
+
+	db.First(&service, "id = ?", service.ID)
+	serviceResponse := fiber.Map{
+		"id":              service.ID,
+		"name":            service.Name,
+		"serviceTypeId":   service.ServiceTypeID,
+		"projectId":       service.ProjectID,
+		"subdomain":       service.Subdomain,
+		"runtime":         service.Runtime,
+		"createdAt":       service.CreatedAt,
+		"updatedAt":       service.UpdatedAt,
+		"status":          service.Status,
+		"instanceTypeId":  service.InstanceTypeID,
+		"healthCheckPath": service.HealthCheckPath,
+	}
+
+	// Determine port for CreatedServiceInfo
+	var servicePort int
+	if len(template.Ports) > 0 {
+		servicePort = template.Ports[0].ContainerPort
+	} else if len(service.Ports) > 0 {
+		servicePort = service.Ports[0].ContainerPort
+	}
+
+	return &CreatedServiceInfo{
+		ID:   service.ID,
+		Name: service.Name,
+		Port: servicePort,
+	}, serviceResponse, nil
+}
+
+// applyDatabaseTemplate updates the plan of an existing database from a template and grows
+// its storage, volumes can't shrink
+func applyDatabaseTemplate(db *gorm.DB, service models.Service, template DatabaseTemplate) (*CreatedServiceInfo, fiber.Map, error) {
+	updates, instanceType, err := planUpdates(db, service, template.Plan)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storageCapacity := template.StorageCapacity
+	if storageCapacity < 10 {
+		storageCapacity = 10
+	}
+	storageResized := service.StorageCapacity == nil || storageCapacity > *service.StorageCapacity
+	if storageResized {
+		// Checked against the instance type the database will run on, like a resize from the dashboard
+		if err := servicestatus.ValidateStorageCapacity(service, instanceType, storageCapacity); err != nil {
+			return nil, nil, &planChangeError{service: service.Name, err: err}
+		}
+		updates["storageCapacity"] = storageCapacity
+		updates["storageCapacityChangedAt"] = time.Now()
+	}
+
+	if err := applyUpdates(db, service, updates, false, nil); err != nil {
+		return nil, nil, err
+	}
+
+	if storageResized {
+		previous := "none"
+		if service.StorageCapacity != nil {
+			previous = fmt.Sprintf("%d GB", *service.StorageCapacity)
+		}
+		db.Create(&models.ServiceEvent{
+			ServiceID: service.ID,
+			Type:      models.EventTypeStorageResized,
+			Message:   utils.Ptr(fmt.Sprintf("Storage capacity changed from %s to %d GB", previous, storageCapacity)),
+		})
+	}
+
+	log.Printf("Database %s updated from template", template.Name)
+
+	db.First(&service, "id = ?", service.ID)
+	serviceResponse := fiber.Map{
+		"id":              service.ID,
+		"name":            service.Name,
+		"serviceTypeId":   service.ServiceTypeID,
+		"projectId":       service.ProjectID,
+		"runtime":         service.Runtime,
+		"createdAt":       service.CreatedAt,
+		"updatedAt":       service.UpdatedAt,
+		"status":          service.Status,
+		"instanceTypeId":  service.InstanceTypeID,
+		"storageCapacity": service.StorageCapacity,
+	}
+
+	return existingServiceInfo(db, service), serviceResponse, nil
+}
+
+// applyMemoryTemplate updates the plan of an existing memory service from a template
+func applyMemoryTemplate(db *gorm.DB, service models.Service, template MemoryTemplate) (*CreatedServiceInfo, fiber.Map, error) {
+	updates, _, err := planUpdates(db, service, template.Plan)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := applyUpdates(db, service, updates, false, nil); err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("Memory service %s updated from template", template.Name)
+
+	db.First(&service, "id = ?", service.ID)
+	serviceResponse := fiber.Map{
+		"id":             service.ID,
+		"name":           service.Name,
+		"serviceTypeId":  service.ServiceTypeID,
+		"projectId":      service.ProjectID,
+		"runtime":        service.Runtime,
+		"createdAt":      service.CreatedAt,
+		"updatedAt":      service.UpdatedAt,
+		"status":         service.Status,
+		"instanceTypeId": service.InstanceTypeID,
+	}
+
+	return existingServiceInfo(db, service), serviceResponse, nil
+}
+
+// planChangeError is returned when a template changes the plan of a service in a way the
+// service can't be changed
+type planChangeError struct {
+	service string
+	err     error
+}
+
+func (e *planChangeError) Error() string {
+	return fmt.Sprintf("Service '%s': %v", e.service, e.err)
+}
+
+// applyFailed responds to an error updating an existing service from a template
+func applyFailed(c *fiber.Ctx, err error) error {
+	var planErr *planChangeError
+	var limitErr *envVarLimitError
+	switch {
+	case errors.As(err, &planErr):
+		return response.BadRequest(c, planErr.Error())
+	case errors.As(err, &limitErr):
+		return response.BadRequest(c, limitErr.Error())
+	case errors.Is(err, servicestatus.ErrStaleService):
+		return response.Conflict(c, "A service of the template changed while it was applied, try again")
+	}
+	return response.InternalServerError(c, "Failed to apply template")
+}
+
+// planUpdates starts the updates of a service with its new plan, if it changed, and returns the
+// instance type the service will run on. The change is checked like a plan change from the
+// dashboard.
+func planUpdates(db *gorm.DB, service models.Service, plan string) (map[string]interface{}, models.InstanceType, error) {
+	updates := make(map[string]interface{})
+	instanceTypeID := strings.ToLower(strings.TrimSpace(plan))
+	if instanceTypeID == service.InstanceTypeID {
+		return updates, service.InstanceType, nil
+	}
+
+	var target models.InstanceType
+	if err := db.Preload("InstanceTypeGroup").Where("id = ?", instanceTypeID).First(&target).Error; err != nil {
+		return nil, target, &planChangeError{service: service.Name, err: fmt.Errorf("Instance type '%s' not found", instanceTypeID)}
+	}
+	if err := servicestatus.ValidateInstanceTypeChange(db, service, target, false); err != nil {
+		return nil, target, &planChangeError{service: service.Name, err: err}
+	}
+
+	updates["instanceTypeId"] = instanceTypeID
+	updates["instanceTypeChangedAt"] = time.Now()
+	return updates, target, nil
+}
+
+// applyUpdates saves the updates of a service and redeploys it if it is running, like an
+// update from the dashboard. Changed ports are saved by savePorts once the service was
+// updated, they need a redeploy too.
+func applyUpdates(db *gorm.DB, service models.Service, updates map[string]interface{}, portsChanged bool, savePorts func()) error {
+	if len(updates) == 0 && !portsChanged {
+		return nil
+	}
+
+	// Changed ports bump the version too
+	if err := servicestatus.UpdateVersioned(db, service.ID, service.Version, updates); err != nil {
+		if errors.Is(err, servicestatus.ErrStaleService) {
+			return fmt.Errorf("service '%s': %w", service.Name, err)
+		}
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+	if portsChanged {
+		savePorts()
+	}
+
+	if service.Status == models.ServiceStatusRunning ||
+		service.Status == models.ServiceStatusFailed ||
+		service.Status == models.ServiceStatusRestarting {
+		go func() {
+			if err := deploy.DeployService("deploy-service", nil, service.ID); err != nil {
+				log.Printf("Error redeploying service: %v", err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// existingServiceInfo returns the info env vars of other services resolve against for an
+// existing database or memory service
+func existingServiceInfo(db *gorm.DB, service models.Service) *CreatedServiceInfo {
+	info := &CreatedServiceInfo{
+		ID:   service.ID,
+		Name: service.Name,
+	}
+	if len(service.Ports) > 0 {
+		info.Port = service.Ports[0].ContainerPort
+	}
+
+	var credential models.ServiceCredential
+	if err := db.Where("serviceId = ?", service.ID).First(&credential).Error; err == nil {
+		info.Credentials = &credential
+		info.Port = credential.Port
+	} else {
+		log.Printf("No credentials found for service %s: %v", service.ID, err)
+	}
+
+	return info
+}
+
+// samePorts reports whether a service already has exactly the ports of a template
+func samePorts(ports []models.ServicePort, templatePorts []PortConfig) bool {
+	if len(ports) != len(templatePorts) {
+		return false
+	}
+	current := make(map[int]int, len(ports))
+	for _, port := range ports {
+		current[port.ServicePort] = port.ContainerPort
+	}
+	for _, port := range templatePorts {
+		if containerPort, ok := current[port.ServicePort]; !ok || containerPort != port.ContainerPort {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,209 @@
+package template
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPlanUpdatesKeepsUnchangedPlan(t *testing.T) {
+	updates, _, err := planUpdates(nil, models.Service{InstanceTypeID: "small"}, " Small ")
+	if err != nil {
+		t.Fatalf("planUpdates: %v", err)
+	}
+	if len(updates) != 0 {
+		t.Errorf("planUpdates = %v, want no updates", updates)
+	}
+}
+
+func TestApplyFailed(t *testing.T) {
+	tests := map[string]struct {
+		err    error
+		status int
+	}{
+		"rejected plan change": {
+			err:    fmt.Errorf("wrapped: %w", &planChangeError{service: "web", err: errors.New("Too small")}),
+			status: fiber.StatusBadRequest,
+		},
+		"too many env vars": {
+			err:    &envVarLimitError{service: "web", err: errors.New("Too many environment variables")},
+			status: fiber.StatusBadRequest,
+		},
+		"stale service": {
+			err:    fmt.Errorf("service 'web': %w", servicestatus.ErrStaleService),
+			status: fiber.StatusConflict,
+		},
+		"other error": {
+			err:    errors.New("database is down"),
+			status: fiber.StatusInternalServerError,
+		},
+	}
+	for name, tt := range tests {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return applyFailed(c, tt.err) })
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		if err != nil {
+			t.Fatalf("%s: request: %v", name, err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, want %d", name, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+// The template applied by the tests below, a database and a web service using it
+const projectTemplate = `
+databases:
+  - name: orders
+    type: postgresql
+    plan: pg-small
+    storageCapacity: 10
+services:
+  - name: web
+    type: web
+    plan: web-small
+`
+
+// projectRows answers the lookups of applying a template to proj1 of user1. The project has
+// the services of projectTemplate while hasServices reports true.
+func projectRows(db *dbtest.DB, hasServices func() bool) {
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": "user1"})
+
+	instanceTypes := []dbtest.Row{
+		{"id": "pg-small", "name": "pg-small", "instanceTypeGroupId": "pg", "maxStorageCapacity": int64(50), "isVisible": true},
+		{"id": "web-small", "name": "web-small", "instanceTypeGroupId": "web", "isVisible": true},
+	}
+	// Preloads only get the instance types of the services they load
+	db.OnQueryFunc("FROM `InstanceType`", func(args []driver.Value) []dbtest.Row {
+		var rows []dbtest.Row
+		for _, row := range instanceTypes {
+			for _, arg := range args {
+				if arg == true || fmt.Sprint(arg) == row["id"] {
+					rows = append(rows, row)
+					break
+				}
+			}
+		}
+		return rows
+	})
+	db.OnQuery("FROM `InstanceTypeGroup`",
+		dbtest.Row{"id": "pg", "serviceTypeId": "postgresql"},
+		dbtest.Row{"id": "web", "serviceTypeId": "web"})
+
+	db.OnQueryFunc("FROM `Service`", func(args []driver.Value) []dbtest.Row {
+		if fmt.Sprint(args[0]) != "proj1" {
+			return []dbtest.Row{{"id": args[0], "projectId": "proj1", "serviceTypeId": "web"}}
+		}
+		if !hasServices() {
+			return nil
+		}
+		return []dbtest.Row{
+			{"id": "db1", "name": "orders", "projectId": "proj1", "serviceTypeId": "postgresql", "instanceTypeId": "pg-small", "storageCapacity": int64(10), "version": int64(1)},
+			{"id": "web1", "name": "web", "projectId": "proj1", "serviceTypeId": "web", "instanceTypeId": "web-small", "runtime": "IMAGE", "version": int64(1)},
+		}
+	})
+	// Generated subdomains are free
+	db.OnQuery("count(*)", dbtest.Row{"count(*)": int64(0)})
+}
+
+type applyResponse struct {
+	Data struct {
+		Created []map[string]interface{} `json:"created"`
+		Updated []map[string]interface{} `json:"updated"`
+	} `json:"data"`
+}
+
+// applyTemplate applies yamlTemplate to proj1 as user1
+func applyTemplate(t *testing.T, yamlTemplate string) (*http.Response, applyResponse) {
+	t.Helper()
+	app := fiber.New()
+	app.Post("/services/template", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user1"})
+		return c.Next()
+	}, Create)
+
+	body, _ := json.Marshal(CreateFromTemplateRequest{ProjectID: "proj1", YamlTemplate: yamlTemplate, Mode: ModeApply})
+	req := httptest.NewRequest(http.MethodPost, "/services/template", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("apply request: %v", err)
+	}
+	var decoded applyResponse
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp, decoded
+}
+
+// waitForDeploys waits until n services were queued for deployment
+func waitForDeploys(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		jobs, _ := redisServer.List(redis.QueueDeployment)
+		if len(jobs) >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d services queued for deployment, want %d", len(jobs), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestApplyTemplateTwiceUpdatesServices(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	projectRows(db, func() bool { return len(db.Statements("INSERT INTO `Service`")) > 0 })
+
+	resp, first := applyTemplate(t, projectTemplate)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("first apply: status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if len(first.Data.Created) != 2 || len(first.Data.Updated) != 0 {
+		t.Fatalf("first apply created %v and updated %v, want both services created", first.Data.Created, first.Data.Updated)
+	}
+	waitForDeploys(t, 2)
+	inserted := len(db.Statements("INSERT INTO `Service`"))
+
+	resp, second := applyTemplate(t, projectTemplate)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("second apply: status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if len(second.Data.Created) != 0 || len(second.Data.Updated) != 2 {
+		t.Errorf("second apply created %v and updated %v, want both services updated", second.Data.Created, second.Data.Updated)
+	}
+	if got := len(db.Statements("INSERT INTO `Service`")); got != inserted {
+		t.Errorf("second apply inserted %d services, want none", got-inserted)
+	}
+	// Nothing of the template changed, so nothing was saved
+	if updates := db.Statements("UPDATE `Service`"); len(updates) != 0 {
+		t.Errorf("updates = %v, want none", updates)
+	}
+}
+
+func TestApplyTemplateChecksStorageCapacity(t *testing.T) {
+	db := dbtest.New(t)
+	projectRows(db, func() bool { return true })
+
+	resp, _ := applyTemplate(t, strings.Replace(projectTemplate, "storageCapacity: 10", "storageCapacity: 80", 1))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if updates := db.Statements("UPDATE `Service`"); len(updates) != 0 {
+		t.Errorf("resized the database past the maximum of its plan: %v", updates)
+	}
+}
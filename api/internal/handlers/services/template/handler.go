@@ -2,6 +2,7 @@ package template
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -10,6 +11,9 @@ import (
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/deploy"
 	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	servicesubdomain "github.com/deployra/deployra/api/internal/subdomain"
 	"github.com/deployra/deployra/api/internal/utils"
 	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/gofiber/fiber/v2"
@@ -40,6 +44,12 @@ func Create(c *fiber.Ctx) error {
 	if req.YamlTemplate == "" {
 		return response.BadRequest(c, "YAML template is required")
 	}
+	if req.Mode == "" {
+		req.Mode = ModeCreate
+	}
+	if req.Mode != ModeCreate && req.Mode != ModeApply {
+		return response.BadRequest(c, "Mode must be create or apply")
+	}
 
 	// Parse YAML template
 	var template ParsedTemplate
@@ -62,6 +72,12 @@ func Create(c *fiber.Ctx) error {
 		return response.Forbidden(c, "Project not found or unauthorized access")
 	}
 
+	// Every service of the template runs in the organization's region
+	serviceRegion, err := region.Resolve(nil, project.Organization)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
 	// Load all instance types for validation
 	var instanceTypes []models.InstanceType
 	db.Preload("InstanceTypeGroup").Where("isVisible = ?", true).Find(&instanceTypes)
@@ -111,13 +127,39 @@ func Create(c *fiber.Ctx) error {
 		}
 	}
 
+	// In apply mode the services of the project are matched by name and updated
+	existing := make(map[string]models.Service)
+	if req.Mode == ModeApply {
+		var err error
+		existing, err = existingServices(db, req.ProjectID)
+		if err != nil {
+			log.Printf("Error loading services of project %s: %v", req.ProjectID, err)
+			return response.InternalServerError(c, "Failed to load project services")
+		}
+		if err := validateApply(template, existing); err != nil {
+			return response.BadRequest(c, err.Error())
+		}
+	}
+
 	// Create all services from the template
 	createdServices := make([]CreatedServiceInfo, 0)
 	createdServiceResponses := make([]fiber.Map, 0)
+	updatedServiceResponses := make([]fiber.Map, 0)
 
 	// Create databases FIRST (they are dependencies for other services)
 	for _, dbTemplate := range template.Databases {
-		service, serviceResponse, err := createDatabaseFromTemplate(db, dbTemplate, req.ProjectID)
+		if current, ok := existing[dbTemplate.Name]; ok {
+			service, serviceResponse, err := applyDatabaseTemplate(db, current, dbTemplate)
+			if err != nil {
+				log.Printf("Error updating database from template: %v", err)
+				return applyFailed(c, err)
+			}
+			createdServices = append(createdServices, *service)
+			updatedServiceResponses = append(updatedServiceResponses, serviceResponse)
+			continue
+		}
+
+		service, serviceResponse, err := createDatabaseFromTemplate(db, dbTemplate, req.ProjectID, serviceRegion)
 		if err != nil {
 			log.Printf("Error creating database from template: %v", err)
 			return response.InternalServerError(c, "Failed to create services from template")
@@ -128,7 +170,18 @@ func Create(c *fiber.Ctx) error {
 
 	// Create memory services SECOND (they might be dependencies)
 	for _, memTemplate := range template.Memory {
-		service, serviceResponse, err := createMemoryFromTemplate(db, memTemplate, req.ProjectID)
+		if current, ok := existing[memTemplate.Name]; ok {
+			service, serviceResponse, err := applyMemoryTemplate(db, current, memTemplate)
+			if err != nil {
+				log.Printf("Error updating memory service from template: %v", err)
+				return applyFailed(c, err)
+			}
+			createdServices = append(createdServices, *service)
+			updatedServiceResponses = append(updatedServiceResponses, serviceResponse)
+			continue
+		}
+
+		service, serviceResponse, err := createMemoryFromTemplate(db, memTemplate, req.ProjectID, serviceRegion)
 		if err != nil {
 			log.Printf("Error creating memory service from template: %v", err)
 			return response.InternalServerError(c, "Failed to create services from template")
@@ -139,15 +192,37 @@ func Create(c *fiber.Ctx) error {
 
 	// Create web/application services LAST (they depend on databases/memory)
 	for _, serviceTemplate := range template.Services {
-		service, serviceResponse, err := createServiceFromTemplate(db, user, serviceTemplate, req.ProjectID, createdServices)
+		if current, ok := existing[serviceTemplate.Name]; ok {
+			service, serviceResponse, err := applyServiceTemplate(db, current, serviceTemplate, createdServices)
+			if err != nil {
+				log.Printf("Error updating service from template: %v", err)
+				return applyFailed(c, err)
+			}
+			createdServices = append(createdServices, *service)
+			updatedServiceResponses = append(updatedServiceResponses, serviceResponse)
+			continue
+		}
+
+		service, serviceResponse, err := createServiceFromTemplate(db, user, serviceTemplate, req.ProjectID, serviceRegion, createdServices)
 		if err != nil {
 			log.Printf("Error creating service from template: %v", err)
+			var limitErr *envVarLimitError
+			if errors.As(err, &limitErr) {
+				return response.BadRequest(c, limitErr.Error())
+			}
 			return response.InternalServerError(c, "Failed to create services from template")
 		}
 		createdServices = append(createdServices, *service)
 		createdServiceResponses = append(createdServiceResponses, serviceResponse)
 	}
 
+	if req.Mode == ModeApply {
+		return response.Success(c, fiber.Map{
+			"created": createdServiceResponses,
+			"updated": updatedServiceResponses,
+		})
+	}
+
 	return response.Success(c, createdServiceResponses)
 }
 
@@ -161,7 +236,7 @@ func findInstanceType(instanceTypes []models.InstanceType, plan string, serviceT
 	return nil
 }
 
-func createServiceFromTemplate(db *gorm.DB, user *models.User, template ServiceTemplate, projectID string, createdServices []CreatedServiceInfo) (*CreatedServiceInfo, fiber.Map, error) {
+func createServiceFromTemplate(db *gorm.DB, user *models.User, template ServiceTemplate, projectID string, serviceRegion *string, createdServices []CreatedServiceInfo) (*CreatedServiceInfo, fiber.Map, error) {
 	// Determine runtime
 	runtime := models.RuntimeImage
 	if template.Runtime == "docker" {
@@ -171,110 +246,24 @@ func createServiceFromTemplate(db *gorm.DB, user *models.User, template ServiceT
 	// Generate subdomain for web services
 	var subdomain *string
 	if template.Type == "web" {
-		sub := utils.GenerateSubdomain(template.Name)
+		sub, err := servicesubdomain.Generate(db, template.Name)
+		if err != nil {
+			return nil, nil, err
+		}
 		subdomain = &sub
 	}
 
 	// Process environment variables
 	var envVarsJSON []byte
-	if len(template.EnvVars) > 0 {
-		envVars := make([]EnvironmentVariable, 0)
-		for _, envVar := range template.EnvVars {
-			if envVar.GenerateValue {
-				envVars = append(envVars, EnvironmentVariable{
-					Key:   envVar.Key,
-					Value: utils.GenerateRandomString(32),
-				})
-			} else if envVar.Value != "" {
-				envVars = append(envVars, EnvironmentVariable{
-					Key:   envVar.Key,
-					Value: envVar.Value,
-				})
-			} else if envVar.FromDatabase != nil {
-				// Find the database service by name in created services
-				var dbService *CreatedServiceInfo
-				for i, s := range createdServices {
-					if s.Name == envVar.FromDatabase.Name {
-						dbService = &createdServices[i]
-						break
-					}
-				}
-
-				if dbService != nil && dbService.Credentials != nil {
-					var value string
-					switch envVar.FromDatabase.Property {
-					case "host":
-						value = dbService.ID + "-service"
-					case "user", "username":
-						value = dbService.Credentials.Username
-					case "password":
-						value = dbService.Credentials.Password
-					case "database":
-						value = dbService.Credentials.Database
-					case "port":
-						value = fmt.Sprintf("%d", dbService.Credentials.Port)
-					default:
-						log.Printf("Unknown database property '%s' for service '%s'", envVar.FromDatabase.Property, envVar.FromDatabase.Name)
-						continue
-					}
-
-					envVars = append(envVars, EnvironmentVariable{
-						Key:   envVar.Key,
-						Value: value,
-					})
-				} else {
-					log.Printf("Database service '%s' not found or has no credentials", envVar.FromDatabase.Name)
-				}
-			} else if envVar.FromService != nil {
-				// Find the referenced service by name in created services
-				var refService *CreatedServiceInfo
-				for i, s := range createdServices {
-					if s.Name == envVar.FromService.Name {
-						refService = &createdServices[i]
-						break
-					}
-				}
-
-				if refService != nil {
-					var value string
-					switch envVar.FromService.Property {
-					case "host":
-						value = refService.ID + "-service"
-					case "port":
-						value = fmt.Sprintf("%d", refService.Port)
-					default:
-						log.Printf("Unknown service property '%s' for service '%s'", envVar.FromService.Property, envVar.FromService.Name)
-						continue
-					}
-
-					envVars = append(envVars, EnvironmentVariable{
-						Key:   envVar.Key,
-						Value: value,
-					})
-				} else {
-					log.Printf("Service '%s' not found in created services", envVar.FromService.Name)
-				}
-			}
+	if envVars := resolveEnvVars(template.EnvVars, createdServices, nil); len(envVars) > 0 {
+		if err := checkEnvVarLimits(template.Name, envVars); err != nil {
+			return nil, nil, err
 		}
-
-		if len(envVars) > 0 {
-			// Convert to crypto type for encryption
-			cryptoEnvVars := make([]crypto.EnvironmentVariable, len(envVars))
-			for i, v := range envVars {
-				cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
-			}
-			encryptedEnvVars, err := crypto.EncryptEnvVars(cryptoEnvVars)
-			if err != nil {
-				log.Printf("Error encrypting environment variables: %v", err)
-				return nil, nil, fmt.Errorf("failed to encrypt environment variables: %w", err)
-			}
-			// Convert back for storage
-			storageEnvVars := make([]EnvironmentVariable, len(encryptedEnvVars))
-			for i, v := range encryptedEnvVars {
-				storageEnvVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value}
-			}
-			envVarsJSON, _ = json.Marshal(storageEnvVars)
+		encrypted, err := encryptEnvVars(envVars)
+		if err != nil {
+			return nil, nil, err
 		}
+		envVarsJSON = encrypted
 	}
 
 	// Create service
@@ -283,6 +272,7 @@ func createServiceFromTemplate(db *gorm.DB, user *models.User, template ServiceT
 		Name:            template.Name,
 		ServiceTypeID:   template.Type,
 		ProjectID:       projectID,
+		Region:          serviceRegion,
 		Subdomain:       subdomain,
 		Runtime:         runtime,
 		InstanceTypeID:  strings.ToLower(strings.TrimSpace(template.Plan)),
@@ -304,6 +294,12 @@ func createServiceFromTemplate(db *gorm.DB, user *models.User, template ServiceT
 		service.ContainerCommand = &commandStr
 	}
 
+	// Add container args if specified
+	if len(template.Args) > 0 {
+		argsJSON, _ := json.Marshal(template.Args)
+		service.ContainerArgs = argsJSON
+	}
+
 	// Add image-specific parameters
 	if runtime == models.RuntimeImage && template.Image != nil && template.Image.URL != "" {
 		containerType := "docker"
@@ -369,7 +365,133 @@ func createServiceFromTemplate(db *gorm.DB, user *models.User, template ServiceT
 	}, serviceResponse, nil
 }
 
-func createDatabaseFromTemplate(db *gorm.DB, template DatabaseTemplate, projectID string) (*CreatedServiceInfo, fiber.Map, error) {
+// resolveEnvVars turns the env vars of a template into values, resolving references to the
+// services created or matched so far. Generated values reuse the existing value of the key, so
+// applying a template again doesn't rotate secrets.
+func resolveEnvVars(envVarTemplates []EnvVarTemplate, createdServices []CreatedServiceInfo, existing map[string]string) []EnvironmentVariable {
+	envVars := make([]EnvironmentVariable, 0)
+	for _, envVar := range envVarTemplates {
+		if envVar.GenerateValue {
+			value, ok := existing[envVar.Key]
+			if !ok {
+				value = utils.GenerateRandomString(32)
+			}
+			envVars = append(envVars, EnvironmentVariable{
+				Key:      envVar.Key,
+				Value:    value,
+				IsSecret: envVar.IsSecret,
+			})
+		} else if envVar.Value != "" {
+			envVars = append(envVars, EnvironmentVariable{
+				Key:      envVar.Key,
+				Value:    envVar.Value,
+				IsSecret: envVar.IsSecret,
+			})
+		} else if envVar.FromDatabase != nil {
+			// Find the database service by name in created services
+			var dbService *CreatedServiceInfo
+			for i, s := range createdServices {
+				if s.Name == envVar.FromDatabase.Name {
+					dbService = &createdServices[i]
+					break
+				}
+			}
+
+			if dbService != nil && dbService.Credentials != nil {
+				value, ok := servicestatus.DatabaseProperty(dbService.ID, *dbService.Credentials, envVar.FromDatabase.Property)
+				if !ok {
+					log.Printf("Unknown database property '%s' for service '%s'", envVar.FromDatabase.Property, envVar.FromDatabase.Name)
+					continue
+				}
+
+				envVars = append(envVars, EnvironmentVariable{
+					Key:      envVar.Key,
+					Value:    value,
+					IsSecret: envVar.IsSecret,
+				})
+			} else {
+				log.Printf("Database service '%s' not found or has no credentials", envVar.FromDatabase.Name)
+			}
+		} else if envVar.FromService != nil {
+			// Find the referenced service by name in created services
+			var refService *CreatedServiceInfo
+			for i, s := range createdServices {
+				if s.Name == envVar.FromService.Name {
+					refService = &createdServices[i]
+					break
+				}
+			}
+
+			if refService != nil {
+				var value string
+				switch envVar.FromService.Property {
+				case "host":
+					value = refService.ID + "-service"
+				case "port":
+					value = fmt.Sprintf("%d", refService.Port)
+				default:
+					log.Printf("Unknown service property '%s' for service '%s'", envVar.FromService.Property, envVar.FromService.Name)
+					continue
+				}
+
+				envVars = append(envVars, EnvironmentVariable{
+					Key:      envVar.Key,
+					Value:    value,
+					IsSecret: envVar.IsSecret,
+				})
+			} else {
+				log.Printf("Service '%s' not found in created services", envVar.FromService.Name)
+			}
+		}
+	}
+	return envVars
+}
+
+// envVarLimitError is returned when a template gives a service more environment variables
+// than the environment variables API allows
+type envVarLimitError struct {
+	service string
+	err     error
+}
+
+func (e *envVarLimitError) Error() string {
+	return fmt.Sprintf("Service '%s': %v", e.service, e.err)
+}
+
+// checkEnvVarLimits checks the environment variables of a service, as they are stored, against
+// the configured limits
+func checkEnvVarLimits(service string, envVars []EnvironmentVariable) error {
+	cryptoEnvVars := make([]crypto.EnvironmentVariable, len(envVars))
+	for i, v := range envVars {
+		cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
+	}
+	if err := servicestatus.CheckEnvVarLimits(cryptoEnvVars); err != nil {
+		return &envVarLimitError{service: service, err: err}
+	}
+	return nil
+}
+
+// encryptEnvVars encrypts env vars for the environmentVariables column
+func encryptEnvVars(envVars []EnvironmentVariable) ([]byte, error) {
+	// Convert to crypto type for encryption
+	cryptoEnvVars := make([]crypto.EnvironmentVariable, len(envVars))
+	for i, v := range envVars {
+		cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
+	}
+	encryptedEnvVars, err := crypto.EncryptEnvVars(cryptoEnvVars)
+	if err != nil {
+		log.Printf("Error encrypting environment variables: %v", err)
+		return nil, fmt.Errorf("failed to encrypt environment variables: %w", err)
+	}
+	// Convert back for storage
+	storageEnvVars := make([]EnvironmentVariable, len(encryptedEnvVars))
+	for i, v := range encryptedEnvVars {
+		storageEnvVars[i] = EnvironmentVariable{Key: v.Key, Value: v.Value, IsSecret: v.IsSecret}
+	}
+	return json.Marshal(storageEnvVars)
+}
+
+func createDatabaseFromTemplate(db *gorm.DB, template DatabaseTemplate, projectID string, serviceRegion *string) (*CreatedServiceInfo, fiber.Map, error) {
 	storageCapacity := template.StorageCapacity
 	if storageCapacity < 10 {
 		storageCapacity = 10
@@ -381,6 +503,7 @@ func createDatabaseFromTemplate(db *gorm.DB, template DatabaseTemplate, projectI
 		Name:            template.Name,
 		ServiceTypeID:   template.Type,
 		ProjectID:       projectID,
+		Region:          serviceRegion,
 		Runtime:         models.RuntimeImage,
 		InstanceTypeID:  strings.ToLower(strings.TrimSpace(template.Plan)),
 		StorageCapacity: &storageCapacity,
@@ -457,13 +580,14 @@ func createDatabaseFromTemplate(db *gorm.DB, template DatabaseTemplate, projectI
 	}, serviceResponse, nil
 }
 
-func createMemoryFromTemplate(db *gorm.DB, template MemoryTemplate, projectID string) (*CreatedServiceInfo, fiber.Map, error) {
+func createMemoryFromTemplate(db *gorm.DB, template MemoryTemplate, projectID string, serviceRegion *string) (*CreatedServiceInfo, fiber.Map, error) {
 	// Create service
 	service := models.Service{
 		ID:             utils.GenerateShortID(),
 		Name:           template.Name,
 		ServiceTypeID:  template.Type,
 		ProjectID:      projectID,
+		Region:         serviceRegion,
 		Runtime:        models.RuntimeImage,
 		InstanceTypeID: strings.ToLower(strings.TrimSpace(template.Plan)),
 	}
@@ -0,0 +1,102 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	os.Setenv("ENCRYPTION_KEY", "0123456789abcdef0123456789abcdef")
+	os.Setenv("ENV_VARS_MAX_COUNT", "3")
+	config.Load()
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+func TestResolveEnvVarsKeepsIsSecret(t *testing.T) {
+	envVars := resolveEnvVars([]EnvVarTemplate{
+		{Key: "LOG_LEVEL", Value: "info", IsSecret: utils.Ptr(false)},
+		{Key: "API_KEY", GenerateValue: true},
+	}, nil, nil)
+
+	if len(envVars) != 2 {
+		t.Fatalf("resolveEnvVars returned %d env vars, want 2", len(envVars))
+	}
+	if envVars[0].IsSecret == nil || *envVars[0].IsSecret {
+		t.Errorf("LOG_LEVEL IsSecret = %v, want false", envVars[0].IsSecret)
+	}
+	if envVars[1].IsSecret != nil {
+		t.Errorf("API_KEY IsSecret = %v, want nil", *envVars[1].IsSecret)
+	}
+}
+
+func TestEncryptEnvVarsKeepsIsSecret(t *testing.T) {
+	data, err := encryptEnvVars([]EnvironmentVariable{
+		{Key: "LOG_LEVEL", Value: "info", IsSecret: utils.Ptr(false)},
+		{Key: "API_KEY", Value: "secret"},
+	})
+	if err != nil {
+		t.Fatalf("encryptEnvVars: %v", err)
+	}
+
+	var stored []EnvironmentVariable
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("unmarshal stored env vars: %v", err)
+	}
+	if stored[0].IsSecret == nil || *stored[0].IsSecret || stored[0].Value != "info" {
+		t.Errorf("LOG_LEVEL stored as %+v, want plain config", stored[0])
+	}
+	if stored[1].IsSecret != nil {
+		t.Errorf("API_KEY stored with IsSecret %v, want nil", *stored[1].IsSecret)
+	}
+	if stored[1].Value == "secret" || crypto.DecryptWithFallback(stored[1].Value) != "secret" {
+		t.Errorf("API_KEY stored as %q, want it encrypted", stored[1].Value)
+	}
+}
+
+func TestCheckEnvVarLimits(t *testing.T) {
+	envVars := []EnvironmentVariable{{Key: "A", Value: "1"}, {Key: "B", Value: "2"}, {Key: "C", Value: "3"}}
+	if err := checkEnvVarLimits("web", envVars); err != nil {
+		t.Fatalf("3 env vars: %v", err)
+	}
+
+	err := checkEnvVarLimits("web", append(envVars, EnvironmentVariable{Key: "D", Value: "4"}))
+	var limitErr *envVarLimitError
+	if !errors.As(err, &limitErr) || !strings.Contains(err.Error(), "Service 'web'") {
+		t.Fatalf("4 env vars: error = %v, want a limit error naming the service", err)
+	}
+}
+
+func TestApplyServiceTemplateChecksEnvVarLimits(t *testing.T) {
+	service := models.Service{Name: "web", InstanceTypeID: "small"}
+	template := ServiceTemplate{Name: "web", Plan: "small", EnvVars: []EnvVarTemplate{
+		{Key: "A", Value: "1"}, {Key: "B", Value: "2"}, {Key: "C", Value: "3"}, {Key: "D", Value: "4"},
+	}}
+
+	// Rejected before anything is written
+	_, _, err := applyServiceTemplate(nil, service, template, nil)
+	var limitErr *envVarLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("applyServiceTemplate error = %v, want a limit error", err)
+	}
+}
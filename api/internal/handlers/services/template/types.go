@@ -6,8 +6,17 @@ import "github.com/deployra/deployra/api/internal/models"
 type CreateFromTemplateRequest struct {
 	ProjectID    string `json:"projectId"`
 	YamlTemplate string `json:"yamlTemplate"`
+	// Mode is create, the default, or apply to update the services of the project that
+	// match the template by name instead of creating duplicates
+	Mode string `json:"mode"`
 }
 
+// Template modes
+const (
+	ModeCreate = "create"
+	ModeApply  = "apply"
+)
+
 // Parsed template types
 type ParsedTemplate struct {
 	Services  []ServiceTemplate  `yaml:"services"`
@@ -26,6 +35,7 @@ type ServiceTemplate struct {
 	Ports           []PortConfig     `yaml:"ports"`
 	StorageCapacity *int             `yaml:"storageCapacity"`
 	Command         []string         `yaml:"command"`
+	Args            []string         `yaml:"args"`
 }
 
 type DatabaseTemplate struct {
@@ -52,6 +62,7 @@ type EnvVarTemplate struct {
 	GenerateValue bool                `yaml:"generateValue"`
 	FromDatabase  *FromDatabaseConfig `yaml:"fromDatabase"`
 	FromService   *FromServiceConfig  `yaml:"fromService"`
+	IsSecret      *bool               `yaml:"isSecret"`
 }
 
 type FromServiceConfig struct {
@@ -71,8 +82,9 @@ type PortConfig struct {
 
 // EnvironmentVariable represents an environment variable
 type EnvironmentVariable struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	IsSecret *bool  `json:"isSecret,omitempty"`
 }
 
 // CreatedServiceInfo holds created service info with credentials for env var resolution
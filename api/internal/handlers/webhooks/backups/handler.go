@@ -0,0 +1,65 @@
+package backups
+
+import (
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// BackupStatusUpdateRequest represents the backup status update request
+type BackupStatusUpdateRequest struct {
+	Status    string  `json:"status"`
+	SizeBytes *int64  `json:"sizeBytes,omitempty"`
+	Error     *string `json:"error,omitempty"`
+}
+
+// POST /api/webhooks/backups/:backupId/status
+func UpdateStatus(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	backupID := c.Params("backupId")
+	if backupID == "" {
+		return response.BadRequest(c, "Backup ID is required")
+	}
+
+	var req BackupStatusUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	status := models.BackupStatus(req.Status)
+	switch status {
+	case models.BackupStatusInProgress, models.BackupStatusCompleted, models.BackupStatusFailed:
+	default:
+		return response.BadRequest(c, "Invalid status")
+	}
+
+	var backup models.ServiceBackup
+	if err := db.Where("id = ?", backupID).First(&backup).Error; err != nil {
+		return response.NotFound(c, "Backup not found")
+	}
+
+	updates := map[string]interface{}{
+		"status": status,
+	}
+	if req.SizeBytes != nil {
+		updates["sizeBytes"] = *req.SizeBytes
+	}
+	if req.Error != nil {
+		updates["error"] = *req.Error
+	}
+	if status == models.BackupStatusCompleted || status == models.BackupStatusFailed {
+		updates["completedAt"] = time.Now()
+	}
+
+	if err := db.Model(&backup).Updates(updates).Error; err != nil {
+		return response.InternalServerError(c, "Failed to update backup status")
+	}
+
+	return response.Success(c, fiber.Map{
+		"message": "Backup status updated",
+	})
+}
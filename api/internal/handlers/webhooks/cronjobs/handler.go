@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/deployra/deployra/api/internal/cron"
 	"github.com/deployra/deployra/api/internal/crypto"
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
@@ -28,6 +29,7 @@ type CronJobResponse struct {
 	ID               string                 `json:"id"`
 	Name             string                 `json:"name"`
 	Schedule         string                 `json:"schedule"`
+	Timezone         string                 `json:"timezone"`
 	Path             string                 `json:"path"`
 	Headers          map[string]string      `json:"headers,omitempty"`
 	Enabled          bool                   `json:"enabled"`
@@ -64,13 +66,10 @@ func List(c *fiber.Ctx) error {
 			Value string `json:"value"`
 		}
 		if job.Service.EnvironmentVariables != nil {
-			job.Service.EnvironmentVariables.UnmarshalTo(&envVars)
-			// Decrypt environment variables
-			cryptoEnvVars := make([]crypto.EnvironmentVariable, len(envVars))
-			for i, v := range envVars {
-				cryptoEnvVars[i] = crypto.EnvironmentVariable{Key: v.Key, Value: v.Value}
-			}
-			decryptedEnvVars, _ := crypto.DecryptEnvVars(cryptoEnvVars)
+			// Decrypt the secret environment variables
+			var storedEnvVars []crypto.EnvironmentVariable
+			job.Service.EnvironmentVariables.UnmarshalTo(&storedEnvVars)
+			decryptedEnvVars, _ := crypto.DecryptEnvVars(storedEnvVars)
 			envVars = make([]struct {
 				Key   string `json:"key"`
 				Value string `json:"value"`
@@ -96,6 +95,7 @@ func List(c *fiber.Ctx) error {
 			ID:               job.ID,
 			Name:             job.Name,
 			Schedule:         job.Schedule,
+			Timezone:         job.Timezone,
 			Path:             job.Path,
 			Headers:          headers,
 			Enabled:          job.Enabled,
@@ -152,6 +152,12 @@ func UpdateStatus(c *fiber.Ctx) error {
 		return response.NotFound(c, "CronJob not found")
 	}
 
+	// The next run follows from the schedule, it is only taken from the executor when the
+	// schedule cannot be parsed here
+	if next := cron.NextRun(cronJob.Schedule, cronJob.Timezone, time.Now()); next != nil {
+		nextRunAt = next
+	}
+
 	// Update the CronJob status
 	updates := map[string]interface{}{
 		"lastRunAt": lastRunAt,
@@ -1,21 +1,19 @@
 package deployments
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"log"
-	"net/http"
 	"time"
 
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/deploy"
 	"github.com/deployra/deployra/api/internal/models"
 	"github.com/deployra/deployra/api/internal/redis"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
 	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/internal/webhook"
 	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 )
 
 // LogEntry represents a log entry
@@ -74,9 +72,10 @@ func UpdateStatus(c *fiber.Ctx) error {
 		"updatedAt": time.Now(),
 	}
 
-	// If builded received, set DEPLOYING
+	// If builded received, set DEPLOYING and record when the build finished
 	if req.Status == string(models.DeploymentStatusBuilded) {
 		updates["status"] = models.DeploymentStatusDeploying
+		updates["builtAt"] = time.Now()
 	}
 
 	// Set completedAt if deployment is completed or failed
@@ -92,6 +91,11 @@ func UpdateStatus(c *fiber.Ctx) error {
 		return response.InternalServerError(c, "Failed to update deployment status")
 	}
 
+	// A build leaving the builder frees a slot for the organization's held builds
+	if deploy.HoldsBuildSlot(deployment.Status) && !deploy.HoldsBuildSlot(models.DeploymentStatus(req.Status)) {
+		go deploy.StartDeferredBuild(deployment.Service.Project.OrganizationID)
+	}
+
 	// Create log entry if logs are provided
 	if req.Logs.Text != "" {
 		logType := req.Logs.Type
@@ -143,17 +147,19 @@ func UpdateStatus(c *fiber.Ctx) error {
 		sendDeploymentWebhook(deployment, req.Status, "Service is deploying", deploymentID)
 
 	case string(models.DeploymentStatusDeploying):
-		db.Model(&models.Service{}).Where("id = ?", deployment.ServiceID).Updates(map[string]interface{}{
-			"status":     models.ServiceStatusDeploying,
+		if err := servicestatus.SetStatus(deployment.ServiceID, models.ServiceStatusDeploying, map[string]interface{}{
 			"deployedAt": time.Now(),
-		})
+		}); err != nil {
+			log.Printf("Error updating status of service %s: %v", deployment.ServiceID, err)
+		}
 		sendDeploymentWebhook(deployment, req.Status, "Service is deploying", deploymentID)
 
 	case string(models.DeploymentStatusDeployed):
-		db.Model(&models.Service{}).Where("id = ?", deployment.ServiceID).Updates(map[string]interface{}{
-			"status":     models.ServiceStatusRunning,
+		if err := servicestatus.SetStatus(deployment.ServiceID, models.ServiceStatusRunning, map[string]interface{}{
 			"deployedAt": time.Now(),
-		})
+		}); err != nil {
+			log.Printf("Error updating status of service %s: %v", deployment.ServiceID, err)
+		}
 
 		// Create deployment completed event
 		db.Create(&models.ServiceEvent{
@@ -241,6 +247,13 @@ func UpdateLogs(c *fiber.Ctx) error {
 		return response.InternalServerError(c, "Failed to create log entry")
 	}
 
+	// Drop the oldest lines once the deployment's logs outgrow the size cap
+	if trimmed, err := deploy.CapLogs(c.UserContext(), deploymentID, len(newLog.Text)); err != nil {
+		log.Printf("Failed to apply log size cap to deployment %s: %v", deploymentID, err)
+	} else if trimmed > 0 {
+		log.Printf("Dropped %d oldest log lines of deployment %s over the size cap", trimmed, deploymentID)
+	}
+
 	// Publish to Socket.IO via Redis
 	publishDeploymentLog(deploymentID, newLog)
 
@@ -251,75 +264,7 @@ func UpdateLogs(c *fiber.Ctx) error {
 
 // sendDeploymentWebhook sends a webhook notification for deployment status changes
 func sendDeploymentWebhook(deployment models.Deployment, status, message, deploymentID string) {
-	// Check if project has a deployment webhook configured
-	if deployment.Service.Project.WebhookUrl == nil || *deployment.Service.Project.WebhookUrl == "" {
-		return
-	}
-
-	webhookPayload := map[string]interface{}{
-		"event":     "deployment_status_changed",
-		"timestamp": time.Now().Format(time.RFC3339),
-		"deployment": map[string]interface{}{
-			"id":          deployment.ID,
-			"status":      status,
-			"message":     message,
-			"createdAt":   deployment.CreatedAt,
-			"completedAt": deployment.CompletedAt,
-		},
-		"service": map[string]interface{}{
-			"id":   deployment.Service.ID,
-			"name": deployment.Service.Name,
-			"type": deployment.Service.ServiceTypeID,
-		},
-		"project": map[string]interface{}{
-			"id":   deployment.Service.Project.ID,
-			"name": deployment.Service.Project.Name,
-		},
-		"organization": map[string]interface{}{
-			"id":   deployment.Service.Project.Organization.ID,
-			"name": deployment.Service.Project.Organization.Name,
-		},
-	}
-
-	go func() {
-		client := &http.Client{Timeout: 10 * time.Second}
-
-		// Generate a random delivery ID
-		deliveryID := uuid.New().String()
-
-		req, err := http.NewRequest("POST", *deployment.Service.Project.WebhookUrl, nil)
-		if err != nil {
-			log.Printf("Failed to create webhook request: %v", err)
-			return
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("User-Agent", "Deployra-Webhook/1.0")
-		req.Header.Set("X-Deployra-Event", "deployment_status_changed")
-		req.Header.Set("X-Deployra-Delivery", deliveryID)
-
-		// Set body
-		body, _ := json.Marshal(webhookPayload)
-		req, err = http.NewRequest("POST", *deployment.Service.Project.WebhookUrl, bytes.NewBuffer(body))
-		if err != nil {
-			log.Printf("Failed to create webhook request: %v", err)
-			return
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("User-Agent", "Deployra-Webhook/1.0")
-		req.Header.Set("X-Deployra-Event", "deployment_status_changed")
-		req.Header.Set("X-Deployra-Delivery", deliveryID)
-
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Printf("Failed to send deployment webhook: %v", err)
-			return
-		}
-		defer resp.Body.Close()
-
-		log.Printf("Webhook sent to %s - Status: %d", *deployment.Service.Project.WebhookUrl, resp.StatusCode)
-	}()
+	webhook.SendDeploymentStatus(deployment, status, message)
 }
 
 // publishDeploymentLog publishes a deployment log to Socket.IO via Redis
@@ -6,6 +6,7 @@ import (
 
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
 	ecrpkg "github.com/deployra/deployra/api/pkg/ecr"
 	"github.com/deployra/deployra/api/pkg/kubernetes"
 	"github.com/deployra/deployra/api/pkg/response"
@@ -43,11 +44,13 @@ func Handle(c *fiber.Ctx) error {
 		// Format: https://YOUR_AWS_ACCOUNT_ID.dkr.ecr.us-east-1.amazonaws.com
 		registryURL := strings.TrimPrefix(ecrDetails.ProxyEndpoint, "https://")
 
-		// Update the system-wide ECR secret
-		if err := kubernetes.CreateECRSecret("ecr-credentials", "system-apps", registryURL, ecrDetails.Token); err != nil {
-			log.Printf("Error renewing system-wide ECR token: %v", err)
-		} else {
-			systemSecretUpdated = true
+		// Update the system-wide ECR secret of every cluster
+		systemSecretUpdated = true
+		for _, name := range region.All() {
+			if err := kubernetes.CreateECRSecret(c.UserContext(), name, "ecr-credentials", "system-apps", registryURL, ecrDetails.Token); err != nil {
+				log.Printf("Error renewing system-wide ECR token: %v", err)
+				systemSecretUpdated = false
+			}
 		}
 	}
 
@@ -93,7 +96,7 @@ func Handle(c *fiber.Ctx) error {
 		// Create or update the secret
 		secretName := service.ID + "-container-registry-secret"
 
-		if err := kubernetes.CreateECRSecret(secretName, namespace, registryURL, ecrToken.Token); err != nil {
+		if err := kubernetes.CreateECRSecret(c.UserContext(), region.Of(service), secretName, namespace, registryURL, ecrToken.Token); err != nil {
 			log.Printf("Error creating ECR secret for service %s: %v", service.ID, err)
 			errors = append(errors, ServiceError{
 				ServiceID: service.ID,
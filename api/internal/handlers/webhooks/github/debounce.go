@@ -0,0 +1,144 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+// pushBuild starts the build of a push, tests replace it to observe builds without a database
+var pushBuild = buildPush
+
+const (
+	// Pending pushes by the key of their service and branch, scored by when they are due
+	pendingPushesKey = "webhook:push:pending"
+
+	// How often due pushes are claimed and built
+	pushPollInterval = time.Second
+
+	// A pending push no instance claimed, e.g. because none ran, is dropped after a day
+	pendingPushTTL = 24 * time.Hour
+)
+
+// pendingPush is a push waiting for the debounce window of its service and branch to pass
+type pendingPush struct {
+	ServiceID string `json:"serviceId"`
+	CommitSha string `json:"commitSha"`
+}
+
+// schedulePushBuild records a push for a service and builds it once no newer push has
+// arrived within the debounce window, so rapid pushes or redeliveries produce a single build.
+// The push is kept in Redis until StartPushBuilds builds it, so it survives a restart.
+func schedulePushBuild(service models.Service, branch string, commitSha string) error {
+	window := time.Duration(config.Get().GitHubPushDebounce) * time.Second
+	if window <= 0 {
+		return pushBuild(service, commitSha)
+	}
+
+	value, err := json.Marshal(pendingPush{ServiceID: service.ID, CommitSha: commitSha})
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("webhook:push:%s:%s", service.ID, branch)
+	return redis.ScheduleDebounce(context.Background(), pendingPushesKey, key, string(value), time.Now().Add(window), pendingPushTTL)
+}
+
+// StartPushBuilds builds debounced pushes once they are due, every pushPollInterval until
+// ctx is cancelled. Pushes that became due while no instance ran are built on the first pass.
+func StartPushBuilds(ctx context.Context) {
+	ticker := time.NewTicker(pushPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := buildDuePushes(ctx, time.Now()); err != nil {
+			log.Printf("Failed to build due pushes: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// buildDuePushes claims the pushes due by now and builds them, the latest push of every
+// service and branch only
+func buildDuePushes(ctx context.Context, now time.Time) error {
+	values, err := redis.ClaimDueDebounces(ctx, pendingPushesKey, now)
+	if err != nil {
+		return err
+	}
+
+	db := database.GetDatabase().WithContext(ctx)
+	for _, value := range values {
+		var push pendingPush
+		if err := json.Unmarshal([]byte(value), &push); err != nil {
+			log.Printf("Invalid pending push %q: %v", value, err)
+			continue
+		}
+
+		// The service may have been deleted while the push was pending
+		var service models.Service
+		if err := db.Where("id = ? AND deletedAt IS NULL", push.ServiceID).First(&service).Error; err != nil {
+			log.Printf("Service %s of push %s not found, skipping: %v", push.ServiceID, push.CommitSha, err)
+			continue
+		}
+
+		if err := pushBuild(service, push.CommitSha); err != nil {
+			log.Printf("Error triggering build for service %s: %v", service.ID, err)
+		}
+	}
+	return nil
+}
+
+// buildPush cancels any active deployment of the service and starts a build for the commit
+func buildPush(service models.Service, commitSha string) error {
+	db := database.GetDatabase()
+	ctx := context.Background()
+
+	// Check if there's already a deployment in progress
+	var activeDeployment models.Deployment
+	err := db.Where("serviceId = ? AND status IN ?", service.ID,
+		[]string{string(models.DeploymentStatusPending), string(models.DeploymentStatusBuilding), string(models.DeploymentStatusDeploying)}).
+		First(&activeDeployment).Error
+
+	if err == nil {
+		// A redelivered push for the commit that is already being deployed needs no new build
+		if activeDeployment.CommitSha != nil && *activeDeployment.CommitSha == commitSha {
+			log.Printf("Deployment %s already in progress for commit %s, skipping", activeDeployment.ID, commitSha)
+			return nil
+		}
+
+		// Cancel the superseded deployment
+		log.Printf("Cancelling existing deployment %s for service %s", activeDeployment.ID, service.Name)
+		if _, err := deploy.CancelDeployment(ctx, activeDeployment.ID); err != nil {
+			return err
+		}
+
+		// Create a service event for the cancelled deployment
+		db.Create(&models.ServiceEvent{
+			ServiceID:    service.ID,
+			Type:         models.EventTypeDeployCancelled,
+			DeploymentID: &activeDeployment.ID,
+			Message:      utils.Ptr("Cancelled by new webhook trigger"),
+		})
+	}
+
+	// Start a new build
+	if _, err := deploy.BuildService(service.ID, "", "webhook", commitSha); err != nil {
+		return err
+	}
+
+	log.Printf("Triggered build for service %s (%s)", service.Name, service.ID)
+	return nil
+}
@@ -0,0 +1,190 @@
+package github
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+)
+
+// redisServer backs the redis client of the tests
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	os.Setenv("GITHUB_PUSH_DEBOUNCE_SECONDS", "1")
+	config.Load()
+
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+// recordBuilds replaces pushBuild for the test and returns the commits built so far
+func recordBuilds(t *testing.T) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var built []string
+	pushBuild = func(service models.Service, commitSha string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		built = append(built, service.ID+"@"+commitSha)
+		return nil
+	}
+	t.Cleanup(func() { pushBuild = buildPush })
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), built...)
+	}
+}
+
+// serviceRows makes the services looked up by ID exist, except the deleted ones. Pending
+// pushes of earlier tests are dropped.
+func serviceRows(t *testing.T, deleted ...string) *dbtest.DB {
+	t.Helper()
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	db.OnQueryFunc("FROM `Service`", func(args []driver.Value) []dbtest.Row {
+		id := fmt.Sprint(args[0])
+		for _, d := range deleted {
+			if id == d {
+				return nil
+			}
+		}
+		return []dbtest.Row{{"id": id, "name": "web"}}
+	})
+	return db
+}
+
+func TestQuickPushesBuildTheNewerCommit(t *testing.T) {
+	builds := recordBuilds(t)
+	serviceRows(t)
+	service := models.Service{ID: "svc1", Name: "web"}
+
+	if err := schedulePushBuild(service, "main", "old"); err != nil {
+		t.Fatalf("schedulePushBuild old: %v", err)
+	}
+	if err := schedulePushBuild(service, "main", "new"); err != nil {
+		t.Fatalf("schedulePushBuild new: %v", err)
+	}
+	if err := buildDuePushes(context.Background(), time.Now()); err != nil {
+		t.Fatalf("buildDuePushes: %v", err)
+	}
+	if got := builds(); len(got) != 0 {
+		t.Fatalf("built %v before the debounce window passed", got)
+	}
+
+	if err := buildDuePushes(context.Background(), time.Now().Add(2*time.Second)); err != nil {
+		t.Fatalf("buildDuePushes: %v", err)
+	}
+	if got := builds(); len(got) != 1 || got[0] != "svc1@new" {
+		t.Fatalf("builds = %v, want only svc1@new", got)
+	}
+}
+
+func TestPushesToOtherBranchesBuildSeparately(t *testing.T) {
+	builds := recordBuilds(t)
+	serviceRows(t)
+	service := models.Service{ID: "svc2", Name: "web"}
+
+	if err := schedulePushBuild(service, "main", "a"); err != nil {
+		t.Fatalf("schedulePushBuild main: %v", err)
+	}
+	if err := schedulePushBuild(service, "develop", "b"); err != nil {
+		t.Fatalf("schedulePushBuild develop: %v", err)
+	}
+
+	if err := buildDuePushes(context.Background(), time.Now().Add(2*time.Second)); err != nil {
+		t.Fatalf("buildDuePushes: %v", err)
+	}
+	if got := builds(); len(got) != 2 {
+		t.Fatalf("builds = %v, want one per branch", got)
+	}
+}
+
+func TestDuePushIsBuiltOnce(t *testing.T) {
+	builds := recordBuilds(t)
+	serviceRows(t)
+
+	if err := schedulePushBuild(models.Service{ID: "svc4"}, "main", "abc"); err != nil {
+		t.Fatalf("schedulePushBuild: %v", err)
+	}
+
+	// Every instance polls, only the first to claim the push builds it
+	due := time.Now().Add(2 * time.Second)
+	for i := 0; i < 2; i++ {
+		if err := buildDuePushes(context.Background(), due); err != nil {
+			t.Fatalf("buildDuePushes: %v", err)
+		}
+	}
+	if got := builds(); len(got) != 1 || got[0] != "svc4@abc" {
+		t.Fatalf("builds = %v, want svc4@abc once", got)
+	}
+}
+
+func TestPendingPushIsBuiltAfterRestart(t *testing.T) {
+	builds := recordBuilds(t)
+	db := serviceRows(t)
+
+	// The push was received by an instance that stopped before it was due
+	if err := schedulePushBuild(models.Service{ID: "svc5"}, "main", "abc"); err != nil {
+		t.Fatalf("schedulePushBuild: %v", err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		StartPushBuilds(ctx)
+	}()
+	deadline := time.Now().Add(3 * time.Second)
+	for len(builds()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if got := builds(); len(got) != 1 || got[0] != "svc5@abc" {
+		t.Errorf("builds = %v, want svc5@abc", got)
+	}
+	// The service is reloaded, it may have changed while the push was pending
+	if finds := db.Statements("FROM `Service`"); len(finds) != 1 || fmt.Sprint(finds[0].Args[0]) != "svc5" {
+		t.Errorf("service queries = %v, want svc5 looked up", finds)
+	}
+}
+
+func TestPendingPushOfDeletedServiceIsDropped(t *testing.T) {
+	builds := recordBuilds(t)
+	serviceRows(t, "svc6")
+
+	if err := schedulePushBuild(models.Service{ID: "svc6"}, "main", "abc"); err != nil {
+		t.Fatalf("schedulePushBuild: %v", err)
+	}
+	if err := buildDuePushes(context.Background(), time.Now().Add(2*time.Second)); err != nil {
+		t.Fatalf("buildDuePushes: %v", err)
+	}
+	if got := builds(); len(got) != 0 {
+		t.Errorf("builds = %v, want none for the deleted service", got)
+	}
+	if n, _ := redisServer.ZMembers(pendingPushesKey); len(n) != 0 {
+		t.Errorf("pending pushes = %v, want the push dropped", n)
+	}
+}
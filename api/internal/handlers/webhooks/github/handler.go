@@ -12,7 +12,6 @@ import (
 	"strings"
 
 	"github.com/deployra/deployra/api/internal/database"
-	"github.com/deployra/deployra/api/internal/deploy"
 	"github.com/deployra/deployra/api/internal/models"
 	"github.com/deployra/deployra/api/internal/utils"
 	"github.com/deployra/deployra/api/pkg/response"
@@ -31,10 +30,47 @@ type InstallationPayload struct {
 // PushPayload represents the push event payload
 type PushPayload struct {
 	Ref        string `json:"ref"`
+	Before     string `json:"before"`
 	After      string `json:"after"`
 	Repository struct {
 		FullName string `json:"full_name"`
 	} `json:"repository"`
+	Installation struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+	Commits []PushCommit `json:"commits"`
+}
+
+// PullRequestPayload represents the pull_request event payload
+type PullRequestPayload struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Merged         bool   `json:"merged"`
+		MergeCommitSha string `json:"merge_commit_sha"`
+		Base           struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Installation struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+}
+
+// merged reports whether the event is a pull request being merged, closing one without
+// merging it deploys nothing
+func (p PullRequestPayload) merged() bool {
+	return p.Action == "closed" && p.PullRequest.Merged
+}
+
+// PushCommit represents a commit included in a push event
+type PushCommit struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
 }
 
 // POST /api/webhooks/github
@@ -118,10 +154,19 @@ func Handle(c *fiber.Ctx) error {
 			db.Where("installationId = ?", installationID).Find(&providers)
 
 			if len(providers) > 0 {
+				providerIDs := []string{}
 				for _, provider := range providers {
 					db.Model(&provider).Update("installationId", nil)
+					providerIDs = append(providerIDs, provider.ID)
 				}
 				log.Printf("Marked %d providers as uninstalled for installation %d", len(providers), installationID)
+
+				// Pushes no longer reach the services of a disconnected provider
+				paused := 0
+				if repositories, reason, pause := autoDeployPause(event, payload.Action, nil); pause {
+					paused = pauseAutoDeploys(db, providerIDs, repositories, reason)
+				}
+				log.Printf("Paused auto deploy for %d services of installation %d", paused, installationID)
 			} else {
 				log.Printf("No providers found for installation %d", installationID)
 			}
@@ -132,6 +177,41 @@ func Handle(c *fiber.Ctx) error {
 		}
 	}
 
+	// Handle repositories added to or removed from an installation
+	if event == "installation_repositories" {
+		var payload InstallationRepositoriesPayload
+		if err := json.Unmarshal(rawBody, &payload); err != nil {
+			return response.BadRequest(c, "Invalid payload")
+		}
+
+		installationID := payload.Installation.ID
+		providerIDs := installationProviders(db, installationID)
+		if len(providerIDs) == 0 {
+			log.Printf("No providers found for installation %d", installationID)
+			return response.Success(c, fiber.Map{
+				"message": "Webhook received but no matching providers found",
+			})
+		}
+
+		// Repository lists are fetched from GitHub, only the selection is stored
+		if payload.RepositorySelection != "" {
+			db.Model(&models.GitProvider{}).
+				Where("id IN ?", providerIDs).
+				Update("repositorySelection", payload.RepositorySelection)
+		}
+
+		paused := 0
+		if repositories, reason, pause := autoDeployPause(event, payload.Action, payload.RepositoriesRemoved); pause {
+			paused = pauseAutoDeploys(db, providerIDs, repositories, reason)
+		}
+		log.Printf("Installation %d: %d repositories added, %d removed, paused auto deploy for %d services",
+			installationID, len(payload.RepositoriesAdded), len(payload.RepositoriesRemoved), paused)
+
+		return response.Success(c, fiber.Map{
+			"message": fmt.Sprintf("Paused auto deploy for %d service(s)", paused),
+		})
+	}
+
 	// Handle push events
 	if event == "push" {
 		var payload PushPayload
@@ -167,6 +247,13 @@ func Handle(c *fiber.Ctx) error {
 
 		log.Printf("Found %d services to rebuild for %s:%s", len(services), repositoryName, branch)
 
+		// Changed files are only resolved when a service has a path filter
+		var changedFiles []string
+		changedFilesKnown, changedFilesResolved := false, false
+
+		// Branch protection is only looked up when a service deploys protected branches only
+		branchProtected, branchProtectionResolved := false, false
+
 		// Trigger builds for each service
 		triggeredServices := []fiber.Map{}
 		for _, service := range services {
@@ -175,34 +262,114 @@ func Handle(c *fiber.Ctx) error {
 				continue
 			}
 
-			// Check if there's already a deployment in progress
-			var activeDeployment models.Deployment
-			err := db.Where("serviceId = ? AND status IN ?", service.ID,
-				[]string{string(models.DeploymentStatusPending), string(models.DeploymentStatusBuilding), string(models.DeploymentStatusDeploying)}).
-				First(&activeDeployment).Error
-
-			// Cancel any existing deployments
-			if err == nil {
-				log.Printf("Cancelling existing deployment %s for service %s", activeDeployment.ID, service.Name)
-				db.Model(&activeDeployment).Update("status", models.DeploymentStatusCancelled)
-
-				// Create a service event for the cancelled deployment
-				db.Create(&models.ServiceEvent{
-					ServiceID:    service.ID,
-					Type:         models.EventTypeDeployCancelled,
-					DeploymentID: &activeDeployment.ID,
-					Message:      utils.Ptr("Cancelled by new webhook trigger"),
-				})
+			// Services deploying on merge build from the pull_request event instead
+			if service.DeployOn == models.DeployTriggerMergeToDefault {
+				log.Printf("Service %s only deploys merged pull requests, skipping", service.Name)
+				continue
+			}
+
+			// Skip services that only deploy protected branches, also when protection is unknown
+			if service.DeployOnlyProtectedBranches {
+				if !branchProtectionResolved {
+					branchProtected = isBranchProtected(payload.Installation.ID, repositoryName, branch)
+					branchProtectionResolved = true
+				}
+				if !branchProtected {
+					log.Printf("Branch %s is not protected and service %s only deploys protected branches, skipping", branch, service.Name)
+					continue
+				}
+			}
+
+			// Skip monorepo services whose paths were not touched by the push
+			if service.DeployPathFilter != nil && *service.DeployPathFilter != "" {
+				if !changedFilesResolved {
+					changedFiles, changedFilesKnown = pushChangedFiles(payload)
+					changedFilesResolved = true
+				}
+				if changedFilesKnown && !matchesPathFilter(*service.DeployPathFilter, changedFiles) {
+					log.Printf("No changed files match path filter %q for service %s, skipping", *service.DeployPathFilter, service.Name)
+					continue
+				}
+			}
+
+			// Coalesce rapid pushes so only the latest commit builds
+			if err := schedulePushBuild(service, branch, payload.After); err != nil {
+				log.Printf("Error scheduling build for service %s: %v", service.ID, err)
+				continue
+			}
+
+			triggeredServices = append(triggeredServices, fiber.Map{
+				"id":   service.ID,
+				"name": service.Name,
+			})
+		}
+
+		return response.Success(c, fiber.Map{
+			"message":  fmt.Sprintf("Scheduled builds for %d service(s)", len(triggeredServices)),
+			"services": triggeredServices,
+		})
+	}
+
+	// Handle pull requests merged into the branch of services deploying on merge
+	if event == "pull_request" {
+		var payload PullRequestPayload
+		if err := json.Unmarshal(rawBody, &payload); err != nil {
+			return response.BadRequest(c, "Invalid payload")
+		}
+
+		if !payload.merged() {
+			return response.Success(c, fiber.Map{
+				"message": "Webhook received but pull request was not merged",
+			})
+		}
+
+		repositoryName := payload.Repository.FullName
+		branch := payload.PullRequest.Base.Ref
+		commitSha := payload.PullRequest.MergeCommitSha
+
+		if repositoryName == "" || branch == "" || commitSha == "" {
+			log.Println("Missing repository name, base branch or merge commit in pull request event")
+			return response.Success(c, fiber.Map{
+				"message": "Webhook received but missing required information",
+			})
+		}
+
+		log.Printf("Received merge of pull request #%d for %s into branch %s", payload.Number, repositoryName, branch)
+
+		var services []models.Service
+		db.Preload("Project.Organization").
+			Where("repositoryName = ? AND branch = ? AND runtime = ? AND deployOn = ? AND deletedAt IS NULL",
+				repositoryName, branch, models.RuntimeDocker, models.DeployTriggerMergeToDefault).
+			Find(&services)
+
+		// Branch protection is only looked up when a service deploys protected branches only
+		branchProtected, branchProtectionResolved := false, false
+
+		// The files a pull request changed aren't in the payload, path filters don't apply
+		triggeredServices := []fiber.Map{}
+		for _, service := range services {
+			if !service.AutoDeployEnabled {
+				log.Printf("Service %s has auto deploy disabled, skipping", service.Name)
+				continue
+			}
+
+			if service.DeployOnlyProtectedBranches {
+				if !branchProtectionResolved {
+					branchProtected = isBranchProtected(payload.Installation.ID, repositoryName, branch)
+					branchProtectionResolved = true
+				}
+				if !branchProtected {
+					log.Printf("Branch %s is not protected and service %s only deploys protected branches, skipping", branch, service.Name)
+					continue
+				}
 			}
 
-			// Start a new build
-			_, buildErr := deploy.BuildService(service.ID, "", "webhook", payload.After)
-			if buildErr != nil {
-				log.Printf("Error triggering build for service %s: %v", service.ID, buildErr)
+			// The merge also pushes to the branch, both share the debounce of the branch
+			if err := schedulePushBuild(service, branch, commitSha); err != nil {
+				log.Printf("Error scheduling build for service %s: %v", service.ID, err)
 				continue
 			}
 
-			log.Printf("Triggered build for service %s (%s)", service.Name, service.ID)
 			triggeredServices = append(triggeredServices, fiber.Map{
 				"id":   service.ID,
 				"name": service.Name,
@@ -210,7 +377,7 @@ func Handle(c *fiber.Ctx) error {
 		}
 
 		return response.Success(c, fiber.Map{
-			"message":  fmt.Sprintf("Triggered builds for %d service(s)", len(triggeredServices)),
+			"message":  fmt.Sprintf("Scheduled builds for %d service(s)", len(triggeredServices)),
 			"services": triggeredServices,
 		})
 	}
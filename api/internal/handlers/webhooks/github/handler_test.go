@@ -0,0 +1,105 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPullRequestPayloadMerged(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		merged bool
+	}{
+		{name: "merged", body: `{"action":"closed","pull_request":{"merged":true,"merge_commit_sha":"abc"}}`, merged: true},
+		{name: "closed without merging", body: `{"action":"closed","pull_request":{"merged":false,"merge_commit_sha":"abc"}}`},
+		{name: "opened", body: `{"action":"opened","pull_request":{"merged":false}}`},
+		{name: "edited after merging", body: `{"action":"edited","pull_request":{"merged":true}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload PullRequestPayload
+			if err := json.Unmarshal([]byte(tt.body), &payload); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got := payload.merged(); got != tt.merged {
+				t.Errorf("merged() = %v, want %v", got, tt.merged)
+			}
+		})
+	}
+}
+
+func TestHandleIgnoresPullRequestClosedWithoutMerging(t *testing.T) {
+	app := fiber.New()
+	app.Post("/", Handle)
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{
+		"action": "closed",
+		"number": 7,
+		"pull_request": {"merged": false, "merge_commit_sha": "abc", "base": {"ref": "main"}},
+		"repository": {"full_name": "acme/api"}
+	}`))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+
+	// Nothing is looked up for it, the handler answers before touching the database
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || !strings.Contains(string(body), "not merged") {
+		t.Errorf("response = %d %s, want the pull request ignored as not merged", resp.StatusCode, body)
+	}
+}
+
+func TestHandleMergedPullRequestBuildsMergeCommit(t *testing.T) {
+	builds := recordBuilds(t)
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{
+		"id": "svc3", "name": "api", "projectId": "proj1", "repositoryName": "acme/api", "branch": "main",
+		"deployOn": "mergeToDefault", "autoDeployEnabled": true,
+	})
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1"})
+
+	app := fiber.New()
+	app.Post("/", Handle)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{
+		"action": "closed",
+		"number": 7,
+		"pull_request": {"merged": true, "merge_commit_sha": "merge7", "head": {"sha": "head7"}, "base": {"ref": "main"}},
+		"repository": {"full_name": "acme/api"}
+	}`))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || !strings.Contains(string(body), `"id":"svc3"`) {
+		t.Fatalf("response = %d %s, want a build scheduled for svc3", resp.StatusCode, body)
+	}
+
+	// Only services deploying on merges into the base branch are looked up
+	finds := db.Statements("FROM `Service`")
+	if len(finds) != 1 || fmt.Sprint(finds[0].Args) != "[acme/api main DOCKER mergeToDefault]" {
+		t.Errorf("service queries = %v, want the mergeToDefault services of acme/api on main", finds)
+	}
+
+	// The merge commit is built once the debounce window of the branch passed
+	if err := buildDuePushes(context.Background(), time.Now().Add(2*time.Second)); err != nil {
+		t.Fatalf("buildDuePushes: %v", err)
+	}
+	if got := builds(); len(got) != 1 || got[0] != "svc3@merge7" {
+		t.Errorf("builds = %v, want svc3@merge7", got)
+	}
+}
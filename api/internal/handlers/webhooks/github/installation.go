@@ -0,0 +1,99 @@
+package github
+
+import (
+	"log"
+
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+	"gorm.io/gorm"
+)
+
+// InstallationRepositoriesPayload represents the installation_repositories webhook payload
+type InstallationRepositoriesPayload struct {
+	Action       string `json:"action"`
+	Installation struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+	RepositorySelection string                   `json:"repository_selection"`
+	RepositoriesAdded   []InstallationRepository `json:"repositories_added"`
+	RepositoriesRemoved []InstallationRepository `json:"repositories_removed"`
+}
+
+// InstallationRepository represents a repository added to or removed from an installation
+type InstallationRepository struct {
+	FullName string `json:"full_name"`
+}
+
+// installationProviders returns the IDs of the Git providers connected to an installation
+func installationProviders(db *gorm.DB, installationID int64) []string {
+	var providerIDs []string
+	db.Model(&models.GitProvider{}).
+		Where("installationId = ? AND deletedAt IS NULL", installationID).
+		Pluck("id", &providerIDs)
+	return providerIDs
+}
+
+// pauseAutoDeploys turns off auto deploy for the services building from the given providers,
+// only those building one of repositoryNames when it isn't nil, and returns how many it paused
+func pauseAutoDeploys(db *gorm.DB, providerIDs []string, repositoryNames []string, reason string) int {
+	if len(providerIDs) == 0 {
+		return 0
+	}
+
+	query := db.Where("gitProviderId IN ? AND autoDeployEnabled = ? AND deletedAt IS NULL", providerIDs, true)
+	if repositoryNames != nil {
+		if len(repositoryNames) == 0 {
+			return 0
+		}
+		query = query.Where("repositoryName IN ?", repositoryNames)
+	}
+
+	var services []models.Service
+	if err := query.Find(&services).Error; err != nil {
+		log.Printf("Error finding services to pause auto deploy for: %v", err)
+		return 0
+	}
+
+	paused := 0
+	for _, service := range services {
+		if err := db.Model(&models.Service{}).Where("id = ?", service.ID).Update("autoDeployEnabled", false).Error; err != nil {
+			log.Printf("Error pausing auto deploy for service %s: %v", service.ID, err)
+			continue
+		}
+		db.Create(&models.ServiceEvent{
+			ServiceID: service.ID,
+			Type:      models.EventTypeConfigUpdated,
+			Message:   utils.Ptr("Auto deploy paused: " + reason),
+		})
+		paused++
+	}
+	return paused
+}
+
+// autoDeployPause returns which services stop auto deploying after an installation event: all
+// of the installation's (nil repositories) when the app is uninstalled, those building a removed
+// repository when repositories are taken out of it. pause is false when the event pauses nothing.
+func autoDeployPause(event, action string, removed []InstallationRepository) (repositories []string, reason string, pause bool) {
+	switch event {
+	case "installation":
+		if action == "deleted" || action == "removed" {
+			return nil, "the GitHub App was uninstalled", true
+		}
+	case "installation_repositories":
+		if names := repositoryNames(removed); len(names) > 0 {
+			return names, "the repository was removed from the GitHub App installation", true
+		}
+	}
+	return nil, "", false
+}
+
+// repositoryNames returns the full names of installation repositories
+func repositoryNames(repositories []InstallationRepository) []string {
+	names := []string{}
+	for _, repository := range repositories {
+		if repository.FullName != "" {
+			names = append(names, repository.FullName)
+		}
+	}
+	return names
+}
@@ -0,0 +1,67 @@
+package github
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAutoDeployPause(t *testing.T) {
+	removed := []InstallationRepository{{FullName: "acme/api"}, {FullName: ""}, {FullName: "acme/web"}}
+
+	tests := []struct {
+		name         string
+		event        string
+		action       string
+		removed      []InstallationRepository
+		repositories []string
+		pause        bool
+	}{
+		{name: "app installed", event: "installation", action: "created"},
+		{name: "installation added", event: "installation", action: "added"},
+		{name: "app suspended", event: "installation", action: "suspend"},
+		{name: "app uninstalled pauses every service", event: "installation", action: "deleted", pause: true},
+		{name: "installation removed pauses every service", event: "installation", action: "removed", pause: true},
+		{name: "repositories added", event: "installation_repositories", action: "added"},
+		{name: "repositories removed pauses their services", event: "installation_repositories", action: "removed", removed: removed, repositories: []string{"acme/api", "acme/web"}, pause: true},
+		{name: "removed repositories without names", event: "installation_repositories", action: "removed", removed: []InstallationRepository{{}}},
+		{name: "other event", event: "push", removed: removed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repositories, reason, pause := autoDeployPause(tt.event, tt.action, tt.removed)
+			if pause != tt.pause {
+				t.Fatalf("pause = %v, want %v", pause, tt.pause)
+			}
+			if !reflect.DeepEqual(repositories, tt.repositories) {
+				t.Errorf("repositories = %#v, want %#v", repositories, tt.repositories)
+			}
+			if pause && reason == "" {
+				t.Error("a pause has no reason to show on the services")
+			}
+		})
+	}
+}
+
+func TestInstallationRepositoriesPayload(t *testing.T) {
+	body := `{
+		"action": "removed",
+		"installation": {"id": 42},
+		"repository_selection": "selected",
+		"repositories_added": [],
+		"repositories_removed": [{"id": 1, "name": "api", "full_name": "acme/api", "private": true}]
+	}`
+
+	var payload InstallationRepositoriesPayload
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if payload.Installation.ID != 42 || payload.RepositorySelection != "selected" {
+		t.Fatalf("payload = %+v, want installation 42 with a selected repository list", payload)
+	}
+
+	repositories, _, pause := autoDeployPause("installation_repositories", payload.Action, payload.RepositoriesRemoved)
+	if !pause || !reflect.DeepEqual(repositories, []string{"acme/api"}) {
+		t.Errorf("pause = %v for %v, want acme/api paused", pause, repositories)
+	}
+}
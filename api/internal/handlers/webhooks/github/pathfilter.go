@@ -0,0 +1,121 @@
+package github
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+
+	githubapi "github.com/deployra/deployra/api/pkg/github"
+)
+
+// GitHub includes at most this many commits in a push payload
+const maxPushPayloadCommits = 20
+
+// pushChangedFiles returns the files touched by a push. The second return value is false
+// when the list could not be determined, in which case path filters should not skip builds.
+func pushChangedFiles(payload PushPayload) ([]string, bool) {
+	seen := make(map[string]bool)
+	files := []string{}
+	add := func(paths []string) {
+		for _, path := range paths {
+			if !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+		}
+	}
+
+	if len(payload.Commits) > 0 && len(payload.Commits) < maxPushPayloadCommits {
+		for _, commit := range payload.Commits {
+			add(commit.Added)
+			add(commit.Removed)
+			add(commit.Modified)
+		}
+		return files, true
+	}
+
+	// The payload is truncated (or empty), ask GitHub for the full comparison
+	if payload.Installation.ID == 0 || strings.Trim(payload.Before, "0") == "" {
+		return nil, false
+	}
+
+	parts := strings.SplitN(payload.Repository.FullName, "/", 2)
+	if len(parts) != 2 {
+		return nil, false
+	}
+
+	ctx := context.Background()
+	client, err := githubapi.NewClientWithInstallation(ctx, payload.Installation.ID)
+	if err != nil {
+		log.Printf("Error creating GitHub client for installation %d: %v", payload.Installation.ID, err)
+		return nil, false
+	}
+
+	changed, err := client.CompareChangedFiles(ctx, parts[0], parts[1], payload.Before, payload.After)
+	if err != nil {
+		log.Printf("Error fetching changed files for %s: %v", payload.Repository.FullName, err)
+		return nil, false
+	}
+
+	add(changed)
+	return files, true
+}
+
+// matchesPathFilter reports whether any of the files matches the service's deploy path filter.
+// The filter is a comma-separated list of globs where "*" matches within a path segment and
+// "**" matches across segments. A pattern without glob characters matches a directory prefix.
+func matchesPathFilter(filter string, files []string) bool {
+	for _, pattern := range strings.Split(filter, ",") {
+		pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+
+		var re *regexp.Regexp
+		if !strings.ContainsAny(pattern, "*?") {
+			prefix := strings.TrimSuffix(pattern, "/")
+			re = regexp.MustCompile("^" + regexp.QuoteMeta(prefix) + "(/.*)?$")
+		} else {
+			re = globToRegexp(pattern)
+		}
+
+		for _, file := range files {
+			if re.MatchString(file) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// globToRegexp converts a path glob into an anchored regular expression
+func globToRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch {
+		case ch == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			// "**/" also matches zero directories
+			if i+2 < len(pattern) && pattern[i+2] == '/' {
+				sb.WriteString("(.*/)?")
+				i += 2
+			} else {
+				sb.WriteString(".*")
+				i++
+			}
+		case ch == '*':
+			sb.WriteString("[^/]*")
+		case ch == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
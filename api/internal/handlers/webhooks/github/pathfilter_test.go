@@ -0,0 +1,64 @@
+package github
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPushChangedFiles(t *testing.T) {
+	var payload PushPayload
+	body := `{"before":"a1","after":"b2","commits":[
+		{"added":["apps/web/new.ts"],"modified":["README.md"]},
+		{"removed":["apps/api/old.go"],"modified":["README.md"]}
+	]}`
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	files, known := pushChangedFiles(payload)
+	if !known {
+		t.Fatal("changed files of a complete payload are unknown")
+	}
+	want := []string{"apps/web/new.ts", "README.md", "apps/api/old.go"}
+	if len(files) != len(want) {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("files = %v, want %v", files, want)
+			break
+		}
+	}
+
+	// Without commits or an installation to compare with, filters can't skip the build
+	if _, known := pushChangedFiles(PushPayload{}); known {
+		t.Error("changed files of an empty payload are known")
+	}
+}
+
+func TestMatchesPathFilter(t *testing.T) {
+	files := []string{"apps/web/src/index.ts", "README.md"}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{filter: "apps/web", want: true},
+		{filter: "/apps/web/", want: true},
+		{filter: "apps/we", want: false},
+		{filter: "apps/api", want: false},
+		{filter: "apps/api, apps/web", want: true},
+		{filter: "apps/*/src/*.ts", want: true},
+		{filter: "apps/*.ts", want: false},
+		{filter: "apps/**", want: true},
+		{filter: "**/index.ts", want: true},
+		{filter: "*.md", want: true},
+		{filter: "docs/**", want: false},
+		{filter: " , ", want: false},
+	}
+	for _, tt := range tests {
+		if got := matchesPathFilter(tt.filter, files); got != tt.want {
+			t.Errorf("matchesPathFilter(%q) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
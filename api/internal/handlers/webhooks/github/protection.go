@@ -0,0 +1,40 @@
+package github
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	githubapi "github.com/deployra/deployra/api/pkg/github"
+)
+
+// isBranchProtected looks up branch protection, tests replace it to push without GitHub
+var isBranchProtected = fetchBranchProtection
+
+// fetchBranchProtection reports whether a branch of a repository of an installation is protected.
+// A branch GitHub could not be asked about counts as unprotected.
+func fetchBranchProtection(installationID int64, repositoryName string, branch string) bool {
+	if installationID == 0 {
+		return false
+	}
+
+	parts := strings.SplitN(repositoryName, "/", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	ctx := context.Background()
+	client, err := githubapi.NewClientWithInstallation(ctx, installationID)
+	if err != nil {
+		log.Printf("Error creating GitHub client for installation %d: %v", installationID, err)
+		return false
+	}
+
+	protected, err := client.IsBranchProtected(ctx, parts[0], parts[1], branch)
+	if err != nil {
+		log.Printf("Error fetching protection of %s:%s: %v", repositoryName, branch, err)
+		return false
+	}
+
+	return protected
+}
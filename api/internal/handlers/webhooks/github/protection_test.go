@@ -0,0 +1,118 @@
+package github
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/gofiber/fiber/v2"
+)
+
+// protectBranch replaces the protection lookup for the test and returns the number of lookups
+func protectBranch(t *testing.T, protected bool) func() int {
+	t.Helper()
+	lookups := 0
+	isBranchProtected = func(installationID int64, repositoryName string, branch string) bool {
+		lookups++
+		return protected
+	}
+	t.Cleanup(func() { isBranchProtected = fetchBranchProtection })
+	return func() int { return lookups }
+}
+
+// buildWithoutDebounce builds pushes as they arrive
+func buildWithoutDebounce(t *testing.T) {
+	t.Helper()
+	cfg := config.Get()
+	previous := cfg.GitHubPushDebounce
+	cfg.GitHubPushDebounce = 0
+	t.Cleanup(func() { cfg.GitHubPushDebounce = previous })
+}
+
+// pushServices answers the service lookup of a push with the given services
+func pushServices(db *dbtest.DB, protectedOnly ...bool) {
+	rows := make([]dbtest.Row, len(protectedOnly))
+	for i, only := range protectedOnly {
+		rows[i] = dbtest.Row{
+			"id": "svc" + string(rune('1'+i)), "name": "web", "projectId": "proj1",
+			"repositoryName": "acme/app", "branch": "main", "runtime": "docker",
+			"autoDeployEnabled": true, "deployOnlyProtectedBranches": only, "deployOn": "push",
+		}
+	}
+	db.OnQuery("FROM `Service`", rows...)
+}
+
+func push(t *testing.T) {
+	t.Helper()
+	app := fiber.New()
+	app.Post("/", Handle)
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{
+		"ref": "refs/heads/main",
+		"after": "abc",
+		"repository": {"full_name": "acme/app"},
+		"installation": {"id": 1}
+	}`))
+	req.Header.Set("X-GitHub-Event", "push")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("push request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+}
+
+func TestPushToProtectedBranchDeploys(t *testing.T) {
+	buildWithoutDebounce(t)
+	builds := recordBuilds(t)
+	lookups := protectBranch(t, true)
+	pushServices(dbtest.New(t), true, true)
+
+	push(t)
+	if got := builds(); len(got) != 2 || got[0] != "svc1@abc" || got[1] != "svc2@abc" {
+		t.Errorf("builds = %v, want both services built", got)
+	}
+	// The protection of the branch is looked up once for the push
+	if n := lookups(); n != 1 {
+		t.Errorf("looked up branch protection %d times, want 1", n)
+	}
+}
+
+func TestPushToUnprotectedBranchSkipsProtectedOnlyServices(t *testing.T) {
+	buildWithoutDebounce(t)
+	builds := recordBuilds(t)
+	protectBranch(t, false)
+	pushServices(dbtest.New(t), true, false)
+
+	push(t)
+	if got := builds(); len(got) != 1 || got[0] != "svc2@abc" {
+		t.Errorf("builds = %v, want only the service deploying every branch", got)
+	}
+}
+
+func TestPushDoesNotLookUpProtectionUnlessNeeded(t *testing.T) {
+	buildWithoutDebounce(t)
+	builds := recordBuilds(t)
+	lookups := protectBranch(t, false)
+	pushServices(dbtest.New(t), false)
+
+	push(t)
+	if got := builds(); len(got) != 1 {
+		t.Errorf("builds = %v, want the service built", got)
+	}
+	if n := lookups(); n != 0 {
+		t.Errorf("looked up branch protection %d times for a service deploying every branch", n)
+	}
+}
+
+func TestBranchProtectionUnknownWithoutInstallation(t *testing.T) {
+	if fetchBranchProtection(0, "acme/app", "main") {
+		t.Error("a push without an installation counted as protected")
+	}
+	if fetchBranchProtection(1, "app", "main") {
+		t.Error("a repository without an owner counted as protected")
+	}
+}
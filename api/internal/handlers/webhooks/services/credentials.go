@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"log"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CredentialRotationRequest represents the outcome of a credential rotation
+type CredentialRotationRequest struct {
+	Status string `json:"status"` // ROTATED, FAILED
+	Error  string `json:"error,omitempty"`
+}
+
+// POST /api/webhooks/services/:serviceId/credentials
+func UpdateCredentialRotation(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	var req CredentialRotationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	rotationErr := ""
+	switch req.Status {
+	case "ROTATED":
+	case "FAILED":
+		rotationErr = req.Error
+		if rotationErr == "" {
+			rotationErr = "unknown error"
+		}
+	default:
+		return response.BadRequest(c, "Status must be ROTATED or FAILED")
+	}
+
+	var service models.Service
+	if err := db.Where("id = ? AND deletedAt IS NULL", serviceID).First(&service).Error; err != nil {
+		return response.NotFound(c, "Service not found")
+	}
+
+	updatedServices, err := servicestatus.CompleteCredentialRotation(service, rotationErr)
+	if err != nil {
+		if errors.Is(err, servicestatus.ErrNoCredentials) || errors.Is(err, servicestatus.ErrNoPendingRotation) {
+			return response.NotFound(c, "Service has no pending credential rotation")
+		}
+		log.Printf("Error completing credential rotation of service %s: %v", serviceID, err)
+		return response.InternalServerError(c, "Failed to complete credential rotation")
+	}
+
+	if updatedServices == nil {
+		updatedServices = []string{}
+	}
+
+	return response.Success(c, fiber.Map{
+		"message":         "Credential rotation updated",
+		"updatedServices": updatedServices,
+	})
+}
@@ -3,13 +3,17 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
+	"github.com/deployra/deployra/api/internal/config"
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
 	"github.com/deployra/deployra/api/internal/redis"
+	servicestatus "github.com/deployra/deployra/api/internal/service"
 	"github.com/deployra/deployra/api/internal/utils"
 	"github.com/deployra/deployra/api/pkg/response"
 	"github.com/gofiber/fiber/v2"
@@ -59,20 +63,26 @@ func UpdateStatus(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Status is required")
 	}
 
-	// Find the service
-	var service models.Service
-	if err := db.Where("id = ?", serviceID).First(&service).Error; err != nil {
-		return response.NotFound(c, "Service not found")
+	status := models.ServiceStatus(req.Status)
+	if !servicestatus.IsValidStatus(status) {
+		return response.BadRequest(c, "Invalid status")
 	}
 
 	// Update the service status
-	if err := db.Model(&models.Service{}).Where("id = ?", serviceID).Updates(map[string]interface{}{
-		"status":    req.Status,
-		"updatedAt": time.Now(),
-	}).Error; err != nil {
-		return response.InternalServerError(c, "Failed to update service status")
+	if err := servicestatus.SetStatus(serviceID, status, nil); err != nil {
+		switch {
+		case errors.Is(err, servicestatus.ErrServiceNotFound):
+			return response.NotFound(c, "Service not found")
+		case errors.Is(err, servicestatus.ErrInvalidTransition):
+			return response.Error(c, fiber.StatusConflict, err.Error())
+		default:
+			return response.InternalServerError(c, "Failed to update service status")
+		}
 	}
 
+	var service models.Service
+	db.Where("id = ?", serviceID).First(&service)
+
 	return response.Success(c, fiber.Map{
 		"service": service,
 	})
@@ -94,7 +104,7 @@ func UpdateReplicas(c *fiber.Ctx) error {
 	}
 
 	// Acquire Redis lock for this service
-	lockKey := fmt.Sprintf("service-replica-lock:%s", serviceID)
+	lockKey := servicestatus.ReplicaLockKey(serviceID)
 	lockAcquired, err := redis.AcquireLock(ctx, lockKey, 30)
 	if err != nil {
 		return response.InternalServerError(c, "Failed to acquire lock")
@@ -118,8 +128,26 @@ func UpdateReplicas(c *fiber.Ctx) error {
 		Order("createdAt DESC").
 		First(&lastScalingHistory).Error == nil
 
-	beginScaling := service.ScalingStatus == "IDLE" && req.TargetReplicas != service.TargetReplicas
-	endScaling := service.ScalingStatus == "SCALING" && req.CurrentReplicas == service.TargetReplicas
+	// An oscillating autoscaler would start a scale on every report, so no scale starts within
+	// the cooldown after the last one. Only the current replica count of such a report is saved.
+	cooldown := time.Duration(config.Get().ScalingCooldownSeconds) * time.Second
+	plan := planReplicaUpdate(service, req, func() bool {
+		if cooldown <= 0 {
+			return false
+		}
+		lastScaleAt, err := redis.GetLastScaleAt(ctx, serviceID)
+		if err != nil {
+			log.Printf("Failed to read last scale time of service %s: %v", serviceID, err)
+			return false
+		}
+		return time.Since(lastScaleAt) < cooldown
+	})
+	beginScaling, endScaling := plan.BeginScaling, plan.EndScaling
+	if (beginScaling || endScaling) && cooldown > 0 {
+		if err := redis.SetLastScaleAt(ctx, serviceID, time.Now(), cooldown); err != nil {
+			log.Printf("Failed to store last scale time of service %s: %v", serviceID, err)
+		}
+	}
 
 	fixedCurrentReplicas := req.CurrentReplicas
 	if req.CurrentReplicas > req.TargetReplicas {
@@ -131,19 +159,11 @@ func UpdateReplicas(c *fiber.Ctx) error {
 	scaleToZero := req.CurrentReplicas == 0 || req.TargetReplicas == 0
 	scaleToOne := req.CurrentReplicas == 1 && req.TargetReplicas == 1
 
-	// Determine new scaling status
-	newScalingStatus := service.ScalingStatus
-	if beginScaling {
-		newScalingStatus = "SCALING"
-	} else if endScaling {
-		newScalingStatus = "IDLE"
-	}
-
 	// Update the service
 	if err := db.Model(&models.Service{}).Where("id = ?", serviceID).Updates(map[string]interface{}{
 		"currentReplicas": fixedCurrentReplicas,
-		"targetReplicas":  req.TargetReplicas,
-		"scalingStatus":   newScalingStatus,
+		"targetReplicas":  plan.TargetReplicas,
+		"scalingStatus":   plan.ScalingStatus,
 	}).Error; err != nil {
 		return response.InternalServerError(c, "Failed to update service replicas")
 	}
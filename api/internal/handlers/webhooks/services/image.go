@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	// Image repository such as "ghcr.io/acme/app" or "registry:5000/app", without tag or digest
+	imageRepositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*(?::[0-9]+)?(?:/[a-z0-9]+(?:[._-]+[a-z0-9]+)*)*$`)
+	imageTagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	imageDigestPattern     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// deployImage deploys a service in the background, tests replace it
+var deployImage = deploy.DeployService
+
+// ImageDeployRequest represents an image pushed by external CI
+type ImageDeployRequest struct {
+	ImageUri  string `json:"imageUri"`
+	Tag       string `json:"tag"`
+	CommitSha string `json:"commitSha"`
+}
+
+// POST /api/webhooks/services/:serviceId/image
+func DeployImage(c *fiber.Ctx) error {
+	db := database.GetDatabase()
+
+	serviceID := c.Params("serviceId")
+	if serviceID == "" {
+		return response.BadRequest(c, "Service ID is required")
+	}
+
+	var service models.Service
+	if err := db.Where("id = ? AND deletedAt IS NULL", serviceID).First(&service).Error; err != nil {
+		return response.Unauthorized(c, "Invalid deploy token")
+	}
+
+	// Same response for unknown services and bad tokens, so service IDs can't be probed
+	token := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+	if service.DeployTokenHash == nil || !crypto.VerifyDeployToken(token, *service.DeployTokenHash) {
+		return response.Unauthorized(c, "Invalid deploy token")
+	}
+
+	var req ImageDeployRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	imageUri, err := imageReference(req.ImageUri, req.Tag)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	if service.Runtime != models.RuntimeImage {
+		return response.BadRequest(c, "Service is built from a repository")
+	}
+	if service.Stopped {
+		return response.Error(c, fiber.StatusConflict, "Service is stopped")
+	}
+
+	// Check if there's already a deployment in progress
+	var activeCount int64
+	db.Model(&models.Deployment{}).
+		Where("serviceId = ? AND status IN ?", serviceID, []string{"PENDING", "BUILDING", "DEPLOYING"}).
+		Count(&activeCount)
+	if activeCount > 0 {
+		return response.Error(c, fiber.StatusConflict, "A deployment is already in progress")
+	}
+
+	updates := map[string]interface{}{
+		"containerRegistryImageUri": imageUri,
+	}
+	if service.ContainerRegistryType == nil {
+		updates["containerRegistryType"] = "docker"
+	}
+	if err := db.Model(&models.Service{}).Where("id = ?", serviceID).Updates(updates).Error; err != nil {
+		return response.InternalServerError(c, "Failed to update service image")
+	}
+
+	// Get the latest deployment number
+	var latestDeployment models.Deployment
+	db.Where("serviceId = ?", serviceID).
+		Order("deploymentNumber DESC").
+		First(&latestDeployment)
+
+	deployment := models.Deployment{
+		ID:               utils.GenerateShortID(),
+		DeploymentNumber: latestDeployment.DeploymentNumber + 1,
+		ServiceID:        serviceID,
+		Status:           models.DeploymentStatusPending,
+		TriggerType:      "webhook",
+	}
+	if req.CommitSha != "" {
+		deployment.CommitSha = &req.CommitSha
+	}
+
+	if err := db.Create(&deployment).Error; err != nil {
+		return response.InternalServerError(c, "Failed to create deployment")
+	}
+
+	db.Create(&models.ServiceEvent{
+		ServiceID:    serviceID,
+		Type:         models.EventTypeDeployStarted,
+		Message:      utils.Ptr("Deploying image " + imageUri),
+		DeploymentID: &deployment.ID,
+	})
+
+	deploymentID := deployment.ID
+	go func() {
+		if err := deployImage("deploy-service", &deploymentID, serviceID); err != nil {
+			log.Printf("Error deploying image for service %s: %v", serviceID, err)
+		}
+	}()
+
+	return response.Success(c, fiber.Map{
+		"deploymentId":     deployment.ID,
+		"deploymentNumber": deployment.DeploymentNumber,
+		"imageUri":         imageUri,
+	})
+}
+
+// imageReference validates an image repository and tag and joins them into the URI that is deployed.
+// The tag may also be given as part of the image URI, or be a sha256 digest.
+func imageReference(imageUri, tag string) (string, error) {
+	imageUri = strings.TrimSpace(imageUri)
+	tag = strings.TrimSpace(tag)
+	if imageUri == "" {
+		return "", errors.New("Image URI is required")
+	}
+
+	repository := imageUri
+	if at := strings.Index(imageUri, "@"); at >= 0 {
+		repository = imageUri[:at]
+		if tag != "" || !imageDigestPattern.MatchString(imageUri[at+1:]) {
+			return "", errors.New("Invalid image digest")
+		}
+	} else if colon := strings.LastIndex(imageUri, ":"); colon > strings.LastIndex(imageUri, "/") {
+		// A colon after the last slash separates the tag, earlier ones belong to a registry port
+		repository = imageUri[:colon]
+		if tag != "" {
+			return "", errors.New("Tag is given both in the image URI and separately")
+		}
+		tag = imageUri[colon+1:]
+	}
+
+	if !imageRepositoryPattern.MatchString(repository) {
+		return "", errors.New("Invalid image URI")
+	}
+
+	if strings.Contains(imageUri, "@") {
+		return imageUri, nil
+	}
+	if tag == "" {
+		return "", errors.New("Image tag is required")
+	}
+	if !imageTagPattern.MatchString(tag) {
+		return "", errors.New("Invalid image tag")
+	}
+
+	return repository + ":" + tag, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const testDeployToken = "ddt_0123456789abcdef"
+
+// stubDeployImage reports the deployment IDs deployImage is called with
+func stubDeployImage(t *testing.T) <-chan string {
+	t.Helper()
+	deployed := make(chan string, 1)
+	previous := deployImage
+	deployImage = func(deployType string, deploymentID *string, serviceID string) error {
+		if deploymentID != nil {
+			deployed <- *deploymentID
+		}
+		return nil
+	}
+	t.Cleanup(func() { deployImage = previous })
+	return deployed
+}
+
+// imageService answers lookups of svc1 with an image service deployed with testDeployToken
+func imageService(db *dbtest.DB) {
+	db.OnQuery("FROM `Service`", dbtest.Row{
+		"id":              "svc1",
+		"runtime":         string(models.RuntimeImage),
+		"deployTokenHash": crypto.HashDeployToken(testDeployToken),
+	})
+}
+
+func postImage(t *testing.T, token, body string) int {
+	t.Helper()
+	app := fiber.New()
+	app.Post("/webhooks/services/:serviceId/image", DeployImage)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/services/svc1/image", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("image request: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestDeployImageTriggersDeploy(t *testing.T) {
+	db := dbtest.New(t)
+	imageService(db)
+	deployed := stubDeployImage(t)
+
+	status := postImage(t, testDeployToken, `{"imageUri":"ghcr.io/acme/app","tag":"v1.2.0","commitSha":"abc123"}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 1 || !containsArg(updates[0], "ghcr.io/acme/app:v1.2.0") {
+		t.Errorf("service updates = %v, want the image set to ghcr.io/acme/app:v1.2.0", updates)
+	}
+
+	inserts := db.Statements("INSERT INTO `Deployment`")
+	if len(inserts) != 1 || !containsArg(inserts[0], "webhook") || !containsArg(inserts[0], "abc123") {
+		t.Fatalf("deployment inserts = %v, want a webhook deployment of abc123", inserts)
+	}
+
+	select {
+	case deploymentID := <-deployed:
+		if !containsArg(inserts[0], deploymentID) {
+			t.Errorf("deployed %s, not the deployment that was created", deploymentID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the service was not deployed")
+	}
+}
+
+func TestDeployImageRejectsBadTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "no token"},
+		{name: "wrong token", token: "ddt_wrong"},
+		{name: "hash instead of the token", token: crypto.HashDeployToken(testDeployToken)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			imageService(db)
+			deployed := stubDeployImage(t)
+
+			status := postImage(t, tt.token, `{"imageUri":"ghcr.io/acme/app","tag":"v1"}`)
+			if status != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", status, http.StatusUnauthorized)
+			}
+			if writes := len(db.Statements("UPDATE")) + len(db.Statements("INSERT")); writes != 0 {
+				t.Errorf("rejected request wrote %d statements", writes)
+			}
+			select {
+			case <-deployed:
+				t.Error("rejected request deployed the service")
+			default:
+			}
+		})
+	}
+}
+
+func TestDeployImageUnknownServiceLooksLikeBadToken(t *testing.T) {
+	dbtest.New(t)
+	stubDeployImage(t)
+
+	if status := postImage(t, testDeployToken, `{"imageUri":"ghcr.io/acme/app","tag":"v1"}`); status != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestImageReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	tests := []struct {
+		imageUri string
+		tag      string
+		want     string
+		wantErr  string
+	}{
+		{imageUri: "ghcr.io/acme/app", tag: "v1", want: "ghcr.io/acme/app:v1"},
+		{imageUri: "ghcr.io/acme/app:v1", want: "ghcr.io/acme/app:v1"},
+		{imageUri: "registry:5000/app", tag: "latest", want: "registry:5000/app:latest"},
+		{imageUri: "registry:5000/app:1.0", want: "registry:5000/app:1.0"},
+		{imageUri: "ghcr.io/acme/app@" + digest, want: "ghcr.io/acme/app@" + digest},
+		{imageUri: " nginx ", tag: " 1.25 ", want: "nginx:1.25"},
+		{imageUri: "", tag: "v1", wantErr: "Image URI is required"},
+		{imageUri: "ghcr.io/acme/app", wantErr: "Image tag is required"},
+		{imageUri: "ghcr.io/acme/app:v1", tag: "v2", wantErr: "Tag is given both"},
+		{imageUri: "ghcr.io/acme/app@sha256:short", wantErr: "Invalid image digest"},
+		{imageUri: "ghcr.io/acme/app@" + digest, tag: "v1", wantErr: "Invalid image digest"},
+		{imageUri: "GHCR.io/Acme/App", tag: "v1", wantErr: "Invalid image URI"},
+		{imageUri: "https://ghcr.io/acme/app", tag: "v1", wantErr: "Invalid image URI"},
+		{imageUri: "ghcr.io/acme/app", tag: "-v1", wantErr: "Invalid image tag"},
+	}
+	for _, tt := range tests {
+		got, err := imageReference(tt.imageUri, tt.tag)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("imageReference(%q, %q) error = %v, want %q", tt.imageUri, tt.tag, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("imageReference(%q, %q) = %q, %v, want %q", tt.imageUri, tt.tag, got, err, tt.want)
+		}
+	}
+}
+
+func containsArg(statement dbtest.Statement, want string) bool {
+	for _, arg := range statement.Args {
+		if s, ok := arg.(string); ok && s == want {
+			return true
+		}
+		if s, ok := arg.(*string); ok && s != nil && *s == want {
+			return true
+		}
+	}
+	return false
+}
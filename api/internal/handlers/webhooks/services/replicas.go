@@ -0,0 +1,35 @@
+package services
+
+import "github.com/deployra/deployra/api/internal/models"
+
+// replicaUpdate is what a replica count report changes on a service
+type replicaUpdate struct {
+	TargetReplicas int
+	ScalingStatus  models.ServiceScalingStatus
+	BeginScaling   bool
+	EndScaling     bool
+}
+
+// planReplicaUpdate returns what a replica count report changes on a service. A scale that
+// would start within the cooldown after the last one is suppressed: the target stays as it
+// was, so the scale starts, with its events, from a report after the cooldown.
+func planReplicaUpdate(service models.Service, req ReplicaCountRequest, inCooldown func() bool) replicaUpdate {
+	update := replicaUpdate{
+		TargetReplicas: req.TargetReplicas,
+		ScalingStatus:  service.ScalingStatus,
+		BeginScaling:   service.ScalingStatus == models.ServiceScalingStatusIdle && req.TargetReplicas != service.TargetReplicas,
+		EndScaling:     service.ScalingStatus == models.ServiceScalingStatusScaling && req.CurrentReplicas == service.TargetReplicas,
+	}
+
+	if update.BeginScaling && inCooldown() {
+		update.BeginScaling = false
+		update.TargetReplicas = service.TargetReplicas
+	}
+
+	if update.BeginScaling {
+		update.ScalingStatus = models.ServiceScalingStatusScaling
+	} else if update.EndScaling {
+		update.ScalingStatus = models.ServiceScalingStatusIdle
+	}
+	return update
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+func idleService(targetReplicas int) models.Service {
+	return models.Service{ScalingStatus: models.ServiceScalingStatusIdle, TargetReplicas: targetReplicas, CurrentReplicas: targetReplicas}
+}
+
+func TestPlanReplicaUpdateBeginsScaling(t *testing.T) {
+	plan := planReplicaUpdate(idleService(2), ReplicaCountRequest{CurrentReplicas: 2, TargetReplicas: 4}, func() bool { return false })
+
+	if !plan.BeginScaling || plan.ScalingStatus != models.ServiceScalingStatusScaling || plan.TargetReplicas != 4 {
+		t.Fatalf("plan = %+v, want a scale to 4 to begin", plan)
+	}
+}
+
+func TestPlanReplicaUpdateSuppressedWithinCooldown(t *testing.T) {
+	service := idleService(2)
+	plan := planReplicaUpdate(service, ReplicaCountRequest{CurrentReplicas: 2, TargetReplicas: 4}, func() bool { return true })
+
+	if plan.BeginScaling || plan.ScalingStatus != models.ServiceScalingStatusIdle {
+		t.Fatalf("plan = %+v, want no scale to begin", plan)
+	}
+	if plan.TargetReplicas != 2 {
+		t.Fatalf("target = %d, want the previous target 2 kept", plan.TargetReplicas)
+	}
+
+	// Once the cooldown passed the same report starts the scale
+	plan = planReplicaUpdate(service, ReplicaCountRequest{CurrentReplicas: 2, TargetReplicas: 4}, func() bool { return false })
+	if !plan.BeginScaling || plan.TargetReplicas != 4 {
+		t.Fatalf("plan after cooldown = %+v, want the scale to begin", plan)
+	}
+}
+
+func TestPlanReplicaUpdateEndsScalingDuringCooldown(t *testing.T) {
+	service := models.Service{ScalingStatus: models.ServiceScalingStatusScaling, TargetReplicas: 4, CurrentReplicas: 2}
+	cooldownChecked := false
+	plan := planReplicaUpdate(service, ReplicaCountRequest{CurrentReplicas: 4, TargetReplicas: 4}, func() bool {
+		cooldownChecked = true
+		return true
+	})
+
+	if !plan.EndScaling || plan.ScalingStatus != models.ServiceScalingStatusIdle {
+		t.Fatalf("plan = %+v, want the scale to end", plan)
+	}
+	if cooldownChecked {
+		t.Fatal("cooldown was checked for a report that starts no scale")
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// How often a connection is checked for a client that went away while its request runs
+const disconnectPollInterval = 250 * time.Millisecond
+
+// RequestContextMiddleware gives every request a context, c.UserContext(), that is cancelled
+// when the client disconnects, the server shuts down or the handler returns, so Kubernetes and
+// GitHub calls made for the request stop once nobody waits for their result
+func RequestContextMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// The fasthttp context is reused once the handler returns, only its channels are kept
+		shutdown := c.Context().Done()
+		conn, _ := c.Context().Conn().(syscall.Conn)
+		go func() {
+			ticker := time.NewTicker(disconnectPollInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-shutdown:
+					cancel()
+					return
+				case <-ticker.C:
+					if conn != nil && peerClosed(conn) {
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package middleware
+
+import "syscall"
+
+// peerClosed can't tell a closed connection without reading it here, requests are only
+// cancelled when the handler returns or the server shuts down
+func peerClosed(conn syscall.Conn) bool {
+	return false
+}
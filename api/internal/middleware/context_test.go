@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequestContextCancelledOnDisconnect(t *testing.T) {
+	cancelled := make(chan struct{})
+	app := fiber.New()
+	app.Use(RequestContextMiddleware())
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		select {
+		case <-c.UserContext().Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+		}
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if _, err := conn.Write([]byte("GET /slow HTTP/1.1\r\nHost: test\r\n\r\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	conn.Close()
+
+	select {
+	case <-cancelled:
+	case <-time.After(3 * time.Second):
+		t.Fatal("request context was not cancelled after the client disconnected")
+	}
+}
+
+func TestRequestContextCancelledWhenHandlerReturns(t *testing.T) {
+	var captured interface{ Done() <-chan struct{} }
+	app := fiber.New()
+	app.Use(RequestContextMiddleware())
+	app.Get("/", func(c *fiber.Ctx) error {
+		captured = c.UserContext()
+		select {
+		case <-captured.Done():
+			t.Error("request context cancelled while the handler runs")
+		default:
+		}
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+
+	select {
+	case <-captured.Done():
+	case <-time.After(time.Second):
+		t.Fatal("request context was not cancelled after the handler returned")
+	}
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package middleware
+
+import "syscall"
+
+// peerClosed reports whether the client closed a connection. The socket is peeked without
+// blocking so a pipelined request stays unread.
+func peerClosed(conn syscall.Conn) bool {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	closed := false
+	raw.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		closed = n == 0 && err == nil
+		return true
+	})
+	return closed
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// CORSMiddleware builds the CORS middleware from the configured origins.
+// Exact origins are matched as-is, patterns starting with "*." match any subdomain of the
+// given origin (e.g. "https://*.preview.example.com") and other patterns are treated as regexes.
+// The matching request origin is echoed back instead of a wildcard so credentials keep working.
+func CORSMiddleware(cfg *config.Config) (fiber.Handler, error) {
+	origins := splitList(cfg.CorsOrigins)
+	patterns := splitList(cfg.CorsOriginPatterns)
+
+	allowAll := false
+	exact := make(map[string]bool)
+	for _, origin := range origins {
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		exact[strings.TrimSuffix(strings.ToLower(origin), "/")] = true
+	}
+
+	if allowAll && cfg.CorsCredentials {
+		return nil, fmt.Errorf("CORS_ORIGINS cannot contain \"*\" when CORS_ALLOW_CREDENTIALS is enabled")
+	}
+
+	var matchers []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := compileOriginPattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CORS origin pattern %q: %w", pattern, err)
+		}
+		matchers = append(matchers, re)
+	}
+
+	log.Printf("CORS policy: origins=%v patterns=%v credentials=%t", origins, patterns, cfg.CorsCredentials)
+
+	corsConfig := cors.Config{
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Request-ID,Idempotency-Key",
+		ExposeHeaders:    "X-Request-ID,Idempotent-Replayed,Retry-After",
+		AllowCredentials: cfg.CorsCredentials,
+	}
+
+	if allowAll {
+		corsConfig.AllowOrigins = "*"
+	} else {
+		corsConfig.AllowOriginsFunc = func(origin string) bool {
+			origin = strings.ToLower(origin)
+			if exact[origin] {
+				return true
+			}
+			for _, re := range matchers {
+				if re.MatchString(origin) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	return cors.New(corsConfig), nil
+}
+
+// compileOriginPattern turns a configured origin pattern into an anchored regex
+func compileOriginPattern(pattern string) (*regexp.Regexp, error) {
+	// Subdomain wildcard, e.g. https://*.preview.example.com
+	if scheme, host, ok := strings.Cut(pattern, "://*."); ok {
+		return regexp.Compile("^" + regexp.QuoteMeta(strings.ToLower(scheme)) + `://[a-z0-9-]+(\.[a-z0-9-]+)*\.` + regexp.QuoteMeta(strings.ToLower(host)) + "$")
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		host := strings.TrimPrefix(pattern, "*.")
+		return regexp.Compile(`^https?://[a-z0-9-]+(\.[a-z0-9-]+)*\.` + regexp.QuoteMeta(strings.ToLower(host)) + "$")
+	}
+
+	if !strings.HasPrefix(pattern, "^") {
+		pattern = "^" + pattern
+	}
+	if !strings.HasSuffix(pattern, "$") {
+		pattern = pattern + "$"
+	}
+	return regexp.Compile(pattern)
+}
+
+// splitList splits a comma-separated config value, dropping empty entries
+func splitList(value string) []string {
+	result := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
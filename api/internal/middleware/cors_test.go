@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCORSRejectsWildcardWithCredentials(t *testing.T) {
+	_, err := CORSMiddleware(&config.Config{CorsOrigins: "https://app.example.com, *", CorsCredentials: true})
+	if err == nil || !strings.Contains(err.Error(), "CORS_ALLOW_CREDENTIALS") {
+		t.Fatalf("CORSMiddleware error = %v, want the wildcard rejected", err)
+	}
+
+	// Without credentials a wildcard is fine
+	if _, err := CORSMiddleware(&config.Config{CorsOrigins: "*"}); err != nil {
+		t.Fatalf("wildcard without credentials: %v", err)
+	}
+}
+
+func TestCORSRejectsInvalidPattern(t *testing.T) {
+	if _, err := CORSMiddleware(&config.Config{CorsOriginPatterns: "https://(broken"}); err == nil {
+		t.Fatal("an invalid origin pattern was accepted")
+	}
+}
+
+func TestCORSEchoesAllowedOrigins(t *testing.T) {
+	handler, err := CORSMiddleware(&config.Config{
+		CorsOrigins:        "https://app.example.com/",
+		CorsOriginPatterns: "https://*.preview.example.com, ^https://pr-[0-9]+\\.review\\.dev$",
+		CorsCredentials:    true,
+	})
+	if err != nil {
+		t.Fatalf("CORSMiddleware: %v", err)
+	}
+	app := fiber.New()
+	app.Use(handler)
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "https://app.example.com", allowed: true},
+		{origin: "https://APP.example.com", allowed: true},
+		{origin: "https://pr-1.preview.example.com", allowed: true},
+		{origin: "https://a.b.preview.example.com", allowed: true},
+		{origin: "https://pr-12.review.dev", allowed: true},
+		{origin: "https://preview.example.com", allowed: false},
+		{origin: "http://pr-1.preview.example.com", allowed: false},
+		{origin: "https://evil.com", allowed: false},
+		{origin: "https://app.example.com.evil.com", allowed: false},
+		{origin: "https://pr-x.review.dev", allowed: false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Origin", tt.origin)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("GET with origin %s: %v", tt.origin, err)
+		}
+
+		got := resp.Header.Get(fiber.HeaderAccessControlAllowOrigin)
+		if tt.allowed {
+			// The request's own origin is echoed, never a wildcard
+			if !strings.EqualFold(got, tt.origin) {
+				t.Errorf("origin %s: Access-Control-Allow-Origin = %q, want it echoed", tt.origin, got)
+			}
+			if creds := resp.Header.Get(fiber.HeaderAccessControlAllowCredentials); creds != "true" {
+				t.Errorf("origin %s: Access-Control-Allow-Credentials = %q, want true", tt.origin, creds)
+			}
+		} else if got != "" {
+			t.Errorf("origin %s: Access-Control-Allow-Origin = %q, want none", tt.origin, got)
+		}
+	}
+}
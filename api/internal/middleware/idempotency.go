@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// How long a key stays reserved while the first request is still running
+const idempotencyInProgressTTL = 5 * time.Minute
+
+// IdempotencyMiddleware replays the first response for requests carrying the same
+// Idempotency-Key header, so client retries don't repeat side effects. Keys are scoped
+// per user, so it must run after the auth middleware. Requests without the header are
+// handled normally.
+func IdempotencyMiddleware(ttlHours int) fiber.Handler {
+	ttl := time.Duration(ttlHours) * time.Hour
+
+	return func(c *fiber.Ctx) error {
+		idempotencyKey := c.Get("Idempotency-Key")
+		if idempotencyKey == "" || ttl <= 0 {
+			return c.Next()
+		}
+
+		user, ok := c.Locals("user").(*models.User)
+		if !ok {
+			return c.Next()
+		}
+
+		if len(idempotencyKey) > 255 {
+			return response.BadRequest(c, "Idempotency-Key must be at most 255 characters")
+		}
+
+		ctx := c.Context()
+		key := fmt.Sprintf("idempotency:%s:%s", user.ID, idempotencyKey)
+		method := c.Method()
+		path := c.Path()
+
+		reserved, err := redis.ReserveIdempotencyKey(ctx, key, method, path, idempotencyInProgressTTL)
+		if err != nil {
+			// Without Redis we can't guarantee idempotency, but the request itself can still run
+			log.Printf("Idempotency check failed for %s: %v", key, err)
+			return c.Next()
+		}
+
+		if !reserved {
+			record, err := redis.GetIdempotentResponse(ctx, key)
+			if err != nil {
+				log.Printf("Failed to read idempotency record %s: %v", key, err)
+				return response.InternalServerError(c, "Failed to process Idempotency-Key")
+			}
+			if record == nil {
+				// The record expired between the reservation attempt and the read
+				return response.Error(c, fiber.StatusConflict, "Request with this Idempotency-Key is being processed, retry shortly")
+			}
+			if record.Method != method || record.Path != path {
+				return response.Error(c, fiber.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+			}
+			if record.InProgress {
+				return response.Error(c, fiber.StatusConflict, "Request with this Idempotency-Key is still being processed")
+			}
+
+			c.Set("Idempotent-Replayed", "true")
+			if record.ContentType != "" {
+				c.Set(fiber.HeaderContentType, record.ContentType)
+			}
+			return c.Status(record.Status).Send(record.Body)
+		}
+
+		if err := c.Next(); err != nil {
+			redis.ReleaseLock(ctx, key)
+			return err
+		}
+
+		// Server errors and rate limiting are not stored so the client can retry them
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError || status == fiber.StatusTooManyRequests {
+			redis.ReleaseLock(ctx, key)
+			return nil
+		}
+
+		record := redis.IdempotentResponse{
+			Method:      method,
+			Path:        path,
+			Status:      status,
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        append([]byte(nil), c.Response().Body()...),
+		}
+		if err := redis.SaveIdempotentResponse(ctx, key, record, ttl); err != nil {
+			log.Printf("Failed to store idempotency record %s: %v", key, err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/gofiber/fiber/v2"
+)
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+func setupRedis(t *testing.T) {
+	t.Helper()
+	redisServer.FlushAll()
+}
+
+func idempotentApp(statuses ...int) *fiber.App {
+	app := fiber.New()
+	calls := 0
+	app.Post("/deploy", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user-1"})
+		return c.Next()
+	}, IdempotencyMiddleware(24), func(c *fiber.Ctx) error {
+		status := statuses[calls]
+		calls++
+		return c.Status(status).SendString(fiber.ErrBadRequest.Message)
+	})
+	return app
+}
+
+func post(t *testing.T, app *fiber.App, key string) int {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/deploy", nil)
+	req.Header.Set("Idempotency-Key", key)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestIdempotencyReplaysSuccess(t *testing.T) {
+	setupRedis(t)
+	app := idempotentApp(fiber.StatusOK, fiber.StatusCreated)
+
+	if status := post(t, app, "key-ok"); status != fiber.StatusOK {
+		t.Fatalf("first request = %d, want 200", status)
+	}
+	if status := post(t, app, "key-ok"); status != fiber.StatusOK {
+		t.Fatalf("retry = %d, want the replayed 200", status)
+	}
+}
+
+func TestIdempotencyDoesNotCacheRateLimitedResponses(t *testing.T) {
+	setupRedis(t)
+	app := idempotentApp(fiber.StatusTooManyRequests, fiber.StatusOK)
+
+	if status := post(t, app, "key-429"); status != fiber.StatusTooManyRequests {
+		t.Fatalf("first request = %d, want 429", status)
+	}
+	if status := post(t, app, "key-429"); status != fiber.StatusOK {
+		t.Fatalf("retry = %d, want 200 from running the handler again", status)
+	}
+}
+
+func TestIdempotencyDoesNotCacheServerErrors(t *testing.T) {
+	setupRedis(t)
+	app := idempotentApp(fiber.StatusServiceUnavailable, fiber.StatusOK)
+
+	if status := post(t, app, "key-503"); status != fiber.StatusServiceUnavailable {
+		t.Fatalf("first request = %d, want 503", status)
+	}
+	if status := post(t, app, "key-503"); status != fiber.StatusOK {
+		t.Fatalf("retry = %d, want 200 from running the handler again", status)
+	}
+}
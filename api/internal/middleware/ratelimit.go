@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/pkg/response"
+	"github.com/deployra/deployra/api/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitMiddleware limits requests with a Redis token bucket of perMinute requests.
+// Requests are keyed by the authenticated user, so it must run after the auth middleware
+// on protected routes; unauthenticated requests are keyed by client IP. The name separates
+// buckets so stricter limits on specific routes don't consume the general allowance.
+func RateLimitMiddleware(name string, perMinute int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if perMinute <= 0 {
+			return c.Next()
+		}
+
+		identity := "ip:" + utils.GetClientIP(c)
+		if user, ok := c.Locals("user").(*models.User); ok {
+			identity = "user:" + user.ID
+		}
+
+		key := fmt.Sprintf("ratelimit:%s:%s", name, identity)
+		allowed, retryAfter, err := redis.TakeRateLimitToken(c.Context(), key, perMinute, time.Minute)
+		if err != nil {
+			// Don't take the API down with Redis, let the request through
+			log.Printf("Rate limit check failed for %s: %v", key, err)
+			return c.Next()
+		}
+
+		if !allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			return response.TooManyRequests(c, "Too many requests, please try again later")
+		}
+
+		return c.Next()
+	}
+}
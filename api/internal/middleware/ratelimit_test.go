@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimitersKeepSeparateBudgets(t *testing.T) {
+	setupRedis(t)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: "user-1"})
+		return c.Next()
+	})
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Post("/deploy", RateLimitMiddleware("deploy", 1), ok)
+	app.Post("/deploy/validate", RateLimitMiddleware("validate", 1), ok)
+
+	status := func(path string) int {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("POST", path, nil))
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		return resp.StatusCode
+	}
+
+	// Validating first leaves the deploy it checked for allowed
+	if got := status("/deploy/validate"); got != fiber.StatusOK {
+		t.Fatalf("validate = %d, want 200", got)
+	}
+	if got := status("/deploy"); got != fiber.StatusOK {
+		t.Fatalf("deploy after validate = %d, want 200", got)
+	}
+	if got := status("/deploy"); got != fiber.StatusTooManyRequests {
+		t.Errorf("second deploy = %d, want 429", got)
+	}
+	if got := status("/deploy/validate"); got != fiber.StatusTooManyRequests {
+		t.Errorf("second validate = %d, want 429", got)
+	}
+}
+
+// rateLimitedApp serves GET / behind a limiter of perMinute, as the user named in the X-User header
+func rateLimitedApp(perMinute int) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if id := c.Get("X-User"); id != "" {
+			c.Locals("user", &models.User{ID: id})
+		}
+		return c.Next()
+	})
+	app.Get("/", RateLimitMiddleware("api", perMinute), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func getAs(t *testing.T, app *fiber.App, user string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("GET /: %v", err)
+	}
+	return resp
+}
+
+func TestRateLimitAllowsBurstThenBlocks(t *testing.T) {
+	setupRedis(t)
+	app := rateLimitedApp(2)
+
+	for i := 0; i < 2; i++ {
+		if resp := getAs(t, app, "user-1"); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("request %d = %d, want 200", i+1, resp.StatusCode)
+		}
+	}
+	resp := getAs(t, app, "user-1")
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("request over the limit = %d, want 429", resp.StatusCode)
+	}
+	// A token comes back every 30s at 2 per minute
+	if retry := resp.Header.Get(fiber.HeaderRetryAfter); retry != "30" {
+		t.Errorf("Retry-After = %q, want 30", retry)
+	}
+
+	// Other users and anonymous clients have their own buckets
+	if resp := getAs(t, app, "user-2"); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("other user = %d, want 200", resp.StatusCode)
+	}
+	if resp := getAs(t, app, ""); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("anonymous client = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestRateLimitRefills(t *testing.T) {
+	setupRedis(t)
+	// Two tokens a second
+	app := rateLimitedApp(120)
+
+	for i := 0; i < 120; i++ {
+		if resp := getAs(t, app, "user-1"); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("request %d = %d, want 200", i+1, resp.StatusCode)
+		}
+	}
+	if resp := getAs(t, app, "user-1"); resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("request over the limit = %d, want 429", resp.StatusCode)
+	}
+
+	time.Sleep(600 * time.Millisecond)
+	if resp := getAs(t, app, "user-1"); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("request after a refill = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	setupRedis(t)
+	app := rateLimitedApp(0)
+	for i := 0; i < 5; i++ {
+		if resp := getAs(t, app, "user-1"); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("request %d with the limit disabled = %d, want 200", i+1, resp.StatusCode)
+		}
+	}
+}
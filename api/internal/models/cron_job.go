@@ -6,6 +6,7 @@ type CronJob struct {
 	ID         string             `gorm:"primaryKey;size:191;column:id" json:"id"`
 	Name       string             `gorm:"size:191;column:name" json:"name"`
 	Schedule   string             `gorm:"size:191;column:schedule" json:"schedule"`
+	Timezone   string             `gorm:"size:191;default:UTC;column:timezone" json:"timezone"`
 	Path       string             `gorm:"size:191;column:path" json:"path"`
 	Headers    JSON               `gorm:"type:json;column:headers" json:"headers,omitempty"`
 	Enabled    bool               `gorm:"default:true;column:enabled" json:"enabled"`
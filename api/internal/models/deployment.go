@@ -12,6 +12,7 @@ type Deployment struct {
 	TriggeredBy      *string                 `gorm:"size:191;column:triggeredBy" json:"triggeredBy,omitempty"`
 	TriggerType      string                  `gorm:"size:191;column:triggerType" json:"triggerType"`
 	StartedAt        time.Time               `gorm:"autoCreateTime;column:startedAt" json:"startedAt"`
+	BuiltAt          *time.Time              `gorm:"column:builtAt" json:"builtAt,omitempty"`
 	CompletedAt      *time.Time              `gorm:"column:completedAt" json:"completedAt,omitempty"`
 	CreatedAt        time.Time               `gorm:"autoCreateTime;column:createdAt" json:"createdAt"`
 	UpdatedAt        time.Time               `gorm:"autoUpdateTime;column:updatedAt" json:"updatedAt"`
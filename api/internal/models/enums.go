@@ -14,6 +14,14 @@ const (
 	ServiceStatusFailed     ServiceStatus = "FAILED"
 )
 
+// DeployTrigger enum
+type DeployTrigger string
+
+const (
+	DeployTriggerPush           DeployTrigger = "push"
+	DeployTriggerMergeToDefault DeployTrigger = "mergeToDefault"
+)
+
 // ServiceScalingStatus enum
 type ServiceScalingStatus string
 
@@ -35,6 +43,13 @@ const (
 	EventTypeConfigUpdated           EventType = "CONFIG_UPDATED"
 	EventTypeServiceScaled           EventType = "SERVICE_SCALED"
 	EventTypeServiceScaling          EventType = "SERVICE_SCALING"
+	EventTypeStatusChanged           EventType = "STATUS_CHANGED"
+	EventTypePodRestarted            EventType = "POD_RESTARTED"
+	EventTypePodExec                 EventType = "POD_EXEC"
+	EventTypeStorageResized          EventType = "STORAGE_RESIZED"
+	EventTypeScalingCancelled        EventType = "SCALING_CANCELLED"
+	EventTypeCredentialsRotated      EventType = "CREDENTIALS_ROTATED"
+	EventTypeServiceRestored         EventType = "SERVICE_RESTORED"
 )
 
 // DeploymentStatus enum
@@ -50,6 +65,16 @@ const (
 	DeploymentStatusCancelled DeploymentStatus = "CANCELLED"
 )
 
+// BackupStatus enum
+type BackupStatus string
+
+const (
+	BackupStatusPending    BackupStatus = "PENDING"
+	BackupStatusInProgress BackupStatus = "IN_PROGRESS"
+	BackupStatusCompleted  BackupStatus = "COMPLETED"
+	BackupStatusFailed     BackupStatus = "FAILED"
+)
+
 // LogType enum
 type LogType string
 
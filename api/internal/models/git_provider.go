@@ -12,7 +12,7 @@ type GitProvider struct {
 	Permissions         JSON            `gorm:"type:json;column:permissions" json:"permissions,omitempty"`
 	URL                 *string         `gorm:"size:191;column:url" json:"url,omitempty"`
 	Username            *string         `gorm:"size:191;column:username" json:"username,omitempty"`
-	Password            *string         `gorm:"size:191;column:password" json:"-"`
+	Password            *string         `gorm:"type:text;column:password" json:"-"`
 	CreatedAt           time.Time       `gorm:"autoCreateTime;column:createdAt" json:"createdAt"`
 	UpdatedAt           time.Time       `gorm:"autoUpdateTime;column:updatedAt" json:"updatedAt"`
 	DeletedAt           *time.Time      `gorm:"index;column:deletedAt" json:"deletedAt,omitempty"`
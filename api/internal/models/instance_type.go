@@ -26,6 +26,7 @@ type InstanceType struct {
 	InstanceTypeGroupID string                  `gorm:"index;size:191;column:instanceTypeGroupId" json:"instanceTypeGroupId"`
 	CpuCount            float64                 `gorm:"column:cpuCount" json:"cpuCount"`
 	MemoryMB            int                     `gorm:"column:memoryMB" json:"memoryMB"`
+	MaxStorageCapacity  *int                    `gorm:"column:maxStorageCapacity" json:"maxStorageCapacity,omitempty"`
 	Index               int                     `gorm:"default:0;column:index" json:"index"`
 	IsVisible           bool                    `gorm:"default:true;column:isVisible" json:"isVisible"`
 	CreatedAt           time.Time               `gorm:"autoCreateTime;column:createdAt" json:"createdAt"`
@@ -34,6 +35,9 @@ type InstanceType struct {
 	Services            []Service               `gorm:"foreignKey:InstanceTypeID" json:"services,omitempty"`
 	Pods                []PodTracking           `gorm:"foreignKey:InstanceTypeID" json:"pods,omitempty"`
 	ScalingHistory      []ServiceScalingHistory `gorm:"foreignKey:InstanceTypeID" json:"scalingHistory,omitempty"`
+
+	// Minutes a deployment may build or deploy, overriding the configured default (0 is no limit)
+	DeploymentTimeoutMinutes *int `gorm:"column:deploymentTimeoutMinutes" json:"deploymentTimeoutMinutes,omitempty"`
 }
 
 func (InstanceType) TableName() string {
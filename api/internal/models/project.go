@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Project struct {
 	ID             string       `gorm:"primaryKey;size:191;column:id" json:"id"`
@@ -13,6 +16,11 @@ type Project struct {
 	DeletedAt      *time.Time   `gorm:"index;column:deletedAt" json:"deletedAt,omitempty"`
 	Organization   Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
 	Services       []Service    `gorm:"foreignKey:ProjectID" json:"services,omitempty"`
+
+	// WebhookEvents are the deployment statuses sent to the webhook, all of them when empty.
+	// WebhookSecret is the encrypted key deliveries are signed with.
+	WebhookEvents json.RawMessage `gorm:"type:json;column:webhookEvents" json:"webhookEvents,omitempty"`
+	WebhookSecret *string         `gorm:"type:text;column:webhookSecret" json:"-"`
 }
 
 func (Project) TableName() string {
@@ -11,6 +11,7 @@ type Service struct {
 	RepositoryName                 *string                 `gorm:"size:191;column:repositoryName" json:"repositoryName,omitempty"`
 	Branch                         *string                 `gorm:"size:191;column:branch" json:"branch,omitempty"`
 	RuntimeFilePath                *string                 `gorm:"size:191;column:runtimeFilePath" json:"runtimeFilePath,omitempty"`
+	BuildContextPath               *string                 `gorm:"size:191;column:buildContextPath" json:"buildContextPath,omitempty"`
 	Runtime                        Runtime                 `gorm:"size:191;default:IMAGE;column:runtime" json:"runtime"`
 	EnvironmentVariables           JSON                    `gorm:"type:json;column:environmentVariables" json:"environmentVariables,omitempty"`
 	CreatedAt                      time.Time               `gorm:"autoCreateTime;column:createdAt" json:"createdAt"`
@@ -19,16 +20,21 @@ type Service struct {
 	DeployedAt                     *time.Time              `gorm:"column:deployedAt" json:"deployedAt,omitempty"`
 	Subdomain                      *string                 `gorm:"uniqueIndex;size:191;column:subdomain" json:"subdomain,omitempty"`
 	CustomDomain                   *string                 `gorm:"size:191;column:customDomain" json:"customDomain,omitempty"`
+	PrimaryDomain                  *string                 `gorm:"size:191;column:primaryDomain" json:"primaryDomain,omitempty"`
+	RedirectWww                    bool                    `gorm:"default:false;column:redirectWww" json:"redirectWww"`
 	HealthCheckPath                *string                 `gorm:"size:191;column:healthCheckPath" json:"healthCheckPath,omitempty"`
 	AutoScalingEnabled             bool                    `gorm:"default:false;column:autoScalingEnabled" json:"autoScalingEnabled"`
 	AutoDeployEnabled              bool                    `gorm:"default:true;column:autoDeployEnabled" json:"autoDeployEnabled"`
+	DeployPathFilter               *string                 `gorm:"size:191;column:deployPathFilter" json:"deployPathFilter,omitempty"`
+	DeployOnlyProtectedBranches    bool                    `gorm:"default:false;column:deployOnlyProtectedBranches" json:"deployOnlyProtectedBranches"`
+	DeployOn                       DeployTrigger           `gorm:"size:191;default:push;column:deployOn" json:"deployOn"`
 	MaxReplicas                    int                     `gorm:"default:1;column:maxReplicas" json:"maxReplicas"`
 	MinReplicas                    int                     `gorm:"default:1;column:minReplicas" json:"minReplicas"`
 	Replicas                       int                     `gorm:"default:1;column:replicas" json:"replicas"`
 	TargetCPUUtilizationPercentage *int                    `gorm:"column:targetCPUUtilizationPercentage" json:"targetCPUUtilizationPercentage,omitempty"`
 	ContainerRegistryType          *string                 `gorm:"size:191;column:containerRegistryType" json:"containerRegistryType,omitempty"`
 	ContainerRegistryImageUri      *string                 `gorm:"type:text;column:containerRegistryImageUri" json:"containerRegistryImageUri,omitempty"`
-	ContainerRegistryUsername      *string                 `gorm:"size:191;column:containerRegistryUsername" json:"containerRegistryUsername,omitempty"`
+	ContainerRegistryUsername      *string                 `gorm:"type:text;column:containerRegistryUsername" json:"containerRegistryUsername,omitempty"`
 	ContainerRegistryPassword      *string                 `gorm:"type:text;column:containerRegistryPassword" json:"-"`
 	InstanceTypeID                 string                  `gorm:"index;size:191;column:instanceTypeId" json:"instanceTypeId"`
 	InstanceTypeChangedAt          *time.Time              `gorm:"column:instanceTypeChangedAt" json:"instanceTypeChangedAt,omitempty"`
@@ -40,6 +46,15 @@ type Service struct {
 	StorageClass                   *string                 `gorm:"size:191;column:storageClass" json:"storageClass,omitempty"`
 	StorageUsage                   *float64                `gorm:"column:storageUsage" json:"storageUsage,omitempty"`
 	ContainerCommand               *string                 `gorm:"type:text;column:containerCommand" json:"containerCommand,omitempty"`
+	ContainerArgs                  JSON                    `gorm:"type:json;column:containerArgs" json:"containerArgs,omitempty"`
+	IngressPort                    *int                    `gorm:"uniqueIndex;column:ingressPort" json:"ingressPort,omitempty"`
+	Region                         *string                 `gorm:"size:191;column:region" json:"region,omitempty"`
+	Version                        int                     `gorm:"default:0;column:version" json:"version"`
+	Stopped                        bool                    `gorm:"default:false;column:stopped" json:"stopped"`
+	DeployTokenHash                *string                 `gorm:"size:191;column:deployTokenHash" json:"-"`
+	TunnelEnabled                  bool                    `gorm:"default:false;column:tunnelEnabled" json:"tunnelEnabled"`
+	TunnelTokenHash                *string                 `gorm:"size:191;column:tunnelTokenHash" json:"-"`
+	CustomDomainVerifiedAt         *time.Time              `gorm:"column:customDomainVerifiedAt" json:"customDomainVerifiedAt,omitempty"`
 	ScalingStatus                  ServiceScalingStatus    `gorm:"size:191;default:IDLE;column:scalingStatus" json:"scalingStatus"`
 	Deployments                    []Deployment            `gorm:"foreignKey:ServiceID" json:"deployments,omitempty"`
 	Ports                          []ServicePort           `gorm:"foreignKey:ServiceID" json:"ports,omitempty"`
@@ -70,6 +85,10 @@ type ServiceCredential struct {
 	CreatedAt time.Time `gorm:"autoCreateTime;column:createdAt" json:"createdAt"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime;column:updatedAt" json:"updatedAt"`
 	Service   Service   `gorm:"foreignKey:ServiceID" json:"service,omitempty"`
+
+	// PendingPassword is the password a rotation changes the server to. It replaces Password
+	// once Kubestrator confirms the server uses it.
+	PendingPassword *string `gorm:"size:191;column:pendingPassword" json:"-"`
 }
 
 func (ServiceCredential) TableName() string {
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type ServiceBackup struct {
+	ID           string       `gorm:"primaryKey;size:191;column:id" json:"id"`
+	ServiceID    string       `gorm:"index;size:191;column:serviceId" json:"serviceId"`
+	Status       BackupStatus `gorm:"size:191;default:PENDING;column:status" json:"status"`
+	SnapshotName string       `gorm:"size:191;column:snapshotName" json:"snapshotName"`
+	SizeBytes    *int64       `gorm:"column:sizeBytes" json:"sizeBytes,omitempty"`
+	Error        *string      `gorm:"type:text;column:error" json:"error,omitempty"`
+	TriggeredBy  *string      `gorm:"size:191;column:triggeredBy" json:"triggeredBy,omitempty"`
+	CreatedAt    time.Time    `gorm:"autoCreateTime;column:createdAt" json:"createdAt"`
+	UpdatedAt    time.Time    `gorm:"autoUpdateTime;column:updatedAt" json:"updatedAt"`
+	CompletedAt  *time.Time   `gorm:"column:completedAt" json:"completedAt,omitempty"`
+	Service      Service      `gorm:"foreignKey:ServiceID" json:"service,omitempty"`
+}
+
+func (ServiceBackup) TableName() string {
+	return "ServiceBackup"
+}
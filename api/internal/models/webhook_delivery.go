@@ -0,0 +1,25 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookDelivery is an event sent to the webhook of a project, and how the receiver answered
+type WebhookDelivery struct {
+	ID         string          `gorm:"primaryKey;size:191;column:id" json:"id"`
+	ProjectID  string          `gorm:"index;size:191;column:projectId" json:"projectId"`
+	Event      string          `gorm:"size:191;column:event" json:"event"`
+	URL        string          `gorm:"type:text;column:url" json:"url"`
+	Payload    json.RawMessage `gorm:"type:json;column:payload" json:"payload"`
+	StatusCode *int            `gorm:"column:statusCode" json:"statusCode,omitempty"`
+	Error      *string         `gorm:"type:text;column:error" json:"error,omitempty"`
+	DurationMs *int64          `gorm:"column:durationMs" json:"durationMs,omitempty"`
+	ReplayOfID *string         `gorm:"size:191;column:replayOfId" json:"replayOfId,omitempty"`
+	CreatedAt  time.Time       `gorm:"autoCreateTime;column:createdAt" json:"createdAt"`
+	Project    Project         `gorm:"foreignKey:ProjectID" json:"-"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "WebhookDelivery"
+}
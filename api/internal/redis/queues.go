@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// QueueDepth is the number of jobs waiting in a queue and when the oldest one was queued
+type QueueDepth struct {
+	Length int64 `json:"length"`
+	// OldestEnqueuedAt is nil when the queue is empty or its oldest job predates enqueuedAt
+	OldestEnqueuedAt *time.Time `json:"oldestEnqueuedAt"`
+}
+
+// GetQueueDepth returns the depth of a queue. Jobs are pushed on the right, so the oldest
+// one is at the head.
+func GetQueueDepth(ctx context.Context, queue string) (QueueDepth, error) {
+	var depth QueueDepth
+
+	length, err := client.LLen(ctx, queue).Result()
+	if err != nil {
+		return depth, err
+	}
+	depth.Length = length
+	if length == 0 {
+		return depth, nil
+	}
+
+	data, err := client.LIndex(ctx, queue, 0).Bytes()
+	if err == redis.Nil {
+		// Drained since LLEN
+		return depth, nil
+	}
+	if err != nil {
+		return depth, err
+	}
+
+	var job struct {
+		EnqueuedAt time.Time `json:"enqueuedAt"`
+	}
+	if err := json.Unmarshal(data, &job); err == nil && !job.EnqueuedAt.IsZero() {
+		depth.OldestEnqueuedAt = &job.EnqueuedAt
+	}
+
+	return depth, nil
+}
+
+// GetDeferredBuilderQueueLengths returns the number of held builds of every organization
+// that has any, keyed by organization ID
+func GetDeferredBuilderQueueLengths(ctx context.Context) (map[string]int64, error) {
+	prefix := DeferredBuilderQueue("")
+	lengths := make(map[string]int64)
+
+	iter := client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		length, err := client.LLen(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		if length > 0 {
+			lengths[strings.TrimPrefix(key, prefix)] = length
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return lengths, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -56,6 +57,14 @@ func GetClient() *redis.Client {
 	return client
 }
 
+// Close closes the Redis client
+func Close() error {
+	if client == nil {
+		return nil
+	}
+	return client.Close()
+}
+
 // RemoveDeploymentFromQueue removes a deployment from the queue
 func RemoveDeploymentFromQueue(ctx context.Context, queue string, deploymentID string) (bool, error) {
 	// Use Lua script for atomicity and efficiency
@@ -116,9 +125,13 @@ type BuilderJob struct {
 	Branch               string                 `json:"branch"`
 	RepositoryName       string                 `json:"repositoryName"`
 	RuntimeFilePath      *string                `json:"runtimeFilePath,omitempty"`
+	BuildContextPath     *string                `json:"buildContextPath,omitempty"`
 	GitProvider          *BuilderGitProvider    `json:"gitProvider,omitempty"`
 	EnvironmentVariables []EnvironmentVariable  `json:"environmentVariables,omitempty"`
 	Ports                []Port                 `json:"ports,omitempty"`
+
+	// EnqueuedAt is set when the job is queued, so the age of a queue can be reported
+	EnqueuedAt time.Time `json:"enqueuedAt"`
 }
 
 type BuilderGitProvider struct {
@@ -156,6 +169,31 @@ type DeploymentJob struct {
 	Domains              []string            `json:"domains,omitempty"`
 	ScaleToZeroEnabled   bool                `json:"scaleToZeroEnabled"`
 	Command              []string            `json:"command,omitempty"`
+	Args                 []string            `json:"args,omitempty"`
+	IngressPort          int                 `json:"ingressPort,omitempty"`
+
+	// Redirect sends a second host of the service to its primary domain
+	Redirect *Redirect `json:"redirect,omitempty"`
+
+	// Tunnel opens the service to HTTP CONNECT tunnels through the web proxy
+	Tunnel *Tunnel `json:"tunnel,omitempty"`
+
+	// Region is the region of the service, its deployment queue is the one of the region
+	Region string `json:"region,omitempty"`
+
+	// EnqueuedAt is set when the job is queued
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+}
+
+// Redirect is a host the web proxy answers with a 301 to another one
+type Redirect struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Tunnel holds the hash of the token tunnel clients authenticate with
+type Tunnel struct {
+	TokenHash string `json:"tokenHash"`
 }
 
 type EnvironmentVariable struct {
@@ -173,6 +211,9 @@ type ContainerRegistry struct {
 	ImageUri string `json:"imageUri"`
 	Username string `json:"username,omitempty"`
 	Password string `json:"password,omitempty"`
+
+	// PullSecretName is set when the API already created the pull secret of the registry
+	PullSecretName string `json:"pullSecretName,omitempty"`
 }
 
 type Scaling struct {
@@ -216,6 +257,7 @@ type Credentials struct {
 
 // AddToBuilderQueue adds a job to the builder queue
 func AddToBuilderQueue(ctx context.Context, job BuilderJob) error {
+	job.EnqueuedAt = time.Now()
 	data, err := json.Marshal(job)
 	if err != nil {
 		return fmt.Errorf("failed to marshal builder job: %w", err)
@@ -224,14 +266,68 @@ func AddToBuilderQueue(ctx context.Context, job BuilderJob) error {
 	return client.RPush(ctx, QueueBuilder, data).Err()
 }
 
+// DeferredBuilderQueue returns the queue holding the builds of an organization that wait
+// for one of its running builds to finish
+func DeferredBuilderQueue(organizationID string) string {
+	return QueueBuilder + ":deferred:" + organizationID
+}
+
+// AddToDeferredBuilderQueue holds a builder job until the organization has a free build slot
+func AddToDeferredBuilderQueue(ctx context.Context, organizationID string, job BuilderJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal builder job: %w", err)
+	}
+
+	return client.RPush(ctx, DeferredBuilderQueue(organizationID), data).Err()
+}
+
+// PopDeferredBuilderJob takes the oldest held builder job of an organization, nil if there is none
+func PopDeferredBuilderJob(ctx context.Context, organizationID string) (*BuilderJob, error) {
+	data, err := client.LPop(ctx, DeferredBuilderQueue(organizationID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job BuilderJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal builder job: %w", err)
+	}
+	return &job, nil
+}
+
 // AddToDeploymentQueue adds a job to the deployment queue
 func AddToDeploymentQueue(ctx context.Context, job DeploymentJob) error {
+	job.EnqueuedAt = time.Now()
 	data, err := json.Marshal(job)
 	if err != nil {
 		return fmt.Errorf("failed to marshal deployment job: %w", err)
 	}
 
-	return client.RPush(ctx, QueueDeployment, data).Err()
+	return client.RPush(ctx, DeploymentQueue(job.Region), data).Err()
+}
+
+// DeploymentQueue returns the deployment queue of a region. The default region keeps
+// QueueDeployment, so single cluster setups and services without a region don't move.
+func DeploymentQueue(region string) string {
+	if region == "" || region == config.Get().DefaultRegion() {
+		return QueueDeployment
+	}
+	return QueueDeployment + ":" + region
+}
+
+// DeploymentQueues returns the deployment queues of every configured region
+func DeploymentQueues() []string {
+	queues := []string{QueueDeployment}
+	for _, region := range config.Get().Regions {
+		if queue := DeploymentQueue(region); queue != QueueDeployment {
+			queues = append(queues, queue)
+		}
+	}
+	return queues
 }
 
 // AcquireLock tries to acquire a distributed lock with optional TTL (default 30 seconds)
@@ -254,11 +350,297 @@ func ReleaseLock(ctx context.Context, key string) error {
 	return client.Del(ctx, key).Err()
 }
 
+// AcquireLeadership makes holder the leader of key for ttl, or extends its term when it
+// already leads. Returns whether holder leads, another instance does until its term expires.
+func AcquireLeadership(ctx context.Context, key string, holder string, ttl time.Duration) (bool, error) {
+	luaScript := `
+		if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+			return 1
+		end
+		if redis.call('GET', KEYS[1]) == ARGV[1] then
+			redis.call('PEXPIRE', KEYS[1], ARGV[2])
+			return 1
+		end
+		return 0
+	`
+
+	result, err := client.Eval(ctx, luaScript, []string{key}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire leadership: %w", err)
+	}
+
+	return result == 1, nil
+}
+
+// ReleaseLeadership ends the term of holder so another instance can lead right away
+func ReleaseLeadership(ctx context.Context, key string, holder string) error {
+	luaScript := `
+		if redis.call('GET', KEYS[1]) == ARGV[1] then
+			return redis.call('DEL', KEYS[1])
+		end
+		return 0
+	`
+
+	return client.Eval(ctx, luaScript, []string{key}, holder).Err()
+}
+
+// TakeRateLimitToken takes a token from the bucket stored at key. The bucket holds up to
+// capacity tokens and refills at capacity tokens per window. Returns whether the request is
+// allowed and, if not, how long until a token is available.
+func TakeRateLimitToken(ctx context.Context, key string, capacity int, window time.Duration) (bool, time.Duration, error) {
+	luaScript := `
+		local capacity = tonumber(ARGV[1])
+		local rate = tonumber(ARGV[2])
+		local now = tonumber(ARGV[3])
+
+		local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+		local tokens = tonumber(bucket[1]) or capacity
+		local ts = tonumber(bucket[2]) or now
+
+		tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+		local allowed = 0
+		local retryAfter = 0
+		if tokens >= 1 then
+			tokens = tokens - 1
+			allowed = 1
+		else
+			retryAfter = math.ceil((1 - tokens) / rate)
+		end
+
+		redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+		redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate))
+
+		return {allowed, retryAfter}
+	`
+
+	rate := float64(capacity) / float64(window.Milliseconds())
+	now := time.Now().UnixMilli()
+
+	result, err := client.Eval(ctx, luaScript, []string{key}, capacity, rate, now).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// IdempotentResponse is a stored response replayed for repeated requests with the same Idempotency-Key
+type IdempotentResponse struct {
+	InProgress  bool   `json:"inProgress"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// ReserveIdempotencyKey marks an idempotency key as in progress. Returns false if the key
+// has already been used, in which case the stored response should be read instead.
+func ReserveIdempotencyKey(ctx context.Context, key string, method string, path string, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(IdempotentResponse{InProgress: true, Method: method, Path: path})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	result, err := client.SetNX(ctx, key, data, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetIdempotentResponse returns the record stored for an idempotency key, or nil if there is none
+func GetIdempotentResponse(ctx context.Context, key string) (*IdempotentResponse, error) {
+	data, err := client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	var record IdempotentResponse
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+
+	return &record, nil
+}
+
+// SaveIdempotentResponse stores the final response for an idempotency key
+func SaveIdempotentResponse(ctx context.Context, key string, record IdempotentResponse, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	return client.Set(ctx, key, data, ttl).Err()
+}
+
+// ScheduleDebounce stores the value of a debounced action under key, replacing any previous
+// one, and makes it due at due in the set of pending actions. Scheduling it again before it
+// is due postpones it. The value expires after ttl if it is never claimed.
+func ScheduleDebounce(ctx context.Context, set string, key string, value string, due time.Time, ttl time.Duration) error {
+	pipe := client.TxPipeline()
+	pipe.Set(ctx, key, value, ttl)
+	pipe.ZAdd(ctx, set, redis.Z{Score: float64(due.UnixMilli()), Member: key})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to schedule debounced action: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueDebounces atomically removes the actions of a set that are due by now and returns
+// their values. Every action is claimed by a single caller, also across API instances.
+func ClaimDueDebounces(ctx context.Context, set string, now time.Time) ([]string, error) {
+	luaScript := `
+		local keys = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+		local values = {}
+		for _, key in ipairs(keys) do
+			redis.call('ZREM', KEYS[1], key)
+			local value = redis.call('GET', key)
+			if value then
+				redis.call('DEL', key)
+				table.insert(values, value)
+			end
+		end
+		return values
+	`
+
+	values, err := client.Eval(ctx, luaScript, []string{set}, now.UnixMilli()).StringSlice()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to claim debounced actions: %w", err)
+	}
+	return values, nil
+}
+
+// deploymentLogBytesKey holds the size in bytes of the stored logs of a deployment
+func deploymentLogBytesKey(deploymentID string) string {
+	return "deployment:log-bytes:" + deploymentID
+}
+
+// AddDeploymentLogBytes adds n bytes to the stored log size of a deployment and returns the
+// new size. A result equal to n means the counter was missing and may need to be set from
+// the database with SetDeploymentLogBytes.
+func AddDeploymentLogBytes(ctx context.Context, deploymentID string, n int64, ttl time.Duration) (int64, error) {
+	key := deploymentLogBytesKey(deploymentID)
+
+	pipe := client.TxPipeline()
+	incr := pipe.IncrBy(ctx, key, n)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to count deployment log size: %w", err)
+	}
+
+	return incr.Val(), nil
+}
+
+// SetDeploymentLogBytes sets the stored log size of a deployment
+func SetDeploymentLogBytes(ctx context.Context, deploymentID string, size int64, ttl time.Duration) error {
+	return client.Set(ctx, deploymentLogBytesKey(deploymentID), size, ttl).Err()
+}
+
+// TrafficKey holds the bytes the web proxy served for a service in a month (UTC)
+func TrafficKey(serviceID string, month time.Time) string {
+	return "service:traffic:" + serviceID + ":" + month.UTC().Format("2006-01")
+}
+
+// GetMonthlyTraffic returns the bytes the web proxy served for the services in a month
+func GetMonthlyTraffic(ctx context.Context, serviceIDs []string, month time.Time) (int64, error) {
+	if len(serviceIDs) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, len(serviceIDs))
+	for i, serviceID := range serviceIDs {
+		keys[i] = TrafficKey(serviceID, month)
+	}
+	values, err := client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get service traffic: %w", err)
+	}
+
+	var total int64
+	for _, value := range values {
+		// Services without traffic this month have no key
+		if s, ok := value.(string); ok {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid traffic counter %q: %w", s, err)
+			}
+			total += n
+		}
+	}
+	return total, nil
+}
+
+func lastScaleKey(serviceID string) string {
+	return "service:last-scale:" + serviceID
+}
+
+// SetLastScaleAt stores when a service last started or finished scaling
+func SetLastScaleAt(ctx context.Context, serviceID string, at time.Time, ttl time.Duration) error {
+	return client.Set(ctx, lastScaleKey(serviceID), at.Unix(), ttl).Err()
+}
+
+// GetLastScaleAt returns when a service last started or finished scaling, the zero time if
+// it is not known
+func GetLastScaleAt(ctx context.Context, serviceID string) (time.Time, error) {
+	seconds, err := client.Get(ctx, lastScaleKey(serviceID)).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+func reapCleanupKey(serviceID string) string {
+	return "service:reap-cleanup:" + serviceID
+}
+
+// ReapCleanup is how often the reaper queued the Kubernetes cleanup of a deleted service, and
+// when it last did
+type ReapCleanup struct {
+	Attempts int       `json:"attempts"`
+	LastAt   time.Time `json:"lastAt"`
+}
+
+// SetReapCleanup stores the Kubernetes cleanups the reaper queued for a deleted service
+func SetReapCleanup(ctx context.Context, serviceID string, cleanup ReapCleanup, ttl time.Duration) error {
+	data, err := json.Marshal(cleanup)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reap cleanup: %w", err)
+	}
+	return client.Set(ctx, reapCleanupKey(serviceID), data, ttl).Err()
+}
+
+// GetReapCleanup returns the Kubernetes cleanups the reaper queued for a deleted service, none
+// if it didn't queue any
+func GetReapCleanup(ctx context.Context, serviceID string) (ReapCleanup, error) {
+	var cleanup ReapCleanup
+	data, err := client.Get(ctx, reapCleanupKey(serviceID)).Bytes()
+	if err == redis.Nil {
+		return cleanup, nil
+	}
+	if err != nil {
+		return cleanup, err
+	}
+	if err := json.Unmarshal(data, &cleanup); err != nil {
+		return cleanup, fmt.Errorf("failed to unmarshal reap cleanup: %w", err)
+	}
+	return cleanup, nil
+}
+
 // CronJobEvent represents a cronjob event payload for Redis
 type CronJobEvent struct {
 	ID        string            `json:"id"`
 	Name      string            `json:"name"`
 	Schedule  string            `json:"schedule"`
+	Timezone  string            `json:"timezone"`
 	Path      string            `json:"path"`
 	Headers   map[string]string `json:"headers,omitempty"`
 	Enabled   bool              `json:"enabled"`
@@ -309,14 +691,83 @@ type ControllerJob struct {
 	ServiceID string `json:"serviceId"`
 	ProjectID string `json:"projectId"`
 	Action    string `json:"action"`
+
+	// Replicas is the replica count of the "scale" action
+	Replicas *int `json:"replicas,omitempty"`
+
+	// Credentials and PreviousPassword are the new and current credentials of the
+	// "rotate-credentials" action
+	Credentials      *Credentials `json:"credentials,omitempty"`
+	PreviousPassword string       `json:"previousPassword,omitempty"`
+
+	// Region is the region of the service
+	Region string `json:"region,omitempty"`
+
+	// EnqueuedAt is set when the job is queued
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+}
+
+// ProjectDeletionJob represents a job for removing every Kubernetes resource of a project
+type ProjectDeletionJob struct {
+	Type           string `json:"type"`
+	ProjectID      string `json:"projectId"`
+	OrganizationID string `json:"organizationId"`
+
+	// EnqueuedAt is set when the job is queued
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+}
+
+// AddToProjectDeletionQueue adds a project deletion job to the deployment queue of every
+// region, a project has a namespace in each cluster it has services in
+func AddToProjectDeletionQueue(ctx context.Context, job ProjectDeletionJob) error {
+	job.EnqueuedAt = time.Now()
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal project deletion job: %w", err)
+	}
+
+	for _, queue := range DeploymentQueues() {
+		if err := client.RPush(ctx, queue, data).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackupJob represents a job for snapshotting the volume of a database service
+type BackupJob struct {
+	Type         string `json:"type"`
+	BackupID     string `json:"backupId"`
+	ServiceID    string `json:"serviceId"`
+	ProjectID    string `json:"projectId"`
+	ServiceType  string `json:"serviceType"`
+	SnapshotName string `json:"snapshotName"`
+
+	// Region is the region of the service
+	Region string `json:"region,omitempty"`
+
+	// EnqueuedAt is set when the job is queued
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+}
+
+// AddToBackupQueue adds a backup job to the deployment queue
+func AddToBackupQueue(ctx context.Context, job BackupJob) error {
+	job.EnqueuedAt = time.Now()
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup job: %w", err)
+	}
+
+	return client.RPush(ctx, DeploymentQueue(job.Region), data).Err()
 }
 
 // AddToControllerQueue adds a controller job to the deployment queue
 func AddToControllerQueue(ctx context.Context, job ControllerJob) error {
+	job.EnqueuedAt = time.Now()
 	data, err := json.Marshal(job)
 	if err != nil {
 		return fmt.Errorf("failed to marshal controller job: %w", err)
 	}
 
-	return client.RPush(ctx, QueueDeployment, data).Err()
+	return client.RPush(ctx, DeploymentQueue(job.Region), data).Err()
 }
@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+)
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	config.Load()
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+func TestGetMonthlyTraffic(t *testing.T) {
+	redisServer.FlushAll()
+	month := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	// Keys the web proxy writes, one of another month
+	redisServer.Set("service:traffic:web-1:2026-10", "1500")
+	redisServer.Set("service:traffic:web-2:2026-10", "500")
+	redisServer.Set("service:traffic:web-1:2026-09", "9000")
+
+	total, err := GetMonthlyTraffic(context.Background(), []string{"web-1", "web-2", "db-1"}, month)
+	if err != nil {
+		t.Fatalf("GetMonthlyTraffic: %v", err)
+	}
+	if total != 2000 {
+		t.Errorf("traffic = %d, want 2000", total)
+	}
+
+	if total, err := GetMonthlyTraffic(context.Background(), nil, month); err != nil || total != 0 {
+		t.Errorf("traffic of no services = %d, %v, want 0", total, err)
+	}
+}
+
+func TestReapCleanupRoundTrip(t *testing.T) {
+	redisServer.FlushAll()
+	ctx := context.Background()
+
+	cleanup, err := GetReapCleanup(ctx, "svc")
+	if err != nil || cleanup.Attempts != 0 {
+		t.Fatalf("GetReapCleanup before any attempt = %+v, %v, want none", cleanup, err)
+	}
+
+	at := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	if err := SetReapCleanup(ctx, "svc", ReapCleanup{Attempts: 2, LastAt: at}, time.Hour); err != nil {
+		t.Fatalf("SetReapCleanup: %v", err)
+	}
+	cleanup, err = GetReapCleanup(ctx, "svc")
+	if err != nil {
+		t.Fatalf("GetReapCleanup: %v", err)
+	}
+	if cleanup.Attempts != 2 || !cleanup.LastAt.Equal(at) {
+		t.Errorf("GetReapCleanup = %+v, want 2 attempts, the last at %s", cleanup, at)
+	}
+	if ttl := redisServer.TTL("service:reap-cleanup:svc"); ttl != time.Hour {
+		t.Errorf("TTL = %s, want 1h", ttl)
+	}
+}
+
+// useRegions configures regions for the test
+func useRegions(t *testing.T, regions ...string) {
+	t.Helper()
+	cfg := config.Get()
+	previous := cfg.Regions
+	cfg.Regions = regions
+	t.Cleanup(func() { cfg.Regions = previous })
+}
+
+// queuedJobs returns the region of every deployment job in a queue
+func queuedJobs(t *testing.T, queue string) []string {
+	t.Helper()
+	if !redisServer.Exists(queue) {
+		return nil
+	}
+	items, err := redisServer.List(queue)
+	if err != nil {
+		t.Fatalf("list %s: %v", queue, err)
+	}
+	regions := make([]string, len(items))
+	for i, item := range items {
+		var job DeploymentJob
+		if err := json.Unmarshal([]byte(item), &job); err != nil {
+			t.Fatalf("job %s: %v", item, err)
+		}
+		regions[i] = job.Region
+	}
+	return regions
+}
+
+func TestDeploymentQueue(t *testing.T) {
+	useRegions(t, "eu-west", "us-east")
+
+	// The default region keeps the queue of single cluster setups
+	for region, want := range map[string]string{
+		"":        QueueDeployment,
+		"eu-west": QueueDeployment,
+		"us-east": QueueDeployment + ":us-east",
+	} {
+		if got := DeploymentQueue(region); got != want {
+			t.Errorf("DeploymentQueue(%q) = %q, want %q", region, got, want)
+		}
+	}
+	if got, want := DeploymentQueues(), []string{QueueDeployment, QueueDeployment + ":us-east"}; !slices.Equal(got, want) {
+		t.Errorf("DeploymentQueues = %q, want %q", got, want)
+	}
+}
+
+func TestDeploymentQueueWithoutRegions(t *testing.T) {
+	useRegions(t)
+
+	if got := DeploymentQueue(""); got != QueueDeployment {
+		t.Errorf("DeploymentQueue = %q, want %q", got, QueueDeployment)
+	}
+	if got := DeploymentQueues(); !slices.Equal(got, []string{QueueDeployment}) {
+		t.Errorf("DeploymentQueues = %q, want only %q", got, QueueDeployment)
+	}
+}
+
+func TestJobsTargetQueueOfRegion(t *testing.T) {
+	redisServer.FlushAll()
+	useRegions(t, "eu-west", "us-east")
+	ctx := context.Background()
+
+	for _, region := range []string{"us-east", "eu-west", ""} {
+		if err := AddToDeploymentQueue(ctx, DeploymentJob{ServiceID: "svc", Region: region}); err != nil {
+			t.Fatalf("AddToDeploymentQueue: %v", err)
+		}
+	}
+	if err := AddToControllerQueue(ctx, ControllerJob{ServiceID: "svc", Region: "us-east"}); err != nil {
+		t.Fatalf("AddToControllerQueue: %v", err)
+	}
+	if err := AddToBackupQueue(ctx, BackupJob{ServiceID: "svc", Region: "us-east"}); err != nil {
+		t.Fatalf("AddToBackupQueue: %v", err)
+	}
+
+	if got, want := queuedJobs(t, QueueDeployment), []string{"eu-west", ""}; !slices.Equal(got, want) {
+		t.Errorf("jobs of %s = %q, want the ones of the default region", QueueDeployment, got)
+	}
+	if got, want := queuedJobs(t, QueueDeployment+":us-east"), []string{"us-east", "us-east", "us-east"}; !slices.Equal(got, want) {
+		t.Errorf("jobs of the us-east queue = %q, want %q", got, want)
+	}
+}
+
+func TestProjectDeletionTargetsEveryRegion(t *testing.T) {
+	redisServer.FlushAll()
+	useRegions(t, "eu-west", "us-east")
+
+	if err := AddToProjectDeletionQueue(context.Background(), ProjectDeletionJob{ProjectID: "proj"}); err != nil {
+		t.Fatalf("AddToProjectDeletionQueue: %v", err)
+	}
+	for _, queue := range []string{QueueDeployment, QueueDeployment + ":us-east"} {
+		if got := queuedJobs(t, queue); len(got) != 1 {
+			t.Errorf("%s holds %d jobs, want the project deletion", queue, len(got))
+		}
+	}
+}
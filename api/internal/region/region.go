@@ -0,0 +1,64 @@
+package region
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+// Enabled reports whether regions are configured, without them every service runs in the one
+// cluster
+func Enabled() bool {
+	return len(config.Get().Regions) > 0
+}
+
+// All returns every configured region, "" for the one cluster when regions aren't enabled
+func All() []string {
+	if !Enabled() {
+		return []string{""}
+	}
+	return config.Get().Regions
+}
+
+// Validate returns an error when a region isn't one of the configured regions
+func Validate(name string) error {
+	regions := config.Get().Regions
+	if len(regions) == 0 {
+		return errors.New("Regions are not enabled")
+	}
+	if !slices.Contains(regions, name) {
+		return fmt.Errorf("Unknown region: %s. Must be one of: %s", name, strings.Join(regions, ", "))
+	}
+	return nil
+}
+
+// Resolve returns the region a new service of an organization runs in: the requested region,
+// else the organization's default region, else the configured default. The organization's
+// region is skipped when it is no longer configured. Nil when regions aren't enabled.
+func Resolve(requested *string, organization models.Organization) (*string, error) {
+	if requested != nil && *requested != "" {
+		if err := Validate(*requested); err != nil {
+			return nil, err
+		}
+		return requested, nil
+	}
+
+	if !Enabled() {
+		return nil, nil
+	}
+	if organization.DefaultRegion != nil && Validate(*organization.DefaultRegion) == nil {
+		return utils.Ptr(*organization.DefaultRegion), nil
+	}
+	return utils.Ptr(config.Get().DefaultRegion()), nil
+}
+
+// Of returns the region a service runs in, the default region for services created before
+// regions were enabled
+func Of(service models.Service) string {
+	return utils.PtrValue(service.Region, config.Get().DefaultRegion())
+}
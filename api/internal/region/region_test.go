@@ -0,0 +1,111 @@
+package region
+
+import (
+	"os"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	config.Load()
+	os.Exit(m.Run())
+}
+
+// useRegions configures regions for the test
+func useRegions(t *testing.T, regions ...string) {
+	t.Helper()
+	cfg := config.Get()
+	previous := cfg.Regions
+	cfg.Regions = regions
+	t.Cleanup(func() { cfg.Regions = previous })
+}
+
+func TestValidate(t *testing.T) {
+	useRegions(t, "eu-west", "us-east")
+
+	for _, name := range []string{"eu-west", "us-east"} {
+		if err := Validate(name); err != nil {
+			t.Errorf("Validate(%q) = %v, want it valid", name, err)
+		}
+	}
+	if err := Validate("ap-south"); err == nil || err.Error() != "Unknown region: ap-south. Must be one of: eu-west, us-east" {
+		t.Errorf("Validate of an unknown region = %v, want the configured regions", err)
+	}
+	if err := Validate("EU-WEST"); err == nil {
+		t.Error("regions matched regardless of case")
+	}
+}
+
+func TestValidateWithoutRegions(t *testing.T) {
+	useRegions(t)
+
+	if err := Validate("eu-west"); err == nil || err.Error() != "Regions are not enabled" {
+		t.Errorf("Validate = %v, want regions reported as not enabled", err)
+	}
+	if Enabled() {
+		t.Error("regions enabled without any configured")
+	}
+	if all := All(); len(all) != 1 || all[0] != "" {
+		t.Errorf("All = %q, want the one cluster", all)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	useRegions(t, "eu-west", "us-east")
+	organization := models.Organization{DefaultRegion: utils.Ptr("us-east")}
+
+	tests := []struct {
+		name         string
+		requested    *string
+		organization models.Organization
+		want         string
+	}{
+		{name: "requested", requested: utils.Ptr("eu-west"), organization: organization, want: "eu-west"},
+		{name: "organization default", organization: organization, want: "us-east"},
+		{name: "empty request", requested: utils.Ptr(""), organization: organization, want: "us-east"},
+		{name: "configured default", organization: models.Organization{}, want: "eu-west"},
+		// A region that was removed from the configuration falls back to the default
+		{name: "removed organization default", organization: models.Organization{DefaultRegion: utils.Ptr("ap-south")}, want: "eu-west"},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.requested, tt.organization)
+		if err != nil {
+			t.Errorf("%s: Resolve: %v", tt.name, err)
+			continue
+		}
+		if utils.PtrValue(got, "") != tt.want {
+			t.Errorf("%s: Resolve = %q, want %q", tt.name, utils.PtrValue(got, ""), tt.want)
+		}
+	}
+
+	if _, err := Resolve(utils.Ptr("ap-south"), organization); err == nil {
+		t.Error("resolved an unknown requested region")
+	}
+}
+
+func TestResolveWithoutRegions(t *testing.T) {
+	useRegions(t)
+
+	got, err := Resolve(nil, models.Organization{DefaultRegion: utils.Ptr("eu-west")})
+	if err != nil || got != nil {
+		t.Errorf("Resolve = %v, %v, want no region", got, err)
+	}
+	if _, err := Resolve(utils.Ptr("eu-west"), models.Organization{}); err == nil {
+		t.Error("resolved a requested region without regions enabled")
+	}
+}
+
+func TestOf(t *testing.T) {
+	useRegions(t, "eu-west", "us-east")
+
+	if got := Of(models.Service{Region: utils.Ptr("us-east")}); got != "us-east" {
+		t.Errorf("Of = %q, want the service's region", got)
+	}
+	// Services created before regions were enabled run in the default region
+	if got := Of(models.Service{}); got != "eu-west" {
+		t.Errorf("Of = %q, want the default region", got)
+	}
+}
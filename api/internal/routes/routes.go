@@ -3,18 +3,22 @@ package routes
 import (
 	"github.com/deployra/deployra/api/internal/config"
 	"github.com/deployra/deployra/api/internal/handlers/account"
+	"github.com/deployra/deployra/api/internal/handlers/admin"
 	"github.com/deployra/deployra/api/internal/handlers/apikeys"
 	"github.com/deployra/deployra/api/internal/handlers/auth"
 	"github.com/deployra/deployra/api/internal/handlers/callback"
 	"github.com/deployra/deployra/api/internal/handlers/deployments"
 	"github.com/deployra/deployra/api/internal/handlers/docker"
+	"github.com/deployra/deployra/api/internal/handlers/domains"
 	githubHandlers "github.com/deployra/deployra/api/internal/handlers/github"
 	"github.com/deployra/deployra/api/internal/handlers/gitproviders"
+	"github.com/deployra/deployra/api/internal/handlers/health"
 	"github.com/deployra/deployra/api/internal/handlers/instancetypegroups"
 	"github.com/deployra/deployra/api/internal/handlers/instancetypes"
 	"github.com/deployra/deployra/api/internal/handlers/organizations"
 	"github.com/deployra/deployra/api/internal/handlers/projects"
 	"github.com/deployra/deployra/api/internal/handlers/services"
+	servicebackups "github.com/deployra/deployra/api/internal/handlers/services/backups"
 	servicescreate "github.com/deployra/deployra/api/internal/handlers/services/create"
 	servicecronjobs "github.com/deployra/deployra/api/internal/handlers/services/cronjobs"
 	serviceenvvars "github.com/deployra/deployra/api/internal/handlers/services/envvars"
@@ -22,6 +26,7 @@ import (
 	servicestemplate "github.com/deployra/deployra/api/internal/handlers/services/template"
 	"github.com/deployra/deployra/api/internal/handlers/servicetypes"
 	"github.com/deployra/deployra/api/internal/handlers/templates"
+	webhookbackups "github.com/deployra/deployra/api/internal/handlers/webhooks/backups"
 	webhookcronjobs "github.com/deployra/deployra/api/internal/handlers/webhooks/cronjobs"
 	webhookdeployments "github.com/deployra/deployra/api/internal/handlers/webhooks/deployments"
 	webhookecr "github.com/deployra/deployra/api/internal/handlers/webhooks/ecr"
@@ -38,6 +43,17 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "ok"})
 	})
+	app.Get("/livez", health.Live)
+	app.Get("/readyz", health.Ready)
+
+	// Rate limiters (per user on protected routes, per IP otherwise)
+	apiLimiter := middleware.RateLimitMiddleware("api", cfg.RateLimitPerMinute)
+	deployLimiter := middleware.RateLimitMiddleware("deploy", cfg.DeployRateLimit)
+	// Checking a deployment doesn't use up the deploys that follow it
+	validateLimiter := middleware.RateLimitMiddleware("validate", cfg.DeployRateLimit)
+
+	// Replays responses for retried requests with an Idempotency-Key header
+	idempotency := middleware.IdempotencyMiddleware(cfg.IdempotencyTTL)
 
 	api := app.Group("/api")
 
@@ -45,13 +61,17 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	api.Use("/socket", wshandler.UpgradeMiddleware)
 	api.Get("/socket", websocket.New(wshandler.Handler))
 
+	// Pod shell (authenticated with a token query param, disabled unless POD_EXEC_ENABLED)
+	api.Use("/services/:serviceId/pods/:podId/exec", wshandler.ExecUpgradeMiddleware)
+	api.Get("/services/:serviceId/pods/:podId/exec", websocket.New(wshandler.ExecHandler))
+
 	authPublic := api.Group("/auth")
 	{
 		// Auth - public
-		authPublic.Post("/login", auth.Login)
+		authPublic.Post("/login", apiLimiter, auth.Login)
 
 		// Auth - protected (JWT)
-		authPublic.Get("/user", middleware.AuthMiddleware(cfg), auth.GetUser)
+		authPublic.Get("/user", middleware.AuthMiddleware(cfg), apiLimiter, auth.GetUser)
 	}
 
 	// Callback (no auth)
@@ -61,7 +81,7 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	}
 
 	// Templates (no auth)
-	templatesRoutes := api.Group("/templates")
+	templatesRoutes := api.Group("/templates", apiLimiter)
 	{
 		templatesRoutes.Get("/", templates.List)
 		templatesRoutes.Get("/categories", templates.Categories)
@@ -75,6 +95,12 @@ func Setup(app *fiber.App, cfg *config.Config) {
 		webhooksGithub.Post("/github", webhookgithub.Handle)
 	}
 
+	// Webhooks - external CI (per-service deploy token), registered before the X-Api-Key group
+	webhooksImages := api.Group("/webhooks")
+	{
+		webhooksImages.Post("/services/:serviceId/image", deployLimiter, webhookservices.DeployImage)
+	}
+
 	// Webhooks - protected (X-Api-Key)
 	webhooksProtected := api.Group("/webhooks", middleware.WebhookApiKeyMiddleware(cfg))
 	{
@@ -84,62 +110,97 @@ func Setup(app *fiber.App, cfg *config.Config) {
 		webhooksProtected.Post("/services/:serviceId/status", webhookservices.UpdateStatus)
 		webhooksProtected.Post("/services/:serviceId/replicas", webhookservices.UpdateReplicas)
 		webhooksProtected.Post("/services/:serviceId/pods", webhookservices.HandlePodEvent)
+		webhooksProtected.Post("/services/:serviceId/credentials", webhookservices.UpdateCredentialRotation)
+		webhooksProtected.Post("/backups/:backupId/status", webhookbackups.UpdateStatus)
 		webhooksProtected.Post("/service-metrics", webhookmetrics.HandleServiceMetrics)
 		webhooksProtected.Post("/storage-metrics", webhookmetrics.HandleStorageMetrics)
 		webhooksProtected.Get("/cronjob", webhookcronjobs.List)
 		webhooksProtected.Post("/cronjob/:cronjobId/status", webhookcronjobs.UpdateStatus)
 	}
 
+	// Admin - operators and worker autoscalers (X-Api-Key)
+	adminRoutes := api.Group("/admin", middleware.WebhookApiKeyMiddleware(cfg))
+	{
+		adminRoutes.Get("/queues", admin.GetQueues)
+	}
+
 	// Docker (JWT)
-	dockerRoutes := api.Group("/docker-images", middleware.AuthMiddleware(cfg))
+	dockerRoutes := api.Group("/docker-images", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		dockerRoutes.Post("/validate", docker.ValidateImage)
 	}
 
 	// Account (JWT)
-	accountRoutes := api.Group("/account", middleware.AuthMiddleware(cfg))
+	accountRoutes := api.Group("/account", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		accountRoutes.Patch("/password", account.UpdatePassword)
 		accountRoutes.Patch("/profile", account.UpdateProfile)
 	}
 
 	// Organizations (JWT)
-	orgs := api.Group("/organizations", middleware.AuthMiddleware(cfg))
+	orgs := api.Group("/organizations", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		orgs.Get("/", organizations.List)
 		orgs.Post("/", organizations.Create)
 		orgs.Get("/:organizationId", organizations.Get)
+		orgs.Post("/:organizationId", organizations.Update)
+		orgs.Get("/:organizationId/usage", organizations.GetUsage)
 	}
 
 	// Projects (JWT)
-	projectsRoutes := api.Group("/projects", middleware.AuthMiddleware(cfg))
+	projectsRoutes := api.Group("/projects", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		projectsRoutes.Get("/", projects.List)
 		projectsRoutes.Post("/", projects.Create)
 		projectsRoutes.Get("/:projectId", projects.Get)
 		projectsRoutes.Post("/:projectId", projects.Update)
 		projectsRoutes.Delete("/:projectId", projects.Delete)
+		projectsRoutes.Post("/:projectId/services/batch", deployLimiter, projects.BatchServices)
+		projectsRoutes.Get("/:projectId/webhook-deliveries", projects.ListWebhookDeliveries)
+		projectsRoutes.Post("/:projectId/webhook-deliveries/:deliveryId/replay", deployLimiter, projects.ReplayWebhookDelivery)
 	}
 
 	// Services (JWT)
-	servicesRoutes := api.Group("/services", middleware.AuthMiddleware(cfg))
+	servicesRoutes := api.Group("/services", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		servicesRoutes.Get("/", services.List)
-		servicesRoutes.Post("/", servicescreate.Create)
-		servicesRoutes.Post("/template", servicestemplate.Create)
+		servicesRoutes.Post("/", idempotency, servicescreate.Create)
+		servicesRoutes.Post("/template", idempotency, servicestemplate.Create)
 		servicesRoutes.Get("/:serviceId", singleservice.Get)
 		servicesRoutes.Patch("/:serviceId", singleservice.Update)
 		servicesRoutes.Delete("/:serviceId", singleservice.Delete)
-		servicesRoutes.Post("/:serviceId/deploy", singleservice.Deploy)
-		servicesRoutes.Post("/:serviceId/restart", singleservice.Restart)
+		servicesRoutes.Post("/:serviceId/restore", deployLimiter, singleservice.Restore)
+		servicesRoutes.Post("/:serviceId/deploy", deployLimiter, idempotency, singleservice.Deploy)
+		servicesRoutes.Post("/:serviceId/deploy/validate", validateLimiter, singleservice.ValidateDeploy)
+		servicesRoutes.Post("/:serviceId/restart", deployLimiter, singleservice.Restart)
 		servicesRoutes.Get("/:serviceId/deployments", singleservice.GetDeployments)
+		servicesRoutes.Get("/:serviceId/deployments/stats", singleservice.GetDeploymentStats)
+		servicesRoutes.Get("/:serviceId/deployments/:deploymentId/logs", singleservice.GetDeploymentLogs)
 		servicesRoutes.Get("/:serviceId/events", singleservice.GetEvents)
 		servicesRoutes.Get("/:serviceId/metrics", singleservice.GetMetrics)
 		servicesRoutes.Get("/:serviceId/pods", singleservice.GetPods)
+		servicesRoutes.Post("/:serviceId/pods/:podId/restart", deployLimiter, singleservice.RestartPod)
+		servicesRoutes.Get("/:serviceId/k8s-events", singleservice.GetKubernetesEvents)
+		servicesRoutes.Get("/:serviceId/plan-change", singleservice.PreviewPlanChange)
+		servicesRoutes.Post("/:serviceId/scaling/cancel", deployLimiter, singleservice.CancelScaling)
+		servicesRoutes.Post("/:serviceId/stop", deployLimiter, singleservice.Stop)
+		servicesRoutes.Post("/:serviceId/start", deployLimiter, singleservice.Start)
+		servicesRoutes.Get("/:serviceId/connection", singleservice.GetConnection)
+		servicesRoutes.Post("/:serviceId/connection/link", singleservice.LinkDatabase)
+		servicesRoutes.Post("/:serviceId/credentials/rotate", deployLimiter, singleservice.RotateCredentials)
+		servicesRoutes.Post("/:serviceId/deploy-token", singleservice.CreateDeployToken)
+		servicesRoutes.Delete("/:serviceId/deploy-token", singleservice.DeleteDeployToken)
+		servicesRoutes.Post("/:serviceId/custom-domain/verify", singleservice.VerifyCustomDomain)
+		servicesRoutes.Post("/:serviceId/tunnel", singleservice.EnableTunnel)
+		servicesRoutes.Delete("/:serviceId/tunnel", singleservice.DisableTunnel)
+		servicesRoutes.Post("/:serviceId/ingress-port", singleservice.AllocateIngressPort)
+		servicesRoutes.Delete("/:serviceId/ingress-port", singleservice.ReleaseIngressPort)
 		servicesRoutes.Get("/:serviceId/environment-variables", serviceenvvars.List)
 		servicesRoutes.Get("/:serviceId/environment-variables/:key", serviceenvvars.Get)
 		servicesRoutes.Patch("/:serviceId/environment-variables/update", serviceenvvars.Update)
 		servicesRoutes.Post("/:serviceId/environment-variables/delete", serviceenvvars.Delete)
+		servicesRoutes.Get("/:serviceId/backups", servicebackups.List)
+		servicesRoutes.Post("/:serviceId/backups", deployLimiter, servicebackups.Create)
 		servicesRoutes.Get("/:serviceId/cronjobs", servicecronjobs.List)
 		servicesRoutes.Post("/:serviceId/cronjobs", servicecronjobs.Create)
 		servicesRoutes.Get("/:serviceId/cronjobs/:cronJobId", servicecronjobs.Get)
@@ -147,8 +208,14 @@ func Setup(app *fiber.App, cfg *config.Config) {
 		servicesRoutes.Delete("/:serviceId/cronjobs/:cronJobId", servicecronjobs.Delete)
 	}
 
+	// Domains (JWT)
+	domainsRoutes := api.Group("/domains", middleware.AuthMiddleware(cfg), apiLimiter)
+	{
+		domainsRoutes.Get("/", domains.List)
+	}
+
 	// Deployments (JWT)
-	deploymentsRoutes := api.Group("/deployments", middleware.AuthMiddleware(cfg))
+	deploymentsRoutes := api.Group("/deployments", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		deploymentsRoutes.Get("/:deploymentId", deployments.GetDeployment)
 		deploymentsRoutes.Post("/:deploymentId/cancel", deployments.CancelDeployment)
@@ -156,7 +223,7 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	}
 
 	// Git Providers (JWT)
-	gitProvidersRoutes := api.Group("/git-providers", middleware.AuthMiddleware(cfg))
+	gitProvidersRoutes := api.Group("/git-providers", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		gitProvidersRoutes.Get("/", gitproviders.ListProviders)
 		gitProvidersRoutes.Post("/", gitproviders.CreateProvider)
@@ -166,8 +233,15 @@ func Setup(app *fiber.App, cfg *config.Config) {
 		gitProvidersRoutes.Post("/:providerId/repositories/description", gitproviders.GetRepositoryDescription)
 	}
 
+	// Git (JWT)
+	gitRoutes := api.Group("/git", middleware.AuthMiddleware(cfg), apiLimiter)
+	{
+		gitRoutes.Post("/analyze", gitproviders.AnalyzeRepository)
+		gitRoutes.Get("/:providerId/repos/:owner/:repo/contents", gitproviders.ListContents)
+	}
+
 	// API Keys (JWT)
-	apiKeysRoutes := api.Group("/api-keys", middleware.AuthMiddleware(cfg))
+	apiKeysRoutes := api.Group("/api-keys", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		apiKeysRoutes.Get("/", apikeys.List)
 		apiKeysRoutes.Post("/", apikeys.Create)
@@ -176,25 +250,25 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	}
 
 	// GitHub (JWT)
-	githubRoutes := api.Group("/github", middleware.AuthMiddleware(cfg))
+	githubRoutes := api.Group("/github", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		githubRoutes.Get("/accounts", githubHandlers.ListAccounts)
 	}
 
 	// Instance Type Groups (JWT)
-	instanceTypeGroupsRoutes := api.Group("/instance-type-groups", middleware.AuthMiddleware(cfg))
+	instanceTypeGroupsRoutes := api.Group("/instance-type-groups", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		instanceTypeGroupsRoutes.Get("/", instancetypegroups.List)
 	}
 
 	// Instance Types (JWT)
-	instanceTypesRoutes := api.Group("/instance-types", middleware.AuthMiddleware(cfg))
+	instanceTypesRoutes := api.Group("/instance-types", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		instanceTypesRoutes.Get("/", instancetypes.List)
 	}
 
 	// Service Types (JWT)
-	serviceTypesRoutes := api.Group("/service-types", middleware.AuthMiddleware(cfg))
+	serviceTypesRoutes := api.Group("/service-types", middleware.AuthMiddleware(cfg), apiLimiter)
 	{
 		serviceTypesRoutes.Get("/", servicetypes.List)
 	}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/kubernetes"
+)
+
+// StoppedLabel marks the Kubernetes service of a stopped service so the web proxy doesn't wake it up
+const StoppedLabel = "stopped"
+
+// setServiceLabel labels the Kubernetes service of a service, tests replace it
+var setServiceLabel = kubernetes.SetServiceLabel
+
+// ErrServiceStopped is returned when an action needs the service to be started first
+var ErrServiceStopped = errors.New("service is stopped")
+
+// ErrServiceNotStopped is returned when starting a service that was not stopped
+var ErrServiceNotStopped = errors.New("service is not stopped")
+
+// Restart marks a service as restarting and redeploys it to Kubernetes in the background
+func Restart(serviceID string) error {
+	db := database.GetDatabase()
+
+	var service models.Service
+	if err := db.Where("id = ? AND deletedAt IS NULL", serviceID).First(&service).Error; err != nil {
+		return ErrServiceNotFound
+	}
+	if service.Stopped {
+		return ErrServiceStopped
+	}
+
+	if err := SetStatus(serviceID, models.ServiceStatusRestarting, nil); err != nil {
+		return err
+	}
+
+	db.Create(&models.ServiceEvent{
+		ServiceID: serviceID,
+		Type:      models.EventTypeServiceRestartStarted,
+		Message:   utils.Ptr("Service restart initiated"),
+	})
+
+	go func() {
+		if err := deploy.DeployService("deploy-service", nil, serviceID); err != nil {
+			log.Printf("Error restarting service: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop marks a service as stopped and asks Kubestrator to scale it down to zero replicas.
+// The service stays stopped, traffic doesn't scale it up, until Start is called.
+func Stop(ctx context.Context, service models.Service) error {
+	if !CanTransition(service.Status, models.ServiceStatusStopped) {
+		return ErrInvalidTransition
+	}
+
+	if err := SetStatus(service.ID, models.ServiceStatusStopped, map[string]interface{}{"stopped": true}); err != nil {
+		return err
+	}
+
+	// Label before scaling down so the web proxy stops waking the service up. Without the label
+	// the stop wouldn't stick, the service is put back as it was.
+	if err := setServiceLabel(ctx, region.Of(service), service.ProjectID, service.ID+"-service", StoppedLabel, utils.Ptr("true")); err != nil {
+		revertStop(service)
+		return err
+	}
+
+	return redis.AddToControllerQueue(context.Background(), redis.ControllerJob{
+		Type:      "control-service",
+		ServiceID: service.ID,
+		ProjectID: service.ProjectID,
+		Region:    region.Of(service),
+		Action:    "scale-down",
+	})
+}
+
+// revertStop puts a service Stop marked as stopped back in the status it had, unless its
+// status changed since
+func revertStop(service models.Service) {
+	db := database.GetDatabase()
+
+	result := db.Model(&models.Service{}).
+		Where("id = ? AND status = ?", service.ID, models.ServiceStatusStopped).
+		Updates(map[string]interface{}{
+			"status":    service.Status,
+			"stopped":   service.Stopped,
+			"updatedAt": time.Now(),
+		})
+	if result.Error != nil {
+		log.Printf("Error reverting stop of service %s: %v", service.ID, result.Error)
+		return
+	}
+	if result.RowsAffected > 0 && service.Status != models.ServiceStatusStopped {
+		publishStatus(service.ID, models.ServiceStatusStopped, service.Status)
+	}
+}
+
+// Start clears the stopped flag of a service and asks Kubestrator to scale it back up to one replica
+func Start(ctx context.Context, service models.Service) error {
+	if !service.Stopped {
+		return ErrServiceNotStopped
+	}
+
+	if err := SetStatus(service.ID, models.ServiceStatusRunning, map[string]interface{}{"stopped": false}); err != nil {
+		return err
+	}
+
+	// The service is already started, finish even if the caller goes away
+	if err := setServiceLabel(context.WithoutCancel(ctx), region.Of(service), service.ProjectID, service.ID+"-service", StoppedLabel, nil); err != nil {
+		log.Printf("Error removing stopped label from service %s: %v", service.ID, err)
+	}
+
+	return redis.AddToControllerQueue(context.Background(), redis.ControllerJob{
+		Type:      "control-service",
+		ServiceID: service.ID,
+		ProjectID: service.ProjectID,
+		Region:    region.Of(service),
+		Action:    "scale-up",
+	})
+}
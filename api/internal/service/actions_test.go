@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+)
+
+type labelCall struct {
+	namespace, name, key string
+	value                *string
+}
+
+// stubServiceLabel records the labels set on Kubernetes services and fails with err
+func stubServiceLabel(t *testing.T, err error) *[]labelCall {
+	t.Helper()
+	var calls []labelCall
+	previous := setServiceLabel
+	setServiceLabel = func(_ context.Context, _, namespace, name, key string, value *string) error {
+		calls = append(calls, labelCall{namespace: namespace, name: name, key: key, value: value})
+		return err
+	}
+	t.Cleanup(func() { setServiceLabel = previous })
+	return &calls
+}
+
+func queuedControllerJobs(t *testing.T) []redis.ControllerJob {
+	t.Helper()
+	if !redisServer.Exists(redis.QueueDeployment) {
+		return nil
+	}
+	items, err := redisServer.List(redis.QueueDeployment)
+	if err != nil {
+		t.Fatalf("read deployment queue: %v", err)
+	}
+	var jobs []redis.ControllerJob
+	for _, item := range items {
+		var job redis.ControllerJob
+		if err := json.Unmarshal([]byte(item), &job); err != nil {
+			t.Fatalf("unmarshal queued job %s: %v", item, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func TestStopLabelsAndScalesDown(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "status": string(models.ServiceStatusRunning)})
+	labels := stubServiceLabel(t, nil)
+
+	service := models.Service{ID: "svc1", ProjectID: "proj1", Status: models.ServiceStatusRunning}
+	if err := Stop(context.Background(), service); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, "`stopped`") || !containsArg(updates[0], string(models.ServiceStatusStopped)) {
+		t.Errorf("updates = %v, want the service marked stopped", updates)
+	}
+
+	if len(*labels) != 1 {
+		t.Fatalf("set %d labels, want 1", len(*labels))
+	}
+	label := (*labels)[0]
+	if label.namespace != "proj1" || label.name != "svc1-service" || label.key != StoppedLabel || label.value == nil || *label.value != "true" {
+		t.Errorf("label = %+v, want stopped=true on proj1/svc1-service", label)
+	}
+
+	jobs := queuedControllerJobs(t)
+	if len(jobs) != 1 || jobs[0].Action != "scale-down" || jobs[0].ServiceID != "svc1" {
+		t.Errorf("queued jobs = %+v, want a scale-down of svc1", jobs)
+	}
+}
+
+func TestStopRevertsWhenLabellingFails(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "status": string(models.ServiceStatusRunning)})
+	stubServiceLabel(t, errors.New("kubernetes is down"))
+
+	service := models.Service{ID: "svc1", ProjectID: "proj1", Status: models.ServiceStatusRunning}
+	if err := Stop(context.Background(), service); err == nil {
+		t.Fatal("Stop succeeded without the stopped label")
+	}
+
+	// The stop, then its revert guarded on the service still being stopped
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 2 {
+		t.Fatalf("updates = %v, want the stop and its revert", updates)
+	}
+	if !containsArg(updates[1], string(models.ServiceStatusRunning)) {
+		t.Errorf("revert args = %v, want the service put back to RUNNING", updates[1].Args)
+	}
+	if jobs := queuedControllerJobs(t); len(jobs) != 0 {
+		t.Errorf("queued %+v for a failed stop", jobs)
+	}
+}
+
+func TestStartClearsLabelAndScalesUp(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "status": string(models.ServiceStatusStopped)})
+	labels := stubServiceLabel(t, nil)
+
+	service := models.Service{ID: "svc1", ProjectID: "proj1", Status: models.ServiceStatusStopped, Stopped: true}
+	if err := Start(context.Background(), service); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 1 || !containsArg(updates[0], string(models.ServiceStatusRunning)) {
+		t.Errorf("updates = %v, want the service marked running", updates)
+	}
+	if len(*labels) != 1 || (*labels)[0].key != StoppedLabel || (*labels)[0].value != nil {
+		t.Errorf("labels = %+v, want the stopped label removed", *labels)
+	}
+
+	jobs := queuedControllerJobs(t)
+	if len(jobs) != 1 || jobs[0].Action != "scale-up" || jobs[0].ServiceID != "svc1" {
+		t.Errorf("queued jobs = %+v, want a scale-up of svc1", jobs)
+	}
+}
+
+func TestStartNeedsStoppedService(t *testing.T) {
+	db := dbtest.New(t)
+	labels := stubServiceLabel(t, nil)
+
+	err := Start(context.Background(), models.Service{ID: "svc1", Status: models.ServiceStatusRunning})
+	if !errors.Is(err, ErrServiceNotStopped) {
+		t.Fatalf("Start error = %v, want ErrServiceNotStopped", err)
+	}
+	if len(db.Statements("UPDATE")) != 0 || len(*labels) != 0 {
+		t.Error("starting a running service changed it")
+	}
+}
+
+func TestRestartRefusesStoppedService(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "status": string(models.ServiceStatusStopped), "stopped": true})
+
+	if err := Restart("svc1"); !errors.Is(err, ErrServiceStopped) {
+		t.Fatalf("Restart error = %v, want ErrServiceStopped", err)
+	}
+	if len(db.Statements("UPDATE")) != 0 {
+		t.Error("restarting a stopped service changed its status")
+	}
+}
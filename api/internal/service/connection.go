@@ -0,0 +1,58 @@
+package service
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+// URL schemes of the connection strings of database and memory services
+var connectionSchemes = map[string]string{
+	"mysql":      "mysql",
+	"postgresql": "postgresql",
+	"memory":     "redis",
+}
+
+// ConnectionString builds the URL clients connect to a database or memory service with. The
+// password is left out when empty, memory services have no database.
+func ConnectionString(serviceType, host string, port int, username, password, databaseName string) string {
+	u := url.URL{
+		Scheme: connectionSchemes[serviceType],
+		Host:   net.JoinHostPort(host, strconv.Itoa(port)),
+	}
+	if password != "" {
+		u.User = url.UserPassword(username, password)
+	} else {
+		u.User = url.User(username)
+	}
+	if serviceType != "memory" && databaseName != "" {
+		u.Path = "/" + databaseName
+	}
+	return u.String()
+}
+
+// InternalHost returns the host other services of the project reach a service on
+func InternalHost(serviceID string) string {
+	return serviceID + "-service"
+}
+
+// DatabaseProperty returns a property of a database or memory service for the env vars of a
+// service connecting to it from the same project: host, port, user, username, password or
+// database. ok is false for an unknown property.
+func DatabaseProperty(serviceID string, credential models.ServiceCredential, property string) (value string, ok bool) {
+	switch property {
+	case "host":
+		return InternalHost(serviceID), true
+	case "user", "username":
+		return credential.Username, true
+	case "password":
+		return credential.Password, true
+	case "database":
+		return credential.Database, true
+	case "port":
+		return strconv.Itoa(credential.Port), true
+	}
+	return "", false
+}
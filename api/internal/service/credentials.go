@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/deploy"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+// CredentialServiceTypes are the service types whose credentials can be rotated
+var CredentialServiceTypes = map[string]bool{
+	"mysql":      true,
+	"postgresql": true,
+	"memory":     true,
+}
+
+// ErrNoCredentials is returned when rotating the credentials of a service that has none
+var ErrNoCredentials = errors.New("service has no credentials")
+
+// ErrServiceNotRunning is returned when an action needs the server of the service to be running
+var ErrServiceNotRunning = errors.New("service is not running")
+
+// ErrNoPendingRotation is returned when confirming a rotation of a service that isn't rotating
+var ErrNoPendingRotation = errors.New("service has no pending credential rotation")
+
+// RotateCredentials starts giving a database or memory service a new password. The new
+// password is kept pending and Kubestrator changes the password of the running server, the
+// stored credential keeps the current password until CompleteCredentialRotation confirms the
+// change. A rotation that wasn't confirmed is retried with the same pending password, as the
+// server may already use it. Returns the credential, still holding the current password.
+func RotateCredentials(ctx context.Context, service models.Service) (*models.ServiceCredential, error) {
+	db := database.GetDatabase()
+
+	// Kubestrator changes the password in the running server
+	if service.Stopped || service.Status != models.ServiceStatusRunning {
+		return nil, ErrServiceNotRunning
+	}
+
+	var credential models.ServiceCredential
+	if err := db.Where("serviceId = ?", service.ID).First(&credential).Error; err != nil {
+		return nil, ErrNoCredentials
+	}
+
+	started := credential.PendingPassword == nil
+	if started {
+		credential.PendingPassword = utils.Ptr(utils.GeneratePassword())
+		if err := db.Model(&credential).Update("pendingPassword", *credential.PendingPassword).Error; err != nil {
+			return nil, fmt.Errorf("failed to update credential: %w", err)
+		}
+	}
+
+	if err := redis.AddToControllerQueue(ctx, redis.ControllerJob{
+		Type:      "control-service",
+		ServiceID: service.ID,
+		ProjectID: service.ProjectID,
+		Region:    region.Of(service),
+		Action:    "rotate-credentials",
+		Credentials: &redis.Credentials{
+			Username: credential.Username,
+			Password: *credential.PendingPassword,
+			Database: credential.Database,
+		},
+		PreviousPassword: credential.Password,
+	}); err != nil {
+		// Nothing reached the server, a rotation started here can be dropped
+		if started {
+			db.Model(&credential).Update("pendingPassword", nil)
+		}
+		return nil, fmt.Errorf("failed to queue credential rotation: %w", err)
+	}
+
+	return &credential, nil
+}
+
+// CompleteCredentialRotation records the outcome Kubestrator reported for the pending rotation
+// of a service. Once the server uses the new password it becomes the password of the
+// credential, env vars of the other services of the project holding the old password get the
+// new one and those services are redeployed. A failed rotation stays pending so retrying it
+// reuses the password. Returns the IDs of the updated services.
+func CompleteCredentialRotation(service models.Service, rotationErr string) ([]string, error) {
+	db := database.GetDatabase()
+
+	var credential models.ServiceCredential
+	if err := db.Where("serviceId = ?", service.ID).First(&credential).Error; err != nil {
+		return nil, ErrNoCredentials
+	}
+	if credential.PendingPassword == nil {
+		return nil, ErrNoPendingRotation
+	}
+
+	if rotationErr != "" {
+		db.Create(&models.ServiceEvent{
+			ServiceID: service.ID,
+			Type:      models.EventTypeCredentialsRotated,
+			Message:   utils.Ptr("Credential rotation failed, the current credentials remain valid: " + rotationErr),
+		})
+		return nil, nil
+	}
+
+	previousPassword := credential.Password
+	password := *credential.PendingPassword
+	result := db.Model(&models.ServiceCredential{}).
+		Where("id = ? AND pendingPassword = ?", credential.ID, password).
+		Updates(map[string]interface{}{
+			"password":        password,
+			"pendingPassword": nil,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update credential: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNoPendingRotation
+	}
+
+	db.Create(&models.ServiceEvent{
+		ServiceID: service.ID,
+		Type:      models.EventTypeCredentialsRotated,
+		Message:   utils.Ptr("Credentials rotated"),
+	})
+
+	return updateDependentEnvVars(service, previousPassword, password), nil
+}
+
+// updateDependentEnvVars replaces the old password of a service in the env vars of the other
+// services of its project, e.g. a DATABASE_URL set from a template, and redeploys the updated
+// services. Returns their IDs.
+func updateDependentEnvVars(service models.Service, previousPassword, password string) []string {
+	db := database.GetDatabase()
+
+	var services []models.Service
+	if err := db.Where("projectId = ? AND id <> ? AND deletedAt IS NULL", service.ProjectID, service.ID).
+		Find(&services).Error; err != nil {
+		log.Printf("Error loading services of project %s: %v", service.ProjectID, err)
+		return nil
+	}
+
+	var updated []string
+	for _, dependent := range services {
+		var stored []crypto.EnvironmentVariable
+		if len(dependent.EnvironmentVariables) == 0 || dependent.EnvironmentVariables.UnmarshalTo(&stored) != nil {
+			continue
+		}
+		envVars, _ := crypto.DecryptEnvVars(stored)
+		if !replacePassword(envVars, previousPassword, password) {
+			continue
+		}
+
+		encrypted, err := crypto.EncryptEnvVars(envVars)
+		if err != nil {
+			log.Printf("Error encrypting environment variables of service %s: %v", dependent.ID, err)
+			continue
+		}
+		envJSON, _ := json.Marshal(encrypted)
+		if err := UpdateVersioned(db, dependent.ID, dependent.Version, map[string]interface{}{"environmentVariables": envJSON}); err != nil {
+			log.Printf("Error updating environment variables of service %s: %v", dependent.ID, err)
+			continue
+		}
+
+		db.Create(&models.ServiceEvent{
+			ServiceID: dependent.ID,
+			Type:      models.EventTypeConfigUpdated,
+			Message:   utils.Ptr(fmt.Sprintf("Environment variables updated with the rotated credentials of %s", service.Name)),
+		})
+
+		if dependent.Status == models.ServiceStatusRunning ||
+			dependent.Status == models.ServiceStatusFailed ||
+			dependent.Status == models.ServiceStatusRestarting {
+			go func(serviceID string) {
+				if err := deploy.DeployService("deploy-service", nil, serviceID); err != nil {
+					log.Printf("Error redeploying service: %v", err)
+				}
+			}(dependent.ID)
+		}
+
+		updated = append(updated, dependent.ID)
+	}
+
+	return updated
+}
+
+// replacePassword replaces the old password in env var values with the new one and reports
+// whether any changed. An empty old password is in every value, nothing is replaced.
+func replacePassword(envVars []crypto.EnvironmentVariable, previousPassword, password string) bool {
+	if previousPassword == "" {
+		return false
+	}
+
+	changed := false
+	for i, v := range envVars {
+		if strings.Contains(v.Value, previousPassword) {
+			envVars[i].Value = strings.ReplaceAll(v.Value, previousPassword, password)
+			changed = true
+		}
+	}
+	return changed
+}
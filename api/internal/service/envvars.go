@@ -0,0 +1,30 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/crypto"
+)
+
+// CheckEnvVarLimits returns an error when a service's environment variables, before
+// encryption, exceed the configured count or total size
+func CheckEnvVarLimits(envVars []crypto.EnvironmentVariable) error {
+	cfg := config.Get()
+
+	if cfg.EnvVarsMaxCount > 0 && len(envVars) > cfg.EnvVarsMaxCount {
+		return fmt.Errorf("Too many environment variables: %d, at most %d are allowed", len(envVars), cfg.EnvVarsMaxCount)
+	}
+
+	if cfg.EnvVarsMaxKB > 0 {
+		size := 0
+		for _, v := range envVars {
+			size += len(v.Key) + len(v.Value)
+		}
+		if limit := cfg.EnvVarsMaxKB * 1024; size > limit {
+			return fmt.Errorf("Environment variables are too large: %d bytes, at most %d KB are allowed", size, cfg.EnvVarsMaxKB)
+		}
+	}
+
+	return nil
+}
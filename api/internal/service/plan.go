@@ -0,0 +1,51 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/models"
+	"gorm.io/gorm"
+)
+
+// How far back metrics are considered when checking that a smaller plan fits current usage
+const planUsageWindow = time.Hour
+
+// ValidateInstanceTypeChange checks that the target instance type belongs to the service's type and,
+// for downgrades, that the service's recent peak usage fits in it
+func ValidateInstanceTypeChange(db *gorm.DB, service models.Service, target models.InstanceType, force bool) error {
+	if target.InstanceTypeGroup.ServiceTypeID != service.ServiceTypeID {
+		return fmt.Errorf("Instance type %s is not available for %s services", target.ID, service.ServiceTypeID)
+	}
+
+	current := service.InstanceType
+	if force || (target.CpuCount >= current.CpuCount && target.MemoryMB >= current.MemoryMB) {
+		return nil
+	}
+
+	var metrics []models.ServiceMetrics
+	db.Where("serviceId = ? AND timestamp >= ?", service.ID, time.Now().Add(-planUsageWindow)).
+		Find(&metrics)
+
+	// Utilization is reported against the current instance type, per pod
+	var peakCpu, peakMemoryMB float64
+	for _, m := range metrics {
+		if m.CpuUtilizationPercentage != nil {
+			peakCpu = max(peakCpu, *m.CpuUtilizationPercentage/100*current.CpuCount)
+		}
+		if m.MemoryUtilizationPercentage != nil {
+			peakMemoryMB = max(peakMemoryMB, *m.MemoryUtilizationPercentage/100*float64(current.MemoryMB))
+		}
+	}
+
+	if peakMemoryMB > float64(target.MemoryMB) {
+		return fmt.Errorf("Instance type %s has %d MB of memory but the service recently used %.0f MB, pass force to change anyway",
+			target.ID, target.MemoryMB, peakMemoryMB)
+	}
+	if peakCpu > target.CpuCount {
+		return fmt.Errorf("Instance type %s has %.2f CPUs but the service recently used %.2f, pass force to change anyway",
+			target.ID, target.CpuCount, peakCpu)
+	}
+
+	return nil
+}
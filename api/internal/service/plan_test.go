@@ -0,0 +1,89 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+func TestValidateInstanceTypeChange(t *testing.T) {
+	service := models.Service{
+		ServiceTypeID: "web",
+		InstanceType:  models.InstanceType{ID: "small", CpuCount: 1, MemoryMB: 1024},
+	}
+	webType := func(id string, cpu float64, memoryMB int) models.InstanceType {
+		return models.InstanceType{
+			ID:                id,
+			CpuCount:          cpu,
+			MemoryMB:          memoryMB,
+			InstanceTypeGroup: models.InstanceTypeGroup{ServiceTypeID: "web"},
+		}
+	}
+
+	// Neither an upgrade nor a forced change looks at the metrics, no database is needed
+	if err := ValidateInstanceTypeChange(nil, service, webType("large", 2, 2048), false); err != nil {
+		t.Errorf("upgrade: %v, want nil", err)
+	}
+	if err := ValidateInstanceTypeChange(nil, service, webType("tiny", 0.5, 512), true); err != nil {
+		t.Errorf("forced downgrade: %v, want nil", err)
+	}
+
+	mysql := webType("db-small", 1, 1024)
+	mysql.InstanceTypeGroup.ServiceTypeID = "mysql"
+	if err := ValidateInstanceTypeChange(nil, service, mysql, true); err == nil {
+		t.Error("instance type of another service type: nil, want an error")
+	}
+}
+
+func TestValidateInstanceTypeChangeDowngradeChecksUsage(t *testing.T) {
+	service := models.Service{
+		ID:            "svc1",
+		ServiceTypeID: "web",
+		InstanceType:  models.InstanceType{ID: "medium", CpuCount: 2, MemoryMB: 2048},
+	}
+	small := models.InstanceType{
+		ID:                "small",
+		CpuCount:          1,
+		MemoryMB:          1024,
+		InstanceTypeGroup: models.InstanceTypeGroup{ServiceTypeID: "web"},
+	}
+
+	tests := []struct {
+		name    string
+		metrics []dbtest.Row
+		wantErr string
+	}{
+		{name: "no recent metrics"},
+		{name: "usage fits", metrics: []dbtest.Row{
+			{"cpuUtilizationPercentage": 40.0, "memoryUtilizationPercentage": 45.0},
+			{"cpuUtilizationPercentage": 25.0, "memoryUtilizationPercentage": 30.0},
+		}},
+		{name: "memory peak too large", metrics: []dbtest.Row{
+			{"cpuUtilizationPercentage": 10.0, "memoryUtilizationPercentage": 20.0},
+			{"cpuUtilizationPercentage": 10.0, "memoryUtilizationPercentage": 75.0},
+		}, wantErr: "has 1024 MB of memory but the service recently used 1536 MB"},
+		{name: "cpu peak too large", metrics: []dbtest.Row{
+			{"cpuUtilizationPercentage": 80.0, "memoryUtilizationPercentage": 20.0},
+		}, wantErr: "has 1.00 CPUs but the service recently used 1.60"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			db.OnQuery("FROM `ServiceMetrics`", tt.metrics...)
+
+			err := ValidateInstanceTypeChange(database.GetDatabase(), service, small, false)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateInstanceTypeChange: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateInstanceTypeChange error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+// ErrNotScaling is returned when cancelling the scaling of a service that isn't scaling
+var ErrNotScaling = errors.New("service is not scaling")
+
+// ErrReplicaUpdateInProgress is returned when another replica update holds the service's lock
+var ErrReplicaUpdateInProgress = errors.New("another replica update is in progress")
+
+// ReplicaLockKey returns the Redis lock serializing the replica updates of a service
+func ReplicaLockKey(serviceID string) string {
+	return fmt.Sprintf("service-replica-lock:%s", serviceID)
+}
+
+// CancelScaling aborts a scale that doesn't finish, e.g. because new pods can't be scheduled.
+// The target goes back to the current replica count and Kubestrator scales the deployment
+// to it.
+func CancelScaling(ctx context.Context, serviceID string) (*models.Service, error) {
+	db := database.GetDatabase()
+
+	lockKey := ReplicaLockKey(serviceID)
+	acquired, err := redis.AcquireLock(ctx, lockKey, 30)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrReplicaUpdateInProgress
+	}
+	defer redis.ReleaseLock(ctx, lockKey)
+
+	// Read under the lock, a replica update may have just finished the scale
+	var service models.Service
+	if err := db.Where("id = ? AND deletedAt IS NULL", serviceID).First(&service).Error; err != nil {
+		return nil, ErrServiceNotFound
+	}
+	if service.ScalingStatus != models.ServiceScalingStatusScaling {
+		return nil, ErrNotScaling
+	}
+
+	previousTarget := service.TargetReplicas
+	if err := db.Model(&models.Service{}).Where("id = ?", serviceID).Updates(map[string]interface{}{
+		"targetReplicas": service.CurrentReplicas,
+		"scalingStatus":  models.ServiceScalingStatusIdle,
+	}).Error; err != nil {
+		return nil, err
+	}
+	service.TargetReplicas = service.CurrentReplicas
+	service.ScalingStatus = models.ServiceScalingStatusIdle
+
+	// Keep the autoscaler from starting the same scale again right away
+	if cooldown := time.Duration(config.Get().ScalingCooldownSeconds) * time.Second; cooldown > 0 {
+		if err := redis.SetLastScaleAt(ctx, serviceID, time.Now(), cooldown); err != nil {
+			log.Printf("Failed to store last scale time of service %s: %v", serviceID, err)
+		}
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"currentReplicas": service.CurrentReplicas,
+		"cancelledTarget": previousTarget,
+		"targetReplicas":  service.CurrentReplicas,
+	})
+	db.Create(&models.ServiceEvent{
+		ServiceID: serviceID,
+		Type:      models.EventTypeScalingCancelled,
+		Message:   utils.Ptr(fmt.Sprintf("Scaling to %d replicas cancelled, staying at %d", previousTarget, service.CurrentReplicas)),
+		Payload:   payload,
+	})
+
+	if err := redis.AddToControllerQueue(ctx, redis.ControllerJob{
+		Type:      "control-service",
+		ServiceID: service.ID,
+		ProjectID: service.ProjectID,
+		Region:    region.Of(service),
+		Action:    "scale",
+		Replicas:  utils.Ptr(service.CurrentReplicas),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &service, nil
+}
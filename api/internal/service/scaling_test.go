@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+// scalingService answers service queries with a service scaling from 2 to 5 replicas
+func scalingService(db *dbtest.DB, status models.ServiceScalingStatus) {
+	db.OnQuery("FROM `Service`", dbtest.Row{
+		"id": "svc1", "projectId": "proj1", "currentReplicas": int64(2), "targetReplicas": int64(5),
+		"scalingStatus": string(status),
+	})
+}
+
+func TestCancelScalingResetsTarget(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	scalingService(db, models.ServiceScalingStatusScaling)
+
+	service, err := CancelScaling(context.Background(), "svc1")
+	if err != nil {
+		t.Fatalf("CancelScaling: %v", err)
+	}
+	if service.TargetReplicas != 2 || service.ScalingStatus != models.ServiceScalingStatusIdle {
+		t.Errorf("service = %d target replicas, %s, want 2 and IDLE", service.TargetReplicas, service.ScalingStatus)
+	}
+
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, "SET `scalingStatus`=?,`targetReplicas`=?") {
+		t.Fatalf("updates = %v, want the target and the status reset", updates)
+	}
+	if args := updates[0].Args; fmt.Sprint(args[0]) != "IDLE" || fmt.Sprint(args[1]) != "2" {
+		t.Errorf("update args = %v, want IDLE and the 2 current replicas", args)
+	}
+
+	events := db.Statements("INSERT INTO `ServiceEvent`")
+	if len(events) != 1 {
+		t.Fatalf("recorded %d service events, want 1", len(events))
+	}
+	args := events[0].Args
+	if fmt.Sprint(args[1]) != string(models.EventTypeScalingCancelled) {
+		t.Errorf("event type = %v, want %s", args[1], models.EventTypeScalingCancelled)
+	}
+	if message, ok := args[2].(*string); !ok || *message != "Scaling to 5 replicas cancelled, staying at 2" {
+		t.Errorf("event message = %v, want the cancelled target", args[2])
+	}
+	if payload := fmt.Sprintf("%s", args[len(args)-1]); !strings.Contains(payload, `"cancelledTarget":5`) {
+		t.Errorf("event payload = %s, want the cancelled target", payload)
+	}
+
+	jobs := queuedControllerJobs(t)
+	if len(jobs) != 1 || jobs[0].Action != "scale" || jobs[0].Replicas == nil || *jobs[0].Replicas != 2 {
+		t.Errorf("queued jobs = %+v, want a scale of svc1 to 2 replicas", jobs)
+	}
+	if redisServer.Exists(ReplicaLockKey("svc1")) {
+		t.Error("the replica lock was not released")
+	}
+	if !redisServer.Exists("service:last-scale:svc1") {
+		t.Error("the cancel didn't start the scaling cooldown")
+	}
+}
+
+func TestCancelScalingNotScaling(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	scalingService(db, models.ServiceScalingStatusIdle)
+
+	if _, err := CancelScaling(context.Background(), "svc1"); !errors.Is(err, ErrNotScaling) {
+		t.Fatalf("CancelScaling error = %v, want %v", err, ErrNotScaling)
+	}
+	if updates := db.Statements("UPDATE `Service`"); len(updates) != 0 {
+		t.Errorf("updated a service that isn't scaling: %v", updates)
+	}
+	if events := db.Statements("INSERT INTO `ServiceEvent`"); len(events) != 0 {
+		t.Errorf("recorded %d events for a service that isn't scaling", len(events))
+	}
+	if jobs := queuedControllerJobs(t); len(jobs) != 0 {
+		t.Errorf("queued %+v for a service that isn't scaling", jobs)
+	}
+}
+
+func TestCancelScalingWaitsForReplicaUpdates(t *testing.T) {
+	redisServer.FlushAll()
+	db := dbtest.New(t)
+	scalingService(db, models.ServiceScalingStatusScaling)
+	redisServer.Set(ReplicaLockKey("svc1"), "locked")
+
+	if _, err := CancelScaling(context.Background(), "svc1"); !errors.Is(err, ErrReplicaUpdateInProgress) {
+		t.Fatalf("CancelScaling error = %v, want %v", err, ErrReplicaUpdateInProgress)
+	}
+	if queries := db.Statements("FROM `Service`"); len(queries) != 0 {
+		t.Errorf("read the service while another replica update holds the lock")
+	}
+	if got, _ := redisServer.Get(ReplicaLockKey("svc1")); got != "locked" {
+		t.Errorf("the lock of the other update was released")
+	}
+}
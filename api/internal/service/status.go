@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+// ErrInvalidTransition is returned when a service cannot move to the requested status
+var ErrInvalidTransition = errors.New("invalid service status transition")
+
+// ErrServiceNotFound is returned when the service does not exist or has been deleted
+var ErrServiceNotFound = errors.New("service not found")
+
+// allowedTransitions lists the statuses a service may move to from each status
+var allowedTransitions = map[models.ServiceStatus][]models.ServiceStatus{
+	models.ServiceStatusPending: {
+		models.ServiceStatusDeploying, models.ServiceStatusRunning, models.ServiceStatusRestarting,
+		models.ServiceStatusStopped, models.ServiceStatusFailed,
+	},
+	models.ServiceStatusDeploying: {
+		models.ServiceStatusRunning, models.ServiceStatusRestarting, models.ServiceStatusStopped,
+		models.ServiceStatusFailed,
+	},
+	models.ServiceStatusRunning: {
+		models.ServiceStatusDeploying, models.ServiceStatusRestarting, models.ServiceStatusStopped,
+		models.ServiceStatusSuspended, models.ServiceStatusSleeping, models.ServiceStatusFailed,
+	},
+	models.ServiceStatusRestarting: {
+		models.ServiceStatusDeploying, models.ServiceStatusRunning, models.ServiceStatusStopped,
+		models.ServiceStatusFailed,
+	},
+	models.ServiceStatusSleeping: {
+		models.ServiceStatusDeploying, models.ServiceStatusRunning, models.ServiceStatusRestarting,
+		models.ServiceStatusStopped, models.ServiceStatusSuspended, models.ServiceStatusFailed,
+	},
+	models.ServiceStatusStopped: {
+		models.ServiceStatusDeploying, models.ServiceStatusRunning, models.ServiceStatusRestarting,
+		models.ServiceStatusSuspended,
+	},
+	models.ServiceStatusSuspended: {
+		models.ServiceStatusDeploying, models.ServiceStatusRunning, models.ServiceStatusRestarting,
+		models.ServiceStatusStopped,
+	},
+	models.ServiceStatusFailed: {
+		models.ServiceStatusPending, models.ServiceStatusDeploying, models.ServiceStatusRunning,
+		models.ServiceStatusRestarting, models.ServiceStatusStopped, models.ServiceStatusSuspended,
+	},
+}
+
+// IsValidStatus reports whether status is a known service status
+func IsValidStatus(status models.ServiceStatus) bool {
+	_, ok := allowedTransitions[status]
+	return ok
+}
+
+// CanTransition reports whether a service may move from one status to another.
+// Staying in the same status is always allowed.
+func CanTransition(from, to models.ServiceStatus) bool {
+	if from == to {
+		return true
+	}
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStatus moves a service to a new status, together with any extra column updates.
+// A status change records a service event and is published to the service's WebSocket room.
+// Deleted services and transitions not in allowedTransitions are rejected.
+func SetStatus(serviceID string, to models.ServiceStatus, extra map[string]interface{}) error {
+	db := database.GetDatabase()
+
+	if !IsValidStatus(to) {
+		return fmt.Errorf("%w: unknown status %s", ErrInvalidTransition, to)
+	}
+
+	var service models.Service
+	if err := db.Where("id = ? AND deletedAt IS NULL", serviceID).First(&service).Error; err != nil {
+		return ErrServiceNotFound
+	}
+
+	from := service.Status
+	if !CanTransition(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+
+	updates := map[string]interface{}{
+		"status":    to,
+		"updatedAt": time.Now(),
+	}
+	for key, value := range extra {
+		updates[key] = value
+	}
+
+	// Only apply the update if nobody changed the status since it was read
+	result := db.Model(&models.Service{}).
+		Where("id = ? AND status = ?", serviceID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: status of service %s changed concurrently", ErrInvalidTransition, serviceID)
+	}
+
+	if from == to {
+		return nil
+	}
+
+	db.Create(&models.ServiceEvent{
+		ServiceID: serviceID,
+		Type:      models.EventTypeStatusChanged,
+		Message:   utils.Ptr(fmt.Sprintf("Status changed from %s to %s", from, to)),
+	})
+
+	publishStatus(serviceID, from, to)
+
+	return nil
+}
+
+// publishStatus publishes a status change to Socket.IO via Redis
+func publishStatus(serviceID string, from, to models.ServiceStatus) {
+	roomID := "service:" + serviceID
+	payload := map[string]interface{}{
+		"event": "service_status",
+		"payload": map[string]interface{}{
+			"serviceId":      serviceID,
+			"status":         to,
+			"previousStatus": from,
+			"timestamp":      time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
+		},
+	}
+
+	if err := redis.PublishWebSocketMessage(context.Background(), roomID, payload); err != nil {
+		log.Printf("Failed to publish service status to Socket.IO: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/redis"
+)
+
+var redisServer *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	config.Load()
+	redisServer = miniredis.NewMiniRedis()
+	if err := redisServer.Start(); err != nil {
+		panic(err)
+	}
+	if err := redis.Initialize(&config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+// A restored service can be in any status it was deleted in
+func TestEveryStatusCanMoveToDeploying(t *testing.T) {
+	for status := range allowedTransitions {
+		if !CanTransition(status, models.ServiceStatusDeploying) {
+			t.Errorf("CanTransition(%s, DEPLOYING) = false, want true", status)
+		}
+	}
+}
+
+func TestSetStatusLegalTransition(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "status": string(models.ServiceStatusRunning)})
+	subscriber := redisServer.NewSubscriber()
+	defer subscriber.Close()
+	subscriber.Subscribe(redis.ChannelWebSocket)
+	// Miniredis delivers a publish only once it's read
+	published := make(chan string, 1)
+	go func() {
+		if msg, ok := <-subscriber.Messages(); ok {
+			published <- msg.Message
+		}
+	}()
+
+	if err := SetStatus("svc1", models.ServiceStatusStopped, map[string]interface{}{"stopped": true}); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+
+	// The update only applies while the service is still in the status it was read in
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, "`stopped`") {
+		t.Fatalf("updates = %v, want one setting the status and stopped", updates)
+	}
+	if !strings.Contains(updates[0].SQL, "status = ?") {
+		t.Errorf("update %q doesn't check the status it read", updates[0].SQL)
+	}
+
+	events := db.Statements("INSERT INTO `ServiceEvent`")
+	if len(events) != 1 {
+		t.Fatalf("recorded %d service events, want 1", len(events))
+	}
+	if !containsArg(events[0], "Status changed from RUNNING to STOPPED") {
+		t.Errorf("event args = %v, want the status change message", events[0].Args)
+	}
+
+	select {
+	case msg := <-published:
+		if !strings.Contains(msg, `"status":"STOPPED"`) || !strings.Contains(msg, `"previousStatus":"RUNNING"`) {
+			t.Errorf("published %s, want the status change", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("status change was not published")
+	}
+}
+
+func TestSetStatusIllegalTransition(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "status": string(models.ServiceStatusStopped)})
+
+	err := SetStatus("svc1", models.ServiceStatusFailed, nil)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("SetStatus error = %v, want ErrInvalidTransition", err)
+	}
+	if n := len(db.Statements("UPDATE")) + len(db.Statements("INSERT")); n != 0 {
+		t.Errorf("rejected transition wrote %d statements", n)
+	}
+}
+
+func TestSetStatusLostRace(t *testing.T) {
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "status": string(models.ServiceStatusRunning)})
+	// Someone else changed the status between the read and the update
+	db.OnExec("UPDATE `Service`", 0)
+
+	if err := SetStatus("svc1", models.ServiceStatusStopped, nil); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("SetStatus error = %v, want ErrInvalidTransition", err)
+	}
+	if len(db.Statements("INSERT INTO `ServiceEvent`")) != 0 {
+		t.Error("an event was recorded for a transition that didn't happen")
+	}
+}
+
+func TestSetStatusUnknownService(t *testing.T) {
+	dbtest.New(t)
+	if err := SetStatus("missing", models.ServiceStatusStopped, nil); !errors.Is(err, ErrServiceNotFound) {
+		t.Fatalf("SetStatus error = %v, want ErrServiceNotFound", err)
+	}
+}
+
+func containsArg(statement dbtest.Statement, want string) bool {
+	for _, arg := range statement.Args {
+		if s, ok := arg.(string); ok && s == want {
+			return true
+		}
+		if s, ok := arg.(*string); ok && s != nil && *s == want {
+			return true
+		}
+		if s, ok := arg.(models.ServiceStatus); ok && string(s) == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+// Service types that always run with a persistent volume
+var storageServiceTypes = map[string]bool{
+	"mysql":      true,
+	"postgresql": true,
+	"memory":     true,
+}
+
+// ValidateStorageCapacity checks a storage capacity change against the service and its instance type.
+// Volumes can only be expanded, so shrinking is rejected.
+func ValidateStorageCapacity(service models.Service, instanceType models.InstanceType, capacity int) error {
+	// Web and private services only have storage when a volume was attached at creation
+	if !storageServiceTypes[service.ServiceTypeID] && service.StorageCapacity == nil {
+		return errors.New("This service has no persistent storage")
+	}
+
+	if capacity < 1 {
+		return errors.New("Storage capacity must be at least 1 GB")
+	}
+
+	if service.StorageCapacity != nil && capacity < *service.StorageCapacity {
+		return fmt.Errorf("Storage capacity cannot be reduced from %d GB to %d GB, volumes can only be expanded",
+			*service.StorageCapacity, capacity)
+	}
+
+	maxCapacity := config.Get().MaxStorageCapacity
+	if instanceType.MaxStorageCapacity != nil {
+		maxCapacity = *instanceType.MaxStorageCapacity
+	}
+	if capacity > maxCapacity {
+		return fmt.Errorf("Storage capacity cannot exceed %d GB for the %s instance type", maxCapacity, instanceType.Name)
+	}
+
+	return nil
+}
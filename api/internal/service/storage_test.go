@@ -0,0 +1,47 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+func TestValidateStorageCapacity(t *testing.T) {
+	postgres := models.Service{ServiceTypeID: "postgresql", StorageCapacity: utils.Ptr(10)}
+	small := models.InstanceType{Name: "small", MaxStorageCapacity: utils.Ptr(50)}
+
+	tests := []struct {
+		name         string
+		service      models.Service
+		instanceType models.InstanceType
+		capacity     int
+		wantErr      string
+	}{
+		{name: "expand", service: postgres, instanceType: small, capacity: 20},
+		{name: "same size", service: postgres, instanceType: small, capacity: 10},
+		{name: "expand to the plan maximum", service: postgres, instanceType: small, capacity: 50},
+		{name: "shrink", service: postgres, instanceType: small, capacity: 5, wantErr: "cannot be reduced from 10 GB to 5 GB"},
+		{name: "over the plan maximum", service: postgres, instanceType: small, capacity: 51, wantErr: "cannot exceed 50 GB for the small"},
+		{name: "over the default maximum", service: postgres, instanceType: models.InstanceType{Name: "custom"}, capacity: 101, wantErr: "cannot exceed 100 GB"},
+		{name: "zero", service: postgres, instanceType: small, capacity: 0, wantErr: "at least 1 GB"},
+		{name: "web service without a volume", service: models.Service{ServiceTypeID: "web"}, instanceType: small, capacity: 10, wantErr: "no persistent storage"},
+		{name: "web service with a volume", service: models.Service{ServiceTypeID: "web", StorageCapacity: utils.Ptr(5)}, instanceType: small, capacity: 10},
+		{name: "database without a recorded size", service: models.Service{ServiceTypeID: "mysql"}, instanceType: small, capacity: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStorageCapacity(tt.service, tt.instanceType, tt.capacity)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateStorageCapacity: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateStorageCapacity error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/deployra/deployra/api/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrStaleService is returned when a service was changed since it was read
+var ErrStaleService = errors.New("Service was changed since it was read, reload it and retry")
+
+// UpdateVersioned saves updates of the configuration of a service and bumps its version, only
+// while the service still has the version it was read at. Every writer of the configuration
+// goes through it so concurrent read-modify-writes don't overwrite each other.
+func UpdateVersioned(db *gorm.DB, serviceID string, version int, updates map[string]interface{}) error {
+	versioned := make(map[string]interface{}, len(updates)+1)
+	for column, value := range updates {
+		versioned[column] = value
+	}
+	versioned["version"] = gorm.Expr("version + 1")
+
+	result := db.Model(&models.Service{}).Where("id = ? AND version = ?", serviceID, version).Updates(versioned)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaleService
+	}
+	return nil
+}
+
+// CheckVersion returns ErrStaleService when a client read a service at another version than
+// its current one. Clients that didn't send the version they read pass nil.
+func CheckVersion(service models.Service, version *int) error {
+	if version != nil && *version != service.Version {
+		return ErrStaleService
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+)
+
+func TestCheckVersion(t *testing.T) {
+	service := models.Service{Version: 3}
+	same, older := 3, 2
+
+	if err := CheckVersion(service, nil); err != nil {
+		t.Fatalf("CheckVersion without a version = %v, want nil", err)
+	}
+	if err := CheckVersion(service, &same); err != nil {
+		t.Fatalf("CheckVersion(3) = %v, want nil", err)
+	}
+	if err := CheckVersion(service, &older); !errors.Is(err, ErrStaleService) {
+		t.Fatalf("CheckVersion(2) = %v, want ErrStaleService", err)
+	}
+}
+
+// versionedRow answers the versioned updates of svc1 like a row at version with replicas
+// replicas, the row is changed only when the update was made at its current version
+type versionedRow struct {
+	replicas int64
+	version  int
+}
+
+func (r *versionedRow) answer(args []driver.Value) int64 {
+	// Updates are sent as replicas, updatedAt, then the id and version they were read at
+	if fmt.Sprint(args[len(args)-2]) != "svc1" || fmt.Sprint(args[len(args)-1]) != fmt.Sprint(r.version) {
+		return 0
+	}
+	r.replicas = args[0].(int64)
+	r.version++
+	return 1
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	db := dbtest.New(t)
+	row := &versionedRow{replicas: 1, version: 4}
+	db.OnExecFunc("UPDATE `Service`", row.answer)
+
+	// A writer that read the service before the last update
+	if err := UpdateVersioned(database.GetDatabase(), "svc1", 3, map[string]interface{}{"replicas": int64(2)}); !errors.Is(err, ErrStaleService) {
+		t.Fatalf("UpdateVersioned at version 3 = %v, want ErrStaleService", err)
+	}
+	if row.replicas != 1 || row.version != 4 {
+		t.Errorf("row = %+v after a stale update, want it unchanged", *row)
+	}
+
+	if err := UpdateVersioned(database.GetDatabase(), "svc1", 4, map[string]interface{}{"replicas": int64(2)}); err != nil {
+		t.Fatalf("UpdateVersioned at version 4 = %v, want nil", err)
+	}
+	if row.replicas != 2 || row.version != 5 {
+		t.Errorf("row = %+v, want 2 replicas at version 5", *row)
+	}
+
+	updates := db.Statements("UPDATE `Service`")
+	if len(updates) != 2 || !strings.Contains(updates[1].SQL, "`version`=version + 1") || !strings.Contains(updates[1].SQL, "id = ? AND version = ?") {
+		t.Errorf("updates = %v, want the version bumped only at the version read", updates)
+	}
+}
@@ -0,0 +1,117 @@
+package subdomain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Generation strategies, selected with SUBDOMAIN_STRATEGY
+const (
+	// StrategyRandom always appends a random suffix to the service name
+	StrategyRandom = "random"
+	// StrategyName uses the plain service name and only appends a suffix on collision
+	StrategyName = "name"
+)
+
+// How many generated subdomains are tried before giving up
+const maxAttempts = 5
+
+// RFC 1123 label, lowercase only
+var labelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Subdomains kept for the platform itself
+var reserved = map[string]bool{
+	"admin": true, "api": true, "app": true, "assets": true, "auth": true, "blog": true,
+	"cdn": true, "dashboard": true, "dev": true, "docs": true, "ftp": true, "help": true,
+	"imap": true, "login": true, "mail": true, "ns1": true, "ns2": true, "pop": true,
+	"registry": true, "smtp": true, "staging": true, "static": true, "status": true,
+	"support": true, "www": true,
+}
+
+// ErrTaken is returned when another service already uses the subdomain
+var ErrTaken = errors.New("Subdomain is already taken")
+
+// Validate checks that a subdomain is a valid DNS label and not reserved
+func Validate(label string) error {
+	if !labelPattern.MatchString(label) {
+		return errors.New("Subdomain must be 1-63 lowercase letters, digits or hyphens and cannot start or end with a hyphen")
+	}
+	if reserved[label] {
+		return fmt.Errorf("Subdomain %s is reserved", label)
+	}
+	return nil
+}
+
+// IsAvailable reports whether no service, deleted or not, uses the subdomain
+func IsAvailable(db *gorm.DB, label string) (bool, error) {
+	var count int64
+	if err := db.Model(&models.Service{}).Where("subdomain = ?", label).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// Resolve returns the requested subdomain after validating it and checking it is free,
+// or generates one from the service name when none was requested
+func Resolve(db *gorm.DB, requested *string, name string) (string, error) {
+	if requested != nil && strings.TrimSpace(*requested) != "" {
+		label := strings.ToLower(strings.TrimSpace(*requested))
+		if err := Validate(label); err != nil {
+			return "", err
+		}
+		available, err := IsAvailable(db, label)
+		if err != nil {
+			return "", err
+		}
+		if !available {
+			return "", ErrTaken
+		}
+		return label, nil
+	}
+
+	return Generate(db, name)
+}
+
+// Generate builds a free subdomain from a service name using the configured strategy
+func Generate(db *gorm.DB, name string) (string, error) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var label string
+		if attempt == 0 && config.Get().SubdomainStrategy == StrategyName {
+			label = base(name)
+		} else {
+			label = utils.GenerateSubdomain(base(name))
+		}
+
+		if Validate(label) != nil {
+			continue
+		}
+		available, err := IsAvailable(db, label)
+		if err != nil {
+			return "", err
+		}
+		if available {
+			return label, nil
+		}
+	}
+
+	return "", fmt.Errorf("could not generate a free subdomain for %s", name)
+}
+
+// base turns a service name into a label short enough to take a random suffix
+func base(name string) string {
+	label := utils.NormalizeSubdomain(name)
+	if len(label) > 54 {
+		label = strings.TrimRight(label[:54], "-")
+	}
+	if label == "" {
+		label = "service"
+	}
+	return label
+}
@@ -0,0 +1,171 @@
+package subdomain
+
+import (
+	"database/sql/driver"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	config.Load()
+	os.Exit(m.Run())
+}
+
+// takenSubdomains makes the given subdomains used by a service and returns the checked ones
+func takenSubdomains(t *testing.T, taken ...string) *[]string {
+	t.Helper()
+	db := dbtest.New(t)
+	var checked []string
+	db.OnQueryFunc("FROM `Service`", func(args []driver.Value) []dbtest.Row {
+		label, _ := args[0].(string)
+		checked = append(checked, label)
+		for _, subdomain := range taken {
+			if label == subdomain {
+				return []dbtest.Row{{"count(*)": int64(1)}}
+			}
+		}
+		return []dbtest.Row{{"count(*)": int64(0)}}
+	})
+	return &checked
+}
+
+func useStrategy(t *testing.T, strategy string) {
+	t.Helper()
+	cfg := config.Get()
+	previous := cfg.SubdomainStrategy
+	cfg.SubdomainStrategy = strategy
+	t.Cleanup(func() { cfg.SubdomainStrategy = previous })
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{"my-app", "a", "app2", strings.Repeat("a", 63)}
+	for _, label := range valid {
+		if err := Validate(label); err != nil {
+			t.Errorf("Validate(%q): %v", label, err)
+		}
+	}
+
+	invalid := []string{"", "-app", "app-", "My-App", "my_app", "my.app", strings.Repeat("a", 64)}
+	for _, label := range invalid {
+		if err := Validate(label); err == nil {
+			t.Errorf("Validate(%q) = nil, want an error", label)
+		}
+	}
+
+	for _, label := range []string{"www", "api", "admin", "dashboard"} {
+		if err := Validate(label); err == nil || !strings.Contains(err.Error(), "reserved") {
+			t.Errorf("Validate(%q) = %v, want it reserved", label, err)
+		}
+	}
+}
+
+func TestResolveExplicitSubdomain(t *testing.T) {
+	checked := takenSubdomains(t)
+
+	label, err := Resolve(database.GetDatabase(), utils.Ptr("  My-Shop "), "web")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if label != "my-shop" {
+		t.Errorf("label = %q, want my-shop", label)
+	}
+	if len(*checked) != 1 || (*checked)[0] != "my-shop" {
+		t.Errorf("checked %v, want my-shop checked for uniqueness", *checked)
+	}
+}
+
+func TestResolveRejectsReservedWords(t *testing.T) {
+	checked := takenSubdomains(t)
+
+	_, err := Resolve(database.GetDatabase(), utils.Ptr("www"), "web")
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Fatalf("Resolve error = %v, want www reserved", err)
+	}
+	if len(*checked) != 0 {
+		t.Errorf("a reserved word was looked up: %v", *checked)
+	}
+}
+
+func TestResolveRejectsTakenSubdomain(t *testing.T) {
+	takenSubdomains(t, "my-shop")
+
+	if _, err := Resolve(database.GetDatabase(), utils.Ptr("my-shop"), "web"); !errors.Is(err, ErrTaken) {
+		t.Fatalf("Resolve error = %v, want ErrTaken", err)
+	}
+}
+
+func TestResolveGeneratesWithoutRequest(t *testing.T) {
+	useStrategy(t, StrategyName)
+	takenSubdomains(t)
+
+	for _, requested := range []*string{nil, utils.Ptr("  ")} {
+		label, err := Resolve(database.GetDatabase(), requested, "My Shop")
+		if err != nil {
+			t.Fatalf("Resolve: %v", err)
+		}
+		if label != "my-shop" {
+			t.Errorf("label = %q, want my-shop generated from the name", label)
+		}
+	}
+}
+
+func TestGenerateNameStrategyOnCollision(t *testing.T) {
+	useStrategy(t, StrategyName)
+	checked := takenSubdomains(t, "my-shop")
+
+	label, err := Generate(database.GetDatabase(), "my-shop")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if label == "my-shop" || !strings.HasPrefix(label, "my-shop-") {
+		t.Errorf("label = %q, want my-shop with a suffix", label)
+	}
+	if len(*checked) != 2 || (*checked)[0] != "my-shop" {
+		t.Errorf("checked %v, want the plain name then one suffixed label", *checked)
+	}
+}
+
+func TestGenerateRandomStrategy(t *testing.T) {
+	useStrategy(t, StrategyRandom)
+	takenSubdomains(t)
+
+	label, err := Generate(database.GetDatabase(), "my-shop")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !strings.HasPrefix(label, "my-shop-") {
+		t.Errorf("label = %q, want my-shop with a random suffix", label)
+	}
+	if err := Validate(label); err != nil {
+		t.Errorf("generated label %q is invalid: %v", label, err)
+	}
+}
+
+func TestGenerateGivesUpWhenEverythingIsTaken(t *testing.T) {
+	useStrategy(t, StrategyRandom)
+	db := dbtest.New(t)
+	db.OnQuery("FROM `Service`", dbtest.Row{"count(*)": int64(1)})
+
+	if _, err := Generate(database.GetDatabase(), "my-shop"); err == nil {
+		t.Fatal("Generate succeeded with every subdomain taken")
+	}
+	if checks := db.Statements("FROM `Service`"); len(checks) != maxAttempts {
+		t.Errorf("checked %d subdomains, want %d", len(checks), maxAttempts)
+	}
+}
+
+func TestBaseShortensLongNames(t *testing.T) {
+	if got := base(strings.Repeat("a", 80)); len(got) > 54 {
+		t.Errorf("base of a long name has %d characters, want at most 54", len(got))
+	}
+	if got := base("!!!"); got != "service" {
+		t.Errorf("base(%q) = %q, want service", "!!!", got)
+	}
+}
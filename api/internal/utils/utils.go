@@ -27,14 +27,19 @@ func GenerateShortID() string {
 	return string(result)
 }
 
-// GenerateSubdomain generates a unique subdomain from service name
-func GenerateSubdomain(name string) string {
+// NormalizeSubdomain lowercases a name and replaces everything but letters and digits with single hyphens
+func NormalizeSubdomain(name string) string {
 	base := strings.ToLower(name)
 	reg := regexp.MustCompile(`[^a-z0-9]`)
 	base = reg.ReplaceAllString(base, "-")
 	reg = regexp.MustCompile(`-+`)
 	base = reg.ReplaceAllString(base, "-")
-	base = strings.Trim(base, "-")
+	return strings.Trim(base, "-")
+}
+
+// GenerateSubdomain generates a unique subdomain from service name
+func GenerateSubdomain(name string) string {
+	base := NormalizeSubdomain(name)
 
 	suffix := make([]byte, 8)
 	for i := 0; i < 8; i++ {
@@ -0,0 +1,297 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/crypto"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/google/uuid"
+)
+
+// How much of a receiver's response is read before the connection is closed, the body
+// itself is never kept
+const maxResponseBody = 4 << 10
+
+// ErrNoWebhook is returned when replaying a delivery of a project without a webhook URL
+var ErrNoWebhook = errors.New("project has no webhook URL")
+
+// ErrForbiddenAddress is returned when a webhook URL resolves to an address that isn't public
+var ErrForbiddenAddress = errors.New("webhook URL must resolve to a public address")
+
+// DeploymentEvents are the deployment statuses a project can subscribe its webhook to
+var DeploymentEvents = map[string]bool{
+	"building":  true,
+	"builded":   true,
+	"deploying": true,
+	"deployed":  true,
+	"failed":    true,
+	"cancelled": true,
+}
+
+// Subscribed reports whether the webhook of a project wants a deployment status, projects
+// without a subscription get every status
+func Subscribed(project models.Project, status string) bool {
+	var events []string
+	if len(project.WebhookEvents) == 0 || json.Unmarshal(project.WebhookEvents, &events) != nil || len(events) == 0 {
+		return true
+	}
+
+	status = strings.ToLower(status)
+	for _, event := range events {
+		if event == status {
+			return true
+		}
+	}
+	return false
+}
+
+// client only dials public addresses, also after DNS resolution, and doesn't follow
+// redirects, so a webhook can't reach the cluster or the metadata service
+var client = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: dialControl,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// dialControl rejects connections to loopback, private, link-local and other non-public
+// addresses
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !PublicAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
+
+// PublicAddr reports whether an address may be sent webhooks
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// Carrier-grade NAT addresses (RFC 6598) aren't private but aren't reachable publicly either
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// validateURL checks the URL of a delivery before it is sent
+var validateURL = ValidateURL
+
+// ValidateURL checks that a webhook URL is an absolute http(s) URL whose host, when it is
+// an IP address, is public. Host names are checked when they are dialed.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("webhook URL must be an absolute http or https URL")
+	}
+	if host := u.Hostname(); strings.EqualFold(host, "localhost") {
+		return ErrForbiddenAddress
+	} else if addr, err := netip.ParseAddr(host); err == nil && !PublicAddr(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// Send records a delivery of an event to the webhook of a project, if it has one, and sends
+// it in the background
+func Send(project models.Project, event string, payload interface{}) {
+	if project.WebhookUrl == nil || *project.WebhookUrl == "" {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to marshal webhook payload: %v", err)
+		return
+	}
+
+	delivery := &models.WebhookDelivery{
+		ID:        uuid.New().String(),
+		ProjectID: project.ID,
+		Event:     event,
+		URL:       *project.WebhookUrl,
+		Payload:   body,
+	}
+	if err := database.GetDatabase().Create(delivery).Error; err != nil {
+		log.Printf("Failed to record webhook delivery: %v", err)
+	}
+
+	go deliver(delivery, signingKey(project))
+}
+
+// SendDeploymentStatus sends a deployment status change to the webhook of the deployment's
+// project, if it subscribed to the status. The deployment needs Service.Project.Organization
+// preloaded.
+func SendDeploymentStatus(deployment models.Deployment, status, message string) {
+	// Check if project has a deployment webhook configured
+	if deployment.Service.Project.WebhookUrl == nil || *deployment.Service.Project.WebhookUrl == "" {
+		return
+	}
+
+	// Only send the statuses the project subscribed to
+	if !Subscribed(deployment.Service.Project, status) {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"event":     "deployment_status_changed",
+		"timestamp": time.Now().Format(time.RFC3339),
+		"deployment": map[string]interface{}{
+			"id":          deployment.ID,
+			"status":      status,
+			"message":     message,
+			"createdAt":   deployment.CreatedAt,
+			"completedAt": deployment.CompletedAt,
+		},
+		"service": map[string]interface{}{
+			"id":   deployment.Service.ID,
+			"name": deployment.Service.Name,
+			"type": deployment.Service.ServiceTypeID,
+		},
+		"project": map[string]interface{}{
+			"id":   deployment.Service.Project.ID,
+			"name": deployment.Service.Project.Name,
+		},
+		"organization": map[string]interface{}{
+			"id":   deployment.Service.Project.Organization.ID,
+			"name": deployment.Service.Project.Organization.Name,
+		},
+	}
+
+	Send(deployment.Service.Project, "deployment_status_changed", payload)
+}
+
+// Replay sends the payload of a past delivery again, to the current webhook URL of its
+// project, as a new delivery and returns it once the receiver answered
+func Replay(original models.WebhookDelivery, project models.Project) (*models.WebhookDelivery, error) {
+	if project.WebhookUrl == nil || *project.WebhookUrl == "" {
+		return nil, ErrNoWebhook
+	}
+
+	delivery := &models.WebhookDelivery{
+		ID:         uuid.New().String(),
+		ProjectID:  project.ID,
+		Event:      original.Event,
+		URL:        *project.WebhookUrl,
+		Payload:    original.Payload,
+		ReplayOfID: utils.Ptr(original.ID),
+	}
+	if err := database.GetDatabase().Create(delivery).Error; err != nil {
+		return nil, err
+	}
+
+	deliver(delivery, signingKey(project))
+	return delivery, nil
+}
+
+// signingKey returns the decrypted webhook secret of a project, empty when it has none
+func signingKey(project models.Project) string {
+	if project.WebhookSecret == nil {
+		return ""
+	}
+	return crypto.DecryptWithFallback(*project.WebhookSecret)
+}
+
+// Sign returns the signature of a payload sent in the X-Deployra-Signature header, the
+// hex HMAC-SHA256 of the body keyed with the webhook secret
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts a delivery to its URL and records the outcome
+func deliver(delivery *models.WebhookDelivery, secret string) {
+	started := time.Now()
+	statusCode, err := post(delivery, secret)
+	delivery.DurationMs = utils.Ptr(time.Since(started).Milliseconds())
+
+	if err != nil {
+		log.Printf("Failed to send webhook %s to %s: %v", delivery.Event, delivery.URL, err)
+		delivery.Error = utils.Ptr(err.Error())
+	} else {
+		log.Printf("Webhook sent to %s - Status: %d", delivery.URL, statusCode)
+		delivery.StatusCode = &statusCode
+	}
+
+	if err := database.GetDatabase().Model(&models.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{
+			"statusCode": delivery.StatusCode,
+			"error":      delivery.Error,
+			"durationMs": delivery.DurationMs,
+		}).Error; err != nil {
+		log.Printf("Failed to record webhook delivery result: %v", err)
+	}
+}
+
+// post sends the payload of a delivery, signed if there is a secret, and returns the status
+// of the response
+func post(delivery *models.WebhookDelivery, secret string) (int, error) {
+	if err := validateURL(delivery.URL); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("POST", delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Deployra-Webhook/1.0")
+	req.Header.Set("X-Deployra-Event", delivery.Event)
+	req.Header.Set("X-Deployra-Delivery", delivery.ID)
+	if secret != "" {
+		req.Header.Set("X-Deployra-Signature", Sign(secret, delivery.Payload))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// The body may be anything the address answers with, it is only drained
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,326 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/utils"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.0.0.5":        false,
+		"172.16.3.4":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"100.64.0.1":      false,
+		"::ffff:10.0.0.1": false,
+	}
+	for raw, want := range tests {
+		if got := PublicAddr(netip.MustParseAddr(raw)); got != want {
+			t.Errorf("PublicAddr(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	valid := []string{"https://example.com/hook", "http://93.184.216.34:8080/hook"}
+	for _, raw := range valid {
+		if err := ValidateURL(raw); err != nil {
+			t.Errorf("ValidateURL(%q) = %v, want nil", raw, err)
+		}
+	}
+
+	invalid := []string{
+		"ftp://example.com/hook",
+		"/relative",
+		"http://localhost/hook",
+		"http://127.0.0.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]:80/",
+	}
+	for _, raw := range invalid {
+		if err := ValidateURL(raw); err == nil {
+			t.Errorf("ValidateURL(%q) = nil, want an error", raw)
+		}
+	}
+}
+
+func TestDialControlRejectsPrivateAddresses(t *testing.T) {
+	if err := dialControl("tcp", "10.1.2.3:443", nil); !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("dialControl(10.1.2.3) = %v, want ErrForbiddenAddress", err)
+	}
+	if err := dialControl("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Fatalf("dialControl(93.184.216.34) = %v, want nil", err)
+	}
+}
+
+func TestPostRefusesLoopbackReceiver(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	_, err := post(&models.WebhookDelivery{ID: "d1", Event: "test", URL: server.URL, Payload: []byte(`{}`)}, "")
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("post to %s = %v, want ErrForbiddenAddress", server.URL, err)
+	}
+	if called {
+		t.Fatal("loopback receiver was called")
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	if err := client.CheckRedirect(req, []*http.Request{req}); !errors.Is(err, http.ErrUseLastResponse) {
+		t.Fatalf("CheckRedirect = %v, want http.ErrUseLastResponse", err)
+	}
+}
+
+// Every status a delivery is sent for can be subscribed to, a project subscribing to them all
+// gets the same deliveries as one without a subscription
+func TestDeploymentEventsCoverDeliveredStatuses(t *testing.T) {
+	statuses := []models.DeploymentStatus{
+		models.DeploymentStatusBuilding, models.DeploymentStatusBuilded, models.DeploymentStatusDeploying,
+		models.DeploymentStatusDeployed, models.DeploymentStatusFailed, models.DeploymentStatusCancelled,
+	}
+	for _, status := range statuses {
+		if !DeploymentEvents[strings.ToLower(string(status))] {
+			t.Errorf("status %s can't be subscribed to", status)
+		}
+	}
+
+	project := models.Project{WebhookEvents: []byte(`["builded"]`)}
+	if !Subscribed(project, string(models.DeploymentStatusBuilded)) {
+		t.Error("project subscribed to builded doesn't get BUILDED")
+	}
+	if Subscribed(project, string(models.DeploymentStatusDeployed)) {
+		t.Error("project subscribed to builded gets DEPLOYED")
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	tests := []struct {
+		name   string
+		events string
+		status models.DeploymentStatus
+		want   bool
+	}{
+		{name: "subscribed", events: `["failed","deployed"]`, status: models.DeploymentStatusFailed, want: true},
+		{name: "not subscribed", events: `["failed"]`, status: models.DeploymentStatusDeployed, want: false},
+		// Projects without a subscription, or with one that can't be read, get every status
+		{name: "no subscription", events: "", status: models.DeploymentStatusDeployed, want: true},
+		{name: "empty subscription", events: `[]`, status: models.DeploymentStatusDeployed, want: true},
+		{name: "null subscription", events: `null`, status: models.DeploymentStatusBuilding, want: true},
+		{name: "invalid subscription", events: `"failed"`, status: models.DeploymentStatusDeployed, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := models.Project{WebhookEvents: []byte(tt.events)}
+			if got := Subscribed(project, string(tt.status)); got != tt.want {
+				t.Errorf("Subscribed(%s, %s) = %v, want %v", tt.events, tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+// receiver is a webhook receiver answering with status, the requests it got are sent on the
+// returned channel. Deliveries to it are allowed for the test although it listens on loopback.
+func receiver(t *testing.T, status int) (string, <-chan receivedRequest) {
+	t.Helper()
+	requests := make(chan receivedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- receivedRequest{header: r.Header, body: string(body)}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	previousClient, previousValidate := client, validateURL
+	client = server.Client()
+	validateURL = func(string) error { return nil }
+	t.Cleanup(func() { client, validateURL = previousClient, previousValidate })
+	return server.URL, requests
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   string
+}
+
+// waitForResult waits until the outcome of n deliveries was recorded
+func waitForResult(t *testing.T, db *dbtest.DB, n int) []dbtest.Statement {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		updates := db.Statements("UPDATE `WebhookDelivery`")
+		if len(updates) >= n {
+			return updates
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d webhook deliveries finished, want %d", len(updates), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// containsArg reports whether a statement was sent with an argument printing as want,
+// pointers print as their value
+func containsArg(statement dbtest.Statement, want string) bool {
+	for _, arg := range statement.Args {
+		if v := reflect.ValueOf(arg); v.Kind() == reflect.Pointer && !v.IsNil() {
+			arg = v.Elem().Interface()
+		}
+		if fmt.Sprint(arg) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSendRecordsDelivery(t *testing.T) {
+	db := dbtest.New(t)
+	url, requests := receiver(t, http.StatusAccepted)
+	project := models.Project{ID: "proj1", WebhookUrl: &url}
+
+	Send(project, "deployment_status_changed", map[string]string{"status": "deployed"})
+
+	inserts := db.Statements("INSERT INTO `WebhookDelivery`")
+	if len(inserts) != 1 || !containsArg(inserts[0], "proj1") || !containsArg(inserts[0], "deployment_status_changed") || !containsArg(inserts[0], url) {
+		t.Fatalf("inserts = %v, want a delivery of the event to the project's URL", inserts)
+	}
+
+	select {
+	case got := <-requests:
+		if got.body != `{"status":"deployed"}` || got.header.Get("X-Deployra-Event") != "deployment_status_changed" {
+			t.Errorf("receiver got %s with headers %v, want the payload of the event", got.body, got.header)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the receiver got no request")
+	}
+
+	updates := waitForResult(t, db, 1)
+	if !containsArg(updates[0], "202") {
+		t.Errorf("result = %v, want status code 202 recorded", updates[0])
+	}
+}
+
+func TestSendWithoutWebhookRecordsNothing(t *testing.T) {
+	db := dbtest.New(t)
+
+	Send(models.Project{ID: "proj1"}, "deployment_status_changed", map[string]string{"status": "deployed"})
+	if inserts := db.Statements("INSERT INTO `WebhookDelivery`"); len(inserts) != 0 {
+		t.Errorf("recorded a delivery of a project without a webhook: %v", inserts)
+	}
+}
+
+// deployment is a deployment of web in a project with a webhook to url subscribed to events
+func deployment(url, events string) models.Deployment {
+	return models.Deployment{
+		ID: "dep1",
+		Service: models.Service{ID: "svc1", Name: "web", Project: models.Project{
+			ID: "proj1", WebhookUrl: &url, WebhookEvents: []byte(events),
+		}},
+	}
+}
+
+func TestSendDeploymentStatusSkipsUnsubscribedStatus(t *testing.T) {
+	db := dbtest.New(t)
+	url, requests := receiver(t, http.StatusOK)
+
+	SendDeploymentStatus(deployment(url, `["failed"]`), string(models.DeploymentStatusDeployed), "Deployed")
+	if inserts := db.Statements("INSERT INTO `WebhookDelivery`"); len(inserts) != 0 {
+		t.Errorf("recorded a delivery of an unsubscribed status: %v", inserts)
+	}
+	select {
+	case got := <-requests:
+		t.Errorf("receiver got %s for an unsubscribed status", got.body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSendDeploymentStatusSendsSubscribedStatus(t *testing.T) {
+	for _, events := range []string{`["failed"]`, "", `not json`} {
+		db := dbtest.New(t)
+		url, requests := receiver(t, http.StatusOK)
+
+		SendDeploymentStatus(deployment(url, events), string(models.DeploymentStatusFailed), "Build failed")
+		if inserts := db.Statements("INSERT INTO `WebhookDelivery`"); len(inserts) != 1 {
+			t.Errorf("events %q: inserts = %v, want the failure delivered", events, inserts)
+			continue
+		}
+		select {
+		case got := <-requests:
+			if !strings.Contains(got.body, `"status":"FAILED"`) {
+				t.Errorf("events %q: receiver got %s, want the FAILED status", events, got.body)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("events %q: the receiver got no request", events)
+		}
+		waitForResult(t, db, 1)
+	}
+}
+
+func TestReplaySendsOriginalPayload(t *testing.T) {
+	db := dbtest.New(t)
+	url, requests := receiver(t, http.StatusOK)
+	project := models.Project{ID: "proj1", WebhookUrl: &url}
+	original := models.WebhookDelivery{
+		ID: "delivery1", ProjectID: "proj1", Event: "deployment_status_changed",
+		URL: "https://old.example.com/hook", Payload: []byte(`{"status":"failed"}`),
+	}
+
+	delivery, err := Replay(original, project)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	// A new delivery to the current URL, pointing at the original one
+	if delivery.ID == original.ID || utils.PtrValue(delivery.ReplayOfID, "") != "delivery1" || delivery.URL != url {
+		t.Errorf("delivery = %+v, want a new delivery to %s replaying delivery1", delivery, url)
+	}
+	if utils.PtrValue(delivery.StatusCode, 0) != http.StatusOK {
+		t.Errorf("status code = %v, want %d", delivery.StatusCode, http.StatusOK)
+	}
+
+	inserts := db.Statements("INSERT INTO `WebhookDelivery`")
+	if len(inserts) != 1 || !containsArg(inserts[0], delivery.ID) || !containsArg(inserts[0], "delivery1") {
+		t.Errorf("inserts = %v, want the replay recorded", inserts)
+	}
+	got := <-requests
+	if got.body != `{"status":"failed"}` || got.header.Get("X-Deployra-Delivery") != delivery.ID {
+		t.Errorf("receiver got %s with headers %v, want the original payload as the new delivery", got.body, got.header)
+	}
+	if updates := db.Statements("UPDATE `WebhookDelivery`"); len(updates) != 1 || !containsArg(updates[0], "200") {
+		t.Errorf("results = %v, want status code 200 recorded", updates)
+	}
+}
+
+func TestReplayWithoutWebhook(t *testing.T) {
+	db := dbtest.New(t)
+
+	if _, err := Replay(models.WebhookDelivery{ID: "delivery1"}, models.Project{ID: "proj1"}); !errors.Is(err, ErrNoWebhook) {
+		t.Errorf("Replay error = %v, want %v", err, ErrNoWebhook)
+	}
+	if inserts := db.Statements("INSERT INTO `WebhookDelivery`"); len(inserts) != 0 {
+		t.Errorf("recorded a replay of a project without a webhook: %v", inserts)
+	}
+}
@@ -0,0 +1,206 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database"
+	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
+	"github.com/deployra/deployra/api/internal/utils"
+	"github.com/deployra/deployra/api/pkg/kubernetes"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Shells a user may open, "sh" is used unless another one is requested
+var execShells = map[string][]string{
+	"sh":   {"/bin/sh"},
+	"bash": {"/bin/bash"},
+}
+
+// getServicePod looks up the pod a shell is opened in, tests replace it
+var getServicePod = kubernetes.GetServicePod
+
+// ExecMessage is a message exchanged on an exec WebSocket.
+// Clients send "stdin" and "resize", the server sends "stdout", "error" and "exit".
+type ExecMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+}
+
+// ExecUpgradeMiddleware only lets WebSocket upgrades through when pod exec is enabled
+func ExecUpgradeMiddleware(c *fiber.Ctx) error {
+	if !config.Get().PodExecEnabled {
+		return fiber.ErrNotFound
+	}
+	return UpgradeMiddleware(c)
+}
+
+// ExecHandler bridges a WebSocket to a shell in a pod of a service
+// GET /api/services/:serviceId/pods/:podId/exec
+func ExecHandler(c *websocket.Conn) {
+	cfg := config.Get()
+	db := database.GetDatabase()
+
+	var writeMu sync.Mutex
+	send := func(msg ExecMessage) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return c.WriteJSON(msg)
+	}
+	fail := func(message string) {
+		send(ExecMessage{Type: "error", Data: message})
+		c.Close()
+	}
+
+	// Browsers can't set headers on WebSocket requests, so the token comes as a query param
+	claims, err := parseJWT(c.Query("token"), cfg.JWTSecret)
+	if err != nil {
+		fail("Invalid token")
+		return
+	}
+
+	var user models.User
+	if err := db.Where("id = ? AND deletedAt IS NULL", claims.UserID).First(&user).Error; err != nil {
+		fail("User not found")
+		return
+	}
+
+	serviceID := c.Params("serviceId")
+	podName := c.Params("podId")
+
+	command, ok := execShells[c.Query("shell", "sh")]
+	if !ok {
+		fail("Unsupported shell")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serviceRegion, namespace, err := authorizeExec(ctx, user.ID, serviceID, podName)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+
+	// Audit the session
+	started := time.Now()
+	log.Printf("[Audit] User %s (%s) opened a shell on pod %s/%s of service %s", user.ID, user.Email, namespace, podName, serviceID)
+	db.Create(&models.ServiceEvent{
+		ServiceID: serviceID,
+		Type:      models.EventTypePodExec,
+		Message:   utils.Ptr(fmt.Sprintf("Shell session opened on pod %s by %s", podName, user.Email)),
+	})
+
+	stdinReader, stdinWriter := io.Pipe()
+	resize := make(chan kubernetes.TerminalSize, 1)
+
+	// Read client messages until the socket closes
+	go func() {
+		defer cancel()
+		defer stdinWriter.Close()
+
+		for {
+			_, data, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var msg ExecMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+
+			switch msg.Type {
+			case "stdin":
+				if _, err := stdinWriter.Write([]byte(msg.Data)); err != nil {
+					return
+				}
+			case "resize":
+				if msg.Cols == 0 || msg.Rows == 0 {
+					continue
+				}
+				// Only the latest size matters
+				select {
+				case <-resize:
+				default:
+				}
+				resize <- kubernetes.TerminalSize{Width: msg.Cols, Height: msg.Rows}
+			}
+		}
+	}()
+
+	output := &execOutput{send: send}
+	err = kubernetes.ExecInPod(ctx, kubernetes.ExecOptions{
+		Region:    serviceRegion,
+		Namespace: namespace,
+		PodName:   podName,
+		Container: serviceID,
+		Command:   command,
+		Stdin:     stdinReader,
+		Stdout:    output,
+		TTY:       true,
+		Resize:    resize,
+	})
+
+	exit := ExecMessage{Type: "exit"}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		exit.Data = err.Error()
+	}
+	send(exit)
+	c.Close()
+
+	log.Printf("[Audit] User %s closed the shell on pod %s/%s after %s", user.ID, namespace, podName, time.Since(started).Round(time.Second))
+}
+
+// authorizeExec checks that the user owns the service and that the pod belongs to it,
+// and returns the region and namespace of the pod
+func authorizeExec(ctx context.Context, userID, serviceID, podName string) (string, string, error) {
+	db := database.GetDatabase()
+
+	if serviceID == "" || podName == "" {
+		return "", "", errors.New("Missing required parameters")
+	}
+
+	var service models.Service
+	if err := db.Preload("Project.Organization").
+		Where("id = ? AND deletedAt IS NULL", serviceID).
+		First(&service).Error; err != nil {
+		return "", "", errors.New("Service not found")
+	}
+
+	if service.Project.Organization.UserID != userID {
+		return "", "", errors.New("Access denied")
+	}
+
+	// The pod must live in the service's namespace and carry its label
+	serviceRegion := region.Of(service)
+	if _, err := getServicePod(ctx, serviceRegion, service.ProjectID, serviceID, podName); err != nil {
+		return "", "", errors.New("Pod not found")
+	}
+
+	return serviceRegion, service.ProjectID, nil
+}
+
+// execOutput forwards exec output to the WebSocket as stdout messages
+type execOutput struct {
+	send func(ExecMessage) error
+}
+
+func (o *execOutput) Write(p []byte) (int, error) {
+	if err := o.send(ExecMessage{Type: "stdout", Data: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,141 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/deployra/deployra/api/internal/config"
+	"github.com/deployra/deployra/api/internal/database/dbtest"
+	"github.com/deployra/deployra/api/pkg/kubernetes"
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMain(m *testing.M) {
+	config.Load()
+	os.Exit(m.Run())
+}
+
+// stubPods makes getServicePod find the pods in pods, keyed by namespace and name, like
+// the Kubernetes client does: a pod is only found in its namespace and with the service label
+func stubPods(t *testing.T, pods ...*corev1.Pod) {
+	t.Helper()
+	previous := getServicePod
+	getServicePod = func(_ context.Context, _, namespace, serviceID, podName string) (*corev1.Pod, error) {
+		for _, pod := range pods {
+			if pod.Namespace == namespace && pod.Name == podName && pod.Labels["service"] == serviceID {
+				return pod, nil
+			}
+		}
+		return nil, kubernetes.ErrPodNotFound
+	}
+	t.Cleanup(func() { getServicePod = previous })
+}
+
+func execPod(namespace, name, serviceID string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{"service": serviceID},
+	}}
+}
+
+// ownedService answers service lookups with a service of proj1 owned by ownerID
+func ownedService(db *dbtest.DB, ownerID string) {
+	db.OnQuery("FROM `Service`", dbtest.Row{"id": "svc1", "projectId": "proj1"})
+	db.OnQuery("FROM `Project`", dbtest.Row{"id": "proj1", "organizationId": "org1"})
+	db.OnQuery("FROM `Organization`", dbtest.Row{"id": "org1", "userId": ownerID})
+}
+
+func TestAuthorizeExec(t *testing.T) {
+	db := dbtest.New(t)
+	ownedService(db, "user1")
+	stubPods(t, execPod("proj1", "svc1-7d9f-xyz", "svc1"))
+
+	_, namespace, err := authorizeExec(context.Background(), "user1", "svc1", "svc1-7d9f-xyz")
+	if err != nil {
+		t.Fatalf("authorizeExec: %v", err)
+	}
+	if namespace != "proj1" {
+		t.Errorf("namespace = %q, want proj1", namespace)
+	}
+}
+
+func TestAuthorizeExecRejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		owner     string
+		serviceID string
+		podName   string
+		pods      []*corev1.Pod
+		want      string
+	}{
+		{name: "missing pod name", owner: "user1", serviceID: "svc1", want: "Missing required parameters"},
+		{name: "another user's service", owner: "user2", serviceID: "svc1", podName: "svc1-7d9f-xyz",
+			pods: []*corev1.Pod{execPod("proj1", "svc1-7d9f-xyz", "svc1")}, want: "Access denied"},
+		{name: "another service's pod", owner: "user1", serviceID: "svc1", podName: "svc2-7d9f-abc",
+			pods: []*corev1.Pod{execPod("proj1", "svc2-7d9f-abc", "svc2")}, want: "Pod not found"},
+		{name: "pod without the service label", owner: "user1", serviceID: "svc1", podName: "svc1-7d9f-abc",
+			pods: []*corev1.Pod{execPod("proj1", "svc1-7d9f-abc", "")}, want: "Pod not found"},
+		{name: "pod in another namespace", owner: "user1", serviceID: "svc1", podName: "svc1-7d9f-abc",
+			pods: []*corev1.Pod{execPod("proj2", "svc1-7d9f-abc", "svc1")}, want: "Pod not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			ownedService(db, tt.owner)
+			stubPods(t, tt.pods...)
+
+			_, _, err := authorizeExec(context.Background(), "user1", tt.serviceID, tt.podName)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("authorizeExec error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeExecUnknownService(t *testing.T) {
+	dbtest.New(t)
+	stubPods(t)
+
+	_, _, err := authorizeExec(context.Background(), "user1", "svc1", "svc1-7d9f-xyz")
+	if err == nil || err.Error() != "Service not found" {
+		t.Errorf("authorizeExec error = %v, want Service not found", err)
+	}
+}
+
+func TestExecUpgradeMiddlewareRequiresFlag(t *testing.T) {
+	cfg := config.Get()
+	previous := cfg.PodExecEnabled
+	t.Cleanup(func() { cfg.PodExecEnabled = previous })
+
+	app := fiber.New()
+	app.Get("/exec", ExecUpgradeMiddleware, func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/exec", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("exec request: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	cfg.PodExecEnabled = false
+	if status := request(); status != http.StatusNotFound {
+		t.Errorf("disabled: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	cfg.PodExecEnabled = true
+	if status := request(); status != http.StatusOK {
+		t.Errorf("enabled: status = %d, want %d", status, http.StatusOK)
+	}
+}
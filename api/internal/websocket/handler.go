@@ -12,6 +12,7 @@ import (
 	"github.com/deployra/deployra/api/internal/config"
 	"github.com/deployra/deployra/api/internal/database"
 	"github.com/deployra/deployra/api/internal/models"
+	"github.com/deployra/deployra/api/internal/region"
 	"github.com/deployra/deployra/api/pkg/kubernetes"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
@@ -38,6 +39,11 @@ type JoinDeploymentLogsPayload struct {
 	DeploymentID string `json:"deploymentId"`
 }
 
+// JoinServicePayload represents the payload for subscribing to service status updates
+type JoinServicePayload struct {
+	ServiceID string `json:"serviceId"`
+}
+
 // UpgradeMiddleware checks if the request is a WebSocket upgrade request
 func UpgradeMiddleware(c *fiber.Ctx) error {
 	if websocket.IsWebSocketUpgrade(c) {
@@ -161,6 +167,24 @@ func Handler(c *websocket.Conn) {
 			roomID := fmt.Sprintf("deployment:%s", payload.DeploymentID)
 			hub.LeaveRoom(client, roomID)
 
+		case "join_service":
+			var payload JoinServicePayload
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				hub.SendToClient(client, "error", map[string]string{
+					"message": "Invalid payload",
+				})
+				continue
+			}
+			handleJoinService(client, hub, claims.UserID, payload)
+
+		case "leave_service":
+			var payload JoinServicePayload
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				continue
+			}
+			roomID := fmt.Sprintf("service:%s", payload.ServiceID)
+			hub.LeaveRoom(client, roomID)
+
 		default:
 			log.Printf("[WebSocket] Unknown event: %s", msg.Event)
 		}
@@ -226,10 +250,10 @@ func handleJoinPodLogs(client *Client, hub *Hub, userID string, payload JoinPodL
 	ctx, cancel := context.WithCancel(context.Background())
 	activeStreams[roomID] = cancel
 
-	go streamPodLogs(ctx, client, hub, service.ProjectID, payload.PodName, payload.Since)
+	go streamPodLogs(ctx, client, hub, region.Of(service), service.ProjectID, payload.PodName, payload.Since)
 }
 
-func streamPodLogs(ctx context.Context, client *Client, hub *Hub, projectID, podName, since string) {
+func streamPodLogs(ctx context.Context, client *Client, hub *Hub, serviceRegion, projectID, podName, since string) {
 	// Parse since duration
 	var sinceSeconds *int64
 	if since != "" {
@@ -239,7 +263,7 @@ func streamPodLogs(ctx context.Context, client *Client, hub *Hub, projectID, pod
 		}
 	}
 
-	stream, err := kubernetes.StreamPodLogsReader(ctx, projectID, podName, sinceSeconds)
+	stream, err := kubernetes.StreamPodLogsReader(ctx, serviceRegion, projectID, podName, sinceSeconds)
 	if err != nil {
 		hub.SendToClient(client, "error", map[string]string{
 			"message": "Failed to stream logs: " + err.Error(),
@@ -353,3 +377,36 @@ func handleJoinDeploymentLogs(client *Client, hub *Hub, userID string, payload J
 		log.Printf("[WebSocket] All %d logs sent successfully for deployment %s", len(logs), payload.DeploymentID)
 	}()
 }
+
+func handleJoinService(client *Client, hub *Hub, userID string, payload JoinServicePayload) {
+	db := database.GetDatabase()
+
+	if payload.ServiceID == "" {
+		hub.SendToClient(client, "error", map[string]string{
+			"message": "Missing required parameters",
+		})
+		return
+	}
+
+	// Check access
+	var service models.Service
+	if err := db.Preload("Project.Organization").Where("id = ?", payload.ServiceID).First(&service).Error; err != nil {
+		hub.SendToClient(client, "error", map[string]string{
+			"message": "Service not found",
+		})
+		return
+	}
+
+	if service.Project.Organization.UserID != userID {
+		hub.SendToClient(client, "error", map[string]string{
+			"message": "Access denied",
+		})
+		return
+	}
+
+	// Join room
+	roomID := fmt.Sprintf("service:%s", payload.ServiceID)
+	hub.JoinRoom(client, roomID)
+
+	log.Printf("[WebSocket] User %s subscribed to status updates for service %s", userID, payload.ServiceID)
+}
@@ -76,6 +76,20 @@ func (h *Hub) Unregister(client *Client) {
 	}
 }
 
+// CloseAll closes every client connection, used on server shutdown
+func (h *Hub) CloseAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients {
+		if client.Conn != nil {
+			client.Conn.Close()
+		}
+	}
+	h.clients = make(map[*Client]bool)
+	h.rooms = make(map[string]map[*Client]bool)
+}
+
 // JoinRoom adds a client to a room
 func (h *Hub) JoinRoom(client *Client, roomID string) {
 	h.mu.Lock()
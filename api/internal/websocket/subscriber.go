@@ -19,16 +19,15 @@ type RedisMessage struct {
 	} `json:"data"`
 }
 
-// StartRedisSubscriber starts listening to the WebSocket Redis channel
-func StartRedisSubscriber(cfg *config.Config) {
+// StartRedisSubscriber starts listening to the WebSocket Redis channel until ctx is cancelled
+func StartRedisSubscriber(ctx context.Context, cfg *config.Config) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisHost + ":" + cfg.RedisPort,
 		Username: cfg.RedisUsername,
 		Password: cfg.RedisPassword,
 		DB:       0,
 	})
-
-	ctx := context.Background()
+	defer client.Close()
 
 	// Test connection
 	if err := client.Ping(ctx).Err(); err != nil {
@@ -39,6 +38,10 @@ func StartRedisSubscriber(cfg *config.Config) {
 	pubsub := client.Subscribe(ctx, appredis.ChannelWebSocket)
 	defer pubsub.Close()
 
+	// ReceiveMessage doesn't return when ctx is cancelled, closing the subscription unblocks it
+	stop := context.AfterFunc(ctx, func() { pubsub.Close() })
+	defer stop()
+
 	log.Printf("[WebSocket] Subscribed to Redis channel: %s", appredis.ChannelWebSocket)
 
 	hub := GetHub()
@@ -46,6 +49,10 @@ func StartRedisSubscriber(cfg *config.Config) {
 	for {
 		msg, err := pubsub.ReceiveMessage(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				log.Printf("[WebSocket] Redis subscriber stopped")
+				return
+			}
 			log.Printf("[WebSocket] Error receiving Redis message: %v", err)
 			continue
 		}
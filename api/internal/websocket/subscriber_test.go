@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/api/internal/config"
+	appredis "github.com/deployra/deployra/api/internal/redis"
+)
+
+func TestRedisSubscriberStopsOnShutdown(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	cfg := &config.Config{RedisHost: redisServer.Host(), RedisPort: redisServer.Port()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		StartRedisSubscriber(ctx, cfg)
+		close(done)
+	}()
+
+	// Wait for the subscription before shutting down
+	deadline := time.Now().Add(2 * time.Second)
+	for redisServer.PubSubNumSub(appredis.ChannelWebSocket)[appredis.ChannelWebSocket] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber never subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscriber still running after its context was cancelled")
+	}
+
+	// Redis sees the subscription go once the connection is closed
+	deadline = time.Now().Add(2 * time.Second)
+	for redisServer.PubSubNumSub(appredis.ChannelWebSocket)[appredis.ChannelWebSocket] != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription left after shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHubCloseAll(t *testing.T) {
+	hub := &Hub{clients: make(map[*Client]bool), rooms: make(map[string]map[*Client]bool)}
+	client := &Client{UserID: "u1", Rooms: make(map[string]bool)}
+	hub.Register(client)
+	hub.JoinRoom(client, "service:1")
+
+	hub.CloseAll()
+	if hub.IsClientConnected(client) {
+		t.Error("client still registered after CloseAll")
+	}
+	if len(hub.rooms) != 0 {
+		t.Errorf("rooms left after CloseAll: %v", hub.rooms)
+	}
+}
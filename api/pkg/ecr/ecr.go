@@ -87,6 +87,32 @@ func GetAuthorizationToken() (*AuthorizationToken, error) {
 	}, nil
 }
 
+// CreateRepository creates the ECR repository of a service, an existing one is kept
+func CreateRepository(serviceID string) error {
+	client, err := GetClient()
+	if err != nil {
+		return err
+	}
+
+	repositoryName := fmt.Sprintf("deployra/%s", serviceID)
+	log.Printf("Creating ECR repository: %s", repositoryName)
+
+	_, err = client.CreateRepository(context.Background(), &ecr.CreateRepositoryInput{
+		RepositoryName: &repositoryName,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "RepositoryAlreadyExistsException") {
+			log.Printf("ECR repository already exists: %s", repositoryName)
+			return nil
+		}
+		log.Printf("Error creating ECR repository: %v", err)
+		return err
+	}
+
+	log.Printf("Successfully created ECR repository: %s", repositoryName)
+	return nil
+}
+
 // DeleteRepository deletes an ECR repository for a service
 func DeleteRepository(serviceID string) error {
 	client, err := GetClient()
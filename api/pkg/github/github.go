@@ -7,9 +7,11 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -29,6 +31,7 @@ type Repository struct {
 	Private       bool   `json:"private"`
 	Description   string `json:"description"`
 	DefaultBranch string `json:"defaultBranch"`
+	Language      string `json:"language"`
 	URL           string `json:"url"`
 	CreatedAt     string `json:"createdAt"`
 	UpdatedAt     string `json:"updatedAt"`
@@ -164,34 +167,76 @@ func parsePrivateKey(key string) (*rsa.PrivateKey, error) {
 	return privateKey, nil
 }
 
-// ListRepositoriesForInstallation lists repositories for a GitHub App installation
+// ListRepositoriesForInstallation lists every repository a GitHub App installation can access
 func ListRepositoriesForInstallation(ctx context.Context, installationID int64) ([]Repository, error) {
 	client, err := NewClientWithInstallation(ctx, installationID)
 	if err != nil {
 		return nil, err
 	}
+	return client.listInstallationRepositories(ctx)
+}
 
-	repos, _, err := client.client.Apps.ListRepos(ctx, &gh.ListOptions{PerPage: 100})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list repos: %w", err)
-	}
-
-	result := make([]Repository, 0, len(repos.Repositories))
-	for _, repo := range repos.Repositories {
-		result = append(result, Repository{
-			ID:            fmt.Sprintf("%d", repo.GetID()),
-			Name:          repo.GetName(),
-			FullName:      repo.GetFullName(),
-			Private:       repo.GetPrivate(),
-			Description:   repo.GetDescription(),
-			DefaultBranch: repo.GetDefaultBranch(),
-			URL:           repo.GetHTMLURL(),
-			CreatedAt:     repo.GetCreatedAt().Format(time.RFC3339),
-			UpdatedAt:     repo.GetUpdatedAt().Format(time.RFC3339),
-		})
+// listInstallationRepositories lists the repositories of the installation the client
+// authenticates as, page by page
+func (c *Client) listInstallationRepositories(ctx context.Context) ([]Repository, error) {
+	result := []Repository{}
+	opts := &gh.ListOptions{PerPage: 100}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		repos, resp, err := c.client.Apps.ListRepos(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repos: %w", err)
+		}
+		for _, repo := range repos.Repositories {
+			result = append(result, toRepository(repo))
+		}
+
+		if resp.NextPage == 0 {
+			return result, nil
+		}
+		opts.Page = resp.NextPage
 	}
+}
 
-	return result, nil
+// toRepository converts a repository returned by GitHub
+func toRepository(repo *gh.Repository) Repository {
+	return Repository{
+		ID:            fmt.Sprintf("%d", repo.GetID()),
+		Name:          repo.GetName(),
+		FullName:      repo.GetFullName(),
+		Private:       repo.GetPrivate(),
+		Description:   repo.GetDescription(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		Language:      repo.GetLanguage(),
+		URL:           repo.GetHTMLURL(),
+		CreatedAt:     repo.GetCreatedAt().Format(time.RFC3339),
+		UpdatedAt:     repo.GetUpdatedAt().Format(time.RFC3339),
+	}
+}
+
+// FilterRepositories returns the repositories whose name contains name and whose primary
+// language is language, both case-insensitive. Empty filters match every repository.
+func FilterRepositories(repos []Repository, name, language string) []Repository {
+	name = strings.ToLower(strings.TrimSpace(name))
+	language = strings.TrimSpace(language)
+	if name == "" && language == "" {
+		return repos
+	}
+
+	result := []Repository{}
+	for _, repo := range repos {
+		if name != "" && !strings.Contains(strings.ToLower(repo.FullName), name) {
+			continue
+		}
+		if language != "" && !strings.EqualFold(repo.Language, language) {
+			continue
+		}
+		result = append(result, repo)
+	}
+	return result
 }
 
 // tokenRefreshResponse represents the response from GitHub OAuth token refresh
@@ -296,33 +341,36 @@ func EnsureValidGithubToken(githubAccountID string) (*models.GithubAccount, erro
 	return &githubAccount, nil
 }
 
-// ListRepositoriesForUser lists repositories for an authenticated user
+// ListRepositoriesForUser lists every repository of an authenticated user
 func ListRepositoriesForUser(ctx context.Context, token string) ([]Repository, error) {
-	client := NewClientWithToken(ctx, token)
+	return NewClientWithToken(ctx, token).listUserRepositories(ctx)
+}
 
-	repos, _, err := client.client.Repositories.List(ctx, "", &gh.RepositoryListOptions{
+// listUserRepositories lists the repositories of the user the client authenticates as, page
+// by page
+func (c *Client) listUserRepositories(ctx context.Context) ([]Repository, error) {
+	result := []Repository{}
+	opts := &gh.RepositoryListOptions{
 		ListOptions: gh.ListOptions{PerPage: 100},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list repos: %w", err)
 	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
-	result := make([]Repository, 0, len(repos))
-	for _, repo := range repos {
-		result = append(result, Repository{
-			ID:            fmt.Sprintf("%d", repo.GetID()),
-			Name:          repo.GetName(),
-			FullName:      repo.GetFullName(),
-			Private:       repo.GetPrivate(),
-			Description:   repo.GetDescription(),
-			DefaultBranch: repo.GetDefaultBranch(),
-			URL:           repo.GetHTMLURL(),
-			CreatedAt:     repo.GetCreatedAt().Format(time.RFC3339),
-			UpdatedAt:     repo.GetUpdatedAt().Format(time.RFC3339),
-		})
-	}
+		repos, resp, err := c.client.Repositories.List(ctx, "", opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repos: %w", err)
+		}
+		for _, repo := range repos {
+			result = append(result, toRepository(repo))
+		}
 
-	return result, nil
+		if resp.NextPage == 0 {
+			return result, nil
+		}
+		opts.Page = resp.NextPage
+	}
 }
 
 // ListBranches lists branches for a repository
@@ -347,6 +395,24 @@ func (c *Client) ListBranches(ctx context.Context, owner, repo string) ([]Branch
 	return result, nil
 }
 
+// CompareChangedFiles lists the files changed between two commits of a repository
+func (c *Client) CompareChangedFiles(ctx context.Context, owner, repo, base, head string) ([]string, error) {
+	comparison, _, err := c.client.Repositories.CompareCommits(ctx, owner, repo, base, head, &gh.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare commits: %w", err)
+	}
+
+	result := make([]string, 0, len(comparison.Files))
+	for _, file := range comparison.Files {
+		result = append(result, file.GetFilename())
+		if file.GetPreviousFilename() != "" {
+			result = append(result, file.GetPreviousFilename())
+		}
+	}
+
+	return result, nil
+}
+
 // EnsureRepositoryWebhook creates or updates a webhook for a GitHub repository using git provider ID
 func EnsureRepositoryWebhook(gitProviderID string, repositoryName string) error {
 	cfg := config.Get()
@@ -499,3 +565,185 @@ func (c *Client) GetRepoDescription(ctx context.Context, owner, repo, branch, do
 
 	return desc, nil
 }
+
+// ErrFileNotFound is returned when a file doesn't exist in a repository, or is a directory
+var ErrFileNotFound = errors.New("file not found")
+
+// GetFileContent returns the content of a file of a repository at a branch, tag or commit
+func (c *Client) GetFileContent(ctx context.Context, owner, repo, ref, path string) ([]byte, error) {
+	file, _, resp, err := c.client.Repositories.GetContents(ctx, owner, repo, path, &gh.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	if file == nil {
+		return nil, ErrFileNotFound
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode file: %w", err)
+	}
+	return []byte(content), nil
+}
+
+// ErrPathNotFound is returned when a path doesn't exist in a repository
+var ErrPathNotFound = errors.New("path not found")
+
+// ContentEntry is a file, directory, symlink or submodule of a repository directory
+type ContentEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size int    `json:"size"`
+}
+
+// ListContents returns the entries of a directory of a repository at a branch, tag or commit,
+// directories first. For a file it returns the file alone and dir is false.
+func (c *Client) ListContents(ctx context.Context, owner, repo, ref, path string) (entries []ContentEntry, dir bool, err error) {
+	file, directory, resp, err := c.client.Repositories.GetContents(ctx, owner, repo, path, &gh.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, false, ErrPathNotFound
+		}
+		return nil, false, fmt.Errorf("failed to get contents: %w", err)
+	}
+	if file != nil {
+		return []ContentEntry{contentEntry(file)}, false, nil
+	}
+
+	entries = make([]ContentEntry, 0, len(directory))
+	for _, content := range directory {
+		entries = append(entries, contentEntry(content))
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if (entries[i].Type == "dir") != (entries[j].Type == "dir") {
+			return entries[i].Type == "dir"
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, true, nil
+}
+
+// contentEntry converts a file or directory entry returned by GitHub
+func contentEntry(content *gh.RepositoryContent) ContentEntry {
+	return ContentEntry{
+		Name: content.GetName(),
+		Path: content.GetPath(),
+		Type: content.GetType(),
+		Size: content.GetSize(),
+	}
+}
+
+// TreeEntry is a file or directory of a repository tree
+type TreeEntry struct {
+	Path string
+	Dir  bool
+	Size int64
+}
+
+// ListTree returns the files and directories of a repository at a commit, recursively.
+// GitHub truncates the tree of large repositories, truncated reports whether it did.
+func (c *Client) ListTree(ctx context.Context, owner, repo, sha string) (entries []TreeEntry, truncated bool, err error) {
+	tree, _, err := c.client.Git.GetTree(ctx, owner, repo, sha, true)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	entries = make([]TreeEntry, 0, len(tree.Entries))
+	for _, entry := range tree.Entries {
+		// Submodules are "commit" entries, they aren't cloned
+		switch entry.GetType() {
+		case "blob":
+			entries = append(entries, TreeEntry{Path: entry.GetPath(), Size: int64(entry.GetSize())})
+		case "tree":
+			entries = append(entries, TreeEntry{Path: entry.GetPath(), Dir: true})
+		}
+	}
+
+	return entries, tree.GetTruncated(), nil
+}
+
+// ErrRefNotFound is returned when a branch, tag or commit doesn't exist in a repository
+var ErrRefNotFound = errors.New("ref not found")
+
+// Kinds of refs a deployment can be built from
+const (
+	RefKindBranch = "branch"
+	RefKindTag    = "tag"
+	RefKindCommit = "commit"
+)
+
+// Ref is a branch, tag or commit resolved to the commit it points at
+type Ref struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	CommitSha string `json:"commitSha"`
+}
+
+// ResolveRef resolves a branch, tag or commit SHA of a repository to its commit. Branches
+// win over tags of the same name, like they do for git.
+func (c *Client) ResolveRef(ctx context.Context, owner, repo, ref string) (*Ref, error) {
+	branch, resp, err := c.client.Git.GetRef(ctx, owner, repo, "heads/"+ref)
+	if err == nil {
+		return &Ref{Name: ref, Kind: RefKindBranch, CommitSha: branch.GetObject().GetSHA()}, nil
+	}
+	if !refMissing(resp) {
+		return nil, fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	tag, resp, err := c.client.Git.GetRef(ctx, owner, repo, "tags/"+ref)
+	if err == nil {
+		sha, err := c.peelTag(ctx, owner, repo, tag.GetObject())
+		if err != nil {
+			return nil, err
+		}
+		return &Ref{Name: ref, Kind: RefKindTag, CommitSha: sha}, nil
+	}
+	if !refMissing(resp) {
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+
+	sha, resp, err := c.client.Repositories.GetCommitSHA1(ctx, owner, repo, ref, "")
+	if err == nil {
+		return &Ref{Name: ref, Kind: RefKindCommit, CommitSha: sha}, nil
+	}
+	if refMissing(resp) {
+		return nil, ErrRefNotFound
+	}
+	return nil, fmt.Errorf("failed to get commit: %w", err)
+}
+
+// peelTag follows annotated tags down to the commit they point at
+func (c *Client) peelTag(ctx context.Context, owner, repo string, object *gh.GitObject) (string, error) {
+	// Tags of tags are allowed, but a chain this long is broken
+	for i := 0; i < 10 && object.GetType() == "tag"; i++ {
+		tag, _, err := c.client.Git.GetTag(ctx, owner, repo, object.GetSHA())
+		if err != nil {
+			return "", fmt.Errorf("failed to get tag: %w", err)
+		}
+		object = tag.GetObject()
+	}
+
+	if object.GetType() != "commit" {
+		return "", fmt.Errorf("tag points at a %s, not a commit", object.GetType())
+	}
+	return object.GetSHA(), nil
+}
+
+// refMissing reports whether GitHub answered that a ref doesn't exist. Unknown commits are
+// a 422 rather than a 404.
+func refMissing(resp *gh.Response) bool {
+	return resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity)
+}
+
+// IsBranchProtected reports whether a branch of a repository has branch protection enabled
+func (c *Client) IsBranchProtected(ctx context.Context, owner, repo, branch string) (bool, error) {
+	b, _, err := c.client.Repositories.GetBranch(ctx, owner, repo, branch, 1)
+	if err != nil {
+		return false, fmt.Errorf("failed to get branch: %w", err)
+	}
+	return b.GetProtected(), nil
+}
@@ -0,0 +1,299 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	gh "github.com/google/go-github/v57/github"
+)
+
+// testClient returns a client talking to a GitHub API served by handler
+func testClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := gh.NewClient(nil)
+	baseURL, _ := url.Parse(server.URL + "/")
+	client.BaseURL = baseURL
+	return &Client{client: client}
+}
+
+// pagedRepos serves two pages of repositories linked with a Link header, as GitHub does
+func pagedRepos(wrap func(body string) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("page") {
+		case "", "1":
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=2&per_page=100>; rel="next"`, r.Host, r.URL.Path))
+			fmt.Fprint(w, wrap(`[{"id":1,"name":"one","full_name":"acme/one"},{"id":2,"name":"two","full_name":"acme/two"}]`))
+		case "2":
+			fmt.Fprint(w, wrap(`[{"id":3,"name":"three","full_name":"acme/three"}]`))
+		default:
+			http.Error(w, "unexpected page", http.StatusBadRequest)
+		}
+	}
+}
+
+func assertRepoIDs(t *testing.T, repos []Repository, want ...string) {
+	t.Helper()
+	if len(repos) != len(want) {
+		t.Fatalf("got %d repositories, want %d: %+v", len(repos), len(want), repos)
+	}
+	for i, id := range want {
+		if repos[i].ID != id {
+			t.Errorf("repository %d has ID %q, want %q", i, repos[i].ID, id)
+		}
+	}
+}
+
+func TestListUserRepositoriesFollowsPages(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/user/repos", pagedRepos(func(body string) string { return body }))
+
+	repos, err := testClient(t, mux).listUserRepositories(context.Background())
+	if err != nil {
+		t.Fatalf("listUserRepositories: %v", err)
+	}
+	assertRepoIDs(t, repos, "1", "2", "3")
+	if repos[2].FullName != "acme/three" {
+		t.Errorf("last repository is %q, want acme/three", repos[2].FullName)
+	}
+}
+
+func TestListInstallationRepositoriesFollowsPages(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/installation/repositories", pagedRepos(func(body string) string {
+		return `{"total_count":3,"repositories":` + body + `}`
+	}))
+
+	repos, err := testClient(t, mux).listInstallationRepositories(context.Background())
+	if err != nil {
+		t.Fatalf("listInstallationRepositories: %v", err)
+	}
+	assertRepoIDs(t, repos, "1", "2", "3")
+}
+
+func TestListRepositoriesStopsWhenContextIsCancelled(t *testing.T) {
+	requests := 0
+	client := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[]`)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.listUserRepositories(ctx); err != context.Canceled {
+		t.Fatalf("listUserRepositories error = %v, want context.Canceled", err)
+	}
+	if requests != 0 {
+		t.Errorf("GitHub was called %d times after the request was cancelled", requests)
+	}
+}
+
+func TestFilterRepositories(t *testing.T) {
+	repos := []Repository{
+		{ID: "1", FullName: "acme/Web-App", Language: "TypeScript"},
+		{ID: "2", FullName: "acme/api", Language: "Go"},
+		{ID: "3", FullName: "acme/web-worker", Language: "Go"},
+		{ID: "4", FullName: "acme/docs", Language: ""},
+	}
+
+	tests := []struct {
+		name     string
+		filter   string
+		language string
+		want     []string
+	}{
+		{name: "no filters", want: []string{"1", "2", "3", "4"}},
+		{name: "name is a case-insensitive substring", filter: "WEB", want: []string{"1", "3"}},
+		{name: "name matches the owner too", filter: "acme/a", want: []string{"2"}},
+		{name: "language is case-insensitive", language: "go", want: []string{"2", "3"}},
+		{name: "language must match exactly", language: "Type", want: []string{}},
+		{name: "both filters", filter: "web", language: "Go", want: []string{"3"}},
+		{name: "blank filters are ignored", filter: "  ", language: " ", want: []string{"1", "2", "3", "4"}},
+		{name: "no match", filter: "mobile", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertRepoIDs(t, FilterRepositories(repos, tt.filter, tt.language), tt.want...)
+		})
+	}
+}
+
+// refsAPI serves the refs of acme/app: branch main, lightweight tag v1.0, annotated tag
+// v2.0 and commit abc1234
+func refsAPI() http.Handler {
+	mux := http.NewServeMux()
+	object := func(w http.ResponseWriter, objectType, sha string) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"object":{"type":%q,"sha":%q}}`, objectType, sha)
+	}
+	mux.HandleFunc("/repos/acme/app/git/ref/heads/main", func(w http.ResponseWriter, r *http.Request) {
+		object(w, "commit", "1111111111111111111111111111111111111111")
+	})
+	mux.HandleFunc("/repos/acme/app/git/ref/tags/v1.0", func(w http.ResponseWriter, r *http.Request) {
+		object(w, "commit", "2222222222222222222222222222222222222222")
+	})
+	mux.HandleFunc("/repos/acme/app/git/ref/tags/v2.0", func(w http.ResponseWriter, r *http.Request) {
+		object(w, "tag", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	})
+	mux.HandleFunc("/repos/acme/app/git/tags/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", func(w http.ResponseWriter, r *http.Request) {
+		object(w, "commit", "3333333333333333333333333333333333333333")
+	})
+	mux.HandleFunc("/repos/acme/app/commits/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/app/commits/abc1234" {
+			// GitHub answers unknown commits with 422
+			http.Error(w, `{"message":"No commit found for SHA"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		fmt.Fprint(w, "4444444444444444444444444444444444444444")
+	})
+	return mux
+}
+
+func TestResolveRef(t *testing.T) {
+	client := testClient(t, refsAPI())
+	tests := []struct {
+		ref  string
+		want Ref
+	}{
+		{ref: "main", want: Ref{Name: "main", Kind: RefKindBranch, CommitSha: "1111111111111111111111111111111111111111"}},
+		{ref: "v1.0", want: Ref{Name: "v1.0", Kind: RefKindTag, CommitSha: "2222222222222222222222222222222222222222"}},
+		{ref: "v2.0", want: Ref{Name: "v2.0", Kind: RefKindTag, CommitSha: "3333333333333333333333333333333333333333"}},
+		{ref: "abc1234", want: Ref{Name: "abc1234", Kind: RefKindCommit, CommitSha: "4444444444444444444444444444444444444444"}},
+	}
+	for _, tt := range tests {
+		got, err := client.ResolveRef(context.Background(), "acme", "app", tt.ref)
+		if err != nil {
+			t.Errorf("ResolveRef(%s): %v", tt.ref, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("ResolveRef(%s) = %+v, want %+v", tt.ref, *got, tt.want)
+		}
+	}
+}
+
+func TestResolveRefUnknownRef(t *testing.T) {
+	client := testClient(t, refsAPI())
+	if _, err := client.ResolveRef(context.Background(), "acme", "app", "does-not-exist"); !errors.Is(err, ErrRefNotFound) {
+		t.Errorf("ResolveRef of an unknown ref = %v, want %v", err, ErrRefNotFound)
+	}
+}
+
+func TestResolveRefGitHubError(t *testing.T) {
+	client := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Server Error"}`, http.StatusInternalServerError)
+	}))
+	_, err := client.ResolveRef(context.Background(), "acme", "app", "main")
+	if err == nil || errors.Is(err, ErrRefNotFound) {
+		t.Errorf("ResolveRef with GitHub failing = %v, want an error other than %v", err, ErrRefNotFound)
+	}
+}
+
+func TestIsBranchProtected(t *testing.T) {
+	mux := http.NewServeMux()
+	branch := func(protected bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"name":"branch","protected":%t}`, protected)
+		}
+	}
+	mux.Handle("/repos/acme/app/branches/main", branch(true))
+	mux.Handle("/repos/acme/app/branches/feature", branch(false))
+	client := testClient(t, mux)
+
+	for name, want := range map[string]bool{"main": true, "feature": false} {
+		protected, err := client.IsBranchProtected(context.Background(), "acme", "app", name)
+		if err != nil {
+			t.Fatalf("IsBranchProtected %s: %v", name, err)
+		}
+		if protected != want {
+			t.Errorf("IsBranchProtected %s = %v, want %v", name, protected, want)
+		}
+	}
+
+	if _, err := client.IsBranchProtected(context.Background(), "acme", "app", "missing"); err == nil {
+		t.Error("IsBranchProtected of a missing branch succeeded")
+	}
+}
+
+// contentsAPI serves the contents of acme/app at ref v1.0: a root directory with a file, a
+// directory and a submodule, and its Dockerfile
+func contentsAPI(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/acme/app/contents/", func(w http.ResponseWriter, r *http.Request) {
+		if ref := r.URL.Query().Get("ref"); ref != "v1.0" {
+			t.Errorf("contents requested at ref %q, want v1.0", ref)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/repos/acme/app/contents/":
+			fmt.Fprint(w, `[
+				{"type":"file","name":"README.md","path":"README.md","size":120},
+				{"type":"submodule","name":"vendor-lib","path":"vendor-lib","size":0},
+				{"type":"dir","name":"services","path":"services","size":0},
+				{"type":"file","name":"Dockerfile","path":"Dockerfile","size":340},
+				{"type":"dir","name":"docs","path":"docs","size":0}
+			]`)
+		case "/repos/acme/app/contents/Dockerfile":
+			fmt.Fprint(w, `{"type":"file","encoding":"base64","name":"Dockerfile","path":"Dockerfile","size":340,"content":"RlJPTSBzY3JhdGNoCg=="}`)
+		default:
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		}
+	})
+	return mux
+}
+
+func TestListContentsOfDirectory(t *testing.T) {
+	client := testClient(t, contentsAPI(t))
+
+	entries, dir, err := client.ListContents(context.Background(), "acme", "app", "v1.0", "")
+	if err != nil {
+		t.Fatalf("ListContents: %v", err)
+	}
+	if !dir {
+		t.Error("the root of the repository listed as a file")
+	}
+	// Directories come first, each group by name
+	want := []ContentEntry{
+		{Name: "docs", Path: "docs", Type: "dir"},
+		{Name: "services", Path: "services", Type: "dir"},
+		{Name: "Dockerfile", Path: "Dockerfile", Type: "file", Size: 340},
+		{Name: "README.md", Path: "README.md", Type: "file", Size: 120},
+		{Name: "vendor-lib", Path: "vendor-lib", Type: "submodule"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v\nwant %+v", entries, want)
+	}
+}
+
+func TestListContentsOfFile(t *testing.T) {
+	client := testClient(t, contentsAPI(t))
+
+	entries, dir, err := client.ListContents(context.Background(), "acme", "app", "v1.0", "Dockerfile")
+	if err != nil {
+		t.Fatalf("ListContents: %v", err)
+	}
+	want := []ContentEntry{{Name: "Dockerfile", Path: "Dockerfile", Type: "file", Size: 340}}
+	if dir || !reflect.DeepEqual(entries, want) {
+		t.Errorf("ListContents = %+v, dir %v, want the file alone", entries, dir)
+	}
+}
+
+func TestListContentsMissingPath(t *testing.T) {
+	client := testClient(t, contentsAPI(t))
+
+	if _, _, err := client.ListContents(context.Background(), "acme", "app", "v1.0", "services/web"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("ListContents of a missing path = %v, want %v", err, ErrPathNotFound)
+	}
+}
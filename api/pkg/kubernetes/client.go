@@ -2,15 +2,24 @@ package kubernetes
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -25,19 +34,58 @@ type Pod struct {
 	Age      string `json:"age"`
 }
 
-var clientset *kubernetes.Clientset
+// cluster is the Kubernetes cluster of a region
+type cluster struct {
+	clientset *kubernetes.Clientset
 
-// GetClient returns a Kubernetes clientset
-func GetClient() (*kubernetes.Clientset, error) {
-	if clientset != nil {
-		return clientset, nil
+	// restConfig is kept for requests the clientset can't make on its own, like exec streams
+	restConfig *rest.Config
+}
+
+// Clusters by the suffix of their kubeconfig variables, "" is the default cluster
+var (
+	clustersMu sync.Mutex
+	clusters   = make(map[string]*cluster)
+)
+
+// RequestTimeout bounds every call to the Kubernetes API that isn't a log stream
+const RequestTimeout = 15 * time.Second
+
+// withTimeout returns ctx limited to RequestTimeout, callers keep any earlier deadline
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, RequestTimeout)
+}
+
+// GetClient returns a Kubernetes clientset for the cluster of a region
+func GetClient(region string) (*kubernetes.Clientset, error) {
+	c, err := getCluster(region)
+	if err != nil {
+		return nil, err
+	}
+	return c.clientset, nil
+}
+
+// getCluster returns the cluster of a region. A region's kubeconfig is read from
+// KUBECONFIG_CONTENT_<REGION> or KUBECONFIG_PATH_<REGION>, KUBECONFIG_PATH_EU_WEST for
+// "eu-west". Regions without one, and "", use the default cluster.
+func getCluster(region string) (*cluster, error) {
+	suffix := regionEnvSuffix(region)
+	if suffix != "" && os.Getenv("KUBECONFIG_CONTENT"+suffix) == "" && os.Getenv("KUBECONFIG_PATH"+suffix) == "" {
+		suffix = ""
+	}
+
+	clustersMu.Lock()
+	defer clustersMu.Unlock()
+
+	if c, ok := clusters[suffix]; ok {
+		return c, nil
 	}
 
 	var config *rest.Config
 	var err error
 
 	// Check for KUBECONFIG_CONTENT environment variable
-	if kubeConfigContent := os.Getenv("KUBECONFIG_CONTENT"); kubeConfigContent != "" {
+	if kubeConfigContent := os.Getenv("KUBECONFIG_CONTENT" + suffix); kubeConfigContent != "" {
 		// Create a temporary file to load the config from
 		tmpFile, err := os.CreateTemp("", "kubeconfig-")
 		if err != nil {
@@ -54,7 +102,7 @@ func GetClient() (*kubernetes.Clientset, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to build config from kubeconfig content: %w", err)
 		}
-	} else if kubeConfigPath := os.Getenv("KUBECONFIG_PATH"); kubeConfigPath != "" {
+	} else if kubeConfigPath := os.Getenv("KUBECONFIG_PATH" + suffix); kubeConfigPath != "" {
 		// Load from KUBECONFIG_PATH
 		config, err = clientcmd.BuildConfigFromFlags("", kubeConfigPath)
 		if err != nil {
@@ -73,25 +121,44 @@ func GetClient() (*kubernetes.Clientset, error) {
 		}
 	}
 
-	clientset, err = kubernetes.NewForConfig(config)
+	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create clientset: %w", err)
 	}
 
-	return clientset, nil
+	c := &cluster{clientset: clientset, restConfig: config}
+	clusters[suffix] = c
+	return c, nil
+}
+
+// regionEnvSuffix returns the suffix of the kubeconfig variables of a region, "_EU_WEST" for
+// "eu-west" and "" for the default cluster
+func regionEnvSuffix(region string) string {
+	if region == "" {
+		return ""
+	}
+	return "_" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, region)
 }
 
 // GetPodsForService returns all pods for a specific service
-func GetPodsForService(projectID, serviceID string) ([]Pod, error) {
-	client, err := GetClient()
+func GetPodsForService(ctx context.Context, region, projectID, serviceID string) ([]Pod, error) {
+	client, err := GetClient(region)
 	if err != nil {
 		return nil, err
 	}
 
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	namespace := projectID
 	labelSelector := fmt.Sprintf("service=%s", serviceID)
 
-	podList, err := client.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{
+	podList, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: labelSelector,
 	})
 	if err != nil {
@@ -154,13 +221,158 @@ func GetPodsForService(projectID, serviceID string) ([]Pod, error) {
 	return pods, nil
 }
 
+// Event represents a Kubernetes event related to a service
+type Event struct {
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Object    string    `json:"object"`
+	Count     int32     `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// GetEventsForService returns the most recent Kubernetes events for the resources of a
+// service (deployment, replica sets, pods, HPA, PVC), newest first
+func GetEventsForService(ctx context.Context, region, projectID, serviceID string, limit int) ([]Event, error) {
+	client, err := GetClient(region)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return listServiceEvents(ctx, client, projectID, serviceID, limit)
+}
+
+// listServiceEvents lists events in the service's namespace whose object name belongs to the service.
+// Kubestrator names every resource of a service "<serviceId>-...".
+func listServiceEvents(ctx context.Context, client kubernetes.Interface, namespace, serviceID string, limit int) ([]Event, error) {
+	eventList, err := client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	prefix := serviceID + "-"
+	events := []Event{}
+	for _, event := range eventList.Items {
+		if !strings.HasPrefix(event.InvolvedObject.Name, prefix) {
+			continue
+		}
+
+		lastSeen := event.LastTimestamp.Time
+		if lastSeen.IsZero() {
+			lastSeen = event.EventTime.Time
+		}
+		if lastSeen.IsZero() {
+			lastSeen = event.CreationTimestamp.Time
+		}
+
+		firstSeen := event.FirstTimestamp.Time
+		if firstSeen.IsZero() {
+			firstSeen = lastSeen
+		}
+
+		count := event.Count
+		if count == 0 {
+			count = 1
+		}
+
+		events = append(events, Event{
+			Type:      event.Type,
+			Reason:    event.Reason,
+			Message:   event.Message,
+			Object:    fmt.Sprintf("%s/%s", event.InvolvedObject.Kind, event.InvolvedObject.Name),
+			Count:     count,
+			FirstSeen: firstSeen,
+			LastSeen:  lastSeen,
+		})
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].LastSeen.After(events[j].LastSeen)
+	})
+
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+// ErrPodNotFound is returned when a pod does not exist or does not belong to the service
+var ErrPodNotFound = errors.New("pod not found")
+
+// DeleteServicePod deletes a pod of a service so its deployment schedules a replacement.
+// The pod must live in the project namespace and carry the service label.
+func DeleteServicePod(ctx context.Context, region, projectID, serviceID, podName string) error {
+	client, err := GetClient(region)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return deleteServicePod(ctx, client, projectID, serviceID, podName)
+}
+
+func deleteServicePod(ctx context.Context, client kubernetes.Interface, namespace, serviceID, podName string) error {
+	if _, err := getServicePod(ctx, client, namespace, serviceID, podName); err != nil {
+		return err
+	}
+
+	if err := client.CoreV1().Pods(namespace).Delete(ctx, podName, metav1.DeleteOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ErrPodNotFound
+		}
+		return fmt.Errorf("failed to delete pod: %w", err)
+	}
+
+	return nil
+}
+
+// GetServicePod returns a pod of a service. The pod must live in the project namespace
+// and carry the service label, otherwise ErrPodNotFound is returned.
+func GetServicePod(ctx context.Context, region, projectID, serviceID, podName string) (*corev1.Pod, error) {
+	client, err := GetClient(region)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return getServicePod(ctx, client, projectID, serviceID, podName)
+}
+
+func getServicePod(ctx context.Context, client kubernetes.Interface, namespace, serviceID, podName string) (*corev1.Pod, error) {
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrPodNotFound
+		}
+		return nil, fmt.Errorf("failed to get pod: %w", err)
+	}
+
+	if pod.Labels["service"] != serviceID {
+		return nil, ErrPodNotFound
+	}
+
+	return pod, nil
+}
+
 // GetPodLogs returns logs for a specific pod
-func GetPodLogs(projectID, serviceID, podName string) (string, error) {
-	client, err := GetClient()
+func GetPodLogs(ctx context.Context, region, projectID, serviceID, podName string) (string, error) {
+	client, err := GetClient(region)
 	if err != nil {
 		return "", err
 	}
 
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	namespace := projectID
 	tailLines := int64(1000)
 
@@ -169,7 +381,7 @@ func GetPodLogs(projectID, serviceID, podName string) (string, error) {
 		Timestamps: true,
 	})
 
-	podLogs, err := req.Stream(context.Background())
+	podLogs, err := req.Stream(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get logs stream: %w", err)
 	}
@@ -187,9 +399,9 @@ func GetPodLogs(projectID, serviceID, podName string) (string, error) {
 	return string(logs), nil
 }
 
-// StreamPodLogs streams logs for a specific pod
-func StreamPodLogs(projectID, serviceID, podName string, sinceTime *time.Time, writer io.Writer) error {
-	client, err := GetClient()
+// StreamPodLogs streams logs for a specific pod until the pod stops or ctx is cancelled
+func StreamPodLogs(ctx context.Context, region, projectID, serviceID, podName string, sinceTime *time.Time, writer io.Writer) error {
+	client, err := GetClient(region)
 	if err != nil {
 		return err
 	}
@@ -211,7 +423,7 @@ func StreamPodLogs(projectID, serviceID, podName string, sinceTime *time.Time, w
 
 	req := client.CoreV1().Pods(namespace).GetLogs(podName, opts)
 
-	podLogs, err := req.Stream(context.Background())
+	podLogs, err := req.Stream(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get logs stream: %w", err)
 	}
@@ -243,12 +455,15 @@ func formatAge(d time.Duration) string {
 }
 
 // CreateOrUpdateSecret creates or updates a Kubernetes secret
-func CreateOrUpdateSecret(name, namespace, secretType string, data map[string][]byte) error {
-	client, err := GetClient()
+func CreateOrUpdateSecret(ctx context.Context, region, name, namespace, secretType string, data map[string][]byte) error {
+	client, err := GetClient(region)
 	if err != nil {
 		return err
 	}
 
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
@@ -259,10 +474,10 @@ func CreateOrUpdateSecret(name, namespace, secretType string, data map[string][]
 	}
 
 	// Try to get existing secret
-	_, err = client.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	_, err = client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		// Secret doesn't exist, create it
-		_, err = client.CoreV1().Secrets(namespace).Create(context.Background(), secret, metav1.CreateOptions{})
+		_, err = client.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
 		if err != nil {
 			return fmt.Errorf("failed to create secret: %w", err)
 		}
@@ -270,7 +485,7 @@ func CreateOrUpdateSecret(name, namespace, secretType string, data map[string][]
 	}
 
 	// Secret exists, update it
-	_, err = client.CoreV1().Secrets(namespace).Update(context.Background(), secret, metav1.UpdateOptions{})
+	_, err = client.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to update secret: %w", err)
 	}
@@ -278,9 +493,115 @@ func CreateOrUpdateSecret(name, namespace, secretType string, data map[string][]
 	return nil
 }
 
+// GetCertificateExpiry returns the expiry time of the certificate stored by the web proxy
+// for a domain. The proxy keeps certificates in "cert-<domain>" secrets in the system-apps
+// namespace, with "cert-wildcard-<domain>" used for wildcard certificates.
+func GetCertificateExpiry(ctx context.Context, region, domain string, wildcard bool) (*time.Time, error) {
+	client, err := GetClient(region)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	secretName := fmt.Sprintf("cert-%s", strings.ReplaceAll(domain, ".", "-"))
+	if wildcard {
+		secretName = fmt.Sprintf("cert-wildcard-%s", strings.ReplaceAll(domain, ".", "-"))
+	}
+
+	secret, err := client.CoreV1().Secrets("system-apps").Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificate secret: %w", err)
+	}
+
+	certPEM, ok := secret.Data["cert.pem"]
+	if !ok {
+		return nil, fmt.Errorf("certificate secret %s is missing cert.pem", secretName)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode certificate PEM for %s", domain)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate for %s: %w", domain, err)
+	}
+
+	return &cert.NotAfter, nil
+}
+
+// DeleteCertificate deletes the certificate the web proxy stored for a domain, a missing one
+// is not an error
+func DeleteCertificate(ctx context.Context, region, domain string) error {
+	client, err := GetClient(region)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	secretName := fmt.Sprintf("cert-%s", strings.ReplaceAll(domain, ".", "-"))
+	err = client.CoreV1().Secrets("system-apps").Delete(ctx, secretName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete certificate secret: %w", err)
+	}
+
+	return nil
+}
+
+// SetServiceLabel sets a label on a Kubernetes service, a nil value removes it
+func SetServiceLabel(ctx context.Context, region, namespace, name, key string, value *string) error {
+	return SetServiceMetadata(ctx, region, namespace, name, map[string]*string{key: value}, nil)
+}
+
+// SetServiceMetadata sets labels and annotations of a Kubernetes service, nil values remove them
+func SetServiceMetadata(ctx context.Context, region, namespace, name string, labels, annotations map[string]*string) error {
+	client, err := GetClient(region)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	metadata := map[string]interface{}{}
+	if len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata patch: %w", err)
+	}
+
+	_, err = client.CoreV1().Services(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to patch service metadata: %w", err)
+	}
+
+	return nil
+}
+
 // CreateDockerConfigSecret creates a docker config secret for container registry authentication
-func CreateDockerConfigSecret(name, namespace, registryURL, username, password string) error {
-	// Build docker config JSON
+func CreateDockerConfigSecret(ctx context.Context, region, name, namespace, registryURL, username, password string) error {
+	dockerConfigJSON, err := DockerConfigJSON(registryURL, username, password)
+	if err != nil {
+		return err
+	}
+
+	return CreateOrUpdateSecret(ctx, region, name, namespace, "kubernetes.io/dockerconfigjson", map[string][]byte{
+		".dockerconfigjson": dockerConfigJSON,
+	})
+}
+
+// DockerConfigJSON builds the .dockerconfigjson of a pull secret for one registry
+func DockerConfigJSON(registryURL, username, password string) ([]byte, error) {
 	dockerConfig := map[string]interface{}{
 		"auths": map[string]interface{}{
 			registryURL: map[string]interface{}{
@@ -294,17 +615,14 @@ func CreateDockerConfigSecret(name, namespace, registryURL, username, password s
 
 	dockerConfigJSON, err := json.Marshal(dockerConfig)
 	if err != nil {
-		return fmt.Errorf("failed to marshal docker config: %w", err)
+		return nil, fmt.Errorf("failed to marshal docker config: %w", err)
 	}
-
-	return CreateOrUpdateSecret(name, namespace, "kubernetes.io/dockerconfigjson", map[string][]byte{
-		".dockerconfigjson": dockerConfigJSON,
-	})
+	return dockerConfigJSON, nil
 }
 
 // CreateECRSecret creates an ECR docker config secret
-func CreateECRSecret(name, namespace, registryURL, token string) error {
-	return CreateDockerConfigSecret(name, namespace, registryURL, "AWS", token)
+func CreateECRSecret(ctx context.Context, region, name, namespace, registryURL, token string) error {
+	return CreateDockerConfigSecret(ctx, region, name, namespace, registryURL, "AWS", token)
 }
 
 // encodeBase64 encodes a string to base64
@@ -313,8 +631,8 @@ func encodeBase64(s string) string {
 }
 
 // StreamPodLogsReader returns a ReadCloser for streaming pod logs
-func StreamPodLogsReader(ctx context.Context, namespace, podName string, sinceSeconds *int64) (io.ReadCloser, error) {
-	client, err := GetClient()
+func StreamPodLogsReader(ctx context.Context, region, namespace, podName string, sinceSeconds *int64) (io.ReadCloser, error) {
+	client, err := GetClient(region)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,217 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var eventTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// serviceEvent returns an event for an object, last seen minutes after eventTime
+func serviceEvent(namespace, name, kind, objectName string, minutes int) *corev1.Event {
+	seen := metav1.NewTime(eventTime.Add(time.Duration(minutes) * time.Minute))
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: namespace},
+		InvolvedObject: corev1.ObjectReference{Kind: kind, Name: objectName},
+		Type:           corev1.EventTypeNormal,
+		Reason:         "Scheduled",
+		Message:        name,
+		Count:          2,
+		FirstTimestamp: seen,
+		LastTimestamp:  seen,
+	}
+}
+
+func TestListServiceEventsFiltersByService(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		serviceEvent("proj1", "deployment", "Deployment", "svc1-deployment", 1),
+		serviceEvent("proj1", "pod", "Pod", "svc1-7d9f-xyz", 3),
+		serviceEvent("proj1", "other-service", "Pod", "svc2-7d9f-abc", 2),
+		serviceEvent("proj1", "same-prefix", "Pod", "svc10-7d9f-abc", 4),
+		serviceEvent("proj2", "other-namespace", "Pod", "svc1-7d9f-xyz", 5),
+	)
+
+	events, err := listServiceEvents(context.Background(), client, "proj1", "svc1", 0)
+	if err != nil {
+		t.Fatalf("listServiceEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	// Newest first
+	if events[0].Message != "pod" || events[1].Message != "deployment" {
+		t.Errorf("events are %q, %q, want pod then deployment", events[0].Message, events[1].Message)
+	}
+	if events[0].Object != "Pod/svc1-7d9f-xyz" {
+		t.Errorf("object = %q, want Pod/svc1-7d9f-xyz", events[0].Object)
+	}
+	if events[1].Count != 2 || events[1].Type != corev1.EventTypeNormal || events[1].Reason != "Scheduled" {
+		t.Errorf("deployment event = %+v, want it copied from the Kubernetes event", events[1])
+	}
+}
+
+func TestListServiceEventsLimit(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		serviceEvent("proj1", "first", "Pod", "svc1-a", 1),
+		serviceEvent("proj1", "second", "Pod", "svc1-b", 2),
+		serviceEvent("proj1", "third", "Pod", "svc1-c", 3),
+	)
+
+	events, err := listServiceEvents(context.Background(), client, "proj1", "svc1", 2)
+	if err != nil {
+		t.Fatalf("listServiceEvents: %v", err)
+	}
+	if len(events) != 2 || events[0].Message != "third" || events[1].Message != "second" {
+		t.Errorf("events = %+v, want the two newest", events)
+	}
+}
+
+func TestListServiceEventsFallbacks(t *testing.T) {
+	// Events from the events.k8s.io API only carry EventTime and no count
+	eventTimeOnly := serviceEvent("proj1", "event-time", "Pod", "svc1-a", 0)
+	eventTimeOnly.LastTimestamp = metav1.Time{}
+	eventTimeOnly.FirstTimestamp = metav1.Time{}
+	eventTimeOnly.EventTime = metav1.NewMicroTime(eventTime.Add(10 * time.Minute))
+	eventTimeOnly.Count = 0
+
+	created := serviceEvent("proj1", "created", "Pod", "svc1-b", 0)
+	created.LastTimestamp = metav1.Time{}
+	created.FirstTimestamp = metav1.Time{}
+	created.CreationTimestamp = metav1.NewTime(eventTime.Add(5 * time.Minute))
+
+	client := fake.NewSimpleClientset(eventTimeOnly, created)
+
+	events, err := listServiceEvents(context.Background(), client, "proj1", "svc1", 0)
+	if err != nil {
+		t.Fatalf("listServiceEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+
+	if got := events[0]; got.Message != "event-time" {
+		t.Fatalf("first event is %q, want event-time", got.Message)
+	}
+	if want := eventTime.Add(10 * time.Minute); !events[0].LastSeen.Equal(want) || !events[0].FirstSeen.Equal(want) {
+		t.Errorf("event-time seen %v to %v, want both %v", events[0].FirstSeen, events[0].LastSeen, want)
+	}
+	if events[0].Count != 1 {
+		t.Errorf("event-time count = %d, want 1", events[0].Count)
+	}
+
+	if want := eventTime.Add(5 * time.Minute); !events[1].LastSeen.Equal(want) || !events[1].FirstSeen.Equal(want) {
+		t.Errorf("created seen %v to %v, want both %v", events[1].FirstSeen, events[1].LastSeen, want)
+	}
+}
+
+// servicePod returns a pod in a namespace labelled for a service
+func servicePod(namespace, name, serviceID string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{"service": serviceID},
+	}}
+}
+
+func TestDeleteServicePod(t *testing.T) {
+	client := fake.NewSimpleClientset(servicePod("proj1", "svc1-7d9f-xyz", "svc1"))
+
+	if err := deleteServicePod(context.Background(), client, "proj1", "svc1", "svc1-7d9f-xyz"); err != nil {
+		t.Fatalf("deleteServicePod: %v", err)
+	}
+
+	var deleted []string
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "delete" && action.GetResource().Resource == "pods" {
+			deleted = append(deleted, action.GetNamespace())
+		}
+	}
+	if len(deleted) != 1 || deleted[0] != "proj1" {
+		t.Errorf("pods deleted in namespaces %v, want one in proj1", deleted)
+	}
+	if _, err := client.CoreV1().Pods("proj1").Get(context.Background(), "svc1-7d9f-xyz", metav1.GetOptions{}); err == nil {
+		t.Error("pod still exists after deleteServicePod")
+	}
+}
+
+func TestDeleteServicePodRejectsForeignPods(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+	}{
+		{name: "another service's pod", pod: servicePod("proj1", "svc2-7d9f-abc", "svc2")},
+		{name: "pod named after the service without its label", pod: servicePod("proj1", "svc1-7d9f-abc", "")},
+		{name: "service's pod in another namespace", pod: servicePod("proj2", "svc1-7d9f-abc", "svc1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.pod)
+
+			err := deleteServicePod(context.Background(), client, "proj1", "svc1", tt.pod.Name)
+			if err != ErrPodNotFound {
+				t.Fatalf("deleteServicePod error = %v, want ErrPodNotFound", err)
+			}
+			for _, action := range client.Actions() {
+				if action.GetVerb() == "delete" {
+					t.Errorf("pod was deleted: %v", action)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteServicePodMissing(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	if err := deleteServicePod(context.Background(), client, "proj1", "svc1", "svc1-gone"); err != ErrPodNotFound {
+		t.Fatalf("deleteServicePod error = %v, want ErrPodNotFound", err)
+	}
+}
+
+func TestDockerConfigJSON(t *testing.T) {
+	data, err := DockerConfigJSON("registry.example.com", "deployer", "s3cret")
+	if err != nil {
+		t.Fatalf("DockerConfigJSON: %v", err)
+	}
+
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("unmarshal %s: %v", data, err)
+	}
+	if len(config.Auths) != 1 {
+		t.Fatalf("auths = %v, want one registry", config.Auths)
+	}
+	auth, ok := config.Auths["registry.example.com"]
+	if !ok || auth.Username != "deployer" || auth.Password != "s3cret" {
+		t.Errorf("auth = %+v, want the credentials of registry.example.com", auth)
+	}
+	if decoded, _ := base64.StdEncoding.DecodeString(auth.Auth); string(decoded) != "deployer:s3cret" {
+		t.Errorf("auth = %q, want base64 of username:password", auth.Auth)
+	}
+}
+
+func TestRegionEnvSuffix(t *testing.T) {
+	for region, want := range map[string]string{
+		"":           "",
+		"eu-west":    "_EU_WEST",
+		"us.east.1":  "_US_EAST_1",
+		"Asia-South": "_ASIA_SOUTH",
+	} {
+		if got := regionEnvSuffix(region); got != want {
+			t.Errorf("regionEnvSuffix(%q) = %q, want %q", region, got, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// TerminalSize is the size of an interactive terminal
+type TerminalSize = remotecommand.TerminalSize
+
+// ExecOptions configures a command run inside a pod container
+type ExecOptions struct {
+	Region    string
+	Namespace string
+	PodName   string
+	Container string
+	Command   []string
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+	TTY       bool
+	Resize    <-chan TerminalSize
+}
+
+// sizeQueue adapts a channel of terminal sizes to remotecommand.TerminalSizeQueue
+type sizeQueue struct {
+	ctx    context.Context
+	resize <-chan TerminalSize
+}
+
+func (q *sizeQueue) Next() *TerminalSize {
+	select {
+	case <-q.ctx.Done():
+		return nil
+	case size, ok := <-q.resize:
+		if !ok {
+			return nil
+		}
+		return &size
+	}
+}
+
+// ExecInPod runs a command in a pod container and streams its input and output
+// until the command exits or ctx is cancelled
+func ExecInPod(ctx context.Context, opts ExecOptions) error {
+	c, err := getCluster(opts.Region)
+	if err != nil {
+		return err
+	}
+	client := c.clientset
+
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(opts.PodName).
+		Namespace(opts.Namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: opts.Container,
+			Command:   opts.Command,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    opts.Stdout != nil,
+			Stderr:    opts.Stderr != nil && !opts.TTY,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(c.restConfig, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create exec stream: %w", err)
+	}
+
+	streamOpts := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Tty:    opts.TTY,
+	}
+	// With a TTY stderr is merged into stdout
+	if !opts.TTY {
+		streamOpts.Stderr = opts.Stderr
+	}
+	if opts.Resize != nil {
+		streamOpts.TerminalSizeQueue = &sizeQueue{ctx: ctx, resize: opts.Resize}
+	}
+
+	return executor.StreamWithContext(ctx, streamOpts)
+}
@@ -5,9 +5,16 @@ import "github.com/gofiber/fiber/v2"
 // Response represents the standard API response structure
 // This structure MUST match the existing NextJS API response format
 type Response struct {
-	Status  string      `json:"status"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
+	Status    string      `json:"status"`
+	Message   string      `json:"message,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
+}
+
+// RequestID returns the ID assigned to the request by the request ID middleware
+func RequestID(c *fiber.Ctx) string {
+	requestID, _ := c.Locals("requestid").(string)
+	return requestID
 }
 
 // Success returns a success response
@@ -30,8 +37,9 @@ func SuccessWithMessage(c *fiber.Ctx, message string, data interface{}) error {
 // Error returns an error response with status code
 func Error(c *fiber.Ctx, statusCode int, message string) error {
 	return c.Status(statusCode).JSON(Response{
-		Status:  "error",
-		Message: message,
+		Status:    "error",
+		Message:   message,
+		RequestID: RequestID(c),
 	})
 }
 
@@ -60,6 +68,11 @@ func InternalServerError(c *fiber.Ctx, message string) error {
 	return Error(c, fiber.StatusInternalServerError, message)
 }
 
+// Conflict returns a 409 error
+func Conflict(c *fiber.Ctx, message string) error {
+	return Error(c, fiber.StatusConflict, message)
+}
+
 // TooManyRequests returns a 429 error
 func TooManyRequests(c *fiber.Ctx, message string) error {
 	return Error(c, fiber.StatusTooManyRequests, message)
@@ -0,0 +1,50 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+func TestErrorCarriesRequestID(t *testing.T) {
+	app := fiber.New()
+	app.Use(requestid.New())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return BadRequest(c, "bad")
+	})
+
+	send := func(requestID string) (header string, body Response) {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/", nil)
+		if requestID != "" {
+			req.Header.Set(fiber.HeaderXRequestID, requestID)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("GET /: %v", err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Header.Get(fiber.HeaderXRequestID), body
+	}
+
+	// A provided ID, from the proxy or the client, is echoed in the header and the error body
+	header, body := send("proxy-id-1")
+	if header != "proxy-id-1" || body.RequestID != "proxy-id-1" {
+		t.Errorf("provided ID echoed as header %q and body %q, want proxy-id-1", header, body.RequestID)
+	}
+
+	// A missing one is generated
+	header, body = send("")
+	if header == "" {
+		t.Fatal("no request ID generated")
+	}
+	if body.RequestID != header {
+		t.Errorf("error body has request ID %q, header has %q", body.RequestID, header)
+	}
+}
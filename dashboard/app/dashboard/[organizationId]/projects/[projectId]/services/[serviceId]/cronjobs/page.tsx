@@ -14,6 +14,13 @@ import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, D
 import { Label } from '@/components/ui/label';
 import { Input } from '@/components/ui/input';
 import { Textarea } from '@/components/ui/textarea';
+import {
+  Pagination,
+  PaginationContent,
+  PaginationItem,
+  PaginationNext,
+  PaginationPrevious
+} from '@/components/ui/pagination';
 import { getService, getCronJobs, createCronJob, updateCronJob, deleteCronJob } from '@/lib/api';
 import { CronJob, CreateCronJobInput, UpdateCronJobInput } from '@/lib/models';
 
@@ -58,20 +65,39 @@ export default function ServiceCronJobsPage() {
   const [isSubmitting, setIsSubmitting] = useState(false);
   const [scheduleError, setScheduleError] = useState<string | null>(null);
 
+  // Pagination state
+  const [currentPage, setCurrentPage] = useState(1);
+  const [totalPages, setTotalPages] = useState(1);
+  const pageSize = 20;
+
   const serviceId = params.serviceId as string;
 
-  // Fetch service and cronjobs data
+  // Fetch service data
+  useEffect(() => {
+    getService(serviceId)
+      .then(setService)
+      .catch((error) => {
+        console.error('Error fetching service:', error);
+        toast.error('Failed to load service');
+      });
+  }, [serviceId]);
+
+  // Fetch the current page of cronjobs
   useEffect(() => {
-    const fetchData = async () => {
+    const fetchCronJobs = async () => {
       setLoading(true);
       try {
-        const [serviceData, cronJobsData] = await Promise.all([
-          getService(serviceId),
-          getCronJobs(serviceId)
-        ]);
+        const cronJobsData = await getCronJobs(serviceId, { page: currentPage, limit: pageSize });
+
+        // A page emptied by deletions moves back to the last page there is
+        const lastPage = Math.max(1, cronJobsData.pagination.totalPages);
+        if (currentPage > lastPage) {
+          setCurrentPage(lastPage);
+          return;
+        }
 
-        setService(serviceData);
-        setCronJobs(cronJobsData);
+        setCronJobs(cronJobsData.cronJobs);
+        setTotalPages(cronJobsData.pagination.totalPages);
       } catch (error) {
         console.error('Error fetching data:', error);
         toast.error('Failed to load data');
@@ -80,8 +106,8 @@ export default function ServiceCronJobsPage() {
       }
     };
 
-    fetchData();
-  }, [serviceId]);
+    fetchCronJobs();
+  }, [serviceId, currentPage]);
 
   // Handle form input changes
   const handleInputChange = (e: React.ChangeEvent<HTMLInputElement | HTMLTextAreaElement>) => {
@@ -255,6 +281,10 @@ export default function ServiceCronJobsPage() {
       await deleteCronJob(serviceId, id);
       toast.success('CronJob deleted successfully');
       setCronJobs((prev) => prev.filter((job) => job.id !== id));
+      // Refetch the last page once it's empty
+      if (cronJobs.length === 1 && currentPage > 1) {
+        setCurrentPage(currentPage - 1);
+      }
     } catch (error) {
       console.error('Error deleting CronJob:', error);
       toast.error('Failed to delete CronJob');
@@ -569,6 +599,31 @@ export default function ServiceCronJobsPage() {
           ))}
         </div>
       )}
+
+      {/* Pagination */}
+      {totalPages > 1 && (
+        <div className="mt-6 flex items-center justify-between">
+          <span className="text-sm text-muted-foreground">
+            Page {currentPage} of {totalPages}
+          </span>
+          <Pagination className="mx-0 w-auto">
+            <PaginationContent>
+              <PaginationItem>
+                <PaginationPrevious
+                  onClick={() => setCurrentPage(Math.max(1, currentPage - 1))}
+                  className={currentPage === 1 ? "pointer-events-none opacity-50" : "cursor-pointer"}
+                />
+              </PaginationItem>
+              <PaginationItem>
+                <PaginationNext
+                  onClick={() => setCurrentPage(Math.min(totalPages, currentPage + 1))}
+                  className={currentPage === totalPages ? "pointer-events-none opacity-50" : "cursor-pointer"}
+                />
+              </PaginationItem>
+            </PaginationContent>
+          </Pagination>
+        </div>
+      )}
     </div>
   );
 }
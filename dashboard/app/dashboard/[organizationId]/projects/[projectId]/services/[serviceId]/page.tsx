@@ -814,6 +814,9 @@ export default function ServicePage() {
                           {event.type === 'SERVICE_SCALED' && (
                               <SquareCheckBig className="h-5 w-5" />
                           )}
+                          {event.type === 'SCALING_CANCELLED' && (
+                              <X className="h-5 w-5" />
+                          )}
                         </div>
                         <div className="flex flex-col">
                           <div className="flex items-center">
@@ -883,6 +886,7 @@ export default function ServicePage() {
                               {event.type === 'CONFIG_UPDATED' && "Configuration updated"}
                               {event.type === 'SERVICE_SCALED' && (event.message || `Service scaled to ${event.payload?.targetReplicas}`)}
                               {event.type === 'SERVICE_SCALING' && (event.message || `Service scaling to ${event.payload?.targetReplicas}`)}
+                              {event.type === 'SCALING_CANCELLED' && (event.message || "Scaling cancelled")}
                             </span>
                             {commitHash && (
                               <span className="ml-2 text-muted-foreground">
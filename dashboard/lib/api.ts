@@ -3,7 +3,7 @@ import Cookies from "js-cookie";
 import { 
   User, 
   Organization, CreateOrganizationInput, 
-  GitProvider, Repository, Branch, RepositoryDescription, 
+  GitProvider, Repository, Branch, RepositoryDescription, RepositoryAnalysis, RepositoryContents, 
   Service, CreateServiceInput, ServiceType, InstanceTypeGroup, 
   InstanceType, ServiceEvent, Deployment, DeploymentLog, PodInfo, ProfileUpdateData, PasswordUpdateData,
   GithubAccount, ApiKey, UpdateServiceScalingInput,
@@ -120,6 +120,13 @@ export async function createOrganization(input: CreateOrganizationInput): Promis
   });
 }
 
+export async function updateOrganization(id: string, data: { name?: string; description?: string | null; defaultRegion?: string }): Promise<Organization> {
+  return fetchApi<Organization>(`/organizations/${id}`, {
+    method: "POST",
+    body: JSON.stringify(data),
+  });
+}
+
 // Git Providers API functions
 export async function getGitProviders(organizationId: string): Promise<GitProvider[]> {
   return fetchApi<GitProvider[]>(`/git-providers?organizationId=${organizationId}`);
@@ -139,8 +146,12 @@ export async function deleteGitProvider(providerId: string): Promise<{ message:
 }
 
 // Repository and branch related functions
-export function getRepositories(providerId: string): Promise<Repository[]> {
-  return fetchApi<Repository[]>(`/git-providers/${providerId}/repositories`);
+export function getRepositories(providerId: string, filters?: { name?: string; language?: string }): Promise<Repository[]> {
+  const params = new URLSearchParams();
+  if (filters?.name) params.set("name", filters.name);
+  if (filters?.language) params.set("language", filters.language);
+  const query = params.toString();
+  return fetchApi<Repository[]>(`/git-providers/${providerId}/repositories${query ? `?${query}` : ""}`);
 }
 
 export function getBranches(providerId: string, repoFullName: string): Promise<Branch[]> {
@@ -162,6 +173,27 @@ export function getRepositoryDescription(providerId: string, repoFullName: strin
   );
 }
 
+export function analyzeRepository(providerId: string, repoFullName: string, branch: string, dockerFilePath?: string): Promise<RepositoryAnalysis> {
+  return fetchApi<RepositoryAnalysis>("/git/analyze", {
+    method: 'POST',
+    body: JSON.stringify({
+      providerId: providerId,
+      repositoryName: repoFullName,
+      branch: branch,
+      dockerfilePath: dockerFilePath || undefined
+    })
+  });
+}
+
+export function getRepositoryContents(providerId: string, repoFullName: string, path?: string, ref?: string): Promise<RepositoryContents> {
+  const searchParams = new URLSearchParams();
+  if (path) searchParams.append('path', path);
+  if (ref) searchParams.append('ref', ref);
+
+  const queryString = searchParams.toString() ? `?${searchParams.toString()}` : '';
+  return fetchApi<RepositoryContents>(`/git/${providerId}/repos/${repoFullName}/contents${queryString}`);
+}
+
 export function getServices(projectId: string): Promise<Service[]> {
   return fetchApi<Service[]>(`/services?projectId=${projectId}`);
 }
@@ -193,6 +225,12 @@ export function deleteService(serviceId: string): Promise<{ message: string }> {
   });
 }
 
+export function restoreService(serviceId: string): Promise<{ message: string }> {
+  return fetchApi<{ message: string }>(`/services/${serviceId}/restore`, {
+    method: "POST",
+  });
+}
+
 // Service events API functions
 export function getServiceEvents(serviceId: string): Promise<ServiceEvent[]> {
   return fetchApi<ServiceEvent[]>(`/services/${serviceId}/events`);
@@ -369,16 +407,36 @@ export function removeToken() {
 }
 
 export function getCronJobs(
-  serviceId: string
-): Promise<CronJob[]> {
-  return fetchApi<CronJob[]>(`/services/${serviceId}/cronjobs`);
+  serviceId: string,
+  filters?: {
+    enabled?: boolean;
+    sort?: 'name' | 'nextRunAt' | 'createdAt';
+    order?: 'asc' | 'desc';
+    page?: number;
+    limit?: number;
+  }
+): Promise<{ cronJobs: CronJob[]; pagination: { totalItems: number; totalPages: number; currentPage: number; itemsPerPage: number } }> {
+  const searchParams = new URLSearchParams();
+
+  if (filters) {
+    if (filters.enabled !== undefined) searchParams.append('enabled', filters.enabled.toString());
+    if (filters.sort) searchParams.append('sort', filters.sort);
+    if (filters.order) searchParams.append('order', filters.order);
+    if (filters.page) searchParams.append('page', filters.page.toString());
+    if (filters.limit) searchParams.append('limit', filters.limit.toString());
+  }
+
+  const queryString = searchParams.toString() ? `?${searchParams.toString()}` : '';
+  return fetchApi<{ cronJobs: CronJob[]; pagination: { totalItems: number; totalPages: number; currentPage: number; itemsPerPage: number } }>(`/services/${serviceId}/cronjobs${queryString}`);
 }
 
 export function getCronJob(
   serviceId: string,
-  cronJobId: string
+  cronJobId: string,
+  runs?: number
 ): Promise<CronJob> {
-  return fetchApi<CronJob>(`/services/${serviceId}/cronjobs/${cronJobId}`);
+  const queryString = runs ? `?runs=${runs}` : '';
+  return fetchApi<CronJob>(`/services/${serviceId}/cronjobs/${cronJobId}${queryString}`);
 }
 
 export function createCronJob(
@@ -425,9 +483,9 @@ export function getServiceMetrics(
   return fetchApi<MetricsResponse>(`/services/${serviceId}/metrics?${params.toString()}`);
 }
 
-// Get all environment variables for a service (keys only, masked values)
-export function getServiceEnvironmentVariables(serviceId: string): Promise<Array<{ key: string; value: string }>> {
-  return fetchApi<Array<{ key: string; value: string }>>(`/services/${serviceId}/environment-variables`);
+// Get all environment variables for a service (secret values masked)
+export function getServiceEnvironmentVariables(serviceId: string): Promise<Array<{ key: string; value: string; isSecret: boolean }>> {
+  return fetchApi<Array<{ key: string; value: string; isSecret: boolean }>>(`/services/${serviceId}/environment-variables`);
 }
 
 // Get a single environment variable value
@@ -436,7 +494,7 @@ export function getEnvironmentVariableValue(serviceId: string, key: string): Pro
 }
 
 // Update environment variables (add or modify)
-export function updateEnvironmentVariables(serviceId: string, variables: Array<{ key: string; value: string }>): Promise<{ message: string; count: number }> {
+export function updateEnvironmentVariables(serviceId: string, variables: Array<{ key: string; value: string; isSecret?: boolean }>): Promise<{ message: string; count: number }> {
   return fetchApi<{ message: string; count: number }>(`/services/${serviceId}/environment-variables/update`, {
     method: "PATCH",
     body: JSON.stringify({ variables }),
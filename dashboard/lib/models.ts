@@ -2,6 +2,7 @@
 export interface CreateOrganizationInput {
   name: string;
   description?: string;
+  defaultRegion?: string;
 }
 
 // GitHub related types
@@ -45,6 +46,7 @@ export interface Repository {
   private: boolean;
   description: string | null;
   defaultBranch: string;
+  language: string;
   url: string;
   createdAt: string;
   updatedAt: string;
@@ -71,6 +73,7 @@ export interface Organization {
   userId: string;
   name: string;
   description?: string | null;
+  defaultRegion?: string | null;
   createdAt: Date;
   updatedAt: Date;
 }
@@ -83,6 +86,34 @@ export interface RepositoryDescription {
   defaultBranch: string;
 }
 
+export interface RepositoryContentEntry {
+  name: string;
+  path: string;
+  type: "file" | "dir" | "symlink" | "submodule";
+  size: number;
+}
+
+export interface RepositoryContents {
+  path: string;
+  type: "file" | "dir";
+  entries: RepositoryContentEntry[];
+}
+
+export interface RepositoryAnalysis {
+  suggestion: {
+    serviceType: string;
+    runtime: 'dockerfile' | 'buildpacks';
+    language?: string;
+    framework?: string;
+    port: number;
+    buildCommand?: string;
+    startCommand?: string;
+    healthCheckPath?: string;
+    detectedFiles: string[];
+  };
+  template: string;
+}
+
 
 // Service related functions
 export interface ServicePort {
@@ -103,6 +134,8 @@ export interface Service {
   gitProviderId: string | null;
   repositoryName: string | null;
   branch: string | null;
+  region?: string | null;
+  version?: number;
   runtime: "IMAGE" | "DOCKER";
   containerRegistryImageUri?: string;
   subdomain?: string;
@@ -123,6 +156,7 @@ export interface Service {
   storageCapacity?: number; // Storage capacity in GB (primarily for MySQL and Memory instances)
   storageUsage?: number;
   runtimeFilePath?: string; // Path to the Dockerfile within the repository
+  buildContextPath?: string | null; // Subdirectory of the repository used as the build context
   ports?: ServicePort[];
   scaleToZeroEnabled?: boolean; // Scale to zero feature for free instances
   containerCommand?: string; // Container command override (JSON string)
@@ -189,7 +223,7 @@ export interface InstanceType {
 export interface ServiceEvent {
   id: string;
   serviceId: string;
-  type: "DEPLOY_STARTED" | "DEPLOY_COMPLETED" | "DEPLOY_FAILED" | "DEPLOY_CANCELLED" | "SERVICE_RESTART_STARTED" | "SERVICE_RESTART_COMPLETED" | "CONFIG_UPDATED" | "SERVICE_SCALED" | "SERVICE_SCALING";
+  type: "DEPLOY_STARTED" | "DEPLOY_COMPLETED" | "DEPLOY_FAILED" | "DEPLOY_CANCELLED" | "SERVICE_RESTART_STARTED" | "SERVICE_RESTART_COMPLETED" | "CONFIG_UPDATED" | "SERVICE_SCALED" | "SERVICE_SCALING" | "SCALING_CANCELLED";
   message?: string;
   deploymentId?: string;
   deployment?: Deployment | null;
@@ -234,12 +268,14 @@ export interface CreateServiceInput {
   repositoryName?: string;
   branch?: string;
   runtimeFilePath?: string;
+  buildContextPath?: string;
   dockerImageUrl?: string;
   environmentVariables?: { key: string; value: string }[];
   portSettings?: { servicePort: number; containerPort: number }[];
   storageCapacity?: number;
   instanceTypeId: string;
   containerCommand?: string[];
+  region?: string;
 }
 
 export interface UpdateServiceScalingInput {
@@ -280,6 +316,7 @@ export interface CronJob {
   id: string;
   name: string;
   schedule: string;
+  timezone: string;
   path: string;
   headers: Record<string, string> | null;
   enabled: boolean;
@@ -288,11 +325,13 @@ export interface CronJob {
   updatedAt: string;
   lastRunAt: string | null;
   nextRunAt: string | null;
+  nextRuns?: string[];
 }
 
 export interface CreateCronJobInput {
   name: string;
   schedule: string;
+  timezone?: string;
   path: string;
   headers?: Record<string, string> | null;
   enabled?: boolean;
@@ -301,6 +340,7 @@ export interface CreateCronJobInput {
 export interface UpdateCronJobInput {
   name?: string;
   schedule?: string;
+  timezone?: string;
   path?: string;
   headers?: Record<string, string> | null;
   enabled?: boolean;
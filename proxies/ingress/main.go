@@ -23,6 +23,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
 
 	// Create context that listens for termination signals
 	ctx, cancel := context.WithCancel(context.Background())
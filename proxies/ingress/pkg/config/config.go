@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -19,6 +20,17 @@ type PortMapping struct {
 
 	// ServicePort is the port on the Kubernetes service
 	ServicePort int `json:"service_port"`
+
+	// TLS terminates TLS on the listener and forwards plaintext to the service when set
+	TLS *TLSConfig `json:"tls,omitempty"`
+}
+
+// TLSConfig is the certificate a port mapping terminates TLS with
+type TLSConfig struct {
+	// CertFile and KeyFile are PEM files, e.g. a mounted Kubernetes TLS secret.
+	// They are re-read when they change on disk.
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
 }
 
 // Config holds the configuration for the Ingress Proxy
@@ -46,6 +58,27 @@ type Config struct {
 
 	// PortMappings defines the TCP port to service mappings
 	PortMappings []PortMapping `json:"port_mappings"`
+
+	// DynamicPorts enables mappings read from Kubernetes services carrying an ingress-port label
+	DynamicPorts bool `json:"dynamic_ports"`
+
+	// DynamicPortMin and DynamicPortMax bound the external ports dynamic mappings may use
+	DynamicPortMin int `json:"dynamic_port_min"`
+	DynamicPortMax int `json:"dynamic_port_max"`
+
+	// KubeConfigPath is the kubeconfig used when not running in-cluster
+	KubeConfigPath string `json:"kube_config_path"`
+
+	// LabelSelector selects the services watched for dynamic mappings
+	LabelSelector string `json:"label_selector"`
+
+	// LabelSelectors are watched besides LabelSelector, a service matching any of them
+	// is picked up
+	LabelSelectors []string `json:"label_selectors"`
+
+	// WatchNamespaces limits the service watch to these namespaces, so the proxy only needs
+	// namespaced RBAC. All namespaces are watched when empty.
+	WatchNamespaces []string `json:"watch_namespaces"`
 }
 
 // DefaultConfig returns a default configuration
@@ -56,6 +89,9 @@ func DefaultConfig() *Config {
 		ConnectionTimeout: 1 * time.Second,
 		ReadBufferSize:    65536,
 		WriteBufferSize:   65536,
+		DynamicPortMin:    20000,
+		DynamicPortMax:    20999,
+		LabelSelector:     "managedBy=kubestrator,type=private,ingress-port",
 		// ReadTimeout:       30 * time.Second,
 		// WriteTimeout:      30 * time.Second,
 		PortMappings: []PortMapping{
@@ -88,3 +124,18 @@ func Load(configPath string) (*Config, error) {
 
 	return config, nil
 }
+
+// Selectors returns the label selectors to watch, label_selector followed by label_selectors.
+// A service matching any of them is picked up.
+func (c *Config) Selectors() []string {
+	var selectors []string
+	seen := make(map[string]bool)
+	for _, selector := range append([]string{c.LabelSelector}, c.LabelSelectors...) {
+		selector = strings.TrimSpace(selector)
+		if selector != "" && !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
+	}
+	return selectors
+}
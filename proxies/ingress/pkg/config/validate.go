@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Validate checks for missing settings, values out of range and settings that depend on
+// each other, and returns every problem found so a config can be fixed in one go
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.MaxConnections <= 0 {
+		errs = append(errs, fmt.Errorf("max_connections must be positive, got %d", c.MaxConnections))
+	}
+	if c.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("idle_timeout must not be negative, got %v", c.IdleTimeout))
+	}
+	if c.ConnectionTimeout < 0 {
+		errs = append(errs, fmt.Errorf("connection_timeout must not be negative, got %v", c.ConnectionTimeout))
+	}
+	if c.ReadBufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("read_buffer_size must be positive, got %d", c.ReadBufferSize))
+	}
+	if c.WriteBufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("write_buffer_size must be positive, got %d", c.WriteBufferSize))
+	}
+
+	ports := make(map[int]bool, len(c.PortMappings))
+	for i, mapping := range c.PortMappings {
+		if !validPort(mapping.Port) {
+			errs = append(errs, fmt.Errorf("port_mappings[%d]: port %d is not between 1 and 65535", i, mapping.Port))
+		} else if ports[mapping.Port] {
+			errs = append(errs, fmt.Errorf("port_mappings[%d]: port %d is mapped more than once", i, mapping.Port))
+		}
+		ports[mapping.Port] = true
+
+		if mapping.ServiceName == "" {
+			errs = append(errs, fmt.Errorf("port_mappings[%d]: service_name is required", i))
+		}
+		if mapping.ServiceNamespace == "" {
+			errs = append(errs, fmt.Errorf("port_mappings[%d]: service_namespace is required", i))
+		}
+		if !validPort(mapping.ServicePort) {
+			errs = append(errs, fmt.Errorf("port_mappings[%d]: service_port %d is not between 1 and 65535", i, mapping.ServicePort))
+		}
+		if mapping.TLS != nil && (mapping.TLS.CertFile == "" || mapping.TLS.KeyFile == "") {
+			errs = append(errs, fmt.Errorf("port_mappings[%d]: tls needs both cert_file and key_file", i))
+		}
+	}
+
+	if c.DynamicPorts {
+		if !validPort(c.DynamicPortMin) || !validPort(c.DynamicPortMax) || c.DynamicPortMin > c.DynamicPortMax {
+			errs = append(errs, fmt.Errorf("dynamic_port_min %d and dynamic_port_max %d must be a range within 1-65535",
+				c.DynamicPortMin, c.DynamicPortMax))
+		}
+
+		if len(c.Selectors()) == 0 {
+			errs = append(errs, errors.New("dynamic_ports requires label_selector or label_selectors"))
+		}
+		for _, selector := range c.Selectors() {
+			if _, err := labels.Parse(selector); err != nil {
+				errs = append(errs, fmt.Errorf("invalid label selector %q: %v", selector, err))
+			}
+		}
+
+		for _, namespace := range c.WatchNamespaces {
+			if problems := validation.IsDNS1123Label(namespace); len(problems) > 0 {
+				errs = append(errs, fmt.Errorf("invalid namespace %q in watch_namespaces: %v", namespace, problems[0]))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validPort reports whether port is a usable TCP port number
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateDefaultConfig(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("default config is invalid: %v", err)
+	}
+}
+
+func TestValidateInvalidConfigs(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{name: "no connections", modify: func(c *Config) { c.MaxConnections = 0 }, want: "max_connections must be positive, got 0"},
+		{name: "negative idle timeout", modify: func(c *Config) { c.IdleTimeout = -time.Second }, want: "idle_timeout must not be negative, got -1s"},
+		{name: "no read buffer", modify: func(c *Config) { c.ReadBufferSize = 0 }, want: "read_buffer_size must be positive"},
+		{name: "port out of range", modify: func(c *Config) { c.PortMappings[0].Port = 70000 }, want: "port_mappings[0]: port 70000 is not between 1 and 65535"},
+		{name: "port mapped twice", modify: func(c *Config) { c.PortMappings[1].Port = 80 }, want: "port_mappings[1]: port 80 is mapped more than once"},
+		{name: "no service name", modify: func(c *Config) { c.PortMappings[2].ServiceName = "" }, want: "port_mappings[2]: service_name is required"},
+		{name: "no service namespace", modify: func(c *Config) { c.PortMappings[2].ServiceNamespace = "" }, want: "port_mappings[2]: service_namespace is required"},
+		{name: "bad service port", modify: func(c *Config) { c.PortMappings[3].ServicePort = 0 }, want: "port_mappings[3]: service_port 0 is not between 1 and 65535"},
+		{name: "tls without a key", modify: func(c *Config) { c.PortMappings[0].TLS = &TLSConfig{CertFile: "/certs/tls.crt"} }, want: "port_mappings[0]: tls needs both cert_file and key_file"},
+		{name: "inverted dynamic range", modify: func(c *Config) { c.DynamicPorts = true; c.DynamicPortMin = 21000 }, want: "dynamic_port_min 21000 and dynamic_port_max 20999 must be a range"},
+		{name: "dynamic ports without a selector", modify: func(c *Config) { c.DynamicPorts = true; c.LabelSelector = "" }, want: "dynamic_ports requires label_selector or label_selectors"},
+		{name: "bad namespace", modify: func(c *Config) { c.DynamicPorts = true; c.WatchNamespaces = []string{"-team"} }, want: `invalid namespace "-team"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// The selector and namespaces are only used by dynamic ports
+func TestValidateSkipsDisabledDynamicPorts(t *testing.T) {
+	config := DefaultConfig()
+	config.LabelSelector = ""
+	config.DynamicPortMin = 0
+	config.WatchNamespaces = []string{"Team_A"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("dynamic port settings checked with dynamic_ports off: %v", err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxConnections = -1
+	config.WriteBufferSize = 0
+	config.PortMappings[4].ServiceName = ""
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate() accepted an invalid config")
+	}
+	for _, want := range []string{"max_connections must be positive", "write_buffer_size must be positive", "port_mappings[4]: service_name is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, missing %q", err, want)
+		}
+	}
+}
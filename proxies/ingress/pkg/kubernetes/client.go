@@ -0,0 +1,265 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+)
+
+// IngressPortLabel is the service label holding the external port of a dynamic mapping
+const IngressPortLabel = "ingress-port"
+
+type MappingAction int
+
+const (
+	Add MappingAction = iota
+	Delete
+)
+
+// Mapping is an external port mapping read from a labelled Kubernetes service
+type Mapping struct {
+	Port             int
+	ServiceName      string
+	ServiceNamespace string
+	ServicePort      int
+}
+
+// MappingChangeCallback is called with the service key ("namespace/name") when a mapping changes
+type MappingChangeCallback func(MappingAction, string, *Mapping)
+
+// Client watches Kubernetes services for dynamic port mappings
+type Client struct {
+	clientset      kubernetes.Interface
+	labelSelectors []string
+	watchContext   context.Context
+	watchCancel    context.CancelFunc
+	namespaces     []string
+}
+
+// NewClient creates a new Kubernetes client
+func NewClient(kubeConfigPath string, labelSelectors []string) (*Client, error) {
+	var config *rest.Config
+	var err error
+
+	if kubeConfigPath == "" {
+		// Try in-cluster config first, then the default kubeconfig location
+		config, err = rest.InClusterConfig()
+		if err != nil {
+			home := homedir.HomeDir()
+			if home == "" {
+				return nil, fmt.Errorf("no kubeconfig found")
+			}
+			config, err = clientcmd.BuildConfigFromFlags("", filepath.Join(home, ".kube", "config"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Kubernetes client config: %v", err)
+			}
+		}
+	} else {
+		path := kubeConfigPath
+		if strings.HasPrefix(path, "~/") {
+			path = filepath.Join(homedir.HomeDir(), path[2:])
+		}
+
+		config, err = clientcmd.BuildConfigFromFlags("", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Kubernetes client config: %v", err)
+		}
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes clientset: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Client{
+		clientset:      clientset,
+		labelSelectors: labelSelectors,
+		watchContext:   ctx,
+		watchCancel:    cancel,
+	}, nil
+}
+
+// StartWatching starts watching services in the configured namespaces
+func (c *Client) StartWatching(callback MappingChangeCallback) {
+	// Start one watcher per namespace and label selector, taking turns to run the callback
+	var mu sync.Mutex
+	matched := newMatchedMappings(callback)
+	for i, labelSelector := range c.labelSelectors {
+		report := matched.callbackFor(i)
+		serialized := func(action MappingAction, key string, info *Mapping) {
+			mu.Lock()
+			defer mu.Unlock()
+			report(action, key, info)
+		}
+		for _, namespace := range c.watchedNamespaces() {
+			go c.watchServices(namespace, labelSelector, serialized)
+		}
+	}
+}
+
+// SetWatchNamespaces limits the watch to services in the given namespaces, all namespaces
+// are watched when none are given
+func (c *Client) SetWatchNamespaces(namespaces []string) {
+	c.namespaces = namespaces
+}
+
+// watchedNamespaces returns the namespaces to watch, metav1.NamespaceAll when none are set
+func (c *Client) watchedNamespaces() []string {
+	if len(c.namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return c.namespaces
+}
+
+// StopWatching stops the watcher
+func (c *Client) StopWatching() {
+	c.watchCancel()
+}
+
+// watchServices lists and then watches labelled services in a namespace, or all of them,
+// restarting the cycle when the watch ends
+func (c *Client) watchServices(namespace, labelSelector string, callback MappingChangeCallback) {
+	log.Printf("Starting to watch %s for services with label selector: %s", describeNamespace(namespace), labelSelector)
+
+	retry := backoff{base: watchRetryBase, max: watchRetryMax}
+	reported := newReportedMappings(callback)
+
+	for {
+		select {
+		case <-c.watchContext.Done():
+			return
+		default:
+		}
+
+		listOptions := metav1.ListOptions{
+			LabelSelector: labelSelector,
+		}
+
+		services, err := c.clientset.CoreV1().Services(namespace).List(c.watchContext, listOptions)
+		if err != nil {
+			delay := retry.next()
+			log.Printf("Error listing services: %v, retrying in %v...", err, delay.Round(time.Millisecond))
+			if !sleepContext(c.watchContext, delay) {
+				return
+			}
+			continue
+		}
+		retry.reset()
+
+		log.Printf("Found %d existing services in %s with label selector: %s", len(services.Items), describeNamespace(namespace), labelSelector)
+		listed := make(map[string]bool, len(services.Items))
+		for i := range services.Items {
+			if key, ok := c.handleServiceChange(&services.Items[i], reported); ok {
+				listed[key] = true
+			}
+		}
+		reported.prune(listed)
+
+		listOptions.ResourceVersion = services.ResourceVersion
+		watcher, err := c.clientset.CoreV1().Services(namespace).Watch(c.watchContext, listOptions)
+		if err != nil {
+			delay := retry.next()
+			log.Printf("Error creating watcher: %v, retrying in %v...", err, delay.Round(time.Millisecond))
+			if !sleepContext(c.watchContext, delay) {
+				return
+			}
+			continue
+		}
+
+		watchLoop := true
+		for watchLoop {
+			select {
+			case <-c.watchContext.Done():
+				watcher.Stop()
+				return
+			case event, ok := <-watcher.ResultChan():
+				if !ok {
+					log.Println("Watcher channel closed, will re-list and restart watcher...")
+					watchLoop = false
+					break
+				}
+
+				service, ok := event.Object.(*corev1.Service)
+				if !ok {
+					continue
+				}
+
+				switch event.Type {
+				case watch.Added, watch.Modified:
+					c.handleServiceChange(service, reported)
+				case watch.Deleted:
+					log.Printf("Service deleted: %s/%s", service.Namespace, service.Name)
+					reported.remove(serviceKey(service))
+				}
+			}
+		}
+
+		watcher.Stop()
+		if !sleepContext(c.watchContext, retry.next()) {
+			return
+		}
+	}
+}
+
+// describeNamespace names a watched namespace in log messages
+func describeNamespace(namespace string) string {
+	if namespace == metav1.NamespaceAll {
+		return "all namespaces"
+	}
+	return "namespace " + namespace
+}
+
+// handleServiceChange reads the mapping of a service and reports it, or reports a removal
+// when the service no longer carries a valid mapping. It returns the key of the service
+// and whether it has a valid mapping.
+func (c *Client) handleServiceChange(service *corev1.Service, reported *reportedMappings) (string, bool) {
+	key := serviceKey(service)
+
+	mapping, err := serviceMapping(service)
+	if err != nil {
+		log.Printf("Ignoring service %s: %v", key, err)
+		reported.remove(key)
+		return key, false
+	}
+
+	reported.add(key, mapping)
+	return key, true
+}
+
+// serviceMapping builds the mapping from the ingress-port label and the first service port
+func serviceMapping(service *corev1.Service) (*Mapping, error) {
+	port, err := strconv.Atoi(service.Labels[IngressPortLabel])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s label %q", IngressPortLabel, service.Labels[IngressPortLabel])
+	}
+
+	if len(service.Spec.Ports) == 0 {
+		return nil, fmt.Errorf("service has no ports")
+	}
+
+	return &Mapping{
+		Port:             port,
+		ServiceName:      service.Name,
+		ServiceNamespace: service.Namespace,
+		ServicePort:      int(service.Spec.Ports[0].Port),
+	}, nil
+}
+
+func serviceKey(service *corev1.Service) string {
+	return fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+}
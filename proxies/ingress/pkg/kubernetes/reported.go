@@ -0,0 +1,84 @@
+package kubernetes
+
+import "reflect"
+
+// reportedMappings remembers what was last reported for every service, so a service seen
+// again by a relist, or by a Modified event that changed nothing the proxy reads, is not
+// reported twice
+type reportedMappings struct {
+	callback MappingChangeCallback
+	mappings map[string]*Mapping
+}
+
+func newReportedMappings(callback MappingChangeCallback) *reportedMappings {
+	return &reportedMappings{
+		callback: callback,
+		mappings: make(map[string]*Mapping),
+	}
+}
+
+// add reports the mapping of a service unless it equals what was last reported for it, and
+// returns whether it was reported
+func (r *reportedMappings) add(key string, mapping *Mapping) bool {
+	if last, ok := r.mappings[key]; ok && reflect.DeepEqual(last, mapping) {
+		return false
+	}
+	r.mappings[key] = mapping
+	r.callback(Add, key, mapping)
+	return true
+}
+
+// remove reports the removal of a mapping, if it was reported before
+func (r *reportedMappings) remove(key string) {
+	if _, ok := r.mappings[key]; !ok {
+		return
+	}
+	delete(r.mappings, key)
+	r.callback(Delete, key, nil)
+}
+
+// prune removes the reported mappings missing from a fresh list, they went away while
+// no watch was running
+func (r *reportedMappings) prune(listed map[string]bool) {
+	for key := range r.mappings {
+		if !listed[key] {
+			r.remove(key)
+		}
+	}
+}
+
+// matchedMappings merges what the watchers of several label selectors report, a service
+// matching more than one of them is only removed once none matches it anymore
+type matchedMappings struct {
+	callback MappingChangeCallback
+	matches  map[string]map[int]bool
+}
+
+func newMatchedMappings(callback MappingChangeCallback) *matchedMappings {
+	return &matchedMappings{
+		callback: callback,
+		matches:  make(map[string]map[int]bool),
+	}
+}
+
+// callbackFor returns the callback of the watchers of a selector, by index. Calls must
+// take turns.
+func (m *matchedMappings) callbackFor(selector int) MappingChangeCallback {
+	return func(action MappingAction, key string, mapping *Mapping) {
+		switch action {
+		case Add:
+			if m.matches[key] == nil {
+				m.matches[key] = make(map[int]bool)
+			}
+			m.matches[key][selector] = true
+			m.callback(Add, key, mapping)
+		case Delete:
+			delete(m.matches[key], selector)
+			if len(m.matches[key]) > 0 {
+				return
+			}
+			delete(m.matches, key)
+			m.callback(Delete, key, nil)
+		}
+	}
+}
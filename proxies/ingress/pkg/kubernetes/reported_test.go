@@ -0,0 +1,27 @@
+package kubernetes
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchedMappingsRemovesOnceNoSelectorMatches(t *testing.T) {
+	var reports []string
+	matched := newMatchedMappings(func(action MappingAction, key string, info *Mapping) {
+		reports = append(reports, fmt.Sprintf("%v %s", action, key))
+	})
+	first, second := matched.callbackFor(0), matched.callbackFor(1)
+
+	first(Add, "ns/svc", &Mapping{})
+	second(Add, "ns/svc", &Mapping{})
+	// The service still matches the second selector
+	first(Delete, "ns/svc", nil)
+	if len(reports) != 2 {
+		t.Fatalf("reports = %v, want the adds only while a selector still matches", reports)
+	}
+
+	second(Delete, "ns/svc", nil)
+	if len(reports) != 3 || reports[2] != fmt.Sprintf("%v ns/svc", Delete) {
+		t.Errorf("reports = %v, want the service removed once no selector matches", reports)
+	}
+}
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"context"
+	"log"
+
+	"github.com/deployra/deployra/proxies/ingress/pkg/config"
+	"github.com/deployra/deployra/proxies/ingress/pkg/kubernetes"
+)
+
+// startDynamicPorts watches Kubernetes services for ingress-port labels and opens or closes
+// listeners as they appear and disappear. The returned function stops the watcher.
+func (s *Server) startDynamicPorts(ctx context.Context) (func(), error) {
+	client, err := kubernetes.NewClient(s.config.KubeConfigPath, s.config.Selectors())
+	if err != nil {
+		return nil, err
+	}
+	client.SetWatchNamespaces(s.config.WatchNamespaces)
+
+	log.Printf("Dynamic port mappings enabled for ports %d-%d", s.config.DynamicPortMin, s.config.DynamicPortMax)
+
+	client.StartWatching(func(action kubernetes.MappingAction, key string, mapping *kubernetes.Mapping) {
+		switch action {
+		case kubernetes.Add:
+			s.setDynamicMapping(ctx, key, mapping)
+		case kubernetes.Delete:
+			s.removeDynamicMapping(key)
+		}
+	})
+
+	return client.StopWatching, nil
+}
+
+// setDynamicMapping adds or updates the mapping of a Kubernetes service
+func (s *Server) setDynamicMapping(ctx context.Context, key string, mapping *kubernetes.Mapping) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target := &config.PortMapping{
+		Port:             mapping.Port,
+		ServiceName:      mapping.ServiceName,
+		ServiceNamespace: mapping.ServiceNamespace,
+		ServicePort:      mapping.ServicePort,
+	}
+
+	// Same port as before, only the target may have changed
+	if port, exists := s.dynamicPorts[key]; exists {
+		if port == mapping.Port {
+			s.portMappings[port] = target
+			return
+		}
+		s.removeDynamicMappingLocked(key)
+	}
+
+	if mapping.Port < s.config.DynamicPortMin || mapping.Port > s.config.DynamicPortMax {
+		log.Printf("Ignoring service %s: port %d is outside the dynamic port range", key, mapping.Port)
+		return
+	}
+
+	if _, taken := s.portMappings[mapping.Port]; taken {
+		log.Printf("Ignoring service %s: port %d is already mapped", key, mapping.Port)
+		return
+	}
+
+	if err := s.startListenerLocked(ctx, mapping.Port); err != nil {
+		log.Printf("Failed to start listener for service %s: %v", key, err)
+		return
+	}
+
+	s.portMappings[mapping.Port] = target
+	s.dynamicPorts[key] = mapping.Port
+	log.Printf("Dynamic port mapping: %d -> %s.%s.svc.cluster.local:%d",
+		mapping.Port, mapping.ServiceName, mapping.ServiceNamespace, mapping.ServicePort)
+}
+
+// removeDynamicMapping removes the mapping of a Kubernetes service, if it has one
+func (s *Server) removeDynamicMapping(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeDynamicMappingLocked(key)
+}
+
+// removeDynamicMappingLocked closes the listener of a dynamic mapping, s.mu must be held.
+// Connections that are already established are left to finish.
+func (s *Server) removeDynamicMappingLocked(key string) {
+	port, exists := s.dynamicPorts[key]
+	if !exists {
+		return
+	}
+
+	if listener, ok := s.listeners[port]; ok {
+		listener.Close()
+		delete(s.listeners, port)
+	}
+	delete(s.portMappings, port)
+	delete(s.dynamicPorts, key)
+
+	log.Printf("Removed dynamic port mapping %d for service %s", port, key)
+}
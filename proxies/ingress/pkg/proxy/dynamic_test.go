@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/deployra/deployra/proxies/ingress/pkg/config"
+	"github.com/deployra/deployra/proxies/ingress/pkg/kubernetes"
+)
+
+func TestDynamicMappingOpensAndReleasesPort(t *testing.T) {
+	port := freePort(t)
+	server := testServer(t, &config.Config{DynamicPortMin: port, DynamicPortMax: port})
+	backend := echoBackend(t)
+
+	server.setDynamicMapping(context.Background(), "project/db-service", &kubernetes.Mapping{
+		Port: port, ServiceName: "db-service", ServiceNamespace: "project", ServicePort: backend,
+	})
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatalf("dial the allocated port: %v", err)
+	}
+	defer conn.Close()
+	if got := echo(t, conn, "ping\n"); got != "ping\n" {
+		t.Errorf("echoed %q through the dynamic mapping, want ping", got)
+	}
+
+	// Releasing the port closes its listener, the connection already open keeps working
+	server.removeDynamicMapping("project/db-service")
+	if c, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port)); err == nil {
+		c.Close()
+		t.Error("released port still accepts connections")
+	}
+	if got := echo(t, conn, "still\n"); got != "still\n" {
+		t.Errorf("open connection echoed %q after the release, want still", got)
+	}
+}
+
+func TestDynamicMappingAvoidsCollisions(t *testing.T) {
+	port := freePort(t)
+	other := freePort(t)
+	server := testServer(t, &config.Config{
+		DynamicPortMin: port,
+		DynamicPortMax: port,
+		PortMappings:   []config.PortMapping{{Port: port, ServiceName: "static", ServiceNamespace: "infra", ServicePort: 5432}},
+	})
+
+	// A port taken by a static mapping isn't given to a service
+	server.setDynamicMapping(context.Background(), "project/db-service", &kubernetes.Mapping{Port: port, ServiceName: "db-service", ServiceNamespace: "project"})
+	if server.portMappings[port].ServiceName != "static" {
+		t.Errorf("port %d mapped to %s, want the static mapping kept", port, server.portMappings[port].ServiceName)
+	}
+	if _, ok := server.dynamicPorts["project/db-service"]; ok {
+		t.Error("colliding service was recorded as mapped")
+	}
+
+	// Nor is a port outside the dynamic range
+	server.setDynamicMapping(context.Background(), "project/other-service", &kubernetes.Mapping{Port: other, ServiceName: "other-service", ServiceNamespace: "project"})
+	if _, ok := server.portMappings[other]; ok {
+		t.Errorf("port %d outside the dynamic range was mapped", other)
+	}
+}
+
+func TestDynamicMappingMovesPort(t *testing.T) {
+	first := freePort(t)
+	second := freePort(t)
+	low, high := first, second
+	if low > high {
+		low, high = high, low
+	}
+	server := testServer(t, &config.Config{DynamicPortMin: low, DynamicPortMax: high})
+
+	server.setDynamicMapping(context.Background(), "project/db-service", &kubernetes.Mapping{Port: first, ServiceName: "db-service", ServiceNamespace: "project"})
+	server.setDynamicMapping(context.Background(), "project/db-service", &kubernetes.Mapping{Port: second, ServiceName: "db-service", ServiceNamespace: "project"})
+
+	if _, ok := server.portMappings[first]; ok {
+		t.Errorf("old port %d still mapped after the service moved", first)
+	}
+	if server.dynamicPorts["project/db-service"] != second {
+		t.Errorf("service mapped to %d, want %d", server.dynamicPorts["project/db-service"], second)
+	}
+}
@@ -2,6 +2,8 @@ package proxy
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -28,16 +30,28 @@ type Server struct {
 	connSem      *semaphore.Weighted         // Semaphore to limit concurrent connections
 	bufferPool   *BufferPool                 // Pool of buffers for I/O operations
 	dnsCache     *DNSCache                   // Cache for DNS resolutions
+	dynamicPorts map[string]int              // Maps Kubernetes service key to its dynamic port
+	tlsConfigs   map[int]*tls.Config         // TLS settings of static mappings that terminate TLS
+	mu           sync.RWMutex                // Guards listeners, portMappings and dynamicPorts
 }
 
 // NewServer creates a new proxy server
 func NewServer(cfg *config.Config) (*Server, error) {
 	// Create port to service mapping for more efficient lookup
 	portMappings := make(map[int]*config.PortMapping)
+	tlsConfigs := make(map[int]*tls.Config)
 	for _, mapping := range cfg.PortMappings {
 		// Store a pointer to the mapping in the config
 		mappingCopy := mapping // Make a copy to avoid pointer issues
 		portMappings[mapping.Port] = &mappingCopy
+
+		if mapping.TLS != nil {
+			tlsConfig, err := newTLSConfig(mapping.TLS)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TLS config for port %d: %v", mapping.Port, err)
+			}
+			tlsConfigs[mapping.Port] = tlsConfig
+		}
 	}
 
 	// Create server instance with connection limiting semaphore, buffer pool, and DNS cache
@@ -48,6 +62,8 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		connSem:      semaphore.NewWeighted(int64(cfg.MaxConnections)),
 		bufferPool:   NewBufferPool(cfg.ReadBufferSize),
 		dnsCache:     NewDNSCache(5 * time.Minute), // 5-minute TTL for DNS cache entries
+		dynamicPorts: make(map[string]int),
+		tlsConfigs:   tlsConfigs,
 	}
 
 	log.Printf("Proxy server initialized with maximum %d concurrent connections and %d byte buffers",
@@ -74,6 +90,15 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}
 
+	// Watch Kubernetes for mappings of services exposed through the ingress
+	if s.config.DynamicPorts {
+		stopWatching, err := s.startDynamicPorts(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to start dynamic port watcher: %v", err)
+		}
+		defer stopWatching()
+	}
+
 	// Wait for context cancellation to stop servers
 	<-ctx.Done()
 	log.Println("Shutting down servers...")
@@ -105,10 +130,12 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 
 	// Close all listeners
+	s.mu.Lock()
 	for port, listener := range s.listeners {
 		log.Printf("Closing listener on port %d", port)
 		listener.Close()
 	}
+	s.mu.Unlock()
 
 	return nil
 }
@@ -144,6 +171,14 @@ func (s *Server) startHealthServer(ctx context.Context) error {
 
 // startListener starts a listener on the specified port
 func (s *Server) startListener(ctx context.Context, port int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.startListenerLocked(ctx, port)
+}
+
+// startListenerLocked starts a listener on the specified port, s.mu must be held
+func (s *Server) startListenerLocked(ctx context.Context, port int) error {
 	// Skip the health check port, as it's handled by the HTTP server
 	if port == 8088 {
 		return nil
@@ -160,15 +195,13 @@ func (s *Server) startListener(ctx context.Context, port int) error {
 	log.Printf("Proxy listening on port %d", port)
 
 	// Start connection handler
-	go s.acceptConnections(ctx, port)
+	go s.acceptConnections(ctx, listener, port)
 
 	return nil
 }
 
 // acceptConnections accepts incoming connections
-func (s *Server) acceptConnections(ctx context.Context, port int) {
-	listener := s.listeners[port]
-
+func (s *Server) acceptConnections(ctx context.Context, listener net.Listener, port int) {
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
@@ -176,6 +209,10 @@ func (s *Server) acceptConnections(ctx context.Context, port int) {
 			case <-ctx.Done():
 				return
 			default:
+				// The listener was closed because its dynamic mapping was removed
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
 				log.Printf("Failed to accept connection on port %d: %v", port, err)
 				continue
 			}
@@ -222,12 +259,26 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn, sour
 	log.Printf("New connection from %s on port %d", clientAddr, sourcePort)
 
 	// Get target service directly from the mapping
+	s.mu.RLock()
 	targetService, exists := s.portMappings[sourcePort]
+	tlsConfig := s.tlsConfigs[sourcePort]
+	s.mu.RUnlock()
 	if !exists {
 		log.Printf("Error: No mapping found for port %d", sourcePort)
 		return
 	}
 
+	// Terminate TLS before proxying so the service receives plaintext
+	if tlsConfig != nil {
+		tlsConn, err := terminateTLS(clientConn, tlsConfig)
+		if err != nil {
+			log.Printf("TLS handshake with %s on port %d failed: %v", clientAddr, sourcePort, err)
+			return
+		}
+		defer tlsConn.Close()
+		clientConn = tlsConn
+	}
+
 	// Build the Kubernetes service DNS name
 	// Format: <service-name>.<namespace>.svc.cluster.local
 	serviceDNS := fmt.Sprintf("%s.%s.svc.cluster.local",
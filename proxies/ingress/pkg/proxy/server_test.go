@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/ingress/pkg/config"
+)
+
+// testServer returns a proxy for cfg resolving every service to localhost
+func testServer(t *testing.T, cfg *config.Config) *Server {
+	t.Helper()
+	if cfg.MaxConnections == 0 {
+		cfg.MaxConnections = 10
+	}
+	if cfg.ReadBufferSize == 0 {
+		cfg.ReadBufferSize = 4096
+	}
+	if cfg.ConnectionTimeout == 0 {
+		cfg.ConnectionTimeout = time.Second
+	}
+
+	server, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	server.dnsCache.resolve = func(string) ([]net.IP, error) { return []net.IP{net.ParseIP("127.0.0.1")}, nil }
+	t.Cleanup(func() {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		for _, listener := range server.listeners {
+			listener.Close()
+		}
+	})
+	return server
+}
+
+// echoBackend accepts connections and writes back whatever it reads, as a service would
+func echoBackend(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// freePort returns a port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// echo sends a line over conn and returns what comes back
+func echo(t *testing.T, conn net.Conn, line string) string {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.WriteString(conn, line); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return got
+}
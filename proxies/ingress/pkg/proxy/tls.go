@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/deployra/deployra/proxies/ingress/pkg/config"
+)
+
+// tlsHandshakeTimeout bounds the TLS handshake of a terminated connection
+const tlsHandshakeTimeout = 10 * time.Second
+
+// certReloader serves a certificate from disk and reloads it when the files change,
+// so rotated Kubernetes secrets are picked up without a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// newTLSConfig builds the TLS config of a port mapping, loading its certificate once
+// up front so a bad certificate fails at startup
+func newTLSConfig(cfg *config.TLSConfig) (*tls.Config, error) {
+	reloader := &certReloader{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+	if _, err := reloader.getCertificate(nil); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.getCertificate,
+	}, nil
+}
+
+// getCertificate returns the current certificate, reloading it if a file was modified
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTime, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		if r.cert != nil {
+			// Keep serving the last good certificate while files are being replaced
+			return r.cert, nil
+		}
+		return nil, err
+	}
+
+	if r.cert != nil && !modTime.After(r.modTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, err
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	return r.cert, nil
+}
+
+// latestModTime returns the most recent modification time of the given files
+func latestModTime(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// terminateTLS wraps an accepted connection in a TLS server connection and completes the handshake
+func terminateTLS(conn net.Conn, tlsConfig *tls.Config) (*tls.Conn, error) {
+	tlsConn := tls.Server(conn, tlsConfig)
+
+	conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	return tlsConn, nil
+}
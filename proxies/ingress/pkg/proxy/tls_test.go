@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/ingress/pkg/config"
+)
+
+// selfSignedCert writes a self-signed certificate for localhost and returns its files and pool
+func selfSignedCert(t *testing.T) (*config.TLSConfig, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &config.TLSConfig{CertFile: certFile, KeyFile: keyFile}, pool
+}
+
+// lineBackend answers each line it receives with "got <line>" and reports the line
+func lineBackend(t *testing.T) (int, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return
+		}
+		received <- line
+		fmt.Fprintf(conn, "got %s", line)
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestTLSMappingForwardsPlaintext(t *testing.T) {
+	tlsConfig, pool := selfSignedCert(t)
+	backend, received := lineBackend(t)
+	port := freePort(t)
+	server := testServer(t, &config.Config{PortMappings: []config.PortMapping{{
+		Port: port, ServiceName: "db-service", ServiceNamespace: "project", ServicePort: backend, TLS: tlsConfig,
+	}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := server.startListener(ctx, port); err != nil {
+		t.Fatalf("startListener: %v", err)
+	}
+
+	conn, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port), &tls.Config{RootCAs: pool, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("TLS dial: %v", err)
+	}
+	defer conn.Close()
+
+	if got := echo(t, conn, "ping\n"); got != "got ping\n" {
+		t.Errorf("answer over TLS = %q, want got ping", got)
+	}
+	select {
+	case line := <-received:
+		if line != "ping\n" {
+			t.Errorf("backend received %q, want the plaintext line", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("backend received nothing")
+	}
+}
+
+func TestTLSMappingRejectsPlainClients(t *testing.T) {
+	tlsConfig, _ := selfSignedCert(t)
+	backend, received := lineBackend(t)
+	port := freePort(t)
+	server := testServer(t, &config.Config{PortMappings: []config.PortMapping{{
+		Port: port, ServiceName: "db-service", ServiceNamespace: "project", ServicePort: backend, TLS: tlsConfig,
+	}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := server.startListener(ctx, port); err != nil {
+		t.Fatalf("startListener: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	fmt.Fprint(conn, "ping\n")
+	// The failed handshake closes the connection, after the TLS alert
+	conn.Read(make([]byte, 64))
+
+	select {
+	case line := <-received:
+		t.Errorf("backend received %q from a client that didn't speak TLS", line)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNewServerRejectsBadCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	os.WriteFile(certFile, []byte("not a certificate"), 0o600)
+
+	_, err := NewServer(&config.Config{MaxConnections: 1, ReadBufferSize: 1024, PortMappings: []config.PortMapping{{
+		Port: 5432, TLS: &config.TLSConfig{CertFile: certFile, KeyFile: certFile},
+	}}})
+	if err == nil || !strings.Contains(err.Error(), "invalid TLS config for port 5432") {
+		t.Fatalf("NewServer error = %v, want an invalid TLS config error", err)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -17,6 +18,18 @@ type Config struct {
 	// MySQLLabelKey is the label key to identify MySQL services
 	LabelSelector string `json:"label_selector"`
 
+	// LabelSelectors are watched besides LabelSelector, a service matching any of them
+	// is picked up
+	LabelSelectors []string `json:"label_selectors"`
+
+	// WatchNamespaces limits the service watch to these namespaces, so the proxy only needs
+	// namespaced RBAC. All namespaces are watched when empty.
+	WatchNamespaces []string `json:"watch_namespaces"`
+
+	// DefaultUsername routes connections whose first command names neither a username nor
+	// a client name, e.g. when one service is behind the proxy. Without it they get an error.
+	DefaultUsername string `json:"default_username"`
+
 	// UseProxyProto is a flag to enable proxy protocol
 	UseProxyProto bool `json:"use_proxy_proto"`
 
@@ -29,6 +42,18 @@ type Config struct {
 	// ConnectionTimeout is the duration after which connections are closed
 	ConnectionTimeout time.Duration `json:"connection_timeout"`
 
+	// KeepAlive is the TCP keepalive period of client and backend connections, so half-open
+	// connections of a failed node are detected and closed. Negative disables keepalive.
+	KeepAlive time.Duration `json:"keep_alive"`
+
+	// ReusePort binds the listener with SO_REUSEPORT, so several proxy processes can share
+	// the address and the kernel spreads connections between them. Linux only.
+	ReusePort bool `json:"reuse_port"`
+
+	// ListenBacklog is the length of the accept queue, the system default when 0. The
+	// kernel caps it at net.core.somaxconn. Linux only.
+	ListenBacklog int `json:"listen_backlog"`
+
 	// ReadBufferSize is the size of the read buffer
 	ReadBufferSize int `json:"read_buffer_size"`
 
@@ -50,6 +75,7 @@ func DefaultConfig() *Config {
 		IdleTimeout:       10 * time.Minute,
 		MaxConnections:    100,
 		ConnectionTimeout: 5 * time.Second,
+		KeepAlive:         30 * time.Second,
 		ReadBufferSize:    32768,
 		WriteBufferSize:   32768,
 		// ReadTimeout:       30 * time.Second,
@@ -80,3 +106,18 @@ func Load(configPath string) (*Config, error) {
 
 	return config, nil
 }
+
+// Selectors returns the label selectors to watch, label_selector followed by label_selectors.
+// A service matching any of them is picked up.
+func (c *Config) Selectors() []string {
+	var selectors []string
+	seen := make(map[string]bool)
+	for _, selector := range append([]string{c.LabelSelector}, c.LabelSelectors...) {
+		selector = strings.TrimSpace(selector)
+		if selector != "" && !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
+	}
+	return selectors
+}
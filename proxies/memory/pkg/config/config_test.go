@@ -0,0 +1,32 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+// The README documents durations in nanoseconds, the format the loader decodes
+func TestLoadDecodesDurationsInNanoseconds(t *testing.T) {
+	config, err := Load(writeConfig(t, `{"connection_timeout": 1000000000, "keep_alive": 30000000000}`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if config.ConnectionTimeout != time.Second || config.KeepAlive != 30*time.Second {
+		t.Errorf("connection_timeout = %s, keep_alive = %s, want 1s and 30s", config.ConnectionTimeout, config.KeepAlive)
+	}
+
+	if _, err := Load(writeConfig(t, `{"keep_alive": "30s"}`)); err == nil {
+		t.Error("Load accepted a duration string")
+	}
+}
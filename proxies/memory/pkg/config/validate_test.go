@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateDefaultConfig(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("default config is invalid: %v", err)
+	}
+}
+
+func TestValidateInvalidConfigs(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{name: "no listen address", modify: func(c *Config) { c.ListenAddr = "" }, want: "listen_addr is required"},
+		{name: "address without a port", modify: func(c *Config) { c.ListenAddr = "PORT" }, want: `invalid listen_addr "PORT"`},
+		{name: "no selector", modify: func(c *Config) { c.LabelSelector = " " }, want: "label_selector or label_selectors is required"},
+		{name: "bad selector", modify: func(c *Config) { c.LabelSelectors = []string{"type in (memory"} }, want: `invalid label selector "type in (memory"`},
+		{name: "bad namespace", modify: func(c *Config) { c.WatchNamespaces = []string{"Team_A"} }, want: `invalid namespace "Team_A" in watch_namespaces`},
+		{name: "negative connections", modify: func(c *Config) { c.MaxConnections = -10 }, want: "max_connections must be positive, got -10"},
+		{name: "negative idle timeout", modify: func(c *Config) { c.IdleTimeout = -time.Minute }, want: "idle_timeout must not be negative, got -1m0s"},
+		{name: "negative connection timeout", modify: func(c *Config) { c.ConnectionTimeout = -time.Second }, want: "connection_timeout must not be negative"},
+		{name: "negative backlog", modify: func(c *Config) { c.ListenBacklog = -1 }, want: "listen_backlog must not be negative, got -1"},
+		{name: "no write buffer", modify: func(c *Config) { c.WriteBufferSize = 0 }, want: "write_buffer_size must be positive, got 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	config := DefaultConfig()
+	config.ListenAddr = ""
+	config.MaxConnections = 0
+	config.ReadBufferSize = -1
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate() accepted an invalid config")
+	}
+	for _, want := range []string{"listen_addr is required", "max_connections must be positive", "read_buffer_size must be positive, got -1"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, missing %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package kubernetes
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Delays between list/watch attempts, grown after every failure and reset by a successful list
+const (
+	watchRetryBase = time.Second
+	watchRetryMax  = 2 * time.Minute
+)
+
+// backoff computes exponentially growing retry delays with jitter, so replicas that lose
+// the API server at the same moment don't retry in lockstep
+type backoff struct {
+	base     time.Duration
+	max      time.Duration
+	failures int
+}
+
+// next counts a failure and returns the delay before the next attempt: a random duration
+// between half and all of base*2^failures, capped at max
+func (b *backoff) next() time.Duration {
+	ceiling := b.max
+	if b.failures < 32 {
+		if d := b.base << b.failures; d > 0 && d < b.max {
+			ceiling = d
+		}
+	}
+	b.failures++
+
+	half := ceiling / 2
+	return half + time.Duration(rand.Int63n(int64(ceiling-half)+1))
+}
+
+// reset starts the delays over from base
+func (b *backoff) reset() {
+	b.failures = 0
+}
+
+// sleepContext waits for d, returning false early if ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// parseCommand reads the arguments of the first command a client sent, either a RESP array
+// of bulk strings or an inline command. Bulk strings are read by their length, so arguments
+// may hold anything, CRLF and RESP markers included. Returns nil when the command is
+// malformed or didn't fit in data.
+func parseCommand(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	// Inline commands, e.g. typed in telnet, are space separated on one line
+	if data[0] != '*' {
+		line, _, found := bytes.Cut(data, []byte("\r\n"))
+		if !found {
+			line, _, _ = bytes.Cut(data, []byte("\n"))
+		}
+		return strings.Fields(string(line))
+	}
+
+	count, rest, ok := readLength(data[1:])
+	if !ok || count <= 0 {
+		return nil
+	}
+
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		if len(rest) == 0 || rest[0] != '$' {
+			return nil
+		}
+		size, after, ok := readLength(rest[1:])
+		if !ok || size < 0 || len(after) < size+2 || string(after[size:size+2]) != "\r\n" {
+			return nil
+		}
+		args = append(args, string(after[:size]))
+		rest = after[size+2:]
+	}
+	return args
+}
+
+// readLength reads the number ending a RESP header line and returns the data after it
+func readLength(data []byte) (int, []byte, bool) {
+	line, rest, found := bytes.Cut(data, []byte("\r\n"))
+	if !found {
+		return 0, nil, false
+	}
+	n, err := strconv.Atoi(string(line))
+	if err != nil {
+		return 0, nil, false
+	}
+	return n, rest, true
+}
+
+// commandUsername returns the username of an AUTH or HELLO command, "default" when it only
+// carries a password, or "" for other commands.
+//
+//	AUTH [username] password
+//	HELLO [protover [AUTH username password] [SETNAME clientname]]
+func commandUsername(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		if len(args) == 2 {
+			return "default"
+		}
+		if len(args) >= 3 {
+			return args[1]
+		}
+	case "HELLO":
+		username, _ := helloOptions(args)
+		return username
+	}
+	return ""
+}
+
+// commandClientName returns the client name set by a CLIENT SETNAME or HELLO command, or ""
+//
+//	CLIENT SETNAME clientname
+func commandClientName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "CLIENT":
+		if len(args) == 3 && strings.ToUpper(args[1]) == "SETNAME" {
+			return args[2]
+		}
+	case "HELLO":
+		_, clientName := helloOptions(args)
+		return clientName
+	}
+	return ""
+}
+
+// helloOptions returns the AUTH username and SETNAME client name of a HELLO command. The
+// options follow the protocol version and are walked in order, so a client name or a
+// password spelled AUTH is never taken for the AUTH option.
+func helloOptions(args []string) (username, clientName string) {
+	i := 1
+	if len(args) > 1 {
+		if _, err := strconv.Atoi(args[1]); err == nil {
+			i = 2
+		}
+	}
+	for i < len(args) {
+		switch strings.ToUpper(args[i]) {
+		case "AUTH":
+			if i+2 >= len(args) {
+				return "", ""
+			}
+			username = args[i+1]
+			i += 3
+		case "SETNAME":
+			if i+1 >= len(args) {
+				return "", ""
+			}
+			clientName = args[i+1]
+			i += 2
+		default:
+			return "", ""
+		}
+	}
+	return username, clientName
+}
@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// respCommand encodes args as a RESP array of bulk strings, the way clients send commands
+func respCommand(args ...string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return []byte(b.String())
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []string
+	}{
+		{name: "array", data: respCommand("AUTH", "app", "s3cret"), want: []string{"AUTH", "app", "s3cret"}},
+		// Bulk strings are read by length, framing inside an argument is kept
+		{name: "password with CRLF and markers", data: respCommand("AUTH", "app", "*2\r\n$4\r\nAUTH"), want: []string{"AUTH", "app", "*2\r\n$4\r\nAUTH"}},
+		{name: "pipelined commands", data: append(respCommand("HELLO", "3"), respCommand("PING")...), want: []string{"HELLO", "3"}},
+		{name: "inline", data: []byte("AUTH app s3cret\r\n"), want: []string{"AUTH", "app", "s3cret"}},
+		{name: "inline without CR", data: []byte("PING\n"), want: []string{"PING"}},
+		{name: "empty", data: nil, want: nil},
+		{name: "truncated", data: respCommand("AUTH", "app", "s3cret")[:20], want: nil},
+		{name: "bad length", data: []byte("*1\r\n$x\r\nPING\r\n"), want: nil},
+		{name: "not a bulk string", data: []byte("*1\r\n+PING\r\n"), want: nil},
+		{name: "length past the data", data: []byte("*1\r\n$10\r\nPING\r\n"), want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCommand(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCommand = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandUsername(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"AUTH", "s3cret"}, want: "default"},
+		{args: []string{"AUTH", "app", "s3cret"}, want: "app"},
+		{args: []string{"auth", "app", "s3cret"}, want: "app"},
+		{args: []string{"HELLO", "3", "AUTH", "app", "s3cret"}, want: "app"},
+		{args: []string{"HELLO", "3", "SETNAME", "worker", "AUTH", "app", "s3cret"}, want: "app"},
+		{args: []string{"HELLO", "3", "AUTH", "app", "s3cret", "SETNAME", "worker"}, want: "app"},
+		{args: []string{"hello", "3", "setname", "worker", "auth", "app", "s3cret"}, want: "app"},
+		// Options may follow HELLO without a protocol version
+		{args: []string{"HELLO", "AUTH", "app", "s3cret"}, want: "app"},
+		// A client name spelled like the option is not taken for it
+		{args: []string{"HELLO", "3", "SETNAME", "AUTH", "AUTH", "app", "s3cret"}, want: "app"},
+		// Nor is a password spelled like it
+		{args: []string{"HELLO", "3", "AUTH", "app", "SETNAME", "SETNAME", "worker"}, want: "app"},
+		{args: []string{"HELLO", "3", "SETNAME", "worker"}, want: ""},
+		{args: []string{"HELLO", "3", "AUTH", "app"}, want: ""},
+		{args: []string{"HELLO", "3", "SETNAME"}, want: ""},
+		{args: []string{"HELLO", "3", "UNKNOWN", "AUTH", "app", "s3cret"}, want: ""},
+		{args: []string{"PING"}, want: ""},
+		{args: nil, want: ""},
+	}
+	for _, tt := range tests {
+		if got := commandUsername(tt.args); got != tt.want {
+			t.Errorf("commandUsername(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCommandUsernameFromRESP(t *testing.T) {
+	for _, data := range [][]byte{
+		respCommand("HELLO", "3", "SETNAME", "x", "AUTH", "user", "pass"),
+		respCommand("HELLO", "3", "AUTH", "user", "pass", "SETNAME", "x"),
+	} {
+		if got := commandUsername(parseCommand(data)); got != "user" {
+			t.Errorf("username of %q = %q, want user", data, got)
+		}
+	}
+}
+
+func TestCommandClientName(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"CLIENT", "SETNAME", "worker"}, want: "worker"},
+		{args: []string{"client", "setname", "worker"}, want: "worker"},
+		{args: []string{"HELLO", "3", "SETNAME", "worker"}, want: "worker"},
+		{args: []string{"HELLO", "3", "AUTH", "app", "s3cret", "SETNAME", "worker"}, want: "worker"},
+		{args: []string{"HELLO", "SETNAME", "worker"}, want: "worker"},
+		{args: []string{"CLIENT", "SETNAME"}, want: ""},
+		{args: []string{"CLIENT", "GETNAME"}, want: ""},
+		{args: []string{"HELLO", "3"}, want: ""},
+		{args: []string{"PING"}, want: ""},
+		{args: nil, want: ""},
+	}
+	for _, tt := range tests {
+		if got := commandClientName(tt.args); got != tt.want {
+			t.Errorf("commandClientName(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
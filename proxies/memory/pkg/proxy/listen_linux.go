@@ -0,0 +1,44 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reusePort sets SO_REUSEPORT on a socket before it is bound, so other processes can bind
+// the same address and the kernel spreads connections between them
+func reusePort(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// setBacklog changes the accept queue length of a listener. Go listens with the system
+// default, Linux applies the new length when listen is called again on the socket.
+func setBacklog(ln net.Listener, backlog int) error {
+	tcpListener, ok := ln.(*net.TCPListener)
+	if !ok {
+		return fmt.Errorf("not a TCP listener")
+	}
+	rawConn, err := tcpListener.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var listenErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		listenErr = unix.Listen(int(fd), backlog)
+	}); err != nil {
+		return err
+	}
+	return listenErr
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package proxy
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// reusePort fails, SO_REUSEPORT is only set on Linux
+func reusePort(network, address string, c syscall.RawConn) error {
+	return errors.New("reuse_port is only supported on Linux")
+}
+
+// setBacklog fails, the backlog is only changed on Linux
+func setBacklog(ln net.Listener, backlog int) error {
+	return errors.New("listen_backlog is only supported on Linux")
+}
@@ -6,7 +6,6 @@ import (
 	"io"
 	"log"
 	"net"
-	"strings"
 	"sync"
 	"time"
 
@@ -35,10 +34,11 @@ type Server struct {
 // NewServer creates a new proxy server
 func NewServer(cfg *config.Config) (*Server, error) {
 	// Create Kubernetes client
-	kubeClient, err := kubernetes.NewClient(cfg.KubeConfigPath, cfg.LabelSelector)
+	kubeClient, err := kubernetes.NewClient(cfg.KubeConfigPath, cfg.Selectors())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
 	}
+	kubeClient.SetWatchNamespaces(cfg.WatchNamespaces)
 
 	// Create server instance
 	server := &Server{
@@ -60,6 +60,8 @@ func NewServer(cfg *config.Config) (*Server, error) {
 
 // Start starts the proxy server
 func (s *Server) Start(ctx context.Context) error {
+	// Start DNS cache cleanup and refresh in background
+	go s.dnsCache.Cleanup(ctx)
 
 	// Start Kubernetes watcher
 	if err := s.kubeClient.StartWatching(s.handleServicesChanged); err != nil {
@@ -69,7 +71,7 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 
 	var err error
-	s.listener, err = net.Listen("tcp", s.config.ListenAddr)
+	s.listener, err = s.listen(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %v", s.config.ListenAddr, err)
 	}
@@ -201,15 +203,16 @@ func (s *Server) handleServicesChanged(action kubernetes.ServiceInfoAction, serv
 	s.routingLock.Unlock()
 }
 
+// errNoIdentifier answers the first command of a client the proxy can't route
+const errNoIdentifier = "-NOAUTH the proxy routes on the first command, send AUTH <username> <password>, " +
+	"HELLO 3 AUTH <username> <password> or CLIENT SETNAME <username> first\r\n"
+
 // handleConnection handles a memory service connection
 func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 	// Create a connection-specific context that will be canceled when this function returns
 	connCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// Start DNS cache cleanup in background if not already running
-	go s.dnsCache.Cleanup(connCtx)
-
 	defer clientConn.Close()
 
 	connectionID := uuid.New().String()
@@ -227,44 +230,21 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 		return
 	}
 
-	// Parse the command
-	command := string(buffer[:n])
-	username := ""
-
-	// Check if it's an AUTH or HELLO command
-	// AUTH command format: *2\r\n$4\r\nAUTH\r\n$<len>\r\n<password>\r\n
-	// or with username and password: *3\r\n$4\r\nAUTH\r\n$<len>\r\n<username>\r\n$<len>\r\n<password>\r\n
-	// HELLO command format (RESP3): HELLO [protover [AUTH username password] [SETNAME clientname]]
-	parts := splitCommand(command)
-
-	if len(parts) >= 1 && strings.ToUpper(parts[0]) == "AUTH" {
-		if len(parts) == 2 {
-			// Simple AUTH with password only
-			username = "default" // Use a default username for simple AUTH
-		} else if len(parts) >= 3 {
-			// AUTH with username and password
-			username = parts[1]
-		}
-	} else if len(parts) >= 1 && strings.ToUpper(parts[0]) == "HELLO" {
-		// HELLO command with optional AUTH: HELLO 3 AUTH username password
-		for i, part := range parts {
-			if strings.ToUpper(part) == "AUTH" {
-				if i+1 < len(parts) && i+2 < len(parts) {
-					// AUTH username password format
-					username = parts[i+1]
-				} else if i+1 < len(parts) {
-					// AUTH password only format
-					username = "default"
-				}
-				break
-			}
-		}
+	// Route on the username of an AUTH or HELLO command, else on the client name of CLIENT
+	// SETNAME or HELLO, else on the configured default. Client IPs are shared behind NAT
+	// and can't identify a service.
+	args := parseCommand(buffer[:n])
+	username := commandUsername(args)
+	if username == "" {
+		username = commandClientName(args)
 	}
-
 	if username == "" {
-		// If no AUTH command or couldn't extract username, use IP address as fallback
-		remoteAddr := clientConn.RemoteAddr().String()
-		username = remoteAddr
+		username = s.config.DefaultUsername
+	}
+	if username == "" {
+		log.Printf("[%s] No username or client name from %s, closing connection", connectionID, clientIP)
+		clientConn.Write([]byte(errNoIdentifier))
+		return
 	}
 
 	var routingService *kubernetes.ServiceInfo
@@ -306,7 +286,7 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 	serverAddr := net.JoinHostPort(ips[0].String(), fmt.Sprintf("%d", routingService.Port))
 
 	// Create connection to the memory server with timeout
-	dialer := &net.Dialer{Timeout: s.config.ConnectionTimeout}
+	dialer := s.dialer()
 	serverConn, err := dialer.DialContext(connCtx, "tcp", serverAddr)
 	if err != nil {
 		log.Printf("Failed to connect to memory server %s: %v", serverAddr, err)
@@ -323,6 +303,7 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 
 	// Proxy data between client and server using buffer pool
 	errCh := make(chan error, 2)
+	serverDone := make(chan struct{})
 
 	// Client -> Server
 	go func() {
@@ -337,6 +318,8 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 
 	// Server -> Client
 	go func() {
+		defer close(serverDone)
+
 		// Get buffer from pool
 		buf := s.bufferPool.Get()
 		defer s.bufferPool.Put(buf) // Return buffer to pool when done
@@ -354,35 +337,20 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 		}
 	case <-connCtx.Done():
 		log.Printf("Connection closed due to server shutdown")
+		closeForShutdown(clientConn, serverConn, serverDone)
 	}
 }
 
-// splitCommand splits a RESP protocol command into its parts
-func splitCommand(command string) []string {
-	parts := []string{}
-	lines := splitLines(command)
-
-	for i := 0; i < len(lines); i++ {
-		// Skip the *n line and the $n lines
-		if len(lines[i]) > 0 && (lines[i][0] == '*' || lines[i][0] == '$') {
-			continue
-		}
-
-		// Add the actual command parts
-		if len(lines[i]) > 0 {
-			parts = append(parts, lines[i])
-		}
-	}
-
-	return parts
-}
-
-// splitLines splits a string by CRLF
-func splitLines(s string) []string {
-	return strings.Split(s, "\r\n")
-}
-
 // Wait waits for all connections to finish
 func (s *Server) Wait() {
 	s.connections.Wait()
 }
+
+// dialer returns the dialer for backend connections, with the connection timeout and
+// keepalive period of the config
+func (s *Server) dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   s.config.ConnectionTimeout,
+		KeepAlive: s.config.KeepAlive,
+	}
+}
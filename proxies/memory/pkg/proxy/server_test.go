@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/memory/pkg/config"
+	"github.com/deployra/deployra/proxies/memory/pkg/kubernetes"
+)
+
+// memoryBackend accepts one connection and answers its first command with +OK, the
+// command it received is sent on the returned channel
+func memoryBackend(t *testing.T) (*kubernetes.ServiceInfo, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buffer := make([]byte, 1024)
+		n, _ := conn.Read(buffer)
+		received <- string(buffer[:n])
+		conn.Write([]byte("+OK\r\n"))
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return &kubernetes.ServiceInfo{Name: "cache-service", Namespace: "project", Port: int32(portNumber)}, received
+}
+
+// memoryServer returns a proxy routing username to service, resolving every service to localhost
+func memoryServer(cfg *config.Config, username string, service *kubernetes.ServiceInfo) *Server {
+	dnsCache := NewDNSCache(time.Minute)
+	dnsCache.resolve = func(string) ([]net.IP, error) { return []net.IP{net.ParseIP("127.0.0.1")}, nil }
+
+	return &Server{
+		config:       cfg,
+		services:     map[string]*kubernetes.ServiceInfo{"project/cache": service},
+		routingTable: map[string]string{username: "project/cache"},
+		bufferPool:   NewBufferPool(1024),
+		dnsCache:     dnsCache,
+	}
+}
+
+// sendFirstCommand connects a client to server, sends data and returns the first reply line
+func sendFirstCommand(t *testing.T, server *Server, data []byte) string {
+	t.Helper()
+	client, proxy := net.Pipe()
+	go server.handleConnection(context.Background(), proxy)
+	t.Cleanup(func() { client.Close() })
+
+	client.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := client.Write(data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	reply, err := bufio.NewReader(client).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read reply: %v", err)
+	}
+	return reply
+}
+
+func TestConnectionRoutedByClientName(t *testing.T) {
+	for _, data := range [][]byte{
+		respCommand("CLIENT", "SETNAME", "cache-user"),
+		respCommand("HELLO", "3", "SETNAME", "cache-user"),
+	} {
+		service, received := memoryBackend(t)
+		server := memoryServer(config.DefaultConfig(), "cache-user", service)
+
+		if reply := sendFirstCommand(t, server, data); reply != "+OK\r\n" {
+			t.Errorf("reply to %q = %q, want the service's +OK", data, reply)
+		}
+		// The command naming the client reaches the service as it was sent
+		if got := <-received; got != string(data) {
+			t.Errorf("service received %q, want %q", got, data)
+		}
+	}
+}
+
+func TestConnectionRoutedByDefaultUsername(t *testing.T) {
+	service, _ := memoryBackend(t)
+	cfg := config.DefaultConfig()
+	cfg.DefaultUsername = "cache-user"
+	server := memoryServer(cfg, "cache-user", service)
+
+	if reply := sendFirstCommand(t, server, respCommand("PING")); reply != "+OK\r\n" {
+		t.Errorf("reply = %q, want the default service's +OK", reply)
+	}
+}
+
+func TestConnectionWithoutIdentifierGetsError(t *testing.T) {
+	service, received := memoryBackend(t)
+	server := memoryServer(config.DefaultConfig(), "cache-user", service)
+
+	reply := sendFirstCommand(t, server, respCommand("PING"))
+	if !strings.HasPrefix(reply, "-NOAUTH ") || !strings.Contains(reply, "AUTH <username> <password>") {
+		t.Errorf("reply = %q, want a NOAUTH error telling the client to AUTH", reply)
+	}
+	select {
+	case command := <-received:
+		t.Errorf("a connection without an identifier reached a service with %q", command)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDialerUsesConfigTimeouts(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		keepAlive time.Duration
+	}{
+		{name: "configured", timeout: 3 * time.Second, keepAlive: 45 * time.Second},
+		// Negative disables keepalive, it must reach the dialer as it is
+		{name: "keepalive disabled", timeout: 5 * time.Second, keepAlive: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &Server{config: &config.Config{ConnectionTimeout: tt.timeout, KeepAlive: tt.keepAlive}}
+			if d := server.dialer(); d.Timeout != tt.timeout || d.KeepAlive != tt.keepAlive {
+				t.Errorf("dialer timeout %v, keepalive %v, want %v and %v", d.Timeout, d.KeepAlive, tt.timeout, tt.keepAlive)
+			}
+		})
+	}
+
+	// The default keepalive is what backend connections get without keep_alive in the config
+	server := &Server{config: config.DefaultConfig()}
+	if d := server.dialer(); d.KeepAlive != 30*time.Second {
+		t.Errorf("default keepalive = %v, want 30s", d.KeepAlive)
+	}
+}
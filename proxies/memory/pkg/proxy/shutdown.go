@@ -0,0 +1,23 @@
+package proxy
+
+import (
+	"net"
+	"time"
+)
+
+// How long a client gets to take the shutdown notice before its connection is closed
+const shutdownNoticeTimeout = 5 * time.Second
+
+// errShutdown tells a client the proxy is shutting down, so clients reconnect instead of
+// seeing a reset
+const errShutdown = "-ERR server is shutting down, reconnect to continue\r\n"
+
+// closeForShutdown stops proxying a session and sends the client the shutdown error. The
+// server -> client flow, which closes serverDone when it returns, is stopped first so the
+// error doesn't land in the middle of a forwarded reply.
+func closeForShutdown(clientConn, serverConn net.Conn, serverDone <-chan struct{}) {
+	clientConn.SetWriteDeadline(time.Now().Add(shutdownNoticeTimeout))
+	serverConn.Close()
+	<-serverDone
+	clientConn.Write([]byte(errShutdown))
+}
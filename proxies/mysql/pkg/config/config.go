@@ -3,9 +3,14 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"strings"
 	"time"
 )
 
+// MaxStartupPacketLimit bounds MaxStartupPacketBytes, the proxy buffers the whole startup
+// packet before it knows where to route the connection
+const MaxStartupPacketLimit = 1 << 20
+
 // Config holds the configuration for the MySQL proxy
 type Config struct {
 	// ListenAddr is the address to listen for MySQL connections
@@ -17,6 +22,14 @@ type Config struct {
 	// MySQLLabelKey is the label key to identify MySQL services
 	LabelSelector string `json:"label_selector"`
 
+	// LabelSelectors are watched besides LabelSelector, a service matching any of them
+	// is picked up
+	LabelSelectors []string `json:"label_selectors"`
+
+	// WatchNamespaces limits the service watch to these namespaces, so the proxy only needs
+	// namespaced RBAC. All namespaces are watched when empty.
+	WatchNamespaces []string `json:"watch_namespaces"`
+
 	// UseProxyProto is a flag to enable proxy protocol
 	UseProxyProto bool `json:"use_proxy_proto"`
 
@@ -29,6 +42,25 @@ type Config struct {
 	// ConnectionTimeout is the duration after which connections are closed
 	ConnectionTimeout time.Duration `json:"connection_timeout"`
 
+	// KeepAlive is the TCP keepalive period of client and backend connections, so half-open
+	// connections of a failed node are detected and closed. Negative disables keepalive.
+	KeepAlive time.Duration `json:"keep_alive"`
+
+	// ReusePort binds the listener with SO_REUSEPORT, so several proxy processes can share
+	// the address and the kernel spreads connections between them. Linux only.
+	ReusePort bool `json:"reuse_port"`
+
+	// ListenBacklog is the length of the accept queue, the system default when 0. The
+	// kernel caps it at net.core.somaxconn. Linux only.
+	ListenBacklog int `json:"listen_backlog"`
+
+	// HandshakeTimeout is the read deadline for the startup/handshake phase of a connection
+	HandshakeTimeout time.Duration `json:"handshake_timeout"`
+
+	// MaxStartupPacketBytes is the largest startup packet a client may send, header excluded.
+	// Raise it for clients with many connection parameters, up to MaxStartupPacketLimit.
+	MaxStartupPacketBytes int `json:"max_startup_packet_bytes"`
+
 	// ReadBufferSize is the size of the read buffer
 	ReadBufferSize int `json:"read_buffer_size"`
 
@@ -46,12 +78,15 @@ type Config struct {
 func DefaultConfig() *Config {
 	return &Config{
 		// KubeConfigPath:    "~/.kube/config",
-		KubeConfigPath:    "",
-		IdleTimeout:       10 * time.Minute,
-		MaxConnections:    100,
-		ConnectionTimeout: 5 * time.Second,
-		ReadBufferSize:    32768,
-		WriteBufferSize:   32768,
+		KubeConfigPath:        "",
+		IdleTimeout:           10 * time.Minute,
+		MaxConnections:        100,
+		ConnectionTimeout:     5 * time.Second,
+		KeepAlive:             30 * time.Second,
+		HandshakeTimeout:      5 * time.Second,
+		MaxStartupPacketBytes: 8192,
+		ReadBufferSize:        32768,
+		WriteBufferSize:       32768,
 		// ReadTimeout:       30 * time.Second,
 		// WriteTimeout:      30 * time.Second,
 		ListenAddr:    ":3306",
@@ -78,5 +113,29 @@ func Load(configPath string) (*Config, error) {
 		}
 	}
 
+	// A zero deadline would fail every handshake immediately
+	if config.HandshakeTimeout <= 0 {
+		config.HandshakeTimeout = DefaultConfig().HandshakeTimeout
+	}
+
+	if config.MaxStartupPacketBytes <= 0 {
+		config.MaxStartupPacketBytes = DefaultConfig().MaxStartupPacketBytes
+	}
+
 	return config, nil
 }
+
+// Selectors returns the label selectors to watch, label_selector followed by label_selectors.
+// A service matching any of them is picked up.
+func (c *Config) Selectors() []string {
+	var selectors []string
+	seen := make(map[string]bool)
+	for _, selector := range append([]string{c.LabelSelector}, c.LabelSelectors...) {
+		selector = strings.TrimSpace(selector)
+		if selector != "" && !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
+	}
+	return selectors
+}
@@ -0,0 +1,131 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// mysqlService returns a MySQL service in a namespace
+func mysqlService(namespace, name string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"project": namespace, "service": name, "type": "mysql"}},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 3306}}},
+	}
+}
+
+// waitForWatch waits until a watch on namespace was opened on the fake clientset
+func waitForWatch(t *testing.T, clientset *fake.Clientset, namespace string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, action := range clientset.Actions() {
+			if action.GetVerb() == "watch" && action.GetNamespace() == namespace {
+				// The fake registers the watcher right after recording the action
+				time.Sleep(50 * time.Millisecond)
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no watch was opened on namespace %s", namespace)
+}
+
+func TestWatchOnlyConfiguredNamespaces(t *testing.T) {
+	clientset := fake.NewSimpleClientset(mysqlService("team-a", "listed"), mysqlService("team-b", "listed"))
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{clientset: clientset, labelSelectors: []string{"type=mysql"}, watchContext: ctx, watchCancel: cancel}
+	defer c.StopWatching()
+	c.SetWatchNamespaces([]string{"team-a"})
+
+	added := make(chan string, 10)
+	c.StartWatching(func(action ServiceInfoAction, key string, info *ServiceInfo) {
+		if action == Add {
+			added <- key
+		}
+	})
+	waitForWatch(t, clientset, "team-a")
+
+	clientset.CoreV1().Services("team-b").Create(context.Background(), mysqlService("team-b", "watched"), metav1.CreateOptions{})
+	clientset.CoreV1().Services("team-a").Create(context.Background(), mysqlService("team-a", "watched"), metav1.CreateOptions{})
+
+	var keys []string
+	for len(keys) < 2 {
+		select {
+		case key := <-added:
+			keys = append(keys, key)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("reported %v, want team-a/listed and team-a/watched", keys)
+		}
+	}
+	if keys[0] != "team-a/listed" || keys[1] != "team-a/watched" {
+		t.Errorf("reported %v, want team-a/listed then team-a/watched", keys)
+	}
+
+	// Nothing is read outside the configured namespace
+	for _, action := range clientset.Actions() {
+		if verb := action.GetVerb(); (verb == "list" || verb == "watch") && action.GetNamespace() != "team-a" {
+			t.Errorf("%s of services in namespace %q, want only team-a", verb, action.GetNamespace())
+		}
+	}
+}
+
+// labeled returns service with extra labels
+func labeled(service *corev1.Service, labels map[string]string) *corev1.Service {
+	for key, value := range labels {
+		service.Labels[key] = value
+	}
+	return service
+}
+
+func TestWatchServicesMatchingAnySelector(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		labeled(mysqlService("proj", "cache"), map[string]string{"tier": "cache"}),
+		labeled(mysqlService("proj", "managed"), map[string]string{"managed": "true"}),
+		labeled(mysqlService("proj", "both"), map[string]string{"tier": "cache", "managed": "true"}),
+		mysqlService("proj", "other"),
+	)
+	selectors := []string{"type=mysql,tier=cache", "type=mysql,managed in (true)"}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{clientset: clientset, labelSelectors: selectors, watchContext: ctx, watchCancel: cancel}
+	defer c.StopWatching()
+
+	added := make(chan string, 10)
+	c.StartWatching(func(action ServiceInfoAction, key string, info *ServiceInfo) {
+		if action == Add {
+			added <- key
+		}
+	})
+
+	// Each selector lists the services matching it before it watches
+	deadline := time.Now().Add(2 * time.Second)
+	for watches := 0; watches < len(selectors); {
+		if time.Now().After(deadline) {
+			t.Fatalf("opened %d watches, want one per selector", watches)
+		}
+		time.Sleep(10 * time.Millisecond)
+		watches = 0
+		for _, action := range clientset.Actions() {
+			if action.GetVerb() == "watch" {
+				watches++
+			}
+		}
+	}
+
+	reported := make(map[string]bool)
+	for len(added) > 0 {
+		reported[<-added] = true
+	}
+	for _, key := range []string{"proj/cache", "proj/managed", "proj/both"} {
+		if !reported[key] {
+			t.Errorf("%s matches a selector but was not reported, reported %v", key, reported)
+		}
+	}
+	if reported["proj/other"] {
+		t.Error("proj/other matches no selector but was reported")
+	}
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingResolver resolves every hostname to 10.0.0.n on the nth resolution
+func countingResolver(resolutions *atomic.Int32) func(string) ([]net.IP, error) {
+	return func(string) ([]net.IP, error) {
+		n := resolutions.Add(1)
+		return []net.IP{net.ParseIP(fmt.Sprintf("10.0.0.%d", n))}, nil
+	}
+}
+
+// cachedIP returns the cached address of hostname without counting a lookup
+func cachedIP(c *DNSCache, hostname string) string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if entry, ok := c.cache[hostname]; ok {
+		return entry.ips[0].String()
+	}
+	return ""
+}
+
+func TestDNSEntryRefreshIsJittered(t *testing.T) {
+	c := NewDNSCache(time.Minute)
+	start := time.Now()
+	entry := c.newEntry(nil)
+
+	// Refreshes fall in the first half of the last fifth of the TTL
+	if entry.refreshAt.Before(start.Add(48*time.Second)) || entry.refreshAt.After(time.Now().Add(54*time.Second)) {
+		t.Errorf("refresh %v after caching, want between 48s and 54s", entry.refreshAt.Sub(start))
+	}
+}
+
+func TestHotDNSEntryIsRefreshedBeforeExpiry(t *testing.T) {
+	var resolutions atomic.Int32
+	c := NewDNSCache(500 * time.Millisecond)
+	c.resolve = countingResolver(&resolutions)
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Lookup("web-service.project.svc.cluster.local"); err != nil {
+			t.Fatalf("Lookup: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Cleanup(ctx)
+
+	// The entry is refreshed between 400ms and 450ms, before it expires at 500ms
+	deadline := time.Now().Add(490 * time.Millisecond)
+	for cachedIP(c, "web-service.project.svc.cluster.local") != "10.0.0.2" {
+		if time.Now().After(deadline) {
+			t.Fatalf("hot entry was not refreshed before expiry, %d resolutions", resolutions.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Lookups are answered from the refreshed entry, without resolving again
+	ips, err := c.Lookup("web-service.project.svc.cluster.local")
+	if err != nil || ips[0].String() != "10.0.0.2" {
+		t.Errorf("Lookup = %v, %v, want the refreshed 10.0.0.2", ips, err)
+	}
+	if n := resolutions.Load(); n != 2 {
+		t.Errorf("resolved %d times, want the first resolution and one refresh", n)
+	}
+}
+
+func TestColdDNSEntryIsNotRefreshed(t *testing.T) {
+	var resolutions atomic.Int32
+	c := NewDNSCache(time.Minute)
+	c.resolve = countingResolver(&resolutions)
+
+	c.Lookup("hot.project.svc.cluster.local")
+	c.Lookup("hot.project.svc.cluster.local")
+	c.Lookup("hot.project.svc.cluster.local")
+	c.Lookup("cold.project.svc.cluster.local")
+
+	// Both entries are due
+	c.mutex.Lock()
+	for _, entry := range c.cache {
+		entry.refreshAt = time.Now().Add(-time.Second)
+	}
+	c.mutex.Unlock()
+
+	c.refreshHotEntries()
+	deadline := time.Now().Add(time.Second)
+	for resolutions.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Give a refresh of the cold entry the chance to happen
+	time.Sleep(20 * time.Millisecond)
+
+	if n := resolutions.Load(); n != 3 {
+		t.Fatalf("resolved %d times, want 2 lookups and a refresh of the hot entry", n)
+	}
+	if ip := cachedIP(c, "cold.project.svc.cluster.local"); ip != "10.0.0.2" {
+		t.Errorf("cold entry is %s, want it left as resolved by its lookup", ip)
+	}
+	if ip := cachedIP(c, "hot.project.svc.cluster.local"); ip != "10.0.0.3" {
+		t.Errorf("hot entry is %s, want the refreshed 10.0.0.3", ip)
+	}
+}
+
+func TestDNSRefreshFailureKeepsAddresses(t *testing.T) {
+	c := NewDNSCache(time.Minute)
+	c.resolve = func(string) ([]net.IP, error) { return []net.IP{net.ParseIP("10.0.0.1")}, nil }
+	for i := 0; i < 3; i++ {
+		c.Lookup("web-service.project.svc.cluster.local")
+	}
+
+	var refreshes atomic.Int32
+	c.resolve = func(string) ([]net.IP, error) {
+		refreshes.Add(1)
+		return nil, &net.DNSError{Err: "server misbehaving", IsTemporary: true}
+	}
+	c.mutex.Lock()
+	entry := c.cache["web-service.project.svc.cluster.local"]
+	entry.refreshAt = time.Now().Add(-time.Second)
+	c.mutex.Unlock()
+
+	c.refreshHotEntries()
+	deadline := time.Now().Add(time.Second)
+	for (refreshes.Load() == 0 || entry.refreshing.Load()) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if ip := cachedIP(c, "web-service.project.svc.cluster.local"); ip != "10.0.0.1" {
+		t.Errorf("entry is %q after a failed refresh, want the old address", ip)
+	}
+}
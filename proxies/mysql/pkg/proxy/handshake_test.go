@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/mysql/pkg/config"
+)
+
+// authPacket returns a HandshakeResponse41 packet for username
+func authPacket(username string) []byte {
+	payload := append(bytes.Repeat([]byte{0}, 32), username...)
+	payload = append(payload, 0)
+	return append([]byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), 1}, payload...)
+}
+
+// slowClient reads the proxy's initial handshake, then sends auth in two halves delay apart
+func slowClient(conn net.Conn, auth []byte, delay time.Duration) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	if _, err := io.ReadFull(conn, make([]byte, length)); err != nil {
+		return
+	}
+
+	time.Sleep(delay)
+	conn.Write(auth[:4])
+	time.Sleep(delay)
+	conn.Write(auth[4:])
+}
+
+func handshakeServer(timeout time.Duration) *Server {
+	return &Server{config: &config.Config{HandshakeTimeout: timeout, MaxStartupPacketBytes: 8192}}
+}
+
+func TestSlowHandshakeWithinTimeout(t *testing.T) {
+	client, proxy := net.Pipe()
+	defer client.Close()
+	defer proxy.Close()
+
+	auth := authPacket("app")
+	go slowClient(client, auth, 150*time.Millisecond)
+
+	username, handshake, err := handshakeServer(2 * time.Second).extractMySQLUsername(proxy)
+	if err != nil {
+		t.Fatalf("extractMySQLUsername: %v", err)
+	}
+	if username != "app" {
+		t.Errorf("username = %q, want app", username)
+	}
+	if !bytes.Equal(handshake, auth) {
+		t.Error("the handshake to forward differs from the one the client sent")
+	}
+}
+
+func TestSlowHandshakePastTimeout(t *testing.T) {
+	client, proxy := net.Pipe()
+	defer client.Close()
+	defer proxy.Close()
+
+	go slowClient(client, authPacket("app"), 300*time.Millisecond)
+
+	_, _, err := handshakeServer(200 * time.Millisecond).extractMySQLUsername(proxy)
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("extractMySQLUsername error = %v, want a timeout", err)
+	}
+}
+
+// paddedAuthPacket returns an auth packet for app whose payload is size bytes, padded with
+// connection attributes
+func paddedAuthPacket(size int) []byte {
+	payload := append(bytes.Repeat([]byte{0}, 32), "app\x00"...)
+	payload = append(payload, bytes.Repeat([]byte{'a'}, size-len(payload))...)
+	return append([]byte{byte(size), byte(size >> 8), byte(size >> 16), 1}, payload...)
+}
+
+func TestAuthPacketSizeCap(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int
+		wantErr bool
+	}{
+		{name: "default cap", max: 8192, wantErr: true},
+		{name: "raised cap", max: 16384},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, proxy := net.Pipe()
+			defer client.Close()
+			defer proxy.Close()
+			go slowClient(client, paddedAuthPacket(8193), 0)
+
+			server := &Server{config: &config.Config{HandshakeTimeout: 2 * time.Second, MaxStartupPacketBytes: tt.max}}
+			username, _, err := server.extractMySQLUsername(proxy)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "exceeds max_startup_packet_bytes") {
+					t.Errorf("extractMySQLUsername error = %v, want the packet rejected", err)
+				}
+				return
+			}
+			if err != nil || username != "app" {
+				t.Errorf("extractMySQLUsername = %q, %v, want app", username, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+//go:build linux
+
+package proxy
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/mysql/pkg/config"
+	"golang.org/x/sys/unix"
+)
+
+// sockopt reads a socket option of a listener with get
+func sockopt[T any](t *testing.T, ln net.Listener, get func(fd int) (T, error)) T {
+	t.Helper()
+	rawConn, err := ln.(*net.TCPListener).SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn: %v", err)
+	}
+	var value T
+	var getErr error
+	if err := rawConn.Control(func(fd uintptr) { value, getErr = get(int(fd)) }); err != nil {
+		t.Fatalf("Control: %v", err)
+	}
+	if getErr != nil {
+		t.Fatalf("getsockopt: %v", getErr)
+	}
+	return value
+}
+
+func TestListenReusePort(t *testing.T) {
+	server := &Server{config: &config.Config{ListenAddr: "127.0.0.1:0", ReusePort: true}}
+
+	first, err := server.listen(context.Background())
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer first.Close()
+	// A second proxy process binds the same port
+	server.config.ListenAddr = first.Addr().String()
+	second, err := server.listen(context.Background())
+	if err != nil {
+		t.Fatalf("listen on the port of the first listener: %v", err)
+	}
+	defer second.Close()
+
+	for _, ln := range []net.Listener{first, second} {
+		reuse := sockopt(t, ln, func(fd int) (int, error) { return unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEPORT) })
+		if reuse != 1 {
+			t.Errorf("SO_REUSEPORT of %s = %d, want 1", ln.Addr(), reuse)
+		}
+	}
+
+	// Without reuse_port the port stays exclusive
+	server.config.ReusePort = false
+	if ln, err := server.listen(context.Background()); err == nil {
+		ln.Close()
+		t.Error("listened on a taken port without reuse_port")
+	}
+}
+
+func TestListenBacklog(t *testing.T) {
+	server := &Server{config: &config.Config{ListenAddr: "127.0.0.1:0", ListenBacklog: 64}}
+
+	ln, err := server.listen(context.Background())
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	// The TCP info of a listening socket reports the length of its accept queue as sacked
+	info := sockopt(t, ln, func(fd int) (*unix.TCPInfo, error) {
+		return unix.GetsockoptTCPInfo(fd, unix.IPPROTO_TCP, unix.TCP_INFO)
+	})
+	if info.Sacked != 64 {
+		t.Errorf("backlog = %d, want 64", info.Sacked)
+	}
+}
+
+func TestListenKeepAlive(t *testing.T) {
+	server := &Server{config: &config.Config{ListenAddr: "127.0.0.1:0", KeepAlive: 45 * time.Second}}
+
+	ln, err := server.listen(context.Background())
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	defer conn.Close()
+
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn: %v", err)
+	}
+	var idle int
+	var getErr error
+	rawConn.Control(func(fd uintptr) { idle, getErr = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPIDLE) })
+	if getErr != nil || idle != 45 {
+		t.Errorf("keepalive idle of an accepted connection = %d (%v), want 45s", idle, getErr)
+	}
+}
@@ -35,10 +35,11 @@ type Server struct {
 // NewServer creates a new proxy server
 func NewServer(cfg *config.Config) (*Server, error) {
 	// Create Kubernetes client
-	kubeClient, err := kubernetes.NewClient(cfg.KubeConfigPath, cfg.LabelSelector)
+	kubeClient, err := kubernetes.NewClient(cfg.KubeConfigPath, cfg.Selectors())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
 	}
+	kubeClient.SetWatchNamespaces(cfg.WatchNamespaces)
 
 	// Create server instance with connection limiting, buffer pool, and DNS cache
 	server := &Server{
@@ -75,7 +76,7 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 
 	var err error
-	s.listener, err = net.Listen("tcp", s.config.ListenAddr)
+	s.listener, err = s.listen(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %v", s.config.ListenAddr, err)
 	}
@@ -270,7 +271,7 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 	log.Printf("Connecting to MySQL service: %s (resolved from %s)", address, serviceDNS)
 
 	// Connect using the resolved IP with context for cancellation
-	dialer := &net.Dialer{Timeout: s.config.ConnectionTimeout}
+	dialer := s.dialer()
 	serverConn, err := dialer.DialContext(connCtx, "tcp", address)
 	if err != nil {
 		log.Printf("Failed to connect to MySQL service %s: %v", address, err)
@@ -288,6 +289,10 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 	log.Printf("Starting data proxy for MySQL connection from %s", clientConn.RemoteAddr())
 
 	errCh := make(chan error, 2)
+	serverDone := make(chan struct{})
+
+	// The shutdown notice continues the packet sequence of the session
+	sequence := &packetSequence{}
 
 	// Client -> Server data flow
 	go func() {
@@ -296,18 +301,20 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 		defer s.bufferPool.Put(buf) // Return buffer to pool when done
 
 		// Use CopyBuffer with pooled buffer
-		_, err := io.CopyBuffer(serverConn, clientConn, *buf)
+		_, err := io.CopyBuffer(&packetWriter{w: serverConn, sequence: sequence}, clientConn, *buf)
 		errCh <- err
 	}()
 
 	// Server -> Client data flow
 	go func() {
+		defer close(serverDone)
+
 		// Get buffer from pool
 		buf := s.bufferPool.Get()
 		defer s.bufferPool.Put(buf) // Return buffer to pool when done
 
 		// Use CopyBuffer with pooled buffer
-		_, err := io.CopyBuffer(clientConn, serverConn, *buf)
+		_, err := io.CopyBuffer(&packetWriter{w: clientConn, sequence: sequence}, serverConn, *buf)
 		errCh <- err
 	}()
 
@@ -319,13 +326,14 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 		}
 	case <-connCtx.Done(): // Use connection-specific context here
 		log.Printf("Connection closed due to server shutdown")
+		closeForShutdown(clientConn, serverConn, serverDone, sequence)
 	}
 }
 
 // extractMySQLUsername extracts the username from a MySQL handshake
 func (s *Server) extractMySQLUsername(clientConn net.Conn) (string, []byte, error) {
 	// Set read deadline to prevent hanging
-	clientConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	clientConn.SetReadDeadline(time.Now().Add(s.config.HandshakeTimeout))
 	defer clientConn.SetReadDeadline(time.Time{})
 
 	// Send initial handshake packet to client
@@ -345,6 +353,12 @@ func (s *Server) extractMySQLUsername(clientConn net.Conn) (string, []byte, erro
 	// Get packet length (first 3 bytes, little endian)
 	clientPacketLen := int(clientHeader[0]) | int(clientHeader[1])<<8 | int(clientHeader[2])<<16
 
+	// Check the length before allocating, any client may claim up to 16MB
+	if clientPacketLen > s.config.MaxStartupPacketBytes {
+		return "", nil, fmt.Errorf("client auth packet of %d bytes exceeds max_startup_packet_bytes (%d)",
+			clientPacketLen, s.config.MaxStartupPacketBytes)
+	}
+
 	// Read client auth packet
 	clientPacket := make([]byte, clientPacketLen)
 	if _, err := io.ReadFull(clientConn, clientPacket); err != nil {
@@ -440,7 +454,7 @@ func (s *Server) createInitialHandshake() []byte {
 // completeHandshake completes the MySQL handshake with the server
 func (s *Server) completeHandshake(clientConn, serverConn net.Conn, clientHandshake []byte) error {
 	// Set read/write deadlines
-	serverConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	serverConn.SetReadDeadline(time.Now().Add(s.config.HandshakeTimeout))
 	defer serverConn.SetReadDeadline(time.Time{})
 
 	// Read initial handshake packet from server
@@ -493,3 +507,12 @@ func (s *Server) completeHandshake(clientConn, serverConn net.Conn, clientHandsh
 func (s *Server) Wait() {
 	s.connections.Wait()
 }
+
+// dialer returns the dialer for backend connections, with the connection timeout and
+// keepalive period of the config
+func (s *Server) dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   s.config.ConnectionTimeout,
+		KeepAlive: s.config.KeepAlive,
+	}
+}
@@ -0,0 +1,34 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/mysql/pkg/config"
+)
+
+func TestDialerUsesConfigTimeouts(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		keepAlive time.Duration
+	}{
+		{name: "configured", timeout: 3 * time.Second, keepAlive: 45 * time.Second},
+		// Negative disables keepalive, it must reach the dialer as it is
+		{name: "keepalive disabled", timeout: 5 * time.Second, keepAlive: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &Server{config: &config.Config{ConnectionTimeout: tt.timeout, KeepAlive: tt.keepAlive}}
+			if d := server.dialer(); d.Timeout != tt.timeout || d.KeepAlive != tt.keepAlive {
+				t.Errorf("dialer timeout %v, keepalive %v, want %v and %v", d.Timeout, d.KeepAlive, tt.timeout, tt.keepAlive)
+			}
+		})
+	}
+
+	// The default keepalive is what backend connections get without keep_alive in the config
+	server := &Server{config: config.DefaultConfig()}
+	if d := server.dialer(); d.KeepAlive != 30*time.Second {
+		t.Errorf("default keepalive = %v, want 30s", d.KeepAlive)
+	}
+}
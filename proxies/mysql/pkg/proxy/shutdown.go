@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// How long a client gets to take the shutdown notice before its connection is closed
+const shutdownNoticeTimeout = 5 * time.Second
+
+// ER_SERVER_SHUTDOWN, what MySQL itself sends to sessions it closes on shutdown
+const (
+	errServerShutdown        = 1053
+	errServerShutdownState   = "08S01"
+	errServerShutdownMessage = "Server shutdown in progress"
+)
+
+// packetSequence follows the MySQL packets proxied in both directions of a session to know
+// the sequence id its next packet takes
+type packetSequence struct {
+	mu   sync.Mutex
+	next byte
+}
+
+func (s *packetSequence) seen(sequence byte) {
+	s.mu.Lock()
+	s.next = sequence + 1
+	s.mu.Unlock()
+}
+
+// Next returns the sequence id that follows the last packet of the session
+func (s *packetSequence) Next() byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next
+}
+
+// packetWriter forwards a stream of MySQL packets, recording the sequence id of each one. The
+// stream may split headers and payloads across writes.
+type packetWriter struct {
+	w        io.Writer
+	sequence *packetSequence
+
+	header    [4]byte
+	headerLen int
+	remaining int // Payload bytes of the current packet still to come
+}
+
+func (p *packetWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.track(b[:n])
+	return n, err
+}
+
+func (p *packetWriter) track(b []byte) {
+	for len(b) > 0 {
+		if p.remaining > 0 {
+			skip := min(p.remaining, len(b))
+			p.remaining -= skip
+			b = b[skip:]
+			continue
+		}
+
+		copied := copy(p.header[p.headerLen:], b)
+		p.headerLen += copied
+		b = b[copied:]
+		if p.headerLen < len(p.header) {
+			return
+		}
+		p.remaining = int(p.header[0]) | int(p.header[1])<<8 | int(p.header[2])<<16
+		p.sequence.seen(p.header[3])
+		p.headerLen = 0
+	}
+}
+
+// shutdownNotice returns the ERR packet telling a client the server is shutting down, so
+// drivers report a lost connection and reconnect instead of seeing a reset. It takes the
+// sequence id that follows the last packet of the session, drivers reject any other.
+func shutdownNotice(sequence byte) []byte {
+	payload := []byte{0xff, errServerShutdown & 0xff, errServerShutdown >> 8, '#'}
+	payload = append(payload, errServerShutdownState...)
+	payload = append(payload, errServerShutdownMessage...)
+
+	header := []byte{
+		byte(len(payload)),
+		byte(len(payload) >> 8),
+		byte(len(payload) >> 16),
+		sequence,
+	}
+	return append(header, payload...)
+}
+
+// closeForShutdown stops proxying a session and sends the client the shutdown notice. The
+// server -> client flow, which closes serverDone when it returns, is stopped first so the
+// notice doesn't land in the middle of a forwarded packet.
+func closeForShutdown(clientConn, serverConn net.Conn, serverDone <-chan struct{}, sequence *packetSequence) {
+	clientConn.SetWriteDeadline(time.Now().Add(shutdownNoticeTimeout))
+	serverConn.Close()
+	<-serverDone
+	clientConn.Write(shutdownNotice(sequence.Next()))
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"bytes"
+	"testing"
+)
+
+// packet returns a MySQL packet with a payload of size bytes
+func packet(sequence byte, size int) []byte {
+	return append([]byte{byte(size), byte(size >> 8), byte(size >> 16), sequence}, bytes.Repeat([]byte{'x'}, size)...)
+}
+
+func TestPacketWriterTracksSequence(t *testing.T) {
+	sequence := &packetSequence{}
+	var client, server bytes.Buffer
+	toServer := &packetWriter{w: &server, sequence: sequence}
+	toClient := &packetWriter{w: &client, sequence: sequence}
+
+	// A command and its three packet result set
+	toServer.Write(packet(0, 12))
+	if got := sequence.Next(); got != 1 {
+		t.Fatalf("after the command, next = %d, want 1", got)
+	}
+	toClient.Write(append(append(packet(1, 1), packet(2, 40)...), packet(3, 7)...))
+	if got := sequence.Next(); got != 4 {
+		t.Fatalf("after the result set, next = %d, want 4", got)
+	}
+	if client.Len() != 4+1+4+40+4+7 {
+		t.Errorf("forwarded %d bytes, want every byte of the result set", client.Len())
+	}
+
+	// The next command starts over
+	toServer.Write(packet(0, 3))
+	if got := sequence.Next(); got != 1 {
+		t.Errorf("after the next command, next = %d, want 1", got)
+	}
+}
+
+func TestPacketWriterTracksSplitPackets(t *testing.T) {
+	sequence := &packetSequence{}
+	w := &packetWriter{w: &bytes.Buffer{}, sequence: sequence}
+
+	stream := append(packet(1, 300), packet(2, 0)...)
+	stream = append(stream, packet(3, 5)...)
+	// Writes cutting through headers and payloads
+	for _, cut := range [][2]int{{0, 2}, {2, 150}, {150, 305}, {305, 306}, {306, len(stream) - 3}} {
+		w.Write(stream[cut[0]:cut[1]])
+	}
+	if got := sequence.Next(); got != 4 {
+		t.Fatalf("next = %d, want 4 once the last header went through", got)
+	}
+	w.Write(stream[len(stream)-3:])
+	if got := sequence.Next(); got != 4 {
+		t.Errorf("next = %d after the last payload bytes, want 4", got)
+	}
+}
+
+func TestShutdownNoticeUsesSequence(t *testing.T) {
+	notice := shutdownNotice(4)
+	if notice[3] != 4 {
+		t.Errorf("sequence id = %d, want 4", notice[3])
+	}
+	if length := int(notice[0]) | int(notice[1])<<8 | int(notice[2])<<16; length != len(notice)-4 {
+		t.Errorf("length = %d, want %d", length, len(notice)-4)
+	}
+	if notice[4] != 0xff || int(notice[5])|int(notice[6])<<8 != errServerShutdown {
+		t.Errorf("payload = %x, want an ERR packet with ER_SERVER_SHUTDOWN", notice[4:7])
+	}
+}
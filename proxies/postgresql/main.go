@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/deployra/deployra/proxies/postgresql/pkg/config"
@@ -23,6 +24,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
 
 	// Create context that listens for termination signals
 	ctx, cancel := context.WithCancel(context.Background())
@@ -44,7 +48,11 @@ func main() {
 func runProxyServer(ctx context.Context, cfg *config.Config) {
 	// Log configuration
 	log.Printf("Starting PostgreSQL proxy server...")
-	log.Printf("Watching all namespaces for services with label selector: %s", cfg.LabelSelector)
+	if len(cfg.WatchNamespaces) > 0 {
+		log.Printf("Watching namespaces %s for services with label selector: %s", strings.Join(cfg.WatchNamespaces, ", "), strings.Join(cfg.Selectors(), " or "))
+	} else {
+		log.Printf("Watching all namespaces for services with label selector: %s", strings.Join(cfg.Selectors(), " or "))
+	}
 	log.Printf("PostgreSQL server listening on %s", cfg.ListenAddr)
 
 	// Create proxy server
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadDefaultsHandshakeTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	os.WriteFile(path, []byte(`{"handshake_timeout": 0}`), 0o600)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.HandshakeTimeout != 5*time.Second {
+		t.Errorf("zero handshake_timeout loaded as %v, want the 5s default", cfg.HandshakeTimeout)
+	}
+
+	// Durations are decoded as nanoseconds
+	os.WriteFile(path, []byte(`{"handshake_timeout": 30000000000}`), 0o600)
+	if cfg, err = Load(path); err != nil || cfg.HandshakeTimeout != 30*time.Second {
+		t.Errorf("handshake_timeout loaded as %v, %v, want 30s", cfg.HandshakeTimeout, err)
+	}
+}
+
+func TestLoadMaxStartupPacketBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	os.WriteFile(path, []byte(`{}`), 0o600)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.MaxStartupPacketBytes != 8192 {
+		t.Errorf("max_startup_packet_bytes defaults to %d, want 8192", cfg.MaxStartupPacketBytes)
+	}
+
+	os.WriteFile(path, []byte(`{"max_startup_packet_bytes": 65536}`), 0o600)
+	if cfg, err = Load(path); err != nil || cfg.MaxStartupPacketBytes != 65536 {
+		t.Errorf("max_startup_packet_bytes loaded as %d, %v, want 65536", cfg.MaxStartupPacketBytes, err)
+	}
+
+	// The proxy buffers the whole packet, so the cap itself is bounded
+	cfg.MaxStartupPacketBytes = MaxStartupPacketLimit + 1
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("max_startup_packet_bytes over %d is valid", MaxStartupPacketLimit)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Validate checks for missing settings and values out of range, and returns every
+// problem found so a config can be fixed in one go
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.ListenAddr == "" {
+		errs = append(errs, errors.New("listen_addr is required"))
+	} else if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		errs = append(errs, fmt.Errorf("invalid listen_addr %q: %v", c.ListenAddr, err))
+	}
+
+	if len(c.Selectors()) == 0 {
+		errs = append(errs, errors.New("label_selector or label_selectors is required"))
+	}
+	for _, selector := range c.Selectors() {
+		if _, err := labels.Parse(selector); err != nil {
+			errs = append(errs, fmt.Errorf("invalid label selector %q: %v", selector, err))
+		}
+	}
+
+	for _, namespace := range c.WatchNamespaces {
+		if problems := validation.IsDNS1123Label(namespace); len(problems) > 0 {
+			errs = append(errs, fmt.Errorf("invalid namespace %q in watch_namespaces: %v", namespace, problems[0]))
+		}
+	}
+
+	if c.MaxConnections <= 0 {
+		errs = append(errs, fmt.Errorf("max_connections must be positive, got %d", c.MaxConnections))
+	}
+	if c.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("idle_timeout must not be negative, got %v", c.IdleTimeout))
+	}
+	if c.ConnectionTimeout < 0 {
+		errs = append(errs, fmt.Errorf("connection_timeout must not be negative, got %v", c.ConnectionTimeout))
+	}
+	if c.ListenBacklog < 0 {
+		errs = append(errs, fmt.Errorf("listen_backlog must not be negative, got %d", c.ListenBacklog))
+	}
+	if c.MaxStartupPacketBytes > MaxStartupPacketLimit {
+		errs = append(errs, fmt.Errorf("max_startup_packet_bytes %d exceeds the limit of %d",
+			c.MaxStartupPacketBytes, MaxStartupPacketLimit))
+	}
+	switch c.RouteBy {
+	case RouteByUser, RouteByDatabase, RouteByUserDatabase:
+	default:
+		errs = append(errs, fmt.Errorf("invalid route_by %q, must be %q, %q or %q",
+			c.RouteBy, RouteByUser, RouteByDatabase, RouteByUserDatabase))
+	}
+	if c.ReadBufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("read_buffer_size must be positive, got %d", c.ReadBufferSize))
+	}
+	if c.WriteBufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("write_buffer_size must be positive, got %d", c.WriteBufferSize))
+	}
+
+	return errors.Join(errs...)
+}
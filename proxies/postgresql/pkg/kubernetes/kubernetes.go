@@ -7,6 +7,8 @@ import (
 	"log"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -33,6 +35,7 @@ type ServiceInfo struct {
 	ServiceID string
 	Port      int32
 	Usernames []string
+	Databases []string
 }
 
 // ServiceChangeCallback is a function called when services change
@@ -40,15 +43,16 @@ type ServiceChangeCallback func(ServiceInfoAction, string, *ServiceInfo)
 
 // Client represents a Kubernetes client
 type Client struct {
-	clientset      *kubernetes.Clientset
-	labelSelector  string
+	clientset      kubernetes.Interface
+	labelSelectors []string
 	watchContext   context.Context
 	watchCancel    context.CancelFunc
-	watcherStarted bool
+	watcherStarted atomic.Bool
+	namespaces     []string
 }
 
 // NewClient creates a new Kubernetes client
-func NewClient(kubeConfigPath, labelSelector string) (*Client, error) {
+func NewClient(kubeConfigPath string, labelSelectors []string) (*Client, error) {
 	var config *rest.Config
 	var err error
 
@@ -93,38 +97,66 @@ func NewClient(kubeConfigPath, labelSelector string) (*Client, error) {
 
 	return &Client{
 		clientset:      clientset,
-		labelSelector:  labelSelector,
+		labelSelectors: labelSelectors,
 		watchContext:   ctx,
 		watchCancel:    cancel,
-		watcherStarted: false,
 	}, nil
 }
 
-// StartWatching starts watching for service changes in all namespaces
+// StartWatching starts watching for service changes in the configured namespaces
 func (c *Client) StartWatching(callback ServiceChangeCallback) error {
-	if c.watcherStarted {
+	if c.watcherStarted.Load() {
 		return fmt.Errorf("watchers already started")
 	}
 
-	// Start watcher for all namespaces
-	go c.watchServices(callback)
+	// Start one watcher per namespace and label selector, taking turns to run the callback
+	var mu sync.Mutex
+	matched := newMatchedServices(callback)
+	for i, labelSelector := range c.labelSelectors {
+		report := matched.callbackFor(i)
+		serialized := func(action ServiceInfoAction, key string, info *ServiceInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			report(action, key, info)
+		}
+		for _, namespace := range c.watchedNamespaces() {
+			go c.watchServices(namespace, labelSelector, serialized)
+		}
+	}
 
-	c.watcherStarted = true
+	c.watcherStarted.Store(true)
 	return nil
 }
 
+// SetWatchNamespaces limits the watch to services in the given namespaces, all namespaces
+// are watched when none are given
+func (c *Client) SetWatchNamespaces(namespaces []string) {
+	c.namespaces = namespaces
+}
+
+// watchedNamespaces returns the namespaces to watch, metav1.NamespaceAll when none are set
+func (c *Client) watchedNamespaces() []string {
+	if len(c.namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return c.namespaces
+}
+
 // StopWatching stops all active watchers
 func (c *Client) StopWatching() {
 	if c.watchCancel != nil {
 		c.watchCancel()
 	}
-	c.watcherStarted = false
+	c.watcherStarted.Store(false)
 }
 
 // watchServices watches for service changes across all namespaces
 // Uses List + Watch pattern to ensure no services are missed
-func (c *Client) watchServices(callback ServiceChangeCallback) {
-	log.Println("Starting to watch all namespaces for services with label selector:", c.labelSelector)
+func (c *Client) watchServices(namespace, labelSelector string, callback ServiceChangeCallback) {
+	log.Printf("Starting to watch %s for services with label selector: %s", describeNamespace(namespace), labelSelector)
+
+	retry := backoff{base: watchRetryBase, max: watchRetryMax}
+	reported := newReportedServices(callback)
 
 	for {
 		// Check if context is cancelled
@@ -136,34 +168,45 @@ func (c *Client) watchServices(callback ServiceChangeCallback) {
 
 		// Step 1: List all existing services first
 		listOptions := metav1.ListOptions{
-			LabelSelector: c.labelSelector,
+			LabelSelector: labelSelector,
 		}
 
-		services, err := c.clientset.CoreV1().Services("").List(c.watchContext, listOptions)
+		services, err := c.clientset.CoreV1().Services(namespace).List(c.watchContext, listOptions)
 		if err != nil {
-			log.Printf("Error listing services: %v, retrying in 5 seconds...", err)
-			time.Sleep(5 * time.Second)
+			delay := retry.next()
+			log.Printf("Error listing services: %v, retrying in %v...", err, delay.Round(time.Millisecond))
+			if !sleepContext(c.watchContext, delay) {
+				return
+			}
 			continue
 		}
+		retry.reset()
 
 		// Process all existing services
-		log.Printf("Found %d existing services with label selector: %s", len(services.Items), c.labelSelector)
+		log.Printf("Found %d existing services in %s with label selector: %s", len(services.Items), describeNamespace(namespace), labelSelector)
+		listed := make(map[string]bool, len(services.Items))
 		for _, service := range services.Items {
 			serviceKey, info, err := c.handleServiceChange(&service)
 			if err == nil {
-				callback(Add, serviceKey, info)
-				log.Printf("Loaded existing service: %s", serviceKey)
+				listed[serviceKey] = true
+				if reported.add(serviceKey, info) {
+					log.Printf("Loaded existing service: %s", serviceKey)
+				}
 			}
 		}
+		reported.prune(listed)
 
 		// Step 2: Start watching from the resource version we got from List
 		listOptions.ResourceVersion = services.ResourceVersion
 		log.Printf("Starting watch from resourceVersion: %s", services.ResourceVersion)
 
-		watcher, err := c.clientset.CoreV1().Services("").Watch(c.watchContext, listOptions)
+		watcher, err := c.clientset.CoreV1().Services(namespace).Watch(c.watchContext, listOptions)
 		if err != nil {
-			log.Printf("Error creating watcher: %v, retrying in 5 seconds...", err)
-			time.Sleep(5 * time.Second)
+			delay := retry.next()
+			log.Printf("Error creating watcher: %v, retrying in %v...", err, delay.Round(time.Millisecond))
+			if !sleepContext(c.watchContext, delay) {
+				return
+			}
 			continue
 		}
 
@@ -187,14 +230,14 @@ func (c *Client) watchServices(callback ServiceChangeCallback) {
 						log.Printf("Service added or modified: %s", service.Name)
 						serviceKey, info, err := c.handleServiceChange(service)
 						if err == nil {
-							callback(Add, serviceKey, info)
+							reported.add(serviceKey, info)
 						}
 					}
 				case watch.Deleted:
 					if service, ok := event.Object.(*corev1.Service); ok {
 						log.Printf("Service deleted: %s", service.Name)
 						serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
-						callback(Delete, serviceKey, nil)
+						reported.remove(serviceKey)
 					}
 				}
 			}
@@ -202,10 +245,20 @@ func (c *Client) watchServices(callback ServiceChangeCallback) {
 
 		watcher.Stop()
 		log.Println("Restarting list + watch cycle after brief delay...")
-		time.Sleep(5 * time.Second)
+		if !sleepContext(c.watchContext, retry.next()) {
+			return
+		}
 	}
 }
 
+// describeNamespace names a watched namespace in log messages
+func describeNamespace(namespace string) string {
+	if namespace == metav1.NamespaceAll {
+		return "all namespaces"
+	}
+	return "namespace " + namespace
+}
+
 // handleServiceChange processes a service change event
 func (c *Client) handleServiceChange(service *corev1.Service) (string, *ServiceInfo, error) {
 	// Extract service name
@@ -232,6 +285,14 @@ func (c *Client) handleServiceChange(service *corev1.Service) (string, *ServiceI
 		}
 	}
 
+	// Extract database names from labels
+	databases := []string{}
+	for k, v := range service.Labels {
+		if strings.HasPrefix(k, "database-") && v != "" {
+			databases = append(databases, v)
+		}
+	}
+
 	// Find the PostgreSQL port
 	var port int32 = 5432 // Default PostgreSQL port
 	for _, servicePort := range service.Spec.Ports {
@@ -249,6 +310,7 @@ func (c *Client) handleServiceChange(service *corev1.Service) (string, *ServiceI
 		ServiceID: serviceID,
 		Port:      port,
 		Usernames: usernames,
+		Databases: databases,
 	}
 
 	return serviceKey, info, nil
@@ -0,0 +1,47 @@
+package kubernetes
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestReportedServicesIgnoresListOrder(t *testing.T) {
+	reports := 0
+	reported := newReportedServices(func(action ServiceInfoAction, key string, info *ServiceInfo) {
+		reports++
+	})
+
+	if !reported.add("ns/svc", &ServiceInfo{Name: "svc", Usernames: []string{"app", "admin"}, Databases: []string{"main", "logs"}}) {
+		t.Fatal("first add was not reported")
+	}
+	if reported.add("ns/svc", &ServiceInfo{Name: "svc", Usernames: []string{"admin", "app"}, Databases: []string{"logs", "main"}}) {
+		t.Fatal("add with the same lists in another order was reported again")
+	}
+	if !reported.add("ns/svc", &ServiceInfo{Name: "svc", Usernames: []string{"admin", "app"}, Databases: []string{"main"}}) {
+		t.Fatal("add with changed lists was not reported")
+	}
+	if reports != 2 {
+		t.Fatalf("reported %d times, want 2", reports)
+	}
+}
+
+func TestMatchedServicesRemovesOnceNoSelectorMatches(t *testing.T) {
+	var reports []string
+	matched := newMatchedServices(func(action ServiceInfoAction, key string, info *ServiceInfo) {
+		reports = append(reports, fmt.Sprintf("%v %s", action, key))
+	})
+	first, second := matched.callbackFor(0), matched.callbackFor(1)
+
+	first(Add, "ns/svc", &ServiceInfo{Name: "svc"})
+	second(Add, "ns/svc", &ServiceInfo{Name: "svc"})
+	// The service still matches the second selector
+	first(Delete, "ns/svc", nil)
+	if len(reports) != 2 {
+		t.Fatalf("reports = %v, want the adds only while a selector still matches", reports)
+	}
+
+	second(Delete, "ns/svc", nil)
+	if len(reports) != 3 || reports[2] != fmt.Sprintf("%v ns/svc", Delete) {
+		t.Errorf("reports = %v, want the service removed once no selector matches", reports)
+	}
+}
@@ -0,0 +1,30 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// listen opens the listener for client connections. Accepted connections get the keepalive
+// period of the config, and the socket SO_REUSEPORT and the backlog when they are set.
+func (s *Server) listen(ctx context.Context) (net.Listener, error) {
+	lc := net.ListenConfig{KeepAlive: s.config.KeepAlive}
+	if s.config.ReusePort {
+		lc.Control = reusePort
+	}
+
+	ln, err := lc.Listen(ctx, "tcp", s.config.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.ListenBacklog > 0 {
+		if err := setBacklog(ln, s.config.ListenBacklog); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to set listen backlog: %v", err)
+		}
+	}
+
+	return ln, nil
+}
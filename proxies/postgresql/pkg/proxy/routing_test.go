@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/deployra/deployra/proxies/postgresql/pkg/config"
+	"github.com/deployra/deployra/proxies/postgresql/pkg/kubernetes"
+)
+
+func routingServer(routeBy string) *Server {
+	return &Server{
+		config:       &config.Config{RouteBy: routeBy},
+		services:     make(map[string]*kubernetes.ServiceInfo),
+		routingTable: make(map[string]string),
+	}
+}
+
+func sortedKeys(table map[string]string) []string {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestRoutingTableByMode(t *testing.T) {
+	info := &kubernetes.ServiceInfo{Name: "svc1-service", Namespace: "proj1", Usernames: []string{"app"}, Databases: []string{"orders", "billing"}}
+
+	tests := []struct {
+		routeBy string
+		want    []string
+	}{
+		{routeBy: config.RouteByUser, want: []string{"app"}},
+		{routeBy: config.RouteByDatabase, want: []string{"billing", "orders"}},
+		{routeBy: config.RouteByUserDatabase, want: []string{"app@billing", "app@orders"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.routeBy, func(t *testing.T) {
+			server := routingServer(tt.routeBy)
+			server.handleServicesChanged(kubernetes.Add, "proj1/svc1-service", info)
+
+			keys := sortedKeys(server.routingTable)
+			if len(keys) != len(tt.want) {
+				t.Fatalf("routes = %v, want %v", keys, tt.want)
+			}
+			for i, key := range tt.want {
+				if keys[i] != key || server.routingTable[key] != "proj1/svc1-service" {
+					t.Errorf("routes = %v, want %v to proj1/svc1-service", server.routingTable, tt.want)
+				}
+			}
+
+			server.handleServicesChanged(kubernetes.Delete, "proj1/svc1-service", nil)
+			if len(server.routingTable) != 0 {
+				t.Errorf("routes left after the service was deleted: %v", server.routingTable)
+			}
+		})
+	}
+}
+
+func TestRoutingTableReplacesOldRoutes(t *testing.T) {
+	server := routingServer(config.RouteByDatabase)
+	server.handleServicesChanged(kubernetes.Add, "proj1/svc1-service", &kubernetes.ServiceInfo{Databases: []string{"orders"}})
+	server.handleServicesChanged(kubernetes.Add, "proj1/svc1-service", &kubernetes.ServiceInfo{Databases: []string{"sales"}})
+
+	if keys := sortedKeys(server.routingTable); len(keys) != 1 || keys[0] != "sales" {
+		t.Errorf("routes = %v, want only the new database", keys)
+	}
+}
+
+func TestConnectionRouteKey(t *testing.T) {
+	tests := []struct {
+		routeBy string
+		params  map[string]string
+		want    string
+	}{
+		{routeBy: config.RouteByUser, params: map[string]string{"user": "app", "database": "orders"}, want: "app"},
+		{routeBy: config.RouteByDatabase, params: map[string]string{"user": "app", "database": "orders"}, want: "orders"},
+		{routeBy: config.RouteByUserDatabase, params: map[string]string{"user": "app", "database": "orders"}, want: "app@orders"},
+		// Without a database PostgreSQL connects to the one named after the user
+		{routeBy: config.RouteByDatabase, params: map[string]string{"user": "app"}, want: "app"},
+		{routeBy: config.RouteByUserDatabase, params: map[string]string{"user": "app"}, want: "app@app"},
+	}
+	for _, tt := range tests {
+		if got := routingServer(tt.routeBy).connectionRouteKey(tt.params); got != tt.want {
+			t.Errorf("%s: connectionRouteKey(%v) = %q, want %q", tt.routeBy, tt.params, got, tt.want)
+		}
+	}
+}
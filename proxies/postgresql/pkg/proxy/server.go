@@ -33,10 +33,11 @@ type Server struct {
 // NewServer creates a new proxy server
 func NewServer(cfg *config.Config) (*Server, error) {
 	// Create Kubernetes client
-	kubeClient, err := kubernetes.NewClient(cfg.KubeConfigPath, cfg.LabelSelector)
+	kubeClient, err := kubernetes.NewClient(cfg.KubeConfigPath, cfg.Selectors())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
 	}
+	kubeClient.SetWatchNamespaces(cfg.WatchNamespaces)
 
 	// Create server instance with connection limiting, buffer pool, and DNS cache
 	server := &Server{
@@ -73,7 +74,7 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 
 	var err error
-	s.listener, err = net.Listen("tcp", s.config.ListenAddr)
+	s.listener, err = s.listen(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %v", s.config.ListenAddr, err)
 	}
@@ -177,29 +178,38 @@ func (s *Server) handleServicesChanged(action kubernetes.ServiceInfoAction, serv
 
 	if action == kubernetes.Add {
 		if info != nil {
+			// Drop the routes of the previous version of the service first
+			if oldServiceInfo, exists := s.services[serviceKey]; exists && oldServiceInfo != nil {
+				for _, key := range s.routeKeys(oldServiceInfo) {
+					if s.routingTable[key] == serviceKey {
+						delete(s.routingTable, key)
+					}
+				}
+			}
+
 			s.services[serviceKey] = info
-			for _, username := range info.Usernames {
-				s.routingTable[username] = serviceKey
-				log.Printf("Added route: %s -> %s", username, serviceKey)
+			for _, key := range s.routeKeys(info) {
+				s.routingTable[key] = serviceKey
+				log.Printf("Added route: %s -> %s", key, serviceKey)
 			}
 		} else {
 			log.Printf("Warning: Received nil ServiceInfo for Add action on service %s", serviceKey)
 		}
 	} else if action == kubernetes.Delete {
 		if s.services != nil {
-			// When deleting a service, remove associated username routes
+			// When deleting a service, remove associated routes
 			if oldServiceInfo, exists := s.services[serviceKey]; exists && oldServiceInfo != nil {
-				for _, username := range oldServiceInfo.Usernames {
-					delete(s.routingTable, username)
-					log.Printf("Removed route for username: %s", username)
+				for _, key := range s.routeKeys(oldServiceInfo) {
+					delete(s.routingTable, key)
+					log.Printf("Removed route: %s", key)
 				}
 				delete(s.services, serviceKey)
 			} else {
 				// If we don't have the service info, clean up by iterating through routing table
-				for username, key := range s.routingTable {
+				for routeKey, key := range s.routingTable {
 					if key == serviceKey {
-						delete(s.routingTable, username)
-						log.Printf("Removed route for username: %s", username)
+						delete(s.routingTable, routeKey)
+						log.Printf("Removed route: %s", routeKey)
 					}
 				}
 			}
@@ -208,10 +218,46 @@ func (s *Server) handleServicesChanged(action kubernetes.ServiceInfoAction, serv
 
 	// Debug logging
 	log.Printf("Current routing table:")
-	for username, serviceKey := range s.routingTable {
+	for routeKey, serviceKey := range s.routingTable {
 		if serviceInfo, ok := s.services[serviceKey]; ok {
-			log.Printf("  %s -> %s/%s", username, serviceInfo.Namespace, serviceInfo.Name)
+			log.Printf("  %s -> %s/%s", routeKey, serviceInfo.Namespace, serviceInfo.Name)
+		}
+	}
+}
+
+// routeKeys returns the routing table keys of a service for the configured routing mode
+func (s *Server) routeKeys(info *kubernetes.ServiceInfo) []string {
+	switch s.config.RouteBy {
+	case config.RouteByDatabase:
+		return info.Databases
+	case config.RouteByUserDatabase:
+		keys := make([]string, 0, len(info.Usernames)*len(info.Databases))
+		for _, username := range info.Usernames {
+			for _, database := range info.Databases {
+				keys = append(keys, username+"@"+database)
+			}
 		}
+		return keys
+	default:
+		return info.Usernames
+	}
+}
+
+// connectionRouteKey returns the routing key of a connection from its startup parameters
+func (s *Server) connectionRouteKey(params map[string]string) string {
+	// PostgreSQL uses the user name when no database is given
+	database := params["database"]
+	if database == "" {
+		database = params["user"]
+	}
+
+	switch s.config.RouteBy {
+	case config.RouteByDatabase:
+		return database
+	case config.RouteByUserDatabase:
+		return params["user"] + "@" + database
+	default:
+		return params["user"]
 	}
 }
 
@@ -226,21 +272,22 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 	log.Printf("New connection from %s", clientIP)
 
 	// Read the initial PostgreSQL startup message
-	username, startupPacket, err := s.extractPostgreSQLUsername(clientConn)
+	params, startupPacket, err := s.readStartupMessage(clientConn)
 	if err != nil {
-		log.Printf("Error extracting username from PostgreSQL startup message: %v", err)
+		log.Printf("Error reading PostgreSQL startup message: %v", err)
 		return
 	}
+	routeKey := s.connectionRouteKey(params)
 
-	// Find target service for username
+	// Find target service for the routing key
 	s.routingLock.RLock()
-	serviceKey, exists := s.routingTable[username]
+	serviceKey, exists := s.routingTable[routeKey]
 	s.routingLock.RUnlock()
 
 	if !exists {
 		log.Printf("No route found for connection from %s", clientIP)
 		// Send error message to client
-		s.sendErrorToClient(clientConn, fmt.Sprintf("No PostgreSQL service found for username: %s", username))
+		s.sendErrorToClient(clientConn, fmt.Sprintf("No PostgreSQL service found for %s: %s", s.config.RouteBy, routeKey))
 		return
 	}
 
@@ -281,7 +328,7 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 	log.Printf("Connecting to PostgreSQL server: %s (resolved from %s)", address, serviceDNS)
 
 	// Connect using the resolved IP with context for cancellation
-	dialer := &net.Dialer{Timeout: s.config.ConnectionTimeout}
+	dialer := s.dialer()
 
 	serverConn, err := dialer.DialContext(connCtx, "tcp", address)
 	if err != nil {
@@ -299,6 +346,7 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 
 	// Proxy data between client and server
 	errCh := make(chan error, 2)
+	serverDone := make(chan struct{})
 
 	// Set up bidirectional proxy with buffer pool
 	// Client -> Server data flow
@@ -314,6 +362,8 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 
 	// Server -> Client data flow
 	go func() {
+		defer close(serverDone)
+
 		// Get buffer from pool
 		buf := s.bufferPool.Get()
 		defer s.bufferPool.Put(buf) // Return buffer to pool when done
@@ -331,193 +381,10 @@ func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
 		}
 	case <-connCtx.Done(): // Use connection-specific context here
 		log.Printf("Connection closed due to server shutdown")
+		closeForShutdown(clientConn, serverConn, serverDone)
 	}
 }
 
-// extractPostgreSQLUsername extracts the username from PostgreSQL startup message
-func (s *Server) extractPostgreSQLUsername(clientConn net.Conn) (string, []byte, error) {
-	// Set read deadline
-	clientConn.SetReadDeadline(time.Now().Add(5 * time.Second))
-	defer clientConn.SetReadDeadline(time.Time{})
-
-	// Read message length (4 bytes)
-	lengthBuf := make([]byte, 4)
-	if _, err := io.ReadFull(clientConn, lengthBuf); err != nil {
-		return "", nil, fmt.Errorf("failed to read message length: %v", err)
-	}
-
-	// Calculate message length (minus the length field itself)
-	messageLength := int(lengthBuf[0])<<24 | int(lengthBuf[1])<<16 | int(lengthBuf[2])<<8 | int(lengthBuf[3])
-	messageLength -= 4
-
-	if messageLength <= 0 || messageLength > 8192 {
-		return "", nil, fmt.Errorf("invalid message length: %d", messageLength)
-	}
-
-	// Read the protocol version (4 bytes)
-	versionBuf := make([]byte, 4)
-	if _, err := io.ReadFull(clientConn, versionBuf); err != nil {
-		return "", nil, fmt.Errorf("failed to read protocol version: %v", err)
-	}
-
-	// Parse version
-	version := int(versionBuf[0])<<24 | int(versionBuf[1])<<16 | int(versionBuf[2])<<8 | int(versionBuf[3])
-
-	// Check if it's an SSL request (80877103)
-	if version == 80877103 {
-		log.Printf("Client requested SSL connection")
-		
-		// Send 'N' to indicate we don't support SSL
-		_, err := clientConn.Write([]byte{"N"[0]})
-		if err != nil {
-			return "", nil, fmt.Errorf("failed to send SSL rejection: %v", err)
-		}
-		
-		// Read the regular startup message that should follow
-		return s.readStartupMessage(clientConn)
-	}
-	
-	// Check that it's a standard startup message (version 196608)
-	if version != 196608 {
-		return "", nil, fmt.Errorf("unexpected protocol version: %d", version)
-	}
-
-	// Read the parameters (messageLength - 4 bytes)
-	paramBuf := make([]byte, messageLength-4)
-	if _, err := io.ReadFull(clientConn, paramBuf); err != nil {
-		return "", nil, fmt.Errorf("failed to read parameters: %v", err)
-	}
-
-	// Combine all parts into complete message for later forwarding
-	startupPacket := append(lengthBuf, versionBuf...)
-	startupPacket = append(startupPacket, paramBuf...)
-
-	// Parse parameters to find username
-	var username string
-	i := 0
-	for i < len(paramBuf) {
-		// Find parameter name (null-terminated)
-		nameStart := i
-		for i < len(paramBuf) && paramBuf[i] != 0 {
-			i++
-		}
-
-		if i >= len(paramBuf) {
-			break // Invalid format
-		}
-
-		paramName := string(paramBuf[nameStart:i])
-		i++ // Skip null terminator
-
-		// Find parameter value (null-terminated)
-		valueStart := i
-		for i < len(paramBuf) && paramBuf[i] != 0 {
-			i++
-		}
-
-		if i >= len(paramBuf) {
-			break // Invalid format
-		}
-
-		paramValue := string(paramBuf[valueStart:i])
-		i++ // Skip null terminator
-
-		// Check if this is the user parameter
-		if paramName == "user" {
-			username = paramValue
-			break
-		}
-	}
-
-	if username == "" {
-		return "", nil, fmt.Errorf("username not found in startup message")
-	}
-
-	return username, startupPacket, nil
-}
-
-// readStartupMessage reads a standard PostgreSQL startup message after handling SSL negotiation
-func (s *Server) readStartupMessage(clientConn net.Conn) (string, []byte, error) {
-	// Read message length (4 bytes)
-	lengthBuf := make([]byte, 4)
-	if _, err := io.ReadFull(clientConn, lengthBuf); err != nil {
-		return "", nil, fmt.Errorf("failed to read message length: %v", err)
-	}
-
-	// Calculate message length (minus the length field itself)
-	messageLength := int(lengthBuf[0])<<24 | int(lengthBuf[1])<<16 | int(lengthBuf[2])<<8 | int(lengthBuf[3])
-	messageLength -= 4
-
-	if messageLength <= 0 || messageLength > 8192 {
-		return "", nil, fmt.Errorf("invalid message length: %d", messageLength)
-	}
-
-	// Read the protocol version (4 bytes)
-	versionBuf := make([]byte, 4)
-	if _, err := io.ReadFull(clientConn, versionBuf); err != nil {
-		return "", nil, fmt.Errorf("failed to read protocol version: %v", err)
-	}
-
-	// Check that it's a startup message (version 196608)
-	version := int(versionBuf[0])<<24 | int(versionBuf[1])<<16 | int(versionBuf[2])<<8 | int(versionBuf[3])
-	if version != 196608 {
-		return "", nil, fmt.Errorf("unexpected protocol version after SSL negotiation: %d", version)
-	}
-	
-	// Read the parameters (messageLength - 4 bytes)
-	paramBuf := make([]byte, messageLength-4)
-	if _, err := io.ReadFull(clientConn, paramBuf); err != nil {
-		return "", nil, fmt.Errorf("failed to read parameters: %v", err)
-	}
-
-	// Combine all parts into complete message for later forwarding
-	startupPacket := append(lengthBuf, versionBuf...)
-	startupPacket = append(startupPacket, paramBuf...)
-
-	// Parse parameters to find username
-	var username string
-	i := 0
-	for i < len(paramBuf) {
-		// Find parameter name (null-terminated)
-		nameStart := i
-		for i < len(paramBuf) && paramBuf[i] != 0 {
-			i++
-		}
-
-		if i >= len(paramBuf) {
-			break // Invalid format
-		}
-
-		paramName := string(paramBuf[nameStart:i])
-		i++ // Skip null terminator
-
-		// Find parameter value (null-terminated)
-		valueStart := i
-		for i < len(paramBuf) && paramBuf[i] != 0 {
-			i++
-		}
-
-		if i >= len(paramBuf) {
-			break // Invalid format
-		}
-
-		paramValue := string(paramBuf[valueStart:i])
-		i++ // Skip null terminator
-
-		// Check if this is the user parameter
-		if paramName == "user" {
-			username = paramValue
-			break
-		}
-	}
-
-	if username == "" {
-		return "", nil, fmt.Errorf("username not found in startup message")
-	}
-
-	return username, startupPacket, nil
-}
-
 // sendErrorToClient sends a PostgreSQL error message to the client
 func (s *Server) sendErrorToClient(conn net.Conn, message string) {
 	// 'E' for error message
@@ -563,3 +430,12 @@ func (s *Server) sendErrorToClient(conn net.Conn, message string) {
 func (s *Server) Wait() {
 	s.connections.Wait()
 }
+
+// dialer returns the dialer for backend connections, with the connection timeout and
+// keepalive period of the config
+func (s *Server) dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   s.config.ConnectionTimeout,
+		KeepAlive: s.config.KeepAlive,
+	}
+}
@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+)
+
+// How long a client gets to take the shutdown notice before its connection is closed
+const shutdownNoticeTimeout = 5 * time.Second
+
+// shutdownNotice returns the FATAL 57P01 (admin_shutdown) ErrorResponse PostgreSQL itself
+// sends to sessions it closes on shutdown, so drivers report a lost connection and
+// reconnect instead of seeing a reset
+func shutdownNotice() []byte {
+	fields := []struct {
+		code  byte
+		value string
+	}{
+		{'S', "FATAL"},
+		{'V', "FATAL"},
+		{'C', "57P01"},
+		{'M', "terminating connection due to server shutdown"},
+	}
+
+	var body []byte
+	for _, field := range fields {
+		body = append(body, field.code)
+		body = append(body, field.value...)
+		body = append(body, 0)
+	}
+	body = append(body, 0) // End of the fields
+
+	packet := []byte{'E', 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(packet[1:], uint32(len(body)+4))
+	return append(packet, body...)
+}
+
+// closeForShutdown stops proxying a session and sends the client the shutdown notice. The
+// server -> client flow, which closes serverDone when it returns, is stopped first so the
+// notice doesn't land in the middle of a forwarded message.
+func closeForShutdown(clientConn, serverConn net.Conn, serverDone <-chan struct{}) {
+	clientConn.SetWriteDeadline(time.Now().Add(shutdownNoticeTimeout))
+	serverConn.Close()
+	<-serverDone
+	clientConn.Write(shutdownNotice())
+}
@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+const (
+	// protocolVersion3 is the version code of a standard startup message
+	protocolVersion3 = 196608
+	// sslRequestCode is the version code of an SSLRequest
+	sslRequestCode = 80877103
+)
+
+// readStartupMessage reads the client's startup message, declining SSL first if the client
+// asks for it. It returns the startup parameters and the raw packet to forward to the server.
+func (s *Server) readStartupMessage(clientConn net.Conn) (map[string]string, []byte, error) {
+	clientConn.SetReadDeadline(time.Now().Add(s.config.HandshakeTimeout))
+	defer clientConn.SetReadDeadline(time.Time{})
+
+	version, startupPacket, err := readStartupPacket(clientConn, s.config.MaxStartupPacketBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if version == sslRequestCode {
+		log.Printf("Client requested SSL connection")
+
+		// Send 'N' to indicate we don't support SSL
+		if _, err := clientConn.Write([]byte{'N'}); err != nil {
+			return nil, nil, fmt.Errorf("failed to send SSL rejection: %v", err)
+		}
+
+		// Read the regular startup message that should follow
+		version, startupPacket, err = readStartupPacket(clientConn, s.config.MaxStartupPacketBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if version != protocolVersion3 {
+			return nil, nil, fmt.Errorf("unexpected protocol version after SSL negotiation: %d", version)
+		}
+	}
+
+	if version != protocolVersion3 {
+		return nil, nil, fmt.Errorf("unexpected protocol version: %d", version)
+	}
+
+	params, err := parseStartupParameters(startupPacket[8:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if params["user"] == "" {
+		return nil, nil, fmt.Errorf("username not found in startup message")
+	}
+
+	return params, startupPacket, nil
+}
+
+// readStartupPacket reads one length-prefixed startup packet of at most maxLength bytes after
+// the length field and returns its version code together with the complete packet, length
+// field included. The length is checked before anything is allocated.
+func readStartupPacket(conn net.Conn, maxLength int) (int, []byte, error) {
+	lengthBuf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, lengthBuf); err != nil {
+		return 0, nil, fmt.Errorf("failed to read message length: %v", err)
+	}
+
+	// The length includes the length field itself
+	messageLength := int(binary.BigEndian.Uint32(lengthBuf)) - 4
+	if messageLength < 4 {
+		return 0, nil, fmt.Errorf("invalid message length: %d", messageLength)
+	}
+	if messageLength > maxLength {
+		return 0, nil, fmt.Errorf("startup message of %d bytes exceeds max_startup_packet_bytes (%d)", messageLength, maxLength)
+	}
+
+	packet := make([]byte, 4+messageLength)
+	copy(packet, lengthBuf)
+	if _, err := io.ReadFull(conn, packet[4:]); err != nil {
+		return 0, nil, fmt.Errorf("failed to read startup message: %v", err)
+	}
+
+	return int(binary.BigEndian.Uint32(packet[4:8])), packet, nil
+}
+
+// parseStartupParameters parses the parameters of a startup message, which are
+// null-terminated name/value pairs followed by a final null byte
+func parseStartupParameters(data []byte) (map[string]string, error) {
+	params := make(map[string]string)
+
+	i := 0
+	for {
+		if i >= len(data) {
+			return nil, errors.New("startup parameters are not terminated")
+		}
+
+		// An empty name is the terminator
+		if data[i] == 0 {
+			if i != len(data)-1 {
+				return nil, errors.New("unexpected data after startup parameters")
+			}
+			return params, nil
+		}
+
+		name, next, err := readCString(data, i)
+		if err != nil {
+			return nil, fmt.Errorf("invalid startup parameter name: %v", err)
+		}
+
+		value, next, err := readCString(data, next)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for startup parameter %q: %v", name, err)
+		}
+
+		params[name] = value
+		i = next
+	}
+}
+
+// readCString reads a null-terminated string starting at offset and returns it with the
+// offset just past its terminator
+func readCString(data []byte, offset int) (string, int, error) {
+	for i := offset; i < len(data); i++ {
+		if data[i] == 0 {
+			return string(data[offset:i]), i + 1, nil
+		}
+	}
+	return "", 0, errors.New("missing null terminator")
+}
@@ -0,0 +1,225 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/postgresql/pkg/config"
+)
+
+// startupParams encodes name/value pairs as the parameters of a startup message
+func startupParams(pairs ...string) []byte {
+	var data []byte
+	for _, s := range pairs {
+		data = append(data, s...)
+		data = append(data, 0)
+	}
+	return append(data, 0)
+}
+
+// startupPacket builds a length-prefixed startup packet with a version code and body
+func startupPacket(version int, body []byte) []byte {
+	packet := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(packet, uint32(8+len(body)))
+	binary.BigEndian.PutUint32(packet[4:], uint32(version))
+	return append(packet, body...)
+}
+
+func TestParseStartupParameters(t *testing.T) {
+	params, err := parseStartupParameters(startupParams(
+		"user", "app", "database", "orders", "application_name", "psql", "options", "",
+	))
+	if err != nil {
+		t.Fatalf("parseStartupParameters: %v", err)
+	}
+
+	want := map[string]string{"user": "app", "database": "orders", "application_name": "psql", "options": ""}
+	if len(params) != len(want) {
+		t.Errorf("params = %v, want %v", params, want)
+	}
+	for name, value := range want {
+		if got, ok := params[name]; !ok || got != value {
+			t.Errorf("params[%q] = %q, want %q", name, got, value)
+		}
+	}
+
+	params, err = parseStartupParameters([]byte{0})
+	if err != nil || len(params) != 0 {
+		t.Errorf("no parameters: %v, %v, want an empty map", params, err)
+	}
+}
+
+func TestParseStartupParametersMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "empty", data: nil, wantErr: "not terminated"},
+		{name: "no final terminator", data: []byte("user\x00app\x00"), wantErr: "not terminated"},
+		{name: "name without terminator", data: []byte("user"), wantErr: "invalid startup parameter name"},
+		{name: "value without terminator", data: []byte("user\x00app"), wantErr: `invalid value for startup parameter "user"`},
+		{name: "trailing data", data: []byte("user\x00app\x00\x00junk"), wantErr: "unexpected data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseStartupParameters(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseStartupParameters error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// startupServer returns a server with the default handshake settings
+func startupServer() *Server {
+	return &Server{config: &config.Config{HandshakeTimeout: time.Second, MaxStartupPacketBytes: 10000}}
+}
+
+func TestReadStartupMessage(t *testing.T) {
+	client, proxy := net.Pipe()
+	defer client.Close()
+	defer proxy.Close()
+
+	packet := startupPacket(protocolVersion3, startupParams("user", "app", "database", "orders"))
+	go client.Write(packet)
+
+	params, raw, err := startupServer().readStartupMessage(proxy)
+	if err != nil {
+		t.Fatalf("readStartupMessage: %v", err)
+	}
+	if params["user"] != "app" || params["database"] != "orders" {
+		t.Errorf("params = %v, want user app and database orders", params)
+	}
+	if string(raw) != string(packet) {
+		t.Error("the packet to forward differs from the one the client sent")
+	}
+}
+
+func TestReadStartupMessageDeclinesSSL(t *testing.T) {
+	client, proxy := net.Pipe()
+	defer client.Close()
+	defer proxy.Close()
+
+	packet := startupPacket(protocolVersion3, startupParams("user", "app"))
+	answer := make(chan byte, 1)
+	go func() {
+		client.Write(startupPacket(sslRequestCode, nil))
+		buf := make([]byte, 1)
+		client.Read(buf)
+		answer <- buf[0]
+		client.Write(packet)
+	}()
+
+	params, raw, err := startupServer().readStartupMessage(proxy)
+	if err != nil {
+		t.Fatalf("readStartupMessage: %v", err)
+	}
+	if got := <-answer; got != 'N' {
+		t.Errorf("answered the SSL request with %q, want N", got)
+	}
+	if params["user"] != "app" || string(raw) != string(packet) {
+		t.Errorf("params = %v, want the startup message after the SSL request", params)
+	}
+}
+
+func TestReadStartupMessageRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		packet  []byte
+		wantErr string
+	}{
+		{name: "no user", packet: startupPacket(protocolVersion3, startupParams("database", "orders")), wantErr: "username not found"},
+		{name: "unknown protocol version", packet: startupPacket(131072, startupParams("user", "app")), wantErr: "unexpected protocol version"},
+		{name: "malformed parameters", packet: startupPacket(protocolVersion3, []byte("user\x00app")), wantErr: "invalid value"},
+		{name: "length too short", packet: []byte{0, 0, 0, 6, 0, 0}, wantErr: "invalid message length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, proxy := net.Pipe()
+			defer client.Close()
+			defer proxy.Close()
+			go client.Write(tt.packet)
+
+			_, _, err := startupServer().readStartupMessage(proxy)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readStartupMessage error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// slowStartup sends a startup packet in two halves delay apart
+func slowStartup(conn net.Conn, packet []byte, delay time.Duration) {
+	time.Sleep(delay)
+	conn.Write(packet[:4])
+	time.Sleep(delay)
+	conn.Write(packet[4:])
+}
+
+func TestSlowStartupWithinHandshakeTimeout(t *testing.T) {
+	client, proxy := net.Pipe()
+	defer client.Close()
+	defer proxy.Close()
+	go slowStartup(client, startupPacket(protocolVersion3, startupParams("user", "app")), 150*time.Millisecond)
+
+	server := &Server{config: &config.Config{HandshakeTimeout: 2 * time.Second, MaxStartupPacketBytes: 10000}}
+	params, _, err := server.readStartupMessage(proxy)
+	if err != nil {
+		t.Fatalf("readStartupMessage: %v", err)
+	}
+	if params["user"] != "app" {
+		t.Errorf("user = %q, want app", params["user"])
+	}
+}
+
+func TestSlowStartupPastHandshakeTimeout(t *testing.T) {
+	client, proxy := net.Pipe()
+	defer client.Close()
+	defer proxy.Close()
+	go slowStartup(client, startupPacket(protocolVersion3, startupParams("user", "app")), 300*time.Millisecond)
+
+	server := &Server{config: &config.Config{HandshakeTimeout: 200 * time.Millisecond, MaxStartupPacketBytes: 10000}}
+	if _, _, err := server.readStartupMessage(proxy); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("readStartupMessage error = %v, want a timeout", err)
+	}
+}
+
+func TestStartupPacketSizeCap(t *testing.T) {
+	// application_name pads the message to just over the 8192 byte default
+	body := startupParams("user", "app", "application_name", "")
+	body = startupParams("user", "app", "application_name", strings.Repeat("a", 8193-4-len(body)))
+	packet := startupPacket(protocolVersion3, body)
+
+	tests := []struct {
+		name    string
+		max     int
+		wantErr bool
+	}{
+		{name: "default cap", max: 8192, wantErr: true},
+		{name: "raised cap", max: 16384},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, proxy := net.Pipe()
+			defer client.Close()
+			defer proxy.Close()
+			go client.Write(packet)
+
+			server := &Server{config: &config.Config{HandshakeTimeout: 2 * time.Second, MaxStartupPacketBytes: tt.max}}
+			params, _, err := server.readStartupMessage(proxy)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "startup message of 8193 bytes exceeds") {
+					t.Errorf("readStartupMessage error = %v, want the 8193 byte message rejected", err)
+				}
+				return
+			}
+			if err != nil || params["user"] != "app" {
+				t.Errorf("readStartupMessage = %v, %v, want user app", params, err)
+			}
+		})
+	}
+}
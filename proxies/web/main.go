@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -27,6 +28,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
 
 	// Create context that listens for termination signals
 	ctx, cancel := context.WithCancel(context.Background())
@@ -46,12 +50,12 @@ func main() {
 		runScaleToZeroTimer(ctx, cfg)
 	} else {
 		// Run in normal proxy mode
-		runProxyServer(ctx, cfg)
+		runProxyServer(ctx, cfg, *configPath)
 	}
 }
 
 // runProxyServer runs the proxy server
-func runProxyServer(ctx context.Context, cfg *config.Config) {
+func runProxyServer(ctx context.Context, cfg *config.Config, configPath string) {
 	// Check for required configuration
 	if cfg.Email == "" {
 		log.Println("Warning: No email provided for ACME registration. Certificates will be requested without an email address.")
@@ -59,7 +63,11 @@ func runProxyServer(ctx context.Context, cfg *config.Config) {
 
 	// Log configuration
 	log.Printf("Starting proxy server...")
-	log.Printf("Watching all namespaces for services with label selector: %s", cfg.LabelSelector)
+	if len(cfg.WatchNamespaces) > 0 {
+		log.Printf("Watching namespaces %s for services with label selector: %s", strings.Join(cfg.WatchNamespaces, ", "), strings.Join(cfg.Selectors(), " or "))
+	} else {
+		log.Printf("Watching all namespaces for services with label selector: %s", strings.Join(cfg.Selectors(), " or "))
+	}
 	log.Printf("HTTP server listening on %s", cfg.HTTPAddr)
 	if cfg.EnableHTTPS {
 		log.Printf("HTTPS server listening on %s", cfg.HTTPSAddr)
@@ -75,6 +83,30 @@ func runProxyServer(ctx context.Context, cfg *config.Config) {
 		log.Fatalf("Failed to create proxy server: %v", err)
 	}
 
+	// Re-read the config file on SIGHUP, invalid files are ignored
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go func() {
+		for range reloadChan {
+			if configPath == "" {
+				log.Println("Received SIGHUP, but no config file was given")
+				continue
+			}
+
+			log.Printf("Received SIGHUP, reloading %s", configPath)
+			next, err := config.Load(configPath)
+			if err != nil {
+				log.Printf("Failed to reload configuration: %v", err)
+				continue
+			}
+			if err := next.Validate(); err != nil {
+				log.Printf("Ignoring invalid configuration:\n%v", err)
+				continue
+			}
+			server.Reload(next)
+		}
+	}()
+
 	// Start the server
 	if err := server.Start(ctx); err != nil {
 		log.Fatalf("Server error: %v", err)
@@ -84,15 +116,20 @@ func runProxyServer(ctx context.Context, cfg *config.Config) {
 // runScaleToZeroTimer runs the scale-to-zero timer service
 func runScaleToZeroTimer(ctx context.Context, cfg *config.Config) {
 	log.Println("Starting scale-to-zero timer service...")
-	log.Printf("Watching all namespaces for services with label selector: %s", cfg.LabelSelector)
+	if len(cfg.WatchNamespaces) > 0 {
+		log.Printf("Watching namespaces %s for services with label selector: %s", strings.Join(cfg.WatchNamespaces, ", "), strings.Join(cfg.Selectors(), " or "))
+	} else {
+		log.Printf("Watching all namespaces for services with label selector: %s", strings.Join(cfg.Selectors(), " or "))
+	}
 	log.Printf("Idle timeout: %d minutes", cfg.IdleTimeoutMinutes)
 	log.Printf("Check interval: %d seconds", cfg.CheckIntervalSeconds)
 
 	// Create Kubernetes client
-	kubeClient, err := kubernetes.NewClient(cfg.KubeConfigPath, cfg.LabelSelector)
+	kubeClient, err := kubernetes.NewClient(cfg.KubeConfigPath, cfg.Selectors())
 	if err != nil {
 		log.Fatalf("Failed to create Kubernetes client: %v", err)
 	}
+	kubeClient.SetWatchNamespaces(cfg.WatchNamespaces)
 
 	// Create Redis client
 	redisClient, err := redis.NewClient(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
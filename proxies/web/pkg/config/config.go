@@ -3,6 +3,8 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"reflect"
+	"strings"
 )
 
 // Config represents the application configuration
@@ -12,10 +14,42 @@ type Config struct {
 	HTTPSAddr   string `json:"https_addr"`
 	EnableHTTPS bool   `json:"enable_https"`
 
+	// ReusePort binds the HTTP and HTTPS listeners with SO_REUSEPORT, so several proxy
+	// processes can share the ports and the kernel spreads connections between them.
+	// ListenBacklog is the length of their accept queues, the system default when 0, capped
+	// by the kernel at net.core.somaxconn. Both are Linux only.
+	ReusePort     bool `json:"reuse_port"`
+	ListenBacklog int  `json:"listen_backlog"`
+
 	// ACME (Let's Encrypt) configuration
 	Email         string `json:"email"`
 	AcmeServerURL string `json:"acme_server_url"`
 
+	// AcmeAccountSecret is the secret in system-apps holding the ACME account key and
+	// registration, so restarts reuse the same account
+	AcmeAccountSecret string `json:"acme_account_secret"`
+
+	// RateLimitCooldownMinutes is how long certificate requests for a domain are paused after
+	// a Let's Encrypt rate limit error that has no retry time
+	RateLimitCooldownMinutes int `json:"rate_limit_cooldown_minutes"`
+
+	// RenewBeforeDays is how many days before expiry certificates are renewed
+	RenewBeforeDays int `json:"renew_before_days"`
+
+	// AdminAddr is the listen address of the admin API, disabled when empty. Without
+	// AdminToken it has no authentication, so bind it to a private interface, e.g.
+	// "127.0.0.1:9090".
+	AdminAddr string `json:"admin_addr"`
+
+	// AdminToken must be sent as a bearer token with every admin API request when set.
+	// The routing table is only served with a token.
+	AdminToken string `json:"admin_token"`
+
+	// DefaultCertFile and DefaultKeyFile are the certificate served to TLS clients that
+	// don't send SNI. Without them those clients get the wildcard certificate, if enabled.
+	DefaultCertFile string `json:"default_cert_file"`
+	DefaultKeyFile  string `json:"default_key_file"`
+
 	// Wildcard certificate configuration
 	WildcardDomain     string `json:"wildcard_domain"`      // e.g., "deployra.app" for *.deployra.app
 	CloudflareAPIToken string `json:"cloudflare_api_token"` // Cloudflare API token with DNS edit permissions
@@ -25,12 +59,51 @@ type Config struct {
 	KubeConfigPath string `json:"kube_config_path"`
 	LabelSelector  string `json:"label_selector"`
 
+	// LabelSelectors are watched besides LabelSelector, a service matching any of them
+	// is picked up
+	LabelSelectors []string `json:"label_selectors"`
+
+	// WatchNamespaces limits the service watch to these namespaces, so the proxy only needs
+	// namespaced RBAC. All namespaces are watched when empty.
+	WatchNamespaces []string `json:"watch_namespaces"`
+
+	// ServiceTypes are the values of the type label routed by the proxy, the label
+	// selector must match them as well
+	ServiceTypes []string `json:"service_types"`
+
+	// Debug enables debug logging
+	Debug bool `json:"debug"`
+
 	// Proxy settings
 	ProxyReadTimeout      int `json:"proxy_read_timeout"`
 	ProxyWriteTimeout     int `json:"proxy_write_timeout"`
 	WebSocketReadTimeout  int `json:"websocket_read_timeout"`
 	WebSocketWriteTimeout int `json:"websocket_write_timeout"`
 
+	// EnableTunnels allows HTTP CONNECT tunnels to services labelled tunnelEnabled
+	EnableTunnels bool `json:"enable_tunnels"`
+
+	// UpstreamResponseTimeout is the time in seconds to wait for response headers
+	// from a backend on non-WebSocket requests before returning 504
+	UpstreamResponseTimeout int `json:"upstream_response_timeout"`
+
+	// SlowRequestThresholdMs logs a warning for proxied requests that take longer, disabled
+	// when 0. LogSlowRequestHeaders adds the request headers, except credentials, to it.
+	SlowRequestThresholdMs int  `json:"slow_request_threshold_ms"`
+	LogSlowRequestHeaders  bool `json:"log_slow_request_headers"`
+
+	// TrustedProxyHeader holds the client IP set by an upstream proxy, e.g. "CF-Connecting-IP"
+	// or "X-Forwarded-For". It is only read on connections from TrustedProxyCIDRs.
+	TrustedProxyHeader string   `json:"trusted_proxy_header"`
+	TrustedProxyCIDRs  []string `json:"trusted_proxy_cidrs"`
+
+	// Object storage holding static sites, any S3-compatible endpoint addressed path-style.
+	// Without an access key, objects are fetched unsigned from public buckets.
+	ObjectStorageEndpoint        string `json:"object_storage_endpoint"`
+	ObjectStorageRegion          string `json:"object_storage_region"`
+	ObjectStorageAccessKeyID     string `json:"object_storage_access_key_id"`
+	ObjectStorageSecretAccessKey string `json:"object_storage_secret_access_key"`
+
 	// Redis configuration for scale-to-zero feature
 	RedisAddr     string `json:"redis_addr"`
 	RedisPassword string `json:"redis_password"`
@@ -44,23 +117,30 @@ type Config struct {
 // DefaultConfig returns a default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		HTTPAddr:              ":80",
-		HTTPSAddr:             ":443",
-		EnableHTTPS:           true,
-		AcmeServerURL:         "https://acme-v02.api.letsencrypt.org/directory",
-		LabelSelector:         "managedBy=kubestrator,type=web",
-		RedisAddr:             "redis:6379",
-		RedisPassword:         "",
-		RedisDB:               0,
-		IdleTimeoutMinutes:    10, // Default 30 minutes for scale-to-zero
-		CheckIntervalSeconds:  60, // Check every 60 seconds
-		ProxyReadTimeout:      30,
-		ProxyWriteTimeout:     30,
-		WebSocketReadTimeout:  3600, // 1 hour for websockets
-		WebSocketWriteTimeout: 3600, // 1 hour for websockets
-		WildcardDomain:        "",
-		CloudflareAPIToken:    "",
-		EnableWildcard:        true,
+		HTTPAddr:                 ":80",
+		HTTPSAddr:                ":443",
+		EnableHTTPS:              true,
+		AcmeServerURL:            "https://acme-v02.api.letsencrypt.org/directory",
+		AcmeAccountSecret:        "acme-account",
+		RateLimitCooldownMinutes: 60,
+		RenewBeforeDays:          30,
+		LabelSelector:            "managedBy=kubestrator,type in (web,static)",
+		ServiceTypes:             []string{"web", "static"},
+		RedisAddr:                "redis:6379",
+		RedisPassword:            "",
+		RedisDB:                  0,
+		IdleTimeoutMinutes:       10, // Default 30 minutes for scale-to-zero
+		CheckIntervalSeconds:     60, // Check every 60 seconds
+		ProxyReadTimeout:         30,
+		ProxyWriteTimeout:        30,
+		WebSocketReadTimeout:     3600, // 1 hour for websockets
+		WebSocketWriteTimeout:    3600, // 1 hour for websockets
+		UpstreamResponseTimeout:  60,
+		WildcardDomain:           "",
+		CloudflareAPIToken:       "",
+		EnableWildcard:           true,
+		ObjectStorageEndpoint:    "https://s3.amazonaws.com",
+		ObjectStorageRegion:      "us-east-1",
 	}
 }
 
@@ -84,3 +164,33 @@ func Load(configPath string) (*Config, error) {
 
 	return config, nil
 }
+
+// ChangedFields returns the json names of the settings that differ between two configurations
+func ChangedFields(a, b *Config) []string {
+	var changed []string
+
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			name, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("json"), ",")
+			changed = append(changed, name)
+		}
+	}
+
+	return changed
+}
+
+// Selectors returns the label selectors to watch, label_selector followed by label_selectors.
+// A service matching any of them is picked up.
+func (c *Config) Selectors() []string {
+	var selectors []string
+	seen := make(map[string]bool)
+	for _, selector := range append([]string{c.LabelSelector}, c.LabelSelectors...) {
+		selector = strings.TrimSpace(selector)
+		if selector != "" && !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
+	}
+	return selectors
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangedFields(t *testing.T) {
+	a, b := DefaultConfig(), DefaultConfig()
+	if changed := ChangedFields(a, b); len(changed) != 0 {
+		t.Errorf("ChangedFields of equal configs = %v, want none", changed)
+	}
+
+	b.UpstreamResponseTimeout = 5
+	b.ServiceTypes = []string{"web"}
+	b.HTTPAddr = ":8080"
+	want := []string{"http_addr", "service_types", "upstream_response_timeout"}
+	if changed := ChangedFields(a, b); !reflect.DeepEqual(changed, want) {
+		t.Errorf("ChangedFields = %v, want %v", changed, want)
+	}
+}
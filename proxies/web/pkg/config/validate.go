@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Validate checks for missing settings, values out of range and settings that depend on
+// each other, and returns every problem found so a config can be fixed in one go
+func (c *Config) Validate() error {
+	var errs []error
+
+	checkAddr := func(key, addr string) {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %v", key, addr, err))
+		}
+	}
+	checkNotNegative := func(key string, value int) {
+		if value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", key, value))
+		}
+	}
+
+	if c.HTTPAddr == "" {
+		errs = append(errs, errors.New("http_addr is required"))
+	} else {
+		checkAddr("http_addr", c.HTTPAddr)
+	}
+	checkNotNegative("listen_backlog", c.ListenBacklog)
+
+	if c.EnableHTTPS {
+		if c.HTTPSAddr == "" {
+			errs = append(errs, errors.New("enable_https requires https_addr"))
+		} else {
+			checkAddr("https_addr", c.HTTPSAddr)
+		}
+
+		if u, err := url.Parse(c.AcmeServerURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("acme_server_url %q must be an https URL", c.AcmeServerURL))
+		}
+		if c.AcmeAccountSecret == "" {
+			errs = append(errs, errors.New("enable_https requires acme_account_secret"))
+		}
+
+		// enable_wildcard is on by default and only takes effect with a wildcard_domain
+		if c.EnableWildcard && c.WildcardDomain != "" && c.CloudflareAPIToken == "" {
+			errs = append(errs, errors.New("enable_wildcard with a wildcard_domain requires cloudflare_api_token"))
+		}
+	}
+	checkNotNegative("rate_limit_cooldown_minutes", c.RateLimitCooldownMinutes)
+
+	// Let's Encrypt certificates are valid for 90 days
+	if c.RenewBeforeDays < 1 || c.RenewBeforeDays > 89 {
+		errs = append(errs, fmt.Errorf("renew_before_days must be between 1 and 89, got %d", c.RenewBeforeDays))
+	}
+
+	if c.AdminAddr != "" {
+		checkAddr("admin_addr", c.AdminAddr)
+	}
+
+	if (c.DefaultCertFile == "") != (c.DefaultKeyFile == "") {
+		errs = append(errs, errors.New("default_cert_file and default_key_file must be set together"))
+	}
+
+	if len(c.Selectors()) == 0 {
+		errs = append(errs, errors.New("label_selector or label_selectors is required"))
+	}
+	for _, selector := range c.Selectors() {
+		if _, err := labels.Parse(selector); err != nil {
+			errs = append(errs, fmt.Errorf("invalid label selector %q: %v", selector, err))
+		}
+	}
+
+	for _, namespace := range c.WatchNamespaces {
+		if problems := validation.IsDNS1123Label(namespace); len(problems) > 0 {
+			errs = append(errs, fmt.Errorf("invalid namespace %q in watch_namespaces: %v", namespace, problems[0]))
+		}
+	}
+
+	if len(c.ServiceTypes) == 0 {
+		errs = append(errs, errors.New("service_types must not be empty"))
+	}
+	for _, serviceType := range c.ServiceTypes {
+		if serviceType != "web" && serviceType != "static" {
+			errs = append(errs, fmt.Errorf("unknown service type %q in service_types, must be \"web\" or \"static\"", serviceType))
+		}
+	}
+
+	checkNotNegative("proxy_read_timeout", c.ProxyReadTimeout)
+	checkNotNegative("proxy_write_timeout", c.ProxyWriteTimeout)
+	checkNotNegative("websocket_read_timeout", c.WebSocketReadTimeout)
+	checkNotNegative("websocket_write_timeout", c.WebSocketWriteTimeout)
+	checkNotNegative("upstream_response_timeout", c.UpstreamResponseTimeout)
+	checkNotNegative("slow_request_threshold_ms", c.SlowRequestThresholdMs)
+
+	// Without trusted proxies the header would never be read
+	if c.TrustedProxyHeader != "" && len(c.TrustedProxyCIDRs) == 0 {
+		errs = append(errs, errors.New("trusted_proxy_header requires trusted_proxy_cidrs"))
+	}
+
+	if (c.ObjectStorageAccessKeyID == "") != (c.ObjectStorageSecretAccessKey == "") {
+		errs = append(errs, errors.New("object_storage_access_key_id and object_storage_secret_access_key must be set together"))
+	}
+
+	if c.RedisAddr == "" {
+		errs = append(errs, errors.New("redis_addr is required"))
+	}
+	checkNotNegative("redis_db", c.RedisDB)
+
+	if c.IdleTimeoutMinutes <= 0 {
+		errs = append(errs, fmt.Errorf("idle_timeout_minutes must be positive, got %d", c.IdleTimeoutMinutes))
+	}
+	if c.CheckIntervalSeconds <= 0 {
+		errs = append(errs, fmt.Errorf("check_interval_seconds must be positive, got %d", c.CheckIntervalSeconds))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDefaultConfig(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("default config is invalid: %v", err)
+	}
+}
+
+func TestValidateInvalidConfigs(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{name: "no http address", modify: func(c *Config) { c.HTTPAddr = "" }, want: "http_addr is required"},
+		{name: "address without a port", modify: func(c *Config) { c.HTTPAddr = "localhost" }, want: `invalid http_addr "localhost"`},
+		{name: "https without an address", modify: func(c *Config) { c.HTTPSAddr = "" }, want: "enable_https requires https_addr"},
+		{name: "plain http acme server", modify: func(c *Config) { c.AcmeServerURL = "http://acme.example.com/directory" }, want: "must be an https URL"},
+		{name: "wildcard without a token", modify: func(c *Config) { c.WildcardDomain = "deployra.app" }, want: "requires cloudflare_api_token"},
+		{name: "renewal after expiry", modify: func(c *Config) { c.RenewBeforeDays = 90 }, want: "renew_before_days must be between 1 and 89, got 90"},
+		{name: "certificate without a key", modify: func(c *Config) { c.DefaultCertFile = "/certs/tls.crt" }, want: "must be set together"},
+		{name: "no selector", modify: func(c *Config) { c.LabelSelector = "" }, want: "label_selector or label_selectors is required"},
+		{name: "bad selector", modify: func(c *Config) { c.LabelSelectors = []string{"type in (web"} }, want: `invalid label selector "type in (web"`},
+		{name: "bad namespace", modify: func(c *Config) { c.WatchNamespaces = []string{"Team_A"} }, want: `invalid namespace "Team_A"`},
+		{name: "unknown service type", modify: func(c *Config) { c.ServiceTypes = []string{"worker"} }, want: `unknown service type "worker"`},
+		{name: "negative timeout", modify: func(c *Config) { c.ProxyReadTimeout = -1 }, want: "proxy_read_timeout must not be negative, got -1"},
+		{name: "header without trusted proxies", modify: func(c *Config) { c.TrustedProxyHeader = "X-Forwarded-For" }, want: "trusted_proxy_header requires trusted_proxy_cidrs"},
+		{name: "no redis", modify: func(c *Config) { c.RedisAddr = "" }, want: "redis_addr is required"},
+		{name: "zero idle timeout", modify: func(c *Config) { c.IdleTimeoutMinutes = 0 }, want: "idle_timeout_minutes must be positive, got 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// Settings only checked when the feature they belong to is enabled
+func TestValidateSkipsDisabledFeatures(t *testing.T) {
+	config := DefaultConfig()
+	config.EnableHTTPS = false
+	config.HTTPSAddr = ""
+	config.AcmeAccountSecret = ""
+	config.WildcardDomain = "deployra.app"
+	if err := config.Validate(); err != nil {
+		t.Errorf("HTTPS settings checked with enable_https off: %v", err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	config := DefaultConfig()
+	config.HTTPAddr = ""
+	config.RedisAddr = ""
+	config.CheckIntervalSeconds = -5
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate() accepted an invalid config")
+	}
+	for _, want := range []string{"http_addr is required", "redis_addr is required", "check_interval_seconds must be positive, got -5"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, missing %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoffGrowsWithJitter(t *testing.T) {
+	for failures, ceiling := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second} {
+		for i := 0; i < 20; i++ {
+			probe := &backoff{base: time.Second, max: time.Minute, failures: failures}
+			if d := probe.next(); d < ceiling/2 || d > ceiling {
+				t.Errorf("delay after %d failures = %v, want between %v and %v", failures, d, ceiling/2, ceiling)
+			}
+		}
+	}
+
+	// The delays aren't all equal, replicas spread out their retries
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		probe := &backoff{base: time.Second, max: time.Minute, failures: 5}
+		seen[probe.next()] = true
+	}
+	if len(seen) < 2 {
+		t.Error("20 delays after the same failures were all equal, want jitter")
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	b := &backoff{base: time.Second, max: time.Minute}
+	for i := 0; i < 100; i++ {
+		if d := b.next(); d > time.Minute {
+			t.Fatalf("delay after %d failures = %v, want at most the 1m cap", i, d)
+		}
+	}
+	if d := b.next(); d < 30*time.Second {
+		t.Errorf("delay after many failures = %v, want at least half the cap", d)
+	}
+}
+
+func TestBackoffReset(t *testing.T) {
+	b := &backoff{base: time.Second, max: time.Minute}
+	for i := 0; i < 10; i++ {
+		b.next()
+	}
+
+	b.reset()
+	if d := b.next(); d > time.Second {
+		t.Errorf("delay after a reset = %v, want at most the 1s base", d)
+	}
+}
+
+func TestSleepContextStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if sleepContext(ctx, time.Minute) {
+		t.Error("sleepContext reported a full sleep with a cancelled context")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sleepContext waited %v after the context was cancelled", elapsed)
+	}
+	if !sleepContext(context.Background(), time.Millisecond) {
+		t.Error("sleepContext returned early without a cancellation")
+	}
+}
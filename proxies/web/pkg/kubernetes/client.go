@@ -2,11 +2,14 @@ package kubernetes
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -25,15 +28,60 @@ const (
 	Delete
 )
 
+// Service types the proxy knows how to route
+const (
+	// ServiceTypeWeb is an application server, optionally scaled to zero
+	ServiceTypeWeb = "web"
+	// ServiceTypeStatic serves static files from object storage, or from a backend that
+	// is never scaled from zero
+	ServiceTypeStatic = "static"
+)
+
 // ServiceInfo contains information about a Kubernetes service
 type ServiceInfo struct {
 	Name               string
 	Namespace          string
+	Type               string
 	ProjectID          string
 	ServiceID          string
 	Port               int32
 	Domains            []string
 	ScaleToZeroEnabled bool
+	Stopped            bool
+	TunnelEnabled      bool
+	TunnelTokenHash    string
+
+	// Ports are the ports the Kubernetes service exposes, the only ones a tunnel may reach
+	Ports []int32
+
+	// AcmeChallenge is the ACME challenge used for the service's certificates, "http" or "dns"
+	AcmeChallenge string
+
+	// StaticBucket and StaticPrefix locate the files of a static site in object storage
+	StaticBucket string
+	StaticPrefix string
+
+	// AccessLogSampleRate is the fraction of successful requests written to the access log
+	AccessLogSampleRate float64
+
+	// H2C makes the proxy speak cleartext HTTP/2 to the service, e.g. for gRPC servers
+	H2C bool
+
+	// Streaming makes the proxy flush every response of the service as it arrives
+	Streaming bool
+
+	// AllowedMethods are the HTTP methods the service accepts, all methods when empty
+	AllowedMethods []string
+
+	// RedirectFrom is a host the proxy answers with a 301 to RedirectTo, e.g. the www form
+	// of a custom domain
+	RedirectFrom string
+	RedirectTo   string
+
+	// ReadTimeout and WriteTimeout replace the global WebSocket timeouts for requests to the
+	// service, e.g. for large uploads, when not zero
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
 }
 
 // ServiceChangeCallback is a function called when services change
@@ -41,16 +89,22 @@ type ServiceChangeCallback func(ServiceInfoAction, string, *ServiceInfo)
 
 // Client represents a Kubernetes client
 type Client struct {
-	clientset      *kubernetes.Clientset
-	labelSelector  string
+	clientset      kubernetes.Interface
+	labelSelectors []string
 	watchContext   context.Context
 	watchCancel    context.CancelFunc
-	watcherStarted bool
+	watcherStarted atomic.Bool
+	namespaces     []string
+	serviceTypes   map[string]bool
+	debug          atomic.Bool
+
+	// Namespaces that haven't been listed yet since the watchers started
+	pendingLists atomic.Int32
 }
 
 // OK !!!
 // NewClient creates a new Kubernetes client
-func NewClient(kubeConfigPath, labelSelector string) (*Client, error) {
+func NewClient(kubeConfigPath string, labelSelectors []string) (*Client, error) {
 	var config *rest.Config
 	var err error
 
@@ -91,43 +145,106 @@ func NewClient(kubeConfigPath, labelSelector string) (*Client, error) {
 		return nil, fmt.Errorf("failed to create Kubernetes clientset: %v", err)
 	}
 
+	return NewClientForClientset(clientset, labelSelectors), nil
+}
+
+// NewClientForClientset creates a Kubernetes client on top of an existing clientset
+func NewClientForClientset(clientset kubernetes.Interface, labelSelectors []string) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Client{
 		clientset:      clientset,
-		labelSelector:  labelSelector,
+		labelSelectors: labelSelectors,
 		watchContext:   ctx,
 		watchCancel:    cancel,
-		watcherStarted: false,
-	}, nil
+		serviceTypes:   map[string]bool{ServiceTypeWeb: true},
+	}
+}
+
+// SetServiceTypes sets the service types the watcher accepts, services of other types are skipped
+func (c *Client) SetServiceTypes(types []string) {
+	c.serviceTypes = make(map[string]bool, len(types))
+	for _, serviceType := range types {
+		c.serviceTypes[serviceType] = true
+	}
+}
+
+// SetDebug enables debug logging, such as services being skipped
+func (c *Client) SetDebug(debug bool) {
+	c.debug.Store(debug)
+}
+
+// debugf logs only when debug logging is enabled
+func (c *Client) debugf(format string, args ...interface{}) {
+	if c.debug.Load() {
+		log.Printf("[debug] "+format, args...)
+	}
 }
 
 // OK !!
-// StartWatching starts watching for service changes in all namespaces
+// StartWatching starts watching for service changes in the configured namespaces
 func (c *Client) StartWatching(callback ServiceChangeCallback) error {
-	if c.watcherStarted {
+	if c.watcherStarted.Load() {
 		return fmt.Errorf("watchers already started")
 	}
 
-	// Start watcher for all namespaces
-	go c.watchServices(callback)
+	// Start one watcher per namespace and label selector, taking turns to run the callback
+	var mu sync.Mutex
+	matched := newMatchedServices(callback)
+	namespaces := c.watchedNamespaces()
+	c.pendingLists.Store(int32(len(namespaces) * len(c.labelSelectors)))
+	for i, labelSelector := range c.labelSelectors {
+		report := matched.callbackFor(i)
+		serialized := func(action ServiceInfoAction, key string, info *ServiceInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			report(action, key, info)
+		}
+		for _, namespace := range namespaces {
+			go c.watchServices(namespace, labelSelector, serialized)
+		}
+	}
 
-	c.watcherStarted = true
+	c.watcherStarted.Store(true)
 	return nil
 }
 
+// SetWatchNamespaces limits the watch to services in the given namespaces, all namespaces
+// are watched when none are given
+func (c *Client) SetWatchNamespaces(namespaces []string) {
+	c.namespaces = namespaces
+}
+
+// watchedNamespaces returns the namespaces to watch, metav1.NamespaceAll when none are set
+func (c *Client) watchedNamespaces() []string {
+	if len(c.namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return c.namespaces
+}
+
+// Synced reports whether the watchers are running and have listed the services of every
+// namespace, so the routing table is complete
+func (c *Client) Synced() bool {
+	return c.watcherStarted.Load() && c.pendingLists.Load() == 0
+}
+
 // StopWatching stops all active watchers
 func (c *Client) StopWatching() {
 	if c.watchCancel != nil {
 		c.watchCancel()
 	}
-	c.watcherStarted = false
+	c.watcherStarted.Store(false)
 }
 
-// watchAllNamespaces watches for service changes across all namespaces
+// watchServices watches for service changes in a namespace, or all of them
 // Uses List + Watch pattern to ensure no services are missed
-func (c *Client) watchServices(callback ServiceChangeCallback) {
-	log.Println("Starting to watch all namespaces for services with label selector:", c.labelSelector)
+func (c *Client) watchServices(namespace, labelSelector string, callback ServiceChangeCallback) {
+	log.Printf("Starting to watch %s for services with label selector: %s", describeNamespace(namespace), labelSelector)
+
+	retry := backoff{base: watchRetryBase, max: watchRetryMax}
+	reported := newReportedServices(callback)
+	synced := false
 
 	for {
 		// Check if context is cancelled
@@ -139,34 +256,51 @@ func (c *Client) watchServices(callback ServiceChangeCallback) {
 
 		// Step 1: List all existing services first
 		listOptions := metav1.ListOptions{
-			LabelSelector: c.labelSelector,
+			LabelSelector: labelSelector,
 		}
 
-		services, err := c.clientset.CoreV1().Services("").List(c.watchContext, listOptions)
+		services, err := c.clientset.CoreV1().Services(namespace).List(c.watchContext, listOptions)
 		if err != nil {
-			log.Printf("Error listing services: %v, retrying in 5 seconds...", err)
-			time.Sleep(5 * time.Second)
+			delay := retry.next()
+			log.Printf("Error listing services: %v, retrying in %v...", err, delay.Round(time.Millisecond))
+			if !sleepContext(c.watchContext, delay) {
+				return
+			}
 			continue
 		}
+		retry.reset()
 
 		// Process all existing services
-		log.Printf("Found %d existing services with label selector: %s", len(services.Items), c.labelSelector)
+		log.Printf("Found %d existing services in %s with label selector: %s", len(services.Items), describeNamespace(namespace), labelSelector)
+		listed := make(map[string]bool, len(services.Items))
 		for _, service := range services.Items {
 			serviceKey, info, err := c.handleServiceChange(&service)
 			if err == nil {
-				callback(Add, serviceKey, info)
-				log.Printf("Loaded existing service: %s with domains: %v", serviceKey, info.Domains)
+				listed[serviceKey] = true
+				if reported.add(serviceKey, info) {
+					log.Printf("Loaded existing service: %s with domains: %v", serviceKey, info.Domains)
+				}
+			} else {
+				c.debugf("Skipping service %s/%s: %v", service.Namespace, service.Name, err)
 			}
 		}
+		reported.prune(listed)
+		if !synced {
+			synced = true
+			c.pendingLists.Add(-1)
+		}
 
 		// Step 2: Start watching from the resource version we got from List
 		listOptions.ResourceVersion = services.ResourceVersion
 		log.Printf("Starting watch from resourceVersion: %s", services.ResourceVersion)
 
-		watcher, err := c.clientset.CoreV1().Services("").Watch(c.watchContext, listOptions)
+		watcher, err := c.clientset.CoreV1().Services(namespace).Watch(c.watchContext, listOptions)
 		if err != nil {
-			log.Printf("Error creating watcher: %v, retrying in 5 seconds...", err)
-			time.Sleep(5 * time.Second)
+			delay := retry.next()
+			log.Printf("Error creating watcher: %v, retrying in %v...", err, delay.Round(time.Millisecond))
+			if !sleepContext(c.watchContext, delay) {
+				return
+			}
 			continue
 		}
 
@@ -190,14 +324,18 @@ func (c *Client) watchServices(callback ServiceChangeCallback) {
 						log.Printf("Service added or modified: %s", service.Name)
 						serviceKey, info, err := c.handleServiceChange(service)
 						if err == nil {
-							callback(Add, serviceKey, info)
+							reported.add(serviceKey, info)
+						} else {
+							c.debugf("Skipping service %s/%s: %v", service.Namespace, service.Name, err)
+							// The service may have been routed before it changed
+							reported.remove(fmt.Sprintf("%s/%s", service.Namespace, service.Name))
 						}
 					}
 				case watch.Deleted:
 					if service, ok := event.Object.(*corev1.Service); ok {
 						log.Printf("Service deleted: %s", service.Name)
 						serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
-						callback(Delete, serviceKey, nil)
+						reported.remove(serviceKey)
 					}
 				}
 			}
@@ -205,8 +343,18 @@ func (c *Client) watchServices(callback ServiceChangeCallback) {
 
 		watcher.Stop()
 		log.Println("Restarting list + watch cycle after brief delay...")
-		time.Sleep(5 * time.Second)
+		if !sleepContext(c.watchContext, retry.next()) {
+			return
+		}
+	}
+}
+
+// describeNamespace names a watched namespace in log messages
+func describeNamespace(namespace string) string {
+	if namespace == metav1.NamespaceAll {
+		return "all namespaces"
 	}
+	return "namespace " + namespace
 }
 
 // handleServiceChange processes a service change event
@@ -222,10 +370,30 @@ func (c *Client) handleServiceChange(service *corev1.Service) (string, *ServiceI
 	serviceID := service.Labels["service"]
 	serviceType := service.Labels["type"]
 	scaleToZeroEnabled := service.Labels["scaleToZeroEnabled"]
+	stopped := service.Labels["stopped"]
+	tunnelEnabled := service.Labels["tunnelEnabled"]
+
+	// A SHA-256 hex digest is too long for a label value, so the tunnel token hash is an annotation
+	tunnelTokenHash := service.Annotations["tunnelTokenHash"]
 
-	// If service type is not good,
-	if serviceType != "web" {
-		return "", nil, errors.New("unsupported service type")
+	if !c.serviceTypes[serviceType] {
+		return "", nil, fmt.Errorf("unsupported service type %q", serviceType)
+	}
+
+	// Static sites are cheap to keep running and are never woken up from zero
+	if serviceType == ServiceTypeStatic {
+		scaleToZeroEnabled = ""
+	}
+
+	// Log every request unless the service asks for a valid sample rate
+	accessLogSampleRate := 1.0
+	if value, ok := service.Labels["accessLogSampleRate"]; ok {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			log.Printf("Ignoring invalid accessLogSampleRate %q on service %s", value, serviceKey)
+		} else {
+			accessLogSampleRate = rate
+		}
 	}
 
 	// Extract domains from individual domain-N labels
@@ -236,34 +404,124 @@ func (c *Client) handleServiceChange(service *corev1.Service) (string, *ServiceI
 		}
 	}
 
+	ports := make([]int32, 0, len(service.Spec.Ports))
+	for _, port := range service.Spec.Ports {
+		ports = append(ports, port.Port)
+	}
+
 	// Create service info
 	info := &ServiceInfo{
 		Name:               name,
 		Namespace:          service.Namespace,
+		Type:               serviceType,
 		ProjectID:          projectID,
 		ServiceID:          serviceID,
 		Port:               80,
 		Domains:            domains,
 		ScaleToZeroEnabled: scaleToZeroEnabled == "true",
+		Stopped:            stopped == "true",
+		TunnelEnabled:      tunnelEnabled == "true",
+		TunnelTokenHash:    tunnelTokenHash,
+		Ports:              ports,
+
+		AccessLogSampleRate: accessLogSampleRate,
+		AcmeChallenge:       service.Labels["acmeChallenge"],
+		H2C:                 service.Labels["h2c"] == "true",
+		Streaming:           service.Labels["streaming"] == "true",
+		AllowedMethods:      parseAllowedMethods(service.Labels["allowedMethods"]),
+	}
+
+	// A redirect needs both ends
+	redirectFrom := strings.ToLower(service.Labels["redirect-from"])
+	redirectTo := strings.ToLower(service.Labels["redirect-to"])
+	if redirectFrom != "" && redirectTo != "" && redirectFrom != redirectTo {
+		info.RedirectFrom = redirectFrom
+		info.RedirectTo = redirectTo
+	}
+
+	// Timeouts in seconds, the global ones apply when unset or invalid
+	info.ReadTimeout = parseTimeoutLabel(service.Labels["readTimeout"], "readTimeout", serviceKey)
+	info.WriteTimeout = parseTimeoutLabel(service.Labels["writeTimeout"], "writeTimeout", serviceKey)
+
+	// Static sites with a bucket are served from object storage instead of a pod
+	if serviceType == ServiceTypeStatic {
+		info.StaticBucket = service.Labels["staticBucket"]
+		info.StaticPrefix = service.Labels["staticPrefix"]
 	}
 
 	return serviceKey, info, nil
 }
 
+// parseTimeoutLabel reads a timeout label holding whole seconds, 0 when it is unset or
+// not a positive number
+func parseTimeoutLabel(value, label, serviceKey string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		log.Printf("Ignoring invalid %s %q on service %s", label, value, serviceKey)
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// parseAllowedMethods reads the allowedMethods label. Label values can't hold commas, so
+// the methods are separated by dots, e.g. "GET.HEAD.OPTIONS". GET implies HEAD.
+func parseAllowedMethods(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == '.' || r == ',' || r == '_'
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+
+	methods := make([]string, 0, len(fields)+1)
+	seen := make(map[string]bool, len(fields)+1)
+	add := func(method string) {
+		if !seen[method] {
+			seen[method] = true
+			methods = append(methods, method)
+		}
+	}
+	for _, field := range fields {
+		method := strings.ToUpper(field)
+		add(method)
+		if method == http.MethodGet {
+			add(http.MethodHead)
+		}
+	}
+
+	return methods
+}
+
 // GetServicesWithScaleToZero gets all services with scale-to-zero enabled
 func (c *Client) GetServicesWithScaleToZero() ([]ServiceInfo, error) {
-	// List all services with the label selector
-	services, err := c.clientset.CoreV1().Services("").List(context.Background(), metav1.ListOptions{
-		LabelSelector: c.labelSelector,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list services: %v", err)
+	// List all services matching a label selector in the watched namespaces, once each
+	var items []corev1.Service
+	listed := make(map[string]bool)
+	for _, labelSelector := range c.labelSelectors {
+		for _, namespace := range c.watchedNamespaces() {
+			services, err := c.clientset.CoreV1().Services(namespace).List(context.Background(), metav1.ListOptions{
+				LabelSelector: labelSelector,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list services: %v", err)
+			}
+			for _, service := range services.Items {
+				key := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+				if !listed[key] {
+					listed[key] = true
+					items = append(items, service)
+				}
+			}
+		}
 	}
 
 	var scaleToZeroServices []ServiceInfo
 
 	// Filter services with scaleToZeroEnabled=true
-	for _, service := range services.Items {
+	for _, service := range items {
 		// Skip services that don't match our selector
 		name := service.Name
 		namespace := service.Namespace
@@ -284,6 +542,11 @@ func (c *Client) GetServicesWithScaleToZero() ([]ServiceInfo, error) {
 			continue
 		}
 
+		// Stopped services are already at zero and must stay there
+		if service.Labels["stopped"] == "true" {
+			continue
+		}
+
 		// Extract domains from individual domain-N labels
 		domains := []string{}
 		for k, v := range service.Labels {
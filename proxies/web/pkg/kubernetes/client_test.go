@@ -0,0 +1,362 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSyncedWaitsForWatchersAndLists(t *testing.T) {
+	c := &Client{}
+	if c.Synced() {
+		t.Fatal("Synced before the watchers started")
+	}
+
+	c.pendingLists.Store(1)
+	c.watcherStarted.Store(true)
+	if c.Synced() {
+		t.Fatal("Synced while a namespace wasn't listed yet")
+	}
+	c.pendingLists.Add(-1)
+	if !c.Synced() {
+		t.Fatal("not Synced after every namespace was listed")
+	}
+}
+
+// Readiness checks call Synced while the watchers start and stop, run with -race
+func TestSyncedIsSafeDuringStop(t *testing.T) {
+	c := &Client{}
+	c.watcherStarted.Store(true)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.Synced()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		c.StopWatching()
+	}()
+	wg.Wait()
+
+	if c.Synced() {
+		t.Fatal("Synced after the watchers stopped")
+	}
+}
+
+func TestHandleServiceChangeStoppedLabel(t *testing.T) {
+	c := &Client{serviceTypes: map[string]bool{ServiceTypeWeb: true}}
+	service := func(labels map[string]string) *corev1.Service {
+		labels["project"] = "proj1"
+		labels["service"] = "svc1"
+		labels["type"] = ServiceTypeWeb
+		return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc1-service", Namespace: "proj1", Labels: labels}}
+	}
+
+	_, info, err := c.handleServiceChange(service(map[string]string{"stopped": "true", "scaleToZeroEnabled": "true"}))
+	if err != nil {
+		t.Fatalf("handleServiceChange: %v", err)
+	}
+	if !info.Stopped {
+		t.Error("service labelled stopped=true is not Stopped")
+	}
+
+	_, info, err = c.handleServiceChange(service(map[string]string{"scaleToZeroEnabled": "true"}))
+	if err != nil {
+		t.Fatalf("handleServiceChange: %v", err)
+	}
+	if info.Stopped {
+		t.Error("service without the stopped label is Stopped")
+	}
+}
+
+func TestHandleServiceChangeStreamingLabel(t *testing.T) {
+	c := &Client{serviceTypes: map[string]bool{ServiceTypeWeb: true}}
+	for label, want := range map[string]bool{"true": true, "false": false, "": false} {
+		labels := map[string]string{"project": "proj1", "service": "svc1", "type": ServiceTypeWeb, "streaming": label}
+		_, info, err := c.handleServiceChange(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc1-service", Namespace: "proj1", Labels: labels}})
+		if err != nil {
+			t.Fatalf("handleServiceChange: %v", err)
+		}
+		if info.Streaming != want {
+			t.Errorf("streaming=%q gives Streaming %v, want %v", label, info.Streaming, want)
+		}
+	}
+}
+
+func TestHandleServiceChangePorts(t *testing.T) {
+	c := &Client{serviceTypes: map[string]bool{ServiceTypeWeb: true}}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc1-service", Namespace: "proj1", Labels: map[string]string{"project": "proj1", "service": "svc1", "type": ServiceTypeWeb}},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}, {Name: "port-9000", Port: 9000}}},
+	}
+
+	_, info, err := c.handleServiceChange(service)
+	if err != nil {
+		t.Fatalf("handleServiceChange: %v", err)
+	}
+	if !reflect.DeepEqual(info.Ports, []int32{80, 9000}) {
+		t.Errorf("Ports = %v, want the service's ports [80 9000]", info.Ports)
+	}
+}
+
+func TestHandleServiceChangeRedirectLabels(t *testing.T) {
+	c := &Client{serviceTypes: map[string]bool{ServiceTypeWeb: true}}
+	tests := []struct {
+		name     string
+		from, to string
+		wantFrom string
+		wantTo   string
+	}{
+		{name: "redirect", from: "WWW.example.com", to: "example.com", wantFrom: "www.example.com", wantTo: "example.com"},
+		{name: "no target", from: "www.example.com"},
+		{name: "no source", to: "example.com"},
+		{name: "to itself", from: "example.com", to: "Example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels := map[string]string{"project": "proj1", "service": "svc1", "type": ServiceTypeWeb}
+			if tt.from != "" {
+				labels["redirect-from"] = tt.from
+			}
+			if tt.to != "" {
+				labels["redirect-to"] = tt.to
+			}
+			_, info, err := c.handleServiceChange(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc1-service", Namespace: "proj1", Labels: labels}})
+			if err != nil {
+				t.Fatalf("handleServiceChange: %v", err)
+			}
+			if info.RedirectFrom != tt.wantFrom || info.RedirectTo != tt.wantTo {
+				t.Errorf("redirect = %q to %q, want %q to %q", info.RedirectFrom, info.RedirectTo, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestHandleServiceChangeTimeoutLabels(t *testing.T) {
+	c := &Client{serviceTypes: map[string]bool{ServiceTypeWeb: true}}
+	tests := []struct {
+		read, write         string
+		wantRead, wantWrite time.Duration
+	}{
+		{read: "", write: ""},
+		{read: "120", write: "600", wantRead: 2 * time.Minute, wantWrite: 10 * time.Minute},
+		{read: "0", write: "-5"},
+		{read: "1m", write: "abc"},
+	}
+	for _, tt := range tests {
+		labels := map[string]string{"project": "proj1", "service": "svc1", "type": ServiceTypeWeb, "readTimeout": tt.read, "writeTimeout": tt.write}
+		_, info, err := c.handleServiceChange(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc1-service", Namespace: "proj1", Labels: labels}})
+		if err != nil {
+			t.Fatalf("handleServiceChange: %v", err)
+		}
+		// Invalid values fall back to the global timeouts
+		if info.ReadTimeout != tt.wantRead || info.WriteTimeout != tt.wantWrite {
+			t.Errorf("readTimeout=%q writeTimeout=%q give %v and %v, want %v and %v",
+				tt.read, tt.write, info.ReadTimeout, info.WriteTimeout, tt.wantRead, tt.wantWrite)
+		}
+	}
+}
+
+func TestParseAllowedMethods(t *testing.T) {
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{label: "", want: nil},
+		{label: "GET.HEAD.OPTIONS", want: []string{"GET", "HEAD", "OPTIONS"}},
+		{label: "get_post", want: []string{"GET", "HEAD", "POST"}},
+		{label: "GET.HEAD.GET", want: []string{"GET", "HEAD"}},
+		{label: "...", want: nil},
+	}
+	for _, tt := range tests {
+		if got := parseAllowedMethods(tt.label); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseAllowedMethods(%q) = %v, want %v", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestHandleServiceChangeAccessLogSampleRate(t *testing.T) {
+	c := &Client{serviceTypes: map[string]bool{ServiceTypeWeb: true}}
+	tests := []struct {
+		label string
+		want  float64
+	}{
+		{label: "", want: 1},
+		{label: "0.1", want: 0.1},
+		{label: "0", want: 0},
+		{label: "1.5", want: 1},
+		{label: "often", want: 1},
+	}
+	for _, tt := range tests {
+		labels := map[string]string{"project": "proj1", "service": "svc1", "type": ServiceTypeWeb}
+		if tt.label != "" {
+			labels["accessLogSampleRate"] = tt.label
+		}
+		_, info, err := c.handleServiceChange(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc1-service", Namespace: "proj1", Labels: labels}})
+		if err != nil {
+			t.Fatalf("handleServiceChange: %v", err)
+		}
+		if info.AccessLogSampleRate != tt.want {
+			t.Errorf("accessLogSampleRate=%q gives %v, want %v", tt.label, info.AccessLogSampleRate, tt.want)
+		}
+	}
+}
+
+func TestHandleServiceChangeServiceTypes(t *testing.T) {
+	c := &Client{}
+	c.SetServiceTypes([]string{ServiceTypeWeb, ServiceTypeStatic})
+	service := func(serviceType string) *corev1.Service {
+		return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc1-service", Namespace: "proj1", Labels: map[string]string{
+			"project": "proj1", "service": "svc1", "type": serviceType, "scaleToZeroEnabled": "true",
+		}}}
+	}
+
+	_, info, err := c.handleServiceChange(service(ServiceTypeStatic))
+	if err != nil {
+		t.Fatalf("configured static type: %v", err)
+	}
+	if info.Type != ServiceTypeStatic {
+		t.Errorf("Type = %q, want %q", info.Type, ServiceTypeStatic)
+	}
+	if info.ScaleToZeroEnabled {
+		t.Error("a static site is scaled from zero")
+	}
+
+	if _, _, err := c.handleServiceChange(service("worker")); err == nil {
+		t.Error("unconfigured type worker was accepted")
+	}
+
+	// Without static in the set it is skipped like any other unknown type
+	c.SetServiceTypes([]string{ServiceTypeWeb})
+	if _, _, err := c.handleServiceChange(service(ServiceTypeStatic)); err == nil {
+		t.Error("static was accepted without being configured")
+	}
+}
+
+// webService returns a web service in a namespace
+func webService(namespace, name string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"project": namespace, "service": name, "type": ServiceTypeWeb}},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+	}
+}
+
+// waitForWatch waits until a watch on namespace was opened on the fake clientset
+func waitForWatch(t *testing.T, clientset *fake.Clientset, namespace string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, action := range clientset.Actions() {
+			if action.GetVerb() == "watch" && action.GetNamespace() == namespace {
+				// The fake registers the watcher right after recording the action
+				time.Sleep(50 * time.Millisecond)
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no watch was opened on namespace %s", namespace)
+}
+
+func TestWatchOnlyConfiguredNamespaces(t *testing.T) {
+	clientset := fake.NewSimpleClientset(webService("team-a", "listed"), webService("team-b", "listed"))
+	c := NewClientForClientset(clientset, []string{"type=web"})
+	defer c.StopWatching()
+	c.SetWatchNamespaces([]string{"team-a"})
+
+	added := make(chan string, 10)
+	c.StartWatching(func(action ServiceInfoAction, key string, info *ServiceInfo) {
+		if action == Add {
+			added <- key
+		}
+	})
+	waitForWatch(t, clientset, "team-a")
+
+	clientset.CoreV1().Services("team-b").Create(context.Background(), webService("team-b", "watched"), metav1.CreateOptions{})
+	clientset.CoreV1().Services("team-a").Create(context.Background(), webService("team-a", "watched"), metav1.CreateOptions{})
+
+	var keys []string
+	for len(keys) < 2 {
+		select {
+		case key := <-added:
+			keys = append(keys, key)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("reported %v, want team-a/listed and team-a/watched", keys)
+		}
+	}
+	if keys[0] != "team-a/listed" || keys[1] != "team-a/watched" {
+		t.Errorf("reported %v, want team-a/listed then team-a/watched", keys)
+	}
+
+	// Nothing is read outside the configured namespace
+	for _, action := range clientset.Actions() {
+		if verb := action.GetVerb(); (verb == "list" || verb == "watch") && action.GetNamespace() != "team-a" {
+			t.Errorf("%s of services in namespace %q, want only team-a", verb, action.GetNamespace())
+		}
+	}
+}
+
+// labeled returns service with extra labels
+func labeled(service *corev1.Service, labels map[string]string) *corev1.Service {
+	for key, value := range labels {
+		service.Labels[key] = value
+	}
+	return service
+}
+
+func TestWatchServicesMatchingAnySelector(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		labeled(webService("proj", "cache"), map[string]string{"tier": "cache"}),
+		labeled(webService("proj", "managed"), map[string]string{"managed": "true"}),
+		labeled(webService("proj", "both"), map[string]string{"tier": "cache", "managed": "true"}),
+		webService("proj", "other"),
+	)
+	selectors := []string{"type=web,tier=cache", "type=web,managed in (true)"}
+	c := NewClientForClientset(clientset, selectors)
+	defer c.StopWatching()
+
+	added := make(chan string, 10)
+	c.StartWatching(func(action ServiceInfoAction, key string, info *ServiceInfo) {
+		if action == Add {
+			added <- key
+		}
+	})
+
+	// Each selector lists the services matching it before it watches
+	deadline := time.Now().Add(2 * time.Second)
+	for watches := 0; watches < len(selectors); {
+		if time.Now().After(deadline) {
+			t.Fatalf("opened %d watches, want one per selector", watches)
+		}
+		time.Sleep(10 * time.Millisecond)
+		watches = 0
+		for _, action := range clientset.Actions() {
+			if action.GetVerb() == "watch" {
+				watches++
+			}
+		}
+	}
+
+	reported := make(map[string]bool)
+	for len(added) > 0 {
+		reported[<-added] = true
+	}
+	for _, key := range []string{"proj/cache", "proj/managed", "proj/both"} {
+		if !reported[key] {
+			t.Errorf("%s matches a selector but was not reported, reported %v", key, reported)
+		}
+	}
+	if reported["proj/other"] {
+		t.Error("proj/other matches no selector but was reported")
+	}
+}
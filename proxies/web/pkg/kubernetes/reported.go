@@ -0,0 +1,103 @@
+package kubernetes
+
+import (
+	"reflect"
+	"sort"
+)
+
+// reportedServices remembers what was last reported for every service, so a service seen
+// again by a relist, or by a Modified event that changed nothing the proxy reads, is not
+// reported twice
+type reportedServices struct {
+	callback ServiceChangeCallback
+	infos    map[string]*ServiceInfo
+}
+
+func newReportedServices(callback ServiceChangeCallback) *reportedServices {
+	return &reportedServices{
+		callback: callback,
+		infos:    make(map[string]*ServiceInfo),
+	}
+}
+
+// add reports a service unless its info equals what was last reported for it, and
+// returns whether it was reported
+func (r *reportedServices) add(key string, info *ServiceInfo) bool {
+	if last, ok := r.infos[key]; ok && sameServiceInfo(last, info) {
+		return false
+	}
+	r.infos[key] = info
+	r.callback(Add, key, info)
+	return true
+}
+
+// sameServiceInfo reports whether two infos are equal. Their lists are built from map
+// iteration, so they are compared regardless of order.
+func sameServiceInfo(a, b *ServiceInfo) bool {
+	x, y := *a, *b
+	x.Domains, y.Domains = sortedStrings(a.Domains), sortedStrings(b.Domains)
+	x.AllowedMethods, y.AllowedMethods = sortedStrings(a.AllowedMethods), sortedStrings(b.AllowedMethods)
+	return reflect.DeepEqual(x, y)
+}
+
+// sortedStrings returns a sorted copy of values
+func sortedStrings(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// remove reports the removal of a service, if it was reported before
+func (r *reportedServices) remove(key string) {
+	if _, ok := r.infos[key]; !ok {
+		return
+	}
+	delete(r.infos, key)
+	r.callback(Delete, key, nil)
+}
+
+// prune removes the reported services missing from a fresh list, they went away while
+// no watch was running
+func (r *reportedServices) prune(listed map[string]bool) {
+	for key := range r.infos {
+		if !listed[key] {
+			r.remove(key)
+		}
+	}
+}
+
+// matchedServices merges what the watchers of several label selectors report, a service
+// matching more than one of them is only removed once none matches it anymore
+type matchedServices struct {
+	callback ServiceChangeCallback
+	matches  map[string]map[int]bool
+}
+
+func newMatchedServices(callback ServiceChangeCallback) *matchedServices {
+	return &matchedServices{
+		callback: callback,
+		matches:  make(map[string]map[int]bool),
+	}
+}
+
+// callbackFor returns the callback of the watchers of a selector, by index. Calls must
+// take turns.
+func (m *matchedServices) callbackFor(selector int) ServiceChangeCallback {
+	return func(action ServiceInfoAction, key string, info *ServiceInfo) {
+		switch action {
+		case Add:
+			if m.matches[key] == nil {
+				m.matches[key] = make(map[int]bool)
+			}
+			m.matches[key][selector] = true
+			m.callback(Add, key, info)
+		case Delete:
+			delete(m.matches[key], selector)
+			if len(m.matches[key]) > 0 {
+				return
+			}
+			delete(m.matches, key)
+			m.callback(Delete, key, nil)
+		}
+	}
+}
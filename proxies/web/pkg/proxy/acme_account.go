@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+	"github.com/go-acme/lego/v4/registration"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Keys of the ACME account secret
+const (
+	acmeAccountKeyField          = "key.pem"
+	acmeAccountRegistrationField = "registration.json"
+	acmeAccountServerField       = "server"
+)
+
+// loadACMEUser returns the ACME user stored in the account secret, or a user with a fresh key
+// when there is none. The stored registration is only kept when it belongs to acmeServerURL,
+// so switching between staging and production registers again with the same key.
+func loadACMEUser(kubeClient *kubernetes.Client, secretName, email, acmeServerURL string) (*User, error) {
+	secret, err := kubeClient.GetSecret("system-apps", secretName)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to read ACME account secret: %v", err)
+	}
+
+	if err == nil {
+		if key, err := parseACMEAccountKey(secret.Data[acmeAccountKeyField]); err != nil {
+			log.Printf("Ignoring ACME account secret %s: %v", secretName, err)
+		} else {
+			user := &User{Email: email, key: key}
+
+			if string(secret.Data[acmeAccountServerField]) == acmeServerURL {
+				var reg registration.Resource
+				if err := json.Unmarshal(secret.Data[acmeAccountRegistrationField], &reg); err == nil && reg.URI != "" {
+					user.Registration = &reg
+				}
+			}
+
+			return user, nil
+		}
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %v", err)
+	}
+
+	return &User{Email: email, key: privateKey}, nil
+}
+
+// saveACMEUser stores the key and registration of an ACME user in the account secret
+func saveACMEUser(kubeClient *kubernetes.Client, secretName, acmeServerURL string, user *User) error {
+	keyDER, err := x509.MarshalECPrivateKey(user.key)
+	if err != nil {
+		return fmt.Errorf("failed to encode ACME account key: %v", err)
+	}
+
+	reg, err := json.Marshal(user.Registration)
+	if err != nil {
+		return fmt.Errorf("failed to encode ACME registration: %v", err)
+	}
+
+	return kubeClient.CreateOrUpdateSecret("system-apps", secretName, map[string][]byte{
+		acmeAccountKeyField:          pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		acmeAccountRegistrationField: reg,
+		acmeAccountServerField:       []byte(acmeServerURL),
+	})
+}
+
+// parseACMEAccountKey decodes the PEM encoded account key
+func parseACMEAccountKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("missing or invalid %s", acmeAccountKeyField)
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// acmeServer is a minimal ACME directory that counts new account registrations
+func acmeServer(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+	var registrations atomic.Int32
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/directory", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"newNonce":"%[1]s/nonce","newAccount":"%[1]s/account","newOrder":"%[1]s/order"}`, server.URL)
+	})
+	mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "nonce")
+	})
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		n := registrations.Add(1)
+		w.Header().Set("Replay-Nonce", "nonce")
+		w.Header().Set("Location", fmt.Sprintf("%s/account/%d", server.URL, n))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"status":"valid"}`)
+	})
+	return server.URL + "/directory", &registrations
+}
+
+func TestNewCertManagerReusesStoredAccount(t *testing.T) {
+	directory, registrations := acmeServer(t)
+	kubeClient := kubernetes.NewClientForClientset(fake.NewSimpleClientset(), nil)
+
+	first, err := NewCertManager("admin@example.com", directory, "acme-account", kubeClient, nil, nil)
+	if err != nil {
+		t.Fatalf("first NewCertManager: %v", err)
+	}
+	if registrations.Load() != 1 {
+		t.Fatalf("first start registered %d accounts, want 1", registrations.Load())
+	}
+	if _, err := kubeClient.GetSecret("system-apps", "acme-account"); err != nil {
+		t.Fatalf("account secret was not stored: %v", err)
+	}
+
+	second, err := NewCertManager("admin@example.com", directory, "acme-account", kubeClient, nil, nil)
+	if err != nil {
+		t.Fatalf("second NewCertManager: %v", err)
+	}
+	if registrations.Load() != 1 {
+		t.Errorf("restart registered again, %d registrations", registrations.Load())
+	}
+	if second.user.Registration == nil || second.user.Registration.URI != first.user.Registration.URI {
+		t.Errorf("restart uses account %v, want %s", second.user.Registration, first.user.Registration.URI)
+	}
+	if !second.user.key.Equal(first.user.key) {
+		t.Error("restart generated a new account key")
+	}
+}
+
+func TestNewCertManagerRegistersStoredKeyWithNewServer(t *testing.T) {
+	staging, _ := acmeServer(t)
+	production, registrations := acmeServer(t)
+	kubeClient := kubernetes.NewClientForClientset(fake.NewSimpleClientset(), nil)
+
+	first, err := NewCertManager("admin@example.com", staging, "acme-account", kubeClient, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCertManager with staging: %v", err)
+	}
+
+	second, err := NewCertManager("admin@example.com", production, "acme-account", kubeClient, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCertManager with production: %v", err)
+	}
+	if registrations.Load() != 1 {
+		t.Errorf("production registered %d accounts, want 1", registrations.Load())
+	}
+	if !second.user.key.Equal(first.user.key) {
+		t.Error("switching servers generated a new account key")
+	}
+}
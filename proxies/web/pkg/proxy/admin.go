@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// certificateStatus is a domain as reported by the admin API
+type certificateStatus struct {
+	Domain string `json:"domain"`
+	// NotAfter is the expiry of the certificate held in memory, if any
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+	// RateLimitRemainingSeconds is what is left of the domain's rate limit cooldown
+	RateLimitRemainingSeconds int64 `json:"rateLimitRemainingSeconds,omitempty"`
+	// RetryAt is when certificate requests for the domain resume
+	RetryAt *time.Time `json:"retryAt,omitempty"`
+}
+
+// routedService is a service of the routing table as reported by the admin API
+type routedService struct {
+	Name               string   `json:"name"`
+	Namespace          string   `json:"namespace"`
+	Type               string   `json:"type"`
+	Port               int32    `json:"port"`
+	Domains            []string `json:"domains"`
+	ScaleToZeroEnabled bool     `json:"scaleToZeroEnabled"`
+	Stopped            bool     `json:"stopped"`
+}
+
+// adminHandler returns the handler of the admin API
+func (s *Server) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/certs", s.handleAdminCerts)
+	mux.HandleFunc("/metrics", s.handleAdminMetrics)
+	mux.HandleFunc("/routes", s.handleAdminRoutes)
+	return s.adminAuth(mux)
+}
+
+// adminAuth rejects admin requests without the admin token, when one is configured
+func (s *Server) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := s.live.Load().AdminToken
+		if token != "" {
+			sent, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleAdminCerts lists the certificates and rate limit cooldowns of every domain
+// GET /certs
+func (s *Server) handleAdminCerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.certManager == nil {
+		http.Error(w, "HTTPS is disabled", http.StatusNotFound)
+		return
+	}
+
+	cooldowns, err := s.certManager.RateLimitCooldowns()
+	if err != nil {
+		log.Printf("Failed to read rate limit cooldowns: %v", err)
+		http.Error(w, "Failed to read rate limit cooldowns", http.StatusInternalServerError)
+		return
+	}
+
+	statuses := make(map[string]*certificateStatus)
+	statusFor := func(domain string) *certificateStatus {
+		status, ok := statuses[domain]
+		if !ok {
+			status = &certificateStatus{Domain: domain}
+			statuses[domain] = status
+		}
+		return status
+	}
+
+	for domain, notAfter := range s.certManager.CertificateExpiries() {
+		statusFor(domain).NotAfter = &notAfter
+	}
+
+	now := time.Now()
+	for domain, remaining := range cooldowns {
+		retryAt := now.Add(remaining).Truncate(time.Second)
+		status := statusFor(domain)
+		status.RateLimitRemainingSeconds = int64(remaining.Round(time.Second) / time.Second)
+		status.RetryAt = &retryAt
+	}
+
+	certificates := make([]*certificateStatus, 0, len(statuses))
+	for _, status := range statuses {
+		certificates = append(certificates, status)
+	}
+	sort.Slice(certificates, func(i, j int) bool {
+		return certificates[i].Domain < certificates[j].Domain
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"certificates": certificates,
+	})
+}
+
+// handleAdminMetrics serves the request histograms in the Prometheus text format
+// GET /metrics
+func (s *Server) handleAdminMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.write(w)
+}
+
+// handleAdminRoutes dumps the routing table: the service routing every domain, the
+// services by key and the redirects
+// GET /routes
+func (s *Server) handleAdminRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The routing table names every service, it is not served without authentication
+	if s.live.Load().AdminToken == "" {
+		http.Error(w, "Set admin_token to serve the routing table", http.StatusNotFound)
+		return
+	}
+
+	s.routingLock.RLock()
+	routes := make(map[string]string, len(s.routingTable))
+	for domain, serviceKey := range s.routingTable {
+		routes[domain] = serviceKey
+	}
+	services := make(map[string]routedService, len(s.services))
+	for serviceKey, info := range s.services {
+		services[serviceKey] = routedService{
+			Name:               info.Name,
+			Namespace:          info.Namespace,
+			Type:               info.Type,
+			Port:               info.Port,
+			Domains:            append([]string(nil), info.Domains...),
+			ScaleToZeroEnabled: info.ScaleToZeroEnabled,
+			Stopped:            info.Stopped,
+		}
+	}
+	redirects := make(map[string]string, len(s.redirects))
+	for from, to := range s.redirects {
+		redirects[from] = to
+	}
+	s.routingLock.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"routes":    routes,
+		"services":  services,
+		"redirects": redirects,
+	})
+}
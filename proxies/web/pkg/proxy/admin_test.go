@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// adminGet sends an admin API request to server
+func adminGet(t *testing.T, server *Server, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.adminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder
+}
+
+func TestAdminCertsReportsRateLimitCooldowns(t *testing.T) {
+	server, _ := proxyServer(t, &config.Config{}, &kubernetes.ServiceInfo{ServiceID: "web"})
+	manager, redisServer := rateLimitManager(t)
+	server.certManager = manager
+	redisServer.Set("cert:app.example.com:ratelimit", "rate_limited")
+	redisServer.SetTTL("cert:app.example.com:ratelimit", 30*time.Minute)
+
+	recorder := adminGet(t, server, "/certs")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	var body struct {
+		Certificates []certificateStatus `json:"certificates"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Certificates) != 1 {
+		t.Fatalf("certificates = %+v, want app.example.com", body.Certificates)
+	}
+	status := body.Certificates[0]
+	if status.Domain != "app.example.com" || status.RateLimitRemainingSeconds != 1800 {
+		t.Errorf("status = %+v, want 1800 seconds left for app.example.com", status)
+	}
+	if status.RetryAt == nil || time.Until(*status.RetryAt) < 29*time.Minute {
+		t.Errorf("retryAt = %v, want about 30 minutes from now", status.RetryAt)
+	}
+}
+
+func TestAdminMetrics(t *testing.T) {
+	server, _ := proxyServer(t, &config.Config{}, &kubernetes.ServiceInfo{ServiceID: "web"})
+	server.metrics.observe(30*time.Millisecond, 100, 2000)
+	server.metrics.observe(2*time.Second, 0, 5<<20)
+
+	recorder := adminGet(t, server, "/metrics")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	for _, want := range []string{
+		"# TYPE proxy_request_duration_seconds histogram",
+		`proxy_request_duration_seconds_bucket{le="0.025"} 0`,
+		`proxy_request_duration_seconds_bucket{le="0.05"} 1`,
+		`proxy_request_duration_seconds_bucket{le="2.5"} 2`,
+		`proxy_request_duration_seconds_bucket{le="+Inf"} 2`,
+		"proxy_request_duration_seconds_sum 2.03",
+		"proxy_request_duration_seconds_count 2",
+		`proxy_response_body_bytes_bucket{le="4096"} 1`,
+		`proxy_response_body_bytes_bucket{le="4194304"} 1`,
+		`proxy_response_body_bytes_bucket{le="16777216"} 2`,
+		"proxy_request_body_bytes_sum 100",
+	} {
+		if !strings.Contains(recorder.Body.String(), want) {
+			t.Errorf("metrics are missing %s:\n%s", want, recorder.Body.String())
+		}
+	}
+}
+
+// routesDump is the JSON of the /routes admin endpoint
+type routesDump struct {
+	Routes    map[string]string        `json:"routes"`
+	Services  map[string]routedService `json:"services"`
+	Redirects map[string]string        `json:"redirects"`
+}
+
+func TestAdminRoutesDumpsWatchedServices(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AdminToken = "s3cret"
+	server, _ := proxyServer(t, cfg, &kubernetes.ServiceInfo{Name: "web-service", Namespace: "project", ServiceID: "web", Port: 80, Domains: []string{serviceHost}})
+
+	clientset := fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop-service", Namespace: "team", Labels: map[string]string{
+			"project": "team", "service": "shop", "type": kubernetes.ServiceTypeWeb,
+			"domain-0": "shop.example.com", "scaleToZeroEnabled": "true",
+		}},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+	})
+	watcher := kubernetes.NewClientForClientset(clientset, []string{"type=web"})
+	defer watcher.StopWatching()
+	watcher.StartWatching(server.handleServicesChanged)
+
+	dump := func() routesDump {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/routes", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		server.adminHandler().ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+		}
+		var decoded routesDump
+		if err := json.NewDecoder(recorder.Body).Decode(&decoded); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return decoded
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	routes := dump()
+	for routes.Routes["shop.example.com"] == "" {
+		if time.Now().After(deadline) {
+			t.Fatalf("routes = %v, want shop.example.com routed once the watcher saw it", routes.Routes)
+		}
+		time.Sleep(10 * time.Millisecond)
+		routes = dump()
+	}
+
+	serviceKey := routes.Routes["shop.example.com"]
+	shop, ok := routes.Services[serviceKey]
+	if !ok {
+		t.Fatalf("services = %v, want %s", routes.Services, serviceKey)
+	}
+	if shop.Name != "shop-service" || shop.Namespace != "team" || shop.Port != 80 || !shop.ScaleToZeroEnabled {
+		t.Errorf("service = %+v, want shop-service in team on port 80 with scale to zero", shop)
+	}
+	if routes.Routes[serviceHost] != "project/web" {
+		t.Errorf("routes = %v, want the existing %s kept", routes.Routes, serviceHost)
+	}
+}
+
+func TestAdminRoutesNeedsToken(t *testing.T) {
+	cfg := config.DefaultConfig()
+	server, _ := proxyServer(t, cfg, &kubernetes.ServiceInfo{ServiceID: "web"})
+
+	// The routing table is not served without authentication
+	if recorder := adminGet(t, server, "/routes"); recorder.Code != http.StatusNotFound {
+		t.Errorf("status without an admin token = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+
+	tokenConfig := config.DefaultConfig()
+	tokenConfig.AdminToken = "s3cret"
+	server.live.Store(tokenConfig)
+	recorder := adminGet(t, server, "/routes")
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status without the token = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+	if strings.Contains(recorder.Body.String(), serviceHost) {
+		t.Errorf("an unauthorized request got the routing table: %s", recorder.Body.String())
+	}
+}
@@ -3,16 +3,16 @@ package proxy
 import (
 	"crypto"
 	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
@@ -49,7 +49,8 @@ type CertManager struct {
 	email         string
 	acmeServerURL string
 	client        *lego.Client
-	dnsClient     *lego.Client // Separate client for DNS-01 challenge (wildcard)
+	dnsClient     *lego.Client               // Separate client for DNS-01 challenge (wildcard and dns services)
+	challengeFor  func(domain string) string // Challenge a domain's service asked for, "" for the default
 	user          *User
 	certificates  map[string]*tls.Certificate
 	certLock      sync.RWMutex
@@ -64,6 +65,11 @@ type CertManager struct {
 	enableWildcard   bool
 	wildcardObtainMu sync.Mutex       // Mutex to prevent concurrent wildcard certificate requests
 	wildcardObtaining bool            // Flag to indicate if wildcard certificate is being obtained
+
+	// Cooldown applied when a rate limit error carries no usable retry time, and how long
+	// before expiry certificates are renewed. Both can change while the proxy runs.
+	rateLimitCooldown atomic.Int64
+	renewBefore       atomic.Int64
 }
 
 // WildcardConfig holds wildcard certificate configuration
@@ -74,17 +80,11 @@ type WildcardConfig struct {
 }
 
 // NewCertManager creates a new certificate manager
-func NewCertManager(email, acmeServerURL string, kubeClient *kubernetes.Client, redisClient *redis.Client, wildcardCfg *WildcardConfig) (*CertManager, error) {
-	// Create user private key
-	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+func NewCertManager(email, acmeServerURL, accountSecret string, kubeClient *kubernetes.Client, redisClient *redis.Client, wildcardCfg *WildcardConfig) (*CertManager, error) {
+	// Reuse the ACME account from previous runs, if there is one
+	user, err := loadACMEUser(kubeClient, accountSecret, email, acmeServerURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate private key: %v", err)
-	}
-
-	// Create user
-	user := &User{
-		Email: email,
-		key:   privateKey,
+		return nil, err
 	}
 
 	// Create LEGO config for HTTP-01 challenge
@@ -111,12 +111,21 @@ func NewCertManager(email, acmeServerURL string, kubeClient *kubernetes.Client,
 	// Create custom HTTP challenge handler
 	challengeHandler := http.FileServer(http.Dir("."))
 
-	// Register user
-	reg, err := client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
-	if err != nil {
-		return nil, fmt.Errorf("failed to register user: %v", err)
+	// Register user, unless the stored account already is. Registering a known key returns
+	// its existing account rather than creating a new one.
+	if user.Registration == nil {
+		reg, err := client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to register user: %v", err)
+		}
+		user.Registration = reg
+
+		if err := saveACMEUser(kubeClient, accountSecret, acmeServerURL, user); err != nil {
+			log.Printf("Failed to save ACME account, it will be registered again on restart: %v", err)
+		}
+	} else {
+		log.Printf("Reusing ACME account %s", user.Registration.URI)
 	}
-	user.Registration = reg
 
 	// Create manager
 	manager := &CertManager{
@@ -131,19 +140,20 @@ func NewCertManager(email, acmeServerURL string, kubeClient *kubernetes.Client,
 		httpProvider:  httpProvider,
 	}
 
-	// Setup wildcard certificate support if enabled
-	if wildcardCfg != nil && wildcardCfg.Enable && wildcardCfg.CloudflareAPIToken != "" {
-		manager.enableWildcard = true
-		manager.wildcardDomain = wildcardCfg.Domain
-
+	// A Cloudflare token enables DNS-01, used for the wildcard certificate and for
+	// services that ask for the dns challenge
+	if wildcardCfg != nil && wildcardCfg.CloudflareAPIToken != "" {
 		// Create separate client for DNS-01 challenge
 		dnsClient, err := manager.createDNSClient(wildcardCfg.CloudflareAPIToken)
 		if err != nil {
-			log.Printf("Warning: Failed to create DNS client for wildcard: %v. Falling back to HTTP-01 only.", err)
-			manager.enableWildcard = false
+			log.Printf("Warning: Failed to create DNS client: %v. Falling back to HTTP-01 only.", err)
 		} else {
 			manager.dnsClient = dnsClient
-			log.Printf("Wildcard certificate support enabled for *.%s", wildcardCfg.Domain)
+			if wildcardCfg.Enable && wildcardCfg.Domain != "" {
+				manager.enableWildcard = true
+				manager.wildcardDomain = wildcardCfg.Domain
+				log.Printf("Wildcard certificate support enabled for *.%s", wildcardCfg.Domain)
+			}
 		}
 	}
 
@@ -155,7 +165,14 @@ func NewCertManager(email, acmeServerURL string, kubeClient *kubernetes.Client,
 	// Load wildcard certificate if enabled
 	if manager.enableWildcard {
 		if err := manager.loadWildcardCertificate(); err != nil {
-			log.Printf("Wildcard certificate not found, will obtain on first request: %v", err)
+			log.Printf("Wildcard certificate not found, obtaining it: %v", err)
+			// The proxy isn't ready until it has the wildcard certificate, so it can't wait
+			// for a request to obtain it
+			go func() {
+				if err := manager.ensureWildcardCertificate(); err != nil {
+					log.Printf("Failed to obtain wildcard certificate: %v", err)
+				}
+			}()
 		}
 	}
 
@@ -339,6 +356,26 @@ func (m *CertManager) getWildcardCertificate() (*tls.Certificate, error) {
 	return cert, nil
 }
 
+// WildcardCertificate returns the wildcard certificate, or an error if wildcard certificates are disabled
+func (m *CertManager) WildcardCertificate() (*tls.Certificate, error) {
+	if !m.enableWildcard {
+		return nil, fmt.Errorf("wildcard certificate is disabled")
+	}
+	return m.getWildcardCertificate()
+}
+
+// WildcardReady reports whether a valid wildcard certificate is loaded, always true when
+// wildcard certificates are disabled
+func (m *CertManager) WildcardReady() bool {
+	if !m.enableWildcard {
+		return true
+	}
+	m.certLock.RLock()
+	cert := m.wildcardCert
+	m.certLock.RUnlock()
+	return m.isCertificateValid(cert)
+}
+
 // loadWildcardCertificate loads the wildcard certificate from Kubernetes secret
 func (m *CertManager) loadWildcardCertificate() error {
 	wildcardKey := fmt.Sprintf("*.%s", m.wildcardDomain)
@@ -455,8 +492,7 @@ func (m *CertManager) ensureWildcardCertificate() error {
 	certificates, err := m.dnsClient.Certificate.Obtain(request)
 	if err != nil {
 		if strings.Contains(err.Error(), "urn:ietf:params:acme:error:rateLimited") {
-			retryAfterStr := extractRetryAfter(err.Error())
-			m.setRateLimitCooldown(wildcardKey, retryAfterStr)
+			m.setRateLimitCooldown(wildcardKey, err.Error())
 			log.Printf("Rate limit hit for wildcard domain: %v", err)
 		}
 		return fmt.Errorf("failed to obtain wildcard certificate: %v", err)
@@ -519,6 +555,40 @@ func (m *CertManager) getCertificateFromKubernetesSecret(domain string) (*tls.Ce
 	return &certData, nil
 }
 
+// ACME challenge types a service may select with its acmeChallenge label
+const (
+	ChallengeHTTP = "http"
+	ChallengeDNS  = "dns"
+)
+
+// SetChallengeResolver sets the function returning the challenge type requested for a domain
+func (m *CertManager) SetChallengeResolver(challengeFor func(domain string) string) {
+	m.challengeFor = challengeFor
+}
+
+// clientForDomain returns the ACME client for the challenge requested for a domain, HTTP-01
+// unless its service asks for DNS-01
+func (m *CertManager) clientForDomain(domain string) (*lego.Client, string, error) {
+	challenge := ChallengeHTTP
+	if m.challengeFor != nil {
+		if requested := m.challengeFor(domain); requested != "" {
+			challenge = requested
+		}
+	}
+
+	switch challenge {
+	case ChallengeHTTP:
+		return m.client, challenge, nil
+	case ChallengeDNS:
+		if m.dnsClient == nil {
+			return nil, "", fmt.Errorf("DNS-01 challenge requested for %s but no DNS provider is configured", domain)
+		}
+		return m.dnsClient, challenge, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported ACME challenge %q requested for %s, use %q or %q", challenge, domain, ChallengeHTTP, ChallengeDNS)
+	}
+}
+
 // EnsureCertificate makes sure a certificate exists for the given domain
 func (m *CertManager) EnsureCertificate(domain string) error {
 	// Check if we already have a valid certificate
@@ -536,7 +606,12 @@ func (m *CertManager) EnsureCertificate(domain string) error {
 		return fmt.Errorf("domain %s is rate limited", domain)
 	}
 
-	log.Printf("Obtaining certificate for %s", domain)
+	client, challenge, err := m.clientForDomain(domain)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Obtaining certificate for %s using the %s-01 challenge", domain, challenge)
 
 	// Request certificate
 	request := certificate.ObtainRequest{
@@ -544,13 +619,11 @@ func (m *CertManager) EnsureCertificate(domain string) error {
 		Bundle:  true,
 	}
 
-	certificates, err := m.client.Certificate.Obtain(request)
+	certificates, err := client.Certificate.Obtain(request)
 	if err != nil {
 		// Check if this is a rate limit error
 		if strings.Contains(err.Error(), "urn:ietf:params:acme:error:rateLimited") {
-			// Extract retry time if available
-			retryAfterStr := extractRetryAfter(err.Error())
-			m.setRateLimitCooldown(domain, retryAfterStr)
+			m.setRateLimitCooldown(domain, err.Error())
 			log.Printf("Rate limit hit for domain %s: %v", domain, err)
 			return fmt.Errorf("rate limited: %v", err)
 		}
@@ -674,8 +747,8 @@ func (m *CertManager) isCertificateValidWithReason(cert *tls.Certificate, domain
 		return fmt.Sprintf("certificate expired on %s", leaf.NotAfter.Format("2006-01-02 15:04:05"))
 	}
 
-	// Check if expiring within 30 days
-	expiryThreshold := leaf.NotAfter.Add(-30 * 24 * time.Hour)
+	// Check if expiring within the renewal window
+	expiryThreshold := leaf.NotAfter.Add(-m.renewalWindow())
 	if now.After(expiryThreshold) {
 		daysLeft := int(leaf.NotAfter.Sub(now).Hours() / 24)
 		if domain != "" {
@@ -798,22 +871,57 @@ func (m *CertManager) isRateLimited(domain string) bool {
 	return exists
 }
 
-// setRateLimitCooldown marks a domain as rate limited with a cooldown period
-func (m *CertManager) setRateLimitCooldown(domain, retryAfterStr string) {
+// CertificateExpiries returns the expiry of every certificate held in memory
+func (m *CertManager) CertificateExpiries() map[string]time.Time {
+	m.certLock.RLock()
+	defer m.certLock.RUnlock()
+
+	expiries := make(map[string]time.Time, len(m.certificates))
+	for domain, cert := range m.certificates {
+		if cert == nil || len(cert.Certificate) == 0 {
+			continue
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			continue
+		}
+		expiries[domain] = leaf.NotAfter
+	}
+
+	return expiries
+}
+
+// SetDefaultRateLimitCooldown sets the cooldown used when a rate limit error has no retry time
+func (m *CertManager) SetDefaultRateLimitCooldown(cooldown time.Duration) {
+	m.rateLimitCooldown.Store(int64(cooldown))
+}
+
+// SetRenewBefore sets how long before expiry a certificate is renewed
+func (m *CertManager) SetRenewBefore(renewBefore time.Duration) {
+	m.renewBefore.Store(int64(renewBefore))
+}
+
+// renewalWindow returns how long before expiry a certificate is renewed
+func (m *CertManager) renewalWindow() time.Duration {
+	if renewBefore := time.Duration(m.renewBefore.Load()); renewBefore > 0 {
+		return renewBefore
+	}
+	return defaultRenewBefore
+}
+
+// setRateLimitCooldown marks a domain as rate limited until the retry time in the ACME
+// error, or for the default cooldown if the error has none
+func (m *CertManager) setRateLimitCooldown(domain, errMsg string) {
 	key := fmt.Sprintf("cert:%s:ratelimit", domain)
 
-	// Default cooldown of 1 hour if we can't parse the retry time
-	cooldown := 1 * time.Hour
+	cooldown := time.Duration(m.rateLimitCooldown.Load())
+	if cooldown <= 0 {
+		cooldown = defaultRateLimitCooldown
+	}
 
-	// Try to parse the retry time from the error message
-	if retryAfterStr != "" {
-		retryTime, err := time.Parse("2006-01-02 15:04:05 MST", retryAfterStr)
-		if err == nil {
-			// Calculate duration until retry time
-			now := time.Now()
-			if retryTime.After(now) {
-				cooldown = retryTime.Sub(now)
-			}
+	if retryTime, ok := parseRetryAfter(errMsg); ok {
+		if wait := time.Until(retryTime); wait > 0 {
+			cooldown = wait
 		}
 	}
 
@@ -825,38 +933,62 @@ func (m *CertManager) setRateLimitCooldown(domain, retryAfterStr string) {
 	log.Printf("Domain %s set as rate limited for %v", domain, cooldown)
 }
 
-// extractRetryAfter extracts the retry after time from a rate limit error message
-func extractRetryAfter(errMsg string) string {
-	// Example: "retry after 2025-04-14 23:30:12 UTC"
-	retryAfterIndex := strings.Index(errMsg, "retry after ")
-	if retryAfterIndex == -1 {
-		return ""
+// RateLimitCooldowns returns the remaining rate limit cooldown of every domain that has one
+func (m *CertManager) RateLimitCooldowns() (map[string]time.Duration, error) {
+	keys, err := m.redisClient.ScanKeys("cert:*:ratelimit")
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract the date part
-	retryAfterStr := errMsg[retryAfterIndex+len("retry after "):]
-
-	// Find the end of the date (usually before a colon or end of string)
-	endIndex := strings.Index(retryAfterStr, ":")
-	if endIndex != -1 {
-		// Include the time part
-		endIndex = strings.Index(retryAfterStr[endIndex+1:], ":")
-		if endIndex != -1 {
-			// Include seconds
-			secondsEndIndex := strings.Index(retryAfterStr[endIndex+1:], " ")
-			if secondsEndIndex != -1 {
-				endIndex += secondsEndIndex + 2 // +1 for the previous slice, +1 for the space
-			} else {
-				endIndex = len(retryAfterStr)
-			}
-		} else {
-			endIndex = len(retryAfterStr)
+	cooldowns := make(map[string]time.Duration, len(keys))
+	for _, key := range keys {
+		ttl, err := m.redisClient.TTL(key)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		endIndex = len(retryAfterStr)
+		// The key expired between the scan and the TTL lookup
+		if ttl <= 0 {
+			continue
+		}
+		domain := strings.TrimSuffix(strings.TrimPrefix(key, "cert:"), ":ratelimit")
+		cooldowns[domain] = ttl
 	}
 
-	return strings.TrimSpace(retryAfterStr[:endIndex])
+	return cooldowns, nil
+}
+
+// defaultRateLimitCooldown is used when no cooldown is configured
+const defaultRateLimitCooldown = time.Hour
+
+// defaultRenewBefore is how long before expiry certificates are renewed when not configured
+const defaultRenewBefore = 30 * 24 * time.Hour
+
+// retryAfterPattern matches the retry time in ACME rate limit errors, e.g.
+// "retry after 2025-04-14 23:30:12 UTC" or "retry after 2025-04-14T23:30:12Z"
+var retryAfterPattern = regexp.MustCompile(`(?i)retry after (\d{4}-\d{2}-\d{2})[T ](\d{2}:\d{2}:\d{2}(?:\.\d+)?) ?(Z|UTC|[+-]\d{2}:?\d{2})?`)
+
+// parseRetryAfter extracts the retry time from a rate limit error message. Times without
+// a zone are taken as UTC.
+func parseRetryAfter(errMsg string) (time.Time, bool) {
+	match := retryAfterPattern.FindStringSubmatch(errMsg)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	zone := strings.ToUpper(match[3])
+	switch {
+	case zone == "" || zone == "UTC":
+		zone = "Z"
+	case len(zone) == 5:
+		// +hhmm
+		zone = zone[:3] + ":" + zone[3:]
+	}
+
+	retryTime, err := time.Parse(time.RFC3339Nano, match[1]+"T"+match[2]+zone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return retryTime, true
 }
 
 // Add custom HTTP01 provider that doesn't start its own server
@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+	"github.com/deployra/deployra/proxies/web/pkg/redis"
+	"github.com/go-acme/lego/v4/lego"
+)
+
+func TestClientForDomainSelectsChallenge(t *testing.T) {
+	httpClient, dnsClient := &lego.Client{}, &lego.Client{}
+	challenges := map[string]string{"http.example.com": ChallengeHTTP, "dns.example.com": ChallengeDNS}
+	manager := &CertManager{client: httpClient, dnsClient: dnsClient}
+	manager.SetChallengeResolver(func(domain string) string { return challenges[domain] })
+
+	tests := []struct {
+		domain     string
+		wantClient *lego.Client
+		want       string
+	}{
+		{domain: "default.example.com", wantClient: httpClient, want: ChallengeHTTP},
+		{domain: "http.example.com", wantClient: httpClient, want: ChallengeHTTP},
+		{domain: "dns.example.com", wantClient: dnsClient, want: ChallengeDNS},
+	}
+	for _, tt := range tests {
+		client, challenge, err := manager.clientForDomain(tt.domain)
+		if err != nil {
+			t.Errorf("clientForDomain(%s): %v", tt.domain, err)
+			continue
+		}
+		if client != tt.wantClient || challenge != tt.want {
+			t.Errorf("clientForDomain(%s) = %s-01, want %s-01", tt.domain, challenge, tt.want)
+		}
+	}
+}
+
+func TestClientForDomainUnavailableChallenge(t *testing.T) {
+	manager := &CertManager{client: &lego.Client{}}
+	manager.SetChallengeResolver(func(domain string) string {
+		if domain == "dns.example.com" {
+			return ChallengeDNS
+		}
+		return "tls-alpn"
+	})
+
+	if _, _, err := manager.clientForDomain("dns.example.com"); err == nil || !strings.Contains(err.Error(), "no DNS provider is configured") {
+		t.Errorf("dns without a provider: error = %v, want a missing DNS provider error", err)
+	}
+	if _, _, err := manager.clientForDomain("app.example.com"); err == nil || !strings.Contains(err.Error(), `unsupported ACME challenge "tls-alpn"`) {
+		t.Errorf("unknown challenge: error = %v, want an unsupported challenge error", err)
+	}
+}
+
+func TestACMEChallengeForRoutedDomain(t *testing.T) {
+	server, _ := proxyServer(t, &config.Config{}, &kubernetes.ServiceInfo{ServiceID: "web", AcmeChallenge: ChallengeDNS})
+
+	if got := server.acmeChallengeFor(serviceHost); got != ChallengeDNS {
+		t.Errorf("challenge for %s = %q, want %q", serviceHost, got, ChallengeDNS)
+	}
+	if got := server.acmeChallengeFor("unknown.example.com"); got != "" {
+		t.Errorf("challenge for an unrouted domain = %q, want the default", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	want := time.Date(2025, 4, 14, 23, 30, 12, 0, time.UTC)
+	prefix := "acme: error: 429 :: POST :: https://acme-v02.api.letsencrypt.org/acme/new-order :: urn:ietf:params:acme:error:rateLimited :: "
+
+	tests := []struct {
+		name   string
+		errMsg string
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "UTC with a space",
+			errMsg: prefix + "Error creating new order :: too many certificates already issued for exact set of domains: app.example.com: retry after 2025-04-14 23:30:12 UTC: see https://letsencrypt.org/docs/rate-limits/",
+			want:   want, wantOK: true,
+		},
+		{
+			name:   "RFC 3339",
+			errMsg: prefix + "too many certificates (5) already issued for this exact set of domains in the last 168 hours: app.example.com, retry after 2025-04-14T23:30:12Z: see https://letsencrypt.org/docs/rate-limits/#new-certificates-per-exact-set-of-hostnames",
+			want:   want, wantOK: true,
+		},
+		{
+			name:   "fractional seconds",
+			errMsg: prefix + "too many new orders recently: retry after 2025-04-14T23:30:12.5Z",
+			want:   want.Add(500 * time.Millisecond), wantOK: true,
+		},
+		{
+			name:   "numeric offset",
+			errMsg: prefix + "too many failed authorizations recently: Retry After 2025-04-15 01:30:12 +0200",
+			want:   want, wantOK: true,
+		},
+		{
+			name:   "offset with a colon",
+			errMsg: prefix + "too many registrations for this IP, retry after 2025-04-14T18:30:12-05:00",
+			want:   want, wantOK: true,
+		},
+		{
+			name:   "no zone is UTC",
+			errMsg: prefix + "too many certificates already issued, retry after 2025-04-14 23:30:12",
+			want:   want, wantOK: true,
+		},
+		{
+			name:   "no retry time",
+			errMsg: prefix + "Error creating new order :: too many failed authorizations recently: see https://letsencrypt.org/docs/failed-validation-limit/",
+		},
+		{
+			name:   "invalid date",
+			errMsg: prefix + "too many certificates already issued, retry after 2025-13-45 23:30:12 UTC",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.errMsg)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("parseRetryAfter = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// rateLimitManager returns a certificate manager keeping its rate limits in miniredis
+func rateLimitManager(t *testing.T) (*CertManager, *miniredis.Miniredis) {
+	t.Helper()
+	redisServer := miniredis.RunT(t)
+	redisClient, err := redis.NewClient(redisServer.Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("redis client: %v", err)
+	}
+	return &CertManager{redisClient: redisClient}, redisServer
+}
+
+func TestSetRateLimitCooldown(t *testing.T) {
+	manager, redisServer := rateLimitManager(t)
+
+	manager.setRateLimitCooldown("default.example.com", "too many failed authorizations recently")
+	if ttl := redisServer.TTL("cert:default.example.com:ratelimit"); ttl != defaultRateLimitCooldown {
+		t.Errorf("unconfigured cooldown = %v, want %v", ttl, defaultRateLimitCooldown)
+	}
+
+	manager.SetDefaultRateLimitCooldown(15 * time.Minute)
+	manager.setRateLimitCooldown("configured.example.com", "too many failed authorizations recently")
+	if ttl := redisServer.TTL("cert:configured.example.com:ratelimit"); ttl != 15*time.Minute {
+		t.Errorf("configured cooldown = %v, want 15m", ttl)
+	}
+
+	retryAt := time.Now().Add(3 * time.Hour).UTC().Format(time.RFC3339)
+	manager.setRateLimitCooldown("retry.example.com", "too many certificates already issued, retry after "+retryAt)
+	if ttl := redisServer.TTL("cert:retry.example.com:ratelimit"); ttl < 3*time.Hour-time.Minute || ttl > 3*time.Hour {
+		t.Errorf("cooldown until the retry time = %v, want about 3h", ttl)
+	}
+
+	// A retry time in the past falls back to the default cooldown
+	manager.setRateLimitCooldown("past.example.com", "retry after 2020-01-01T00:00:00Z")
+	if ttl := redisServer.TTL("cert:past.example.com:ratelimit"); ttl != 15*time.Minute {
+		t.Errorf("cooldown for a past retry time = %v, want the default", ttl)
+	}
+}
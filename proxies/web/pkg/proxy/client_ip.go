@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIPKey is the request context key holding the resolved client IP
+type clientIPKey struct{}
+
+// ClientIPResolver finds the client IP of a request. The trusted header is only honoured
+// when the connection comes from one of the trusted proxy networks, otherwise anybody
+// could choose the IP the proxy logs and forwards.
+type ClientIPResolver struct {
+	header  string
+	trusted []*net.IPNet
+}
+
+// NewClientIPResolver creates a resolver for the given header and trusted CIDRs.
+// With no header the connection's remote address is always used.
+func NewClientIPResolver(header string, cidrs []string) (*ClientIPResolver, error) {
+	resolver := &ClientIPResolver{header: http.CanonicalHeaderKey(header)}
+
+	for _, cidr := range cidrs {
+		// A bare IP trusts that single address
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy CIDR %q: %v", cidr, err)
+		}
+		resolver.trusted = append(resolver.trusted, network)
+	}
+
+	return resolver, nil
+}
+
+// ClientIP returns the client IP of a request
+func (c *ClientIPResolver) ClientIP(r *http.Request) string {
+	remote := remoteIP(r)
+	if c == nil || c.header == "" || !c.isTrusted(remote) {
+		return remote
+	}
+
+	values := r.Header.Values(c.header)
+	if len(values) == 0 {
+		return remote
+	}
+
+	// List headers like X-Forwarded-For grow to the right as proxies append to them,
+	// so the client is the rightmost address that isn't one of our trusted proxies
+	var hops []string
+	for _, value := range values {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			// Garbage in the header, fall back to the proxy's own address
+			return remote
+		}
+		if i == 0 || !c.isTrusted(hop) {
+			return hop
+		}
+	}
+
+	return remote
+}
+
+// isTrusted reports whether ip belongs to a trusted proxy network
+func (c *ClientIPResolver) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range c.trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the IP of the connection a request arrived on
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// withClientIP stores the resolved client IP in the request context
+func withClientIP(r *http.Request, ip string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip))
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+)
+
+func TestClientIP(t *testing.T) {
+	resolver, err := NewClientIPResolver("x-forwarded-for", []string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("NewClientIPResolver: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		header []string
+		want   string
+	}{
+		{name: "trusted proxy", remote: "10.1.2.3:4000", header: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "trusted single address", remote: "192.168.1.1:4000", header: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "untrusted source", remote: "198.51.100.9:4000", header: []string{"203.0.113.7"}, want: "198.51.100.9"},
+		{name: "trusted proxy without the header", remote: "10.1.2.3:4000", want: "10.1.2.3"},
+		{name: "spoofed hop before the client", remote: "10.1.2.3:4000", header: []string{"1.1.1.1, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "chain of trusted proxies", remote: "10.1.2.3:4000", header: []string{"203.0.113.7, 10.9.9.9"}, want: "203.0.113.7"},
+		{name: "repeated header", remote: "10.1.2.3:4000", header: []string{"1.1.1.1", "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "every hop trusted", remote: "10.1.2.3:4000", header: []string{"10.4.4.4, 10.9.9.9"}, want: "10.4.4.4"},
+		{name: "garbage in the header", remote: "10.1.2.3:4000", header: []string{"not-an-ip"}, want: "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, value := range tt.header {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := resolver.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutHeaderUsesRemoteAddress(t *testing.T) {
+	resolver, err := NewClientIPResolver("", []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("NewClientIPResolver: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.1.2.3:4000"
+	r.Header.Set("CF-Connecting-IP", "203.0.113.7")
+	if got := resolver.ClientIP(r); got != "10.1.2.3" {
+		t.Errorf("ClientIP = %q, want the remote address", got)
+	}
+}
+
+func TestNewClientIPResolverRejectsBadCIDR(t *testing.T) {
+	if _, err := NewClientIPResolver("CF-Connecting-IP", []string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("NewClientIPResolver accepted an invalid CIDR")
+	}
+}
+
+func TestClientIPForwardedToBackend(t *testing.T) {
+	realIPs := make(chan string, 1)
+	service := backendService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		realIPs <- r.Header.Get("X-Real-IP")
+	}))
+
+	send := func(resolver *ClientIPResolver) string {
+		t.Helper()
+		server, _ := proxyServer(t, &config.Config{}, service)
+		server.logger = NewAccessLogger(resolver)
+		proxy := httptest.NewServer(server.httpHandler())
+		defer proxy.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/", nil)
+		req.Host = serviceHost
+		req.Header.Set("CF-Connecting-IP", "203.0.113.7")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET through the proxy: %v", err)
+		}
+		resp.Body.Close()
+		return <-realIPs
+	}
+
+	// The test client connects from localhost
+	trusted, _ := NewClientIPResolver("CF-Connecting-IP", []string{"127.0.0.1"})
+	if got := send(trusted); got != "203.0.113.7" {
+		t.Errorf("X-Real-IP from a trusted proxy = %q, want the header's address", got)
+	}
+
+	untrusted, _ := NewClientIPResolver("CF-Connecting-IP", []string{"10.0.0.0/8"})
+	if got := send(untrusted); got != "127.0.0.1" {
+		t.Errorf("X-Real-IP from an untrusted source = %q, want the connection's address", got)
+	}
+}
@@ -2,15 +2,26 @@ package proxy
 
 import (
 	"context"
+	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Entries looked up at least this often per TTL are refreshed before they expire
+const dnsRefreshMinHits = 2
+
 // dnsEntry represents a cached DNS resolution result
 type dnsEntry struct {
 	ips      []net.IP
 	expireAt time.Time
+
+	// refreshAt is a random point shortly before expiry, so entries cached together
+	// aren't resolved again together. hits counts the lookups served since caching.
+	refreshAt  time.Time
+	hits       atomic.Int64
+	refreshing atomic.Bool
 }
 
 // DNSCache provides a cache for DNS resolutions
@@ -18,13 +29,28 @@ type DNSCache struct {
 	cache map[string]*dnsEntry
 	mutex sync.RWMutex
 	ttl   time.Duration
+
+	// resolve performs the actual DNS resolution
+	resolve func(hostname string) ([]net.IP, error)
 }
 
 // NewDNSCache creates a new DNS cache with the specified TTL
 func NewDNSCache(ttl time.Duration) *DNSCache {
 	return &DNSCache{
-		cache: make(map[string]*dnsEntry),
-		ttl:   ttl,
+		cache:   make(map[string]*dnsEntry),
+		ttl:     ttl,
+		resolve: net.LookupIP,
+	}
+}
+
+// newEntry creates a cache entry that is refreshed within the last fifth of its TTL
+func (c *DNSCache) newEntry(ips []net.IP) *dnsEntry {
+	now := time.Now()
+	window := c.ttl / 5
+	return &dnsEntry{
+		ips:       ips,
+		expireAt:  now.Add(c.ttl),
+		refreshAt: now.Add(c.ttl - window + time.Duration(rand.Int63n(int64(window/2)+1))),
 	}
 }
 
@@ -37,41 +63,79 @@ func (c *DNSCache) Lookup(hostname string) ([]net.IP, error) {
 
 	// If we have a valid cache entry, return it
 	if exists && time.Now().Before(entry.expireAt) {
+		entry.hits.Add(1)
 		return entry.ips, nil
 	}
 
 	// Perform actual DNS resolution
-	ips, err := net.LookupIP(hostname)
+	ips, err := c.resolve(hostname)
 	if err != nil {
 		return nil, err
 	}
 
 	// Cache the result
 	c.mutex.Lock()
-	c.cache[hostname] = &dnsEntry{
-		ips:      ips,
-		expireAt: time.Now().Add(c.ttl),
-	}
+	c.cache[hostname] = c.newEntry(ips)
 	c.mutex.Unlock()
 
 	return ips, nil
 }
 
-// Cleanup periodically removes expired entries from the cache
+// Cleanup periodically removes expired entries from the cache and refreshes the
+// frequently used ones in the background before they expire
 func (c *DNSCache) Cleanup(ctx context.Context) {
-	ticker := time.NewTicker(c.ttl / 2)
+	// Tick more often than the refresh window is wide, so no due entry is missed
+	ticker := time.NewTicker(c.ttl / 20)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
 			c.removeExpiredEntries()
+			c.refreshHotEntries()
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// refreshHotEntries resolves the entries due for a refresh that were looked up often
+// enough, keeping the old addresses if the resolution fails
+func (c *DNSCache) refreshHotEntries() {
+	now := time.Now()
+
+	c.mutex.RLock()
+	due := make(map[string]*dnsEntry)
+	for hostname, entry := range c.cache {
+		if now.After(entry.refreshAt) && now.Before(entry.expireAt) && entry.hits.Load() >= dnsRefreshMinHits {
+			due[hostname] = entry
+		}
+	}
+	c.mutex.RUnlock()
+
+	for hostname, entry := range due {
+		if !entry.refreshing.CompareAndSwap(false, true) {
+			continue
+		}
+
+		go func(hostname string, entry *dnsEntry) {
+			defer entry.refreshing.Store(false)
+
+			ips, err := c.resolve(hostname)
+			if err != nil {
+				return
+			}
+
+			c.mutex.Lock()
+			// Only replace the entry that was refreshed, a lookup may have replaced it meanwhile
+			if c.cache[hostname] == entry {
+				c.cache[hostname] = c.newEntry(ips)
+			}
+			c.mutex.Unlock()
+		}(hostname, entry)
+	}
+}
+
 // removeExpiredEntries removes expired entries from the cache
 func (c *DNSCache) removeExpiredEntries() {
 	now := time.Now()
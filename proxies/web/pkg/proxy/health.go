@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Timeout for the Redis check of the readiness endpoint
+const healthCheckTimeout = 2 * time.Second
+
+// readinessChecks returns the result of every readiness check, "ok" or the reason it failed
+func (s *Server) readinessChecks(ctx context.Context) map[string]string {
+	checks := make(map[string]string)
+
+	if s.kubeClient.Synced() {
+		checks["kubernetes"] = "ok"
+	} else {
+		checks["kubernetes"] = "service watcher not started or not synced"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	if err := s.redisClient.Ping(ctx); err != nil {
+		checks["redis"] = err.Error()
+	} else {
+		checks["redis"] = "ok"
+	}
+
+	// Subdomains of the wildcard domain are served with the wildcard certificate, other
+	// domains get theirs on their first handshake
+	if s.config.EnableHTTPS {
+		if s.certManager.WildcardReady() {
+			checks["certificates"] = "ok"
+		} else {
+			checks["certificates"] = "wildcard certificate not loaded"
+		}
+	}
+
+	return checks
+}
+
+// handleHealthz answers 200 once the proxy can route requests, 503 with the failed checks
+// until then
+// GET /healthz
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	checks := s.readinessChecks(r.Context())
+
+	status := "ok"
+	code := http.StatusOK
+	for _, result := range checks {
+		if result != "ok" {
+			status = "error"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// handleLivez answers 200 as long as the process serves HTTP
+// GET /livez
+func (s *Server) handleLivez(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
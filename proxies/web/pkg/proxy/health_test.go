@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type healthzResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// healthz asks server whether it is ready
+func healthz(t *testing.T, server *Server) (int, healthzResponse) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.handleHealthz(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	var decoded healthzResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return recorder.Code, decoded
+}
+
+// watchingServer returns a proxy with a watcher of no services, started and synced when
+// start is set
+func watchingServer(t *testing.T, cfg *config.Config, start bool) *Server {
+	t.Helper()
+	server, _ := proxyServer(t, cfg, &kubernetes.ServiceInfo{ServiceID: "web"})
+	server.kubeClient = kubernetes.NewClientForClientset(fake.NewSimpleClientset(), []string{"type=web"})
+	t.Cleanup(server.kubeClient.StopWatching)
+	if start {
+		if err := server.kubeClient.StartWatching(server.handleServicesChanged); err != nil {
+			t.Fatalf("StartWatching: %v", err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for !server.kubeClient.Synced() {
+			if time.Now().After(deadline) {
+				t.Fatal("the watcher didn't sync")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	return server
+}
+
+func TestHealthzWithUnstartedWatcher(t *testing.T) {
+	server := watchingServer(t, &config.Config{}, false)
+
+	code, health := healthz(t, server)
+	if code != http.StatusServiceUnavailable || health.Status != "error" {
+		t.Errorf("healthz = %d %s, want %d", code, health.Status, http.StatusServiceUnavailable)
+	}
+	if health.Checks["kubernetes"] == "ok" || health.Checks["redis"] != "ok" {
+		t.Errorf("checks = %v, want only the watcher failing", health.Checks)
+	}
+}
+
+func TestHealthzWithStartedWatcher(t *testing.T) {
+	server := watchingServer(t, &config.Config{}, true)
+
+	code, health := healthz(t, server)
+	if code != http.StatusOK || health.Status != "ok" {
+		t.Errorf("healthz = %d %s with checks %v, want %d", code, health.Status, health.Checks, http.StatusOK)
+	}
+	if _, ok := health.Checks["certificates"]; ok {
+		t.Errorf("checks = %v, want no certificate check without HTTPS", health.Checks)
+	}
+}
+
+func TestHealthzWaitsForWildcardCertificate(t *testing.T) {
+	server := watchingServer(t, &config.Config{EnableHTTPS: true}, true)
+	server.certManager = &CertManager{enableWildcard: true, wildcardDomain: "example.com"}
+
+	if code, health := healthz(t, server); code != http.StatusServiceUnavailable || health.Checks["certificates"] == "ok" {
+		t.Errorf("healthz = %d with checks %v, want %d until the wildcard certificate is loaded", code, health.Checks, http.StatusServiceUnavailable)
+	}
+
+	server.certManager.wildcardCert = testCertificate(t, "*.example.com")
+	if code, health := healthz(t, server); code != http.StatusOK || health.Checks["certificates"] != "ok" {
+		t.Errorf("healthz = %d with checks %v, want %d", code, health.Checks, http.StatusOK)
+	}
+
+	// Without wildcard certificates there is nothing to wait for
+	server.certManager = &CertManager{}
+	if code, health := healthz(t, server); code != http.StatusOK {
+		t.Errorf("healthz = %d with checks %v, want %d", code, health.Checks, http.StatusOK)
+	}
+}
@@ -0,0 +1,30 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// listen opens the listener of the HTTP or HTTPS server, with SO_REUSEPORT and the backlog
+// of the config when they are set
+func (s *Server) listen(ctx context.Context, addr string) (net.Listener, error) {
+	var lc net.ListenConfig
+	if s.config.ReusePort {
+		lc.Control = reusePort
+	}
+
+	ln, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.ListenBacklog > 0 {
+		if err := setBacklog(ln, s.config.ListenBacklog); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to set listen backlog: %v", err)
+		}
+	}
+
+	return ln, nil
+}
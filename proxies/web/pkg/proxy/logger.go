@@ -2,24 +2,42 @@ package proxy
 
 import (
 	"bufio"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
+	mathrand "math/rand"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 )
 
+// RequestIDHeader is the header used to correlate a request across the proxy, backends and the API
+const RequestIDHeader = "X-Request-ID"
+
+// Headers left out of slow request logs because they carry credentials
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+}
+
 // AccessLogger is a struct that represents an Nginx-like access logger
 type AccessLogger struct {
 	// Standard logger
 	logger *log.Logger
+
+	// clientIPs resolves the client IP of each request
+	clientIPs *ClientIPResolver
 }
 
 // NewAccessLogger creates a new access logger
-func NewAccessLogger() *AccessLogger {
+func NewAccessLogger(clientIPs *ClientIPResolver) *AccessLogger {
 	return &AccessLogger{
-		logger: log.Default(),
+		logger:    log.Default(),
+		clientIPs: clientIPs,
 	}
 }
 
@@ -28,6 +46,8 @@ type LogResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
 	size       int
+	// unsampled is set when the request fell outside its service's access log sample
+	unsampled bool
 }
 
 // NewLogResponseWriter creates a new LogResponseWriter
@@ -51,6 +71,17 @@ func (lrw *LogResponseWriter) Write(b []byte) (int, error) {
 	return size, err
 }
 
+// SetSampleRate decides once per request whether a successful response is logged,
+// rate is the fraction of requests to keep. Wrapped LogResponseWriters share the decision
+// so every log line of the request agrees.
+func (lrw *LogResponseWriter) SetSampleRate(rate float64) {
+	unsampled := rate < 1 && mathrand.Float64() >= rate
+	for current := lrw; current != nil; {
+		current.unsampled = unsampled
+		current, _ = current.ResponseWriter.(*LogResponseWriter)
+	}
+}
+
 // Hijack implements the http.Hijacker interface for WebSocket support
 func (lrw *LogResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	hijacker, ok := lrw.ResponseWriter.(http.Hijacker)
@@ -60,24 +91,34 @@ func (lrw *LogResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return hijacker.Hijack()
 }
 
-// GetClientIP extracts the client IP from the request
+// Unwrap returns the underlying ResponseWriter so http.ResponseController can reach it
+func (lrw *LogResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
+
+// GetClientIP returns the client IP resolved by the access log middleware,
+// or the connection's remote address for requests that didn't pass through it
 func GetClientIP(r *http.Request) string {
-	// Check for X-Forwarded-For header first
-	xForwardedFor := r.Header.Get("X-Forwarded-For")
-	if xForwardedFor != "" {
-		// Extract the first IP in the list
-		ips := strings.Split(xForwardedFor, ",")
-		return strings.TrimSpace(ips[0])
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// EnsureRequestID returns the request ID of the request, generating and setting one if missing
+func EnsureRequestID(r *http.Request) string {
+	if requestID := r.Header.Get(RequestIDHeader); requestID != "" {
+		return requestID
 	}
 
-	// Check for X-Real-IP header
-	xRealIP := r.Header.Get("X-Real-IP")
-	if xRealIP != "" {
-		return xRealIP
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
 	}
 
-	// Use RemoteAddr as fallback
-	return strings.Split(r.RemoteAddr, ":")[0]
+	requestID := hex.EncodeToString(b)
+	r.Header.Set(RequestIDHeader, requestID)
+	return requestID
 }
 
 // LogRequest logs a request in Nginx-like format
@@ -85,7 +126,7 @@ func (al *AccessLogger) LogRequest(w http.ResponseWriter, r *http.Request, durat
 	lrw, ok := w.(*LogResponseWriter)
 	if !ok {
 		// If we didn't use our wrapper, we can't get status code and size
-		al.logger.Printf("%s - %s \"%s %s %s\" - - \"unknown\" \"%s\" %s",
+		al.logger.Printf("%s - %s \"%s %s %s\" - - \"unknown\" \"%s\" %s request_id=%s",
 			GetClientIP(r),
 			r.Host,
 			r.Method,
@@ -93,16 +134,22 @@ func (al *AccessLogger) LogRequest(w http.ResponseWriter, r *http.Request, durat
 			r.Proto,
 			r.UserAgent(),
 			upstream,
+			r.Header.Get(RequestIDHeader),
 		)
 		return
 	}
 
+	// Responses outside 2xx are always logged
+	if lrw.unsampled && lrw.statusCode >= 200 && lrw.statusCode < 300 {
+		return
+	}
+
 	// Calculate duration in milliseconds
 	durationMs := float64(duration.Nanoseconds()) / 1e6
 
 	// Format log line in Nginx-like format
-	// IP - [time] "METHOD PATH HTTP/VERSION" STATUS SIZE "REFERER" "USER-AGENT" RT=duration UPSTREAM=upstream
-	logLine := fmt.Sprintf("%s - \"%s %s %s\" %d %d \"%s\" \"%s\" rt=%.2fms upstream=%s",
+	// IP - [time] "METHOD PATH HTTP/VERSION" STATUS SIZE "REFERER" "USER-AGENT" RT=duration UPSTREAM=upstream REQUEST_ID=id
+	logLine := fmt.Sprintf("%s - \"%s %s %s\" %d %d \"%s\" \"%s\" rt=%.2fms upstream=%s request_id=%s",
 		GetClientIP(r),
 		r.Method,
 		r.RequestURI,
@@ -113,6 +160,7 @@ func (al *AccessLogger) LogRequest(w http.ResponseWriter, r *http.Request, durat
 		r.UserAgent(),
 		durationMs,
 		upstream,
+		r.Header.Get(RequestIDHeader),
 	)
 
 	// Add host information
@@ -123,11 +171,49 @@ func (al *AccessLogger) LogRequest(w http.ResponseWriter, r *http.Request, durat
 	al.logger.Println(logLine)
 }
 
+// LogSlowRequest logs a warning for a proxied request that took longer than the slow
+// request threshold, with its headers if requested
+func (al *AccessLogger) LogSlowRequest(r *http.Request, duration time.Duration, upstream string, withHeaders bool) {
+	logLine := fmt.Sprintf("[WARN] Slow request: host=%s path=%s rt=%.2fms upstream=%s request_id=%s",
+		r.Host,
+		r.URL.Path,
+		float64(duration.Nanoseconds())/1e6,
+		upstream,
+		r.Header.Get(RequestIDHeader),
+	)
+
+	if withHeaders {
+		names := make([]string, 0, len(r.Header))
+		for name := range r.Header {
+			if !redactedHeaders[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		headers := make([]string, 0, len(names))
+		for _, name := range names {
+			headers = append(headers, fmt.Sprintf("%s=%q", name, strings.Join(r.Header[name], ", ")))
+		}
+		logLine = fmt.Sprintf("%s headers=[%s]", logLine, strings.Join(headers, " "))
+	}
+
+	al.logger.Println(logLine)
+}
+
 // Middleware creates a middleware that logs requests
 func (al *AccessLogger) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		// Resolve the client IP once for everything that handles the request
+		r = withClientIP(r, al.clientIPs.ClientIP(r))
+
+		// Tag the request with an ID and echo it back to the client
+		if requestID := EnsureRequestID(r); requestID != "" {
+			w.Header().Set(RequestIDHeader, requestID)
+		}
+
 		// Create response writer wrapper
 		lrw := NewLogResponseWriter(w)
 
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	received := make(chan string, 1)
+	service := backendService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(RequestIDHeader)
+	}))
+	server, _ := proxyServer(t, &config.Config{}, service)
+	proxy := httptest.NewServer(server.httpHandler())
+	defer proxy.Close()
+
+	send := func(requestID string) (echoed, forwarded string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/", nil)
+		req.Host = serviceHost
+		if requestID != "" {
+			req.Header.Set(RequestIDHeader, requestID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET through the proxy: %v", err)
+		}
+		resp.Body.Close()
+		return resp.Header.Get(RequestIDHeader), <-received
+	}
+
+	// A provided ID is echoed to the client and forwarded to the backend as it is
+	echoed, forwarded := send("client-id-1")
+	if echoed != "client-id-1" || forwarded != "client-id-1" {
+		t.Errorf("provided ID echoed as %q and forwarded as %q, want client-id-1", echoed, forwarded)
+	}
+
+	// A missing one is generated, the client and the backend see the same ID
+	echoed, forwarded = send("")
+	if len(echoed) != 32 {
+		t.Errorf("generated ID %q is not 16 hex-encoded bytes", echoed)
+	}
+	if forwarded != echoed {
+		t.Errorf("backend got ID %q, client got %q", forwarded, echoed)
+	}
+	if other, _ := send(""); other == echoed {
+		t.Errorf("two requests were given the same ID %q", other)
+	}
+}
+
+func TestAccessLogSampleRateZeroLogsOnlyErrors(t *testing.T) {
+	service := backendService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	service.AccessLogSampleRate = 0
+	server, _ := proxyServer(t, &config.Config{}, service)
+	var logs bytes.Buffer
+	server.logger.logger = log.New(&logs, "", 0)
+	proxy := httptest.NewServer(server.httpHandler())
+
+	for _, path := range []string{"/ok", "/missing", "/ok", "/broken"} {
+		req, _ := http.NewRequest(http.MethodGet, proxy.URL+path, nil)
+		req.Host = serviceHost
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s through the proxy: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	// Close waits for the handlers, so every access log line has been written
+	proxy.Close()
+
+	if strings.Contains(logs.String(), "GET /ok") {
+		t.Errorf("successful requests were logged at sample rate 0:\n%s", logs.String())
+	}
+	for _, want := range []string{"\"GET /missing HTTP/1.1\" 404", "\"GET /broken HTTP/1.1\" 500"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("access log is missing %s:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestSlowRequestIsLogged(t *testing.T) {
+	service := backendService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	server, _ := proxyServer(t, &config.Config{SlowRequestThresholdMs: 50, LogSlowRequestHeaders: true}, service)
+	var logs bytes.Buffer
+	server.logger.logger = log.New(&logs, "", 0)
+	proxy := httptest.NewServer(server.httpHandler())
+
+	for _, path := range []string{"/fast", "/slow"} {
+		req, _ := http.NewRequest(http.MethodGet, proxy.URL+path, nil)
+		req.Host = serviceHost
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("X-Trace", "abc")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s through the proxy: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	proxy.Close()
+
+	var slow []string
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.HasPrefix(line, "[WARN] Slow request") {
+			slow = append(slow, line)
+		}
+	}
+	if len(slow) != 1 {
+		t.Fatalf("logged %d slow requests, want 1:\n%s", len(slow), logs.String())
+	}
+	for _, want := range []string{"host=" + serviceHost, "path=/slow", "upstream=127.0.0.1:", `X-Trace="abc"`} {
+		if !strings.Contains(slow[0], want) {
+			t.Errorf("slow request log %q is missing %s", slow[0], want)
+		}
+	}
+	if strings.Contains(slow[0], "secret") || strings.Contains(slow[0], "Authorization") {
+		t.Errorf("slow request log %q has the credentials", slow[0])
+	}
+}
+
+func TestSlowRequestLogDisabled(t *testing.T) {
+	service := backendService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	server, _ := proxyServer(t, &config.Config{}, service)
+	var logs bytes.Buffer
+	server.logger.logger = log.New(&logs, "", 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = serviceHost
+	server.handleProxyRequest(httptest.NewRecorder(), req)
+	if strings.Contains(logs.String(), "Slow request") {
+		t.Errorf("logged a slow request without a threshold:\n%s", logs.String())
+	}
+}
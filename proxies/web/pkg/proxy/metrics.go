@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Upper bounds of the histogram buckets, in seconds and bytes
+var (
+	durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+	sizeBuckets     = []float64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20}
+)
+
+// histogram is a cumulative histogram in the Prometheus exposition format
+type histogram struct {
+	name    string
+	help    string
+	buckets []float64
+	counts  []uint64 // counts[i] is the number of observations <= buckets[i]
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(name, help string, buckets []float64) *histogram {
+	return &histogram{
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+func (h *histogram) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", h.name, h.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+	for i, bound := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, strconv.FormatFloat(bound, 'f', -1, 64), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.name, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", h.name, h.count)
+}
+
+// requestMetrics holds the histograms of proxied requests served on the admin API
+type requestMetrics struct {
+	mu           sync.Mutex
+	duration     *histogram
+	requestSize  *histogram
+	responseSize *histogram
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{
+		duration:     newHistogram("proxy_request_duration_seconds", "Duration of proxied requests.", durationBuckets),
+		requestSize:  newHistogram("proxy_request_body_bytes", "Size of the request bodies of proxied requests.", sizeBuckets),
+		responseSize: newHistogram("proxy_response_body_bytes", "Size of the response bodies of proxied requests.", sizeBuckets),
+	}
+}
+
+// observe records a proxied request
+func (m *requestMetrics) observe(duration time.Duration, requestBytes, responseBytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.duration.observe(duration.Seconds())
+	m.requestSize.observe(float64(requestBytes))
+	m.responseSize.observe(float64(responseBytes))
+}
+
+// write writes the metrics in the Prometheus text format
+func (m *requestMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.duration.write(w)
+	m.requestSize.write(w)
+	m.responseSize.write(w)
+}
+
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	io.ReadCloser
+	n atomic.Int64 // The transport may still be reading when the handler returns
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+)
+
+// Reload applies the settings of a new configuration that don't need the listeners to be
+// restarted, and logs the changed settings that only take effect after a restart
+func (s *Server) Reload(cfg *config.Config) {
+	current := s.live.Load()
+
+	next := *current
+	next.UpstreamResponseTimeout = cfg.UpstreamResponseTimeout
+	next.EnableTunnels = cfg.EnableTunnels
+	next.RateLimitCooldownMinutes = cfg.RateLimitCooldownMinutes
+	next.RenewBeforeDays = cfg.RenewBeforeDays
+	next.Debug = cfg.Debug
+	next.SlowRequestThresholdMs = cfg.SlowRequestThresholdMs
+	next.LogSlowRequestHeaders = cfg.LogSlowRequestHeaders
+	next.AdminToken = cfg.AdminToken
+
+	applied := config.ChangedFields(current, &next)
+	restart := config.ChangedFields(&next, cfg)
+
+	if next.UpstreamResponseTimeout != current.UpstreamResponseTimeout {
+		previous := s.transport.Swap(newUpstreamTransport(&next))
+		// Requests in flight finish on the previous transport
+		previous.CloseIdleConnections()
+	}
+	if s.certManager != nil {
+		s.certManager.SetDefaultRateLimitCooldown(time.Duration(next.RateLimitCooldownMinutes) * time.Minute)
+		s.certManager.SetRenewBefore(time.Duration(next.RenewBeforeDays) * 24 * time.Hour)
+	}
+	s.kubeClient.SetDebug(next.Debug)
+
+	s.live.Store(&next)
+
+	if len(applied) == 0 {
+		log.Println("Configuration reloaded, no reloadable setting changed")
+	} else {
+		log.Printf("Configuration reloaded, applied: %s", strings.Join(applied, ", "))
+	}
+	if len(restart) > 0 {
+		log.Printf("Changed settings that need a restart, kept as they were: %s", strings.Join(restart, ", "))
+	}
+}
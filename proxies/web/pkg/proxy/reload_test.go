@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+)
+
+// reloadFile loads content as a config file, the way SIGHUP reloads it
+func reloadFile(t *testing.T, content string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	return cfg
+}
+
+func TestReloadUpdatesUpstreamTimeout(t *testing.T) {
+	service := backendService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(1500 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	cfg := config.DefaultConfig()
+	cfg.UpstreamResponseTimeout = 1
+	server, _ := proxyServer(t, cfg, service)
+	server.kubeClient = &kubernetes.Client{}
+
+	if resp := proxyRequest(t, server, http.MethodGet); resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("status before the reload = %d, want 504", resp.StatusCode)
+	}
+
+	server.Reload(reloadFile(t, `{"upstream_response_timeout": 5}`))
+	if got := server.live.Load().UpstreamResponseTimeout; got != 5 {
+		t.Errorf("live upstream_response_timeout = %d, want 5", got)
+	}
+	if resp := proxyRequest(t, server, http.MethodGet); resp.StatusCode != http.StatusOK {
+		t.Errorf("status after the reload = %d, want the slow response", resp.StatusCode)
+	}
+}
+
+func TestReloadKeepsSettingsThatNeedARestart(t *testing.T) {
+	cfg := config.DefaultConfig()
+	server, _ := proxyServer(t, cfg, &kubernetes.ServiceInfo{ServiceID: "web"})
+	server.kubeClient = &kubernetes.Client{}
+	server.certManager = &CertManager{}
+
+	server.Reload(reloadFile(t, `{
+		"http_addr": ":8080",
+		"redis_addr": "other-redis:6379",
+		"rate_limit_cooldown_minutes": 15,
+		"renew_before_days": 14,
+		"debug": true
+	}`))
+
+	live := server.live.Load()
+	if live.HTTPAddr != ":80" || live.RedisAddr != "redis:6379" {
+		t.Errorf("http_addr %q and redis_addr %q were reloaded, want them kept until a restart", live.HTTPAddr, live.RedisAddr)
+	}
+	if live.RateLimitCooldownMinutes != 15 || live.RenewBeforeDays != 14 || !live.Debug {
+		t.Errorf("live config = %+v, want the reloadable settings applied", live)
+	}
+	if got := time.Duration(server.certManager.rateLimitCooldown.Load()); got != 15*time.Minute {
+		t.Errorf("rate limit cooldown = %v, want 15m", got)
+	}
+	if got := server.certManager.renewalWindow(); got != 14*24*time.Hour {
+		t.Errorf("renewal window = %v, want 14 days", got)
+	}
+	// The configuration the server started with is left alone
+	if cfg.RateLimitCooldownMinutes != 60 {
+		t.Errorf("reload changed the startup config")
+	}
+}
@@ -3,6 +3,7 @@ package proxy
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -10,11 +11,14 @@ import (
 	"net/http/httputil"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/deployra/deployra/proxies/web/pkg/config"
 	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
 	"github.com/deployra/deployra/proxies/web/pkg/redis"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // Server represents the proxy server
@@ -24,22 +28,34 @@ type Server struct {
 	redisClient  *redis.Client
 	httpServer   *http.Server
 	httpsServer  *http.Server
+	adminServer  *http.Server
 	certManager  *CertManager
 	services     map[string]*kubernetes.ServiceInfo
 	routingTable map[string]string
 	routingLock  sync.RWMutex
 	redirects    map[string]string
 	logger       *AccessLogger
-	dnsCache     *DNSCache // Cache for DNS resolutions
+	dnsCache     *DNSCache        // Cache for DNS resolutions
+	metrics      *requestMetrics  // Histograms of proxied requests for the admin API
+	h2cTransport *http2.Transport // Shared transport for gRPC and other cleartext HTTP/2 upstream requests
+	objectStore  *ObjectStore     // Object storage client for static sites
+	defaultCert  *tls.Certificate // Certificate for TLS clients that don't send SNI
+
+	// Reload swaps these while requests are being served
+	live      atomic.Pointer[config.Config]  // Latest configuration, s.config is the one the server started with
+	transport atomic.Pointer[http.Transport] // Shared transport for non-WebSocket upstream requests
 }
 
 // NewServer creates a new proxy server
 func NewServer(cfg *config.Config) (*Server, error) {
 	// Create Kubernetes client
-	kubeClient, err := kubernetes.NewClient(cfg.KubeConfigPath, cfg.LabelSelector)
+	kubeClient, err := kubernetes.NewClient(cfg.KubeConfigPath, cfg.Selectors())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
 	}
+	kubeClient.SetWatchNamespaces(cfg.WatchNamespaces)
+	kubeClient.SetServiceTypes(cfg.ServiceTypes)
+	kubeClient.SetDebug(cfg.Debug)
 
 	// Create Redis client for scale-to-zero functionality
 	redisClient, err := redis.NewClient(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
@@ -50,23 +66,28 @@ func NewServer(cfg *config.Config) (*Server, error) {
 	// Create certificate manager if HTTPS is enabled
 	var certManager *CertManager
 	if cfg.EnableHTTPS {
-		// Setup wildcard configuration if enabled
+		// Setup DNS-01 configuration, used for the wildcard certificate when enabled
 		var wildcardCfg *WildcardConfig
-		if cfg.EnableWildcard && cfg.WildcardDomain != "" && cfg.CloudflareAPIToken != "" {
+		if cfg.CloudflareAPIToken != "" {
 			wildcardCfg = &WildcardConfig{
-				Enable:             cfg.EnableWildcard,
+				Enable:             cfg.EnableWildcard && cfg.WildcardDomain != "",
 				Domain:             cfg.WildcardDomain,
 				CloudflareAPIToken: cfg.CloudflareAPIToken,
 			}
-			log.Printf("Wildcard certificate enabled for *.%s", cfg.WildcardDomain)
 		}
 
-		certManager, err = NewCertManager(cfg.Email, cfg.AcmeServerURL, kubeClient, redisClient, wildcardCfg)
+		certManager, err = NewCertManager(cfg.Email, cfg.AcmeServerURL, cfg.AcmeAccountSecret, kubeClient, redisClient, wildcardCfg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create certificate manager: %v", err)
 		}
 	}
 
+	// Resolve client IPs from the trusted proxy header, if one is configured
+	clientIPs, err := NewClientIPResolver(cfg.TrustedProxyHeader, cfg.TrustedProxyCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create server instance
 	server := &Server{
 		config:       cfg,
@@ -75,9 +96,37 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		certManager:  certManager,
 		services:     make(map[string]*kubernetes.ServiceInfo),
 		routingTable: make(map[string]string),
-		logger:       NewAccessLogger(),
+		logger:       NewAccessLogger(clientIPs),
 		dnsCache:     NewDNSCache(5 * time.Minute), // 5-minute TTL for DNS cache entries
 		redirects:    make(map[string]string),
+		h2cTransport: newH2CTransport(),
+		metrics:      newRequestMetrics(),
+	}
+	server.live.Store(cfg)
+	server.transport.Store(newUpstreamTransport(cfg))
+
+	// Services choose the ACME challenge of their domains
+	if certManager != nil {
+		certManager.SetChallengeResolver(server.acmeChallengeFor)
+		certManager.SetDefaultRateLimitCooldown(time.Duration(cfg.RateLimitCooldownMinutes) * time.Minute)
+		certManager.SetRenewBefore(time.Duration(cfg.RenewBeforeDays) * 24 * time.Hour)
+	}
+
+	// Clients without SNI get the configured default certificate
+	if cfg.EnableHTTPS && cfg.DefaultCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.DefaultCertFile, cfg.DefaultKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load default certificate: %v", err)
+		}
+		server.defaultCert = &cert
+	}
+
+	// Static sites are served from object storage when an endpoint is configured
+	if cfg.ObjectStorageEndpoint != "" {
+		server.objectStore, err = NewObjectStore(cfg, server.transport.Load())
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	log.Printf("Web proxy initialized with DNS cache (5-minute TTL)")
@@ -105,6 +154,16 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		}
 	}
 
+	// Create admin server if an address is configured
+	if cfg.AdminAddr != "" {
+		server.adminServer = &http.Server{
+			Addr:         cfg.AdminAddr,
+			Handler:      server.adminHandler(),
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+	}
+
 	return server, nil
 }
 
@@ -115,8 +174,13 @@ func (s *Server) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, e
 		return nil, fmt.Errorf("HTTPS is disabled in configuration")
 	}
 
+	// Without SNI there is no domain to pick a certificate for
+	if hello.ServerName == "" {
+		return s.defaultCertificate()
+	}
+
 	// Check redirect found
-	redirectDomain, redirectFound := s.redirects[strings.ToLower(hello.ServerName)]
+	redirectDomain, redirectFound := s.redirectFor(hello.ServerName)
 	if redirectFound {
 		// Use the redirect domain to look up certificate
 		log.Printf("Using %s certificate for %s", redirectDomain, hello.ServerName)
@@ -135,6 +199,33 @@ func (s *Server) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, e
 	return s.certManager.GetCertificate(hello)
 }
 
+// defaultCertificate returns the certificate for TLS clients that don't send SNI: the
+// configured default certificate, else the wildcard certificate
+func (s *Server) defaultCertificate() (*tls.Certificate, error) {
+	if s.defaultCert != nil {
+		return s.defaultCert, nil
+	}
+
+	cert, err := s.certManager.WildcardCertificate()
+	if err != nil {
+		return nil, fmt.Errorf("no default certificate for a client without SNI: %v", err)
+	}
+	return cert, nil
+}
+
+// acmeChallengeFor returns the ACME challenge requested by the service routing a domain
+func (s *Server) acmeChallengeFor(domain string) string {
+	s.routingLock.RLock()
+	defer s.routingLock.RUnlock()
+
+	if serviceKey, exists := s.routingTable[domain]; exists {
+		if info := s.services[serviceKey]; info != nil {
+			return info.AcmeChallenge
+		}
+	}
+	return ""
+}
+
 // Start starts the proxy server
 func (s *Server) Start(ctx context.Context) error {
 	// Start Kubernetes watcher
@@ -148,18 +239,30 @@ func (s *Server) Start(ctx context.Context) error {
 	go s.dnsCache.Cleanup(ctx)
 
 	// Start HTTP server
+	httpListener, err := s.listen(ctx, s.config.HTTPAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", s.config.HTTPAddr, err)
+	}
 	go func() {
 		log.Printf("Starting HTTP server on %s", s.config.HTTPAddr)
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.httpServer.Serve(httpListener); err != nil && err != http.ErrServerClosed {
 			log.Printf("HTTP server error: %v", err)
 		}
 	}()
 
 	// Start HTTPS server if enabled
 	if s.config.EnableHTTPS {
+		httpsListener, err := s.listen(ctx, s.config.HTTPSAddr)
+		if err != nil {
+			// Don't leave the HTTP server and the watcher running behind a failed start
+			s.httpServer.Close()
+			httpListener.Close()
+			s.kubeClient.StopWatching()
+			return fmt.Errorf("failed to listen on %s: %v", s.config.HTTPSAddr, err)
+		}
 		go func() {
 			log.Printf("Starting HTTPS server on %s", s.config.HTTPSAddr)
-			if err := s.httpsServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			if err := s.httpsServer.ServeTLS(httpsListener, "", ""); err != nil && err != http.ErrServerClosed {
 				log.Printf("HTTPS server error: %v", err)
 			}
 		}()
@@ -167,6 +270,16 @@ func (s *Server) Start(ctx context.Context) error {
 		log.Println("HTTPS server is disabled by configuration")
 	}
 
+	// Start admin server if enabled
+	if s.adminServer != nil {
+		go func() {
+			log.Printf("Starting admin server on %s", s.config.AdminAddr)
+			if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Admin server error: %v", err)
+			}
+		}()
+	}
+
 	// Wait for context cancellation to stop servers
 	<-ctx.Done()
 	log.Println("Shutting down servers...")
@@ -190,6 +303,12 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}
 
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Admin server shutdown error: %v", err)
+		}
+	}
+
 	// Close the Redis client
 	if err := s.redisClient.Close(); err != nil {
 		log.Printf("Redis client close error: %v", err)
@@ -208,47 +327,83 @@ func (s *Server) handleServicesChanged(action kubernetes.ServiceInfoAction, serv
 	if action == kubernetes.Add {
 		// Add a nil check for info in the Add case as well
 		if info != nil {
+			// A modified service may have dropped domains or its redirect
+			if existingInfo, exists := s.services[serviceKey]; exists && existingInfo != nil {
+				s.removeRoutesLocked(serviceKey, existingInfo)
+			}
+
 			s.services[serviceKey] = info
 			for _, domain := range info.Domains {
 				s.routingTable[domain] = serviceKey
 			}
+			if info.RedirectFrom != "" {
+				s.redirects[info.RedirectFrom] = info.RedirectTo
+			}
 		} else {
 			log.Printf("Warning: Received nil ServiceInfo for Add action on service %s", serviceKey)
 		}
 	} else if action == kubernetes.Delete {
-		if s.services != nil {
-			delete(s.services, serviceKey)
-		}
-
 		// When deleting a service, info might be nil
 		// Get the domains from the existing service info before deleting
-		var domains []string
 		if existingInfo, exists := s.services[serviceKey]; exists && existingInfo != nil {
-			domains = existingInfo.Domains
+			s.removeRoutesLocked(serviceKey, existingInfo)
 		} else if info != nil {
-			domains = info.Domains
+			s.removeRoutesLocked(serviceKey, info)
 		}
 
-		// Delete routing table entries for all domains
-		if s.routingTable != nil && len(domains) > 0 {
-			for _, domain := range domains {
-				delete(s.routingTable, domain)
-			}
+		if s.services != nil {
+			delete(s.services, serviceKey)
 		}
 	}
 
 	s.routingLock.Unlock()
 }
 
+// removeRoutesLocked deletes the routing table entries and redirect of a service, leaving
+// domains another service has taken over since. The caller holds routingLock.
+func (s *Server) removeRoutesLocked(serviceKey string, info *kubernetes.ServiceInfo) {
+	for _, domain := range info.Domains {
+		if s.routingTable[domain] == serviceKey {
+			delete(s.routingTable, domain)
+		}
+	}
+	if info.RedirectFrom != "" && s.redirects[info.RedirectFrom] == info.RedirectTo {
+		delete(s.redirects, info.RedirectFrom)
+	}
+}
+
+// redirectFor returns the host a host is redirected to, if any
+func (s *Server) redirectFor(host string) (string, bool) {
+	s.routingLock.RLock()
+	defer s.routingLock.RUnlock()
+
+	target, ok := s.redirects[strings.ToLower(host)]
+	return target, ok
+}
+
+// applyServiceTimeouts replaces the connection deadlines the server set from the global
+// WebSocket timeouts with the service's own, if it has any
+func applyServiceTimeouts(w http.ResponseWriter, info *kubernetes.ServiceInfo) {
+	rc := http.NewResponseController(w)
+	if info.ReadTimeout > 0 {
+		if err := rc.SetReadDeadline(time.Now().Add(info.ReadTimeout)); err != nil {
+			log.Printf("Failed to set read timeout of service %s/%s: %v", info.Namespace, info.Name, err)
+		}
+	}
+	if info.WriteTimeout > 0 {
+		if err := rc.SetWriteDeadline(time.Now().Add(info.WriteTimeout)); err != nil {
+			log.Printf("Failed to set write timeout of service %s/%s: %v", info.Namespace, info.Name, err)
+		}
+	}
+}
+
 // httpHandler returns the HTTP handler for HTTP requests
 func (s *Server) httpHandler() http.Handler {
 	mux := http.NewServeMux()
 
-	// Add health check endpoint
-	mux.HandleFunc("/healthz", s.logger.WrapHandlerFunc("healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	}))
+	// Add health check endpoints, /healthz only passes once the proxy can route requests
+	mux.HandleFunc("/healthz", s.logger.WrapHandlerFunc("healthz", s.handleHealthz))
+	mux.HandleFunc("/livez", s.logger.WrapHandlerFunc("livez", s.handleLivez))
 
 	// If HTTPS is enabled, handle ACME challenges and redirect to HTTPS
 	if s.config.EnableHTTPS {
@@ -269,6 +424,9 @@ func (s *Server) httpHandler() http.Handler {
 		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 			s.handleProxyRequest(w, r)
 		})
+
+		// Accept cleartext HTTP/2 as well, so gRPC clients can reach services without TLS
+		return h2c.NewHandler(s.logger.Middleware(s.connectHandler(mux)), &http2.Server{})
 	}
 
 	return s.logger.Middleware(mux)
@@ -279,10 +437,33 @@ func (s *Server) httpsHandler() http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// A connection without SNI was served the default certificate, which doesn't
+		// belong to the requested host
+		if r.TLS != nil && r.TLS.ServerName == "" {
+			http.Error(w, "Misdirected Request: the TLS handshake did not name a host (SNI)", http.StatusMisdirectedRequest)
+			return
+		}
 		s.handleProxyRequest(w, r)
 	})
 
-	return s.logger.Middleware(mux)
+	return s.logger.Middleware(s.connectHandler(mux))
+}
+
+// connectHandler sends CONNECT requests to the tunnel handler when tunnels are enabled,
+// ServeMux would otherwise route them by path and reject them
+func (s *Server) connectHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !s.live.Load().EnableTunnels {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			s.logger.LogRequest(w, r, 0, "tunnel-disabled")
+			return
+		}
+		s.handleConnect(w, r)
+	})
 }
 
 // handleProxyRequest handles proxying a request to the appropriate backend service
@@ -291,9 +472,10 @@ func (s *Server) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
 	// Check redirect found
-	redirectDomain, redirectFound := s.redirects[strings.ToLower(r.Host)]
+	redirectDomain, redirectFound := s.redirectFor(r.Host)
 	if redirectFound {
-		targetURL := fmt.Sprintf("https://%s%s", redirectDomain, "/")
+		// Keep the path and query, links to the other form of a domain land on the same page
+		targetURL := fmt.Sprintf("https://%s%s", redirectDomain, r.URL.RequestURI())
 		log.Printf("301 Redirecting HTTP %s to %s", r.Host, targetURL)
 		http.Redirect(w, r, targetURL, http.StatusMovedPermanently) // 301 redirect
 
@@ -324,88 +506,38 @@ func (s *Server) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Service not found", http.StatusNotFound)
 		return
 	}
+	lrw.SetSampleRate(routingService.AccessLogSampleRate)
+	applyServiceTimeouts(w, routingService)
 	deploymentName := routingService.ServiceID + "-deployment"
 
-	// If service has ScaleToZero=true label and is scaled to zero, scale it up
-	if routingService.ScaleToZeroEnabled {
-		// Check if deployment is marked as being in CrashLoopBackOff
-		inCrashLoop, err := s.redisClient.IsDeploymentInCrashLoop(routingService.Namespace, deploymentName)
-		if err != nil {
-			log.Printf("Error checking crashloop status in Redis: %v", err)
-		}
-
-		// If in crashloop, don't scale up automatically
-		if inCrashLoop {
-			log.Printf("Deployment %s/%s is marked as CrashLoopBackOff, not scaling up", routingService.Namespace, deploymentName)
-			http.Error(w, "Service is currently unavailable due to errors. Please contact support.", http.StatusServiceUnavailable)
-
-			// Log the crashloop error
-			duration := time.Since(start)
-			s.logger.LogRequest(w, r, duration, "crashloop-blocked")
-			return
-		}
-
-		// Check if we have the deployment status cached in Redis first
-		exists, isActive, err := s.redisClient.GetDeploymentStatus(routingService.Namespace, deploymentName)
-		if err != nil {
-			log.Printf("Error checking deployment status in Redis: %v", err)
-		}
-
-		// If not in Redis or shows inactive, check with Kubernetes and cache the result
-		if !exists || !isActive {
-			isDeploymentReady := s.kubeClient.IsDeploymentReady(routingService.Namespace, deploymentName)
-
-			if !isDeploymentReady {
-				log.Printf("Scaling up service %s/%s from zero", routingService.Namespace, deploymentName)
-
-				err := s.kubeClient.ScaleUpDeployment(routingService.Namespace, deploymentName, 1)
-				if err != nil {
-					log.Printf("Error scaling up deployment: %v", err)
-					http.Error(w, "Service is currently scaling up, please try again in a moment", http.StatusServiceUnavailable)
-
-					// Log the scaling up error
-					duration := time.Since(start)
-					s.logger.LogRequest(w, r, duration, "scaling-up")
-					return
-				}
+	// Stopped by the user, never scale it up on traffic
+	if routingService.Stopped {
+		http.Error(w, "Service is stopped", http.StatusServiceUnavailable)
 
-				// Wait for the service to be ready
-				log.Printf("Waiting for service %s/%s to be ready", routingService.Namespace, deploymentName)
-
-				// Simple polling mechanism to check if service is ready
-				ready := false
-				for i := 0; i < 30; i++ { // Try for up to 30 seconds
-					if s.kubeClient.IsDeploymentReady(routingService.Namespace, deploymentName) {
-						ready = true
-						break
-					}
-					time.Sleep(1 * time.Second)
-				}
+		duration := time.Since(start)
+		s.logger.LogRequest(w, r, duration, "stopped")
+		return
+	}
 
-				if !ready {
-					log.Printf("Service %s/%s is not ready after waiting", routingService.Namespace, deploymentName)
-					http.Error(w, "Service is starting up, please try again in a moment", http.StatusServiceUnavailable)
+	// Reject methods the service doesn't accept, WebSocket handshakes always go through
+	if !methodAllowed(routingService, r) {
+		w.Header().Set("Allow", strings.Join(routingService.AllowedMethods, ", "))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 
-					// Log the startup error
-					duration := time.Since(start)
-					s.logger.LogRequest(w, r, duration, "starting-up")
-					return
-				}
+		duration := time.Since(start)
+		s.logger.LogRequest(w, r, duration, "method-not-allowed")
+		return
+	}
 
-				// Update the deployment status in Redis
-				if err := s.redisClient.SetDeploymentStatus(routingService.Namespace, deploymentName, true); err != nil {
-					log.Printf("Error setting deployment status in Redis: %v", err)
-				}
+	// Static sites in object storage have no pod to proxy to
+	if routingService.StaticBucket != "" {
+		s.serveStatic(w, r, routingService)
+		return
+	}
 
-				log.Printf("Service %s/%s is now ready", routingService.Namespace, deploymentName)
-			} else {
-				log.Printf("Service %s/%s is already ready", routingService.Namespace, deploymentName)
-				// Update the deployment status in Redis
-				if err := s.redisClient.SetDeploymentStatus(routingService.Namespace, deploymentName, true); err != nil {
-					log.Printf("Error setting deployment status in Redis: %v", err)
-				}
-			}
-		}
+	// If service has ScaleToZero=true label and is scaled to zero, scale it up
+	if !s.wakeService(w, r, routingService, start) {
+		return
 	}
 
 	// Record service access time in Redis
@@ -449,6 +581,12 @@ func (s *Server) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 		req.URL.Scheme = "http"
 		req.URL.Host = upstream
 
+		// Forward the request ID so backends can correlate their logs with ours
+		EnsureRequestID(req)
+
+		// Tell backends the same client IP we log
+		req.Header.Set("X-Real-IP", GetClientIP(req))
+
 		// Preserve original request headers that are important for WebSockets
 		// Check if this is a WebSocket request
 		if isWebSocket := isWebSocketRequest(req); isWebSocket {
@@ -507,8 +645,11 @@ func (s *Server) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create enhanced websocket transport with appropriate timeout settings for WebSocket connections
-	var transport http.RoundTripper
-	if isWebSocket {
+	var transport http.RoundTripper = s.transport.Load()
+	if useH2C(routingService, r) {
+		// gRPC needs HTTP/2 to the backend for trailers and streaming
+		transport = s.h2cTransport
+	} else if isWebSocket {
 		transport = &http.Transport{
 			ResponseHeaderTimeout: time.Duration(s.config.WebSocketReadTimeout) * time.Second,
 			IdleConnTimeout:       time.Duration(s.config.WebSocketReadTimeout) * time.Second,
@@ -524,28 +665,241 @@ func (s *Server) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Event streams and responses without a length are flushed as they arrive anyway,
+	// services labelled streaming get every response flushed immediately
+	var flushInterval time.Duration
+	if routingService.Streaming {
+		flushInterval = -1
+	}
+
 	proxy := &httputil.ReverseProxy{
-		Director:  director,
-		Transport: transport, // Set transport during initialization
+		Director:      director,
+		Transport:     transport, // Set transport during initialization
+		FlushInterval: flushInterval,
 		ModifyResponse: func(resp *http.Response) error {
 			// For WebSocket upgrade responses, ensure headers are preserved
 			if resp.StatusCode == http.StatusSwitchingProtocols {
 				log.Printf("Handling WebSocket upgrade response")
 			}
+			// Ask buffering proxies in front of us not to hold back events
+			if isEventStream(resp) && resp.Header.Get("X-Accel-Buffering") == "" {
+				resp.Header.Set("X-Accel-Buffering", "no")
+			}
 			return nil
 		},
 		ErrorHandler: func(rw http.ResponseWriter, req *http.Request, err error) {
+			if isUpstreamTimeout(err) {
+				log.Printf("Proxy error: upstream %s for %s timed out waiting for response: %v", upstream, host, err)
+				rw.WriteHeader(http.StatusGatewayTimeout)
+				return
+			}
 			log.Printf("Proxy error: %v", err)
 			rw.WriteHeader(http.StatusBadGateway)
 		},
 	}
 
+	// Count the request body as the backend reads it
+	var requestBody *countingReader
+	if r.Body != nil && r.Body != http.NoBody {
+		requestBody = &countingReader{ReadCloser: r.Body}
+		r.Body = requestBody
+	}
+
 	// Track the proxy completion time
 	proxy.ServeHTTP(w, r)
 
 	// Log the request with the specific upstream
 	duration := time.Since(start)
 	s.logger.LogRequest(w, r, duration, upstream)
+
+	// WebSocket durations are connection lifetimes, they'd only skew the metrics
+	if isWebSocket {
+		return
+	}
+
+	var requestBytes, responseBytes int64
+	if requestBody != nil {
+		requestBytes = requestBody.n.Load()
+	}
+	if lrw, ok := w.(*LogResponseWriter); ok {
+		responseBytes = int64(lrw.size)
+	}
+	s.metrics.observe(duration, requestBytes, responseBytes)
+	s.redisClient.RecordTraffic(routingService.ServiceID, requestBytes+responseBytes)
+
+	cfg := s.live.Load()
+	if cfg.SlowRequestThresholdMs > 0 && duration > time.Duration(cfg.SlowRequestThresholdMs)*time.Millisecond {
+		s.logger.LogSlowRequest(r, duration, upstream, cfg.LogSlowRequestHeaders)
+	}
+}
+
+// wakeService scales a scale-to-zero service up from zero and waits for it to be ready.
+// It answers the request itself and returns false when the service can't take it.
+func (s *Server) wakeService(w http.ResponseWriter, r *http.Request, routingService *kubernetes.ServiceInfo, start time.Time) bool {
+	if !routingService.ScaleToZeroEnabled {
+		return true
+	}
+	deploymentName := routingService.ServiceID + "-deployment"
+
+	// Check if deployment is marked as being in CrashLoopBackOff
+	inCrashLoop, err := s.redisClient.IsDeploymentInCrashLoop(routingService.Namespace, deploymentName)
+	if err != nil {
+		log.Printf("Error checking crashloop status in Redis: %v", err)
+	}
+
+	// If in crashloop, don't scale up automatically
+	if inCrashLoop {
+		log.Printf("Deployment %s/%s is marked as CrashLoopBackOff, not scaling up", routingService.Namespace, deploymentName)
+		http.Error(w, "Service is currently unavailable due to errors. Please contact support.", http.StatusServiceUnavailable)
+
+		// Log the crashloop error
+		duration := time.Since(start)
+		s.logger.LogRequest(w, r, duration, "crashloop-blocked")
+		return false
+	}
+
+	// Check if we have the deployment status cached in Redis first
+	exists, isActive, err := s.redisClient.GetDeploymentStatus(routingService.Namespace, deploymentName)
+	if err != nil {
+		log.Printf("Error checking deployment status in Redis: %v", err)
+	}
+
+	// If not in Redis or shows inactive, check with Kubernetes and cache the result
+	if !exists || !isActive {
+		isDeploymentReady := s.kubeClient.IsDeploymentReady(routingService.Namespace, deploymentName)
+
+		if !isDeploymentReady {
+			log.Printf("Scaling up service %s/%s from zero", routingService.Namespace, deploymentName)
+
+			err := s.kubeClient.ScaleUpDeployment(routingService.Namespace, deploymentName, 1)
+			if err != nil {
+				log.Printf("Error scaling up deployment: %v", err)
+				http.Error(w, "Service is currently scaling up, please try again in a moment", http.StatusServiceUnavailable)
+
+				// Log the scaling up error
+				duration := time.Since(start)
+				s.logger.LogRequest(w, r, duration, "scaling-up")
+				return false
+			}
+
+			// Wait for the service to be ready
+			log.Printf("Waiting for service %s/%s to be ready", routingService.Namespace, deploymentName)
+
+			// Simple polling mechanism to check if service is ready
+			ready := false
+			for i := 0; i < 30; i++ { // Try for up to 30 seconds
+				if s.kubeClient.IsDeploymentReady(routingService.Namespace, deploymentName) {
+					ready = true
+					break
+				}
+				time.Sleep(1 * time.Second)
+			}
+
+			if !ready {
+				log.Printf("Service %s/%s is not ready after waiting", routingService.Namespace, deploymentName)
+				http.Error(w, "Service is starting up, please try again in a moment", http.StatusServiceUnavailable)
+
+				// Log the startup error
+				duration := time.Since(start)
+				s.logger.LogRequest(w, r, duration, "starting-up")
+				return false
+			}
+
+			// Update the deployment status in Redis
+			if err := s.redisClient.SetDeploymentStatus(routingService.Namespace, deploymentName, true); err != nil {
+				log.Printf("Error setting deployment status in Redis: %v", err)
+			}
+
+			log.Printf("Service %s/%s is now ready", routingService.Namespace, deploymentName)
+		} else {
+			log.Printf("Service %s/%s is already ready", routingService.Namespace, deploymentName)
+			// Update the deployment status in Redis
+			if err := s.redisClient.SetDeploymentStatus(routingService.Namespace, deploymentName, true); err != nil {
+				log.Printf("Error setting deployment status in Redis: %v", err)
+			}
+		}
+	}
+
+	return true
+}
+
+// newUpstreamTransport creates the transport used for regular (non-WebSocket) upstream requests
+func newUpstreamTransport(cfg *config.Config) *http.Transport {
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: time.Duration(cfg.UpstreamResponseTimeout) * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   100,
+		MaxIdleConns:          1000,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+	}
+}
+
+// newH2CTransport creates the transport used for cleartext HTTP/2 upstream requests. It has
+// no response header timeout, gRPC streams may wait for messages indefinitely.
+func newH2CTransport() *http2.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http2.Transport{
+		AllowHTTP: true,
+		// Dial plain TCP for the http:// upstream URLs instead of TLS
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		// Detect dead backend connections by pinging idle ones
+		ReadIdleTimeout: 30 * time.Second,
+		PingTimeout:     15 * time.Second,
+	}
+}
+
+// useH2C reports whether a request goes to the backend over cleartext HTTP/2. Upgrades,
+// WebSockets among them, only exist in HTTP/1.1 and keep using it on h2c services.
+func useH2C(service *kubernetes.ServiceInfo, r *http.Request) bool {
+	if r.Header.Get("Upgrade") != "" {
+		return false
+	}
+	return service.H2C || isGRPCRequest(r)
+}
+
+// isGRPCRequest reports whether the request is gRPC, gRPC-Web works over HTTP/1.1 and is not
+func isGRPCRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	return strings.HasPrefix(contentType, "application/grpc") && !strings.HasPrefix(contentType, "application/grpc-web")
+}
+
+// isEventStream reports whether a response is Server-Sent Events
+func isEventStream(resp *http.Response) bool {
+	contentType := resp.Header.Get("Content-Type")
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), "text/event-stream")
+}
+
+// isUpstreamTimeout reports whether a proxy error was caused by the upstream not responding in time
+func isUpstreamTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// methodAllowed reports whether a service accepts the method of a request
+func methodAllowed(service *kubernetes.ServiceInfo, r *http.Request) bool {
+	if len(service.AllowedMethods) == 0 || isWebSocketRequest(r) {
+		return true
+	}
+	for _, method := range service.AllowedMethods {
+		if r.Method == method {
+			return true
+		}
+	}
+	return false
 }
 
 // isWebSocketRequest checks if the request is a WebSocket handshake request
@@ -0,0 +1,636 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+	"github.com/deployra/deployra/proxies/web/pkg/redis"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const serviceHost = "app.example.com"
+
+// proxyServer returns a proxy configured with cfg routing serviceHost to service, resolving
+// every service to localhost
+func proxyServer(t *testing.T, cfg *config.Config, service *kubernetes.ServiceInfo) (*Server, *miniredis.Miniredis) {
+	t.Helper()
+
+	redisServer := miniredis.RunT(t)
+	redisClient, err := redis.NewClient(redisServer.Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("redis client: %v", err)
+	}
+	clientIPs, err := NewClientIPResolver("", nil)
+	if err != nil {
+		t.Fatalf("client IP resolver: %v", err)
+	}
+
+	dnsCache := NewDNSCache(time.Minute)
+	dnsCache.resolve = func(string) ([]net.IP, error) { return []net.IP{net.ParseIP("127.0.0.1")}, nil }
+
+	server := &Server{
+		config:       cfg,
+		redisClient:  redisClient,
+		services:     map[string]*kubernetes.ServiceInfo{"project/web": service},
+		routingTable: map[string]string{serviceHost: "project/web"},
+		redirects:    make(map[string]string),
+		logger:       NewAccessLogger(clientIPs),
+		dnsCache:     dnsCache,
+		metrics:      newRequestMetrics(),
+		h2cTransport: newH2CTransport(),
+	}
+	server.live.Store(cfg)
+	server.transport.Store(newUpstreamTransport(cfg))
+	return server, redisServer
+}
+
+// backendService returns a service whose pods are served by handler
+func backendService(t *testing.T, handler http.Handler) *kubernetes.ServiceInfo {
+	t.Helper()
+	backend := httptest.NewServer(handler)
+	t.Cleanup(backend.Close)
+
+	u, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return &kubernetes.ServiceInfo{Name: "web-service", Namespace: "project", ServiceID: "web", Port: int32(port)}
+}
+
+// proxyRequest sends a request for serviceHost through the proxy
+func proxyRequest(t *testing.T, server *Server, method string) *http.Response {
+	t.Helper()
+	proxy := httptest.NewServer(http.HandlerFunc(server.handleProxyRequest))
+	t.Cleanup(proxy.Close)
+
+	req, _ := http.NewRequest(method, proxy.URL+"/", nil)
+	req.Host = serviceHost
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s through the proxy: %v", method, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestUseH2C(t *testing.T) {
+	tests := []struct {
+		name        string
+		h2c         bool
+		contentType string
+		upgrade     string
+		want        bool
+	}{
+		{name: "HTTP/1.1 service", want: false},
+		{name: "h2c service", h2c: true, want: true},
+		{name: "gRPC to an HTTP/1.1 service", contentType: "application/grpc+proto", want: true},
+		{name: "gRPC-Web stays on HTTP/1.1", contentType: "application/grpc-web", want: false},
+		{name: "WebSocket to an h2c service", h2c: true, upgrade: "websocket", want: false},
+		{name: "other upgrade to an h2c service", h2c: true, upgrade: "h2c", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.upgrade != "" {
+				r.Header.Set("Connection", "Upgrade")
+				r.Header.Set("Upgrade", tt.upgrade)
+			}
+			if got := useH2C(&kubernetes.ServiceInfo{H2C: tt.h2c}, r); got != tt.want {
+				t.Errorf("useH2C = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCTrailersReachClient(t *testing.T) {
+	var backendProto atomic.Value
+	service := backendService(t, h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendProto.Store(r.Proto)
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", "0")
+		w.Header().Set("Grpc-Message", "done")
+	}), &http2.Server{}))
+	service.H2C = true
+
+	cfg := config.DefaultConfig()
+	cfg.EnableHTTPS = false
+	server, _ := proxyServer(t, cfg, service)
+	proxy := httptest.NewServer(server.httpHandler())
+	t.Cleanup(proxy.Close)
+
+	// gRPC clients speak cleartext HTTP/2 to the proxy without an upgrade
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	req, _ := http.NewRequest(http.MethodPost, proxy.URL+"/echo.Echo/Say", strings.NewReader("\x00\x00\x00\x00\x00"))
+	req.Host = serviceHost
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("gRPC request through the proxy: %v", err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+		t.Errorf("response = %s %d, want HTTP/2 200", resp.Proto, resp.StatusCode)
+	}
+	if proto, _ := backendProto.Load().(string); proto != "HTTP/2.0" {
+		t.Errorf("backend got %q, want HTTP/2.0", proto)
+	}
+	// Trailers are only read once the body was
+	if status := resp.Trailer.Get("Grpc-Status"); status != "0" || resp.Trailer.Get("Grpc-Message") != "done" {
+		t.Errorf("trailers = %v, want Grpc-Status 0 from the backend", resp.Trailer)
+	}
+}
+
+func TestStartClosesHTTPWhenHTTPSCannotListen(t *testing.T) {
+	// The HTTPS address is taken
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer taken.Close()
+
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	httpAddr := free.Addr().String()
+	free.Close()
+
+	server := &Server{
+		config:      &config.Config{HTTPAddr: httpAddr, HTTPSAddr: taken.Addr().String(), EnableHTTPS: true},
+		kubeClient:  &kubernetes.Client{},
+		dnsCache:    NewDNSCache(time.Minute),
+		httpServer:  &http.Server{Handler: http.NotFoundHandler()},
+		httpsServer: &http.Server{Handler: http.NotFoundHandler()},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := server.Start(ctx); err == nil {
+		t.Fatal("Start succeeded with the HTTPS address taken")
+	}
+
+	// The HTTP address is free again, nothing serves on it
+	ln, err := net.Listen("tcp", httpAddr)
+	if err != nil {
+		t.Fatalf("HTTP address still in use after the failed start: %v", err)
+	}
+	ln.Close()
+}
+
+func TestSlowUpstreamTimesOut(t *testing.T) {
+	service := backendService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(3 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	server, _ := proxyServer(t, &config.Config{UpstreamResponseTimeout: 1}, service)
+
+	start := time.Now()
+	resp := proxyRequest(t, server, http.MethodGet)
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > 2500*time.Millisecond {
+		t.Errorf("the proxy answered after %v, not the configured 1s", elapsed)
+	}
+}
+
+func TestUnreachableUpstreamIsBadGateway(t *testing.T) {
+	service := backendService(t, http.NotFoundHandler())
+	// Nothing listens on the port once the backend is closed
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	service.Port = int32(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	server, _ := proxyServer(t, &config.Config{UpstreamResponseTimeout: 1}, service)
+	if resp := proxyRequest(t, server, http.MethodGet); resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", resp.StatusCode)
+	}
+}
+
+// A stopped service answers 503 and isn't woken up, even with scale to zero enabled
+func TestStoppedServiceIsNotScaledUp(t *testing.T) {
+	var backendCalls atomic.Int32
+	service := backendService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendCalls.Add(1)
+	}))
+	service.Stopped = true
+	service.ScaleToZeroEnabled = true
+
+	// The server has no Kubernetes client, scaling the deployment up would panic
+	server, _ := proxyServer(t, &config.Config{UpstreamResponseTimeout: 1}, service)
+
+	resp := proxyRequest(t, server, http.MethodGet)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if calls := backendCalls.Load(); calls != 0 {
+		t.Errorf("backend was called %d times for a stopped service", calls)
+	}
+}
+
+func TestDisallowedMethodGetsMethodNotAllowed(t *testing.T) {
+	var backendCalls atomic.Int32
+	service := backendService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendCalls.Add(1)
+	}))
+	service.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	server, _ := proxyServer(t, &config.Config{UpstreamResponseTimeout: 1}, service)
+
+	resp := proxyRequest(t, server, http.MethodPost)
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+	if got := resp.Header.Get("Allow"); got != "GET, HEAD, OPTIONS" {
+		t.Errorf("Allow = %q, want %q", got, "GET, HEAD, OPTIONS")
+	}
+	if calls := backendCalls.Load(); calls != 0 {
+		t.Errorf("backend was called %d times for a disallowed method", calls)
+	}
+
+	if resp := proxyRequest(t, server, http.MethodGet); resp.StatusCode != http.StatusOK {
+		t.Errorf("GET status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestMethodAllowed(t *testing.T) {
+	readOnly := &kubernetes.ServiceInfo{AllowedMethods: []string{http.MethodGet, http.MethodHead}}
+	postOnly := &kubernetes.ServiceInfo{AllowedMethods: []string{http.MethodPost}}
+
+	webSocket := httptest.NewRequest(http.MethodGet, "/socket", nil)
+	webSocket.Header.Set("Connection", "Upgrade")
+	webSocket.Header.Set("Upgrade", "websocket")
+
+	tests := []struct {
+		name    string
+		service *kubernetes.ServiceInfo
+		r       *http.Request
+		want    bool
+	}{
+		{name: "no restriction", service: &kubernetes.ServiceInfo{}, r: httptest.NewRequest(http.MethodDelete, "/", nil), want: true},
+		{name: "allowed", service: readOnly, r: httptest.NewRequest(http.MethodHead, "/", nil), want: true},
+		{name: "disallowed", service: readOnly, r: httptest.NewRequest(http.MethodPut, "/", nil), want: false},
+		{name: "WebSocket handshake", service: postOnly, r: webSocket, want: true},
+	}
+	for _, tt := range tests {
+		if got := methodAllowed(tt.service, tt.r); got != tt.want {
+			t.Errorf("%s: methodAllowed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// chunkedBackend returns a service writing first, flushing, and writing second once the
+// client has read first or after a second, and whether second was written
+func chunkedBackend(t *testing.T, header http.Header, first, second string) (*kubernetes.ServiceInfo, chan struct{}, *atomic.Bool) {
+	t.Helper()
+	read := make(chan struct{})
+	var wroteSecond atomic.Bool
+	service := backendService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		io.WriteString(w, first)
+		w.(http.Flusher).Flush()
+		select {
+		case <-read:
+		case <-time.After(time.Second):
+		}
+		wroteSecond.Store(true)
+		io.WriteString(w, second)
+	}))
+	return service, read, &wroteSecond
+}
+
+// readsIncrementally reports whether the client of server read first before the backend
+// wrote the rest of the response
+func readsIncrementally(t *testing.T, server *Server, first string, read chan struct{}, wroteSecond *atomic.Bool) bool {
+	t.Helper()
+	resp := proxyRequest(t, server, http.MethodGet)
+	chunk := make([]byte, len(first))
+	if _, err := io.ReadFull(resp.Body, chunk); err != nil || string(chunk) != first {
+		t.Fatalf("first chunk = %q, %v, want %q", chunk, err, first)
+	}
+	incremental := !wroteSecond.Load()
+	close(read)
+	io.Copy(io.Discard, resp.Body)
+	return incremental
+}
+
+func TestEventStreamReachesClientIncrementally(t *testing.T) {
+	header := http.Header{"Content-Type": {"text/event-stream"}}
+	service, read, wroteSecond := chunkedBackend(t, header, "data: 1\n\n", "data: 2\n\n")
+	server, _ := proxyServer(t, &config.Config{UpstreamResponseTimeout: 5}, service)
+
+	resp := proxyRequest(t, server, http.MethodGet)
+	event := make([]byte, len("data: 1\n\n"))
+	if _, err := io.ReadFull(resp.Body, event); err != nil {
+		t.Fatalf("read the first event: %v", err)
+	}
+	if wroteSecond.Load() {
+		t.Error("the first event arrived only with the second one")
+	}
+	close(read)
+	if got := resp.Header.Get("X-Accel-Buffering"); got != "no" {
+		t.Errorf("X-Accel-Buffering = %q, want no", got)
+	}
+	rest, _ := io.ReadAll(resp.Body)
+	if string(rest) != "data: 2\n\n" {
+		t.Errorf("second event = %q, want %q", rest, "data: 2\n\n")
+	}
+}
+
+// Responses with a length are buffered unless the service is labelled streaming
+func TestStreamingServiceFlushesEveryResponse(t *testing.T) {
+	header := http.Header{"Content-Length": {"12"}}
+
+	service, read, wroteSecond := chunkedBackend(t, header, "first\n", "second")
+	server, _ := proxyServer(t, &config.Config{UpstreamResponseTimeout: 5}, service)
+	if readsIncrementally(t, server, "first\n", read, wroteSecond) {
+		t.Error("a response with a length was flushed before the end without the streaming label")
+	}
+
+	service, read, wroteSecond = chunkedBackend(t, header, "first\n", "second")
+	service.Streaming = true
+	server, _ = proxyServer(t, &config.Config{UpstreamResponseTimeout: 5}, service)
+	if !readsIncrementally(t, server, "first\n", read, wroteSecond) {
+		t.Error("the streaming service's response arrived only once complete")
+	}
+}
+
+// testCertificate returns a self-signed certificate for name, valid for 90 days
+func testCertificate(t *testing.T, name string) *tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestGetCertificateWithoutSNI(t *testing.T) {
+	server, _ := proxyServer(t, &config.Config{EnableHTTPS: true}, &kubernetes.ServiceInfo{ServiceID: "web"})
+	server.certManager = &CertManager{}
+	server.defaultCert = testCertificate(t, "default.example.com")
+
+	cert, err := server.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetCertificate without SNI: %v", err)
+	}
+	if cert != server.defaultCert {
+		t.Errorf("got %s, want the default certificate", cert.Leaf.Subject.CommonName)
+	}
+}
+
+func TestGetCertificateWithoutSNIFallsBackToWildcard(t *testing.T) {
+	server, _ := proxyServer(t, &config.Config{EnableHTTPS: true}, &kubernetes.ServiceInfo{ServiceID: "web"})
+	wildcard := testCertificate(t, "*.example.com")
+	server.certManager = &CertManager{enableWildcard: true, wildcardDomain: "example.com", wildcardCert: wildcard}
+
+	cert, err := server.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetCertificate without SNI: %v", err)
+	}
+	if cert != wildcard {
+		t.Errorf("got %s, want the wildcard certificate", cert.Leaf.Subject.CommonName)
+	}
+
+	// Without either the handshake is refused
+	server.certManager = &CertManager{}
+	if _, err := server.GetCertificate(&tls.ClientHelloInfo{}); err == nil {
+		t.Error("GetCertificate without SNI succeeded with no default or wildcard certificate")
+	}
+}
+
+func TestClientWithoutSNIGetsMisdirectedRequest(t *testing.T) {
+	server, _ := proxyServer(t, &config.Config{EnableHTTPS: true}, &kubernetes.ServiceInfo{ServiceID: "web"})
+	server.certManager = &CertManager{}
+	server.defaultCert = testCertificate(t, "default.example.com")
+
+	// httptest would answer clients without SNI with its own certificate
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: server.GetCertificate})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	https := &http.Server{Handler: server.httpsHandler()}
+	go https.Serve(listener)
+	defer https.Close()
+
+	// Clients don't send SNI when connecting to an IP address
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get("https://" + listener.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("GET without SNI: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMisdirectedRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusMisdirectedRequest)
+	}
+	if got := resp.TLS.PeerCertificates[0].Subject.CommonName; got != "default.example.com" {
+		t.Errorf("handshake served %s, want the default certificate", got)
+	}
+}
+
+func TestRedirectedHostGetsMovedPermanently(t *testing.T) {
+	server, _ := proxyServer(t, config.DefaultConfig(), &kubernetes.ServiceInfo{ServiceID: "web"})
+	server.redirects["www.example.com"] = "example.com"
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/pricing?plan=pro", nil)
+	req.Host = "WWW.example.com"
+	server.handleProxyRequest(recorder, req)
+
+	if recorder.Code != http.StatusMovedPermanently {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusMovedPermanently)
+	}
+	// The path and query are kept so links to the other form land on the same page
+	if location := recorder.Header().Get("Location"); location != "https://example.com/pricing?plan=pro" {
+		t.Errorf("Location = %q, want https://example.com/pricing?plan=pro", location)
+	}
+}
+
+func TestServiceRedirectLabelsRedirect(t *testing.T) {
+	server, _ := proxyServer(t, config.DefaultConfig(), &kubernetes.ServiceInfo{ServiceID: "web"})
+	shop := &kubernetes.ServiceInfo{
+		Name: "shop-service", Namespace: "project", ServiceID: "shop",
+		Domains: []string{"example.com"}, RedirectFrom: "www.example.com", RedirectTo: "example.com",
+	}
+	redirectStatus := func() (int, string) {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = "www.example.com"
+		server.handleProxyRequest(recorder, req)
+		return recorder.Code, recorder.Header().Get("Location")
+	}
+
+	server.handleServicesChanged(kubernetes.Add, "project/shop", shop)
+	if status, location := redirectStatus(); status != http.StatusMovedPermanently || location != "https://example.com/" {
+		t.Fatalf("www.example.com = %d to %q, want a 301 to https://example.com/", status, location)
+	}
+
+	// Dropping the labels removes the redirect
+	updated := *shop
+	updated.RedirectFrom, updated.RedirectTo = "", ""
+	server.handleServicesChanged(kubernetes.Add, "project/shop", &updated)
+	if _, ok := server.redirectFor("www.example.com"); ok {
+		t.Error("the redirect was kept after the service dropped it")
+	}
+
+	server.handleServicesChanged(kubernetes.Add, "project/shop", shop)
+	server.handleServicesChanged(kubernetes.Delete, "project/shop", nil)
+	if _, ok := server.redirectFor("www.example.com"); ok {
+		t.Error("the redirect was kept after the service was deleted")
+	}
+}
+
+func TestDeletedServiceKeepsRedirectOfAnother(t *testing.T) {
+	server, _ := proxyServer(t, config.DefaultConfig(), &kubernetes.ServiceInfo{ServiceID: "web"})
+	old := &kubernetes.ServiceInfo{ServiceID: "old", RedirectFrom: "www.example.com", RedirectTo: "example.com"}
+	replacement := &kubernetes.ServiceInfo{ServiceID: "new", RedirectFrom: "www.example.com", RedirectTo: "shop.example.com"}
+
+	server.handleServicesChanged(kubernetes.Add, "project/old", old)
+	server.handleServicesChanged(kubernetes.Add, "project/new", replacement)
+	server.handleServicesChanged(kubernetes.Delete, "project/old", nil)
+
+	if target, ok := server.redirectFor("www.example.com"); !ok || target != "shop.example.com" {
+		t.Errorf("redirect = %q, %v, want the one of the remaining service", target, ok)
+	}
+}
+
+// timedProxy serves the proxy with a global write timeout shorter than service's responses
+func timedProxy(t *testing.T, service *kubernetes.ServiceInfo) *httptest.Server {
+	t.Helper()
+	server, _ := proxyServer(t, config.DefaultConfig(), service)
+	proxy := httptest.NewUnstartedServer(http.HandlerFunc(server.handleProxyRequest))
+	proxy.Config.WriteTimeout = 300 * time.Millisecond
+	proxy.Start()
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+// getHost sends a GET for serviceHost to a proxy
+func getHost(proxy *httptest.Server) (*http.Response, error) {
+	req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/", nil)
+	req.Host = serviceHost
+	return proxy.Client().Do(req)
+}
+
+func TestServiceWriteTimeoutOverridesGlobal(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(700 * time.Millisecond)
+		io.WriteString(w, "uploaded")
+	})
+
+	// The global write timeout cuts the response off
+	if resp, err := getHost(timedProxy(t, backendService(t, slow))); err == nil {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil && string(body) == "uploaded" {
+			t.Fatalf("slow response passed the global write timeout")
+		}
+	}
+
+	service := backendService(t, slow)
+	service.WriteTimeout = 5 * time.Second
+	resp, err := getHost(timedProxy(t, service))
+	if err != nil {
+		t.Fatalf("request to a service with a longer write timeout: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "uploaded" {
+		t.Errorf("response = %d %q, want the slow response", resp.StatusCode, body)
+	}
+}
+
+func TestServiceReadTimeoutOverridesGlobal(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	})
+	service := backendService(t, echo)
+	service.ReadTimeout = 5 * time.Second
+	server, _ := proxyServer(t, config.DefaultConfig(), service)
+	proxy := httptest.NewUnstartedServer(http.HandlerFunc(server.handleProxyRequest))
+	proxy.Config.ReadTimeout = 300 * time.Millisecond
+	proxy.Start()
+	t.Cleanup(proxy.Close)
+
+	// The upload trickles in for longer than the global read timeout
+	body, writer := io.Pipe()
+	go func() {
+		for i := 0; i < 4; i++ {
+			writer.Write([]byte("chunk"))
+			time.Sleep(200 * time.Millisecond)
+		}
+		writer.Close()
+	}()
+	req, _ := http.NewRequest(http.MethodPost, proxy.URL+"/", body)
+	req.Host = serviceHost
+	resp, err := proxy.Client().Do(req)
+	if err != nil {
+		t.Fatalf("slow upload to a service with a longer read timeout: %v", err)
+	}
+	defer resp.Body.Close()
+	echoed, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(echoed) != "chunkchunkchunkchunk" {
+		t.Errorf("response = %d %q, want the whole upload", resp.StatusCode, echoed)
+	}
+}
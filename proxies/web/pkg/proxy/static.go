@@ -0,0 +1,217 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty body, sent with every signed GET
+var emptyPayloadHash = func() string {
+	sum := sha256.Sum256(nil)
+	return hex.EncodeToString(sum[:])
+}()
+
+// Request headers passed on to object storage for conditional and partial requests
+var objectRequestHeaders = []string{"Range", "If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since"}
+
+// Object headers passed back to the client
+var objectResponseHeaders = []string{
+	"Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Last-Modified",
+	"Cache-Control", "Content-Encoding", "Content-Disposition", "Expires",
+}
+
+// ObjectStore reads objects from an S3-compatible bucket over path-style URLs
+type ObjectStore struct {
+	endpoint    *url.URL
+	region      string
+	credentials *aws.Credentials
+	signer      *v4.Signer
+	client      *http.Client
+}
+
+// NewObjectStore creates an object store client from the proxy configuration.
+// Without credentials requests are sent unsigned, which works for public buckets.
+func NewObjectStore(cfg *config.Config, transport http.RoundTripper) (*ObjectStore, error) {
+	endpoint, err := url.Parse(strings.TrimRight(cfg.ObjectStorageEndpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid object storage endpoint %q", cfg.ObjectStorageEndpoint)
+	}
+
+	store := &ObjectStore{
+		endpoint: endpoint,
+		region:   cfg.ObjectStorageRegion,
+		client:   &http.Client{Transport: transport},
+	}
+
+	if cfg.ObjectStorageAccessKeyID != "" {
+		store.credentials = &aws.Credentials{
+			AccessKeyID:     cfg.ObjectStorageAccessKeyID,
+			SecretAccessKey: cfg.ObjectStorageSecretAccessKey,
+		}
+		// S3 expects the path to be escaped exactly once
+		store.signer = v4.NewSigner(func(options *v4.SignerOptions) {
+			options.DisableURIPathEscaping = true
+		})
+	}
+
+	return store, nil
+}
+
+// Get fetches an object, passing through the conditional and range headers of the client request
+func (o *ObjectStore) Get(ctx context.Context, method, bucket, key string, clientHeaders http.Header) (*http.Response, error) {
+	target, err := url.Parse(fmt.Sprintf("%s/%s/%s", o.endpoint.String(), escapeObjectPath(bucket), escapeObjectPath(key)))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range objectRequestHeaders {
+		if value := clientHeaders.Get(header); value != "" {
+			req.Header.Set(header, value)
+		}
+	}
+
+	if o.signer != nil {
+		req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+		if err := o.signer.SignHTTP(ctx, *o.credentials, req, emptyPayloadHash, "s3", o.region, time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to sign object request: %v", err)
+		}
+	}
+
+	return o.client.Do(req)
+}
+
+// escapeObjectPath escapes a bucket or key the way S3 signs it: everything except
+// unreserved characters and slashes is percent-encoded
+func escapeObjectPath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// serveStatic serves a static site from object storage. Directory paths serve their
+// index.html and paths without a file extension fall back to the site's index.html
+// so single-page apps can handle their own routes.
+func (s *Server) serveStatic(w http.ResponseWriter, r *http.Request, svc *kubernetes.ServiceInfo) {
+	start := time.Now()
+	upstream := fmt.Sprintf("s3://%s/%s", svc.StaticBucket, svc.StaticPrefix)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		s.logger.LogRequest(w, r, time.Since(start), upstream)
+		return
+	}
+
+	if s.objectStore == nil {
+		http.Error(w, "Static sites are not configured", http.StatusServiceUnavailable)
+		s.logger.LogRequest(w, r, time.Since(start), upstream)
+		return
+	}
+
+	filePath := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") {
+		filePath = path.Join(filePath, "index.html")
+	}
+
+	resp, err := s.objectStore.Get(r.Context(), r.Method, svc.StaticBucket, staticObjectKey(svc.StaticPrefix, filePath), r.Header)
+	if err == nil && isMissingObject(resp) && path.Ext(filePath) == "" {
+		// SPA fallback, the app's router handles the path
+		resp.Body.Close()
+		filePath = "/index.html"
+		resp, err = s.objectStore.Get(r.Context(), r.Method, svc.StaticBucket, staticObjectKey(svc.StaticPrefix, filePath), r.Header)
+	}
+	if err != nil {
+		log.Printf("Failed to fetch static object for %s%s: %v", r.Host, r.URL.Path, err)
+		http.Error(w, "Bad gateway", http.StatusBadGateway)
+		s.logger.LogRequest(w, r, time.Since(start), upstream)
+		return
+	}
+	defer resp.Body.Close()
+
+	if isMissingObject(resp) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		s.logger.LogRequest(w, r, time.Since(start), upstream)
+		return
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusNotModified,
+		http.StatusRequestedRangeNotSatisfiable, http.StatusPreconditionFailed:
+	default:
+		log.Printf("Object storage returned %d for %s%s (bucket %s)", resp.StatusCode, r.Host, r.URL.Path, svc.StaticBucket)
+		http.Error(w, "Bad gateway", http.StatusBadGateway)
+		s.logger.LogRequest(w, r, time.Since(start), upstream)
+		return
+	}
+
+	for _, header := range objectResponseHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.Header().Set("Content-Type", staticContentType(filePath, resp.Header.Get("Content-Type")))
+
+	w.WriteHeader(resp.StatusCode)
+	if r.Method != http.MethodHead {
+		io.Copy(w, resp.Body)
+	}
+
+	s.logger.LogRequest(w, r, time.Since(start), upstream)
+}
+
+// staticObjectKey joins the site prefix and a cleaned request path
+func staticObjectKey(prefix, filePath string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return strings.TrimPrefix(filePath, "/")
+	}
+	return prefix + filePath
+}
+
+// isMissingObject reports whether object storage answered that the object doesn't exist.
+// Buckets without list permission answer 403 instead of 404 for missing keys.
+func isMissingObject(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden
+}
+
+// staticContentType prefers the stored content type unless it is the generic default
+// uploaders leave behind, in which case the type is taken from the file extension
+func staticContentType(filePath, stored string) string {
+	if stored != "" && stored != "binary/octet-stream" && stored != "application/octet-stream" {
+		return stored
+	}
+	if byExtension := mime.TypeByExtension(path.Ext(filePath)); byExtension != "" {
+		return byExtension
+	}
+	if stored != "" {
+		return stored
+	}
+	return "application/octet-stream"
+}
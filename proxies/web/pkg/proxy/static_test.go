@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+)
+
+// staticObject is a file in the fake object storage
+type staticObject struct {
+	contentType string
+	body        string
+}
+
+// staticServer returns a proxy serving serviceHost from the site bucket under prefix v1,
+// and the object keys requested from storage
+func staticServer(t *testing.T, objects map[string]staticObject) (*Server, *[]string) {
+	t.Helper()
+	var requested []string
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		object, ok := objects[r.URL.Path]
+		if !ok {
+			// Like buckets without list permission
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if object.contentType != "" {
+			w.Header().Set("Content-Type", object.contentType)
+		}
+		io.WriteString(w, object.body)
+	}))
+	t.Cleanup(storage.Close)
+
+	service := &kubernetes.ServiceInfo{
+		Name: "site-service", Namespace: "project", ServiceID: "site", Type: kubernetes.ServiceTypeStatic,
+		StaticBucket: "site", StaticPrefix: "v1/",
+	}
+	server, _ := proxyServer(t, &config.Config{}, service)
+	store, err := NewObjectStore(&config.Config{ObjectStorageEndpoint: storage.URL}, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("NewObjectStore: %v", err)
+	}
+	server.objectStore = store
+	return server, &requested
+}
+
+// staticGet requests path from the static site through the proxy
+func staticGet(t *testing.T, server *Server, path string) (*http.Response, string) {
+	t.Helper()
+	proxy := httptest.NewServer(http.HandlerFunc(server.handleProxyRequest))
+	defer proxy.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, proxy.URL+path, nil)
+	req.Host = serviceHost
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s through the proxy: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestServeStaticObject(t *testing.T) {
+	server, requested := staticServer(t, map[string]staticObject{
+		"/site/v1/assets/app.js": {contentType: "binary/octet-stream", body: "console.log(1)"},
+		"/site/v1/index.html":    {contentType: "text/html", body: "<html>home</html>"},
+	})
+
+	resp, body := staticGet(t, server, "/assets/app.js")
+	if resp.StatusCode != http.StatusOK || body != "console.log(1)" {
+		t.Fatalf("GET /assets/app.js = %d %q, want the object", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/javascript; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want it taken from the extension over the generic stored type", got)
+	}
+
+	resp, body = staticGet(t, server, "/")
+	if resp.StatusCode != http.StatusOK || body != "<html>home</html>" {
+		t.Errorf("GET / = %d %q, want index.html", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/html" {
+		t.Errorf("Content-Type = %q, want the stored text/html", got)
+	}
+	if (*requested)[0] != "/site/v1/assets/app.js" {
+		t.Errorf("requested %v, want the key under the site prefix", *requested)
+	}
+}
+
+func TestServeStaticMissingFile(t *testing.T) {
+	server, requested := staticServer(t, map[string]staticObject{
+		"/site/v1/index.html": {contentType: "text/html", body: "<html>home</html>"},
+	})
+
+	resp, _ := staticGet(t, server, "/assets/missing.css")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	// Paths with an extension are files, the SPA doesn't handle them
+	if len(*requested) != 1 {
+		t.Errorf("requested %v, want no index.html fallback for a file", *requested)
+	}
+}
+
+func TestServeStaticSPAFallback(t *testing.T) {
+	server, requested := staticServer(t, map[string]staticObject{
+		"/site/v1/index.html": {contentType: "text/html", body: "<html>home</html>"},
+	})
+
+	resp, body := staticGet(t, server, "/dashboard/settings")
+	if resp.StatusCode != http.StatusOK || body != "<html>home</html>" {
+		t.Fatalf("GET /dashboard/settings = %d %q, want index.html", resp.StatusCode, body)
+	}
+	want := []string{"/site/v1/dashboard/settings", "/site/v1/index.html"}
+	if len(*requested) != 2 || (*requested)[0] != want[0] || (*requested)[1] != want[1] {
+		t.Errorf("requested %v, want %v", *requested, want)
+	}
+}
@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+)
+
+// handleConnect opens a raw TCP tunnel to a service for an HTTP CONNECT request.
+// The target is "<service domain>:<service port>", one of the ports the service exposes,
+// and the service must carry the tunnelEnabled label. Clients authenticate with the token whose SHA-256 is in the
+// service's tunnelTokenHash annotation, as a Bearer token or as the Basic auth password.
+func (s *Server) handleConnect(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	host, portValue, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		http.Error(w, "CONNECT target must be host:port", http.StatusBadRequest)
+		s.logger.LogRequest(w, r, time.Since(start), "tunnel-bad-target")
+		return
+	}
+	port, err := strconv.Atoi(portValue)
+	if err != nil || port < 1 || port > 65535 {
+		http.Error(w, "Invalid CONNECT port", http.StatusBadRequest)
+		s.logger.LogRequest(w, r, time.Since(start), "tunnel-bad-target")
+		return
+	}
+
+	var routingService *kubernetes.ServiceInfo
+	s.routingLock.RLock()
+	if serviceKey, exists := s.routingTable[strings.ToLower(host)]; exists {
+		routingService = s.services[serviceKey]
+	}
+	s.routingLock.RUnlock()
+
+	if routingService == nil {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		s.logger.LogRequest(w, r, time.Since(start), "tunnel-not-found")
+		return
+	}
+
+	if !routingService.TunnelEnabled {
+		http.Error(w, "Tunneling is not enabled for this service", http.StatusForbidden)
+		s.logger.LogRequest(w, r, time.Since(start), "tunnel-disabled")
+		return
+	}
+
+	if !validTunnelToken(r, routingService.TunnelTokenHash) {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="deployra"`)
+		http.Error(w, "Proxy authentication required", http.StatusProxyAuthRequired)
+		s.logger.LogRequest(w, r, time.Since(start), "tunnel-unauthorized")
+		return
+	}
+
+	if !exposesPort(routingService, port) {
+		http.Error(w, "Port is not exposed by this service", http.StatusForbidden)
+		s.logger.LogRequest(w, r, time.Since(start), "tunnel-port-not-exposed")
+		return
+	}
+
+	if routingService.Stopped {
+		http.Error(w, "Service is stopped", http.StatusServiceUnavailable)
+		s.logger.LogRequest(w, r, time.Since(start), "stopped")
+		return
+	}
+
+	// Tunnels wake scale-to-zero services like requests do, and keep them awake
+	if !s.wakeService(w, r, routingService, start) {
+		return
+	}
+	s.redisClient.RecordServiceAccess(routingService.Namespace, routingService.ServiceID+"-deployment")
+
+	serviceDNS := fmt.Sprintf("%s.%s.svc.cluster.local", routingService.Name, routingService.Namespace)
+	ips, err := s.dnsCache.Lookup(serviceDNS)
+	if err != nil || len(ips) == 0 {
+		log.Printf("Failed to resolve service DNS %s for tunnel: %v", serviceDNS, err)
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		s.logger.LogRequest(w, r, time.Since(start), "dns-error")
+		return
+	}
+
+	upstream := net.JoinHostPort(ips[0].String(), strconv.Itoa(port))
+	backend, err := net.DialTimeout("tcp", upstream, 10*time.Second)
+	if err != nil {
+		log.Printf("Failed to open tunnel to %s for %s: %v", upstream, host, err)
+		http.Error(w, "Bad gateway", http.StatusBadGateway)
+		s.logger.LogRequest(w, r, time.Since(start), upstream)
+		return
+	}
+	defer backend.Close()
+
+	log.Printf("Tunnel opened from %s to %s (%s)", GetClientIP(r), upstream, host)
+
+	var transferred int64
+	if r.ProtoMajor == 1 {
+		client, buffered, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			log.Printf("Failed to hijack connection for tunnel: %v", err)
+			http.Error(w, "Tunneling is not supported on this connection", http.StatusInternalServerError)
+			return
+		}
+		defer client.Close()
+
+		if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+			return
+		}
+
+		// Bytes the client sent after the CONNECT request may already be buffered
+		transferred = pipeTunnel(backend, io.MultiReader(buffered.Reader, client), client)
+	} else {
+		// HTTP/2 tunnels run over the request and response bodies of the CONNECT stream
+		w.WriteHeader(http.StatusOK)
+		controller := http.NewResponseController(w)
+		if err := controller.Flush(); err != nil {
+			log.Printf("Failed to flush tunnel response: %v", err)
+			return
+		}
+		transferred = pipeTunnel(backend, r.Body, &flushWriter{w: w, controller: controller})
+	}
+
+	log.Printf("Tunnel from %s to %s closed after %s", GetClientIP(r), upstream, time.Since(start).Round(time.Second))
+	s.redisClient.RecordTraffic(routingService.ServiceID, transferred)
+	s.logger.LogRequest(w, r, time.Since(start), upstream)
+}
+
+// pipeTunnel copies bytes in both directions until both sides are done and returns how many
+// it copied
+func pipeTunnel(backend net.Conn, fromClient io.Reader, toClient io.Writer) int64 {
+	var wg sync.WaitGroup
+	var sent, received int64
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		sent, _ = io.Copy(backend, fromClient)
+		// Let the backend see EOF while it may still be answering
+		if tcp, ok := backend.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		received, _ = io.Copy(toClient, backend)
+		// Unblock the other direction when the backend hangs up
+		if closer, ok := fromClient.(io.Closer); ok {
+			closer.Close()
+		}
+		if closer, ok := toClient.(io.Closer); ok {
+			closer.Close()
+		}
+	}()
+
+	wg.Wait()
+	return sent + received
+}
+
+// exposesPort reports whether a port is one of the ports of a service
+func exposesPort(service *kubernetes.ServiceInfo, port int) bool {
+	for _, exposed := range service.Ports {
+		if int(exposed) == port {
+			return true
+		}
+	}
+	return false
+}
+
+// validTunnelToken checks the Proxy-Authorization header against the service's token hash
+func validTunnelToken(r *http.Request, tokenHash string) bool {
+	if tokenHash == "" {
+		return false
+	}
+
+	var token string
+	auth := r.Header.Get("Proxy-Authorization")
+	switch {
+	case strings.HasPrefix(auth, "Bearer "):
+		token = strings.TrimPrefix(auth, "Bearer ")
+	case strings.HasPrefix(auth, "Basic "):
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
+		if err != nil {
+			return false
+		}
+		if _, password, ok := strings.Cut(string(decoded), ":"); ok {
+			token = password
+		}
+	}
+	if token == "" {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(tokenHash))) == 1
+}
+
+// flushWriter flushes after every write so tunnelled bytes aren't held back
+type flushWriter struct {
+	w          io.Writer
+	controller *http.ResponseController
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, f.controller.Flush()
+}
@@ -0,0 +1,183 @@
+package proxy
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/deployra/deployra/proxies/web/pkg/config"
+	"github.com/deployra/deployra/proxies/web/pkg/kubernetes"
+)
+
+// tunnelServer returns a proxy routing serviceHost to service, resolving every service to localhost
+func tunnelServer(t *testing.T, service *kubernetes.ServiceInfo) (*Server, *miniredis.Miniredis) {
+	t.Helper()
+	return proxyServer(t, &config.Config{}, service)
+}
+
+// echoBackend accepts connections and writes back whatever it reads
+func echoBackend(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// connect sends a CONNECT request through the proxy and returns the connection and response
+func connect(t *testing.T, proxyURL string, port int, token string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", proxyURL[len("http://"):])
+	if err != nil {
+		t.Fatalf("dial proxy: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	target := fmt.Sprintf("%s:%d", serviceHost, port)
+	fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\nProxy-Authorization: Bearer %s\r\n\r\n", target, target, token)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		t.Fatalf("read CONNECT response: %v", err)
+	}
+	return conn, reader, resp
+}
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTunnelEchoesThroughBackend(t *testing.T) {
+	port := echoBackend(t)
+	server, redisServer := tunnelServer(t, &kubernetes.ServiceInfo{
+		Name:            "web-service",
+		Namespace:       "project",
+		ServiceID:       "web",
+		TunnelEnabled:   true,
+		TunnelTokenHash: tokenHash("secret-token"),
+		Ports:           []int32{int32(port)},
+	})
+	proxy := httptest.NewServer(http.HandlerFunc(server.handleConnect))
+	defer proxy.Close()
+
+	conn, reader, resp := connect(t, proxy.URL, port, "secret-token")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("CONNECT status = %d, want 200", resp.StatusCode)
+	}
+
+	if _, err := conn.Write([]byte("ping\n")); err != nil {
+		t.Fatalf("write through tunnel: %v", err)
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read through tunnel: %v", err)
+	}
+	if line != "ping\n" {
+		t.Errorf("tunnel echoed %q, want %q", line, "ping\n")
+	}
+
+	// The tunnel counts as traffic, the service isn't scaled to zero under it
+	if !redisServer.Exists("service:access:project:web-deployment") {
+		t.Error("tunnel did not record access to the service")
+	}
+
+	// Its bytes are added to the monthly traffic once it closes
+	conn.Close()
+	trafficKey := "service:traffic:web:" + time.Now().UTC().Format("2006-01")
+	deadline := time.Now().Add(2 * time.Second)
+	for !redisServer.Exists(trafficKey) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if traffic, _ := redisServer.Get(trafficKey); traffic != "10" {
+		t.Errorf("tunnel traffic = %q, want 10 bytes", traffic)
+	}
+}
+
+func TestTunnelRejectsWrongToken(t *testing.T) {
+	port := echoBackend(t)
+	server, _ := tunnelServer(t, &kubernetes.ServiceInfo{
+		Name:            "web-service",
+		Namespace:       "project",
+		ServiceID:       "web",
+		TunnelEnabled:   true,
+		TunnelTokenHash: tokenHash("secret-token"),
+		Ports:           []int32{int32(port)},
+	})
+	proxy := httptest.NewServer(http.HandlerFunc(server.handleConnect))
+	defer proxy.Close()
+
+	_, _, resp := connect(t, proxy.URL, port, "wrong-token")
+	if resp.StatusCode != http.StatusProxyAuthRequired {
+		t.Fatalf("CONNECT status = %d, want 407", resp.StatusCode)
+	}
+}
+
+func TestTunnelGoesThroughScaleToZeroWakeUp(t *testing.T) {
+	port := echoBackend(t)
+	server, redisServer := tunnelServer(t, &kubernetes.ServiceInfo{
+		Name:               "web-service",
+		Namespace:          "project",
+		ServiceID:          "web",
+		ScaleToZeroEnabled: true,
+		TunnelEnabled:      true,
+		TunnelTokenHash:    tokenHash("secret-token"),
+		Ports:              []int32{int32(port)},
+	})
+	// A crash-looping deployment is never woken up, by requests or tunnels
+	redisServer.Set("deployment:crashloop:project:web-deployment", "1")
+
+	proxy := httptest.NewServer(http.HandlerFunc(server.handleConnect))
+	defer proxy.Close()
+
+	_, _, resp := connect(t, proxy.URL, port, "secret-token")
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("CONNECT status = %d, want 503 from the wake-up check", resp.StatusCode)
+	}
+}
+
+func TestTunnelRejectsPortNotExposed(t *testing.T) {
+	port := echoBackend(t)
+	server, redisServer := tunnelServer(t, &kubernetes.ServiceInfo{
+		Name:            "web-service",
+		Namespace:       "project",
+		ServiceID:       "web",
+		TunnelEnabled:   true,
+		TunnelTokenHash: tokenHash("secret-token"),
+		Ports:           []int32{80},
+	})
+	proxy := httptest.NewServer(http.HandlerFunc(server.handleConnect))
+	defer proxy.Close()
+
+	// The backend listens on the port but the service doesn't expose it
+	_, _, resp := connect(t, proxy.URL, port, "secret-token")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("CONNECT status = %d, want 403", resp.StatusCode)
+	}
+	if redisServer.Exists("service:access:project:web-deployment") {
+		t.Error("rejected tunnel recorded access to the service")
+	}
+}
@@ -37,6 +37,11 @@ func NewClient(redisAddr string, redisPassword string, redisDB int) (*Client, er
 	}, nil
 }
 
+// Ping checks that Redis is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
 // Close closes the Redis client
 func (c *Client) Close() error {
 	return c.client.Close()
@@ -52,6 +57,25 @@ func (c *Client) RecordServiceAccess(namespace, serviceName string) {
 	}
 }
 
+// trafficTTL keeps the traffic of the previous month around after it ended
+const trafficTTL = 62 * 24 * time.Hour
+
+// RecordTraffic adds bytes proxied for a service to its traffic of the current month (UTC),
+// the API reports it in the organization usage
+func (c *Client) RecordTraffic(serviceID string, bytes int64) {
+	if bytes <= 0 {
+		return
+	}
+	key := fmt.Sprintf("service:traffic:%s:%s", serviceID, time.Now().UTC().Format("2006-01"))
+
+	pipe := c.client.TxPipeline()
+	pipe.IncrBy(c.ctx, key, bytes)
+	pipe.Expire(c.ctx, key, trafficTTL)
+	if _, err := pipe.Exec(c.ctx); err != nil {
+		log.Printf("Error recording service traffic: %v", err)
+	}
+}
+
 // GetTimestamp gets the timestamp value for a key
 func (c *Client) GetTimestamp(key string) (int64, error) {
 	// Get the timestamp from Redis
@@ -129,6 +153,26 @@ func (c *Client) Exists(key string) (bool, error) {
 	return result > 0, nil
 }
 
+// TTL returns the remaining time to live of a key, or a negative duration if the key
+// doesn't exist or has no expiry
+func (c *Client) TTL(key string) (time.Duration, error) {
+	return c.client.TTL(c.ctx, key).Result()
+}
+
+// ScanKeys returns every key matching a glob pattern, scanning instead of blocking Redis with KEYS
+func (c *Client) ScanKeys(pattern string) ([]string, error) {
+	var keys []string
+	iter := c.client.Scan(c.ctx, 0, pattern, 100).Iterator()
+	for iter.Next(c.ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
 // IsDeploymentInCrashLoop checks if a deployment is marked as being in CrashLoopBackOff
 func (c *Client) IsDeploymentInCrashLoop(namespace, deploymentName string) (bool, error) {
 	key := fmt.Sprintf("deployment:crashloop:%s:%s", namespace, deploymentName)
@@ -190,6 +190,23 @@ export class BuildProcessor {
       
       // Check for cancellation before Docker build
       if (await checkCancellation()) return;
+
+      // Step 1.2: Resolve the build context, a subdirectory of the repository for monorepos
+      const repoDir = path.resolve(workDir);
+      const contextDir = path.resolve(repoDir, request.buildContextPath || '.');
+      if (contextDir !== repoDir && !contextDir.startsWith(repoDir + path.sep)) {
+        throw new Error(`Build context path ${request.buildContextPath} is outside the repository`);
+      }
+      if (!fs.existsSync(contextDir) || !fs.statSync(contextDir).isDirectory()) {
+        throw new Error(`Build context path ${request.buildContextPath} is not a directory of the repository`);
+      }
+      if (contextDir !== repoDir) {
+        logger.info(`Using build context: ${request.buildContextPath}`);
+        await dashboardApi.updateDeploymentLogs({
+          deploymentId: request.deploymentId,
+          text: `Using build context: ${request.buildContextPath}`
+        });
+      }
       
       // Save env variables to the .env file in the folder
       if (request.environmentVariables && request.environmentVariables.length > 0) {
@@ -199,7 +216,7 @@ export class BuildProcessor {
           text: `Saving environment variables to .env file`
         });
         
-        const envFilePath = path.join(workDir, '.env');
+        const envFilePath = path.join(contextDir, '.env');
         let envContent = '';
         
         // Convert environment variables object to .env file format
@@ -229,7 +246,7 @@ export class BuildProcessor {
         text: `Creating/updating .dockerignore file to prevent including files outside project directory`
       });
       
-      const dockerignorePath = path.join(workDir, '.dockerignore');
+      const dockerignorePath = path.join(contextDir, '.dockerignore');
       const dockerignoreContent = `# Ignore git directories
 .git
 .github
@@ -319,11 +336,12 @@ Thumbs.db`;
         text: `Building Docker image: ${imageName}`
       });
       
+      // The Dockerfile path is relative to the repository, the build to its context
       const builtImage = await this.imageBuilder.buildImage(
-        workDir,
+        contextDir,
         imageName,
         "",
-        request.runtimeFilePath ? request.runtimeFilePath : undefined,
+        request.runtimeFilePath ? path.relative(contextDir, path.join(repoDir, request.runtimeFilePath)) : undefined,
         request.ports,
         async ({ source, data }: CommandOutput) => {
           await dashboardApi.updateDeploymentLogs({ deploymentId: request.deploymentId, text: data });
@@ -32,7 +32,9 @@ export class GitService {
         }
       }
       
-      logger.info(`Cloning repository ${repoUrl} branch ${branch} into ${workDir}`);
+      // Strip credentials embedded in the URL before logging
+      const safeRepoUrl = repoUrl.replace(/\/\/[^@\/]+@/, '//***@');
+      logger.info(`Cloning repository ${safeRepoUrl} branch ${branch} into ${workDir}`);
       
       // Clone the repository with the specified branch and optimizations for minimal download
       await this.git.clone(repoUrl, workDir, [
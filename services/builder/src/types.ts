@@ -60,6 +60,8 @@ export interface DeploymentQueueItem {
   branch: string;
   repositoryName: string;
   runtimeFilePath: string | null;
+  // Subdirectory of the repository used as the build context, the root when unset
+  buildContextPath?: string | null;
   gitProvider: GitProvider;
   environmentVariables?: DeployEnvironmentVariable[];
   ports?: DeployPort[];
@@ -51,7 +51,7 @@ class SchedulerService {
 
       const scheduler = schedule(job.schedule, () => {
         this.executeJob(job);
-      });
+      }, { timezone: job.timezone || 'UTC' });
 
       this.cronJobs.set(job.id, { job, scheduler });
       logger.info(`Added job to scheduler: ${job.id}`);
@@ -2,6 +2,7 @@ export interface CronJob {
   id: string;
   name: string;
   schedule: string;
+  timezone?: string;
   path: string;
   headers: Record<string, string> | null;
   enabled: boolean;
@@ -40,5 +40,10 @@ export const config = {
   privilegedServices: {
     serviceIds: (process.env.PRIVILEGED_SERVICE_IDS || '').split(',').filter(Boolean),
   },
+  // Volume snapshots for database service backups
+  backups: {
+    volumeSnapshotClassName: process.env.VOLUME_SNAPSHOT_CLASS_NAME || '',
+    timeoutMinutes: parseInt(process.env.BACKUP_TIMEOUT_MINUTES || '30'),
+  },
   logLevel: process.env.LOG_LEVEL || 'info'
 };
@@ -1,4 +1,5 @@
-import { CoreV1Api, KubeConfig, AppsV1Api, CustomObjectsApi, NetworkingV1Api } from '@kubernetes/client-node';
+import { CoreV1Api, KubeConfig, AppsV1Api, CustomObjectsApi, NetworkingV1Api, Exec, V1Status } from '@kubernetes/client-node';
+import { PassThrough } from 'stream';
 import { ContainerRegistryConfig, KubeDeploymentConfig } from '../types';
 import { logger } from '../utils/logger';
 import { config } from '../config';
@@ -39,12 +40,47 @@ interface ControlServiceEvent {
   serviceId: string;
   projectId: string;
   organizationId: string;
-  action: "scale-up" | "scale-down";
+  action: "scale-up" | "scale-down" | "scale" | "rotate-credentials";
+  // replicas is the replica count of the "scale" action
+  replicas?: number;
+  // credentials and previousPassword are the new and current credentials of the
+  // "rotate-credentials" action
+  credentials?: {
+    username: string;
+    password: string;
+    database: string;
+  };
+  previousPassword?: string;
   type: 'control-service';
 }
 
+interface BackupServiceEvent {
+  backupId: string;
+  serviceId: string;
+  projectId: string;
+  serviceType: string;
+  snapshotName: string;
+  type: 'backup-service';
+}
+
 // Union type for all event types
-type KubestratorEvent = DeployServiceEvent | DeleteOrganizationEvent | DeleteProjectEvent | DeleteServiceEvent | ControlServiceEvent;
+type KubestratorEvent = DeployServiceEvent | DeleteOrganizationEvent | DeleteProjectEvent | DeleteServiceEvent | ControlServiceEvent | BackupServiceEvent;
+
+// Convert a Kubernetes quantity such as "10Gi" to bytes
+function parseQuantity(quantity?: string): number | undefined {
+  if (!quantity) {
+    return undefined;
+  }
+  const match = /^([0-9.]+)([KMGTPE]i?|k)?$/.exec(quantity);
+  if (!match) {
+    return undefined;
+  }
+  const units: Record<string, number> = {
+    k: 1e3, K: 1e3, M: 1e6, G: 1e9, T: 1e12, P: 1e15, E: 1e18,
+    Ki: 2 ** 10, Mi: 2 ** 20, Gi: 2 ** 30, Ti: 2 ** 40, Pi: 2 ** 50, Ei: 2 ** 60,
+  };
+  return Math.round(parseFloat(match[1]) * (match[2] ? units[match[2]] : 1));
+}
 
 class KubeStratorService {
   private redis: Redis;
@@ -53,6 +89,7 @@ class KubeStratorService {
   private appsApi: AppsV1Api;
   private customObjectsApi: CustomObjectsApi;
   private k8sNetworkingApi: NetworkingV1Api;
+  private exec: Exec;
   private ecrService: ECRService;
 
   constructor() {
@@ -79,6 +116,7 @@ class KubeStratorService {
     this.appsApi = kc.makeApiClient(AppsV1Api);
     this.customObjectsApi = kc.makeApiClient(CustomObjectsApi);
     this.k8sNetworkingApi = kc.makeApiClient(NetworkingV1Api);
+    this.exec = new Exec(kc);
     this.ecrService = new ECRService();
   }
 
@@ -132,6 +170,9 @@ class KubeStratorService {
               case 'control-service':
                 await this.controlService(parsedEvent);
                 break;
+              case 'backup-service':
+                await this.backupService(parsedEvent);
+                break;
               default:
                 logger.warn(`Unknown event type: ${(parsedEvent as any).type}`);
             }
@@ -343,7 +384,22 @@ class KubeStratorService {
   }
 
   private async controlService(event: ControlServiceEvent) {
-    const { serviceId, projectId, action } = event;
+    const { serviceId, projectId, action, replicas: scaleReplicas } = event;
+    if (action === 'rotate-credentials') {
+      // The API only stores the new password once the server is reported to use it
+      try {
+        await this.rotateCredentials(event);
+      } catch (error) {
+        await dashboardApi.updateCredentialRotation({
+          serviceId,
+          status: 'FAILED',
+          error: error instanceof Error ? error.message : String(error),
+        }).catch(reportError => logger.error(`Failed to report credential rotation of service ${serviceId}:`, reportError));
+        throw error;
+      }
+      await dashboardApi.updateCredentialRotation({ serviceId, status: 'ROTATED' });
+      return;
+    }
     logger.info(`Controlling service: ${serviceId} with action: ${action}`);
 
     const namespace = projectId || 'default';
@@ -362,7 +418,7 @@ class KubeStratorService {
       }
 
       // Determine replica count based on action
-      const replicas = action === 'scale-up' ? 1 : 0;
+      const replicas = action === 'scale' ? (scaleReplicas ?? 0) : action === 'scale-up' ? 1 : 0;
 
       // Create patch to update replicas
       const replicaPatch = {
@@ -387,10 +443,10 @@ class KubeStratorService {
         }
       );
 
-      logger.info(`Successfully ${action === 'scale-up' ? 'scaled up' : 'scaled down'} service: ${serviceId} to ${replicas} replicas`);
+      logger.info(`Successfully ${action === 'scale' ? 'scaled' : action === 'scale-up' ? 'scaled up' : 'scaled down'} service: ${serviceId} to ${replicas} replicas`);
 
       // Update deployment status in Redis (only for web services)
-      const isActive = action === 'scale-up';
+      const isActive = replicas > 0;
       await this.setDeploymentStatus(namespace, deployName, isActive, serviceType);
     } catch (error) {
       logger.error(`Failed to control service ${serviceId}:`, error);
@@ -398,6 +454,198 @@ class KubeStratorService {
     }
   }
 
+  // Change the password of the running database or memory server of a service, then the env
+  // secret and Valkey config it is started with so a restart keeps the new password
+  private async rotateCredentials(event: ControlServiceEvent) {
+    const { serviceId, projectId, credentials, previousPassword } = event;
+    const namespace = projectId || 'default';
+    if (!credentials || !previousPassword) {
+      throw new Error(`Credential rotation of service ${serviceId} needs the new and previous credentials`);
+    }
+    logger.info(`Rotating credentials of service: ${serviceId}`);
+
+    const deployment = await this.appsApi.readNamespacedDeployment(`${serviceId}-deployment`, namespace);
+    const serviceType = deployment.body.metadata?.labels?.type;
+
+    // Generated credentials are alphanumeric, they can be quoted as they are
+    const { username, password, database } = credentials;
+    let command: (loginPassword: string) => string[];
+    let secretKeys: string[];
+    if (serviceType === 'mysql') {
+      // The root password is the user password, see createDeployment
+      command = loginPassword => [
+        'mysql', '-uroot', `-p${loginPassword}`, '-e',
+        `ALTER USER '${username}'@'%' IDENTIFIED BY '${password}'; ` +
+        `ALTER USER 'root'@'%' IDENTIFIED BY '${password}'; ` +
+        `ALTER USER 'root'@'localhost' IDENTIFIED BY '${password}';`
+      ];
+      secretKeys = ['MYSQL_ROOT_PASSWORD', 'MYSQL_PASSWORD'];
+    } else if (serviceType === 'postgresql') {
+      // Local connections are trusted by the image, the current password isn't needed
+      command = () => ['psql', '-U', username, '-d', database, '-c', `ALTER USER "${username}" WITH PASSWORD '${password}'`];
+      secretKeys = ['POSTGRES_PASSWORD'];
+    } else if (serviceType === 'memory') {
+      command = loginPassword => [
+        'valkey-cli', '--no-auth-warning', '--user', username, '--pass', loginPassword,
+        'ACL', 'SETUSER', username, 'resetpass', `>${password}`
+      ];
+      secretKeys = ['VALKEY_PASSWORD'];
+    } else {
+      throw new Error(`Credentials of ${serviceType} service ${serviceId} can't be rotated`);
+    }
+
+    const pods = await this.k8sApi.listNamespacedPod(namespace, undefined, undefined, undefined, undefined, `service=${serviceId}`);
+    const pod = pods.body.items.find(item => item.status?.phase === 'Running');
+    if (!pod?.metadata?.name) {
+      throw new Error(`No running pod found for service ${serviceId}`);
+    }
+    // A retried rotation may find the server already changed by an earlier attempt that failed
+    // later on, it then only accepts the new password
+    try {
+      await this.execInPod(namespace, pod.metadata.name, serviceId, command(previousPassword));
+    } catch (error) {
+      if (serviceType === 'postgresql') {
+        throw error;
+      }
+      logger.warn(`Changing the password of service ${serviceId} with the current password failed, retrying with the new one: ${error}`);
+      await this.execInPod(namespace, pod.metadata.name, serviceId, command(password));
+    }
+
+    const secretName = `${serviceId}-env-secret`;
+    const secret = await this.k8sApi.readNamespacedSecret(secretName, namespace);
+    const secretData = secret.body.data || {};
+    for (const key of secretKeys) {
+      secretData[key] = Buffer.from(password).toString('base64');
+    }
+    await this.createSecret(secretName, namespace, secret.body.type || 'Opaque', secretData);
+
+    if (serviceType === 'memory') {
+      const configMapName = `${serviceId}-memory-config`;
+      const configMap = await this.k8sApi.readNamespacedConfigMap(configMapName, namespace);
+      const valkeyConf = configMap.body.data?.['valkey.conf'];
+      if (valkeyConf) {
+        configMap.body.data!['valkey.conf'] = valkeyConf.replace(`>${previousPassword}`, `>${password}`);
+        await this.k8sApi.replaceNamespacedConfigMap(configMapName, namespace, configMap.body);
+      }
+    }
+
+    logger.info(`Successfully rotated credentials of service: ${serviceId}`);
+  }
+
+  // Run a command in a container and resolve once it exited successfully
+  private execInPod(namespace: string, podName: string, containerName: string, command: string[]): Promise<void> {
+    return new Promise((resolve, reject) => {
+      const stderr = new PassThrough();
+      let errorOutput = '';
+      stderr.on('data', chunk => errorOutput += chunk.toString());
+
+      this.exec.exec(namespace, podName, containerName, command, null, stderr, null, false, (status: V1Status) => {
+        if (status.status === 'Success') {
+          resolve();
+        } else {
+          reject(new Error(`Command failed in pod ${podName}: ${status.message || status.reason} ${errorOutput.trim()}`));
+        }
+      }).catch(reject);
+    });
+  }
+
+  private async backupService(event: BackupServiceEvent) {
+    const { backupId, serviceId, projectId, snapshotName } = event;
+    const namespace = projectId || 'default';
+    const pvcName = `${serviceId}-pvc`;
+    logger.info(`Creating volume snapshot ${snapshotName} for service: ${serviceId}`);
+
+    try {
+      await dashboardApi.updateBackupStatus({ backupId, status: 'IN_PROGRESS' });
+
+      const snapshot: any = {
+        apiVersion: 'snapshot.storage.k8s.io/v1',
+        kind: 'VolumeSnapshot',
+        metadata: {
+          name: snapshotName,
+          namespace,
+          labels: {
+            managedBy: 'kubestrator',
+            service: serviceId,
+            backup: backupId
+          }
+        },
+        spec: {
+          source: {
+            persistentVolumeClaimName: pvcName
+          }
+        }
+      };
+      if (config.backups.volumeSnapshotClassName) {
+        snapshot.spec.volumeSnapshotClassName = config.backups.volumeSnapshotClassName;
+      }
+
+      await this.customObjectsApi.createNamespacedCustomObject(
+        'snapshot.storage.k8s.io',
+        'v1',
+        namespace,
+        'volumesnapshots',
+        snapshot
+      );
+    } catch (error) {
+      await this.failBackup(backupId, serviceId, error);
+      return;
+    }
+
+    // Snapshots may take up to the backup timeout, other jobs aren't held up waiting for it
+    this.waitForSnapshot(event).catch(error => this.failBackup(backupId, serviceId, error));
+  }
+
+  // Poll a volume snapshot until it is ready, then report the backup completed
+  private async waitForSnapshot(event: BackupServiceEvent) {
+    const { backupId, serviceId, projectId, snapshotName } = event;
+    const namespace = projectId || 'default';
+
+    const deadline = Date.now() + config.backups.timeoutMinutes * 60 * 1000;
+    while (Date.now() < deadline) {
+      const response = await this.customObjectsApi.getNamespacedCustomObject(
+        'snapshot.storage.k8s.io',
+        'v1',
+        namespace,
+        'volumesnapshots',
+        snapshotName
+      );
+      const status = (response.body as any).status || {};
+
+      if (status.error?.message) {
+        throw new Error(status.error.message);
+      }
+
+      if (status.readyToUse) {
+        logger.info(`Volume snapshot ${snapshotName} is ready for service: ${serviceId}`);
+        await dashboardApi.updateBackupStatus({
+          backupId,
+          status: 'COMPLETED',
+          sizeBytes: parseQuantity(status.restoreSize)
+        });
+        return;
+      }
+
+      await new Promise(resolve => setTimeout(resolve, 5000));
+    }
+
+    throw new Error(`Snapshot was not ready after ${config.backups.timeoutMinutes} minutes`);
+  }
+
+  // Report a backup failed
+  private async failBackup(backupId: string, serviceId: string, error: unknown) {
+    logger.error(`Failed to back up service ${serviceId}:`, error);
+    try {
+      await dashboardApi.updateBackupStatus({
+        backupId,
+        status: 'FAILED',
+        error: error instanceof Error ? error.message : String(error)
+      });
+    } catch (statusError) {
+      logger.error(`Failed to update backup status for ${backupId}:`, statusError);
+    }
+  }
+
   private async deleteOrganization(event: DeleteOrganizationEvent) {
     const { organizationId } = event;
     logger.info(`Deleting all resources for organization: ${organizationId}`);
@@ -1302,7 +1550,12 @@ class KubeStratorService {
 
     let registrySecretName: string | undefined;
     if (config.serviceType === 'private' || config.serviceType === 'web') {
-      registrySecretName = await this.createContainerRegistrySecret(namespace, config.serviceId, config.containerRegistry);
+      if (config.containerRegistry.pullSecretName) {
+        logger.info(`Using pull secret ${config.containerRegistry.pullSecretName} created by the API for service ${config.serviceId}`);
+        registrySecretName = config.containerRegistry.pullSecretName;
+      } else {
+        registrySecretName = await this.createContainerRegistrySecret(namespace, config.serviceId, config.containerRegistry);
+      }
     }
 
     // Create environment variables secret if there are environment variables
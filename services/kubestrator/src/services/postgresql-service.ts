@@ -262,6 +262,7 @@ export function createPostgresqlServiceServiceManifest(serviceName: string, conf
 				service: config.serviceId,
 				type: config.serviceType,
 				'username-1': config.credentials?.username || undefined,
+				'database-1': config.credentials?.database || undefined,
 			},
 		},
 		spec: {
@@ -83,6 +83,7 @@ export function createPrivateServiceDeploymentManifest(deploymentName: string, c
 						image: config.containerRegistry.imageUri,
 						imagePullPolicy: "Always",
 						...(config.command && config.command.length > 0 ? { command: config.command } : {}),
+						...(config.args && config.args.length > 0 ? { args: config.args } : {}),
 						ports: config.ports?.map((port, index) => ({
 							containerPort: port.containerPort,
 							name: `port-${index}`,
@@ -199,6 +200,7 @@ export function createPrivateServiceDeploymentCompletePatch(config: KubeDeployme
 							name: config.serviceId,
 							image: config.containerRegistry.imageUri,
 							...(config.command && config.command.length > 0 ? { command: config.command } : {}),
+							...(config.args && config.args.length > 0 ? { args: config.args } : {}),
 							...(config.resources && { resources: config.resources }),
 							ports: portsWithPatchDirective,
 							...(envSecretName && {
@@ -267,6 +269,7 @@ export function createPrivateServiceServiceManifest(serviceName: string, config:
 				service: config.serviceId,
 				type: config.serviceType,
 				scaleToZeroEnabled: config.scaleToZeroEnabled ? 'true' : 'false',
+				...(config.ingressPort ? { 'ingress-port': String(config.ingressPort) } : {}),
 			},
 		},
 		spec: {
@@ -91,6 +91,7 @@ export function createWebServiceDeploymentManifest(deploymentName: string, confi
 						image: config.containerRegistry.imageUri,
 						imagePullPolicy: "Always",
 						...(config.command && config.command.length > 0 ? { command: config.command } : {}),
+						...(config.args && config.args.length > 0 ? { args: config.args } : {}),
 						ports: config.ports?.map((port, index) => ({
 							containerPort: port.containerPort,
 							name: `port-${index}`,
@@ -207,6 +208,7 @@ export function createWebServiceDeploymentCompletePatch(config: KubeDeploymentCo
 							name: config.serviceId,
 							image: config.containerRegistry.imageUri,
 							...(config.command && config.command.length > 0 ? { command: config.command } : {}),
+							...(config.args && config.args.length > 0 ? { args: config.args } : {}),
 							...(config.resources && { resources: config.resources }),
 							ports: portsWithPatchDirective,
 							...(envSecretName && {
@@ -280,8 +282,19 @@ export function createWebServiceServiceManifest(serviceName: string, config: Kub
 						return acc;
 					}, {} as Record<string, string>)
 					: {}),
+				...(config.redirect ? {
+					'redirect-from': config.redirect.from,
+					'redirect-to': config.redirect.to,
+				} : {}),
 				scaleToZeroEnabled: config.scaleToZeroEnabled ? 'true' : 'false',
+				...(config.tunnel ? { tunnelEnabled: 'true' } : {}),
 			},
+			// A SHA-256 hex digest is too long for a label value
+			...(config.tunnel ? {
+				annotations: {
+					tunnelTokenHash: config.tunnel.tokenHash,
+				},
+			} : {}),
 		},
 		spec: {
 			selector: {
@@ -31,6 +31,8 @@ export interface ContainerRegistryConfig {
   username?: string;
   password?: string;
   type?: 'ecr' | 'ghcr' | 'docker';
+  // Set when the API already created the pull secret
+  pullSecretName?: string;
 }
 
 export interface KubeDeploymentConfig {
@@ -79,6 +81,15 @@ export interface KubeDeploymentConfig {
     servicePort: number;
   }[];
   domains?: string[];
+  // A second host of the service the web proxy 301s to its primary domain
+  redirect?: {
+    from: string;
+    to: string;
+  };
+  // The web proxy accepts HTTP CONNECT tunnels to the service with the token of this hash
+  tunnel?: {
+    tokenHash: string;
+  };
   storage?: {
     size?: string;
     storageClass?: string;
@@ -90,4 +101,6 @@ export interface KubeDeploymentConfig {
   };
   scaleToZeroEnabled: boolean;
   command?: string[];
+  args?: string[];
+  ingressPort?: number;
 }
@@ -70,6 +70,13 @@ export async function updateDeploymentLogs({ deploymentId, text, type }: UpdateD
   }
 }
 
+export interface UpdateBackupStatusParams {
+  backupId: string;
+  status: 'IN_PROGRESS' | 'COMPLETED' | 'FAILED';
+  sizeBytes?: number;
+  error?: string;
+}
+
 export async function updateServiceStatus({ serviceId, status }: UpdateServiceStatusParams) {
   try {
     const response = await api.post(`/webhooks/services/${serviceId}/status`, {
@@ -83,8 +90,49 @@ export async function updateServiceStatus({ serviceId, status }: UpdateServiceSt
   }
 }
 
+/**
+ * Update the status of a service backup
+ */
+export async function updateBackupStatus({ backupId, status, sizeBytes, error }: UpdateBackupStatusParams) {
+  try {
+    const response = await api.post(`/webhooks/backups/${backupId}/status`, {
+      status,
+      sizeBytes,
+      error
+    });
+
+    return response.data;
+  } catch (error) {
+    throw error;
+  }
+}
+
+export interface UpdateCredentialRotationParams {
+  serviceId: string;
+  status: 'ROTATED' | 'FAILED';
+  error?: string;
+}
+
+/**
+ * Report whether the server of a service uses the password of its pending credential rotation
+ */
+export async function updateCredentialRotation({ serviceId, status, error }: UpdateCredentialRotationParams) {
+  try {
+    const response = await api.post(`/webhooks/services/${serviceId}/credentials`, {
+      status,
+      error
+    });
+
+    return response.data;
+  } catch (error) {
+    throw error;
+  }
+}
+
 export default {
   updateDeploymentLogs,
   updateDeploymentStatus,
-  updateServiceStatus
+  updateServiceStatus,
+  updateBackupStatus,
+  updateCredentialRotation
 };